@@ -0,0 +1,187 @@
+// Package audit records how discovery changed the graph: who added a node or
+// relationship, when, why, and the evidence it was based on, so the origin of
+// any edge can be traced with "audit <nodeID>". Records are appended to a
+// JSON Lines file, or kept in memory when no file is open.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"margraf/clock"
+	"os"
+	"sync"
+	"time"
+)
+
+// Action is what a record changed
+type Action string
+
+const (
+	ActionAddNode Action = "add_node"
+	ActionAddEdge Action = "add_edge"
+)
+
+// Actors that change the graph through discovery
+const (
+	ActorSeeder    = "seeder"             // LLM / web search / Wikidata / Comtrade discovery
+	ActorStarter   = "starter"            // Starter dataset
+	ActorNews      = "news"               // Entities first seen in headlines
+	ActorInference = "relation_inference" // Supply chain edges derived from existing edges
+	ActorCoMention = "co_mention"         // Edges between nodes named together in news and social posts
+)
+
+// ActorDataSource is the actor for a registered data source
+func ActorDataSource(name string) string {
+	return "datasource:" + name
+}
+
+// maxEvidence bounds the evidence snippet stored per record
+const maxEvidence = 300
+
+// maxMemory bounds the records kept when no file is open
+const maxMemory = 10000
+
+// Record is one audited change
+type Record struct {
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	Action   Action    `json:"action"`
+	NodeID   string    `json:"node_id,omitempty"`   // add_node
+	SourceID string    `json:"source_id,omitempty"` // add_edge
+	TargetID string    `json:"target_id,omitempty"` // add_edge
+	EdgeType string    `json:"edge_type,omitempty"` // add_edge
+	Reason   string    `json:"reason,omitempty"`
+	Evidence string    `json:"evidence,omitempty"` // Headline, search snippet or LLM answer the change rests on
+}
+
+// Involves reports whether the record concerns the node
+func (r Record) Involves(nodeID string) bool {
+	return r.NodeID == nodeID || r.SourceID == nodeID || r.TargetID == nodeID
+}
+
+var (
+	mu     sync.Mutex
+	file   *os.File
+	path   string
+	memory []Record
+)
+
+// Open appends records to the file at p from now on; records kept in memory
+// so far are written to it first
+func Open(p string) error {
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	file, path = f, p
+	for _, r := range memory {
+		if err := writeLocked(r); err != nil {
+			return err
+		}
+	}
+	memory = nil
+	return nil
+}
+
+// Close stops writing to the file; later records are kept in memory
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file, path = nil, ""
+	return err
+}
+
+// Log records a change, stamping the time if unset
+func Log(r Record) {
+	if r.Time.IsZero() {
+		r.Time = clock.Now()
+	}
+	if runes := []rune(r.Evidence); len(runes) > maxEvidence {
+		r.Evidence = string(runes[:maxEvidence]) + "..."
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		if err := writeLocked(r); err == nil {
+			return
+		}
+		// Keep the record rather than lose it if the file is unwritable
+	}
+	memory = append(memory, r)
+	if len(memory) > maxMemory {
+		memory = append(memory[:0:0], memory[len(memory)-maxMemory:]...)
+	}
+}
+
+func writeLocked(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// ForNode returns the records that added the node or an edge touching it,
+// oldest first
+func ForNode(nodeID string) ([]Record, error) {
+	return find(func(r Record) bool { return r.Involves(nodeID) })
+}
+
+// ForEdge returns the records that added an edge from sourceID to targetID,
+// of any type, oldest first
+func ForEdge(sourceID, targetID string) ([]Record, error) {
+	return find(func(r Record) bool {
+		return r.Action == ActionAddEdge && r.SourceID == sourceID && r.TargetID == targetID
+	})
+}
+
+// find returns the records match accepts, oldest first
+func find(match func(Record) bool) ([]Record, error) {
+	mu.Lock()
+	p := path
+	var out []Record
+	for _, r := range memory {
+		if match(r) {
+			out = append(out, r)
+		}
+	}
+	mu.Unlock()
+	if p == "" {
+		return out, nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var fromFile []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue // A line cut short by a crash
+		}
+		if match(r) {
+			fromFile = append(fromFile, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return append(fromFile, out...), nil
+}
@@ -0,0 +1,194 @@
+// Package bench holds the graph benchmarks: seeded synthetic supply chains
+// and the operations measured on them. cmd/bench runs them against a stored
+// baseline, and the Benchmark functions in graph and simulation run the same
+// bodies under go test -bench, so results also feed benchstat.
+package bench
+
+import (
+	"margraf/graph"
+	"margraf/simulation"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Sizes are the edge counts every benchmark runs at by default
+var Sizes = []int{1000, 10000, 100000}
+
+// Benchmark is one operation measured on a graph with a given edge count
+type Benchmark struct {
+	Name string
+	Run  func(b *testing.B, edges int)
+}
+
+// All lists the benchmarks in the order they are run
+var All = []Benchmark{
+	{"AddEdge", AddEdge},
+	{"UpdateEdgeWeight", UpdateEdgeWeight},
+	{"GetIncomingEdges", GetIncomingEdges},
+	{"ToJSON", ToJSON},
+	{"ApplyTemporalDecay", ApplyTemporalDecay},
+	{"RunShock", RunShock},
+}
+
+// SizeLabel names an edge count as in benchmark names, e.g. "10k"
+func SizeLabel(edges int) string {
+	if edges%1000 == 0 {
+		return strconv.Itoa(edges/1000) + "k"
+	}
+	return strconv.Itoa(edges)
+}
+
+// RunSizes runs fn as a sub-benchmark per size in Sizes, for Benchmark
+// functions
+func RunSizes(b *testing.B, fn func(b *testing.B, edges int)) {
+	for _, edges := range Sizes {
+		b.Run(SizeLabel(edges), func(b *testing.B) { fn(b, edges) })
+	}
+}
+
+// SupplyChain builds a layered supply chain with the given number of
+// Supplies edges: companies in tiers of 50, each supplying companies in the
+// next tier, so shocks on tier 0 travel all the way down. The same seed gives
+// the same graph, keeping runs comparable.
+func SupplyChain(edges int) *graph.Graph {
+	const tierSize = 50
+	companies := max(edges/4, 2*tierSize)
+	rng := rand.New(rand.NewSource(1))
+
+	g := graph.NewGraph()
+	g.DisableAutoSave()
+	nodes := make([]*graph.Node, companies)
+	for i := range nodes {
+		nodes[i] = &graph.Node{ID: CompanyID(i), Type: graph.NodeTypeCorporation, Name: "Company " + strconv.Itoa(i)}
+	}
+	g.AddNodes(nodes)
+
+	tiers := companies / tierSize
+	list := make([]*graph.Edge, 0, edges)
+	for len(list) < edges {
+		tier := rng.Intn(tiers - 1)
+		src := tier*tierSize + rng.Intn(tierSize)
+		tgt := (tier+1)*tierSize + rng.Intn(tierSize)
+		list = append(list, &graph.Edge{
+			SourceID: CompanyID(src),
+			TargetID: CompanyID(tgt),
+			Type:     graph.EdgeTypeSupplies,
+			Weight:   0.1 + 0.9*rng.Float64(),
+		})
+	}
+	g.AddEdges(list)
+	return g
+}
+
+// CompanyID is the ID of the i-th company of a SupplyChain
+func CompanyID(i int) string {
+	return "company_" + strconv.Itoa(i)
+}
+
+// readOnly caches graphs for benchmarks that don't change them
+var readOnly = make(map[int]*graph.Graph)
+
+func sharedChain(edges int) *graph.Graph {
+	g, ok := readOnly[edges]
+	if !ok {
+		g = SupplyChain(edges)
+		readOnly[edges] = g
+	}
+	return g
+}
+
+// AddEdge adds Trade edges between random companies
+func AddEdge(b *testing.B, edges int) {
+	g := SupplyChain(edges)
+	nodes := max(edges/4, 100)
+	rng := rand.New(rand.NewSource(2))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.AddEdge(&graph.Edge{
+			SourceID: CompanyID(rng.Intn(nodes)),
+			TargetID: CompanyID(rng.Intn(nodes)),
+			Type:     graph.EdgeTypeTrade,
+			Weight:   0.5,
+		})
+	}
+}
+
+// UpdateEdgeWeight moves the weight of each edge in turn
+func UpdateEdgeWeight(b *testing.B, edges int) {
+	g := SupplyChain(edges)
+	var list []*graph.Edge
+	g.EdgesRange(func(e *graph.Edge) { list = append(list, e) })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := list[i%len(list)]
+		g.UpdateEdgeWeight(e.SourceID, e.TargetID, e.Type, 0.1, 0.5, "bench_"+strconv.Itoa(i)) // Distinct IDs, so no update is a replay
+	}
+}
+
+// GetIncomingEdges reads the suppliers of each company in turn
+func GetIncomingEdges(b *testing.B, edges int) {
+	g := sharedChain(edges)
+	nodes := max(edges/4, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.GetIncomingEdges(CompanyID(i % nodes))
+	}
+}
+
+// ToJSON encodes the whole graph
+func ToJSON(b *testing.B, edges int) {
+	g := sharedChain(edges)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := g.ToJSON(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// ApplyTemporalDecay decays every edge. Decay skips edges updated within the
+// hour and stamps the ones it changes, so each iteration first backdates the
+// edges by 30 days and restores their weights, off the clock.
+func ApplyTemporalDecay(b *testing.B, edges int) {
+	g := SupplyChain(edges)
+	var list []*graph.Edge
+	g.EdgesRange(func(e *graph.Edge) { list = append(list, e) })
+	weights := make([]float64, len(list))
+	for i, e := range list {
+		weights[i] = e.Weight
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		stale := time.Now().Add(-30 * 24 * time.Hour)
+		for j, e := range list {
+			e.Weight, e.Timestamp = weights[j], stale
+		}
+		b.StartTimer()
+		if g.ApplyTemporalDecay(0.05) == 0 {
+			b.Fatal("no edge decayed")
+		}
+	}
+}
+
+// RunShock shocks tier-0 companies in turn. Health keeps falling across
+// iterations, as it would under repeated real shocks.
+func RunShock(b *testing.B, edges int) {
+	sim := simulation.NewSimulator(SupplyChain(edges))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sim.RunShock(simulation.ShockEvent{
+			TargetNodeID: CompanyID(i % 50),
+			Description:  "bench",
+			ImpactFactor: 0.5,
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// Package bus coordinates multiple margraf processes over a Redis or NATS
+// pub/sub channel. Every message carries the publishing instance's ID so
+// instances ignore their own events.
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"margraf/logger"
+	"margraf/retry"
+	"margraf/syserr"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Topics published between instances
+const (
+	TopicGraphDelta = "graph_delta" // graph.Delta: node/edge upserts and health changes
+	TopicHub        = "hub"         // server.BroadcastMessage: shock events, news impacts, pulses
+)
+
+// Message is the envelope sent over the wire
+type Message struct {
+	Origin  string          `json:"origin"`
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// backend is a pub/sub transport. run keeps a subscription to channel alive
+// (reconnecting as needed) until done is closed.
+type backend interface {
+	publish(channel string, data []byte) error
+	run(channel string, deliver func([]byte), done <-chan struct{})
+	close() error
+}
+
+// Bus is a connection to the shared message bus. A nil Bus is a no-op,
+// which is what single-instance deployments use.
+type Bus struct {
+	origin  string
+	channel string
+	backend backend
+
+	mu       sync.RWMutex
+	handlers map[string][]func(Message)
+	done     chan struct{}
+}
+
+// Open connects to rawURL (redis://host:6379 or nats://host:4222) and starts
+// listening on channel. origin identifies this instance. An empty URL returns nil.
+func Open(rawURL, channel, origin string) (*Bus, error) {
+	if rawURL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse bus url: %w", err)
+	}
+
+	var be backend
+	switch u.Scheme {
+	case "redis":
+		be = newRedisBackend(u)
+	case "nats":
+		be = newNATSBackend(u)
+	default:
+		return nil, fmt.Errorf("unsupported bus scheme %q (use redis:// or nats://)", u.Scheme)
+	}
+
+	b := &Bus{
+		origin:   origin,
+		channel:  channel,
+		backend:  be,
+		handlers: make(map[string][]func(Message)),
+		done:     make(chan struct{}),
+	}
+	go be.run(channel, b.deliver, b.done)
+	return b, nil
+}
+
+// Origin returns this instance's ID
+func (b *Bus) Origin() string {
+	if b == nil {
+		return ""
+	}
+	return b.origin
+}
+
+// Publish sends payload to all other instances under topic
+func (b *Bus) Publish(topic string, payload interface{}) error {
+	if b == nil {
+		return nil
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(Message{Origin: b.origin, Topic: topic, Payload: raw})
+	if err != nil {
+		return err
+	}
+	return b.backend.publish(b.channel, data)
+}
+
+// Subscribe registers handler for messages on topic from other instances.
+// Handlers run on the bus reader goroutine and should not block.
+func (b *Bus) Subscribe(topic string, handler func(Message)) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.handlers[topic] = append(b.handlers[topic], handler)
+	b.mu.Unlock()
+}
+
+// Close stops the subscription and releases connections
+func (b *Bus) Close() error {
+	if b == nil {
+		return nil
+	}
+	close(b.done)
+	return b.backend.close()
+}
+
+// deliver decodes a raw frame and hands it to topic handlers
+func (b *Bus) deliver(data []byte) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		logger.Warn(logger.StatusWarn, "Bus: dropping malformed message: %v", err)
+		return
+	}
+	if msg.Origin == b.origin {
+		return
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers[msg.Topic]
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(msg)
+	}
+}
+
+// reconnectPolicy backs off between subscription reconnect attempts. Jitter
+// keeps replicas from reconnecting in lockstep after a broker restart.
+var reconnectPolicy = retry.Policy{Initial: time.Second, Max: 30 * time.Second, Multiplier: 2, Jitter: 0.2}
+
+// reconnectDelay is the wait before reconnect attempt (0-based)
+func reconnectDelay(attempt int) time.Duration {
+	return reconnectPolicy.Delay(attempt + 1)
+}
+
+// sleepOrDone waits d, returning false if done closes first
+func sleepOrDone(d time.Duration, done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Async returns a non-blocking publisher for topic backed by a queue of size
+// buffer, for callers that can't wait on the network (e.g. graph change hooks).
+// Messages are dropped when the queue is full.
+func (b *Bus) Async(topic string, buffer int) func(payload interface{}) {
+	if b == nil {
+		return func(interface{}) {}
+	}
+
+	queue := make(chan interface{}, buffer)
+	go func() {
+		for {
+			select {
+			case payload := <-queue:
+				if err := b.Publish(topic, payload); err != nil {
+					logger.Warn(logger.StatusWarn, "Bus: publish %s failed: %v", topic, err)
+					syserr.Report(syserr.ModuleBus, "publish "+topic, err)
+				}
+			case <-b.done:
+				return
+			}
+		}
+	}()
+
+	return func(payload interface{}) {
+		select {
+		case queue <- payload:
+		default:
+			logger.Warn(logger.StatusWarn, "Bus: %s queue full, dropping message", topic)
+		}
+	}
+}
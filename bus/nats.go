@@ -0,0 +1,185 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"margraf/logger"
+	"margraf/syserr"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsBackend speaks the NATS text protocol (CONNECT / PUB / SUB / MSG / PING)
+type natsBackend struct {
+	addr string
+	user string
+	pass string
+
+	mu      sync.Mutex // guards pubConn
+	pubConn net.Conn
+
+	subMu   sync.Mutex // guards subConn
+	subConn net.Conn
+}
+
+func newNATSBackend(u *url.URL) *natsBackend {
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	pass, _ := u.User.Password()
+	return &natsBackend{addr: addr, user: u.User.Username(), pass: pass}
+}
+
+// dial connects and sends CONNECT; the server's INFO line is consumed
+func (b *natsBackend) dial() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", b.addr, 5*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := bufio.NewReader(conn)
+	if _, err := r.ReadString('\n'); err != nil { // INFO {...}
+		conn.Close()
+		return nil, nil, err
+	}
+
+	opts := map[string]interface{}{"verbose": false, "pedantic": false, "name": "margraf"}
+	if b.user != "" {
+		opts["user"] = b.user
+		opts["pass"] = b.pass
+	}
+	connect, _ := json.Marshal(opts)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", connect); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, r, nil
+}
+
+func (b *natsBackend) publish(channel string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// One retry with a fresh connection if the cached one went stale
+	for attempt := 0; attempt < 2; attempt++ {
+		if b.pubConn == nil {
+			conn, r, err := b.dial()
+			if err != nil {
+				return fmt.Errorf("nats dial %s: %w", b.addr, err)
+			}
+			// Publish-only conn: answer server PINGs so it isn't dropped as stale
+			go answerPings(conn, r)
+			b.pubConn = conn
+		}
+
+		frame := fmt.Sprintf("PUB %s %d\r\n%s\r\n", channel, len(data), data)
+		_, err := b.pubConn.Write([]byte(frame))
+		if err == nil {
+			return nil
+		}
+		b.pubConn.Close()
+		b.pubConn = nil
+		if attempt == 1 {
+			return fmt.Errorf("nats publish: %w", err)
+		}
+	}
+	return nil
+}
+
+func (b *natsBackend) run(channel string, deliver func([]byte), done <-chan struct{}) {
+	for attempt := 0; ; attempt++ {
+		conn, r, err := b.dial()
+		if err == nil {
+			_, err = fmt.Fprintf(conn, "SUB %s 1\r\n", channel)
+		}
+		if err == nil {
+			b.subMu.Lock()
+			b.subConn = conn
+			b.subMu.Unlock()
+			logger.Info(logger.StatusGlob, "Bus: subscribed to nats %s/%s", b.addr, channel)
+			attempt = 0
+			err = b.readLoop(conn, r, deliver)
+		}
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+		logger.Warn(logger.StatusWarn, "Bus: nats subscription lost: %v", err)
+		syserr.Report(syserr.ModuleBus, "subscribe", syserr.Warning(syserr.ModuleBus, "subscribe", err))
+		if !sleepOrDone(reconnectDelay(attempt), done) {
+			return
+		}
+	}
+}
+
+// readLoop delivers MSG payloads and answers PINGs until the connection fails
+func (b *natsBackend) readLoop(conn net.Conn, r *bufio.Reader, deliver func([]byte)) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			n, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return fmt.Errorf("nats: bad MSG line %q", line)
+			}
+			data := make([]byte, n+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return err
+			}
+			deliver(data[:n])
+		case line == "PING":
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("nats: " + line)
+		}
+	}
+}
+
+// answerPings keeps a publish-only connection alive
+func answerPings(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if strings.TrimRight(line, "\r\n") == "PING" {
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (b *natsBackend) close() error {
+	b.mu.Lock()
+	if b.pubConn != nil {
+		b.pubConn.Close()
+		b.pubConn = nil
+	}
+	b.mu.Unlock()
+
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	if b.subConn != nil {
+		return b.subConn.Close()
+	}
+	return nil
+}
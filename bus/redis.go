@@ -0,0 +1,210 @@
+package bus
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"margraf/logger"
+	"margraf/syserr"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// redisBackend speaks just enough RESP for PUBLISH / SUBSCRIBE
+type redisBackend struct {
+	addr     string
+	password string
+
+	mu      sync.Mutex // guards pubConn
+	pubConn *redisConn
+
+	subMu   sync.Mutex // guards subConn
+	subConn *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newRedisBackend(u *url.URL) *redisBackend {
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	password, _ := u.User.Password()
+	return &redisBackend{addr: addr, password: password}
+}
+
+func (b *redisBackend) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", b.addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	if b.password != "" {
+		if err := c.command("AUTH", b.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if _, err := c.read(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (b *redisBackend) publish(channel string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// One retry with a fresh connection if the cached one went stale
+	for attempt := 0; attempt < 2; attempt++ {
+		if b.pubConn == nil {
+			c, err := b.dial()
+			if err != nil {
+				return fmt.Errorf("redis dial %s: %w", b.addr, err)
+			}
+			b.pubConn = c
+		}
+
+		err := b.pubConn.command("PUBLISH", channel, string(data))
+		if err == nil {
+			_, err = b.pubConn.read()
+		}
+		if err == nil {
+			return nil
+		}
+		b.pubConn.conn.Close()
+		b.pubConn = nil
+		if attempt == 1 {
+			return fmt.Errorf("redis publish: %w", err)
+		}
+	}
+	return nil
+}
+
+func (b *redisBackend) run(channel string, deliver func([]byte), done <-chan struct{}) {
+	for attempt := 0; ; attempt++ {
+		c, err := b.dial()
+		if err == nil {
+			err = c.command("SUBSCRIBE", channel)
+		}
+		if err == nil {
+			b.subMu.Lock()
+			b.subConn = c
+			b.subMu.Unlock()
+			logger.Info(logger.StatusGlob, "Bus: subscribed to redis %s/%s", b.addr, channel)
+			attempt = 0
+			err = b.readLoop(c, deliver)
+		}
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+		logger.Warn(logger.StatusWarn, "Bus: redis subscription lost: %v", err)
+		syserr.Report(syserr.ModuleBus, "subscribe", syserr.Warning(syserr.ModuleBus, "subscribe", err))
+		if !sleepOrDone(reconnectDelay(attempt), done) {
+			return
+		}
+	}
+}
+
+// readLoop delivers ["message", channel, payload] frames until the connection fails
+func (b *redisBackend) readLoop(c *redisConn, deliver func([]byte)) error {
+	for {
+		v, err := c.read()
+		if err != nil {
+			return err
+		}
+		frame, ok := v.([]interface{})
+		if !ok || len(frame) != 3 {
+			continue
+		}
+		if kind, _ := frame[0].(string); kind != "message" {
+			continue
+		}
+		if payload, ok := frame[2].(string); ok {
+			deliver([]byte(payload))
+		}
+	}
+}
+
+func (b *redisBackend) close() error {
+	b.mu.Lock()
+	if b.pubConn != nil {
+		b.pubConn.conn.Close()
+		b.pubConn = nil
+	}
+	b.mu.Unlock()
+
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	if b.subConn != nil {
+		return b.subConn.conn.Close()
+	}
+	return nil
+}
+
+// command writes a RESP array of bulk strings
+func (c *redisConn) command(args ...string) error {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(a))+"\r\n"...)
+		buf = append(buf, a...)
+		buf = append(buf, "\r\n"...)
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// read parses one RESP value: string, int64, []interface{} or nil
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, errors.New("redis: short reply")
+	}
+	body := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, errors.New("redis: " + body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,718 @@
+// Package client is a Go client for the Margraf WebSocket stream.
+// It wraps the wire format, reconnects on failure, fans out broadcasts
+// to subscribers and matches request/response pairs.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margraf/graph"
+	"margraf/server"
+	"margraf/syserr"
+	"margraf/task"
+	"margraf/trading"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Broadcast message types sent by the server; see server/protocol.go for
+// each type's payload
+const (
+	TypeSystem             = server.TypeSystem
+	TypeError              = server.TypeError
+	TypeGraphUpdate        = server.TypeGraphUpdate
+	TypeGraphNotice        = server.TypeGraphNotice
+	TypeNewsAlert          = server.TypeNewsAlert
+	TypeSocialPulse        = server.TypeSocialPulse
+	TypeMentionSpike       = server.TypeMentionSpike
+	TypeEdgeAnomaly        = server.TypeEdgeAnomaly
+	TypeShockEvent         = server.TypeShockEvent
+	TypeShockTrace         = server.TypeShockTrace
+	TypeMarketUpdate       = server.TypeMarketUpdate
+	TypeCompanyRelation    = server.TypeCompanyRelations
+	TypeNationRelations    = server.TypeNationRelations
+	TypeCompaniesList      = server.TypeCompaniesList
+	TypeProjection         = server.TypeProjection
+	TypeHealthHistory      = server.TypeHealthHistory
+	TypeImpact             = server.TypeImpact
+	TypeImpacts            = server.TypeImpacts
+	TypeStressUpdate       = server.TypeStressUpdate
+	TypePortfolioUpdate    = server.TypePortfolioUpdate
+	TypePaperPerformance   = server.TypePaperPerformance
+	TypePairAlert          = server.TypePairAlert
+	TypeCalendar           = server.TypeCalendar
+	TypeTaskUpdate         = server.TypeTaskUpdate
+	TypeTasks              = server.TypeTasks
+	TypeSystemError        = server.TypeSystemError
+	TypeSystemErrors       = server.TypeSystemErrors
+	TypeScenarioComparison = server.TypeScenarioComparison
+	TypeSession            = server.TypeSession
+	TypeWatchEvent         = server.TypeWatchEvent
+	TypeWatching           = server.TypeWatching
+	TypeAskChunk           = server.TypeAskChunk
+	TypeAskAnswer          = server.TypeAskAnswer
+	TypeNote               = server.TypeNote
+	TypeNotes              = server.TypeNotes
+	TypeTimeline           = server.TypeTimeline
+
+	TypeCompanyRelationsUpdate = server.TypeCompanyRelationsUpdate
+)
+
+// ErrClosed is returned for requests made on, or pending in, a closed client
+var ErrClosed = errors.New("client closed")
+
+// Message is a server -> client frame. ID echoes the request ID on responses.
+// Version is the server's protocol version (0 for servers older than 2).
+type Message struct {
+	Version int             `json:"v,omitempty"`
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Decode unmarshals the payload into v, e.g. a server.ShockPayload for a
+// shock_event. Version 1 servers sent some payloads (company relations,
+// graph snapshots) as JSON-encoded strings; those are unwrapped first.
+func (m Message) Decode(v interface{}) error {
+	var inner string
+	if err := json.Unmarshal(m.Payload, &inner); err == nil {
+		if s, ok := v.(*string); ok {
+			*s = inner
+			return nil
+		}
+		return json.Unmarshal([]byte(inner), v)
+	}
+	return json.Unmarshal(m.Payload, v)
+}
+
+// ServerError is a structured error response from the server
+type ServerError server.ErrorPayload
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server error (%s): %s", e.Code, e.Message)
+}
+
+// request is a client -> server frame
+type request struct {
+	ID      string                 `json:"id,omitempty"`
+	Type    string                 `json:"type"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// pendingRequest waits for the response carrying its ID. Responses without
+// an ID (older servers) fall back to the first one accepted by match.
+type pendingRequest struct {
+	id    string
+	match func(Message) bool
+	reply chan Message
+	chunk func(Message) // Receives ask_chunk frames carrying id (nil = none expected)
+}
+
+// Options configures a Client
+type Options struct {
+	Reconnect        bool          // Redial after the connection drops
+	MinBackoff       time.Duration // First reconnect delay (default 1s)
+	MaxBackoff       time.Duration // Reconnect delay cap (default 30s)
+	SubscriberBuffer int           // Channel buffer per subscription (default 64)
+}
+
+// Client is a connection to a Margraf hub
+type Client struct {
+	url  string
+	opts Options
+
+	writeMu sync.Mutex
+	conn    *websocket.Conn
+
+	mu      sync.Mutex
+	subs    map[string][]chan Message // type -> subscribers ("" = all)
+	pending []*pendingRequest
+	nextID  uint64
+	closed  bool
+	done    chan struct{}
+}
+
+// Dial connects to a hub, e.g. Dial("ws://localhost:8080/ws", Options{Reconnect: true})
+func Dial(url string, opts Options) (*Client, error) {
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.SubscriberBuffer == 0 {
+		opts.SubscriberBuffer = 64
+	}
+
+	c := &Client{
+		url:  url,
+		opts: opts,
+		subs: make(map[string][]chan Message),
+		done: make(chan struct{}),
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", url, err)
+	}
+	c.conn = conn
+
+	go c.readLoop()
+	return c, nil
+}
+
+// Subscribe returns a channel receiving every message of the given type.
+// An empty type subscribes to all messages. Slow subscribers drop messages.
+func (c *Client) Subscribe(msgType string) <-chan Message {
+	ch := make(chan Message, c.opts.SubscriberBuffer)
+	c.mu.Lock()
+	c.subs[msgType] = append(c.subs[msgType], ch)
+	c.mu.Unlock()
+	return ch
+}
+
+// Close shuts down the connection and all subscription channels
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	err := c.conn.Close()
+	c.writeMu.Unlock()
+	return err
+}
+
+// Request sends a message and waits for its response of type expect.
+// Error frames are returned as *ServerError.
+func (c *Client) Request(ctx context.Context, msgType string, payload map[string]interface{}, expect string) (Message, error) {
+	return c.request(ctx, msgType, payload, func(m Message) bool { return m.Type == expect })
+}
+
+func (c *Client) request(ctx context.Context, msgType string, payload map[string]interface{}, match func(Message) bool) (Message, error) {
+	return c.await(ctx, msgType, payload, &pendingRequest{match: match, reply: make(chan Message, 1)})
+}
+
+// await sends a request and waits for the response p matches
+func (c *Client) await(ctx context.Context, msgType string, payload map[string]interface{}, p *pendingRequest) (Message, error) {
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return Message{}, ErrClosed
+	}
+	c.nextID++
+	p.id = strconv.FormatUint(c.nextID, 10)
+	c.pending = append(c.pending, p)
+	c.mu.Unlock()
+
+	if err := c.send(request{ID: p.id, Type: msgType, Payload: payload}); err != nil {
+		c.dropPending(p)
+		return Message{}, err
+	}
+
+	select {
+	case msg, ok := <-p.reply:
+		if !ok {
+			return Message{}, ErrClosed
+		}
+		if msg.Type == TypeError {
+			serverErr := &ServerError{RequestID: msg.ID}
+			if err := msg.Decode(serverErr); err != nil {
+				// Older servers send a bare string
+				serverErr.Code = "unknown"
+				msg.Decode(&serverErr.Message)
+			}
+			return msg, serverErr
+		}
+		return msg, nil
+	case <-ctx.Done():
+		c.dropPending(p)
+		return Message{}, ctx.Err()
+	}
+}
+
+// GetCompanyRelations fetches suppliers, clients, materials and products for a company
+func (c *Client) GetCompanyRelations(ctx context.Context, companyID string) (*graph.CompanyRelations, error) {
+	msg, err := c.Request(ctx, "get_company_relations", map[string]interface{}{"company_id": companyID}, TypeCompanyRelation)
+	if err != nil {
+		return nil, err
+	}
+	var relations graph.CompanyRelations
+	if err := msg.Decode(&relations); err != nil {
+		return nil, err
+	}
+	return &relations, nil
+}
+
+// GetNationRelations fetches industries, key companies, trade partners,
+// commodities and import dependencies for a nation
+func (c *Client) GetNationRelations(ctx context.Context, nationID string) (*graph.NationRelations, error) {
+	msg, err := c.Request(ctx, "get_nation_relations", map[string]interface{}{"nation_id": nationID}, TypeNationRelations)
+	if err != nil {
+		return nil, err
+	}
+	var relations graph.NationRelations
+	if err := msg.Decode(&relations); err != nil {
+		return nil, err
+	}
+	return &relations, nil
+}
+
+// CompanySummary is an entry of the companies list
+type CompanySummary = server.CompanySummary
+
+// GetCompaniesList fetches the IDs and names of all corporations
+func (c *Client) GetCompaniesList(ctx context.Context) ([]CompanySummary, error) {
+	msg, err := c.Request(ctx, "get_companies_list", nil, TypeCompaniesList)
+	if err != nil {
+		return nil, err
+	}
+	var companies []CompanySummary
+	if err := msg.Decode(&companies); err != nil {
+		return nil, err
+	}
+	return companies, nil
+}
+
+// GetFullGraph fetches a full graph snapshot. Textual graph_update
+// broadcasts from version 1 servers ("New Node: ...") are skipped while waiting.
+func (c *Client) GetFullGraph(ctx context.Context) (*graph.GraphData, error) {
+	msg, err := c.request(ctx, "get_full_graph", nil, func(m Message) bool {
+		if m.Type != TypeGraphUpdate {
+			return false
+		}
+		var data graph.GraphData
+		return m.Decode(&data) == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var data graph.GraphData
+	if err := msg.Decode(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// GetProjection fetches a summary graph at the given level (graph.ProjectionNation or graph.ProjectionIndustry)
+func (c *Client) GetProjection(ctx context.Context, level graph.ProjectionLevel) (*graph.Projection, error) {
+	msg, err := c.Request(ctx, "get_projection", map[string]interface{}{"level": string(level)}, TypeProjection)
+	if err != nil {
+		return nil, err
+	}
+	var projection graph.Projection
+	if err := msg.Decode(&projection); err != nil {
+		return nil, err
+	}
+	return &projection, nil
+}
+
+// GetHealthHistory fetches a node's recorded health samples, oldest first
+func (c *Client) GetHealthHistory(ctx context.Context, nodeID string) ([]graph.HealthSnapshot, error) {
+	msg, err := c.Request(ctx, "get_health_history", map[string]interface{}{"node_id": nodeID}, TypeHealthHistory)
+	if err != nil {
+		return nil, err
+	}
+	var history graph.HealthHistory
+	if err := msg.Decode(&history); err != nil {
+		return nil, err
+	}
+	return history.History, nil
+}
+
+// GetTasks fetches running and recently finished background tasks
+func (c *Client) GetTasks(ctx context.Context) ([]task.Info, error) {
+	msg, err := c.Request(ctx, "get_tasks", nil, TypeTasks)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []task.Info
+	if err := msg.Decode(&tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// GetPaperPerformance fetches the paper trader's PnL, open risk, hit rate and
+// daily NAV
+func (c *Client) GetPaperPerformance(ctx context.Context) (*trading.PaperPerformance, error) {
+	msg, err := c.Request(ctx, "get_paper_performance", nil, TypePaperPerformance)
+	if err != nil {
+		return nil, err
+	}
+	var perf trading.PaperPerformance
+	if err := msg.Decode(&perf); err != nil {
+		return nil, err
+	}
+	return &perf, nil
+}
+
+// GetCalendar fetches the scheduled events of nodeIDs, or of the nodes the
+// connection watches when none are given, over the next days (0 = 30)
+func (c *Client) GetCalendar(ctx context.Context, days int, nodeIDs ...string) ([]graph.ScheduledEvent, error) {
+	payload := map[string]interface{}{}
+	if len(nodeIDs) > 0 {
+		payload["node_ids"] = nodeIDs
+	}
+	if days > 0 {
+		payload["days"] = days
+	}
+	msg, err := c.Request(ctx, "get_calendar", payload, TypeCalendar)
+	if err != nil {
+		return nil, err
+	}
+	var calendar server.CalendarPayload
+	if err := msg.Decode(&calendar); err != nil {
+		return nil, err
+	}
+	return calendar.Events, nil
+}
+
+// Ask asks the server a question about the graph, passing the answer to
+// onChunk as it is written, and returns the whole answer. onChunk runs on the
+// client's read loop, so it should not block. The server drops chunks for a
+// connection that falls behind, but the returned answer is always complete.
+func (c *Client) Ask(ctx context.Context, question string, onChunk func(string)) (string, error) {
+	p := &pendingRequest{
+		match: func(m Message) bool { return m.Type == TypeAskAnswer },
+		reply: make(chan Message, 1),
+		chunk: func(m Message) {
+			var chunk server.AskChunkPayload
+			if m.Decode(&chunk) == nil && onChunk != nil {
+				onChunk(chunk.Text)
+			}
+		},
+	}
+	msg, err := c.await(ctx, "ask", map[string]interface{}{"question": question}, p)
+	if err != nil {
+		return "", err
+	}
+	var answer server.AskAnswerPayload
+	if err := msg.Decode(&answer); err != nil {
+		return "", err
+	}
+	return answer.Answer, nil
+}
+
+// CancelTask cancels a running background task and returns the updated task list
+func (c *Client) CancelTask(ctx context.Context, taskID string) ([]task.Info, error) {
+	msg, err := c.Request(ctx, "cancel_task", map[string]interface{}{"task_id": taskID}, TypeTasks)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []task.Info
+	if err := msg.Decode(&tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// AddNote attaches an analyst note to note.NodeID, or to the edge named by
+// note.SourceID, TargetID, EdgeType and Commodity, and returns it with its ID
+func (c *Client) AddNote(ctx context.Context, note graph.Note) (graph.Note, error) {
+	payload := map[string]interface{}{"author": note.Author, "text": note.Text}
+	if note.NodeID != "" {
+		payload["node_id"] = note.NodeID
+	} else {
+		payload["source_id"] = note.SourceID
+		payload["target_id"] = note.TargetID
+		payload["edge_type"] = string(note.EdgeType)
+		if note.Commodity != "" {
+			payload["commodity"] = note.Commodity
+		}
+	}
+	if len(note.Tags) > 0 {
+		payload["tags"] = note.Tags
+	}
+	msg, err := c.Request(ctx, "add_note", payload, TypeNote)
+	if err != nil {
+		return graph.Note{}, err
+	}
+	var added graph.Note
+	if err := msg.Decode(&added); err != nil {
+		return graph.Note{}, err
+	}
+	return added, nil
+}
+
+// GetNotes fetches the notes on a node and its edges ("" = every note),
+// only those tagged tag unless it is empty, oldest first
+func (c *Client) GetNotes(ctx context.Context, nodeID, tag string) ([]graph.Note, error) {
+	payload := map[string]interface{}{}
+	if nodeID != "" {
+		payload["node_id"] = nodeID
+	}
+	if tag != "" {
+		payload["tag"] = tag
+	}
+	msg, err := c.Request(ctx, "get_notes", payload, TypeNotes)
+	if err != nil {
+		return nil, err
+	}
+	var notes []graph.Note
+	if err := msg.Decode(&notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// GetTimeline fetches one page of the timeline, newest first. Pass the
+// page's Next as req.Cursor for the following one.
+func (c *Client) GetTimeline(ctx context.Context, req server.TimelineRequest) (server.TimelinePayload, error) {
+	payload := map[string]interface{}{}
+	for key, v := range map[string]string{"since": req.Since, "until": req.Until, "node_id": req.NodeID, "cursor": req.Cursor} {
+		if v != "" {
+			payload[key] = v
+		}
+	}
+	if len(req.Kinds) > 0 {
+		payload["kinds"] = req.Kinds
+	}
+	if req.Limit > 0 {
+		payload["limit"] = req.Limit
+	}
+	var page server.TimelinePayload
+	msg, err := c.Request(ctx, "get_timeline", payload, TypeTimeline)
+	if err != nil {
+		return page, err
+	}
+	err = msg.Decode(&page)
+	return page, err
+}
+
+// DeleteNote removes a note and returns it
+func (c *Client) DeleteNote(ctx context.Context, noteID string) (graph.Note, error) {
+	msg, err := c.Request(ctx, "delete_note", map[string]interface{}{"note_id": noteID}, TypeNote)
+	if err != nil {
+		return graph.Note{}, err
+	}
+	var note graph.Note
+	if err := msg.Decode(&note); err != nil {
+		return graph.Note{}, err
+	}
+	return note, nil
+}
+
+// GetSession fetches the state saved under the client's API token. Dial with
+// ?token= (or an Authorization header on a custom dialer) to use sessions.
+func (c *Client) GetSession(ctx context.Context) (*server.Session, error) {
+	msg, err := c.Request(ctx, "get_session", nil, TypeSession)
+	if err != nil {
+		return nil, err
+	}
+	var session server.Session
+	if err := msg.Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// UpdateSession saves the fields set in update and returns the whole session
+func (c *Client) UpdateSession(ctx context.Context, update server.SessionUpdate) (*server.Session, error) {
+	payload := make(map[string]interface{})
+	if update.WatchedNodes != nil {
+		payload["watched_nodes"] = *update.WatchedNodes
+	}
+	if update.Topics != nil {
+		payload["topics"] = *update.Topics
+	}
+	if update.Layout != nil {
+		payload["layout"] = update.Layout
+	}
+	msg, err := c.Request(ctx, "update_session", payload, TypeSession)
+	if err != nil {
+		return nil, err
+	}
+	var session server.Session
+	if err := msg.Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Watch starts the watch_event stream for a node (subscribe to TypeWatchEvent)
+// and returns every node this connection watches. Watches last until Unwatch
+// or the connection drops; a reconnect starts with none.
+func (c *Client) Watch(ctx context.Context, nodeID string) ([]string, error) {
+	return c.watchRequest(ctx, "watch", map[string]interface{}{"node_id": nodeID})
+}
+
+// Unwatch stops the watch_event stream for a node ("" = all) and returns the
+// nodes still watched
+func (c *Client) Unwatch(ctx context.Context, nodeID string) ([]string, error) {
+	var payload map[string]interface{}
+	if nodeID != "" {
+		payload = map[string]interface{}{"node_id": nodeID}
+	}
+	return c.watchRequest(ctx, "unwatch", payload)
+}
+
+func (c *Client) watchRequest(ctx context.Context, msgType string, payload map[string]interface{}) ([]string, error) {
+	msg, err := c.Request(ctx, msgType, payload, TypeWatching)
+	if err != nil {
+		return nil, err
+	}
+	var watching server.WatchingPayload
+	if err := msg.Decode(&watching); err != nil {
+		return nil, err
+	}
+	return watching.NodeIDs, nil
+}
+
+func (c *Client) send(req request) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(req)
+}
+
+func (c *Client) readLoop() {
+	backoff := c.opts.MinBackoff
+
+	for {
+		var msg Message
+		err := c.conn.ReadJSON(&msg)
+		if err == nil {
+			backoff = c.opts.MinBackoff
+			c.dispatch(msg)
+			continue
+		}
+
+		// Connection lost: fail in-flight requests
+		c.failPending()
+
+		if c.isClosed() || !c.opts.Reconnect {
+			c.shutdown()
+			return
+		}
+
+		for {
+			select {
+			case <-c.done:
+				c.shutdown()
+				return
+			case <-time.After(backoff):
+			}
+
+			conn, _, err := websocket.DefaultDialer.Dial(c.url, nil)
+			if err == nil {
+				c.writeMu.Lock()
+				c.conn = conn
+				c.writeMu.Unlock()
+				break
+			}
+
+			backoff *= 2
+			if backoff > c.opts.MaxBackoff {
+				backoff = c.opts.MaxBackoff
+			}
+		}
+	}
+}
+
+// dispatch routes a response to its pending request, then hands every
+// message to subscribers
+func (c *Client) dispatch(msg Message) {
+	var chunk func(Message)
+	c.mu.Lock()
+	for i, p := range c.pending {
+		if msg.Type == TypeAskChunk {
+			if msg.ID == p.id {
+				chunk = p.chunk
+				break
+			}
+			continue
+		}
+		var matched bool
+		if msg.ID != "" {
+			matched = msg.ID == p.id
+		} else {
+			matched = msg.Type == TypeError || p.match(msg)
+		}
+		if matched {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			p.reply <- msg
+			break
+		}
+	}
+
+	targets := append([]chan Message{}, c.subs[msg.Type]...)
+	targets = append(targets, c.subs[""]...)
+	c.mu.Unlock()
+
+	if chunk != nil {
+		chunk(msg)
+	}
+
+	for _, ch := range targets {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+func (c *Client) dropPending(target *pendingRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, p := range c.pending {
+		if p == target {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *Client) failPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.pending {
+		close(p.reply)
+	}
+	c.pending = nil
+}
+
+func (c *Client) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// shutdown closes all subscriber channels once the read loop exits
+func (c *Client) shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+	for _, list := range c.subs {
+		for _, ch := range list {
+			close(ch)
+		}
+	}
+	c.subs = make(map[string][]chan Message)
+}
+
+// GetSystemErrors fetches recent subsystem failures, newest first
+func (c *Client) GetSystemErrors(ctx context.Context) ([]syserr.Event, error) {
+	msg, err := c.Request(ctx, "get_system_errors", nil, TypeSystemErrors)
+	if err != nil {
+		return nil, err
+	}
+	var events []syserr.Event
+	if err := msg.Decode(&events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
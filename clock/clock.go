@@ -0,0 +1,62 @@
+// Package clock keeps Margraf's time handling in one place. Times are
+// recorded, compared and saved in UTC. The display timezone only applies when
+// a time is shown (console, logs, digests) and to wall-clock settings such as
+// news quiet hours and job schedules.
+package clock
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	mu      sync.RWMutex
+	display = time.Local
+)
+
+// SetDisplay sets the display timezone: an IANA name such as
+// "Europe/London", "UTC", or "Local" for the machine's zone. Empty keeps the
+// current one.
+func SetDisplay(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	var loc *time.Location
+	if strings.EqualFold(name, "local") {
+		loc = time.Local
+	} else {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return fmt.Errorf("timezone %q: %w", name, err)
+		}
+	}
+	mu.Lock()
+	display = loc
+	mu.Unlock()
+	return nil
+}
+
+// Display returns the display timezone
+func Display() *time.Location {
+	mu.RLock()
+	defer mu.RUnlock()
+	return display
+}
+
+// Now returns the current time in UTC
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Local returns t in the display timezone
+func Local(t time.Time) time.Time {
+	return t.In(Display())
+}
+
+// Format formats t in the display timezone
+func Format(t time.Time, layout string) string {
+	return Local(t).Format(layout)
+}
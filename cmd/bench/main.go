@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"margraf/bench"
+	"margraf/logger"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Performance harness for the core graph operations in package bench. Each
+// benchmark runs on synthetic supply chains of several sizes; results can be
+// saved as a baseline and later runs compared against it:
+//
+//	go run ./cmd/bench -save cmd/bench/baseline.json
+//	go run ./cmd/bench -compare cmd/bench/baseline.json
+//
+// A run exits non-zero when any benchmark is slower than the baseline by more
+// than -threshold, or when the baseline to compare with is missing. The same
+// benchmarks run under go test -bench as BenchmarkXxx in graph and simulation.
+
+// Result is one benchmark's measurement
+type Result struct {
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	Iterations  int     `json:"iterations"`
+}
+
+func main() {
+	sizes := flag.String("sizes", "1k,10k,100k", "Comma-separated edge counts of the synthetic graphs")
+	filter := flag.String("run", "", "Only run benchmarks whose name matches this regexp")
+	save := flag.String("save", "", "Write the results to this baseline file")
+	compare := flag.String("compare", "", "Compare the results with this baseline file")
+	threshold := flag.Float64("threshold", 0.20, "Slowdown (fraction of ns/op) that counts as a regression")
+	flag.Parse()
+
+	logger.Init("error", false)
+	logger.SetOutput(io.Discard) // Shock propagation logs every step
+
+	edgeCounts, err := parseSizes(*sizes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	match, err := regexp.Compile(*filter)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var baseline map[string]Result
+	if *compare != "" {
+		baseline, err = loadBaseline(*compare)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "No baseline at %s; record one with -save (make bench-baseline)\n", *compare)
+			os.Exit(2)
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	results := make(map[string]Result)
+	regressions := 0
+	for _, bm := range bench.All {
+		for _, edges := range edgeCounts {
+			name := fmt.Sprintf("%s/%s", bm.Name, bench.SizeLabel(edges))
+			if !match.MatchString(name) {
+				continue
+			}
+			r := testing.Benchmark(func(b *testing.B) { bm.Run(b, edges) })
+			res := Result{
+				NsPerOp:     float64(r.T.Nanoseconds()) / float64(max(r.N, 1)),
+				BytesPerOp:  r.AllocedBytesPerOp(),
+				AllocsPerOp: r.AllocsPerOp(),
+				Iterations:  r.N,
+			}
+			results[name] = res
+
+			line := fmt.Sprintf("%-28s %10d %14.0f ns/op %12d B/op %9d allocs/op", name, res.Iterations, res.NsPerOp, res.BytesPerOp, res.AllocsPerOp)
+			if base, ok := baseline[name]; ok && base.NsPerOp > 0 {
+				change := res.NsPerOp/base.NsPerOp - 1
+				line += fmt.Sprintf("  %+6.1f%%", change*100)
+				if change > *threshold {
+					line += "  REGRESSION"
+					regressions++
+				}
+			}
+			fmt.Println(line)
+		}
+	}
+
+	if *save != "" {
+		if err := saveBaseline(*save, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Printf("Baseline saved to %s (%d benchmarks)\n", *save, len(results))
+	}
+	if regressions > 0 {
+		fmt.Printf("%d benchmarks regressed by more than %.0f%%\n", regressions, *threshold*100)
+		os.Exit(1)
+	}
+}
+
+// parseSizes reads edge counts such as "1k,10k,100k" or "5000"
+func parseSizes(s string) ([]int, error) {
+	var sizes []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		mult := 1
+		if strings.HasSuffix(part, "k") {
+			mult, part = 1000, strings.TrimSuffix(part, "k")
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid size %q", part)
+		}
+		sizes = append(sizes, n*mult)
+	}
+	return sizes, nil
+}
+
+func loadBaseline(path string) (map[string]Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var baseline map[string]Result
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return baseline, nil
+}
+
+func saveBaseline(path string, results map[string]Result) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// names lists the benchmarks, for the usage message
+func names() string {
+	list := make([]string, len(bench.All))
+	for i, bm := range bench.All {
+		list[i] = bm.Name
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: bench [flags]\n\nBenchmarks: %s\n\n", names())
+		flag.PrintDefaults()
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"margraf/discovery"
+	"margraf/graph"
+	"margraf/llm"
+	"margraf/logger"
+	"margraf/news"
+	"margraf/pipeline"
+	"margraf/scraper"
+	"margraf/server"
+	"margraf/simulation"
+	"margraf/social"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// End-to-end scenario runner. Each scenario loads a fixture graph, replays
+// news headlines and social posts through the real news engine and social
+// monitor, with the RSS feed and the LLM served by in-process fakes, and
+// checks the resulting node health and edge weights:
+//
+//	go run ./cmd/e2e                       # every scenario in cmd/e2e/scenarios
+//	go run ./cmd/e2e -v cmd/e2e/scenarios/tsmc_fire.json
+//
+// It exits non-zero if any expectation fails.
+
+// Scenario is one fixture file
+type Scenario struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Graph       string   `json:"graph"` // "starter", "synthetic:N,I,C[,density[,seed]]" or a graph JSON file
+	Events      []Event  `json:"events"`
+	Expect      []Expect `json:"expect"`
+}
+
+// Event is a headline or a batch of social posts, replayed in file order
+type Event struct {
+	News *NewsEvent `json:"news,omitempty"`
+	Post *PostEvent `json:"social,omitempty"`
+}
+
+// NewsEvent is a headline served on the fake feed, with the analysis the
+// fake LLM returns for it
+type NewsEvent struct {
+	Title    string          `json:"title"`
+	Analysis json.RawMessage `json:"analysis"` // The reply nlp.Extractor.Headline asks for
+}
+
+// PostEvent is a set of posts about a topic
+type PostEvent struct {
+	Topic string `json:"topic"`
+	Posts []Post `json:"posts"`
+}
+
+// Post is one social post and the sentiment the fake LLM gives it
+type Post struct {
+	Platform  string  `json:"platform"`
+	User      string  `json:"user"`
+	Content   string  `json:"content"`
+	Sentiment float64 `json:"sentiment"`
+}
+
+// Expect is a check on a node's health or an edge's weight after all events.
+// Change compares with the value before the events: "down", "up" or "same".
+type Expect struct {
+	Node   string   `json:"node,omitempty"`
+	Edge   *EdgeRef `json:"edge,omitempty"`
+	Change string   `json:"change,omitempty"`
+	Below  *float64 `json:"below,omitempty"`
+	Above  *float64 `json:"above,omitempty"`
+	Status string   `json:"status,omitempty"` // Edges only
+}
+
+// EdgeRef names an edge
+type EdgeRef struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+func (e Expect) String() string {
+	if e.Edge != nil {
+		return fmt.Sprintf("edge %s -[%s]-> %s", e.Edge.Source, e.Edge.Type, e.Edge.Target)
+	}
+	return "node " + e.Node
+}
+
+func main() {
+	verbose := flag.Bool("v", false, "Show the engines' log output")
+	flag.Parse()
+
+	level := "error"
+	if *verbose {
+		level = "info"
+	}
+	logger.Init(level, false)
+	if !*verbose {
+		logger.SetOutput(io.Discard)
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		found, err := filepath.Glob(filepath.Join("cmd", "e2e", "scenarios", "*.json"))
+		if err != nil || len(found) == 0 {
+			fmt.Fprintln(os.Stderr, "no scenarios given or found in cmd/e2e/scenarios")
+			os.Exit(2)
+		}
+		files = found
+	}
+	sort.Strings(files)
+
+	// News-triggered social crawls would go to the network; scenarios
+	// replay their social posts explicitly
+	pipeline.Set(pipeline.Social, false)
+
+	failed := 0
+	for _, file := range files {
+		failures, err := runFile(file)
+		switch {
+		case err != nil:
+			fmt.Printf("ERROR %s: %v\n", file, err)
+			failed++
+		case len(failures) > 0:
+			fmt.Printf("FAIL  %s\n", file)
+			for _, f := range failures {
+				fmt.Printf("      %s\n", f)
+			}
+			failed++
+		default:
+			fmt.Printf("ok    %s\n", file)
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d scenarios failed\n", failed, len(files))
+		os.Exit(1)
+	}
+}
+
+// runFile runs one scenario and returns its failed expectations
+func runFile(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var sc Scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	g, err := loadGraph(sc.Graph, filepath.Dir(file))
+	if err != nil {
+		return nil, fmt.Errorf("graph %q: %w", sc.Graph, err)
+	}
+	g.DisableAutoSave()
+
+	before := make([]float64, len(sc.Expect))
+	for i, exp := range sc.Expect {
+		v, _, err := observe(g, exp)
+		if err != nil {
+			return nil, err
+		}
+		before[i] = v
+	}
+
+	// Fake LLM: answers each prompt with the reply registered for the
+	// headline or post it quotes
+	model := newFakeLLM()
+	for _, ev := range sc.Events {
+		if ev.News != nil {
+			model.reply(ev.News.Title, string(ev.News.Analysis))
+		}
+		if ev.Post != nil {
+			for _, p := range ev.Post.Posts {
+				model.reply(p.Content, fmt.Sprintf(`{"sentiment": %g}`, p.Sentiment))
+			}
+		}
+	}
+	llmServer := httptest.NewServer(model)
+	defer llmServer.Close()
+
+	// Fake feed: serves the headline being replayed
+	feed := &fakeFeed{}
+	feedServer := httptest.NewServer(feed)
+	defer feedServer.Close()
+
+	client := llm.NewEndpointClient(llmServer.URL, "e2e", "fake")
+	hub := server.NewHub()
+	go hub.Run()
+	sim := simulation.NewSimulator(g)
+	monitor := social.NewMonitor(client, hub, g)
+	engine := news.NewEngine(g, client, discovery.NewSeeder(client), sim, hub, monitor)
+	engine.Sources = []news.FeedSource{news.NewRSSSource(feedServer.URL)}
+
+	ctx := context.Background()
+	for _, ev := range sc.Events {
+		if ev.News != nil {
+			feed.set(ev.News.Title)
+			engine.LastCheck = time.Time{}
+			engine.FetchAndProcess(ctx)
+		}
+		if ev.Post != nil {
+			posts := make([]scraper.SocialPost, len(ev.Post.Posts))
+			for i, p := range ev.Post.Posts {
+				posts[i] = scraper.SocialPost{Platform: p.Platform, User: p.User, Content: p.Content, Time: time.Now()}
+			}
+			monitor.Analyze(ctx, ev.Post.Topic, posts)
+		}
+	}
+
+	var failures []string
+	for _, prompt := range model.unmatched() {
+		failures = append(failures, "LLM prompt with no fixture reply: "+prompt)
+	}
+	for i, exp := range sc.Expect {
+		after, status, err := observe(g, exp)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		if msg := check(exp, before[i], after, status); msg != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", exp, msg))
+		}
+	}
+	return failures, nil
+}
+
+// loadGraph builds the scenario's starting graph
+func loadGraph(source, dir string) (*graph.Graph, error) {
+	switch {
+	case source == "starter":
+		bundle, err := discovery.LoadStarterBundle(context.Background(), "")
+		if err != nil {
+			return nil, err
+		}
+		g := graph.NewGraph()
+		g.DisableAutoSave()
+		discovery.ApplyStarter(g, bundle)
+		return g, nil
+	case strings.HasPrefix(source, "synthetic:"):
+		parts := strings.Split(strings.TrimPrefix(source, "synthetic:"), ",")
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("want synthetic:nations,industries,companies[,density[,seed]]")
+		}
+		var n [3]int
+		for i := range n {
+			v, err := strconv.Atoi(strings.TrimSpace(parts[i]))
+			if err != nil {
+				return nil, err
+			}
+			n[i] = v
+		}
+		density, seed := 0.05, int64(1)
+		if len(parts) > 3 {
+			density, _ = strconv.ParseFloat(strings.TrimSpace(parts[3]), 64)
+		}
+		if len(parts) > 4 {
+			seed, _ = strconv.ParseInt(strings.TrimSpace(parts[4]), 10, 64)
+		}
+		return graph.GenerateSynthetic(n[0], n[1], n[2], density, seed), nil
+	default:
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(dir, source)
+		}
+		return graph.Load(source)
+	}
+}
+
+// observe reads the value an expectation checks: node health or edge weight
+func observe(g *graph.Graph, exp Expect) (float64, string, error) {
+	if exp.Edge != nil {
+		for _, e := range g.GetOutgoingEdges(exp.Edge.Source) {
+			if e.TargetID == exp.Edge.Target && string(e.Type) == exp.Edge.Type {
+				return e.Weight, string(e.Status), nil
+			}
+		}
+		return 0, "", fmt.Errorf("%s not in graph", exp)
+	}
+	n, ok := g.GetNode(exp.Node)
+	if !ok {
+		return 0, "", fmt.Errorf("%s not in graph", exp)
+	}
+	return n.Health, "", nil
+}
+
+// check returns why an expectation failed, or ""
+func check(exp Expect, before, after float64, status string) string {
+	const epsilon = 1e-9
+	switch exp.Change {
+	case "down":
+		if after >= before-epsilon {
+			return fmt.Sprintf("expected a decrease, got %.4f -> %.4f", before, after)
+		}
+	case "up":
+		if after <= before+epsilon {
+			return fmt.Sprintf("expected an increase, got %.4f -> %.4f", before, after)
+		}
+	case "same":
+		if after-before > epsilon || before-after > epsilon {
+			return fmt.Sprintf("expected no change, got %.4f -> %.4f", before, after)
+		}
+	case "":
+	default:
+		return fmt.Sprintf("unknown change %q (want down, up or same)", exp.Change)
+	}
+	if exp.Below != nil && after >= *exp.Below {
+		return fmt.Sprintf("expected below %.4f, got %.4f", *exp.Below, after)
+	}
+	if exp.Above != nil && after <= *exp.Above {
+		return fmt.Sprintf("expected above %.4f, got %.4f", *exp.Above, after)
+	}
+	if exp.Status != "" && status != exp.Status {
+		return fmt.Sprintf("expected status %q, got %q", exp.Status, status)
+	}
+	return ""
+}
+
+// fakeLLM is an OpenAI-compatible chat endpoint answering from fixtures
+type fakeLLM struct {
+	mu      sync.Mutex
+	replies map[string]string // Quoted text -> reply
+	misses  []string
+}
+
+func newFakeLLM() *fakeLLM {
+	return &fakeLLM{replies: make(map[string]string)}
+}
+
+func (f *fakeLLM) reply(quote, reply string) {
+	f.replies[quote] = reply
+}
+
+func (f *fakeLLM) unmatched() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.misses
+}
+
+func (f *fakeLLM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req llm.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	prompt := req.Messages[len(req.Messages)-1].Content
+
+	// The longest quote wins, so a headline containing a shorter one still matches itself
+	best := ""
+	for quote := range f.replies {
+		if strings.Contains(prompt, quote) && len(quote) > len(best) {
+			best = quote
+		}
+	}
+	if best == "" {
+		f.mu.Lock()
+		f.misses = append(f.misses, firstLine(prompt))
+		f.mu.Unlock()
+		http.Error(w, "no fixture reply", http.StatusNotFound)
+		return
+	}
+
+	var resp llm.ChatResponse
+	resp.Choices = make([]struct {
+		Message llm.ChatMessage `json:"message"`
+	}, 1)
+	resp.Choices[0].Message = llm.ChatMessage{Role: "assistant", Content: f.replies[best]}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// fakeFeed serves an RSS feed holding the current headline
+type fakeFeed struct {
+	mu    sync.Mutex
+	title string
+}
+
+func (f *fakeFeed) set(title string) {
+	f.mu.Lock()
+	f.title = title
+	f.mu.Unlock()
+}
+
+func (f *fakeFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	item := news.RSSItem{Title: f.title, PubDate: time.Now().UTC().Format(time.RFC1123)}
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/rss+xml")
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(news.RSSFeed{Channel: news.RSSChannel{Items: []news.RSSItem{item}}})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"margraf/graph"
+	"margraf/replay"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Writes the offline trading demo: a small graph of semiconductor and oil
+// companies and, for each ticker, a Yahoo daily-history fixture that
+// cmd/trading -offline replays. The prices are a seeded random walk around a
+// shared sector factor, not market data, so the demo runs without network
+// and gives the same result every time:
+//
+//	go run ./cmd/fixtures
+//	go run ./cmd/trading -offline -graph testdata/trading_graph.json -mode backtest
+//
+// Fixtures recorded from the live API with -record sit alongside these.
+
+// company is one ticker of the demo graph
+type company struct {
+	id, name, ticker, sector string
+	price                    float64 // Starting close
+}
+
+var companies = []company{
+	{"nvidia", "NVIDIA", "NVDA", "semiconductors", 110},
+	{"amd", "AMD", "AMD", "semiconductors", 150},
+	{"intel", "Intel", "INTC", "semiconductors", 30},
+	{"qualcomm", "Qualcomm", "QCOM", "semiconductors", 160},
+	{"texas_instruments", "Texas Instruments", "TXN", "semiconductors", 190},
+	{"micron", "Micron", "MU", "semiconductors", 95},
+	{"applied_materials", "Applied Materials", "AMAT", "semiconductors", 170},
+	{"lam_research", "Lam Research", "LRCX", "semiconductors", 80},
+	{"exxonmobil", "ExxonMobil", "XOM", "oil_gas", 110},
+	{"chevron", "Chevron", "CVX", "oil_gas", 150},
+	{"conocophillips", "ConocoPhillips", "COP", "oil_gas", 100},
+}
+
+// supplies are the demo's supply links, which feed the correlation prior
+var supplies = [][2]string{
+	{"applied_materials", "intel"},
+	{"lam_research", "intel"},
+	{"applied_materials", "micron"},
+	{"lam_research", "micron"},
+	{"micron", "nvidia"},
+	{"micron", "amd"},
+}
+
+func main() {
+	dir := flag.String("fixtures", replay.DefaultDir, "Fixture directory to write")
+	graphFile := flag.String("graph", "testdata/trading_graph.json", "Demo graph file to write")
+	end := flag.String("end", "2026-06-30", "Last day of the price histories (YYYY-MM-DD)")
+	days := flag.Int("days", 365, "Calendar days of history per ticker")
+	flag.Parse()
+
+	last, err := time.Parse("2006-01-02", *end)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -end: %v\n", err)
+		os.Exit(2)
+	}
+	if err := writeGraph(*graphFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := writeFixtures(*dir, last, *days); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Wrote %s and %d fixtures to %s\n", *graphFile, len(companies), *dir)
+}
+
+func writeGraph(path string) error {
+	g := graph.NewGraph()
+	g.DisableAutoSave()
+	industries := map[string]string{"semiconductors": "Semiconductors", "oil_gas": "Oil & Gas"}
+	for id, name := range industries {
+		g.AddNode(&graph.Node{ID: id, Name: name, Type: graph.NodeTypeIndustry, Health: 1})
+	}
+	for _, c := range companies {
+		g.AddNode(&graph.Node{ID: c.id, Name: c.name, Type: graph.NodeTypeCorporation, Ticker: c.ticker, Currency: "USD", Price: c.price, Health: 1})
+		g.AddEdge(&graph.Edge{SourceID: c.sector, TargetID: c.id, Type: graph.EdgeTypeHasCompany, Weight: 1})
+	}
+	for _, s := range supplies {
+		g.AddEdge(&graph.Edge{SourceID: s[0], TargetID: s[1], Type: graph.EdgeTypeSupplies, Weight: 0.7})
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return g.Save(path)
+}
+
+// writeFixtures writes one CSV history per ticker in the format of Yahoo's
+// download endpoint. Each daily return is 70% the sector's and 30% the
+// company's own, so same-sector pairs correlate strongly and drift apart at
+// times for the strategy to trade.
+func writeFixtures(dir string, last time.Time, days int) error {
+	rng := rand.New(rand.NewSource(7))
+	var dates []time.Time
+	for d := last.AddDate(0, 0, -days); !d.After(last); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			dates = append(dates, d)
+		}
+	}
+	sectorReturns := make(map[string][]float64)
+	for _, c := range companies {
+		if _, ok := sectorReturns[c.sector]; !ok {
+			r := make([]float64, len(dates))
+			for i := range r {
+				r[i] = 0.0003 + 0.015*rng.NormFloat64()
+			}
+			sectorReturns[c.sector] = r
+		}
+	}
+
+	for _, c := range companies {
+		var csv strings.Builder
+		csv.WriteString("Date,Open,High,Low,Close,Adj Close,Volume\n")
+		price := c.price
+		for i, d := range dates {
+			open := price
+			price *= math.Exp(0.7*sectorReturns[c.sector][i] + 0.3*0.02*rng.NormFloat64())
+			high, low := math.Max(open, price)*1.005, math.Min(open, price)*0.995
+			fmt.Fprintf(&csv, "%s,%.2f,%.2f,%.2f,%.2f,%.2f,%d\n", d.Format("2006-01-02"), open, high, low, price, price, 1000000+rng.Intn(9000000))
+		}
+		err := replay.WriteFixture(dir, replay.Fixture{
+			Method:      "GET",
+			URL:         fmt.Sprintf("https://query2.finance.yahoo.com/v7/finance/download/%s?interval=1d&events=history&includeAdjustedClose=true", c.ticker),
+			Status:      200,
+			ContentType: "text/csv",
+			Body:        csv.String(),
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.ticker, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/server"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Load test for the WebSocket hub. It starts an in-process hub, connects N
+// synthetic clients with a mix of topic filters, replays recorded broadcast
+// traffic through it, and reports how long broadcasts took to reach clients
+// and how many were dropped on full queues:
+//
+//	go run ./cmd/loadtest                                 # 500 clients, cmd/loadtest/traffic.jsonl
+//	go run ./cmd/loadtest -clients 2000 -slow 0.1 -speed 0
+//	go run ./cmd/loadtest -record live.jsonl -url ws://localhost:8080/ws -duration 10m
+//
+// -record captures a running instance's broadcasts as a traffic file. A run
+// exits non-zero when the drop rate or the p99 latency passes -max-drop or
+// -max-p99.
+
+// Frame is one recorded broadcast, a line of a traffic file
+type Frame struct {
+	At      int64           `json:"at_ms"` // Since the recording started
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// seqField tags replayed object payloads with their sequence number, so
+// clients can tell when each was sent
+const seqField = "loadtest_seq"
+
+func main() {
+	traffic := flag.String("traffic", "cmd/loadtest/traffic.jsonl", "Traffic file to replay (JSON Lines of {at_ms, type, payload})")
+	clients := flag.Int("clients", 500, "WebSocket clients to connect")
+	topics := flag.String("topics", ";market_update;shock_event,shock_trace,news_alert;social_pulse,graph_notice", "Topic filters assigned to clients in turn, separated by \";\" (empty = all types)")
+	speed := flag.Float64("speed", 5, "Replay this many times faster than recorded (0 = as fast as the hub takes them)")
+	loops := flag.Int("loops", 1, "Times to replay the traffic")
+	slow := flag.Float64("slow", 0, "Share of clients that read slowly")
+	slowDelay := flag.Duration("slow-delay", 50*time.Millisecond, "Pause after each message read by a slow client")
+	drain := flag.Duration("drain", 2*time.Second, "How long to wait for queued messages after the replay")
+	maxDrop := flag.Float64("max-drop", 1, "Fail when more than this share of expected deliveries is dropped")
+	maxP99 := flag.Duration("max-p99", 0, "Fail when the p99 delivery latency is above this (0 = no limit)")
+	asJSON := flag.Bool("json", false, "Print the report as JSON")
+	record := flag.String("record", "", "Record broadcasts from -url into this traffic file instead of replaying")
+	url := flag.String("url", "ws://localhost:8080/ws", "Stream to record")
+	duration := flag.Duration("duration", 5*time.Minute, "How long to record")
+	flag.Parse()
+
+	logger.Init("error", false)
+	logger.SetOutput(io.Discard) // Full queues log every drop
+
+	if *record != "" {
+		n, err := recordTraffic(*url, *record, *duration)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Printf("Recorded %d broadcasts to %s\n", n, *record)
+		return
+	}
+
+	frames, err := loadTraffic(*traffic)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if len(frames) == 0 {
+		fmt.Fprintf(os.Stderr, "%s holds no broadcasts\n", *traffic)
+		os.Exit(2)
+	}
+
+	rep, err := run(config{
+		frames:    frames,
+		clients:   *clients,
+		filters:   strings.Split(*topics, ";"),
+		speed:     *speed,
+		loops:     max(*loops, 1),
+		slow:      *slow,
+		slowDelay: *slowDelay,
+		drain:     *drain,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(rep)
+	} else {
+		rep.print()
+	}
+
+	failed := false
+	if rep.DropRate > *maxDrop {
+		fmt.Printf("Drop rate %.2f%% is above -max-drop %.2f%%\n", rep.DropRate*100, *maxDrop*100)
+		failed = true
+	}
+	if *maxP99 > 0 && rep.Latency.P99 > *maxP99 {
+		fmt.Printf("p99 latency %v is above -max-p99 %v\n", rep.Latency.P99, *maxP99)
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// loadTraffic reads a traffic file, oldest broadcast first
+func loadTraffic(path string) ([]Frame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var frames []Frame
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var fr Frame
+		if err := json.Unmarshal(scanner.Bytes(), &fr); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		if fr.Type == "" {
+			return nil, fmt.Errorf("%s:%d: no type", path, line)
+		}
+		frames = append(frames, fr)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(frames, func(i, j int) bool { return frames[i].At < frames[j].At })
+	return frames, nil
+}
+
+// recordTraffic writes the broadcasts a stream sends within d to path.
+// Interrupting the recording keeps what was captured.
+func recordTraffic(url, path string, d time.Duration) (int, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("connecting to %s: %w", url, err)
+	}
+	defer conn.Close()
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	defer w.Flush()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	go func() {
+		select {
+		case <-stop:
+		case <-time.After(d):
+		}
+		conn.Close()
+	}()
+
+	start := time.Now()
+	n := 0
+	for {
+		var msg server.BroadcastMessage
+		var raw struct {
+			Payload json.RawMessage `json:"payload"`
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return n, nil // Closed at the end of the recording
+		}
+		if json.Unmarshal(data, &msg) != nil || json.Unmarshal(data, &raw) != nil {
+			continue
+		}
+		// Replies and per-connection messages aren't broadcast traffic
+		if msg.ID != "" || msg.Type == server.TypeSystem || msg.Type == server.TypeSession {
+			continue
+		}
+		line, err := json.Marshal(Frame{At: time.Since(start).Milliseconds(), Type: msg.Type, Payload: raw.Payload})
+		if err != nil {
+			continue
+		}
+		w.Write(append(line, '\n'))
+		n++
+	}
+}
+
+// config is one load test run
+type config struct {
+	frames    []Frame
+	clients   int
+	filters   []string // Topic filters assigned in turn ("" = all)
+	speed     float64
+	loops     int
+	slow      float64
+	slowDelay time.Duration
+	drain     time.Duration
+}
+
+// client is one synthetic connection and what it received
+type client struct {
+	topics   map[string]bool // nil = all
+	slow     bool
+	received atomic.Int64
+	expected int64
+	latency  []time.Duration // Of tagged broadcasts received
+	byType   map[string]*typeStats
+}
+
+// wants reports whether the client's filter accepts msgType
+func (c *client) wants(msgType string) bool {
+	return c.topics == nil || c.topics[msgType]
+}
+
+// typeStats counts one broadcast type's deliveries
+type typeStats struct {
+	expected, received int64
+	latency            []time.Duration
+}
+
+// run connects the clients, replays the traffic and collects the report
+func run(cfg config) (*Report, error) {
+	g := graph.NewGraph()
+	g.DisableAutoSave()
+	hub := server.NewHub()
+	hub.SetGraph(g)
+	go hub.Run()
+	srv := httptest.NewServer(httpHandler(hub))
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	// Payloads are decoded up front, so the replay measures the hub
+	type prepared struct {
+		msgType string
+		object  map[string]interface{} // Tagged with seqField when sent
+		raw     json.RawMessage        // Non-object payloads, sent untagged
+	}
+	prep := make([]prepared, len(cfg.frames))
+	for i, fr := range cfg.frames {
+		prep[i].msgType = fr.Type
+		if json.Unmarshal(fr.Payload, &prep[i].object) != nil || prep[i].object == nil {
+			prep[i].raw = fr.Payload
+		}
+	}
+	total := len(prep) * cfg.loops
+	sent := make([]int64, total) // Unix nanoseconds each broadcast was handed to the hub
+
+	clients := make([]*client, cfg.clients)
+	conns := make([]*websocket.Conn, 0, cfg.clients)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	var wg sync.WaitGroup
+	connectStart := time.Now()
+	for i := range clients {
+		c := &client{byType: make(map[string]*typeStats)}
+		filter := strings.TrimSpace(cfg.filters[i%len(cfg.filters)])
+		url := wsURL
+		if filter != "" {
+			c.topics = make(map[string]bool)
+			for _, t := range strings.Split(filter, ",") {
+				c.topics[strings.TrimSpace(t)] = true
+			}
+			url += "?topics=" + filter
+		}
+		c.slow = float64(i) < cfg.slow*float64(cfg.clients)
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("connecting client %d: %w", i+1, err)
+		}
+		conns = append(conns, conn)
+		clients[i] = c
+		for _, p := range prep {
+			if c.wants(p.msgType) {
+				c.expected += int64(cfg.loops)
+				st := c.stats(p.msgType)
+				st.expected += int64(cfg.loops)
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.read(conn, sent, cfg.slowDelay)
+		}()
+	}
+	connectTime := time.Since(connectStart)
+
+	// Let every writeLoop start before the first broadcast
+	time.Sleep(100 * time.Millisecond)
+
+	replayStart := time.Now()
+	seq := 0
+	for loop := 0; loop < cfg.loops; loop++ {
+		loopStart := time.Now()
+		for i, p := range prep {
+			if cfg.speed > 0 {
+				due := loopStart.Add(time.Duration(float64(cfg.frames[i].At) * float64(time.Millisecond) / cfg.speed))
+				time.Sleep(time.Until(due))
+			}
+			var payload interface{} = p.raw
+			if p.object != nil {
+				tagged := make(map[string]interface{}, len(p.object)+1)
+				for k, v := range p.object {
+					tagged[k] = v
+				}
+				tagged[seqField] = seq
+				payload = tagged
+			}
+			atomic.StoreInt64(&sent[seq], time.Now().UnixNano())
+			hub.Broadcast(p.msgType, payload)
+			seq++
+		}
+	}
+	replayTime := time.Since(replayStart)
+
+	// Wait for the queues to empty, or for deliveries to stop arriving
+	deadline := time.Now().Add(cfg.drain)
+	for time.Now().Before(deadline) && !allReceived(clients) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+	wg.Wait()
+
+	return newReport(cfg, clients, total, connectTime, replayTime), nil
+}
+
+// stats returns the client's counters for msgType
+func (c *client) stats(msgType string) *typeStats {
+	st, ok := c.byType[msgType]
+	if !ok {
+		st = &typeStats{}
+		c.byType[msgType] = st
+	}
+	return st
+}
+
+// read counts the broadcasts arriving on conn until it closes. The latency
+// of a tagged broadcast runs from when it was handed to the hub.
+func (c *client) read(conn *websocket.Conn, sent []int64, slowDelay time.Duration) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		now := time.Now().UnixNano()
+		var msg struct {
+			Type    string `json:"type"`
+			Payload struct {
+				Seq *int `json:"loadtest_seq"`
+			} `json:"payload"`
+		}
+		if json.Unmarshal(data, &msg) != nil || msg.Type == server.TypeSystem {
+			continue
+		}
+		c.received.Add(1)
+		st := c.stats(msg.Type)
+		st.received++
+		if s := msg.Payload.Seq; s != nil && *s >= 0 && *s < len(sent) {
+			d := time.Duration(now - atomic.LoadInt64(&sent[*s]))
+			c.latency = append(c.latency, d)
+			st.latency = append(st.latency, d)
+		}
+		if c.slow {
+			time.Sleep(slowDelay)
+		}
+	}
+}
+
+// allReceived reports whether every client got everything it expected
+func allReceived(clients []*client) bool {
+	for _, c := range clients {
+		if c.received.Load() < c.expected {
+			return false
+		}
+	}
+	return true
+}
+
+// httpHandler serves the hub's stream endpoint
+func httpHandler(hub *server.Hub) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", hub.HandleWebSocket)
+	return mux
+}
+
+// Percentiles summarizes delivery latencies
+type Percentiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+func percentiles(d []time.Duration) Percentiles {
+	if len(d) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	at := func(q float64) time.Duration {
+		return d[min(len(d)-1, int(q*float64(len(d))))]
+	}
+	return Percentiles{P50: at(0.50), P90: at(0.90), P99: at(0.99), Max: d[len(d)-1]}
+}
+
+// TypeReport is one broadcast type's share of the run
+type TypeReport struct {
+	Type      string      `json:"type"`
+	Expected  int64       `json:"expected"`
+	Delivered int64       `json:"delivered"`
+	DropRate  float64     `json:"drop_rate"`
+	Latency   Percentiles `json:"latency"`
+}
+
+// Report is the outcome of a run. Deliveries still queued when the drain
+// ends count as dropped.
+type Report struct {
+	Clients     int           `json:"clients"`
+	SlowClients int           `json:"slow_clients"`
+	Broadcasts  int           `json:"broadcasts"`
+	ConnectTime time.Duration `json:"connect_time"`
+	ReplayTime  time.Duration `json:"replay_time"`
+	Rate        float64       `json:"broadcasts_per_second"`
+	Expected    int64         `json:"expected"` // Deliveries the clients' filters accept
+	Delivered   int64         `json:"delivered"`
+	DropRate    float64       `json:"drop_rate"`
+	SlowDrop    float64       `json:"slow_drop_rate"` // Among slow clients only
+	FastDrop    float64       `json:"fast_drop_rate"`
+	Latency     Percentiles   `json:"latency"`
+	Types       []TypeReport  `json:"types"`
+}
+
+func dropRate(expected, delivered int64) float64 {
+	if expected == 0 {
+		return 0
+	}
+	return 1 - float64(min(delivered, expected))/float64(expected)
+}
+
+func newReport(cfg config, clients []*client, broadcasts int, connectTime, replayTime time.Duration) *Report {
+	r := &Report{
+		Clients:     len(clients),
+		Broadcasts:  broadcasts,
+		ConnectTime: connectTime,
+		ReplayTime:  replayTime,
+		Rate:        float64(broadcasts) / replayTime.Seconds(),
+	}
+	var all []time.Duration
+	var slowExpected, slowDelivered, fastExpected, fastDelivered int64
+	types := make(map[string]*typeStats)
+	for _, c := range clients {
+		received := c.received.Load()
+		r.Expected += c.expected
+		r.Delivered += received
+		if c.slow {
+			r.SlowClients++
+			slowExpected += c.expected
+			slowDelivered += received
+		} else {
+			fastExpected += c.expected
+			fastDelivered += received
+		}
+		all = append(all, c.latency...)
+		for msgType, st := range c.byType {
+			t, ok := types[msgType]
+			if !ok {
+				t = &typeStats{}
+				types[msgType] = t
+			}
+			t.expected += st.expected
+			t.received += st.received
+			t.latency = append(t.latency, st.latency...)
+		}
+	}
+	r.DropRate = dropRate(r.Expected, r.Delivered)
+	r.SlowDrop = dropRate(slowExpected, slowDelivered)
+	r.FastDrop = dropRate(fastExpected, fastDelivered)
+	r.Latency = percentiles(all)
+	for msgType, t := range types {
+		r.Types = append(r.Types, TypeReport{
+			Type:      msgType,
+			Expected:  t.expected,
+			Delivered: t.received,
+			DropRate:  dropRate(t.expected, t.received),
+			Latency:   percentiles(t.latency),
+		})
+	}
+	sort.Slice(r.Types, func(i, j int) bool { return r.Types[i].Expected > r.Types[j].Expected })
+	return r
+}
+
+func (r *Report) print() {
+	fmt.Printf("%d clients (%d slow), connected in %v\n", r.Clients, r.SlowClients, r.ConnectTime.Round(time.Millisecond))
+	fmt.Printf("%d broadcasts replayed in %v (%.0f/s)\n", r.Broadcasts, r.ReplayTime.Round(time.Millisecond), r.Rate)
+	fmt.Printf("%d of %d deliveries, %.2f%% dropped (fast clients %.2f%%, slow %.2f%%)\n",
+		r.Delivered, r.Expected, r.DropRate*100, r.FastDrop*100, r.SlowDrop*100)
+	fmt.Printf("Latency p50 %v  p90 %v  p99 %v  max %v\n", round(r.Latency.P50), round(r.Latency.P90), round(r.Latency.P99), round(r.Latency.Max))
+	fmt.Println()
+	fmt.Printf("%-22s %10s %10s %8s %10s %10s\n", "Type", "Expected", "Delivered", "Dropped", "p50", "p99")
+	for _, t := range r.Types {
+		fmt.Printf("%-22s %10d %10d %7.2f%% %10v %10v\n", t.Type, t.Expected, t.Delivered, t.DropRate*100, round(t.Latency.P50), round(t.Latency.P99))
+	}
+}
+
+// round shortens a latency for display
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
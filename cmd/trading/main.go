@@ -1,17 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"margraf/config"
 	"margraf/graph"
+	"margraf/replay"
 	"margraf/trading"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 func main() {
 	// Command line flags
-	graphFile := flag.String("graph", "margraf_graph.json", "Path to graph JSON file")
+	graphFile := flag.String("graph", "", "Path to graph JSON file (default: margraf_graph.json in the data directory, see -data-dir)")
+	dataDir := flag.String("data-dir", "", "Data directory of margraf (default: the working directory if it holds a graph, else $XDG_DATA_HOME/margraf)")
 	mode := flag.String("mode", "analyze", "Mode: analyze, backtest, mock")
 	minCorrelation := flag.Float64("min-correlation", 0.7, "Minimum correlation threshold")
 	daysBack := flag.Int("days", 365, "Number of days for historical data")
@@ -21,9 +29,41 @@ func main() {
 	exitThreshold := flag.Float64("exit", 0.5, "Z-score exit threshold")
 	stopLoss := flag.Float64("stoploss", 0.05, "Stop loss percentage")
 	lookback := flag.Int("lookback", 20, "Lookback window for strategy")
+	scaleIn := flag.String("scale-in", "", "Comma-separated z-scores beyond -entry at which to add a lot, e.g. 2.5,3.0")
+	scaleOut := flag.String("scale-out", "", "Comma-separated z-scores above -exit at which to take part of the position off, e.g. 1.0")
+	offline := flag.Bool("offline", false, "Serve Yahoo requests from recorded fixtures")
+	record := flag.Bool("record", false, "Record Yahoo responses as fixtures")
+	fixtureDir := flag.String("fixtures", replay.DefaultDir, "Fixture directory for -offline / -record")
+	strict := flag.Bool("strict", false, "Exit if the graph file holds invalid data instead of loading it as is")
+	maxHold := flag.Float64("max-hold", 0, "Close positions held this many days (0 = no limit)")
+	cooldown := flag.Float64("cooldown", 0, "Days after an exit before the pair may enter again")
+	earningsDays := flag.Int("earnings-blackout", 0, "Days either side of either leg's earnings with no entries and positions closed (0 = off)")
+	targetEarnings := flag.Bool("target-earnings", false, "Trade only inside the -earnings-blackout windows, closing when they end")
+	shrinkage := flag.Float64("prior-shrinkage", 20, "Price observations the graph prior (shared industries, suppliers, edges) is worth when blending correlations (0 = prices only)")
+	priceSource := flag.String("prices", "yahoo", "Price history source: yahoo, or graph for the daily closes margraf has recorded (instant, works offline)")
+	earningsFile := flag.String("earnings-file", "", "File of TICKER,YYYY-MM-DD earnings dates (default: Yahoo's calendar, which lists upcoming dates only)")
 
 	flag.Parse()
 
+	if *graphFile == "" {
+		if _, err := config.SetDataDir(*dataDir); err != nil {
+			fmt.Printf("Error setting up data directory: %v\n", err)
+			os.Exit(1)
+		}
+		*graphFile = config.DataPath(config.GraphFile)
+	}
+
+	replayMode := replay.ModeLive
+	if *offline {
+		replayMode = replay.ModeReplay
+	} else if *record {
+		replayMode = replay.ModeRecord
+	}
+	if err := replay.Install(replayMode, *fixtureDir); err != nil {
+		fmt.Printf("Error setting up fixtures: %v\n", err)
+		os.Exit(1)
+	}
+
 	fmt.Println("================================================================================")
 	fmt.Println("MARGRAF CORRELATION TRADING SYSTEM")
 	fmt.Println("================================================================================")
@@ -31,7 +71,15 @@ func main() {
 
 	// Load graph
 	fmt.Printf("Loading graph from %s...\n", *graphFile)
-	g, err := graph.Load(*graphFile)
+	loadGraph := graph.Load
+	if *strict {
+		loadGraph = graph.LoadStrict
+	}
+	g, err := loadGraph(*graphFile)
+	if errors.Is(err, graph.ErrInvalidGraph) {
+		fmt.Printf("Error loading graph: %v\n", err)
+		os.Exit(1)
+	}
 	if err != nil {
 		fmt.Printf("Error loading graph: %v\n", err)
 		fmt.Println("Creating new graph...")
@@ -39,14 +87,34 @@ func main() {
 	} else {
 		fmt.Printf("Graph loaded: %d nodes, %d edges\n\n", len(g.Nodes), len(g.Edges))
 	}
+	// Analysis only: never write over the graph file the main app maintains
+	g.SetReadOnly()
+
+	constraints := trading.Constraints{
+		MaxHolding: time.Duration(*maxHold * 24 * float64(time.Hour)),
+		Cooldown:   time.Duration(*cooldown * 24 * float64(time.Hour)),
+		Target:     *targetEarnings,
+	}
+
+	bands, err := parseBands(*scaleIn, *scaleOut)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	source, err := trading.NewPriceSource(*priceSource, g, *daysBack)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
 	switch *mode {
 	case "analyze":
-		analyzeMode(g, *minCorrelation, *daysBack)
+		analyzeMode(g, source, *minCorrelation, *daysBack, *shrinkage)
 	case "backtest":
-		backtestMode(g, *minCorrelation, *daysBack, *initialCapital, *positionSize, *entryThreshold, *exitThreshold, *stopLoss, *lookback)
+		backtestMode(g, source, *minCorrelation, *daysBack, *shrinkage, *initialCapital, *positionSize, *entryThreshold, *exitThreshold, *stopLoss, *lookback, bands, constraints, *earningsDays, *earningsFile)
 	case "mock":
-		mockBacktestMode(*minCorrelation, *initialCapital, *positionSize, *entryThreshold, *exitThreshold, *stopLoss, *lookback)
+		mockBacktestMode(*minCorrelation, *initialCapital, *positionSize, *entryThreshold, *exitThreshold, *stopLoss, *lookback, bands, constraints)
 	default:
 		fmt.Printf("Unknown mode: %s\n", *mode)
 		flag.Usage()
@@ -54,7 +122,31 @@ func main() {
 	}
 }
 
-func analyzeMode(g *graph.Graph, minCorrelation float64, daysBack int) {
+// fetchPriceHistories reads the price history of each node from source,
+// keyed by node ID, skipping (with a warning) the nodes it has none for
+func fetchPriceHistories(source trading.PriceSource, nodes []*graph.Node, verbose bool) map[string]*trading.AssetPriceHistory {
+	priceHistories := make(map[string]*trading.AssetPriceHistory)
+	for _, node := range nodes {
+		fmt.Printf("  Fetching %s (%s)...\n", node.Name, node.Ticker)
+		prices, err := source.Prices(context.Background(), node.Ticker)
+		if err != nil {
+			fmt.Printf("    Warning: %v\n", err)
+			continue
+		}
+
+		priceHistories[node.ID] = &trading.AssetPriceHistory{
+			AssetID: node.ID,
+			Ticker:  node.Ticker,
+			Prices:  prices,
+		}
+		if verbose {
+			fmt.Printf("    Success: %d data points\n", len(prices))
+		}
+	}
+	return priceHistories
+}
+
+func analyzeMode(g *graph.Graph, source trading.PriceSource, minCorrelation float64, daysBack int, shrinkage float64) {
 	fmt.Println("MODE: CORRELATION ANALYSIS")
 	fmt.Println("--------------------------------------------------------------------------------")
 	fmt.Println()
@@ -78,28 +170,7 @@ func analyzeMode(g *graph.Graph, minCorrelation float64, daysBack int) {
 
 	// Fetch historical data
 	fmt.Printf("Fetching %d days of historical data...\n", daysBack)
-	fetcher := trading.NewHistoricalDataFetcher()
-
-	endDate := time.Now()
-	startDate := endDate.AddDate(0, 0, -daysBack)
-
-	priceHistories := make(map[string]*trading.AssetPriceHistory)
-
-	for _, node := range tickerNodes {
-		fmt.Printf("  Fetching %s (%s)...\n", node.Name, node.Ticker)
-		prices, err := fetcher.FetchYahooHistoricalData(node.Ticker, startDate, endDate)
-		if err != nil {
-			fmt.Printf("    Warning: %v\n", err)
-			continue
-		}
-
-		priceHistories[node.ID] = &trading.AssetPriceHistory{
-			AssetID: node.ID,
-			Ticker:  node.Ticker,
-			Prices:  prices,
-		}
-		fmt.Printf("    Success: %d data points\n", len(prices))
-	}
+	priceHistories := fetchPriceHistories(source, tickerNodes, true)
 
 	if len(priceHistories) < 2 {
 		fmt.Println("\nError: Failed to fetch sufficient historical data")
@@ -110,6 +181,7 @@ func analyzeMode(g *graph.Graph, minCorrelation float64, daysBack int) {
 	// Analyze correlations
 	fmt.Println("\nAnalyzing correlations...")
 	analyzer := trading.NewCorrelationAnalyzer(g)
+	analyzer.Shrinkage = shrinkage
 
 	pairs, err := analyzer.FindCorrelatedPairs(priceHistories, minCorrelation)
 	if err != nil {
@@ -131,7 +203,8 @@ func analyzeMode(g *graph.Graph, minCorrelation float64, daysBack int) {
 	for i := 0; i < displayLimit; i++ {
 		pair := pairs[i]
 		fmt.Printf("\n%d. %s (%s) <-> %s (%s)\n", i+1, pair.Asset1, pair.Ticker1, pair.Asset2, pair.Ticker2)
-		fmt.Printf("   Correlation:    %.4f\n", pair.Correlation)
+		fmt.Printf("   Correlation:    %.4f (raw %.4f over %d points, graph prior %.4f at %.0f%% weight)\n",
+			pair.Correlation, pair.RawCorrelation, pair.Observations, pair.GraphPrior, pair.PriorWeight*100)
 		fmt.Printf("   Graph Distance: %d\n", pair.GraphDistance)
 		fmt.Printf("   Direct Edge:    %v\n", pair.HasDirectEdge)
 		if pair.HasDirectEdge {
@@ -144,7 +217,7 @@ func analyzeMode(g *graph.Graph, minCorrelation float64, daysBack int) {
 	fmt.Println("================================================================================")
 }
 
-func backtestMode(g *graph.Graph, minCorrelation float64, daysBack int, initialCapital, positionSize, entryThreshold, exitThreshold, stopLoss float64, lookback int) {
+func backtestMode(g *graph.Graph, source trading.PriceSource, minCorrelation float64, daysBack int, shrinkage float64, initialCapital, positionSize, entryThreshold, exitThreshold, stopLoss float64, lookback int, bands [2][]float64, constraints trading.Constraints, earningsDays int, earningsFile string) {
 	fmt.Println("MODE: BACKTEST")
 	fmt.Println("--------------------------------------------------------------------------------")
 	fmt.Println()
@@ -164,27 +237,7 @@ func backtestMode(g *graph.Graph, minCorrelation float64, daysBack int, initialC
 
 	// Fetch historical data
 	fmt.Printf("Fetching %d days of historical data...\n", daysBack)
-	fetcher := trading.NewHistoricalDataFetcher()
-
-	endDate := time.Now()
-	startDate := endDate.AddDate(0, 0, -daysBack)
-
-	priceHistories := make(map[string]*trading.AssetPriceHistory)
-
-	for _, node := range tickerNodes {
-		fmt.Printf("  Fetching %s (%s)...\n", node.Name, node.Ticker)
-		prices, err := fetcher.FetchYahooHistoricalData(node.Ticker, startDate, endDate)
-		if err != nil {
-			fmt.Printf("    Warning: %v\n", err)
-			continue
-		}
-
-		priceHistories[node.ID] = &trading.AssetPriceHistory{
-			AssetID: node.ID,
-			Ticker:  node.Ticker,
-			Prices:  prices,
-		}
-	}
+	priceHistories := fetchPriceHistories(source, tickerNodes, false)
 
 	if len(priceHistories) < 2 {
 		fmt.Println("\nError: Failed to fetch sufficient historical data")
@@ -193,6 +246,7 @@ func backtestMode(g *graph.Graph, minCorrelation float64, daysBack int, initialC
 
 	// Find correlated pairs
 	analyzer := trading.NewCorrelationAnalyzer(g)
+	analyzer.Shrinkage = shrinkage
 	pairs, err := analyzer.FindCorrelatedPairs(priceHistories, minCorrelation)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
@@ -206,6 +260,7 @@ func backtestMode(g *graph.Graph, minCorrelation float64, daysBack int, initialC
 
 	// Backtest the top pair
 	fmt.Printf("\nBacktesting top pair: %s <-> %s\n", pairs[0].Ticker1, pairs[0].Ticker2)
+	fmt.Printf("Correlation %.4f (raw %.4f, graph prior %.4f)\n", pairs[0].Correlation, pairs[0].RawCorrelation, pairs[0].GraphPrior)
 
 	strategy := trading.NewPairsTradingStrategy(
 		pairs[0],
@@ -214,6 +269,20 @@ func backtestMode(g *graph.Graph, minCorrelation float64, daysBack int, initialC
 		stopLoss,
 		lookback,
 	)
+	strategy.Constraints = constraints
+	strategy.ScaleIn, strategy.ScaleOut = bands[0], bands[1]
+	if earningsDays > 0 {
+		blackouts, err := earningsBlackouts(trading.NewHistoricalDataFetcher(), earningsFile, earningsDays, pairs[0].Ticker1, pairs[0].Ticker2)
+		if err != nil {
+			fmt.Printf("Warning: no earnings blackouts: %v\n", err)
+		}
+		strategy.Constraints.Blackouts = blackouts
+		if strategy.Constraints.Target {
+			fmt.Printf("Earnings windows (targeted): %d windows of +/-%d days\n", len(blackouts), earningsDays)
+		} else {
+			fmt.Printf("Earnings blackouts: %d windows of +/-%d days\n", len(blackouts), earningsDays)
+		}
+	}
 
 	backtester := trading.NewBacktester(initialCapital, positionSize, 0.001)
 
@@ -229,7 +298,7 @@ func backtestMode(g *graph.Graph, minCorrelation float64, daysBack int, initialC
 	result.PrintReport()
 }
 
-func mockBacktestMode(minCorrelation float64, initialCapital, positionSize, entryThreshold, exitThreshold, stopLoss float64, lookback int) {
+func mockBacktestMode(minCorrelation float64, initialCapital, positionSize, entryThreshold, exitThreshold, stopLoss float64, lookback int, bands [2][]float64, constraints trading.Constraints) {
 	fmt.Println("MODE: MOCK BACKTEST (Synthetic Data)")
 	fmt.Println("--------------------------------------------------------------------------------")
 	fmt.Println()
@@ -267,6 +336,8 @@ func mockBacktestMode(minCorrelation float64, initialCapital, positionSize, entr
 		stopLoss,
 		lookback,
 	)
+	strategy.Constraints = constraints
+	strategy.ScaleIn, strategy.ScaleOut = bands[0], bands[1]
 
 	backtester := trading.NewBacktester(initialCapital, positionSize, 0.001)
 
@@ -281,3 +352,49 @@ func mockBacktestMode(minCorrelation float64, initialCapital, positionSize, entr
 	fmt.Println("\nNOTE: This is a demonstration using synthetic data.")
 	fmt.Println("For real backtesting, use -mode=backtest with actual market data.")
 }
+
+// parseBands reads the -scale-in and -scale-out lists, sorted the way the
+// strategy walks them: scale-ins ascending, scale-outs descending
+func parseBands(scaleIn, scaleOut string) ([2][]float64, error) {
+	var bands [2][]float64
+	for i, list := range []string{scaleIn, scaleOut} {
+		for _, f := range strings.Split(list, ",") {
+			if f = strings.TrimSpace(f); f == "" {
+				continue
+			}
+			z, err := strconv.ParseFloat(f, 64)
+			if err != nil || z <= 0 {
+				return bands, fmt.Errorf("invalid z-score %q in -scale-in/-scale-out", f)
+			}
+			bands[i] = append(bands[i], z)
+		}
+	}
+	sort.Float64s(bands[0])
+	sort.Sort(sort.Reverse(sort.Float64Slice(bands[1])))
+	return bands, nil
+}
+
+// earningsBlackouts builds blackout windows of days either side of each
+// ticker's earnings dates, read from file or else fetched from Yahoo
+func earningsBlackouts(fetcher *trading.HistoricalDataFetcher, file string, days int, tickers ...string) ([]trading.Blackout, error) {
+	dates := make(map[string][]time.Time)
+	if file != "" {
+		all, err := trading.LoadEarningsDates(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tickers {
+			dates[t] = all[strings.ToUpper(t)]
+		}
+	} else {
+		for _, t := range tickers {
+			d, err := fetcher.FetchEarningsDates(context.Background(), t)
+			if err != nil {
+				return nil, err
+			}
+			dates[t] = d
+		}
+	}
+	window := time.Duration(days) * 24 * time.Hour
+	return trading.EarningsBlackouts(dates, window, window), nil
+}
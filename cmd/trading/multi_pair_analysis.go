@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"margraf/trading"
 	"time"
@@ -45,8 +46,8 @@ func main() {
 	for _, p := range pairs {
 		fmt.Printf("Analyzing %s (%s) vs %s (%s)...\n", p.name1, p.ticker1, p.name2, p.ticker2)
 
-		prices1, err1 := fetcher.FetchYahooHistoricalData(p.ticker1, startDate, endDate)
-		prices2, err2 := fetcher.FetchYahooHistoricalData(p.ticker2, startDate, endDate)
+		prices1, err1 := fetcher.FetchYahooHistoricalData(context.Background(), p.ticker1, startDate, endDate)
+		prices2, err2 := fetcher.FetchYahooHistoricalData(context.Background(), p.ticker2, startDate, endDate)
 
 		if err1 != nil || err2 != nil {
 			fmt.Printf("  ERROR: Could not fetch data\n")
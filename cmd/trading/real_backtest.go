@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"margraf/trading"
 	"time"
@@ -32,7 +33,7 @@ func main() {
 
 	// Fetch data
 	fmt.Printf("Fetching %s...\n", ticker1)
-	prices1, err := fetcher.FetchYahooHistoricalData(ticker1, startDate, endDate)
+	prices1, err := fetcher.FetchYahooHistoricalData(context.Background(), ticker1, startDate, endDate)
 	if err != nil {
 		fmt.Printf("Error fetching %s: %v\n", ticker1, err)
 		return
@@ -40,7 +41,7 @@ func main() {
 	fmt.Printf("  Success: %d data points\n", len(prices1))
 
 	fmt.Printf("Fetching %s...\n", ticker2)
-	prices2, err := fetcher.FetchYahooHistoricalData(ticker2, startDate, endDate)
+	prices2, err := fetcher.FetchYahooHistoricalData(context.Background(), ticker2, startDate, endDate)
 	if err != nil {
 		fmt.Printf("Error fetching %s: %v\n", ticker2, err)
 		return
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"margraf/trading"
 	"time"
@@ -21,15 +22,15 @@ func main() {
 
 	for _, ticker := range testTickers {
 		fmt.Printf("\nFetching data for %s...\n", ticker)
-		prices, err := fetcher.FetchYahooHistoricalData(ticker, startDate, endDate)
+		prices, err := fetcher.FetchYahooHistoricalData(context.Background(), ticker, startDate, endDate)
 
 		if err != nil {
 			fmt.Printf("  ERROR: %v\n", err)
 		} else {
 			fmt.Printf("  SUCCESS: Fetched %d data points\n", len(prices))
 			if len(prices) > 0 {
-				fmt.Printf("  First: %s - $%.2f\n", time.Unix(prices[0].Timestamp, 0).Format("2006-01-02"), prices[0].Price)
-				fmt.Printf("  Last:  %s - $%.2f\n", time.Unix(prices[len(prices)-1].Timestamp, 0).Format("2006-01-02"), prices[len(prices)-1].Price)
+				fmt.Printf("  First: %s - $%.2f\n", time.Unix(prices[0].Timestamp, 0).UTC().Format("2006-01-02"), prices[0].Price)
+				fmt.Printf("  Last:  %s - $%.2f\n", time.Unix(prices[len(prices)-1].Timestamp, 0).UTC().Format("2006-01-02"), prices[len(prices)-1].Price)
 			}
 		}
 	}
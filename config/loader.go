@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,30 +19,353 @@ type Config struct {
 	Simulation struct {
 		ShockImpact    float64 `yaml:"shock_health_impact"`
 		SentimentScale float64 `yaml:"sentiment_scale"`
+		Health         struct {
+			Default   HealthParams            `yaml:"default"`
+			NodeTypes map[string]HealthParams `yaml:"node_types"` // Keyed by node type, e.g. "Nation"
+		} `yaml:"health"`
+		Propagation struct {
+			Default   float64            `yaml:"default"`    // Share of a shock passed on by edge types not listed (0 = built-in 0.5)
+			EdgeTypes map[string]float64 `yaml:"edge_types"` // Keyed by edge type, e.g. "Supplies"; missing types keep their built-in factor
+			Pairs     []PropagationPair  `yaml:"pairs"`      // Factors for an edge type between particular node types
+			Overrides string             `yaml:"overrides"`  // File for factors set with "propagation set" (empty = "margraf_propagation.json")
+		} `yaml:"propagation"`
+		Confidence struct {
+			Enabled bool               `yaml:"enabled"` // Scale each edge's share of a shock by how sure we are the edge exists
+			Weight  float64            `yaml:"weight"`  // 1 = multiply by confidence, 0.5 = halfway (0 = 1)
+			Default float64            `yaml:"default"` // Edges of unknown origin (0 = built-in 0.5)
+			Origins map[string]float64 `yaml:"origins"` // Keyed by the edge's "origin" attribute, e.g. "co_mention"; missing origins keep their built-in confidence
+		} `yaml:"confidence"`
+		Traces struct {
+			Enabled bool   `yaml:"enabled"` // Record how each shock spreads, save it and broadcast shock_trace
+			Dir     string `yaml:"dir"`     // Directory of saved traces, one JSON file per shock (empty = "traces")
+		} `yaml:"traces"`
 	} `yaml:"simulation"`
 	News struct {
-		RSSUrl       string `yaml:"rss_url"`
-		PollInterval int    `yaml:"poll_interval"`
+		RSSUrl       string       `yaml:"rss_url"` // Single RSS feed, used when feeds is empty
+		Feeds        []FeedConfig `yaml:"feeds"`
+		PollInterval int          `yaml:"poll_interval"`
+		QuietHours   string       `yaml:"quiet_hours"` // Local hours no feed is polled, e.g. "22-06" (empty = none)
+		CountryFeeds struct {
+			Enabled      bool   `yaml:"enabled"`       // Add the catalog's feeds for each Nation node's country
+			Catalog      string `yaml:"catalog"`       // YAML catalog whose countries replace the bundled entries (empty = bundled only)
+			PollInterval int    `yaml:"poll_interval"` // Seconds between polls of added feeds (0 = news.poll_interval)
+		} `yaml:"country_feeds"`
 	} `yaml:"news"`
+	Social struct {
+		Telegram []string `yaml:"telegram"` // Public Telegram channel usernames searched in each crawl
+		Discord  []string `yaml:"discord"`  // Discord channel IDs searched in each crawl (needs DISCORD_BOT_TOKEN)
+		Crawl    struct {
+			Workers         int `yaml:"workers"`          // Crawls run at once (0 = 2)
+			CooldownMinutes int `yaml:"cooldown_minutes"` // Minutes before a topic is crawled again (0 = 30, -1 = none)
+			MaxQueue        int `yaml:"max_queue"`        // Crawls waiting for a worker; more are dropped (0 = 20)
+		} `yaml:"crawl"`
+		Volume struct {
+			BaselineHours int     `yaml:"baseline_hours"` // Hourly mention counts a spike is measured against (0 = 168)
+			Threshold     float64 `yaml:"threshold"`      // Standard deviations above the baseline that make a spike (0 = 3)
+			MinMentions   int     `yaml:"min_mentions"`   // Fewest mentions in an hour that can be a spike (0 = 5)
+		} `yaml:"volume"`
+	} `yaml:"social"`
+	CoMentions struct {
+		MinItems   int     `yaml:"min_items"`   // Headlines or posts naming both nodes before an edge is proposed (0 = 3)
+		WindowDays int     `yaml:"window_days"` // Days those items must fall within (0 = 30)
+		AutoCreate bool    `yaml:"auto_create"` // Add proposed edges without "comentions accept"
+		Weight     float64 `yaml:"weight"`      // Weight of edges created from co-mentions (0 = 0.2)
+	} `yaml:"comentions"`
 	Market struct {
 		PollInterval int `yaml:"poll_interval"`
 	} `yaml:"market"`
+	DataSources struct {
+		RefreshInterval int    `yaml:"refresh_interval"` // Hours between World Bank / Comtrade refreshes (0 = manual only)
+		Year            string `yaml:"year"`             // Data year to pull; empty = latest complete year
+		Climate         struct {
+			Dataset     string   `yaml:"dataset"`      // CSV of climate exposures keyed by node ID, name or country
+			LLMEstimate bool     `yaml:"llm_estimate"` // Estimate exposures with the LLM when the dataset has no row
+			NodeTypes   []string `yaml:"node_types"`   // Node types the LLM scores (empty = all)
+		} `yaml:"climate"`
+	} `yaml:"datasources"`
+	Scenarios struct {
+		Library string `yaml:"library"` // Directory of shared scenario files loaded at startup (empty = "scenarios")
+	} `yaml:"scenarios"`
+	RAG struct {
+		Store         string  `yaml:"store"`          // Node description vectors (empty = "margraf_vectors.json")
+		LinkThreshold float64 `yaml:"link_threshold"` // Similarity needed to link a news entity to a described node (0 = 0.75)
+	} `yaml:"rag"`
+	Audit struct {
+		Path string `yaml:"path"` // JSON Lines log of discovery changes (empty = "margraf_audit.jsonl")
+	} `yaml:"audit"`
+	WAL struct {
+		Path  string `yaml:"path"`   // JSON Lines log of every graph change (empty = "margraf_wal.jsonl")
+		MaxMB int    `yaml:"max_mb"` // Rotate to <path>.1 at the next save past this size (0 = 64)
+	} `yaml:"wal"`
+	Jobs struct {
+		DecayPrune       string  `yaml:"decay_prune"`        // Cron schedule of temporal decay then pruning (empty = manual only)
+		Refresh          string  `yaml:"refresh"`            // Cron schedule of the World Bank / Comtrade refresh
+		Reseed           string  `yaml:"reseed"`             // Cron schedule of re-running discovery on stale industries
+		Digest           string  `yaml:"digest"`             // Cron schedule of the change digest
+		Tickers          string  `yaml:"tickers"`            // Cron schedule of looking up missing tickers
+		Embeddings       string  `yaml:"embeddings"`         // Cron schedule of recomputing node embeddings
+		History          string  `yaml:"history"`            // Cron schedule of the end-of-day snapshot
+		PruneOlderThan   int     `yaml:"prune_older_than"`   // Days without evidence before an edge is pruned (0 = 180)
+		PruneWeightBelow float64 `yaml:"prune_weight_below"` // Only edges weaker than this are pruned (0 = 0.05)
+		StaleDays        int     `yaml:"stale_days"`         // Days since an industry was explored before it is stale (0 = 30)
+		ReseedLimit      int     `yaml:"reseed_limit"`       // Industries re-seeded per run (0 = 10)
+		TickerLimit      int     `yaml:"ticker_limit"`       // Tickers looked up per run, most central companies first (0 = 50)
+		DigestFile       string  `yaml:"digest_file"`        // Latest digest, as Markdown (empty = "margraf_digest.md")
+		HistoryDir       string  `yaml:"history_dir"`        // Directory of the end-of-day rows, one subdirectory per day (empty = "margraf_history")
+	} `yaml:"jobs"`
+	Embeddings struct {
+		File         string  `yaml:"file"`           // Vectors written by "embed" and the embeddings job, served at /api/embeddings (empty = "margraf_embeddings.json")
+		Dimensions   int     `yaml:"dimensions"`     // Vector length (0 = 64)
+		WalksPerNode int     `yaml:"walks_per_node"` // Random walks started from each node (0 = 10)
+		WalkLength   int     `yaml:"walk_length"`    // Nodes per walk (0 = 40)
+		Window       int     `yaml:"window"`         // Skip-gram context either side of a node (0 = 5)
+		P            float64 `yaml:"p"`              // node2vec return parameter (0 = 1)
+		Q            float64 `yaml:"q"`              // node2vec in-out parameter; 1 with p = 1 is DeepWalk (0 = 1)
+		Epochs       int     `yaml:"epochs"`         // Training passes over the walks (0 = 1)
+		MinWeight    float64 `yaml:"min_weight"`     // Edges lighter than this are not walked
+		Seed         int64   `yaml:"seed"`           // Same seed and graph give the same vectors
+	} `yaml:"embeddings"`
+	Analytics struct {
+		Threshold int     `yaml:"threshold"`  // Nodes above which centrality and hitting times are sampled (0 = 2000)
+		Sources   int     `yaml:"sources"`    // Source nodes sampled for betweenness (0 = 200)
+		Walks     int     `yaml:"walks"`      // Random walks per sampled hitting time (0 = 2000)
+		MaxSteps  int     `yaml:"max_steps"`  // Steps before a walk gives up (0 = 10000)
+		MinWeight float64 `yaml:"min_weight"` // Edges lighter than this are ignored
+		Seed      int64   `yaml:"seed"`       // Same seed and graph give the same estimates
+	} `yaml:"analytics"`
+	Weights struct {
+		Normalize struct {
+			Method   string   `yaml:"method"`   // rank (percentile within type) or minmax
+			Floor    float64  `yaml:"floor"`    // Lowest normalized weight (at least 0.1, the blocked threshold)
+			Interval int      `yaml:"interval"`  // Hours between automatic passes (0 = manual only)
+			Types    []string `yaml:"types"`    // Edge types to normalize (empty = all)
+		} `yaml:"normalize"`
+	} `yaml:"weights"`
+	Pipelines map[string]bool `yaml:"pipelines"` // Background engines on at startup, e.g. news: false (missing = on)
+	Stress    struct {
+		WindowHours int                `yaml:"window_hours"` // How far back news, social, edge and health signals count
+		Interval    int                `yaml:"interval"`     // Seconds between recomputations when the graph is quiet
+		Top         int                `yaml:"top"`          // Nodes per stress_update (0 = all)
+		Weights     map[string]float64 `yaml:"weights"`      // Keyed by signal: news, social, edges, health
+	} `yaml:"stress"`
+	Anomalies struct {
+		Threshold   float64 `yaml:"threshold"`    // |z| of a change against the edge's earlier changes that flags it (0 = 3)
+		MinChange   float64 `yaml:"min_change"`   // Smallest weight move flagged (0 = 0.1)
+		MinHistory  int     `yaml:"min_history"`  // Earlier changes an edge needs before it is judged (0 = 5)
+		WindowHours int     `yaml:"window_hours"` // Changes checked at startup and listed by "anomalies" (0 = 24)
+		Interval    int     `yaml:"interval"`     // Seconds between checks when the graph is quiet (0 = 300)
+	} `yaml:"anomalies"`
+	Industries struct {
+		RollupInterval int `yaml:"rollup_interval"` // Seconds between industry rollup refreshes (0 = 300)
+	} `yaml:"industries"`
+	Portfolio struct {
+		File        string  `yaml:"file"`         // Holdings saved by "portfolio add" (empty = "margraf_portfolio.json")
+		Depth       int     `yaml:"depth"`        // Supply chain hops followed upstream of each holding (0 = 3)
+		Threshold   float64 `yaml:"threshold"`    // Share of the portfolio that flags a hidden exposure (0 = 0.3)
+		Interval    int     `yaml:"interval"`     // Seconds between recomputations when the graph is quiet (0 = 300)
+		HedgeDays   int     `yaml:"hedge_days"`   // Days of price history used to pick and size pair hedges (0 = 90, -1 = don't fetch prices)
+		HedgePrices string  `yaml:"hedge_prices"` // Where that history comes from: yahoo (default) or graph (the prices margraf has recorded)
+	} `yaml:"portfolio"`
+	Paper struct {
+		File         string           `yaml:"file"`          // Ledger of orders, fills, positions and daily NAV (empty = "margraf_paper.json")
+		Pairs        []string         `yaml:"pairs"`         // Pairs to trade, as "TICKER1/TICKER2"
+		Strategies   []StrategyConfig `yaml:"strategies"`    // Other strategies to trade, by registered name
+		Capital      float64          `yaml:"capital"`       // Starting capital of a new ledger (0 = 100000)
+		PositionSize float64          `yaml:"position_size"` // Notional per pair position (0 = 10000)
+		Commission   float64          `yaml:"commission"`    // Share of notional per fill
+		Currency     string           `yaml:"currency"`      // Capital, sizing and prices are in this currency (empty = USD)
+		Entry        float64          `yaml:"entry"`         // Z-score that opens a position (0 = 2)
+		Exit         float64          `yaml:"exit"`          // Z-score that closes it (0 = 0.5)
+		StopLoss     float64          `yaml:"stop_loss"`     // Loss as a share of entry prices that closes it (0 = 0.05)
+		Lookback     int              `yaml:"lookback"`      // Price ticks behind the z-score (0 = 20)
+		ScaleIn      []float64        `yaml:"scale_in"`      // Z-scores beyond entry at which a lot is added, ascending (empty = all in)
+		ScaleOut     []float64        `yaml:"scale_out"`     // Z-scores above exit at which part of the position is taken off, descending (empty = all out)
+		Interval     int              `yaml:"interval"`      // Seconds between ticks (0 = 60)
+		MaxHolding   float64          `yaml:"max_holding"`   // Hours after which a position is closed (0 = no limit)
+		Cooldown     float64          `yaml:"cooldown"`      // Hours after an exit before the pair may enter again (0 = none)
+		Events       int              `yaml:"events"`        // Days around high-impact calendar events on either leg that pairs avoid or target (0 = off)
+		EventMode    string           `yaml:"event_mode"`    // avoid (default: no entries, positions closed) or target (trade only inside the windows)
+	} `yaml:"paper"`
+	Pairs struct {
+		Top       int     `yaml:"top"`       // Graph-connected pairs watched, strongest graph prior first (0 = 20)
+		Entry     float64 `yaml:"entry"`     // |z| of the price ratio that raises a pair_alert (0 = 2)
+		Lookback  int     `yaml:"lookback"`  // Daily prices behind the z-score (0 = 20)
+		Days      int     `yaml:"days"`      // Days of price history fetched and cached per ticker (0 = 90)
+		Prices    string  `yaml:"prices"`    // Where that history comes from: yahoo (default) or graph (the prices margraf has recorded)
+		Shrinkage float64 `yaml:"shrinkage"` // Observations the graph prior is worth in the reported correlation (0 = 20)
+		Interval  int     `yaml:"interval"`  // Hours between scans (0 = 24)
+	} `yaml:"pairs"`
+	Calendar struct {
+		Interval int    `yaml:"interval"` // Hours between calendar refreshes (0 = 12, -1 = off)
+		Earnings bool   `yaml:"earnings"` // Fetch the earnings dates of ticker nodes from Yahoo
+		Economic string `yaml:"economic"` // File of DATE,NODE,IMPACT,TITLE economic events (empty = none)
+		Window   int    `yaml:"window"`   // Hours ahead the news engine announces high-impact events and weighs their news in full (0 = 48, -1 = off)
+	} `yaml:"calendar"`
+	Countries struct {
+		Aliases map[string]string `yaml:"aliases"` // Country name -> ISO code or known name, for names fuzzy matching misses
+	} `yaml:"countries"`
+	Types struct {
+		Nodes []NodeTypeConfig `yaml:"nodes"` // Node types beyond the built-in ones (see graph.DeclareTypes)
+		Edges []EdgeTypeConfig `yaml:"edges"` // Edge types beyond the built-in ones
+	} `yaml:"types"`
 	Server struct {
-		Port string `yaml:"port"`
+		Port      string `yaml:"port"`
+		RateLimit struct {
+			RequestsPerMinute   int `yaml:"requests_per_minute"`    // HTTP requests per client (0 = unlimited)
+			WSMessagesPerMinute int `yaml:"ws_messages_per_minute"` // WS messages per client (0 = unlimited)
+			Burst               int `yaml:"burst"`
+		} `yaml:"rate_limit"`
+		Sessions   string `yaml:"sessions"`    // File for per-token client sessions ("" = kept in memory)
+		AdminToken string `yaml:"admin_token"` // Token for /admin and WS admin commands ("" = disabled; MARGRAF_ADMIN_TOKEN overrides)
+		Timeline   struct {
+			Entries    int     `yaml:"entries"`     // Market and social entries kept for /api/timeline (0 = 2000)
+			MarketMove float64 `yaml:"market_move"` // Daily price change recorded as a market anomaly (0 = 0.05)
+		} `yaml:"timeline"`
 	} `yaml:"server"`
+	Bus struct {
+		URL     string `yaml:"url"`     // redis://host:6379 or nats://host:4222; empty = single instance
+		Channel string `yaml:"channel"` // Pub/sub channel shared by all instances
+		Writer  bool   `yaml:"writer"`  // This instance seeds, runs engines and persists the graph
+	} `yaml:"bus"`
+	LLM struct {
+		Providers []LLMProvider        `yaml:"providers"` // Failover chain, tried healthiest first (empty = OpenRouter then Gemini, by API key)
+		Budgets   map[string]LLMBudget `yaml:"budgets"`   // Daily allowance per consumer: seeder, news, social, queries, other (missing = unlimited)
+		Log       struct {
+			Path      string `yaml:"path"`        // JSON Lines log of every prompt and reply (empty = "margraf_llm.jsonl", "off" = memory only)
+			MaxSizeMB int    `yaml:"max_size_mb"` // Rotate past this size (0 = 10)
+			Backups   int    `yaml:"backups"`     // Rotated files kept (0 = 3)
+			Redact    bool   `yaml:"redact"`      // Replace API keys and bearer tokens with [REDACTED]
+		} `yaml:"log"`
+	} `yaml:"llm"`
+	Timeouts struct {
+		LLM       int `yaml:"llm"`       // One LLM completion, including retries
+		HTTP      int `yaml:"http"`      // One scraper / data source request
+		Seed      int `yaml:"seed"`      // A full seed or reseed
+		Expansion int `yaml:"expansion"` // Expanding one nation
+		Crawl     int `yaml:"crawl"`     // One social crawl
+		News      int `yaml:"news"`      // One news poll
+		Refresh   int `yaml:"refresh"`   // One World Bank / Comtrade / data source refresh
+	} `yaml:"timeouts"` // Seconds; 0 = built-in default
 	Logging struct {
 		Level        string `yaml:"level"`
 		EnableColors bool   `yaml:"enable_colors"`
 	} `yaml:"logging"`
+	Console struct {
+		Macros string `yaml:"macros"` // Macros defined with "name = ...", kept across restarts (empty = "margraf_macros.json")
+		Author string `yaml:"author"` // Author of notes added with "note" (empty = $USER)
+	} `yaml:"console"`
+	DataDir  string `yaml:"data_dir"` // Where the graph, logs and other saved files go (empty = see SetDataDir)
+	Timezone string `yaml:"timezone"` // Times are shown, and quiet hours and job schedules read, in this zone: an IANA name, UTC or Local (empty = the server's)
+}
+
+// HealthParams configures health bounds, input weights and update function
+// for one node type (see graph.HealthModel)
+type HealthParams struct {
+	Min     float64            `yaml:"min"`
+	Max     float64            `yaml:"max"`
+	Weights map[string]float64 `yaml:"weights"` // Keyed by input: direct, shock, propagation, winner, market, sentiment
+	Update  string             `yaml:"update"`  // "linear" (default) or "damped"
+}
+
+// PropagationPair sets an edge type's propagation factor between node types;
+// an empty source or target matches any type
+type PropagationPair struct {
+	EdgeType string  `yaml:"edge_type"`
+	Source   string  `yaml:"source"`
+	Target   string  `yaml:"target"`
+	Factor   float64 `yaml:"factor"`
+}
+
+// NodeTypeConfig declares a node type (see graph.NodeTypeDef)
+type NodeTypeConfig struct {
+	Name        string `yaml:"name"`  // e.g. "ShippingRoute"
+	Color       string `yaml:"color"` // "#rrggbb" or a color name on the dashboard and in DOT exports (empty = grey)
+	Description string `yaml:"description"`
+}
+
+// EdgeTypeConfig declares an edge type (see graph.EdgeTypeDef)
+type EdgeTypeConfig struct {
+	Name           string  `yaml:"name"`           // e.g. "CallsAt"
+	Directionality string  `yaml:"directionality"` // unidirectional (source to target), reverse or bidirectional (default)
+	Propagation    float64 `yaml:"propagation"`    // Share of a shock passed on (0 = simulation.propagation.default)
+	Description    string  `yaml:"description"`
+}
+
+// LLMBudget is one LLM consumer's daily allowance (see llm.Limit)
+// LLMProvider is one LLM in the failover chain
+type LLMProvider struct {
+	Kind              string `yaml:"kind"`                // openrouter (any OpenAI-compatible API), gemini or ollama
+	Model             string `yaml:"model"`               // Empty = the kind's default
+	BaseURL           string `yaml:"base_url"`            // Empty = the kind's public endpoint (ollama: localhost:11434)
+	APIKeyEnv         string `yaml:"api_key_env"`         // Environment variable holding the key (empty = the kind's usual one)
+	EmbedModel        string `yaml:"embed_model"`         // Empty = the kind's default; "none" = no embeddings
+	RequestsPerMinute int    `yaml:"requests_per_minute"` // 0 = 60
+}
+
+type LLMBudget struct {
+	Requests    int    `yaml:"requests"`     // Completions per day (0 = unlimited)
+	Tokens      int    `yaml:"tokens"`       // Estimated prompt and reply tokens per day (0 = unlimited)
+	OnExhausted string `yaml:"on_exhausted"` // degrade (default: cached answers, lexicon sentiment) or queue (wait for the next day)
+}
+
+// StrategyConfig configures one paper trading strategy (see trading.Strategy)
+type StrategyConfig struct {
+	Name     string             `yaml:"name"`      // pairs, momentum, mean_reversion or a strategy registered in Go
+	Tickers  string             `yaml:"tickers"`   // "TICKER", or "TICKER1/TICKER2" for strategies trading two
+	Entry    float64            `yaml:"entry"`     // Z-score that opens a position (0 = paper.entry)
+	Exit     float64            `yaml:"exit"`      // Z-score that closes it (0 = paper.exit)
+	StopLoss float64            `yaml:"stop_loss"` // Loss as a share of entry prices that closes it (0 = paper.stop_loss)
+	Lookback int                `yaml:"lookback"`  // Price ticks the strategy looks back (0 = paper.lookback)
+	ScaleIn  []float64          `yaml:"scale_in"`  // Z-scores at which pairs add a lot (empty = paper.scale_in)
+	ScaleOut []float64          `yaml:"scale_out"` // Z-scores at which pairs take part off (empty = paper.scale_out)
+	Params   map[string]float64 `yaml:"params"`    // Anything else a custom strategy reads
+}
+
+// FeedConfig configures one news source (see news.FeedSource)
+type FeedConfig struct {
+	Name     string `yaml:"name"`     // Shown in logs (empty = type and host)
+	Type     string `yaml:"type"`     // rss (default), atom, jsonfeed or newsapi
+	URL      string `yaml:"url"`      // Feed URL; for newsapi, overrides the API endpoint
+	APIKey   string `yaml:"api_key"`  // newsapi key (empty = NEWSAPI_KEY)
+	Query    string `yaml:"query"`    // newsapi search terms (empty = top headlines)
+	Category string `yaml:"category"` // newsapi top-headlines category (empty = business)
+	Language string `yaml:"language"` // newsapi language (empty = en)
+
+	PollInterval int    `yaml:"poll_interval"` // Seconds between polls of this feed (0 = news.poll_interval)
+	QuietHours   string `yaml:"quiet_hours"`   // Overrides news.quiet_hours for this feed ("off" = none)
 }
 
 var Global Config
 
-// Load reads the config.yaml file.
+// Timeout converts a timeouts setting (seconds) to a duration, using def when unset
+func Timeout(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Load reads the config file (see FindConfig).
 func Load() error {
-	data, err := os.ReadFile("config.yaml")
+	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return err
 	}
 	return yaml.Unmarshal(data, &Global)
 }
+
+// Reload re-reads the config file into a fresh Config and swaps it in only if
+// it parses, so a broken edit keeps the running settings. Settings read once
+// at startup (server port, bus, intervals, data directory) still need a restart.
+func Reload() error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	var fresh Config
+	if err := yaml.Unmarshal(data, &fresh); err != nil {
+		return err
+	}
+	Global = fresh
+	return nil
+}
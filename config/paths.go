@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Everything margraf keeps between runs (the graph and its auto-save copy,
+// logs, vector store, ledgers, traces, digests) lives in one data directory,
+// and file settings in config.yaml are relative to it. config.yaml and .env
+// are found separately, so a packaged install keeps its settings in
+// $XDG_CONFIG_HOME and its data in $XDG_DATA_HOME. A working directory that
+// already holds a graph stays the data directory, so existing setups keep
+// their files where they are.
+
+// GraphFile is the graph's file name in the data directory
+const GraphFile = "margraf_graph.json"
+
+var (
+	configPath = "config.yaml"
+	dataDir    = "."
+)
+
+// FindConfig picks the config file: path when set, else $MARGRAF_CONFIG,
+// else ./config.yaml if it exists, else config.yaml under
+// $XDG_CONFIG_HOME/margraf (~/.config/margraf). Load and Reload read it.
+func FindConfig(path string) string {
+	if path == "" {
+		path = os.Getenv("MARGRAF_CONFIG")
+	}
+	if path == "" {
+		path = "config.yaml"
+		if _, err := os.Stat(path); err != nil {
+			if dir, err := os.UserConfigDir(); err == nil {
+				path = filepath.Join(dir, "margraf", "config.yaml")
+			}
+		}
+	}
+	configPath = path
+	return path
+}
+
+// ConfigPath returns the config file Load reads
+func ConfigPath() string {
+	return configPath
+}
+
+// SetDataDir picks and creates the data directory: dir when set, else
+// $MARGRAF_DATA_DIR, else data_dir from config.yaml, else the working
+// directory if it holds GraphFile, else $XDG_DATA_HOME/margraf
+// (~/.local/share/margraf). Call it after Load.
+func SetDataDir(dir string) (string, error) {
+	if dir == "" {
+		dir = os.Getenv("MARGRAF_DATA_DIR")
+	}
+	if dir == "" {
+		dir = Global.DataDir
+	}
+	if dir == "" {
+		var err error
+		if dir, err = defaultDataDir(); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("data directory: %w", err)
+	}
+	dataDir = dir
+	return dir, nil
+}
+
+// defaultDataDir is the working directory when it holds a graph, otherwise
+// the XDG data directory
+func defaultDataDir() (string, error) {
+	if _, err := os.Stat(GraphFile); err == nil {
+		return ".", nil
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "margraf"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("data directory: %w (set -data-dir or MARGRAF_DATA_DIR)", err)
+	}
+	return filepath.Join(home, ".local", "share", "margraf"), nil
+}
+
+// DataDir returns the data directory ("." until SetDataDir is called)
+func DataDir() string {
+	return dataDir
+}
+
+// DataPath resolves a file setting against the data directory. Absolute
+// paths are kept as they are.
+func DataPath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dataDir, path)
+}
@@ -0,0 +1,210 @@
+package datasources
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"margraf/config"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/pipeline"
+	"margraf/syserr"
+	"margraf/task"
+	"margraf/trading"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Calendar sources, recorded on each event they report
+const (
+	CalendarSourceYahoo = "yahoo"    // Earnings dates of ticker nodes
+	CalendarSourceFile  = "economic" // The economic calendar file
+)
+
+// CalendarWorker keeps the graph's scheduled events current: earnings dates
+// for every node with a ticker, and economic events from a calendar file.
+type CalendarWorker struct {
+	Graph        *graph.Graph
+	Earnings     *trading.HistoricalDataFetcher // nil = no earnings dates
+	EconomicFile string                         // See LoadEconomicCalendar; "" = none
+
+	Timeout time.Duration // Per-run limit; 0 = none
+
+	mu      sync.Mutex
+	running bool
+}
+
+// CalendarReport summarizes a calendar refresh
+type CalendarReport struct {
+	Earnings int // Earnings events attached
+	Economic int // Economic events attached
+	Errors   int
+}
+
+func NewCalendarWorker(g *graph.Graph) *CalendarWorker {
+	return &CalendarWorker{
+		Graph:    g,
+		Earnings: trading.NewHistoricalDataFetcher(),
+		Timeout:  config.Timeout(config.Global.Timeouts.Refresh, time.Hour),
+	}
+}
+
+// Start refreshes the calendar now and then every interval until ctx is
+// cancelled. Each run is a cancellable "calendar" task.
+func (w *CalendarWorker) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if pipeline.Enabled(pipeline.Calendar) {
+				task.StartTimeout("calendar", w.Timeout, func(ctx context.Context, t *task.Task) error {
+					_, err := w.Refresh(ctx)
+					return err
+				})
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Refresh refetches every source and replaces its events on the graph.
+// Overlapping runs are skipped.
+func (w *CalendarWorker) Refresh(ctx context.Context) (*CalendarReport, error) {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return nil, fmt.Errorf("calendar refresh already in progress")
+	}
+	w.running = true
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+	}()
+
+	report := &CalendarReport{}
+
+	if w.EconomicFile != "" {
+		events, err := LoadEconomicCalendar(w.EconomicFile, w.Graph)
+		if err != nil {
+			report.Errors++
+			syserr.Report(syserr.ModuleDataSource, "economic calendar", err)
+		} else {
+			report.Economic = w.Graph.SetScheduledEvents(CalendarSourceFile, events)
+		}
+	}
+
+	if w.Earnings != nil {
+		tickers := make(map[string]string) // Ticker -> node ID
+		w.Graph.NodesRange(func(n *graph.Node) {
+			if n.Ticker != "" {
+				tickers[strings.ToUpper(n.Ticker)] = n.ID
+			}
+		})
+		var events []graph.ScheduledEvent
+		done := 0
+		for ticker, nodeID := range tickers {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			done++
+			task.Report(ctx, done, len(tickers), "earnings "+ticker)
+			dates, err := w.Earnings.FetchEarningsDates(ctx, ticker)
+			if err != nil {
+				report.Errors++
+				continue
+			}
+			for _, d := range dates {
+				events = append(events, graph.ScheduledEvent{
+					NodeID: nodeID,
+					Kind:   graph.EventEarnings,
+					Title:  ticker + " earnings",
+					Time:   d,
+					Impact: graph.ImpactHigh,
+				})
+			}
+		}
+		report.Earnings = w.Graph.SetScheduledEvents(CalendarSourceYahoo, events)
+	}
+
+	logger.Info(logger.StatusData, "Calendar refreshed: %d earnings, %d economic events (%d errors)",
+		report.Earnings, report.Economic, report.Errors)
+	return report, nil
+}
+
+// LoadEconomicCalendar reads economic events from a file of
+// "DATE,NODE,IMPACT,TITLE" lines, where DATE is YYYY-MM-DD or
+// "YYYY-MM-DD HH:MM" (UTC), NODE is a node ID, a node name or a country
+// (matched to its Nation node), and IMPACT is high, medium or low. Blank
+// lines and lines starting with # are skipped, as are nodes not in the graph.
+func LoadEconomicCalendar(path string, g *graph.Graph) ([]graph.ScheduledEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []graph.ScheduledEvent
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, ",", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s:%d: want DATE,NODE,IMPACT,TITLE", path, line)
+		}
+		day := strings.TrimSpace(fields[0])
+		at, err := time.Parse("2006-01-02 15:04", day)
+		if err != nil {
+			if at, err = time.Parse("2006-01-02", day); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		}
+		nodeID, ok := calendarNode(g, strings.TrimSpace(fields[1]))
+		if !ok {
+			logger.Warn(logger.StatusWarn, "%s:%d: no node for %q", path, line, strings.TrimSpace(fields[1]))
+			continue
+		}
+		events = append(events, graph.ScheduledEvent{
+			NodeID: nodeID,
+			Kind:   graph.EventEconomic,
+			Title:  strings.TrimSpace(fields[3]),
+			Time:   at,
+			Impact: graph.ParseEventImpact(fields[2]),
+		})
+	}
+	return events, scanner.Err()
+}
+
+// calendarNode finds the node a calendar line names: by ID, by name, or as
+// the Nation node of a country
+func calendarNode(g *graph.Graph, ref string) (string, bool) {
+	if _, ok := g.GetNode(ref); ok {
+		return ref, true
+	}
+	country, isCountry := graph.ResolveCountry(ref)
+	var found string
+	g.NodesRange(func(n *graph.Node) {
+		if found != "" {
+			return
+		}
+		if strings.EqualFold(n.Name, ref) {
+			found = n.ID
+		} else if isCountry && n.Type == graph.NodeTypeNation {
+			if c, ok := graph.ResolveCountry(n.Name); ok && c.Alpha3 == country.Alpha3 {
+				found = n.ID
+			}
+		}
+	})
+	return found, found != ""
+}
@@ -0,0 +1,196 @@
+package datasources
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"margraf/clock"
+	"margraf/graph"
+	"margraf/llm"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClimateSource scores nodes' climate exposure (drought, flood, heat, carbon
+// intensity) from a CSV dataset, falling back to LLM estimates. Every scored
+// node records where its numbers came from in climate_source.
+//
+// The dataset has a header row naming a key column ("key") and any of the
+// hazard columns (climate_drought, climate_flood, climate_heat,
+// carbon_intensity). Keys match a node's ID, its name, or its country.
+type ClimateSource struct {
+	DatasetPath string
+	Client      *llm.Client             // nil disables LLM estimation
+	NodeTypes   map[graph.NodeType]bool // Types the LLM scores; empty = all
+
+	mu      sync.Mutex
+	dataset map[string]map[string]float64 // lowercased key -> hazard -> score
+}
+
+// NewClimateSource creates a climate source. Either argument may be empty/nil.
+func NewClimateSource(datasetPath string, client *llm.Client, nodeTypes []string) *ClimateSource {
+	types := make(map[graph.NodeType]bool, len(nodeTypes))
+	for _, t := range nodeTypes {
+		types[graph.NodeType(t)] = true
+	}
+	return &ClimateSource{DatasetPath: datasetPath, Client: client, NodeTypes: types}
+}
+
+func (c *ClimateSource) Name() string { return "climate" }
+
+// Discover adds nothing; climate scores are attributes only
+func (c *ClimateSource) Discover(ctx context.Context, nodes []*graph.Node) (*Discovery, error) {
+	return nil, nil
+}
+
+// Enrich scores a node that has no climate scores yet
+func (c *ClimateSource) Enrich(ctx context.Context, node *graph.Node) (map[string]interface{}, error) {
+	if node.HasClimateScores() {
+		return nil, nil
+	}
+
+	if scores, ok := c.lookup(node); ok {
+		return climateAttributes(scores, "dataset:"+filepath.Base(c.DatasetPath)), nil
+	}
+
+	if c.Client == nil || (len(c.NodeTypes) > 0 && !c.NodeTypes[node.Type]) {
+		return nil, nil
+	}
+	scores, err := c.estimate(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	return climateAttributes(scores, "llm:"+c.Client.Model), nil
+}
+
+// Refresh reloads the dataset
+func (c *ClimateSource) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dataset = nil
+	return c.loadLocked()
+}
+
+// lookup finds dataset scores for a node by ID, name or country
+func (c *ClimateSource) lookup(node *graph.Node) (map[string]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dataset == nil {
+		if err := c.loadLocked(); err != nil {
+			return nil, false
+		}
+	}
+
+	for _, key := range []string{node.ID, node.Name, node.Country()} {
+		if key == "" {
+			continue
+		}
+		if scores, ok := c.dataset[strings.ToLower(key)]; ok {
+			return scores, true
+		}
+	}
+	return nil, false
+}
+
+// loadLocked parses the CSV dataset (must be called with c.mu held)
+func (c *ClimateSource) loadLocked() error {
+	c.dataset = make(map[string]map[string]float64)
+	if c.DatasetPath == "" {
+		return nil
+	}
+
+	f, err := os.Open(c.DatasetPath)
+	if err != nil {
+		return fmt.Errorf("open climate dataset: %w", err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return fmt.Errorf("parse climate dataset: %w", err)
+	}
+	if len(rows) < 2 {
+		return nil
+	}
+
+	header := rows[0]
+	for _, row := range rows[1:] {
+		var key string
+		scores := make(map[string]float64)
+		for i, col := range header {
+			if i >= len(row) {
+				break
+			}
+			col = strings.TrimSpace(col)
+			if col == "key" {
+				key = strings.ToLower(strings.TrimSpace(row[i]))
+				continue
+			}
+			if v, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 64); err == nil {
+				scores[col] = v
+			}
+		}
+		if key != "" {
+			c.dataset[key] = scores
+		}
+	}
+	return nil
+}
+
+// estimate asks the LLM for a node's climate exposures
+func (c *ClimateSource) estimate(ctx context.Context, node *graph.Node) (map[string]float64, error) {
+	prompt := fmt.Sprintf(`
+Estimate the physical climate risk and transition risk exposure of "%s" (%s).
+
+Return ONLY a JSON object with scores from 0.0 (no exposure) to 1.0 (severe exposure):
+{
+  "climate_drought": 0.0,
+  "climate_flood": 0.0,
+  "climate_heat": 0.0,
+  "carbon_intensity": 0.0
+}
+
+carbon_intensity is emissions per unit of output relative to peers (1.0 = most carbon-intensive).
+`, node.Name, node.Type)
+
+	resp, err := c.Client.Complete(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	var scores map[string]float64
+	if err := json.Unmarshal([]byte(llm.CleanJSON(resp)), &scores); err != nil {
+		return nil, fmt.Errorf("parse climate estimate: %w", err)
+	}
+	return scores, nil
+}
+
+// climateAttributes keeps known hazards, clamps them to [0, 1] and adds provenance
+func climateAttributes(scores map[string]float64, source string) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	for _, hazard := range graph.ClimateHazards {
+		v, ok := scores[string(hazard)]
+		if !ok {
+			continue
+		}
+		if v < 0 {
+			v = 0
+		}
+		if v > 1 {
+			v = 1
+		}
+		attrs[string(hazard)] = v
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	attrs[graph.AttrClimateSource] = source
+	attrs[graph.AttrClimateUpdated] = clock.Now().Format(time.RFC3339)
+	return attrs
+}
@@ -1,9 +1,13 @@
 package datasources
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"margraf/config"
+	"margraf/graph"
+	"margraf/retry"
 	"net/http"
 	"net/url"
 	"time"
@@ -20,7 +24,7 @@ func NewComtradeClient() *ComtradeClient {
 	return &ComtradeClient{
 		BaseURL: "https://comtradeapi.un.org/data/v1",
 		Client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: config.Timeout(config.Global.Timeouts.HTTP, 30*time.Second),
 		},
 	}
 }
@@ -48,7 +52,7 @@ type ComtradeResponse struct {
 // countryCode1: ISO3 code (e.g., "USA", "IND", "ARE")
 // countryCode2: Partner country ISO3 code
 // year: Trade year (e.g., "2023")
-func (c *ComtradeClient) GetBilateralTrade(countryCode1, countryCode2, year string) ([]TradeFlow, error) {
+func (c *ComtradeClient) GetBilateralTrade(ctx context.Context, countryCode1, countryCode2, year string) ([]TradeFlow, error) {
 	// Build API URL
 	params := url.Values{}
 	params.Add("reporterCode", countryCode1)
@@ -59,14 +63,53 @@ func (c *ComtradeClient) GetBilateralTrade(countryCode1, countryCode2, year stri
 
 	apiURL := fmt.Sprintf("%s/get?%s", c.BaseURL, params.Encode())
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("User-Agent", "MargrafFDKG/1.0")
 
-	resp, err := c.Client.Do(req)
+	resp, err := retry.DoRequest(c.Client, req, retry.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("comtrade API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("comtrade API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result ComtradeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse comtrade response: %v", err)
+	}
+
+	return result.Data, nil
+}
+
+// GetBilateralTradeByCommodity fetches bilateral exports broken down by HS chapter
+// (2-digit commodity codes) instead of the single TOTAL aggregate.
+func (c *ComtradeClient) GetBilateralTradeByCommodity(ctx context.Context, countryCode1, countryCode2, year string) ([]TradeFlow, error) {
+	params := url.Values{}
+	params.Add("reporterCode", countryCode1)
+	params.Add("partnerCode", countryCode2)
+	params.Add("period", year)
+	params.Add("flowCode", "X")
+	params.Add("frequency", "A")
+	params.Add("cmdCode", "AG2") // All 2-digit HS chapters
+
+	apiURL := fmt.Sprintf("%s/get?%s", c.BaseURL, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "MargrafFDKG/1.0")
+
+	resp, err := retry.DoRequest(c.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, fmt.Errorf("comtrade API request failed: %v", err)
 	}
@@ -86,7 +129,7 @@ func (c *ComtradeClient) GetBilateralTrade(countryCode1, countryCode2, year stri
 }
 
 // GetTopExports returns the top exported commodities from a country
-func (c *ComtradeClient) GetTopExports(countryCode string, year string, limit int) ([]TradeFlow, error) {
+func (c *ComtradeClient) GetTopExports(ctx context.Context, countryCode string, year string, limit int) ([]TradeFlow, error) {
 	params := url.Values{}
 	params.Add("reporterCode", countryCode)
 	params.Add("partnerCode", "0") // World (all partners)
@@ -96,14 +139,14 @@ func (c *ComtradeClient) GetTopExports(countryCode string, year string, limit in
 
 	apiURL := fmt.Sprintf("%s/get?%s", c.BaseURL, params.Encode())
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("User-Agent", "MargrafFDKG/1.0")
 
-	resp, err := c.Client.Do(req)
+	resp, err := retry.DoRequest(c.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, err
 	}
@@ -136,36 +179,31 @@ func (c *ComtradeClient) GetTopExports(countryCode string, year string, limit in
 	return flows, nil
 }
 
-// CountryCodeMap maps common country names to ISO3 codes
-var CountryCodeMap = map[string]string{
-	"united states": "USA",
-	"usa": "USA",
-	"china": "CHN",
-	"india": "IND",
-	"united arab emirates": "ARE",
-	"uae": "ARE",
-	"japan": "JPN",
-	"germany": "DEU",
-	"united kingdom": "GBR",
-	"uk": "GBR",
-	"france": "FRA",
-	"brazil": "BRA",
-	"canada": "CAN",
-	"russia": "RUS",
-	"australia": "AUS",
-	"south korea": "KOR",
-	"mexico": "MEX",
-	"indonesia": "IDN",
-	"saudi arabia": "SAU",
-	"turkey": "TUR",
-	"switzerland": "CHE",
-	"pakistan": "PAK",
-	"vietnam": "VNM",
-	"thailand": "THA",
+// GetCountryCode returns ISO3 code for a country name, matched against the
+// ISO registry, tolerating variants like "Korea, Rep." and small
+// misspellings; names that don't resolve are logged once for manual mapping.
+func GetCountryCode(countryName string) (string, bool) {
+	if c, ok := graph.ResolveCountry(countryName); ok {
+		return c.Alpha3, true
+	}
+	noteUnresolved(countryName)
+	return "", false
+}
+
+// TradeWeight normalizes a total bilateral trade value (USD) into an edge weight.
+func TradeWeight(totalValue float64) float64 {
+	weight := 0.3 + (0.5 * (totalValue / 1e11))
+	if weight > 1.0 {
+		weight = 1.0
+	}
+	return weight
 }
 
-// GetCountryCode returns ISO3 code for a country name
-func GetCountryCode(countryName string) (string, bool) {
-	code, ok := CountryCodeMap[countryName]
-	return code, ok
+// CommodityTradeWeight normalizes a single commodity flow ($1B = 0.1, $50B+ = 1.0).
+func CommodityTradeWeight(value float64) float64 {
+	weight := 0.1 + (0.9 * (value / 5e10))
+	if weight > 1.0 {
+		weight = 1.0
+	}
+	return weight
 }
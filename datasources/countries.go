@@ -0,0 +1,46 @@
+package datasources
+
+import (
+	"margraf/graph"
+	"margraf/logger"
+	"sort"
+	"sync"
+)
+
+// unresolved holds country names GetCountryCode could not map to a code
+var unresolved = struct {
+	mu    sync.Mutex
+	names map[string]bool
+}{names: make(map[string]bool)}
+
+// noteUnresolved records a country name with no known code, warning the
+// first time it is seen
+func noteUnresolved(name string) {
+	if name == "" {
+		return
+	}
+	unresolved.mu.Lock()
+	seen := unresolved.names[name]
+	unresolved.names[name] = true
+	unresolved.mu.Unlock()
+	if !seen {
+		logger.Warn(logger.StatusWarn, "No ISO code for country %q; map it under countries.aliases in config.yaml", name)
+	}
+}
+
+// UnresolvedCountries returns the country names GetCountryCode has failed
+// on, sorted, leaving out any an alias added since has resolved
+func UnresolvedCountries() []string {
+	unresolved.mu.Lock()
+	defer unresolved.mu.Unlock()
+	var names []string
+	for name := range unresolved.names {
+		if _, ok := graph.ResolveCountry(name); ok {
+			delete(unresolved.names, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,155 @@
+package datasources
+
+import (
+	"context"
+	"fmt"
+	"margraf/audit"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/syserr"
+	"sort"
+	"sync"
+)
+
+// DataSource is a pluggable connector (internal ERP supplier lists, Bloomberg
+// exports, ...) that feeds entities and attributes into the graph without
+// changes to the discovery package. Register implementations with Register,
+// typically from an init function. Methods that call out to external systems
+// should stop and return ctx.Err() once ctx is cancelled.
+type DataSource interface {
+	// Name uniquely identifies the connector
+	Name() string
+
+	// Discover proposes new nodes and edges related to the given nodes
+	Discover(ctx context.Context, nodes []*graph.Node) (*Discovery, error)
+
+	// Enrich returns attributes to merge into an existing node (nil = nothing to add)
+	Enrich(ctx context.Context, node *graph.Node) (map[string]interface{}, error)
+
+	// Refresh re-pulls any upstream data the connector caches
+	Refresh(ctx context.Context) error
+}
+
+// Discovery holds the entities a DataSource found
+type Discovery struct {
+	Nodes []*graph.Node
+	Edges []*graph.Edge
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]DataSource)
+)
+
+// Register makes a data source available to the seeder and refresh worker.
+// It panics if a source with the same name is registered twice.
+func Register(ds DataSource) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if ds == nil {
+		panic("datasources: Register source is nil")
+	}
+	if _, dup := registry[ds.Name()]; dup {
+		panic("datasources: Register called twice for source " + ds.Name())
+	}
+	registry[ds.Name()] = ds
+}
+
+// Registered returns all registered data sources sorted by name
+func Registered() []DataSource {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	sources := make([]DataSource, 0, len(registry))
+	for _, ds := range registry {
+		sources = append(sources, ds)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Name() < sources[j].Name()
+	})
+	return sources
+}
+
+// Lookup returns a registered data source by name
+func Lookup(name string) (DataSource, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	ds, ok := registry[name]
+	return ds, ok
+}
+
+// ApplyRegistered runs Discover and Enrich for every registered source against the graph.
+// Returns the number of nodes/edges added and nodes enriched.
+func ApplyRegistered(ctx context.Context, g *graph.Graph) (added, enriched int) {
+	for _, ds := range Registered() {
+		if ctx.Err() != nil {
+			return added, enriched
+		}
+		a, e, err := Apply(ctx, g, ds)
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "Data source %s failed: %v", ds.Name(), err)
+			syserr.Report(syserr.ModuleDataSource, ds.Name(), err)
+		}
+		added += a
+		enriched += e
+	}
+	return added, enriched
+}
+
+// Apply runs a single data source against the graph
+func Apply(ctx context.Context, g *graph.Graph, ds DataSource) (added, enriched int, err error) {
+	eventID := "source_" + ds.Name()
+
+	nodes := make([]*graph.Node, 0)
+	g.NodesRange(func(n *graph.Node) {
+		nodes = append(nodes, n)
+	})
+
+	found, err := ds.Discover(ctx, nodes)
+	if err != nil {
+		return 0, 0, fmt.Errorf("discover: %w", err)
+	}
+
+	if found != nil {
+		batch := g.NewBatch(0)
+		for _, n := range found.Nodes {
+			if batch.AddNode(n) {
+				audit.Log(audit.Record{Actor: audit.ActorDataSource(ds.Name()), Action: audit.ActionAddNode, NodeID: n.ID, Reason: "discovered by data source"})
+				added++
+			}
+		}
+		for _, e := range found.Edges {
+			if !batch.HasNode(e.SourceID) || !batch.HasNode(e.TargetID) {
+				continue
+			}
+			batch.AddEdge(e)
+			audit.Log(audit.Record{Actor: audit.ActorDataSource(ds.Name()), Action: audit.ActionAddEdge, SourceID: e.SourceID, TargetID: e.TargetID, EdgeType: string(e.Type), Reason: "discovered by data source"})
+			added++
+		}
+		batch.Flush()
+	}
+
+	g.NodesRange(func(n *graph.Node) {
+		if ctx.Err() != nil {
+			return
+		}
+		attrs, err := ds.Enrich(ctx, n)
+		if err != nil || len(attrs) == 0 {
+			return
+		}
+		if g.UpdateNodeAttributes(n.ID, attrs, eventID) == nil {
+			enriched++
+		}
+	})
+
+	if err := ctx.Err(); err != nil {
+		return added, enriched, err
+	}
+
+	if added > 0 || enriched > 0 {
+		logger.InfoDepth(1, logger.StatusData, "Data source %s: %d added, %d enriched", ds.Name(), added, enriched)
+	}
+
+	return added, enriched, nil
+}
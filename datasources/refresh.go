@@ -0,0 +1,217 @@
+package datasources
+
+import (
+	"context"
+	"fmt"
+	"margraf/config"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/pipeline"
+	"margraf/syserr"
+	"margraf/task"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RefreshWorker periodically re-pulls World Bank profiles and Comtrade trade values
+// for nations already in the graph, so seed-time attributes don't go stale.
+type RefreshWorker struct {
+	Graph     *graph.Graph
+	WorldBank *WorldBankClient
+	Comtrade  *ComtradeClient
+	Year      string // Fixed data year; empty means LatestDataYear()
+
+	Timeout time.Duration // Per-run limit; 0 = none
+
+	mu      sync.Mutex
+	running bool
+}
+
+// RefreshReport summarizes a refresh run
+type RefreshReport struct {
+	Year         string
+	NodesUpdated int
+	EdgesUpdated int
+	Errors       int
+}
+
+func NewRefreshWorker(g *graph.Graph, wb *WorldBankClient, ct *ComtradeClient) *RefreshWorker {
+	return &RefreshWorker{
+		Graph:     g,
+		WorldBank: wb,
+		Comtrade:  ct,
+		Timeout:   config.Timeout(config.Global.Timeouts.Refresh, time.Hour),
+	}
+}
+
+// LatestDataYear returns the most recent year that World Bank / Comtrade
+// annual series are reasonably complete for (two years back).
+func LatestDataYear() string {
+	return strconv.Itoa(time.Now().Year() - 2)
+}
+
+// Start runs a refresh every interval (typically yearly) in the background
+// until ctx is cancelled. Each run is a cancellable "refresh" task.
+func (w *RefreshWorker) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if !pipeline.Enabled(pipeline.Refresh) {
+				continue
+			}
+			task.StartTimeout("refresh", w.Timeout, func(ctx context.Context, t *task.Task) error {
+				_, err := w.Refresh(ctx)
+				return err
+			})
+		}
+	}()
+}
+
+// Refresh re-pulls all nation attributes and trade edges. Overlapping runs are
+// skipped; a cancelled ctx stops the run between requests.
+func (w *RefreshWorker) Refresh(ctx context.Context) (*RefreshReport, error) {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return nil, fmt.Errorf("refresh already in progress")
+	}
+	w.running = true
+	w.mu.Unlock()
+
+	defer func() {
+		w.mu.Lock()
+		w.running = false
+		w.mu.Unlock()
+	}()
+
+	year := w.Year
+	if year == "" {
+		year = LatestDataYear()
+	}
+	report := &RefreshReport{Year: year}
+	eventID := fmt.Sprintf("refresh_%s_%d", year, time.Now().Unix())
+
+	logger.Info(logger.StatusData, "Refreshing World Bank / Comtrade data for %s...", year)
+
+	// Map nation node IDs to ISO3 codes
+	codes := make(map[string]string)
+	w.Graph.NodesRange(func(n *graph.Node) {
+		if n.Type != graph.NodeTypeNation {
+			return
+		}
+		if code, ok := GetCountryCode(n.Name); ok {
+			codes[n.ID] = code
+		}
+	})
+
+	// 1. Node attributes from World Bank
+	done, total := 0, len(codes)
+	for nodeID, code := range codes {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		done++
+		task.Report(ctx, done, total, "World Bank "+nodeID)
+		profile, err := w.WorldBank.GetEconomicProfile(ctx, code, year)
+		if err != nil || profile.GDP == 0 {
+			report.Errors++
+			continue
+		}
+		if err := w.Graph.UpdateNodeAttributes(nodeID, profile.Attributes(), eventID); err != nil {
+			report.Errors++
+			continue
+		}
+		report.NodesUpdated++
+		logger.InfoDepth(1, logger.StatusData, "%s: GDP $%.2fB, Exports $%.2fB", nodeID, profile.GDP/1e9, profile.Exports/1e9)
+	}
+
+	// 2. Trade edges between known nations
+	type pair struct{ src, tgt string }
+	pairs := make(map[pair]bool)
+	w.Graph.EdgesRange(func(e *graph.Edge) {
+		if e.Type != graph.EdgeTypeTrade {
+			return
+		}
+		if _, ok := codes[e.SourceID]; !ok {
+			return
+		}
+		if _, ok := codes[e.TargetID]; !ok {
+			return
+		}
+		pairs[pair{e.SourceID, e.TargetID}] = true
+	})
+
+	total += len(pairs)
+	for p := range pairs {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		done++
+		task.Report(ctx, done, total, "Comtrade "+p.src+" -> "+p.tgt)
+		flows, err := w.Comtrade.GetBilateralTradeByCommodity(ctx, codes[p.src], codes[p.tgt], year)
+		if err != nil {
+			logger.WarnDepth(1, logger.StatusWarn, "Comtrade refresh %s -> %s failed: %v", p.src, p.tgt, err)
+			report.Errors++
+			continue
+		}
+
+		totalValue := 0.0
+		for _, flow := range flows {
+			totalValue += flow.PrimaryValue
+
+			if flow.CommodityCode == "" {
+				continue
+			}
+			attrs := map[string]interface{}{
+				"trade_value": flow.PrimaryValue,
+				"year":        year,
+			}
+			// Only commodity edges that already exist are refreshed; new ones come from discovery
+			if err := w.Graph.RefreshEdge(p.src, p.tgt, graph.EdgeTypeTrade, flow.CommodityCode, CommodityTradeWeight(flow.PrimaryValue), attrs, eventID); err == nil {
+				report.EdgesUpdated++
+			}
+		}
+
+		attrs := map[string]interface{}{
+			"trade_value": totalValue,
+			"year":        year,
+		}
+		if err := w.Graph.RefreshEdge(p.src, p.tgt, graph.EdgeTypeTrade, "", TradeWeight(totalValue), attrs, eventID); err == nil {
+			report.EdgesUpdated++
+		}
+	}
+
+	// 3. Custom connectors
+	for _, ds := range Registered() {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		if err := ds.Refresh(ctx); err != nil {
+			logger.WarnDepth(1, logger.StatusWarn, "Data source %s refresh failed: %v", ds.Name(), err)
+			syserr.Report(syserr.ModuleDataSource, ds.Name()+" refresh", err)
+			report.Errors++
+			continue
+		}
+		added, enriched, err := Apply(ctx, w.Graph, ds)
+		if err != nil {
+			report.Errors++
+		}
+		report.NodesUpdated += added + enriched
+	}
+
+	logger.Success("Data refresh complete (%s): %d nodes, %d edges updated, %d errors",
+		year, report.NodesUpdated, report.EdgesUpdated, report.Errors)
+	if report.Errors > 0 {
+		err := fmt.Errorf("%d World Bank / Comtrade / data source lookups failed for %s", report.Errors, year)
+		syserr.Report(syserr.ModuleDataSource, "refresh", syserr.Warning(syserr.ModuleDataSource, "refresh", err))
+	}
+
+	return report, nil
+}
@@ -0,0 +1,233 @@
+package datasources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"margraf/config"
+	"margraf/retry"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WikidataClient looks up corporate ownership from Wikidata
+// Documentation: https://www.wikidata.org/wiki/Wikidata:SPARQL_query_service
+type WikidataClient struct {
+	APIURL    string
+	SPARQLURL string
+	Client    *http.Client
+}
+
+func NewWikidataClient() *WikidataClient {
+	return &WikidataClient{
+		APIURL:    "https://www.wikidata.org/w/api.php",
+		SPARQLURL: "https://query.wikidata.org/sparql",
+		Client: &http.Client{
+			Timeout: config.Timeout(config.Global.Timeouts.HTTP, 30*time.Second),
+		},
+	}
+}
+
+// Ownership lists a company's parent organizations and subsidiaries by name
+type Ownership struct {
+	EntityID     string
+	Parents      []string
+	Subsidiaries []string
+}
+
+// Classes a company entity must be an instance of, directly or through
+// subclasses: business (Q4830453) and enterprise (Q891723)
+const companyClasses = "wd:Q4830453 wd:Q891723"
+
+// findCandidates is how many search hits FindEntity checks
+const findCandidates = 10
+
+// FindEntity returns the Wikidata ID (e.g. Q95) best matching a company name:
+// the highest-ranked search hit that is an instance (P31) of a business or
+// enterprise class. Names shared with fruit, planets or people ("Apple",
+// "Mercury") would otherwise resolve to whatever ranks first.
+func (w *WikidataClient) FindEntity(ctx context.Context, name string) (string, error) {
+	params := url.Values{}
+	params.Set("action", "wbsearchentities")
+	params.Set("search", name)
+	params.Set("language", "en")
+	params.Set("type", "item")
+	params.Set("limit", strconv.Itoa(findCandidates))
+	params.Set("format", "json")
+
+	body, err := w.get(ctx, w.APIURL+"?"+params.Encode())
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Search []struct {
+			ID string `json:"id"`
+		} `json:"search"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse wikidata search: %v", err)
+	}
+	if len(result.Search) == 0 {
+		return "", fmt.Errorf("no wikidata entity for %s", name)
+	}
+
+	values := make([]string, len(result.Search))
+	for i, hit := range result.Search {
+		values[i] = "wd:" + hit.ID
+	}
+	bindings, err := w.sparql(ctx, fmt.Sprintf(`SELECT DISTINCT ?item WHERE {
+  VALUES ?item { %s }
+  VALUES ?class { %s }
+  ?item wdt:P31/wdt:P279* ?class .
+}`, strings.Join(values, " "), companyClasses))
+	if err != nil {
+		return "", err
+	}
+	companies := make(map[string]bool, len(bindings))
+	for _, b := range bindings {
+		companies[strings.TrimPrefix(b["item"], "http://www.wikidata.org/entity/")] = true
+	}
+	for _, hit := range result.Search { // Search order is relevance
+		if companies[hit.ID] {
+			return hit.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no wikidata company for %s", name)
+}
+
+// GetOwnership fetches parent organizations (P749) and subsidiaries (P355)
+// for a company, checking both directions of each property since Wikidata
+// often records the relationship on only one side.
+func (w *WikidataClient) GetOwnership(ctx context.Context, companyName string) (*Ownership, error) {
+	entityID, err := w.FindEntity(ctx, companyName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT DISTINCT ?rel ?label WHERE {
+  { wd:%[1]s wdt:P749 ?item . BIND("parent" AS ?rel) }
+  UNION { ?item wdt:P355 wd:%[1]s . BIND("parent" AS ?rel) }
+  UNION { wd:%[1]s wdt:P355 ?item . BIND("subsidiary" AS ?rel) }
+  UNION { ?item wdt:P749 wd:%[1]s . BIND("subsidiary" AS ?rel) }
+  ?item rdfs:label ?label . FILTER(LANG(?label) = "en")
+} LIMIT 200`, entityID)
+
+	bindings, err := w.sparql(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	ownership := &Ownership{EntityID: entityID}
+	for _, b := range bindings {
+		switch b["rel"] {
+		case "parent":
+			ownership.Parents = append(ownership.Parents, b["label"])
+		case "subsidiary":
+			ownership.Subsidiaries = append(ownership.Subsidiaries, b["label"])
+		}
+	}
+
+	return ownership, nil
+}
+
+// Headquarters is a company's head office location
+type Headquarters struct {
+	EntityID string
+	Lat      float64
+	Lon      float64
+	Country  string
+}
+
+// GetHeadquarters fetches the coordinates of a company's headquarters
+// (P159 → P625) and its country (P17).
+func (w *WikidataClient) GetHeadquarters(ctx context.Context, companyName string) (*Headquarters, error) {
+	entityID, err := w.FindEntity(ctx, companyName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT ?lat ?lon ?country WHERE {
+  OPTIONAL { wd:%[1]s wdt:P159 ?hq . ?hq wdt:P625 ?coord .
+    BIND(geof:latitude(?coord) AS ?lat) BIND(geof:longitude(?coord) AS ?lon) }
+  OPTIONAL { wd:%[1]s wdt:P17 ?c . ?c rdfs:label ?country . FILTER(LANG(?country) = "en") }
+} LIMIT 1`, entityID)
+
+	bindings, err := w.sparql(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(bindings) == 0 {
+		return nil, fmt.Errorf("no headquarters for %s", companyName)
+	}
+
+	hq := &Headquarters{EntityID: entityID, Country: bindings[0]["country"]}
+	fmt.Sscanf(bindings[0]["lat"], "%g", &hq.Lat)
+	fmt.Sscanf(bindings[0]["lon"], "%g", &hq.Lon)
+	if hq.Country == "" && hq.Lat == 0 && hq.Lon == 0 {
+		return nil, fmt.Errorf("no headquarters for %s", companyName)
+	}
+
+	return hq, nil
+}
+
+// sparql runs a query and flattens each result row to variable -> value
+func (w *WikidataClient) sparql(ctx context.Context, query string) ([]map[string]string, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("format", "json")
+
+	body, err := w.get(ctx, w.SPARQLURL+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Results struct {
+			Bindings []map[string]struct {
+				Value string `json:"value"`
+			} `json:"bindings"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse wikidata sparql response: %v", err)
+	}
+
+	rows := make([]map[string]string, 0, len(result.Results.Bindings))
+	for _, b := range result.Results.Bindings {
+		row := make(map[string]string, len(b))
+		for k, v := range b {
+			row[k] = v.Value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// get performs a GET request with the Wikimedia-required User-Agent
+func (w *WikidataClient) get(ctx context.Context, endpoint string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "MargrafFDKG/1.0")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := retry.DoRequest(w.Client, req, retry.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("wikidata request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("wikidata API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	return io.ReadAll(resp.Body)
+}
@@ -1,9 +1,12 @@
 package datasources
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"margraf/config"
+	"margraf/retry"
 	"net/http"
 	"time"
 )
@@ -19,7 +22,7 @@ func NewWorldBankClient() *WorldBankClient {
 	return &WorldBankClient{
 		BaseURL: "https://api.worldbank.org/v2",
 		Client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: config.Timeout(config.Global.Timeouts.HTTP, 30*time.Second),
 		},
 	}
 }
@@ -51,47 +54,47 @@ type WorldBankResponse struct {
 
 // GetGDP fetches GDP data for a country
 // Indicator: NY.GDP.MKTP.CD (GDP current USD)
-func (w *WorldBankClient) GetGDP(countryCode string, year string) (*IndicatorValue, error) {
-	return w.getIndicator(countryCode, "NY.GDP.MKTP.CD", year)
+func (w *WorldBankClient) GetGDP(ctx context.Context, countryCode string, year string) (*IndicatorValue, error) {
+	return w.getIndicator(ctx, countryCode, "NY.GDP.MKTP.CD", year)
 }
 
 // GetFDI fetches Foreign Direct Investment data
 // Indicator: BX.KLT.DINV.CD.WD (FDI net inflows)
-func (w *WorldBankClient) GetFDI(countryCode string, year string) (*IndicatorValue, error) {
-	return w.getIndicator(countryCode, "BX.KLT.DINV.CD.WD", year)
+func (w *WorldBankClient) GetFDI(ctx context.Context, countryCode string, year string) (*IndicatorValue, error) {
+	return w.getIndicator(ctx, countryCode, "BX.KLT.DINV.CD.WD", year)
 }
 
 // GetTradeBalance fetches trade balance
 // Indicator: NE.RSB.GNFS.CD (External balance on goods and services)
-func (w *WorldBankClient) GetTradeBalance(countryCode string, year string) (*IndicatorValue, error) {
-	return w.getIndicator(countryCode, "NE.RSB.GNFS.CD", year)
+func (w *WorldBankClient) GetTradeBalance(ctx context.Context, countryCode string, year string) (*IndicatorValue, error) {
+	return w.getIndicator(ctx, countryCode, "NE.RSB.GNFS.CD", year)
 }
 
 // GetExports fetches total exports
 // Indicator: NE.EXP.GNFS.CD (Exports of goods and services)
-func (w *WorldBankClient) GetExports(countryCode string, year string) (*IndicatorValue, error) {
-	return w.getIndicator(countryCode, "NE.EXP.GNFS.CD", year)
+func (w *WorldBankClient) GetExports(ctx context.Context, countryCode string, year string) (*IndicatorValue, error) {
+	return w.getIndicator(ctx, countryCode, "NE.EXP.GNFS.CD", year)
 }
 
 // GetImports fetches total imports
 // Indicator: NE.IMP.GNFS.CD (Imports of goods and services)
-func (w *WorldBankClient) GetImports(countryCode string, year string) (*IndicatorValue, error) {
-	return w.getIndicator(countryCode, "NE.IMP.GNFS.CD", year)
+func (w *WorldBankClient) GetImports(ctx context.Context, countryCode string, year string) (*IndicatorValue, error) {
+	return w.getIndicator(ctx, countryCode, "NE.IMP.GNFS.CD", year)
 }
 
 // getIndicator is a generic method to fetch any indicator
-func (w *WorldBankClient) getIndicator(countryCode, indicatorCode, year string) (*IndicatorValue, error) {
+func (w *WorldBankClient) getIndicator(ctx context.Context, countryCode, indicatorCode, year string) (*IndicatorValue, error) {
 	url := fmt.Sprintf("%s/country/%s/indicator/%s?date=%s&format=json",
 		w.BaseURL, countryCode, indicatorCode, year)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("User-Agent", "MargrafFDKG/1.0")
 
-	resp, err := w.Client.Do(req)
+	resp, err := retry.DoRequest(w.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, fmt.Errorf("world bank API request failed: %v", err)
 	}
@@ -141,35 +144,47 @@ type EconomicProfile struct {
 	TradeBalance float64
 }
 
-func (w *WorldBankClient) GetEconomicProfile(countryCode, year string) (*EconomicProfile, error) {
+// Attributes returns the profile as graph node attributes
+func (p *EconomicProfile) Attributes() map[string]interface{} {
+	return map[string]interface{}{
+		"gdp":           p.GDP,
+		"exports":       p.Exports,
+		"imports":       p.Imports,
+		"fdi":           p.FDI,
+		"trade_balance": p.TradeBalance,
+		"data_year":     p.Year,
+	}
+}
+
+func (w *WorldBankClient) GetEconomicProfile(ctx context.Context, countryCode, year string) (*EconomicProfile, error) {
 	profile := &EconomicProfile{
 		CountryCode: countryCode,
 		Year:        year,
 	}
 
 	// Fetch GDP
-	if gdp, err := w.GetGDP(countryCode, year); err == nil {
+	if gdp, err := w.GetGDP(ctx, countryCode, year); err == nil {
 		profile.GDP = gdp.Value
 		profile.CountryName = gdp.Country.Value
 	}
 
 	// Fetch FDI
-	if fdi, err := w.GetFDI(countryCode, year); err == nil {
+	if fdi, err := w.GetFDI(ctx, countryCode, year); err == nil {
 		profile.FDI = fdi.Value
 	}
 
 	// Fetch Exports
-	if exports, err := w.GetExports(countryCode, year); err == nil {
+	if exports, err := w.GetExports(ctx, countryCode, year); err == nil {
 		profile.Exports = exports.Value
 	}
 
 	// Fetch Imports
-	if imports, err := w.GetImports(countryCode, year); err == nil {
+	if imports, err := w.GetImports(ctx, countryCode, year); err == nil {
 		profile.Imports = imports.Value
 	}
 
 	// Fetch Trade Balance
-	if balance, err := w.GetTradeBalance(countryCode, year); err == nil {
+	if balance, err := w.GetTradeBalance(ctx, countryCode, year); err == nil {
 		profile.TradeBalance = balance.Value
 	}
 
@@ -178,13 +193,13 @@ func (w *WorldBankClient) GetEconomicProfile(countryCode, year string) (*Economi
 
 // GetTopTradingPartners estimates top trading partners based on trade volume
 // Note: World Bank doesn't provide bilateral trade, so this is estimated from total trade
-func (w *WorldBankClient) GetTradeIntensity(countryCode string, year string) (float64, error) {
-	exports, err := w.GetExports(countryCode, year)
+func (w *WorldBankClient) GetTradeIntensity(ctx context.Context, countryCode string, year string) (float64, error) {
+	exports, err := w.GetExports(ctx, countryCode, year)
 	if err != nil {
 		return 0, err
 	}
 
-	gdp, err := w.GetGDP(countryCode, year)
+	gdp, err := w.GetGDP(ctx, countryCode, year)
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,150 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/task"
+	"sync"
+)
+
+// visitedSet is the set of nodes one seeding campaign has claimed. Claiming
+// is a single check-and-set, so concurrent company goroutines never expand
+// the same node twice.
+type visitedSet struct {
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+// claim marks id visited and reports whether this call was the first
+func (v *visitedSet) claim(id string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.ids[id] {
+		return false
+	}
+	v.ids[id] = true
+	return true
+}
+
+// seen reports whether id has been claimed
+func (v *visitedSet) seen(id string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.ids[id]
+}
+
+type campaignKey struct{}
+
+// withCampaign starts a seeding campaign under ctx with an empty visited set.
+// Across campaigns, progress lives in the graph's exploration state instead.
+func withCampaign(ctx context.Context) context.Context {
+	return context.WithValue(ctx, campaignKey{}, &visitedSet{ids: make(map[string]bool)})
+}
+
+// campaignFrom returns the campaign running under ctx, starting one if there
+// is none (e.g. a nation expansion triggered by news)
+func campaignFrom(ctx context.Context) (context.Context, *visitedSet) {
+	if v, ok := ctx.Value(campaignKey{}).(*visitedSet); ok {
+		return ctx, v
+	}
+	ctx = withCampaign(ctx)
+	return ctx, ctx.Value(campaignKey{}).(*visitedSet)
+}
+
+// explored reports whether a node exists and finished exploring in an
+// earlier campaign
+func explored(g *graph.Graph, id string) bool {
+	n, ok := g.GetNode(id)
+	return ok && n.Exploration() == graph.ExplorationComplete
+}
+
+// ExpandFrontier runs one campaign over up to limit nodes that earlier runs
+// added but did not finish exploring (see graph.Frontier), limited to types
+// (none = nations, industries, companies and raw materials). It returns the
+// number of nodes expanded.
+func (s *Seeder) ExpandFrontier(ctx context.Context, g *graph.Graph, limit int, types ...graph.NodeType) (int, error) {
+	if s.Client.ApiKey == "" {
+		return 0, fmt.Errorf("no LLM API key set; cannot expand the graph")
+	}
+	if len(types) == 0 {
+		types = []graph.NodeType{graph.NodeTypeNation, graph.NodeTypeIndustry, graph.NodeTypeCorporation, graph.NodeTypeRawMaterial}
+	}
+	ctx = withCampaign(ctx)
+
+	frontier := g.Frontier(limit, types...)
+	for i, n := range frontier {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		task.Report(ctx, i, len(frontier), fmt.Sprintf("%s %s (%s)", n.Type, n.Name, n.Exploration()))
+		logger.Info(logger.StatusChk, "Expanding %s %s (%s)...", n.Type, n.Name, n.Exploration())
+		if err := s.expand(ctx, g, n); err != nil {
+			logger.Warn(logger.StatusWarn, "Failed to expand %s: %v", n.Name, err)
+		}
+	}
+	task.Report(ctx, len(frontier), len(frontier), "")
+	return len(frontier), nil
+}
+
+// ExpandNode runs discovery for one node, whatever its exploration state:
+// a nation's industries, an industry's companies and materials, a company's
+// suppliers and clients, or a raw material's producers
+func (s *Seeder) ExpandNode(ctx context.Context, g *graph.Graph, id string) error {
+	if s.Client.ApiKey == "" {
+		return fmt.Errorf("no LLM API key set; cannot expand the graph")
+	}
+	n, ok := g.GetNode(id)
+	if !ok {
+		return fmt.Errorf("node %s not found", id)
+	}
+	if n.Exploration() == graph.ExplorationComplete {
+		// Re-explore: a complete node is skipped by the discovery steps
+		if err := g.SetExploration(id, graph.ExplorationPartial); err != nil {
+			return err
+		}
+	}
+	return s.expand(withCampaign(ctx), g, n)
+}
+
+// expand dispatches a node to the discovery step for its type
+func (s *Seeder) expand(ctx context.Context, g *graph.Graph, n *graph.Node) error {
+	switch n.Type {
+	case graph.NodeTypeNation:
+		return s.ProcessNation(ctx, g, n.Name, 0)
+	case graph.NodeTypeIndustry:
+		nation := parentOf(g, n.ID, graph.EdgeTypeHasIndustry)
+		if nation == nil {
+			return fmt.Errorf("industry %s has no nation", n.Name)
+		}
+		return s.processIndustry(ctx, g, n.Name, nation.Name, 0)
+	case graph.NodeTypeCorporation:
+		industry := ""
+		if parent := parentOf(g, n.ID, graph.EdgeTypeHasCompany); parent != nil {
+			industry = parent.Name
+		}
+		s.discoverCompanyRelations(ctx, g, n.Name, n.ID, industry, 0)
+		return nil
+	case graph.NodeTypeRawMaterial:
+		industryID := ""
+		if parent := parentOf(g, n.ID, graph.EdgeTypeRequires); parent != nil {
+			industryID = parent.ID
+		}
+		return s.processMaterial(ctx, g, n.Name, industryID, 0)
+	}
+	return fmt.Errorf("cannot expand %s nodes", n.Type)
+}
+
+// parentOf returns the source of an edge of type t into id, if any
+func parentOf(g *graph.Graph, id string, t graph.EdgeType) *graph.Node {
+	for _, e := range g.GetIncomingEdges(id) {
+		if e.Type != t {
+			continue
+		}
+		if n, ok := g.GetNode(e.SourceID); ok {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package discovery
+
+import (
+	"fmt"
+	"margraf/audit"
+	"margraf/config"
+	"margraf/graph"
+	"margraf/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The news and social pipelines see the same entities over and over. When
+// two nodes with no edge between them keep appearing in the same items, the
+// pair is proposed as a new edge, with those items as its evidence. Accepted
+// proposals, or every proposal with auto_create, become low-weight edges, so
+// monitoring keeps teaching the graph relationships discovery missed.
+
+// Defaults for comentions
+const (
+	defaultCoMentionItems  = 3
+	defaultCoMentionWindow = 30 * 24 * time.Hour
+	defaultCoMentionWeight = 0.2
+	maxCoMentionEntities   = 6 // Nodes per item paired up, so long posts don't pair everything
+)
+
+// CoMentionSettings decide when a co-mentioned pair becomes a proposal
+type CoMentionSettings struct {
+	MinItems   int           // Distinct items naming both nodes
+	Window     time.Duration // Items older than this no longer count
+	AutoCreate bool          // Add proposed edges without review
+	Weight     float64       // Weight of edges created from co-mentions
+}
+
+// CoMentionSettingsFromConfig reads comentions, filling in defaults
+func CoMentionSettingsFromConfig() CoMentionSettings {
+	cfg := config.Global.CoMentions
+	c := CoMentionSettings{
+		MinItems:   cfg.MinItems,
+		Window:     time.Duration(cfg.WindowDays) * 24 * time.Hour,
+		AutoCreate: cfg.AutoCreate,
+		Weight:     cfg.Weight,
+	}
+	if c.MinItems <= 0 {
+		c.MinItems = defaultCoMentionItems
+	}
+	if c.Window <= 0 {
+		c.Window = defaultCoMentionWindow
+	}
+	if c.Weight <= 0 {
+		c.Weight = defaultCoMentionWeight
+	}
+	return c
+}
+
+// Proposal is an edge suggested by co-mentions
+type Proposal struct {
+	Edge  *graph.Edge
+	Items []graph.CoMentionItem // Supporting items, oldest first
+}
+
+// CoMentionLearner records which known nodes are named together and turns
+// frequent pairs into edge proposals
+type CoMentionLearner struct {
+	Graph    *graph.Graph
+	Settings CoMentionSettings
+	Notify   func(p Proposal, created bool) // Called when a pair first becomes a proposal (optional)
+
+	mu sync.Mutex // Serializes proposals, so a pair is not created twice
+}
+
+// NewCoMentionLearner creates a learner with settings from the config
+func NewCoMentionLearner(g *graph.Graph) *CoMentionLearner {
+	return &CoMentionLearner{Graph: g, Settings: CoMentionSettingsFromConfig()}
+}
+
+// Observe records that one item named the given nodes. Unknown IDs are
+// ignored. A pair reaching MinItems items without an edge between its
+// nodes is proposed, and created too with AutoCreate.
+func (l *CoMentionLearner) Observe(ids []string, item graph.CoMentionItem) {
+	var known []string
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if id == "" || seen[id] || len(known) >= maxCoMentionEntities {
+			continue
+		}
+		seen[id] = true
+		if _, ok := l.Graph.GetNode(id); ok {
+			known = append(known, id)
+		}
+	}
+
+	for i := range known {
+		for _, other := range known[i+1:] {
+			n, added, err := l.Graph.RecordCoMention(known[i], other, item, l.Settings.Window)
+			if err != nil || !added || n != l.Settings.MinItems {
+				continue
+			}
+			p, ok := l.propose(known[i], other)
+			if !ok {
+				continue
+			}
+			created := false
+			if l.Settings.AutoCreate {
+				created = l.create(p, "auto-created")
+			}
+			if created {
+				logger.Info(logger.StatusNew, "Co-mention edge: %s -[%s]-> %s (%d items)", p.Edge.SourceID, p.Edge.Type, p.Edge.TargetID, len(p.Items))
+			} else {
+				logger.Info(logger.StatusNew, "Proposed edge: %s -[%s]-> %s (%d items; \"comentions accept %s %s\")", p.Edge.SourceID, p.Edge.Type, p.Edge.TargetID, len(p.Items), p.Edge.SourceID, p.Edge.TargetID)
+			}
+			if l.Notify != nil {
+				l.Notify(p, created)
+			}
+		}
+	}
+}
+
+// Proposals lists the pairs named together often enough that have no edge
+// yet, most items first
+func (l *CoMentionLearner) Proposals() []Proposal {
+	var out []Proposal
+	for _, pair := range l.Graph.FrequentCoMentions(l.Settings.MinItems, l.Settings.Window) {
+		if p, ok := l.propose(pair.A, pair.B); ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Accept creates the proposed edge between a and b
+func (l *CoMentionLearner) Accept(a, b string) (*graph.Edge, error) {
+	if _, ok := l.Graph.CoMentionOf(a, b); !ok {
+		return nil, fmt.Errorf("%s and %s have not been named together", a, b)
+	}
+	p, ok := l.propose(a, b)
+	if !ok {
+		return nil, fmt.Errorf("no edge to propose between %s and %s (already linked, or no co-mention edge fits their types)", a, b)
+	}
+	if !l.create(p, "accepted") {
+		return nil, fmt.Errorf("%s and %s were linked meanwhile", a, b)
+	}
+	return p.Edge, nil
+}
+
+// propose builds the edge a pair suggests, if the pair has no edge and
+// their node types suggest one
+func (l *CoMentionLearner) propose(a, b string) (Proposal, bool) {
+	if l.Graph.Linked(a, b) {
+		return Proposal{}, false
+	}
+	pair, ok := l.Graph.CoMentionOf(a, b)
+	if !ok {
+		return Proposal{}, false
+	}
+	src, tgt, edgeType := l.edgeFor(pair.A, pair.B)
+	if edgeType == "" {
+		return Proposal{}, false
+	}
+	return Proposal{
+		Edge: &graph.Edge{
+			SourceID: src,
+			TargetID: tgt,
+			Type:     edgeType,
+			Weight:   l.Settings.Weight,
+			Attributes: map[string]interface{}{
+				"origin":      graph.OriginCoMention,
+				"co_mentions": len(pair.Items),
+			},
+		},
+		Items: pair.Items,
+	}, true
+}
+
+// edgeFor picks the edge two co-mentioned nodes most likely share: Trade
+// between nations, Capital between a nation and a company, CompetesWith
+// between companies in the same industry and Trade between other companies.
+// Other pairs get no edge type.
+func (l *CoMentionLearner) edgeFor(a, b string) (string, string, graph.EdgeType) {
+	na, okA := l.Graph.GetNode(a)
+	nb, okB := l.Graph.GetNode(b)
+	if !okA || !okB {
+		return "", "", ""
+	}
+	switch {
+	case na.Type == graph.NodeTypeNation && nb.Type == graph.NodeTypeNation:
+		return a, b, graph.EdgeTypeTrade
+	case na.Type == graph.NodeTypeNation && nb.Type == graph.NodeTypeCorporation:
+		return a, b, graph.EdgeTypeCapital
+	case na.Type == graph.NodeTypeCorporation && nb.Type == graph.NodeTypeNation:
+		return b, a, graph.EdgeTypeCapital
+	case na.Type == graph.NodeTypeCorporation && nb.Type == graph.NodeTypeCorporation:
+		if l.shareIndustry(a, b) {
+			return a, b, graph.EdgeTypeCompetesWith
+		}
+		return a, b, graph.EdgeTypeTrade
+	}
+	return "", "", ""
+}
+
+// shareIndustry reports whether an industry lists both companies
+func (l *CoMentionLearner) shareIndustry(a, b string) bool {
+	industries := make(map[string]bool)
+	for _, e := range l.Graph.GetIncomingEdges(a) {
+		if e.Type == graph.EdgeTypeHasCompany {
+			industries[e.SourceID] = true
+		}
+	}
+	for _, e := range l.Graph.GetIncomingEdges(b) {
+		if e.Type == graph.EdgeTypeHasCompany && industries[e.SourceID] {
+			return true
+		}
+	}
+	return false
+}
+
+// create adds a proposal's edge unless the pair was linked meanwhile,
+// auditing it with the supporting items as evidence
+func (l *CoMentionLearner) create(p Proposal, how string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.Graph.Linked(p.Edge.SourceID, p.Edge.TargetID) {
+		return false
+	}
+	l.Graph.AddEdge(p.Edge)
+	auditEdge(audit.ActorCoMention, p.Edge, why{
+		reason:   fmt.Sprintf("%s from %d co-mentions (%s)", p.Edge.Type, len(p.Items), how),
+		evidence: coMentionEvidence(p.Items),
+	})
+	return true
+}
+
+// coMentionEvidence quotes the supporting items, newest first
+func coMentionEvidence(items []graph.CoMentionItem) string {
+	parts := make([]string, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		it := items[i]
+		part := fmt.Sprintf("[%s] %s", it.Source, it.Text)
+		if it.URL != "" {
+			part += " (" + it.URL + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,93 @@
+package discovery
+
+import (
+	"margraf/audit"
+	"margraf/graph"
+	"margraf/scraper"
+	"strings"
+)
+
+// why is the reason and evidence the audit log keeps for an addition
+type why struct {
+	reason   string
+	evidence string
+}
+
+// auditNode records that actor added n
+func auditNode(actor string, n *graph.Node, w why) {
+	audit.Log(audit.Record{
+		Actor:    actor,
+		Action:   audit.ActionAddNode,
+		NodeID:   n.ID,
+		Reason:   w.reason,
+		Evidence: w.evidence,
+	})
+}
+
+// auditEdge records that actor added e
+func auditEdge(actor string, e *graph.Edge, w why) {
+	audit.Log(audit.Record{
+		Actor:    actor,
+		Action:   audit.ActionAddEdge,
+		SourceID: e.SourceID,
+		TargetID: e.TargetID,
+		EdgeType: string(e.Type),
+		Reason:   w.reason,
+		Evidence: w.evidence,
+	})
+}
+
+// addNode adds n unless a node with its ID exists, auditing it as the
+// seeder's, and reports whether it was added
+func addNode(g *graph.Graph, n *graph.Node, w why) bool {
+	if _, exists := g.GetNode(n.ID); exists {
+		return false
+	}
+	g.AddNode(n)
+	auditNode(audit.ActorSeeder, n, w)
+	return true
+}
+
+// withOrigin records where e came from in its "origin" attribute, unless it
+// already names an origin (see graph.EdgeOrigin)
+func withOrigin(e *graph.Edge, origin string) *graph.Edge {
+	if _, ok := e.Attributes["origin"]; ok {
+		return e
+	}
+	if e.Attributes == nil {
+		e.Attributes = make(map[string]interface{}, 1)
+	}
+	e.Attributes["origin"] = origin
+	return e
+}
+
+// addEdgeOnce adds e unless an edge of the same type already joins its
+// endpoints, auditing it as the seeder's, and reports whether it was added.
+// An edge without an origin is recorded as an LLM suggestion.
+func addEdgeOnce(g *graph.Graph, e *graph.Edge, w why) bool {
+	for _, existing := range g.GetOutgoingEdges(e.SourceID) {
+		if existing.TargetID == e.TargetID && existing.Type == e.Type {
+			return false
+		}
+	}
+	g.AddEdge(withOrigin(e, graph.OriginLLM))
+	auditEdge(audit.ActorSeeder, e, w)
+	return true
+}
+
+// listEvidence quotes the list an LLM answered with
+func listEvidence(items []string) string {
+	return "LLM answer: " + strings.Join(items, ", ")
+}
+
+// snippetFor returns the first search result that mentions name, as
+// "title: snippet", or fallback when none does
+func snippetFor(name string, results []scraper.SearchResult, fallback string) string {
+	lower := strings.ToLower(name)
+	for _, r := range results {
+		if strings.Contains(strings.ToLower(r.Title+" "+r.Snippet), lower) {
+			return r.Title + ": " + r.Snippet
+		}
+	}
+	return fallback
+}
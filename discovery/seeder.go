@@ -1,14 +1,18 @@
 package discovery
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"margraf/audit"
 	"margraf/config"
 	"margraf/datasources"
 	"margraf/graph"
 	"margraf/llm"
 	"margraf/logger"
 	"margraf/scraper"
+	"margraf/syserr"
+	"margraf/task"
 	"strings"
 	"sync"
 )
@@ -19,8 +23,7 @@ type Seeder struct {
 	WebSearcher     *scraper.WebSearcher
 	ComtradeClient  *datasources.ComtradeClient
 	WorldBankClient *datasources.WorldBankClient
-	visited         map[string]bool
-	mu              sync.Mutex
+	WikidataClient  *datasources.WikidataClient
 }
 
 func NewSeeder(client *llm.Client) *Seeder {
@@ -30,12 +33,17 @@ func NewSeeder(client *llm.Client) *Seeder {
 		WebSearcher:     scraper.NewWebSearcher(),
 		ComtradeClient:  datasources.NewComtradeClient(),
 		WorldBankClient: datasources.NewWorldBankClient(),
-		visited:         make(map[string]bool),
+		WikidataClient:  datasources.NewWikidataClient(),
 	}
 }
 
-func (s *Seeder) Seed(g *graph.Graph) error {
+// Seed builds the graph from the top economies outward as one campaign.
+// Nodes a previous run fully explored are not expanded again. Cancelling ctx
+// stops discovery between steps; progress is reported to the task running
+// under ctx.
+func (s *Seeder) Seed(ctx context.Context, g *graph.Graph) error {
 	logger.Info(logger.StatusInit, "Starting Recursive Graph Discovery (Real Data + AI)...")
+	ctx = withCampaign(ctx)
 
 	if s.Client.ApiKey == "" {
 		return fmt.Errorf("GEMINI_API_KEY is not set. Cannot fetch live data")
@@ -43,35 +51,63 @@ func (s *Seeder) Seed(g *graph.Graph) error {
 
 	// 1. Start with major economies via Scraping
 	logger.InfoDepth(1, logger.StatusGlob, "[Root] Fetching Top Global Economies from Wikipedia...")
-	nations, err := s.MarketScraper.FetchTopNations(10)
+	nations, err := s.MarketScraper.FetchTopNations(ctx, 10)
 	if err != nil {
 		logger.WarnDepth(2, logger.StatusWarn, "Scraping failed (%v). Falling back to LLM...", err)
-		nations, err = s.fetchList("List the top 10 major global economies covering all continents. Return ONLY a JSON array of strings.")
+		syserr.Report(syserr.ModuleDiscovery, "fetch top nations", syserr.Warning(syserr.ModuleDiscovery, "fetch top nations", err))
+		nations, err = s.fetchList(ctx, "List the top 10 major global economies covering all continents. Return ONLY a JSON array of strings.")
 		if err != nil {
-			return fmt.Errorf("failed to fetch nations: %v", err)
+			return fmt.Errorf("failed to fetch nations: %w", err)
 		}
 	} else {
 		logger.SuccessDepth(2, "Scraped %d nations successfully", len(nations))
 	}
 
-	for _, name := range nations {
+	steps := len(nations) + 1 // Each nation, then cross-nation links and data sources
+	for i, name := range nations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		task.Report(ctx, i, steps, "Nation "+name)
+
 		// We start recursion at depth 0
-		if err := s.ProcessNation(g, name, 0); err != nil {
+		if err := s.ProcessNation(ctx, g, name, 0); err != nil {
 			fmt.Printf("Error processing nation %s: %v\n", name, err)
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	task.Report(ctx, len(nations), steps, "Trade links and data sources")
+
 	// 3. Discover Relationships (Cross-Nation Trade) - Simplified for now, usually part of deeper logic
 	// We can try to find major trade partners for the top nations found.
 	// For this prototype, we will do a targeted discovery for the first few nations to link them.
 	if len(nations) > 1 {
-		s.discoverTradeLinks(g, nations)
+		s.discoverTradeLinks(ctx, g, nations)
+	}
+
+	// Link trade flows to the canals, straits and ports they depend on
+	if routed := g.RouteTradeThroughChokepoints(); routed > 0 {
+		logger.Success("Routed trade through chokepoints (%d links)", routed)
+	}
+
+	// Link nations and companies to their currencies and central banks
+	if linked := g.LinkCurrencies(); linked > 0 {
+		logger.Success("Linked currencies (%d links)", linked)
+	}
+
+	// 4. Custom connectors registered via datasources.Register
+	if sources := datasources.Registered(); len(sources) > 0 {
+		logger.Info(logger.StatusData, "Running %d custom data sources...", len(sources))
+		datasources.ApplyRegistered(ctx, g)
 	}
 
 	return nil
 }
 
-func (s *Seeder) discoverTradeLinks(g *graph.Graph, nations []string) {
+func (s *Seeder) discoverTradeLinks(ctx context.Context, g *graph.Graph, nations []string) {
 	logger.Info(logger.StatusLink, "Discovering Major Trade Relationships (UN Comtrade + World Bank)...")
 
 	// Limit to first 5 to avoid N^2 explosion and API rate limits
@@ -83,10 +119,18 @@ func (s *Seeder) discoverTradeLinks(g *graph.Graph, nations []string) {
 	targetNations := nations[:limit]
 	year := "2023" // Most recent complete year
 
+	// Commodity nodes and trade edges are added in batches
+	batch := g.NewBatch(0)
+	defer batch.Flush()
+
 	// Strategy 1: Use UN Comtrade for REAL bilateral trade data
 	for _, nation1 := range targetNations {
+		if ctx.Err() != nil {
+			return
+		}
+
 		// Get country code
-		code1, ok := datasources.GetCountryCode(strings.ToLower(nation1))
+		code1, ok := datasources.GetCountryCode(nation1)
 		if !ok {
 			logger.WarnDepth(1, logger.StatusWarn, "No ISO code for %s, skipping Comtrade lookup", nation1)
 			continue
@@ -94,21 +138,17 @@ func (s *Seeder) discoverTradeLinks(g *graph.Graph, nations []string) {
 
 		// Get economic profile from World Bank
 		logger.InfoDepth(1, logger.StatusData, "Fetching economic data for %s from World Bank...", nation1)
-		profile, err := s.WorldBankClient.GetEconomicProfile(code1, year)
+		profile, err := s.WorldBankClient.GetEconomicProfile(ctx, code1, year)
 		if err == nil && profile.GDP > 0 {
 			// Store economic data in node attributes
-			if node, ok := g.GetNode(cleanID(nation1)); ok {
-				node.Attributes["gdp"] = profile.GDP
-				node.Attributes["exports"] = profile.Exports
-				node.Attributes["imports"] = profile.Imports
-				node.Attributes["fdi"] = profile.FDI
+			if err := g.UpdateNodeAttributes(cleanID(nation1), profile.Attributes(), "worldbank_"+year); err == nil {
 				logger.SuccessDepth(2, "GDP: $%.2fB, Exports: $%.2fB", profile.GDP/1e9, profile.Exports/1e9)
 			}
 		}
 
 		// Get top exports from Comtrade
 		logger.InfoDepth(1, logger.StatusGlob, "Fetching trade data for %s from UN Comtrade...", nation1)
-		topExports, err := s.ComtradeClient.GetTopExports(code1, year, 5)
+		topExports, err := s.ComtradeClient.GetTopExports(ctx, code1, year, 5)
 		if err != nil {
 			logger.WarnDepth(2, logger.StatusWarn, "Comtrade error: %v", err)
 			continue
@@ -122,15 +162,17 @@ func (s *Seeder) discoverTradeLinks(g *graph.Graph, nations []string) {
 
 			// Add commodity node if it doesn't exist
 			commodityID := cleanID(trade.CommodityDesc)
-			if _, exists := g.GetNode(commodityID); !exists {
-				g.AddNode(&graph.Node{
-					ID:   commodityID,
-					Type: graph.NodeTypeRawMaterial,
-					Name: trade.CommodityDesc,
-					Attributes: map[string]interface{}{
-						"hs_code": trade.CommodityCode,
-					},
-				})
+			exportEvidence := fmt.Sprintf("UN Comtrade %s: %s exports of HS %s %s worth $%.2fB", year, nation1, trade.CommodityCode, trade.CommodityDesc, trade.PrimaryValue/1e9)
+			commodity := &graph.Node{
+				ID:   commodityID,
+				Type: graph.NodeTypeRawMaterial,
+				Name: trade.CommodityDesc,
+				Attributes: map[string]interface{}{
+					"hs_code": trade.CommodityCode,
+				},
+			}
+			if batch.AddNode(commodity) {
+				auditNode(audit.ActorSeeder, commodity, why{"top export of " + nation1, exportEvidence})
 			}
 
 			// Create PRODUCES edge with real trade value as weight
@@ -140,12 +182,18 @@ func (s *Seeder) discoverTradeLinks(g *graph.Graph, nations []string) {
 				weight = 1.0
 			}
 
-			g.AddEdge(&graph.Edge{
+			produces := &graph.Edge{
 				SourceID: cleanID(nation1),
 				TargetID: commodityID,
 				Type:     graph.EdgeTypeProduces,
 				Weight:   weight,
-			})
+				Attributes: map[string]interface{}{
+					"origin":      graph.OriginTradeData,
+					"trade_value": trade.PrimaryValue,
+				},
+			}
+			batch.AddEdge(produces)
+			auditEdge(audit.ActorSeeder, produces, why{"top export", exportEvidence})
 
 			logger.SuccessDepth(2, "%s exports %s ($%.2fB, weight=%.2f)",
 				nation1, trade.CommodityDesc, trade.PrimaryValue/1e9, weight)
@@ -153,50 +201,77 @@ func (s *Seeder) discoverTradeLinks(g *graph.Graph, nations []string) {
 
 		// Check bilateral trade with other nations in the list
 		for _, nation2 := range targetNations {
-			if nation1 == nation2 {
+			if nation1 == nation2 || ctx.Err() != nil {
 				continue
 			}
 
-			code2, ok := datasources.GetCountryCode(strings.ToLower(nation2))
+			code2, ok := datasources.GetCountryCode(nation2)
 			if !ok {
 				continue
 			}
 
-			// Get bilateral trade
-			bilateralTrade, err := s.ComtradeClient.GetBilateralTrade(code1, code2, year)
+			// Get bilateral trade broken down by HS chapter
+			bilateralTrade, err := s.ComtradeClient.GetBilateralTradeByCommodity(ctx, code1, code2, year)
 			if err != nil {
 				continue
 			}
 
+			srcID := cleanID(nation1)
+			tgtID := cleanID(nation2)
+
+			if !batch.HasNode(srcID) || !batch.HasNode(tgtID) {
+				continue
+			}
+
 			// Sum up total bilateral trade value
 			totalValue := 0.0
 			for _, trade := range bilateralTrade {
 				totalValue += trade.PrimaryValue
 			}
 
-			if totalValue > 5e9 { // Only create edges for significant trade (>$5B)
-				srcID := cleanID(nation1)
-				tgtID := cleanID(nation2)
-
-				if _, ok := g.GetNode(srcID); !ok {
-					continue
-				}
-				if _, ok := g.GetNode(tgtID); !ok {
+			// Per-commodity Trade edges so shocks can be routed by HS code
+			for _, trade := range bilateralTrade {
+				if trade.PrimaryValue < 1e9 || trade.CommodityCode == "" { // Skip commodity flows < $1B
 					continue
 				}
 
-				// Normalize weight
-				weight := 0.3 + (0.5 * (totalValue / 1e11))
-				if weight > 1.0 {
-					weight = 1.0
+				weight := datasources.CommodityTradeWeight(trade.PrimaryValue)
+
+				flow := &graph.Edge{
+					SourceID: srcID,
+					TargetID: tgtID,
+					Type:     graph.EdgeTypeTrade,
+					Weight:   weight,
+					Attributes: map[string]interface{}{
+						"origin":      graph.OriginTradeData,
+						"hs_code":     trade.CommodityCode,
+						"commodity":   trade.CommodityDesc,
+						"trade_value": trade.PrimaryValue,
+						"year":        year,
+					},
 				}
+				batch.AddEdge(flow)
+				auditEdge(audit.ActorSeeder, flow, why{"bilateral commodity trade", fmt.Sprintf("UN Comtrade %s: %s -> %s HS %s %s worth $%.2fB", year, nation1, nation2, trade.CommodityCode, trade.CommodityDesc, trade.PrimaryValue/1e9)})
+
+				logger.SuccessDepth(2, "%s -> %s: %s $%.2fB (weight=%.2f)", nation1, nation2, trade.CommodityDesc, trade.PrimaryValue/1e9, weight)
+			}
 
-				g.AddEdge(&graph.Edge{
+			if totalValue > 5e9 { // Only create edges for significant trade (>$5B)
+				weight := datasources.TradeWeight(totalValue)
+
+				total := &graph.Edge{
 					SourceID: srcID,
 					TargetID: tgtID,
 					Type:     graph.EdgeTypeTrade,
 					Weight:   weight,
-				})
+					Attributes: map[string]interface{}{
+						"origin":      graph.OriginTradeData,
+						"trade_value": totalValue,
+						"year":        year,
+					},
+				}
+				batch.AddEdge(total)
+				auditEdge(audit.ActorSeeder, total, why{"bilateral trade above $5B", fmt.Sprintf("UN Comtrade %s: %s -> %s total $%.2fB", year, nation1, nation2, totalValue/1e9)})
 
 				logger.SuccessDepth(1, "%s -> %s: $%.2fB trade (weight=%.2f)", nation1, nation2, totalValue/1e9, weight)
 			}
@@ -206,11 +281,11 @@ func (s *Seeder) discoverTradeLinks(g *graph.Graph, nations []string) {
 	logger.SuccessDepth(1, "Trade discovery complete with real UN Comtrade + World Bank data")
 }
 
-func (s *Seeder) validateRelationship(source, target, product string) (bool, error) {
+func (s *Seeder) validateRelationship(ctx context.Context, source, target, product string) (bool, error) {
 	logger.InfoDepth(2, logger.StatusChk, "Validating: %s exports %s to %s", source, product, target)
 	query := fmt.Sprintf("Does %s export %s to %s", source, product, target)
 
-	results, err := s.WebSearcher.Search(query)
+	results, err := s.WebSearcher.Search(ctx, query)
 	if err != nil {
 		// Silently trust if search fails - no need to warn
 		return true, nil
@@ -249,53 +324,74 @@ func (s *Seeder) validateRelationship(source, target, product string) (bool, err
 	return false, nil
 }
 
-// ProcessNation adds a nation, finds its industries
-func (s *Seeder) ProcessNation(g *graph.Graph, name string, depth int) error {
+// ProcessNation adds a nation, finds its industries. A nation already
+// expanded in this campaign or fully explored in an earlier one is skipped.
+func (s *Seeder) ProcessNation(ctx context.Context, g *graph.Graph, name string, depth int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	id := cleanID(name)
 
-	if s.isVisited(id) {
+	ctx, visited := campaignFrom(ctx)
+	if explored(g, id) || !visited.claim(id) {
 		return nil
 	}
-	s.markVisited(id)
 
 	// 1. Add Nation Node
-	if valid, _ := s.validateEntity(name, "Nation"); valid {
-		g.AddNode(&graph.Node{ID: id, Type: graph.NodeTypeNation, Name: name})
+	if _, exists := g.GetNode(id); !exists {
+		if valid, _ := s.validateEntity(ctx, name, "Nation"); !valid {
+			return nil // Skip if invalid
+		}
+		addNode(g, &graph.Node{ID: id, Type: graph.NodeTypeNation, Name: name}, why{"nation to explore", "LLM validated " + name + " as a nation"})
+		if info, ok := graph.LookupCountry(name); ok {
+			g.UpdateNodeAttributes(id, graph.LocationAttributes(info.Lat, info.Lon, info.Name), "geo")
+		}
 		logger.InfoDepth(depth, logger.StatusNat, "Added Nation: %s", name)
-	} else {
-		return nil // Skip if invalid
 	}
+	g.SetExploration(id, graph.ExplorationPartial)
 
 	// 2. Find Industries (Expanded sectors)
 	prompt := fmt.Sprintf("List the top %d major industries driving the economy of %s. Ensure to cover diverse sectors like Agriculture, Manufacturing, Tech, Finance, and Energy. Return ONLY a JSON array of strings.", config.Global.Scraping.BranchingLimit, name)
-	industries, err := s.fetchList(prompt)
+	industries, err := s.fetchList(ctx, prompt)
 	if err != nil {
 		return err
 	}
 
 	for _, ind := range industries {
-		if err := s.processIndustry(g, ind, name, depth); err != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.processIndustry(ctx, g, ind, name, depth); err != nil {
 			fmt.Printf("    Error processing industry %s: %v\n", ind, err)
 		}
 	}
 
+	g.SetExploration(id, graph.ExplorationComplete)
 	return nil
 }
 
 // processIndustry adds industry, links to nation, finds companies and raw materials
-func (s *Seeder) processIndustry(g *graph.Graph, industryName, nationName string, depth int) error {
+func (s *Seeder) processIndustry(ctx context.Context, g *graph.Graph, industryName, nationName string, depth int) error {
 	indID := cleanID(nationName + "_" + industryName)
 	nationID := cleanID(nationName)
 
 	// Add Industry Node
-	g.AddNode(&graph.Node{ID: indID, Type: graph.NodeTypeIndustry, Name: industryName})
-	g.AddEdge(&graph.Edge{SourceID: nationID, TargetID: indID, Type: graph.EdgeTypeHasIndustry, Weight: 1.0})
-	logger.InfoDepth(2, logger.StatusInd, "Added Industry: %s (in %s)", industryName, nationName)
+	industryWhy := why{"major industry of " + nationName, "LLM listed " + industryName + " among the industries of " + nationName}
+	if addNode(g, &graph.Node{ID: indID, Type: graph.NodeTypeIndustry, Name: industryName}, industryWhy) {
+		logger.InfoDepth(2, logger.StatusInd, "Added Industry: %s (in %s)", industryName, nationName)
+	}
+	addEdgeOnce(g, &graph.Edge{SourceID: nationID, TargetID: indID, Type: graph.EdgeTypeHasIndustry, Weight: 1.0}, industryWhy)
+
+	ctx, visited := campaignFrom(ctx)
+	if explored(g, indID) || !visited.claim(indID) {
+		return nil
+	}
+	g.SetExploration(indID, graph.ExplorationPartial)
 
 	// 1. Find Major Companies (RAG: Search + LLM Extraction)
 	logger.InfoDepth(3, logger.StatusChk, "Finding companies in '%s' (%s)...", industryName, nationName)
 	searchQuery := fmt.Sprintf("Largest %s companies in %s market cap", industryName, nationName)
-	searchResults, err := s.WebSearcher.Search(searchQuery)
+	searchResults, err := s.WebSearcher.Search(ctx, searchQuery)
 
 	var companies []string
 	searchSucceeded := false
@@ -315,7 +411,7 @@ Search Results:
 Return ONLY a JSON array of strings, e.g. ["Company A", "Company B"].
 `, config.Global.Scraping.BranchingLimit, industryName, nationName, contextBuilder.String())
 
-		companies, _ = s.fetchList(ragPrompt)
+		companies, _ = s.fetchList(ctx, ragPrompt)
 		if len(companies) > 0 {
 			searchSucceeded = true
 			logger.InfoDepth(3, logger.StatusOK, "Found %d companies via web search", len(companies))
@@ -329,61 +425,95 @@ Return ONLY a JSON array of strings, e.g. ["Company A", "Company B"].
 			logger.InfoDepth(3, logger.StatusChk, "Using LLM knowledge base for companies...")
 		}
 		cPrompt := fmt.Sprintf("List %d largest companies by market cap in the %s industry in %s. Return ONLY a JSON array of strings.", config.Global.Scraping.BranchingLimit, industryName, nationName)
-		companies, _ = s.fetchList(cPrompt)
+		companies, _ = s.fetchList(ctx, cPrompt)
 	}
 
+	// The relation searches run alongside the materials below; the task's
+	// ctx ends when this returns, so it waits for them first
+	var relations sync.WaitGroup
+	defer relations.Wait()
 	for _, comp := range companies {
 		compID := cleanID(comp)
-		g.AddNode(&graph.Node{ID: compID, Type: graph.NodeTypeCorporation, Name: comp})
-		g.AddEdge(&graph.Edge{SourceID: indID, TargetID: compID, Type: graph.EdgeTypeHasCompany, Weight: 1.0})
-		logger.InfoDepth(3, logger.StatusCor, "Added Company: %s", comp)
+		compWhy := why{fmt.Sprintf("large %s company in %s", industryName, nationName), listEvidence(companies)}
+		if searchSucceeded {
+			compWhy.evidence = snippetFor(comp, searchResults, compWhy.evidence)
+		}
+		if addNode(g, &graph.Node{ID: compID, Type: graph.NodeTypeCorporation, Name: comp}, compWhy) {
+			logger.InfoDepth(3, logger.StatusCor, "Added Company: %s", comp)
+		}
+		addEdgeOnce(g, &graph.Edge{SourceID: indID, TargetID: compID, Type: graph.EdgeTypeHasCompany, Weight: 1.0}, compWhy)
 
 		// Discover supplier/client relationships for this company
-		go s.discoverCompanyRelations(g, comp, compID, industryName, depth)
+		relations.Add(1)
+		go func() {
+			defer relations.Done()
+			s.discoverCompanyRelations(ctx, g, comp, compID, industryName, depth)
+		}()
 	}
 
 	// 2. Find Raw Materials
 	mPrompt := fmt.Sprintf("List %d key raw materials or commodities required for the %s industry. Return ONLY a JSON array of strings.", config.Global.Scraping.BranchingLimit, industryName)
-	materials, _ := s.fetchList(mPrompt)
+	materials, _ := s.fetchList(ctx, mPrompt)
 	for _, mat := range materials {
-		if err := s.processMaterial(g, mat, indID, depth); err != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.processMaterial(ctx, g, mat, indID, depth); err != nil {
 			fmt.Printf("      Error processing material %s: %v\n", mat, err)
 		}
 	}
 
+	g.SetExploration(indID, graph.ExplorationComplete)
 	return nil
 }
 
-// processMaterial adds material, links to industry, finds top producers (recursion)
-func (s *Seeder) processMaterial(g *graph.Graph, matName, industryNodeID string, depth int) error {
+// processMaterial adds material, links to industry (if industryNodeID is
+// set), finds top producers (recursion)
+func (s *Seeder) processMaterial(ctx context.Context, g *graph.Graph, matName, industryNodeID string, depth int) error {
 	matID := cleanID(matName)
 
 	// Add Material Node (idempotent check done by AddNode usually, but we might want to ensure it exists)
-	if _, exists := g.GetNode(matID); !exists {
-		g.AddNode(&graph.Node{ID: matID, Type: graph.NodeTypeRawMaterial, Name: matName})
+	materialWhy := why{"raw material to explore", "material " + matName}
+	if industryNodeID != "" {
+		materialWhy.reason = "raw material required by " + industryNodeID
+	}
+	if addNode(g, &graph.Node{ID: matID, Type: graph.NodeTypeRawMaterial, Name: matName}, materialWhy) {
 		logger.InfoDepth(3, logger.StatusMat, "Added Material: %s", matName)
 	}
 
 	// Link Industry -> Requires -> Material
-	g.AddEdge(&graph.Edge{SourceID: industryNodeID, TargetID: matID, Type: graph.EdgeTypeRequires, Weight: 1.0})
+	if industryNodeID != "" {
+		addEdgeOnce(g, &graph.Edge{SourceID: industryNodeID, TargetID: matID, Type: graph.EdgeTypeRequires, Weight: 1.0}, materialWhy)
+	}
 
-	// RECURSION CHECK
+	// RECURSION CHECK (deeper materials stay unexplored for a later campaign)
 	if depth >= config.Global.Scraping.SearchDepth {
 		return nil
 	}
+	ctx, visited := campaignFrom(ctx)
+	if explored(g, matID) || !visited.claim(matID) {
+		return nil
+	}
+	g.SetExploration(matID, graph.ExplorationPartial)
 
 	// Find Producer Nations
 	pPrompt := fmt.Sprintf("List top %d countries that produce %s. Return ONLY a JSON array of strings.", config.Global.Scraping.BranchingLimit, matName)
-	producers, _ := s.fetchList(pPrompt)
+	producers, err := s.fetchList(ctx, pPrompt)
+	if err != nil {
+		return err
+	}
 
 	for _, producerName := range producers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		prodID := cleanID(producerName)
 
 		// Recursively process this nation
-		// We rely on s.visited to stop infinite loops if we've already seen this nation
-		if !s.isVisited(prodID) {
+		// The campaign's visited set and the nation's exploration state stop infinite loops
+		if !visited.seen(prodID) && !explored(g, prodID) {
 			logger.InfoDepth(4, logger.StatusRec, "Discovered Producer: %s (Recursing...)", producerName)
-			if err := s.ProcessNation(g, producerName, depth+1); err != nil {
+			if err := s.ProcessNation(ctx, g, producerName, depth+1); err != nil {
 				fmt.Printf("Error recursing nation %s: %v\n", producerName, err)
 			}
 		}
@@ -391,35 +521,24 @@ func (s *Seeder) processMaterial(g *graph.Graph, matName, industryNodeID string,
 		// Link Producer -> Produces -> Material
 		// (Even if nation was already visited, we establish the link)
 		if _, ok := g.GetNode(prodID); ok {
-			g.AddEdge(&graph.Edge{SourceID: prodID, TargetID: matID, Type: graph.EdgeTypeProduces, Weight: 1.0})
+			addEdgeOnce(g, &graph.Edge{SourceID: prodID, TargetID: matID, Type: graph.EdgeTypeProduces, Weight: 1.0}, why{"top producer of " + matName, listEvidence(producers)})
 			logger.InfoDepth(4, logger.StatusLink, "Link: %s -> Produces -> %s", producerName, matName)
 		}
 	}
 
+	g.SetExploration(matID, graph.ExplorationComplete)
 	return nil
 }
 
 // Helpers
 
-func (s *Seeder) isVisited(id string) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.visited[id]
-}
-
-func (s *Seeder) markVisited(id string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.visited[id] = true
-}
-
-func (s *Seeder) fetchList(prompt string) ([]string, error) {
-	resp, err := s.Client.Complete(prompt)
+func (s *Seeder) fetchList(ctx context.Context, prompt string) ([]string, error) {
+	resp, err := s.Client.Complete(llm.WithConsumer(ctx, llm.ConsumerSeeder), prompt)
 	if err != nil {
 		return nil, err
 	}
 
-	cleaned := cleanJSON(resp)
+	cleaned := llm.CleanJSON(resp)
 	var list []string
 	if err := json.Unmarshal([]byte(cleaned), &list); err != nil {
 		// Try to parse simplified list if JSON fails or if LLM returned bullets
@@ -435,13 +554,13 @@ type edgeDTO struct {
 	Weight  float64 `json:"weight"`
 }
 
-func (s *Seeder) fetchEdges(prompt string) ([]edgeDTO, error) {
-	resp, err := s.Client.Complete(prompt)
+func (s *Seeder) fetchEdges(ctx context.Context, prompt string) ([]edgeDTO, error) {
+	resp, err := s.Client.Complete(llm.WithConsumer(ctx, llm.ConsumerSeeder), prompt)
 	if err != nil {
 		return nil, err
 	}
 
-	cleaned := cleanJSON(resp)
+	cleaned := llm.CleanJSON(resp)
 	var list []edgeDTO
 	if err := json.Unmarshal([]byte(cleaned), &list); err != nil {
 		return nil, fmt.Errorf("json parse error: %v | raw: %s", err, resp)
@@ -449,12 +568,12 @@ func (s *Seeder) fetchEdges(prompt string) ([]edgeDTO, error) {
 	return list, nil
 }
 
-func (s *Seeder) validateEntity(name, category string) (bool, error) {
+func (s *Seeder) validateEntity(ctx context.Context, name, category string) (bool, error) {
 	// Real Web Validation
 	logger.InfoDepth(2, logger.StatusChk, "Validating '%s'...", name)
 
 	query := fmt.Sprintf("%s %s wikipedia", name, category)
-	results, err := s.WebSearcher.Search(query)
+	results, err := s.WebSearcher.Search(ctx, query)
 	if err != nil {
 		// Silently assume valid if search fails
 		return true, nil
@@ -487,15 +606,6 @@ func (s *Seeder) validateEntity(name, category string) (bool, error) {
 	return false, nil
 }
 
-// Reuse existing clean helpers
-func cleanJSON(s string) string {
-	s = strings.TrimSpace(s)
-	s = strings.TrimPrefix(s, "```json")
-	s = strings.TrimPrefix(s, "```")
-	s = strings.TrimSuffix(s, "```")
-	return strings.TrimSpace(s)
-}
-
 func cleanID(s string) string {
 	return strings.ToLower(strings.ReplaceAll(s, " ", "_"))
 }
@@ -591,11 +701,16 @@ func contains(slice []string, item string) bool {
 }
 
 // discoverCompanyRelations discovers and adds supplier/client relationships for a company
-func (s *Seeder) discoverCompanyRelations(g *graph.Graph, companyName, companyID, industryName string, depth int) {
-	// Don't go too deep to avoid infinite recursion
-	if depth > config.Global.Scraping.SearchDepth {
+func (s *Seeder) discoverCompanyRelations(ctx context.Context, g *graph.Graph, companyName, companyID, industryName string, depth int) {
+	// Don't go too deep to avoid infinite recursion, or keep going after cancellation
+	if depth > config.Global.Scraping.SearchDepth || ctx.Err() != nil {
+		return
+	}
+	ctx, visited := campaignFrom(ctx)
+	if explored(g, companyID) || !visited.claim(companyID) {
 		return
 	}
+	g.SetExploration(companyID, graph.ExplorationPartial)
 
 	logger.InfoDepth(4, logger.StatusChk, "Discovering relations for %s...", companyName)
 
@@ -606,7 +721,7 @@ func (s *Seeder) discoverCompanyRelations(g *graph.Graph, companyName, companyID
 
 	// Strategy 1: Web search for supplier relationships
 	suppliersQuery := fmt.Sprintf("%s suppliers major partners procurement", companyName)
-	suppliersResults, err := s.WebSearcher.Search(suppliersQuery)
+	suppliersResults, err := s.WebSearcher.Search(ctx, suppliersQuery)
 
 	if err == nil && len(suppliersResults) > 0 {
 		// Extract company names from search results
@@ -620,7 +735,7 @@ func (s *Seeder) discoverCompanyRelations(g *graph.Graph, companyName, companyID
 
 	// Strategy 2: Web search for client/customer relationships
 	clientsQuery := fmt.Sprintf("%s customers clients major contracts partnerships", companyName)
-	clientsResults, err := s.WebSearcher.Search(clientsQuery)
+	clientsResults, err := s.WebSearcher.Search(ctx, clientsQuery)
 
 	if err == nil && len(clientsResults) > 0 {
 		// Extract company names from search results
@@ -632,6 +747,10 @@ func (s *Seeder) discoverCompanyRelations(g *graph.Graph, companyName, companyID
 		}
 	}
 
+	if ctx.Err() != nil {
+		return
+	}
+
 	// Strategy 3: Use LLM with search context as RAG to supplement findings
 	logger.InfoDepth(4, logger.StatusChk, "Analyzing with LLM for additional relations...")
 
@@ -670,9 +789,9 @@ Return ONLY a JSON object in this format:
 Include all companies explicitly mentioned in the search results. Return empty arrays if no clear relationships are found.
 `, companyName, industryName, contextBuilder.String())
 
-	resp, err := s.Client.Complete(prompt)
+	resp, err := s.Client.Complete(llm.WithConsumer(ctx, llm.ConsumerSeeder), prompt)
 	if err == nil {
-		cleaned := cleanJSON(resp)
+		cleaned := llm.CleanJSON(resp)
 
 		var llmRelations struct {
 			Suppliers []string `json:"suppliers"`
@@ -703,34 +822,34 @@ Include all companies explicitly mentioned in the search results. Return empty a
 		supplierID := cleanID(supplier)
 
 		// Add supplier node if it doesn't exist
-		if _, exists := g.GetNode(supplierID); !exists {
-			g.AddNode(&graph.Node{
-				ID:   supplierID,
-				Type: graph.NodeTypeCorporation,
-				Name: supplier,
-			})
+		supplierWhy := why{"supplier of " + companyName, snippetFor(supplier, suppliersResults, "LLM extraction from web search results")}
+		if addNode(g, &graph.Node{
+			ID:   supplierID,
+			Type: graph.NodeTypeCorporation,
+			Name: supplier,
+		}, supplierWhy) {
 			logger.InfoDepth(4, logger.StatusNew, "Added supplier: %s", supplier)
 		}
 
 		// Add Supplies edge (supplier -> company)
-		g.AddEdge(&graph.Edge{
+		addEdgeOnce(g, &graph.Edge{
 			SourceID:       supplierID,
 			TargetID:       companyID,
 			Type:           graph.EdgeTypeSupplies,
 			Weight:         0.7,
 			Status:         "Active",
 			Directionality: graph.DirectionalityUnidirectional,
-		})
+		}, supplierWhy)
 
 		// Add ProcuresFrom edge (company -> supplier)
-		g.AddEdge(&graph.Edge{
+		addEdgeOnce(g, &graph.Edge{
 			SourceID:       companyID,
 			TargetID:       supplierID,
 			Type:           graph.EdgeTypeProcuresFrom,
 			Weight:         0.7,
 			Status:         "Active",
 			Directionality: graph.DirectionalityReverse,
-		})
+		}, supplierWhy)
 
 		logger.SuccessDepth(4, "%s ← supplies ← %s", companyName, supplier)
 	}
@@ -744,40 +863,174 @@ Include all companies explicitly mentioned in the search results. Return empty a
 		clientID := cleanID(client)
 
 		// Add client node if it doesn't exist
-		if _, exists := g.GetNode(clientID); !exists {
-			g.AddNode(&graph.Node{
-				ID:   clientID,
-				Type: graph.NodeTypeCorporation,
-				Name: client,
-			})
+		clientWhy := why{"client of " + companyName, snippetFor(client, clientsResults, "LLM extraction from web search results")}
+		if addNode(g, &graph.Node{
+			ID:   clientID,
+			Type: graph.NodeTypeCorporation,
+			Name: client,
+		}, clientWhy) {
 			logger.InfoDepth(4, logger.StatusNew, "Added client: %s", client)
 		}
 
 		// Add Supplies edge (company -> client)
-		g.AddEdge(&graph.Edge{
+		addEdgeOnce(g, &graph.Edge{
 			SourceID:       companyID,
 			TargetID:       clientID,
 			Type:           graph.EdgeTypeSupplies,
 			Weight:         0.7,
 			Status:         "Active",
 			Directionality: graph.DirectionalityUnidirectional,
-		})
+		}, clientWhy)
 
 		// Add ProcuresFrom edge (client -> company)
-		g.AddEdge(&graph.Edge{
+		addEdgeOnce(g, &graph.Edge{
 			SourceID:       clientID,
 			TargetID:       companyID,
 			Type:           graph.EdgeTypeProcuresFrom,
 			Weight:         0.7,
 			Status:         "Active",
 			Directionality: graph.DirectionalityReverse,
-		})
+		}, clientWhy)
 
 		logger.SuccessDepth(4, "%s → supplies → %s", companyName, client)
 	}
 
+	if ctx.Err() != nil {
+		return
+	}
+	s.locateCompany(ctx, g, companyName, companyID)
+
 	relationCount := len(relations.Suppliers) + len(relations.Clients)
+	relationCount += s.discoverOwnership(ctx, g, companyName, companyID)
 	if relationCount > 0 {
 		logger.SuccessDepth(4, "Discovered %d relations for %s", relationCount, companyName)
 	}
+	if ctx.Err() == nil {
+		g.SetExploration(companyID, graph.ExplorationComplete)
+	}
+}
+
+// locateCompany sets a company's headquarters coordinates and country from
+// Wikidata. Companies that can't be located inherit their nation's location
+// for region shocks.
+func (s *Seeder) locateCompany(ctx context.Context, g *graph.Graph, companyName, companyID string) {
+	hq, err := s.WikidataClient.GetHeadquarters(ctx, companyName)
+	if err != nil {
+		return
+	}
+	g.UpdateNodeAttributes(companyID, graph.LocationAttributes(hq.Lat, hq.Lon, hq.Country), "wikidata_"+hq.EntityID)
+	logger.InfoDepth(4, logger.StatusOK, "Located %s in %s", companyName, hq.Country)
+}
+
+// discoverOwnership links a company to its parents and subsidiaries so shocks
+// propagate through conglomerate structures. Wikidata is tried first; the LLM
+// fills in when Wikidata has no entity or no ownership statements.
+// Returns the number of ownership links added.
+func (s *Seeder) discoverOwnership(ctx context.Context, g *graph.Graph, companyName, companyID string) int {
+	var parents, subsidiaries []string
+	evidence := "LLM ownership answer"
+
+	ownership, err := s.WikidataClient.GetOwnership(ctx, companyName)
+	if err == nil {
+		parents, subsidiaries = ownership.Parents, ownership.Subsidiaries
+		if len(parents)+len(subsidiaries) > 0 {
+			logger.InfoDepth(4, logger.StatusOK, "Found %d parents, %d subsidiaries via Wikidata (%s)",
+				len(parents), len(subsidiaries), ownership.EntityID)
+			evidence = "Wikidata " + ownership.EntityID
+		}
+	} else {
+		logger.InfoDepth(4, logger.StatusWarn, "Wikidata lookup failed for %s: %v", companyName, err)
+	}
+
+	if len(parents)+len(subsidiaries) == 0 {
+		prompt := fmt.Sprintf(`
+List the corporate ownership structure of "%s".
+
+Return ONLY a JSON object in this format:
+{
+  "parents": ["Parent Company"],
+  "subsidiaries": ["Subsidiary 1", "Subsidiary 2", ...]
+}
+
+Parents are companies that own a controlling stake in %s. Subsidiaries are companies %s controls.
+Return empty arrays if the company is independent or you are not sure.
+`, companyName, companyName, companyName)
+
+		resp, err := s.Client.Complete(llm.WithConsumer(ctx, llm.ConsumerSeeder), prompt)
+		if err == nil {
+			var llmOwnership struct {
+				Parents      []string `json:"parents"`
+				Subsidiaries []string `json:"subsidiaries"`
+			}
+			if err := json.Unmarshal([]byte(llm.CleanJSON(resp)), &llmOwnership); err == nil {
+				parents, subsidiaries = llmOwnership.Parents, llmOwnership.Subsidiaries
+			}
+		}
+	}
+
+	added := 0
+
+	for _, parent := range parents {
+		parentID := cleanID(parent)
+		if parent == "" || parentID == companyID {
+			continue
+		}
+		s.addOwnershipEdges(g, parentID, parent, companyID, companyName, evidence)
+		logger.SuccessDepth(4, "%s ← owned by ← %s", companyName, parent)
+		added++
+	}
+
+	for _, sub := range subsidiaries {
+		subID := cleanID(sub)
+		if sub == "" || subID == companyID {
+			continue
+		}
+		s.addOwnershipEdges(g, companyID, companyName, subID, sub, evidence)
+		logger.SuccessDepth(4, "%s → owns → %s", companyName, sub)
+		added++
+	}
+
+	return added
+}
+
+// addOwnershipEdges adds the Owns/SubsidiaryOf pair between parent and
+// subsidiary, creating whichever corporation node is missing. evidence names
+// where the ownership came from (Wikidata entity or LLM).
+func (s *Seeder) addOwnershipEdges(g *graph.Graph, parentID, parentName, subID, subName, evidence string) {
+	ownershipWhy := why{parentName + " owns " + subName, evidence}
+	origin := graph.OriginLLM
+	if strings.HasPrefix(evidence, "Wikidata") {
+		origin = graph.OriginWikidata
+	}
+	for _, n := range []struct{ id, name string }{{parentID, parentName}, {subID, subName}} {
+		if addNode(g, &graph.Node{
+			ID:   n.id,
+			Type: graph.NodeTypeCorporation,
+			Name: n.name,
+		}, ownershipWhy) {
+			logger.InfoDepth(4, logger.StatusNew, "Added company: %s", n.name)
+		}
+	}
+
+	// Add Owns edge (parent -> subsidiary)
+	addEdgeOnce(g, &graph.Edge{
+		SourceID:       parentID,
+		TargetID:       subID,
+		Type:           graph.EdgeTypeOwns,
+		Weight:         0.8,
+		Status:         "Active",
+		Directionality: graph.DirectionalityUnidirectional,
+		Attributes:     map[string]interface{}{"origin": origin},
+	}, ownershipWhy)
+
+	// Add SubsidiaryOf edge (subsidiary -> parent)
+	addEdgeOnce(g, &graph.Edge{
+		SourceID:       subID,
+		TargetID:       parentID,
+		Type:           graph.EdgeTypeSubsidiaryOf,
+		Weight:         0.8,
+		Status:         "Active",
+		Directionality: graph.DirectionalityUnidirectional,
+		Attributes:     map[string]interface{}{"origin": origin},
+	}, ownershipWhy)
 }
@@ -0,0 +1,215 @@
+package discovery
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"margraf/audit"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/retry"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// starterBundle is the built-in starter dataset: major listed companies with
+// verified tickers, sectors and headquarters, plus well-documented supplier
+// relations between them.
+//
+//go:embed starter.json
+var starterBundle []byte
+
+// StarterBundle is a curated dataset that seeds a graph without LLM discovery
+type StarterBundle struct {
+	Version   int               `json:"version"`
+	Name      string            `json:"name"`
+	Companies []StarterCompany  `json:"companies"`
+	Relations []StarterRelation `json:"relations"`
+}
+
+// StarterCompany is a listed company in a starter bundle
+type StarterCompany struct {
+	Name    string `json:"name"`
+	Ticker  string `json:"ticker"` // Yahoo Finance symbol
+	Sector  string `json:"sector"`
+	Country string `json:"country"` // Headquarters
+}
+
+// StarterRelation is a supplier -> client link between two bundle companies
+type StarterRelation struct {
+	Supplier string `json:"supplier"`
+	Client   string `json:"client"`
+	Product  string `json:"product,omitempty"`
+}
+
+// LoadStarterBundle reads a starter bundle from a file path or http(s) URL.
+// An empty source returns the built-in bundle.
+func LoadStarterBundle(ctx context.Context, source string) (*StarterBundle, error) {
+	var data []byte
+	switch {
+	case source == "":
+		data = starterBundle
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := retry.DoRequest(&http.Client{Timeout: 60 * time.Second}, req, retry.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download starter bundle: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to download starter bundle: status %d", resp.StatusCode)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	default:
+		var err error
+		if data, err = os.ReadFile(source); err != nil {
+			return nil, err
+		}
+	}
+
+	var bundle StarterBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid starter bundle: %w", err)
+	}
+	if len(bundle.Companies) == 0 {
+		return nil, fmt.Errorf("starter bundle has no companies")
+	}
+	return &bundle, nil
+}
+
+// ApplyStarter adds a bundle to g using the seeder's layout: Nation ->
+// HasIndustry -> Industry (per country and sector) -> HasCompany -> company,
+// and Supplies / ProcuresFrom pairs for each relation. Existing nodes and
+// edges are kept, so a bundle can be merged into a discovered graph or
+// applied twice. It returns the number of companies and relations applied.
+func ApplyStarter(g *graph.Graph, bundle *StarterBundle) (companies, relations int) {
+	// Nodes and edges go in through a batch; attributes are set once the
+	// nodes they belong to have been flushed
+	batch := g.NewBatch(0)
+	type update struct {
+		id, ticker string
+		attrs      map[string]interface{}
+	}
+	updates := make([]update, 0, len(bundle.Companies))
+	var newNations []graph.CountryInfo
+
+	known := make(map[string]bool, len(bundle.Companies))
+	for _, c := range bundle.Companies {
+		if c.Name == "" {
+			continue
+		}
+		compID := cleanID(c.Name)
+		known[compID] = true
+
+		attrs := map[string]interface{}{"source": "starter"}
+		if c.Sector != "" {
+			attrs["sector"] = c.Sector
+		}
+		if c.Country != "" {
+			var lat, lon float64
+			if info, ok := graph.LookupCountry(c.Country); ok {
+				lat, lon = info.Lat, info.Lon
+			}
+			for k, v := range graph.LocationAttributes(lat, lon, c.Country) {
+				attrs[k] = v
+			}
+		}
+		listing := why{"listed in starter dataset", fmt.Sprintf("starter company %s (%s, %s, %s)", c.Name, c.Ticker, c.Country, c.Sector)}
+		starterNode(batch, &graph.Node{ID: compID, Type: graph.NodeTypeCorporation, Name: c.Name}, listing)
+		updates = append(updates, update{id: compID, ticker: c.Ticker, attrs: attrs})
+		companies++
+
+		if c.Country == "" || c.Sector == "" {
+			continue
+		}
+		nation := c.Country
+		if info, ok := graph.LookupCountry(c.Country); ok {
+			nation = info.Name
+		}
+		nationID := cleanID(nation)
+		if starterNode(batch, &graph.Node{ID: nationID, Type: graph.NodeTypeNation, Name: nation}, listing) {
+			if info, ok := graph.LookupCountry(nation); ok {
+				newNations = append(newNations, info)
+			}
+		}
+		indID := cleanID(nation + "_" + c.Sector)
+		starterNode(batch, &graph.Node{ID: indID, Type: graph.NodeTypeIndustry, Name: c.Sector}, listing)
+		starterEdge(batch, &graph.Edge{SourceID: nationID, TargetID: indID, Type: graph.EdgeTypeHasIndustry, Weight: 1.0}, listing)
+		starterEdge(batch, &graph.Edge{SourceID: indID, TargetID: compID, Type: graph.EdgeTypeHasCompany, Weight: 1.0}, listing)
+	}
+	batch.Flush()
+
+	for _, info := range newNations {
+		g.UpdateNodeAttributes(cleanID(info.Name), graph.LocationAttributes(info.Lat, info.Lon, info.Name), "geo")
+	}
+	for _, u := range updates {
+		g.UpdateNodeAttributes(u.id, u.attrs, "starter")
+		if u.ticker != "" {
+			g.SetNodeTicker(u.id, u.ticker)
+		}
+	}
+
+	for _, r := range bundle.Relations {
+		supplierID, clientID := cleanID(r.Supplier), cleanID(r.Client)
+		if !known[supplierID] || !known[clientID] {
+			logger.Warn(logger.StatusWarn, "Starter relation %s -> %s skipped: unknown company", r.Supplier, r.Client)
+			continue
+		}
+		attrs := func() map[string]interface{} {
+			if r.Product == "" {
+				return nil
+			}
+			return map[string]interface{}{"product": r.Product}
+		}
+		relation := why{"supply relation in starter dataset", fmt.Sprintf("starter relation %s -> %s", r.Supplier, r.Client)}
+		if r.Product != "" {
+			relation.evidence += " (" + r.Product + ")"
+		}
+		starterEdge(batch, &graph.Edge{
+			SourceID:       supplierID,
+			TargetID:       clientID,
+			Type:           graph.EdgeTypeSupplies,
+			Weight:         0.7,
+			Status:         "Active",
+			Directionality: graph.DirectionalityUnidirectional,
+			Attributes:     attrs(),
+		}, relation)
+		starterEdge(batch, &graph.Edge{
+			SourceID:       clientID,
+			TargetID:       supplierID,
+			Type:           graph.EdgeTypeProcuresFrom,
+			Weight:         0.7,
+			Status:         "Active",
+			Directionality: graph.DirectionalityReverse,
+			Attributes:     attrs(),
+		}, relation)
+		relations++
+	}
+	batch.Flush()
+	return companies, relations
+}
+
+// starterNode queues n unless present, auditing it as the starter dataset's
+func starterNode(batch *graph.Batch, n *graph.Node, w why) bool {
+	if !batch.AddNode(n) {
+		return false
+	}
+	auditNode(audit.ActorStarter, n, w)
+	return true
+}
+
+// starterEdge queues e unless present, auditing it as the starter dataset's
+func starterEdge(batch *graph.Batch, e *graph.Edge, w why) {
+	if batch.AddEdgeOnce(withOrigin(e, graph.OriginStarter)) {
+		auditEdge(audit.ActorStarter, e, w)
+	}
+}
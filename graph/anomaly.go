@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Edge anomalies are weight moves far outside an edge's own history: a
+// relationship collapsing or surging with no news the pipeline tied to it,
+// or more than its usual updates explain. Each change is measured as its
+// residual after decay (the new weight less the old one decayed over the
+// gap) and scored against the edge's earlier residuals, so a quiet edge
+// that suddenly moves stands out while a volatile one needs a larger move.
+// Maintenance (decay sweeps, normalization, status overrides) and pruning
+// are not changes.
+
+// Edge anomaly kinds
+const (
+	EdgeCollapse = "collapse" // Weight fell far below its decayed expectation
+	EdgeSpike    = "spike"    // Weight rose far above it
+)
+
+// EdgeAnomalyOptions tunes EdgeAnomalies. Zero fields take the defaults.
+type EdgeAnomalyOptions struct {
+	Since      time.Time // Only changes after this are judged
+	Threshold  float64   // |z| against the edge's earlier changes that flags a change (0 = 3)
+	MinChange  float64   // Smallest residual flagged, however unusual (0 = 0.1)
+	MinHistory int       // Earlier changes an edge needs before it is judged (0 = 5)
+	Lambda     float64   // Daily decay rate, as in ApplyTemporalDecay (0 = 0.05)
+}
+
+// withDefaults fills in the zero fields
+func (o EdgeAnomalyOptions) withDefaults() EdgeAnomalyOptions {
+	if o.Threshold <= 0 {
+		o.Threshold = 3
+	}
+	if o.MinChange <= 0 {
+		o.MinChange = 0.1
+	}
+	if o.MinHistory <= 0 {
+		o.MinHistory = 5
+	}
+	if o.Lambda <= 0 {
+		o.Lambda = 0.05
+	}
+	return o
+}
+
+// maxAnomalyBaseline bounds the earlier changes a change is scored against
+const maxAnomalyBaseline = 50
+
+// minAnomalySpread floors the spread of earlier changes, so an edge that has
+// never moved does not flag the first tiny move
+const minAnomalySpread = 0.02
+
+// EdgeAnomaly is one weight change far outside its edge's history
+type EdgeAnomaly struct {
+	SourceID  string    `json:"source_id"`
+	TargetID  string    `json:"target_id"`
+	Type      EdgeType  `json:"type"`
+	Commodity string    `json:"commodity,omitempty"`
+	Kind      string    `json:"kind"` // collapse or spike
+	Time      time.Time `json:"time"`
+	EventID   string    `json:"event_id,omitempty"` // Event that made the change, if any
+	From      float64   `json:"from"`               // Weight before
+	To        float64   `json:"to"`                 // Weight after
+	Expected  float64   `json:"expected"`           // From, decayed over the gap
+	Residual  float64   `json:"residual"`           // To less Expected
+	ZScore    float64   `json:"z_score"`            // Residual against the edge's earlier residuals
+	Mean      float64   `json:"mean"`               // Mean of the earlier residuals
+	StdDev    float64   `json:"std_dev"`
+	Changes   int       `json:"changes"` // Earlier residuals scored against
+}
+
+// Key identifies the change, for de-duplicating alerts
+func (a EdgeAnomaly) Key() string {
+	return edgeKey(a.SourceID, a.TargetID, a.Type, a.Commodity) + "@" + a.Time.UTC().Format(time.RFC3339Nano)
+}
+
+// edgeResidual is one history change after decay
+type edgeResidual struct {
+	snapshot EdgeSnapshot
+	from     float64
+	expected float64
+	residual float64
+}
+
+// EdgeAnomalies returns the edge weight changes since opts.Since that lie
+// far outside their edges' own histories, most unusual first
+func (g *Graph) EdgeAnomalies(opts EdgeAnomalyOptions) []EdgeAnomaly {
+	opts = opts.withDefaults()
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var out []EdgeAnomaly
+	for _, history := range g.EdgeHistories {
+		snaps := history.History
+		if len(snaps) < opts.MinHistory+2 || !snaps[len(snaps)-1].Timestamp.After(opts.Since) {
+			continue
+		}
+		var residuals []edgeResidual
+		for i := 1; i < len(snaps); i++ {
+			s := snaps[i]
+			if maintenanceEvents[s.EventID] || s.Status == EdgeStatusPruned {
+				continue
+			}
+			prev := snaps[i-1]
+			days := math.Max(0, s.Timestamp.Sub(prev.Timestamp).Hours()/24)
+			expected := prev.Weight * math.Exp(-opts.Lambda*days)
+			residuals = append(residuals, edgeResidual{snapshot: s, from: prev.Weight, expected: expected, residual: s.Weight - expected})
+		}
+
+		for i, r := range residuals {
+			if !r.snapshot.Timestamp.After(opts.Since) || i < opts.MinHistory {
+				continue
+			}
+			baseline := residuals[max(0, i-maxAnomalyBaseline):i]
+			var sum, sumSquares float64
+			for _, b := range baseline {
+				sum += b.residual
+				sumSquares += b.residual * b.residual
+			}
+			n := float64(len(baseline))
+			mean := sum / n
+			std := math.Sqrt(math.Max(0, sumSquares/n-mean*mean))
+			z := (r.residual - mean) / math.Max(std, minAnomalySpread)
+			if math.Abs(z) < opts.Threshold || math.Abs(r.residual) < opts.MinChange {
+				continue
+			}
+			kind := EdgeSpike
+			if r.residual < 0 {
+				kind = EdgeCollapse
+			}
+			out = append(out, EdgeAnomaly{
+				SourceID:  history.SourceID,
+				TargetID:  history.TargetID,
+				Type:      history.Type,
+				Commodity: history.Commodity,
+				Kind:      kind,
+				Time:      r.snapshot.Timestamp,
+				EventID:   r.snapshot.EventID,
+				From:      r.from,
+				To:        r.snapshot.Weight,
+				Expected:  r.expected,
+				Residual:  r.residual,
+				ZScore:    z,
+				Mean:      mean,
+				StdDev:    std,
+				Changes:   len(baseline),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if a, b := math.Abs(out[i].ZScore), math.Abs(out[j].ZScore); a != b {
+			return a > b
+		}
+		return out[i].Key() < out[j].Key()
+	})
+	return out
+}
@@ -0,0 +1,94 @@
+package graph
+
+// defaultBatchSize is how many nodes and edges a Batch buffers before flushing
+const defaultBatchSize = 500
+
+// Batch buffers nodes and edges and adds them with AddNodes / AddEdges, so
+// bulk loaders (seeding, starter bundles, data sources) take the write lock
+// and check the auto-save threshold once per batch instead of once per
+// element. Pending nodes count as present for HasNode and AddEdgeOnce. A
+// Batch is not safe for concurrent use; call Flush when done.
+type Batch struct {
+	g       *Graph
+	size    int
+	nodes   []*Node
+	edges   []*Edge
+	nodeIDs map[string]bool
+	edgeIDs map[string]bool // edgeKey of pending edges
+}
+
+// NewBatch returns a batch that flushes every size elements (0 = default)
+func (g *Graph) NewBatch(size int) *Batch {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	return &Batch{
+		g:       g,
+		size:    size,
+		nodeIDs: make(map[string]bool),
+		edgeIDs: make(map[string]bool),
+	}
+}
+
+// HasNode reports whether id is in the graph or pending in the batch
+func (b *Batch) HasNode(id string) bool {
+	if b.nodeIDs[id] {
+		return true
+	}
+	_, ok := b.g.GetNode(id)
+	return ok
+}
+
+// AddNode queues n unless a node with its ID exists or is pending, and
+// reports whether it was queued
+func (b *Batch) AddNode(n *Node) bool {
+	if b.HasNode(n.ID) {
+		return false
+	}
+	b.nodes = append(b.nodes, n)
+	b.nodeIDs[n.ID] = true
+	b.flushIfFull()
+	return true
+}
+
+// AddEdge queues e
+func (b *Batch) AddEdge(e *Edge) {
+	b.edges = append(b.edges, e)
+	b.edgeIDs[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())] = true
+	b.flushIfFull()
+}
+
+// AddEdgeOnce queues e unless an edge of the same type and commodity already
+// joins its endpoints, in the graph or the batch, and reports whether it was queued
+func (b *Batch) AddEdgeOnce(e *Edge) bool {
+	if b.edgeIDs[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())] {
+		return false
+	}
+	for _, existing := range b.g.GetOutgoingEdges(e.SourceID) {
+		if existing.TargetID == e.TargetID && existing.Type == e.Type && existing.Commodity() == e.Commodity() {
+			return false
+		}
+	}
+	b.AddEdge(e)
+	return true
+}
+
+func (b *Batch) flushIfFull() {
+	if len(b.nodes)+len(b.edges) >= b.size {
+		b.Flush()
+	}
+}
+
+// Flush adds the pending nodes, then the pending edges
+func (b *Batch) Flush() {
+	if len(b.nodes) > 0 {
+		b.g.AddNodes(b.nodes)
+		b.nodes = nil
+		b.nodeIDs = make(map[string]bool)
+	}
+	if len(b.edges) > 0 {
+		b.g.AddEdges(b.edges)
+		b.edges = nil
+		b.edgeIDs = make(map[string]bool)
+	}
+}
@@ -0,0 +1,15 @@
+package graph_test
+
+import (
+	"margraf/bench"
+	"testing"
+)
+
+// The bodies live in package bench, shared with cmd/bench; run these with
+// go test -bench . ./graph (add -benchmem and feed the output to benchstat)
+
+func BenchmarkAddEdge(b *testing.B)            { bench.RunSizes(b, bench.AddEdge) }
+func BenchmarkUpdateEdgeWeight(b *testing.B)   { bench.RunSizes(b, bench.UpdateEdgeWeight) }
+func BenchmarkGetIncomingEdges(b *testing.B)   { bench.RunSizes(b, bench.GetIncomingEdges) }
+func BenchmarkToJSON(b *testing.B)             { bench.RunSizes(b, bench.ToJSON) }
+func BenchmarkApplyTemporalDecay(b *testing.B) { bench.RunSizes(b, bench.ApplyTemporalDecay) }
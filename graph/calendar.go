@@ -0,0 +1,162 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scheduled events - earnings reports, central bank meetings, data releases -
+// are attached to the nodes they concern so the news engine, the traders and
+// the dashboard can see what is coming. Each source replaces its own events
+// on every refresh; events are dropped once they are calendarRetention past.
+
+// EventKind classifies a scheduled event
+type EventKind string
+
+const (
+	EventEarnings EventKind = "earnings" // A company's earnings report
+	EventEconomic EventKind = "economic" // Rate decision, data release, auction, ...
+)
+
+// EventImpact is how much a scheduled event is expected to move its node
+type EventImpact string
+
+const (
+	ImpactHigh   EventImpact = "high"
+	ImpactMedium EventImpact = "medium"
+	ImpactLow    EventImpact = "low"
+)
+
+// ParseEventImpact reads an impact name; anything unrecognized is medium
+func ParseEventImpact(s string) EventImpact {
+	switch EventImpact(strings.ToLower(strings.TrimSpace(s))) {
+	case ImpactHigh:
+		return ImpactHigh
+	case ImpactLow:
+		return ImpactLow
+	default:
+		return ImpactMedium
+	}
+}
+
+// calendarRetention is how long an event stays on its node after it happened
+const calendarRetention = 7 * 24 * time.Hour
+
+// ScheduledEvent is a dated event expected to affect a node
+type ScheduledEvent struct {
+	NodeID string      `json:"node_id"`
+	Kind   EventKind   `json:"kind"`
+	Title  string      `json:"title"` // e.g. "AAPL earnings", "FOMC rate decision"
+	Time   time.Time   `json:"time"`
+	Impact EventImpact `json:"impact"`
+	Source string      `json:"source"` // Calendar that reported it, e.g. "yahoo"
+}
+
+// End is when the event is over: a day after Time for all-day events (those
+// at midnight, like most earnings dates), else Time
+func (ev ScheduledEvent) End() time.Time {
+	if h, m, s := ev.Time.Clock(); h == 0 && m == 0 && s == 0 {
+		return ev.Time.Add(24 * time.Hour)
+	}
+	return ev.Time
+}
+
+// SetScheduledEvents replaces the events source reported earlier with events,
+// skipping those for nodes not in the graph and those already past the
+// retention window. It returns the number kept.
+func (g *Graph) SetScheduledEvents(source string, events []ScheduledEvent) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	cutoff := time.Now().Add(-calendarRetention)
+	for id, list := range g.Calendar {
+		kept := list[:0]
+		for _, ev := range list {
+			if ev.Source != source && ev.Time.After(cutoff) {
+				kept = append(kept, ev)
+			}
+		}
+		if len(kept) == 0 {
+			delete(g.Calendar, id)
+		} else {
+			g.Calendar[id] = kept
+		}
+	}
+
+	added := 0
+	for _, ev := range events {
+		if _, ok := g.Nodes[ev.NodeID]; !ok || !ev.Time.After(cutoff) {
+			continue
+		}
+		if g.Calendar == nil {
+			g.Calendar = make(map[string][]ScheduledEvent)
+		}
+		ev.NodeID = intern(ev.NodeID)
+		ev.Source = source
+		g.Calendar[ev.NodeID] = append(g.Calendar[ev.NodeID], ev)
+		added++
+	}
+	for _, list := range g.Calendar {
+		SortEvents(list)
+	}
+	return added
+}
+
+// UpcomingEvents returns nodeID's events that are not over and start before
+// within ahead (0 = no limit), soonest first. An empty nodeID returns every node's events.
+func (g *Graph) UpcomingEvents(nodeID string, within time.Duration) []ScheduledEvent {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.upcomingEventsLocked(nodeID, time.Now(), within)
+}
+
+// EdgeEvents returns the upcoming events on either end of an edge: news about
+// a relationship matters more just before one side reports
+func (g *Graph) EdgeEvents(e *Edge, within time.Duration) []ScheduledEvent {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	now := time.Now()
+	events := append(g.upcomingEventsLocked(e.SourceID, now, within), g.upcomingEventsLocked(e.TargetID, now, within)...)
+	SortEvents(events)
+	return events
+}
+
+// upcomingEventsLocked lists events between now and within ahead (must be
+// called with lock held)
+func (g *Graph) upcomingEventsLocked(nodeID string, now time.Time, within time.Duration) []ScheduledEvent {
+	var events []ScheduledEvent
+	add := func(list []ScheduledEvent) {
+		for _, ev := range list {
+			if ev.End().Before(now) || (within > 0 && ev.Time.After(now.Add(within))) {
+				continue
+			}
+			events = append(events, ev)
+		}
+	}
+	if nodeID != "" {
+		add(g.Calendar[nodeID])
+		return events
+	}
+	for _, list := range g.Calendar {
+		add(list)
+	}
+	SortEvents(events)
+	return events
+}
+
+// SortEvents orders events soonest first
+func SortEvents(events []ScheduledEvent) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+}
+
+// HasHighImpactEvent reports whether nodeID has a high-impact event within
+// the window ahead
+func (g *Graph) HasHighImpactEvent(nodeID string, within time.Duration) bool {
+	for _, ev := range g.UpcomingEvents(nodeID, within) {
+		if ev.Impact == ImpactHigh {
+			return true
+		}
+	}
+	return false
+}
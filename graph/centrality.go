@@ -0,0 +1,355 @@
+package graph
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Centrality and hitting times are computed exactly on small graphs and
+// estimated by sampling on large ones, where the exact algorithms (shortest
+// paths from every node, an iterative solve over the whole graph) take too
+// long. Both work on the undirected view the embedding walks use (see
+// walkSnapshot): the weights of every edge between two nodes are summed, a
+// heavier tie is a shorter hop, and a walk steps along ties in proportion
+// to their weight.
+
+// Sampling defaults
+const (
+	defaultSamplingThreshold = 2000  // Nodes above which estimates are sampled
+	defaultSamplingSources   = 200   // Betweenness sources sampled
+	defaultSamplingWalks     = 2000  // Walks per hitting time
+	defaultSamplingMaxSteps  = 10000 // Steps before a walk gives up
+	maxHittingSweeps         = 20000 // Sweeps before an exact hitting time gives up
+)
+
+// SamplingOptions trades accuracy for time in the graph analytics. Zero
+// fields take the defaults.
+type SamplingOptions struct {
+	Threshold int     `json:"threshold"`  // Nodes above which estimates are sampled (0 = 2000)
+	Exact     bool    `json:"exact"`      // Compute exactly whatever the graph's size
+	Sampled   bool    `json:"sampled"`    // Sample whatever the graph's size
+	Sources   int     `json:"sources"`    // Source nodes sampled for betweenness (0 = 200); more is slower and closer
+	Walks     int     `json:"walks"`      // Random walks per hitting time (0 = 2000); error shrinks with the square root
+	MaxSteps  int     `json:"max_steps"`  // Steps a walk takes before it gives up (0 = 10000)
+	MinWeight float64 `json:"min_weight"` // Edges lighter than this are ignored
+	Seed      int64   `json:"seed"`       // The same seed over the same graph gives the same estimates
+}
+
+// withDefaults fills in the zero fields
+func (o SamplingOptions) withDefaults() SamplingOptions {
+	if o.Threshold <= 0 {
+		o.Threshold = defaultSamplingThreshold
+	}
+	if o.Sources <= 0 {
+		o.Sources = defaultSamplingSources
+	}
+	if o.Walks <= 0 {
+		o.Walks = defaultSamplingWalks
+	}
+	if o.MaxSteps <= 0 {
+		o.MaxSteps = defaultSamplingMaxSteps
+	}
+	return o
+}
+
+// sample reports whether a graph of n nodes is sampled
+func (o SamplingOptions) sample(n int) bool {
+	switch {
+	case o.Exact:
+		return false
+	case o.Sampled:
+		return true
+	}
+	return n > o.Threshold
+}
+
+// NodeCentrality is how much of the graph's shortest-path traffic passes
+// through a node
+type NodeCentrality struct {
+	NodeID      string   `json:"node_id"`
+	Name        string   `json:"name"`
+	Type        NodeType `json:"type"`
+	Betweenness float64  `json:"betweenness"` // Share of shortest paths between other nodes through it, 0 to 1
+}
+
+// BetweennessReport ranks nodes by betweenness
+type BetweennessReport struct {
+	Nodes    []NodeCentrality `json:"nodes"` // Most central first
+	Total    int              `json:"total"` // Nodes in the graph
+	Sampled  bool             `json:"sampled"`
+	Sources  int              `json:"sources"` // Nodes shortest paths were followed from
+	Duration time.Duration    `json:"duration"`
+}
+
+// Betweenness ranks the top nodes (0 = all) by betweenness centrality. On a
+// sampled graph, shortest paths are followed from opts.Sources random nodes
+// instead of every node and the counts scaled up, which keeps the ranking of
+// the most central nodes while cutting the time by the same factor. It
+// returns ctx's error if ctx is done first.
+func (g *Graph) Betweenness(ctx context.Context, opts SamplingOptions, top int) (*BetweennessReport, error) {
+	opts = opts.withDefaults()
+	start := time.Now()
+	wg := g.walkSnapshot(opts.MinWeight)
+	n := len(wg.nodes)
+	if n == 0 {
+		return nil, fmt.Errorf("graph has no nodes")
+	}
+
+	sources := make([]int32, n)
+	for i := range sources {
+		sources[i] = int32(i)
+	}
+	r := &BetweennessReport{Total: n, Sampled: opts.sample(n)}
+	if r.Sampled && opts.Sources < n {
+		rng := rand.New(rand.NewSource(opts.Seed))
+		rng.Shuffle(n, func(i, j int) { sources[i], sources[j] = sources[j], sources[i] })
+		sources = sources[:opts.Sources]
+	}
+	r.Sources = len(sources)
+
+	scores := make([]float64, n)
+	b := newBrandes(n)
+	for i, s := range sources {
+		if i%64 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		b.accumulate(wg, s, scores)
+	}
+
+	// Each path was counted from both ends; scale sampled sources up to all
+	// of them, then to a share of the (n-1)(n-2) ordered pairs of other nodes
+	scale := float64(n) / float64(len(sources))
+	if n > 2 {
+		scale /= float64((n - 1) * (n - 2))
+	}
+	r.Nodes = make([]NodeCentrality, n)
+	for i, node := range wg.nodes {
+		r.Nodes[i] = NodeCentrality{NodeID: node.ID, Name: node.Name, Type: node.Type, Betweenness: math.Min(scores[i]*scale, 1)}
+	}
+	sort.SliceStable(r.Nodes, func(i, j int) bool { return r.Nodes[i].Betweenness > r.Nodes[j].Betweenness })
+	if top > 0 && len(r.Nodes) > top {
+		r.Nodes = r.Nodes[:top]
+	}
+	r.Duration = time.Since(start)
+	return r, nil
+}
+
+// brandes holds the scratch state of Brandes' algorithm, reused across sources
+type brandes struct {
+	dist  []float64
+	sigma []float64
+	delta []float64
+	preds [][]int32
+	order []int32 // Nodes by distance from the source, nearest first
+	queue distQueue
+}
+
+func newBrandes(n int) *brandes {
+	return &brandes{
+		dist:  make([]float64, n),
+		sigma: make([]float64, n),
+		delta: make([]float64, n),
+		preds: make([][]int32, n),
+	}
+}
+
+// accumulate adds every node's dependency on the shortest paths from s to
+// scores, a hop of weight w being 1/w long
+func (b *brandes) accumulate(wg *walkGraph, s int32, scores []float64) {
+	for i := range b.dist {
+		b.dist[i], b.sigma[i], b.delta[i] = math.Inf(1), 0, 0
+		b.preds[i] = b.preds[i][:0]
+	}
+	b.order = b.order[:0]
+	b.dist[s], b.sigma[s] = 0, 1
+	b.queue = append(b.queue[:0], distItem{s, 0})
+	for len(b.queue) > 0 {
+		it := heap.Pop(&b.queue).(distItem)
+		v := it.node
+		if it.dist > b.dist[v] {
+			continue // Stale entry
+		}
+		b.order = append(b.order, v)
+		for _, nb := range wg.adj[v] {
+			d := b.dist[v] + 1/nb.weight
+			switch {
+			case d < b.dist[nb.to]-1e-12:
+				b.dist[nb.to], b.sigma[nb.to] = d, b.sigma[v]
+				b.preds[nb.to] = append(b.preds[nb.to][:0], v)
+				heap.Push(&b.queue, distItem{nb.to, d})
+			case math.Abs(d-b.dist[nb.to]) <= 1e-12:
+				b.sigma[nb.to] += b.sigma[v]
+				b.preds[nb.to] = append(b.preds[nb.to], v)
+			}
+		}
+	}
+	for i := len(b.order) - 1; i >= 0; i-- {
+		w := b.order[i]
+		for _, v := range b.preds[w] {
+			b.delta[v] += b.sigma[v] / b.sigma[w] * (1 + b.delta[w])
+		}
+		if w != s {
+			scores[w] += b.delta[w]
+		}
+	}
+}
+
+// distItem is a node queued at a tentative distance
+type distItem struct {
+	node int32
+	dist float64
+}
+
+// distQueue is a min-heap of distItems
+type distQueue []distItem
+
+func (q distQueue) Len() int            { return len(q) }
+func (q distQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q distQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *distQueue) Push(x interface{}) { *q = append(*q, x.(distItem)) }
+func (q *distQueue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
+
+// HittingTime is the expected number of steps a weighted random walk from
+// one node takes to first reach another: how many hops, on average, a
+// disturbance wandering the graph needs to get there
+type HittingTime struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Steps    float64       `json:"steps"`
+	StdErr   float64       `json:"std_err,omitempty"` // Standard error of a sampled estimate
+	Reached  float64       `json:"reached"`           // Share of walks that arrived within MaxSteps (1 when exact)
+	Sampled  bool          `json:"sampled"`
+	Walks    int           `json:"walks,omitempty"` // Walks behind a sampled estimate
+	Duration time.Duration `json:"duration"`
+}
+
+// HittingTime estimates the steps a weighted random walk from one node takes
+// to reach another. Exactly, it solves for the expected steps from every
+// node of the target's component; sampled, it averages opts.Walks walks,
+// which takes time in proportion to the walks rather than the graph. Walks
+// that give up after opts.MaxSteps are left out of the average (and counted
+// in Reached), so a sampled estimate runs low when many do.
+func (g *Graph) HittingTime(ctx context.Context, from, to string, opts SamplingOptions) (*HittingTime, error) {
+	opts = opts.withDefaults()
+	start := time.Now()
+	wg := g.walkSnapshot(opts.MinWeight)
+	s, ok := wg.index(from)
+	if !ok {
+		return nil, fmt.Errorf("node %s not found", from)
+	}
+	t, ok := wg.index(to)
+	if !ok {
+		return nil, fmt.Errorf("node %s not found", to)
+	}
+
+	h := &HittingTime{From: from, To: to, Sampled: opts.sample(len(wg.nodes)), Reached: 1}
+	if s == t {
+		h.Duration = time.Since(start)
+		return h, nil
+	}
+	var err error
+	if h.Sampled {
+		err = wg.sampleHitting(ctx, s, t, opts, h)
+	} else {
+		h.Steps, err = wg.exactHitting(ctx, s, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.Duration = time.Since(start)
+	return h, nil
+}
+
+// sampleHitting averages the steps walks from s take to reach t
+func (wg *walkGraph) sampleHitting(ctx context.Context, s, t int32, opts SamplingOptions, h *HittingTime) error {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	var sum, sumSquares float64
+	reached := 0
+	for i := 0; i < opts.Walks; i++ {
+		if i%64 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		cur, steps := s, 0
+		for cur != t && steps < opts.MaxSteps {
+			next, ok := wg.step(rng, cur)
+			if !ok {
+				break
+			}
+			cur = next
+			steps++
+		}
+		if cur == t {
+			reached++
+			sum += float64(steps)
+			sumSquares += float64(steps) * float64(steps)
+		}
+	}
+	h.Walks = opts.Walks
+	h.Reached = float64(reached) / float64(opts.Walks)
+	if reached == 0 {
+		return fmt.Errorf("no walk from %s reached %s within %d steps", wg.nodes[s].ID, wg.nodes[t].ID, opts.MaxSteps)
+	}
+	h.Steps = sum / float64(reached)
+	if reached > 1 {
+		variance := (sumSquares - float64(reached)*h.Steps*h.Steps) / float64(reached-1)
+		h.StdErr = math.Sqrt(math.Max(variance, 0) / float64(reached))
+	}
+	return nil
+}
+
+// exactHitting solves h(t) = 0, h(v) = 1 + sum of P(v, u) h(u) over t's
+// component by Gauss-Seidel sweeps
+func (wg *walkGraph) exactHitting(ctx context.Context, s, t int32) (float64, error) {
+	// The walk never leaves t's component, so only it needs solving
+	component := []int32{t}
+	seen := map[int32]bool{t: true}
+	for i := 0; i < len(component); i++ {
+		for _, nb := range wg.adj[component[i]] {
+			if !seen[nb.to] {
+				seen[nb.to] = true
+				component = append(component, nb.to)
+			}
+		}
+	}
+	if !seen[s] {
+		return 0, fmt.Errorf("%s and %s are not connected", wg.nodes[s].ID, wg.nodes[t].ID)
+	}
+
+	h := make([]float64, len(wg.nodes))
+	for sweep := 0; sweep < maxHittingSweeps; sweep++ {
+		if sweep%16 == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+		}
+		change := 0.0
+		for _, v := range component[1:] {
+			cum := wg.cum[v]
+			total := cum[len(cum)-1]
+			next := 1.0
+			for _, nb := range wg.adj[v] {
+				next += nb.weight / total * h[nb.to]
+			}
+			change = math.Max(change, math.Abs(next-h[v])/math.Max(next, 1))
+			h[v] = next
+		}
+		if change < 1e-9 {
+			return h[s], nil
+		}
+	}
+	return 0, fmt.Errorf("hitting time from %s to %s did not converge; sample it instead", wg.nodes[s].ID, wg.nodes[t].ID)
+}
@@ -0,0 +1,191 @@
+package graph
+
+import "strings"
+
+// AttrRoutesThrough is the trade edge attribute listing the chokepoint IDs
+// (comma-separated) its goods pass through.
+const AttrRoutesThrough = "routes_through"
+
+// Chokepoint is a logistics bottleneck that trade between certain countries
+// or regions depends on.
+type Chokepoint struct {
+	ID        string
+	Name      string
+	Kind      string // canal, strait, port
+	Country   string
+	Lat       float64
+	Lon       float64
+	Countries []string    // Trade to or from these countries passes through
+	Lanes     [][2]string // Trade between these region pairs (either direction) passes through
+}
+
+// Chokepoints is the built-in catalog. Lanes are approximate main shipping routes.
+var Chokepoints = []Chokepoint{
+	{
+		ID: "suez_canal", Name: "Suez Canal", Kind: "canal", Country: "Egypt", Lat: 30.6, Lon: 32.3,
+		Lanes: [][2]string{
+			{"Europe", "East Asia"}, {"Europe", "Southeast Asia"}, {"Europe", "South Asia"},
+			{"Europe", "Middle East"}, {"Europe", "Oceania"}, {"North America", "South Asia"},
+		},
+	},
+	{
+		ID: "bab_el_mandeb", Name: "Bab el-Mandeb", Kind: "strait", Country: "Yemen", Lat: 12.6, Lon: 43.3,
+		Lanes: [][2]string{
+			{"Europe", "East Asia"}, {"Europe", "Southeast Asia"}, {"Europe", "South Asia"},
+			{"Europe", "Oceania"}, {"North America", "South Asia"},
+		},
+	},
+	{
+		ID: "strait_of_malacca", Name: "Strait of Malacca", Kind: "strait", Country: "Malaysia", Lat: 2.5, Lon: 101.5,
+		Lanes: [][2]string{
+			{"East Asia", "South Asia"}, {"East Asia", "Middle East"}, {"East Asia", "Europe"},
+			{"East Asia", "Africa"}, {"Southeast Asia", "South Asia"}, {"Southeast Asia", "Middle East"},
+		},
+	},
+	{
+		ID: "strait_of_hormuz", Name: "Strait of Hormuz", Kind: "strait", Country: "Iran", Lat: 26.6, Lon: 56.3,
+		Countries: []string{"Qatar", "Kuwait", "Iraq", "Iran", "United Arab Emirates", "Saudi Arabia"},
+	},
+	{
+		ID: "panama_canal", Name: "Panama Canal", Kind: "canal", Country: "Panama", Lat: 9.1, Lon: -79.7,
+		Lanes: [][2]string{
+			{"North America", "East Asia"}, {"Latin America", "East Asia"}, {"Europe", "Oceania"},
+		},
+	},
+	{
+		ID: "bosphorus", Name: "Bosphorus", Kind: "strait", Country: "Turkey", Lat: 41.1, Lon: 29.0,
+		Countries: []string{"Ukraine", "Russia"},
+	},
+	{
+		ID: "port_of_shanghai", Name: "Port of Shanghai", Kind: "port", Country: "China", Lat: 31.2, Lon: 121.5,
+		Countries: []string{"China"},
+	},
+	{
+		ID: "port_of_singapore", Name: "Port of Singapore", Kind: "port", Country: "Singapore", Lat: 1.26, Lon: 103.8,
+		Countries: []string{"Singapore", "Malaysia"},
+	},
+	{
+		ID: "port_of_rotterdam", Name: "Port of Rotterdam", Kind: "port", Country: "Netherlands", Lat: 51.9, Lon: 4.1,
+		Countries: []string{"Netherlands", "Germany", "Belgium"},
+	},
+	{
+		ID: "port_of_los_angeles", Name: "Port of Los Angeles", Kind: "port", Country: "United States", Lat: 33.7, Lon: -118.3,
+		Lanes: [][2]string{{"North America", "East Asia"}, {"North America", "Southeast Asia"}},
+	},
+}
+
+// Routes reports whether trade between two countries passes through the chokepoint
+func (c Chokepoint) Routes(from, to CountryInfo) bool {
+	for _, country := range c.Countries {
+		if strings.EqualFold(country, from.Name) || strings.EqualFold(country, to.Name) {
+			return true
+		}
+	}
+	for _, lane := range c.Lanes {
+		if (lane[0] == from.Region && lane[1] == to.Region) || (lane[0] == to.Region && lane[1] == from.Region) {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteTradeThroughChokepoints tags every nation-to-nation trade edge with the
+// chokepoints it depends on and links both trading nations to each of them
+// with RoutesThrough edges, adding chokepoint nodes as needed. Nations whose
+// country isn't in the built-in table are skipped. Returns the number of
+// RoutesThrough edges added.
+func (g *Graph) RouteTradeThroughChokepoints() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	linked := make(map[string]bool) // nationID|chokepointID
+	for _, e := range g.Edges {
+		if e.Type == EdgeTypeRoutesThrough {
+			linked[e.SourceID+"|"+e.TargetID] = true
+		}
+	}
+
+	link := func(nationID string, c Chokepoint) bool {
+		key := nationID + "|" + c.ID
+		if linked[key] {
+			return false
+		}
+		linked[key] = true
+
+		if _, ok := g.Nodes[c.ID]; !ok {
+			node := &Node{
+				ID:         c.ID,
+				Type:       NodeTypeInfrastructure,
+				Name:       c.Name,
+				Attributes: LocationAttributes(c.Lat, c.Lon, c.Country),
+			}
+			node.Attributes["kind"] = c.Kind
+			g.addNodeLocked(node)
+		}
+
+		g.addEdgeLocked(&Edge{
+			SourceID: nationID,
+			TargetID: c.ID,
+			Type:     EdgeTypeRoutesThrough,
+			Weight:   1.0,
+		})
+		return true
+	}
+
+	added := 0
+	for _, e := range g.Edges {
+		if e.Type != EdgeTypeTrade {
+			continue
+		}
+		src, okSrc := g.Nodes[e.SourceID]
+		tgt, okTgt := g.Nodes[e.TargetID]
+		if !okSrc || !okTgt || src.Type != NodeTypeNation || tgt.Type != NodeTypeNation {
+			continue
+		}
+		from, okFrom := LookupCountry(src.Country())
+		to, okTo := LookupCountry(tgt.Country())
+		if !okFrom || !okTo {
+			continue
+		}
+
+		var route []string
+		for _, c := range Chokepoints {
+			if !c.Routes(from, to) {
+				continue
+			}
+			route = append(route, c.ID)
+			if link(src.ID, c) {
+				added++
+			}
+			if link(tgt.ID, c) {
+				added++
+			}
+		}
+
+		routes := strings.Join(route, ",")
+		if current, _ := e.Attributes[AttrRoutesThrough].(string); current == routes {
+			continue
+		}
+		if e.Attributes == nil {
+			e.Attributes = make(map[string]interface{})
+		}
+		e.Attributes[AttrRoutesThrough] = routes
+		g.emit(Delta{Kind: DeltaEdge, Edge: e})
+	}
+
+	if added > 0 {
+		g.triggerAutoSave()
+	}
+	return added
+}
+
+// RoutesThrough reports whether a trade edge passes through the chokepoint
+func (e *Edge) RoutesThrough(chokepointID string) bool {
+	routes, _ := e.Attributes[AttrRoutesThrough].(string)
+	for _, id := range strings.Split(routes, ",") {
+		if id == chokepointID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package graph
+
+// ClimateHazard names a climate exposure attribute. Exposures range from 0 (none) to 1 (severe).
+type ClimateHazard string
+
+const (
+	HazardDrought         ClimateHazard = "climate_drought"
+	HazardFlood           ClimateHazard = "climate_flood"
+	HazardHeat            ClimateHazard = "climate_heat"
+	HazardCarbonIntensity ClimateHazard = "carbon_intensity" // Emissions per unit of output, relative to peers
+)
+
+// ClimateHazards lists every exposure attribute
+var ClimateHazards = []ClimateHazard{HazardDrought, HazardFlood, HazardHeat, HazardCarbonIntensity}
+
+// Climate provenance attribute keys
+const (
+	AttrClimateSource  = "climate_source"  // e.g. "dataset:climate.csv" or "llm:gemini-1.5-flash"
+	AttrClimateUpdated = "climate_updated" // RFC 3339 time the scores were set
+)
+
+// ClimateExposure returns the node's exposure to a hazard, or 0 if unscored
+func (n *Node) ClimateExposure(hazard ClimateHazard) float64 {
+	v, _ := n.Attributes[string(hazard)].(float64)
+	return v
+}
+
+// HasClimateScores reports whether the node's climate exposures have been set
+func (n *Node) HasClimateScores() bool {
+	src, _ := n.Attributes[AttrClimateSource].(string)
+	return src != ""
+}
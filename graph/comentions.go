@@ -0,0 +1,170 @@
+package graph
+
+import (
+	"fmt"
+	"margraf/clock"
+	"sort"
+	"time"
+)
+
+// Two nodes that keep turning up in the same headlines and posts are
+// probably related, whether or not the graph says so yet. Each item naming
+// both is kept against the pair, so a missing edge can be proposed with the
+// items that support it.
+
+// Bounds on what is kept per pair
+const (
+	maxCoMentionAge   = 90 * 24 * time.Hour
+	maxCoMentionItems = 20
+)
+
+// CoMention is the items that named both nodes of a pair
+type CoMention struct {
+	A     string          `json:"a"` // The lesser node ID
+	B     string          `json:"b"`
+	Items []CoMentionItem `json:"items"` // Oldest first
+}
+
+// CoMentionItem is a headline or post that named both nodes of a pair
+type CoMentionItem struct {
+	Time   time.Time       `json:"time"`
+	Source SentimentSource `json:"source"` // News headline or social post
+	Text   string          `json:"text"`
+	URL    string          `json:"url,omitempty"`
+}
+
+// coMentionKey is the key of a pair, whichever order its nodes come in
+func coMentionKey(a, b string) (string, string, string) {
+	if b < a {
+		a, b = b, a
+	}
+	return a + "|" + b, a, b
+}
+
+// RecordCoMention keeps an item that named both a and b, and returns how
+// many distinct items within window have named the pair. An item already
+// kept (same URL, or same text without one) is not counted twice; added
+// reports whether this one was new.
+func (g *Graph) RecordCoMention(a, b string, item CoMentionItem, window time.Duration) (n int, added bool, err error) {
+	if a == b {
+		return 0, false, fmt.Errorf("co-mention of %s with itself", a)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, id := range []string{a, b} {
+		if _, ok := g.Nodes[id]; !ok {
+			return 0, false, fmt.Errorf("node %s not found", id)
+		}
+	}
+	if item.Time.IsZero() {
+		item.Time = clock.Now()
+	}
+	if g.CoMentions == nil {
+		g.CoMentions = make(map[string]*CoMention)
+	}
+	key, a, b := coMentionKey(a, b)
+	pair, exists := g.CoMentions[key]
+	if !exists {
+		pair = &CoMention{A: a, B: b}
+		g.CoMentions[key] = pair
+	}
+
+	added = true
+	for _, it := range pair.Items {
+		if item.URL != "" && it.URL == item.URL || item.URL == "" && it.URL == "" && it.Text == item.Text {
+			added = false
+			break
+		}
+	}
+	if added {
+		pair.Items = append(pair.Items, item)
+		sort.SliceStable(pair.Items, func(i, j int) bool { return pair.Items[i].Time.Before(pair.Items[j].Time) })
+	}
+
+	cutoff := time.Now().Add(-maxCoMentionAge)
+	drop := 0
+	for drop < len(pair.Items) && (pair.Items[drop].Time.Before(cutoff) || len(pair.Items)-drop > maxCoMentionItems) {
+		drop++
+	}
+	if drop > 0 {
+		pair.Items = append(pair.Items[:0:0], pair.Items[drop:]...)
+	}
+	return pair.countSince(time.Now().Add(-window)), added, nil
+}
+
+// countSince counts the pair's items at or after since
+func (c *CoMention) countSince(since time.Time) int {
+	n := 0
+	for _, it := range c.Items {
+		if !it.Time.Before(since) {
+			n++
+		}
+	}
+	return n
+}
+
+// CoMentionOf returns a copy of the items that named both a and b
+func (g *Graph) CoMentionOf(a, b string) (CoMention, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	key, _, _ := coMentionKey(a, b)
+	pair, ok := g.CoMentions[key]
+	if !ok {
+		return CoMention{}, false
+	}
+	out := *pair
+	out.Items = append([]CoMentionItem(nil), pair.Items...)
+	return out, true
+}
+
+// FrequentCoMentions returns copies of the pairs named together by at least
+// min items within window, most items first
+func (g *Graph) FrequentCoMentions(min int, window time.Duration) []CoMention {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	since := time.Now().Add(-window)
+	var out []CoMention
+	counts := make(map[*CoMention]int)
+	for _, pair := range g.CoMentions {
+		if n := pair.countSince(since); n >= min {
+			counts[pair] = n
+		}
+	}
+	pairs := make([]*CoMention, 0, len(counts))
+	for pair := range counts {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if counts[pairs[i]] != counts[pairs[j]] {
+			return counts[pairs[i]] > counts[pairs[j]]
+		}
+		return pairs[i].A+"|"+pairs[i].B < pairs[j].A+"|"+pairs[j].B
+	})
+	for _, pair := range pairs {
+		c := *pair
+		c.Items = append([]CoMentionItem(nil), pair.Items...)
+		out = append(out, c)
+	}
+	return out
+}
+
+// Linked reports whether any edge joins a and b, in either direction
+func (g *Graph) Linked(a, b string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	for _, e := range g.Adjacency[a] {
+		if e.TargetID == b {
+			return true
+		}
+	}
+	for _, e := range g.Adjacency[b] {
+		if e.TargetID == a {
+			return true
+		}
+	}
+	return false
+}
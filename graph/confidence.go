@@ -0,0 +1,181 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Edges differ in how sure we are they exist: a Comtrade trade flow is
+// reported, a supplier link an LLM suggested is a guess. An edge's confidence
+// is its "confidence" attribute when set, otherwise what its origin is worth.
+// The simulator scales the share of a shock an edge passes on by it, so weak
+// edges don't transmit shocks as strongly as verified ones.
+
+// Edge origins, kept in the "origin" attribute
+const (
+	OriginTradeData = "trade_data" // Reported trade statistics (UN Comtrade, World Bank)
+	OriginStarter   = "starter"    // Curated starter dataset
+	OriginWikidata  = "wikidata"   // Wikidata statements, e.g. ownership
+	OriginLLM       = "llm"        // Suggested by an LLM during discovery
+	OriginCoMention = "co_mention" // Named together in news and social posts
+)
+
+// defaultEdgeConfidence applies to edges of unknown origin
+const defaultEdgeConfidence = 0.5
+
+// defaultOriginConfidence is the built-in confidence of each origin
+var defaultOriginConfidence = map[string]float64{
+	OriginTradeData: 0.95,
+	OriginStarter:   0.9,
+	OriginWikidata:  0.85,
+	OriginLLM:       0.5,
+	OriginCoMention: 0.3,
+}
+
+// EdgeOrigin returns where e came from: its "origin" attribute, or
+// OriginTradeData for an edge carrying a trade value (saved before origins
+// were recorded). Empty when unknown.
+func EdgeOrigin(e *Edge) string {
+	if origin, ok := e.Attributes["origin"].(string); ok && origin != "" {
+		return origin
+	}
+	if _, ok := e.Attributes["trade_value"].(float64); ok {
+		return OriginTradeData
+	}
+	return ""
+}
+
+// provenance copies the attributes that say where from came from, for an
+// edge derived from it
+func provenance(from *Edge) map[string]interface{} {
+	origin := EdgeOrigin(from)
+	confidence, hasConfidence := from.Attributes["confidence"]
+	if origin == "" && !hasConfidence {
+		return nil
+	}
+	attrs := make(map[string]interface{}, 2)
+	if origin != "" {
+		attrs["origin"] = origin
+	}
+	if hasConfidence {
+		attrs["confidence"] = confidence
+	}
+	return attrs
+}
+
+// ConfidenceModel holds what each edge origin is worth and how far confidence
+// scales shock propagation
+type ConfidenceModel struct {
+	Weight   float64            // 1 = multiply propagation by confidence, 0 = ignore it
+	Default  float64            // Edges without a confidence attribute or a listed origin
+	ByOrigin map[string]float64 // Keyed by origin
+}
+
+// DefaultConfidenceModel returns the built-in confidences, fully weighted
+func DefaultConfidenceModel() *ConfidenceModel {
+	m := &ConfidenceModel{
+		Weight:   1,
+		Default:  defaultEdgeConfidence,
+		ByOrigin: make(map[string]float64, len(defaultOriginConfidence)),
+	}
+	for origin, c := range defaultOriginConfidence {
+		m.ByOrigin[origin] = c
+	}
+	return m
+}
+
+// builtinConfidence backs graphs without a confidence model
+var builtinConfidence = DefaultConfidenceModel()
+
+// Of returns e's confidence, between 0 and 1
+func (m *ConfidenceModel) Of(e *Edge) float64 {
+	if c, ok := e.Attributes["confidence"].(float64); ok {
+		return math.Max(0, math.Min(1, c))
+	}
+	if c, ok := m.ByOrigin[EdgeOrigin(e)]; ok {
+		return c
+	}
+	return m.Default
+}
+
+// Scale returns the share of its propagation factor an edge of confidence c
+// keeps: c at full weight, 1 at none
+func (m *ConfidenceModel) Scale(c float64) float64 {
+	return 1 - m.Weight*(1-c)
+}
+
+// Validate checks the weight and every confidence is between 0 and 1
+func (m *ConfidenceModel) Validate() error {
+	check := func(name string, v float64) error {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("edge confidence %s: %.2f is outside [0, 1]", name, v)
+		}
+		return nil
+	}
+	if err := check("weight", m.Weight); err != nil {
+		return err
+	}
+	if err := check("default", m.Default); err != nil {
+		return err
+	}
+	for origin, c := range m.ByOrigin {
+		if err := check(origin, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Origins returns the origins with a confidence, sorted
+func (m *ConfidenceModel) Origins() []string {
+	origins := make([]string, 0, len(m.ByOrigin))
+	for origin := range m.ByOrigin {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	return origins
+}
+
+// clone returns a deep copy of the model
+func (m *ConfidenceModel) clone() *ConfidenceModel {
+	c := &ConfidenceModel{
+		Weight:   m.Weight,
+		Default:  m.Default,
+		ByOrigin: make(map[string]float64, len(m.ByOrigin)),
+	}
+	for origin, v := range m.ByOrigin {
+		c.ByOrigin[origin] = v
+	}
+	return c
+}
+
+// SetConfidenceModel replaces the graph's edge confidences
+func (g *Graph) SetConfidenceModel(m *ConfidenceModel) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.confidence = m
+}
+
+// ConfidenceModel returns a copy of the graph's edge confidences
+func (g *Graph) ConfidenceModel() *ConfidenceModel {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.confidence == nil {
+		return builtinConfidence.clone()
+	}
+	return g.confidence.clone()
+}
+
+// EdgeConfidence returns e's confidence and the share of its propagation
+// factor a shock keeps for it
+func (g *Graph) EdgeConfidence(e *Edge) (confidence, scale float64) {
+	g.mu.RLock()
+	m := g.confidence
+	g.mu.RUnlock()
+	if m == nil {
+		m = builtinConfidence
+	}
+	c := m.Of(e)
+	return c, m.Scale(c)
+}
@@ -0,0 +1,251 @@
+package graph
+
+import "strings"
+
+// CurrencyInfo is the built-in data for a currency
+type CurrencyInfo struct {
+	Code        string
+	Name        string
+	CentralBank string
+}
+
+// Currencies maps ISO 4217 codes to currency names and issuing central banks
+var Currencies = map[string]CurrencyInfo{
+	"USD": {"USD", "US Dollar", "Federal Reserve"},
+	"EUR": {"EUR", "Euro", "European Central Bank"},
+	"GBP": {"GBP", "Pound Sterling", "Bank of England"},
+	"JPY": {"JPY", "Japanese Yen", "Bank of Japan"},
+	"CNY": {"CNY", "Chinese Yuan", "People's Bank of China"},
+	"CHF": {"CHF", "Swiss Franc", "Swiss National Bank"},
+	"CAD": {"CAD", "Canadian Dollar", "Bank of Canada"},
+	"AUD": {"AUD", "Australian Dollar", "Reserve Bank of Australia"},
+	"NZD": {"NZD", "New Zealand Dollar", "Reserve Bank of New Zealand"},
+	"INR": {"INR", "Indian Rupee", "Reserve Bank of India"},
+	"KRW": {"KRW", "South Korean Won", "Bank of Korea"},
+	"TWD": {"TWD", "New Taiwan Dollar", "Central Bank of the Republic of China (Taiwan)"},
+	"HKD": {"HKD", "Hong Kong Dollar", "Hong Kong Monetary Authority"},
+	"SGD": {"SGD", "Singapore Dollar", "Monetary Authority of Singapore"},
+	"BRL": {"BRL", "Brazilian Real", "Central Bank of Brazil"},
+	"MXN": {"MXN", "Mexican Peso", "Bank of Mexico"},
+	"ARS": {"ARS", "Argentine Peso", "Central Bank of Argentina"},
+	"RUB": {"RUB", "Russian Ruble", "Bank of Russia"},
+	"TRY": {"TRY", "Turkish Lira", "Central Bank of the Republic of Turkey"},
+	"SAR": {"SAR", "Saudi Riyal", "Saudi Central Bank"},
+	"AED": {"AED", "UAE Dirham", "Central Bank of the UAE"},
+	"ZAR": {"ZAR", "South African Rand", "South African Reserve Bank"},
+	"NGN": {"NGN", "Nigerian Naira", "Central Bank of Nigeria"},
+	"EGP": {"EGP", "Egyptian Pound", "Central Bank of Egypt"},
+	"IDR": {"IDR", "Indonesian Rupiah", "Bank Indonesia"},
+	"THB": {"THB", "Thai Baht", "Bank of Thailand"},
+	"MYR": {"MYR", "Malaysian Ringgit", "Bank Negara Malaysia"},
+	"VND": {"VND", "Vietnamese Dong", "State Bank of Vietnam"},
+	"PHP": {"PHP", "Philippine Peso", "Bangko Sentral ng Pilipinas"},
+	"SEK": {"SEK", "Swedish Krona", "Sveriges Riksbank"},
+	"NOK": {"NOK", "Norwegian Krone", "Norges Bank"},
+	"DKK": {"DKK", "Danish Krone", "Danmarks Nationalbank"},
+	"PLN": {"PLN", "Polish Zloty", "National Bank of Poland"},
+	"ILS": {"ILS", "Israeli Shekel", "Bank of Israel"},
+	"CLP": {"CLP", "Chilean Peso", "Central Bank of Chile"},
+	"PKR": {"PKR", "Pakistani Rupee", "State Bank of Pakistan"},
+}
+
+// countryCurrencies maps lowercased ISO registry names of countries to currency codes
+var countryCurrencies = map[string]string{
+	"united states":        "USD",
+	"canada":               "CAD",
+	"mexico":               "MXN",
+	"brazil":               "BRL",
+	"argentina":            "ARS",
+	"chile":                "CLP",
+	"united kingdom":       "GBP",
+	"ireland":              "EUR",
+	"france":               "EUR",
+	"germany":              "EUR",
+	"italy":                "EUR",
+	"spain":                "EUR",
+	"portugal":             "EUR",
+	"netherlands":          "EUR",
+	"belgium":              "EUR",
+	"austria":              "EUR",
+	"finland":              "EUR",
+	"greece":               "EUR",
+	"switzerland":          "CHF",
+	"sweden":               "SEK",
+	"norway":               "NOK",
+	"denmark":              "DKK",
+	"poland":               "PLN",
+	"russia":               "RUB",
+	"turkey":               "TRY",
+	"israel":               "ILS",
+	"saudi arabia":         "SAR",
+	"united arab emirates": "AED",
+	"egypt":                "EGP",
+	"nigeria":              "NGN",
+	"south africa":         "ZAR",
+	"china":                "CNY",
+	"japan":                "JPY",
+	"south korea":          "KRW",
+	"taiwan":               "TWD",
+	"hong kong":            "HKD",
+	"india":                "INR",
+	"pakistan":             "PKR",
+	"indonesia":            "IDR",
+	"thailand":             "THB",
+	"vietnam":              "VND",
+	"malaysia":             "MYR",
+	"singapore":            "SGD",
+	"philippines":          "PHP",
+	"australia":            "AUD",
+	"new zealand":          "NZD",
+}
+
+// CountryCurrency returns the currency code used by a country
+func CountryCurrency(country string) (string, bool) {
+	info, ok := LookupCountry(country)
+	if !ok {
+		return "", false
+	}
+	code, ok := countryCurrencies[strings.ToLower(info.Name)]
+	return code, ok
+}
+
+// CurrencyNodeID returns the node ID for a currency code. IDs are returned unchanged.
+func CurrencyNodeID(code string) string {
+	id := strings.ToLower(code)
+	if strings.HasPrefix(id, "currency_") {
+		return id
+	}
+	return "currency_" + id
+}
+
+// centralBankNodeID returns the node ID for the central bank issuing a currency
+func centralBankNodeID(code string) string {
+	return "central_bank_" + strings.ToLower(code)
+}
+
+// reportingCurrency returns the currency a corporation reports in: its
+// fundamentals currency, falling back to its quote currency.
+func (n *Node) reportingCurrency() string {
+	if c, ok := n.Attributes["fundamentals_currency"].(string); ok && c != "" {
+		return strings.ToUpper(c)
+	}
+	return strings.ToUpper(n.Currency)
+}
+
+// LinkCurrencies adds currency and central-bank nodes for every nation and
+// corporation whose currency is known, linking nations with UsesCurrency,
+// corporations with ReportsIn and central banks with Issues edges.
+// Returns the number of edges added.
+func (g *Graph) LinkCurrencies() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	existing := make(map[string]bool)
+	for _, e := range g.Edges {
+		existing[e.SourceID+"|"+e.TargetID+"|"+string(e.Type)] = true
+	}
+
+	added := 0
+	addEdge := func(sourceID, targetID string, edgeType EdgeType) {
+		key := sourceID + "|" + targetID + "|" + string(edgeType)
+		if existing[key] {
+			return
+		}
+		existing[key] = true
+
+		g.addEdgeLocked(&Edge{
+			SourceID: sourceID,
+			TargetID: targetID,
+			Type:     edgeType,
+			Weight:   1.0,
+		})
+		added++
+	}
+
+	addNode := func(n *Node) {
+		if _, ok := g.Nodes[n.ID]; ok {
+			return
+		}
+		g.addNodeLocked(n)
+	}
+
+	// ensureCurrency adds the currency and its central bank, returning the currency node ID
+	ensureCurrency := func(code string) (string, bool) {
+		info, ok := Currencies[code]
+		if !ok {
+			return "", false
+		}
+		currencyID := CurrencyNodeID(code)
+		bankID := centralBankNodeID(code)
+		addNode(&Node{
+			ID:         currencyID,
+			Type:       NodeTypeCurrency,
+			Name:       info.Name,
+			Currency:   code,
+			Attributes: map[string]interface{}{"code": code},
+		})
+		addNode(&Node{
+			ID:         bankID,
+			Type:       NodeTypeCentralBank,
+			Name:       info.CentralBank,
+			Currency:   code,
+			Attributes: map[string]interface{}{"code": code},
+		})
+		addEdge(bankID, currencyID, EdgeTypeIssues)
+		return currencyID, true
+	}
+
+	nodes := make([]*Node, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n)
+	}
+
+	for _, n := range nodes {
+		switch n.Type {
+		case NodeTypeNation:
+			code, ok := CountryCurrency(n.Country())
+			if !ok {
+				continue
+			}
+			if currencyID, ok := ensureCurrency(code); ok {
+				addEdge(n.ID, currencyID, EdgeTypeUsesCurrency)
+			}
+		case NodeTypeCorporation:
+			code := n.reportingCurrency()
+			if code == "" {
+				continue
+			}
+			if currencyID, ok := ensureCurrency(code); ok {
+				addEdge(n.ID, currencyID, EdgeTypeReportsIn)
+			}
+		}
+	}
+
+	if added > 0 {
+		g.triggerAutoSave()
+	}
+	return added
+}
+
+// CurrencyUsers returns the nations using and corporations reporting in a currency
+func (g *Graph) CurrencyUsers(currencyID string) (nations, companies []*Node) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	for _, e := range g.Edges {
+		if e.TargetID != currencyID {
+			continue
+		}
+		n, ok := g.Nodes[e.SourceID]
+		if !ok {
+			continue
+		}
+		switch e.Type {
+		case EdgeTypeUsesCurrency:
+			nations = append(nations, n)
+		case EdgeTypeReportsIn:
+			companies = append(companies, n)
+		}
+	}
+	return nations, companies
+}
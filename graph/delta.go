@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"fmt"
+	"margraf/clock"
+	"margraf/logger"
+)
+
+// DeltaKind identifies what a Delta changes
+type DeltaKind string
+
+const (
+	DeltaNode        DeltaKind = "node"         // Node added or its fields/attributes changed
+	DeltaEdge        DeltaKind = "edge"         // Edge added or its weight/status/attributes changed
+	DeltaEdgeRemoved DeltaKind = "edge_removed" // Edge pruned from the graph
+	DeltaHealth      DeltaKind = "health"       // Node health set to an absolute value
+	DeltaNote        DeltaKind = "note"         // Analyst note added (see notes.go)
+	DeltaNoteRemoved DeltaKind = "note_removed" // Analyst note deleted
+)
+
+// Delta is a single graph change, used to replicate graphs across instances
+type Delta struct {
+	Kind   DeltaKind `json:"kind"`
+	Node   *Node     `json:"node,omitempty"`
+	Edge   *Edge     `json:"edge,omitempty"`
+	NodeID string    `json:"node_id,omitempty"`
+	Health float64   `json:"health,omitempty"`
+	Note   *Note     `json:"note,omitempty"`
+}
+
+// SetChangeHook registers fn to receive every local change. fn is called with
+// the graph lock held, so it must not block or call back into the graph.
+// Changes applied through ApplyDelta are not reported.
+func (g *Graph) SetChangeHook(fn func(Delta)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.changeHook = fn
+}
+
+// emit logs a change and reports it to the hook (must be called with lock held)
+func (g *Graph) emit(d Delta) {
+	if g.applyingRemote {
+		return
+	}
+	if g.wal != nil {
+		if err := g.wal.Append(d); err != nil {
+			logger.Warn(logger.StatusWarn, "Write-ahead log append failed: %v", err)
+		}
+	}
+	if g.changeHook == nil {
+		return
+	}
+	// Hand out copies so the hook can serialize them after the lock is released
+	if d.Node != nil {
+		n := *d.Node
+		n.Attributes = copyAttributes(d.Node.Attributes)
+		d.Node = &n
+	}
+	if d.Edge != nil {
+		e := *d.Edge
+		e.Attributes = copyAttributes(d.Edge.Attributes)
+		d.Edge = &e
+	}
+	if d.Note != nil {
+		n := *d.Note
+		d.Note = &n
+	}
+	g.changeHook(d)
+}
+
+func copyAttributes(attrs map[string]interface{}) map[string]interface{} {
+	if attrs == nil {
+		return nil
+	}
+	cp := make(map[string]interface{}, len(attrs))
+	for k, v := range attrs {
+		cp[k] = v
+	}
+	return cp
+}
+
+// ApplyDelta applies a change published by another instance without
+// reporting it back to the change hook.
+func (g *Graph) ApplyDelta(d Delta) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.applyingRemote = true
+	defer func() { g.applyingRemote = false }()
+
+	switch d.Kind {
+	case DeltaNode:
+		if d.Node == nil {
+			return fmt.Errorf("node delta without node")
+		}
+		internNode(d.Node)
+		if existing, ok := g.Nodes[d.Node.ID]; ok {
+			repriced := existing.Price != d.Node.Price
+			*existing = *d.Node
+			if repriced {
+				g.recordPrice(existing, existing.Price, existing.LastUpdated)
+			}
+		} else {
+			g.Nodes[d.Node.ID] = d.Node
+			g.recordHealth(d.Node.ID, d.Node.Health)
+			g.recordPrice(d.Node, d.Node.Price, d.Node.LastUpdated)
+		}
+		g.forgetRelationsLocked(d.Node.ID)
+
+	case DeltaEdge:
+		if d.Edge == nil {
+			return fmt.Errorf("edge delta without edge")
+		}
+		e := d.Edge
+		var existing *Edge
+		for _, candidate := range g.Adjacency[e.SourceID] {
+			if candidate.TargetID == e.TargetID && candidate.Type == e.Type && candidate.Commodity() == e.Commodity() {
+				existing = candidate
+				break
+			}
+		}
+		if existing != nil {
+			existing.Weight = e.Weight
+			existing.Status = EdgeStatus(intern(string(e.Status)))
+			existing.Override = e.Override
+			existing.Timestamp = e.Timestamp
+			existing.Attributes = e.Attributes
+			e = existing
+		} else {
+			if e.Timestamp.IsZero() {
+				e.Timestamp = clock.Now()
+			}
+			if e.Directionality == "" {
+				e.Directionality = GetEdgeDirectionality(e.Type)
+			}
+			internEdge(e)
+			g.Edges = append(g.Edges, e)
+			if g.Adjacency == nil {
+				g.Adjacency = make(map[string][]*Edge)
+			}
+			g.Adjacency[e.SourceID] = append(g.Adjacency[e.SourceID], e)
+			g.forgetEdgeRelationsLocked(e)
+		}
+		g.recordEdgeHistory(e, "")
+
+	case DeltaEdgeRemoved:
+		if d.Edge == nil {
+			return fmt.Errorf("edge removal without edge")
+		}
+		e := d.Edge
+		for _, candidate := range g.Adjacency[e.SourceID] {
+			if candidate.TargetID == e.TargetID && candidate.Type == e.Type && candidate.Commodity() == e.Commodity() {
+				candidate.Status = e.Status
+				candidate.Timestamp = e.Timestamp
+				g.recordEdgeHistory(candidate, "")
+				g.removeEdgesLocked(map[*Edge]bool{candidate: true})
+				break
+			}
+		}
+
+	case DeltaHealth:
+		node, ok := g.Nodes[d.NodeID]
+		if !ok {
+			return fmt.Errorf("node %s not found", d.NodeID)
+		}
+		node.Health = d.Health
+		g.recordHealth(d.NodeID, d.Health)
+
+	case DeltaNote:
+		if d.Note == nil {
+			return fmt.Errorf("note delta without note")
+		}
+		if _, _, ok := g.findNoteLocked(d.Note.ID); !ok {
+			g.addNoteLocked(*d.Note)
+		}
+
+	case DeltaNoteRemoved:
+		if d.Note == nil {
+			return fmt.Errorf("note removal without note")
+		}
+		g.deleteNoteLocked(d.Note.ID)
+
+	default:
+		return fmt.Errorf("unknown delta kind %q", d.Kind)
+	}
+
+	g.triggerAutoSave()
+	return nil
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"sort"
+	"time"
+)
+
+// Digest summarizes how the graph changed over a period
+type Digest struct {
+	Since       time.Time    `json:"since"`
+	Nodes       int          `json:"nodes"`
+	Edges       int          `json:"edges"`
+	EdgesAdded  int          `json:"edges_added"`  // First recorded in the period
+	EdgesPruned int          `json:"edges_pruned"` // Removed by prune in the period
+	Fallers     []HealthMove `json:"fallers"`      // Largest health drops first
+	Risers      []HealthMove `json:"risers"`       // Largest health gains first
+	Notes       []Note       `json:"notes"`        // Analyst notes added in the period, oldest first
+}
+
+// HealthMove is a node's health change over a period
+type HealthMove struct {
+	NodeID string  `json:"node_id"`
+	Name   string  `json:"name"`
+	From   float64 `json:"from"`
+	To     float64 `json:"to"`
+}
+
+// Change is To - From
+func (m HealthMove) Change() float64 {
+	return m.To - m.From
+}
+
+// Digest summarizes the changes since since, listing up to top fallers and
+// risers. A node's move runs from its last health sample before since (its
+// first in the period if it has none before) to its current health.
+func (g *Graph) Digest(since time.Time, top int) Digest {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	d := Digest{Since: since, Nodes: len(g.Nodes), Edges: len(g.Edges)}
+	for _, h := range g.EdgeHistories {
+		if len(h.History) == 0 {
+			continue
+		}
+		if !h.History[0].Timestamp.Before(since) {
+			d.EdgesAdded++
+		}
+		if last := h.History[len(h.History)-1]; last.Status == EdgeStatusPruned && !last.Timestamp.Before(since) {
+			d.EdgesPruned++
+		}
+	}
+
+	var moves []HealthMove
+	for id, h := range g.HealthHistories {
+		n, ok := g.Nodes[id]
+		if !ok || len(h.History) == 0 || h.History[len(h.History)-1].Timestamp.Before(since) {
+			continue
+		}
+		i := sort.Search(len(h.History), func(i int) bool { return !h.History[i].Timestamp.Before(since) })
+		from := h.History[i].Health
+		if i > 0 {
+			from = h.History[i-1].Health
+		}
+		if m := (HealthMove{NodeID: id, Name: n.Name, From: from, To: n.Health}); m.Change() != 0 {
+			moves = append(moves, m)
+		}
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].Change() != moves[j].Change() {
+			return moves[i].Change() < moves[j].Change()
+		}
+		return moves[i].NodeID < moves[j].NodeID
+	})
+	for _, m := range moves {
+		if m.Change() >= 0 || len(d.Fallers) == top {
+			break
+		}
+		d.Fallers = append(d.Fallers, m)
+	}
+	for i := len(moves) - 1; i >= 0 && moves[i].Change() > 0 && len(d.Risers) < top; i-- {
+		d.Risers = append(d.Risers, moves[i])
+	}
+
+	for _, list := range g.Notes {
+		for _, n := range list {
+			if !n.Time.Before(since) {
+				d.Notes = append(d.Notes, n)
+			}
+		}
+	}
+	SortNotes(d.Notes)
+	return d
+}
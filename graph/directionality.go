@@ -17,6 +17,12 @@ func GetEdgeDirectionality(edgeType EdgeType) EdgeDirectionality {
 		return DirectionalityUnidirectional
 	case EdgeTypeHasCompany:
 		return DirectionalityUnidirectional
+	case EdgeTypeIssues:
+		return DirectionalityUnidirectional // Central bank policy moves its currency
+	case EdgeTypeOwns:
+		return DirectionalityUnidirectional // Parent distress hits subsidiaries
+	case EdgeTypeSubsidiaryOf:
+		return DirectionalityUnidirectional // Subsidiary losses hit the parent
 
 	// Reverse flow edges - shocks flow upstream (client -> supplier)
 	case EdgeTypeProcuresFrom:
@@ -27,6 +33,12 @@ func GetEdgeDirectionality(edgeType EdgeType) EdgeDirectionality {
 		return DirectionalityReverse
 	case EdgeTypeDependsOn:
 		return DirectionalityReverse
+	case EdgeTypeRoutesThrough:
+		return DirectionalityReverse // A blocked chokepoint hits every nation routing through it
+	case EdgeTypeUsesCurrency:
+		return DirectionalityReverse // A currency crisis hits the nations using it
+	case EdgeTypeReportsIn:
+		return DirectionalityReverse
 
 	// Bidirectional edges - shocks flow both ways
 	case EdgeTypeTrade:
@@ -41,7 +53,10 @@ func GetEdgeDirectionality(edgeType EdgeType) EdgeDirectionality {
 		return DirectionalityBidirectional
 
 	default:
-		// Default to bidirectional for unknown types
+		// Declared types say how shocks cross them; unknown ones go both ways
+		if d, ok := declaredEdgeType(edgeType); ok {
+			return d.Directionality
+		}
 		return DirectionalityBidirectional
 	}
 }
@@ -72,47 +87,11 @@ func ShouldPropagateShock(edge *Edge, fromSource bool) bool {
 	}
 }
 
-// GetShockPropagationFactor returns how much of the shock energy propagates through this edge type
-// Some edge types attenuate shocks more than others
+// GetShockPropagationFactor returns the built-in share of shock energy that
+// propagates through this edge type. Shocks use the graph's configured model
+// instead (see Graph.ShockPropagationFactor).
 func GetShockPropagationFactor(edgeType EdgeType) float64 {
-	switch edgeType {
-	// Strong propagation - direct supply chain relationships
-	case EdgeTypeSupplies:
-		return 0.9 // 90% of shock propagates downstream
-	case EdgeTypeManufactures:
-		return 0.9
-	case EdgeTypeProduces:
-		return 0.8
-	case EdgeTypeProcuresFrom:
-		return 0.7 // Upstream propagation slightly weaker
-	case EdgeTypeConsumes:
-		return 0.7
-	case EdgeTypeRequires:
-		return 0.7
-	case EdgeTypeDependsOn:
-		return 0.8
-
-	// Medium propagation - trade and capital
-	case EdgeTypeTrade:
-		return 0.6
-	case EdgeTypeCapital:
-		return 0.5
-
-	// Weak propagation - indirect relationships
-	case EdgeTypeCompetesWith:
-		return 0.3 // Competitors less directly affected
-	case EdgeTypeSubstituteFor:
-		return 0.4
-	case EdgeTypeRegulatory:
-		return 0.4
-	case EdgeTypeHasIndustry:
-		return 0.6
-	case EdgeTypeHasCompany:
-		return 0.5
-
-	default:
-		return 0.5 // Default medium propagation
-	}
+	return builtinPropagation.Factor(edgeType, "", "")
 }
 
 // EdgeDirectionalityDescription returns a human-readable description
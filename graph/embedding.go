@@ -0,0 +1,410 @@
+package graph
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Node embeddings turn the graph's structure into fixed-length vectors that
+// downstream models can use as features. They are learned node2vec-style:
+// weighted random walks over the graph, with edges followed either way, are
+// read as sentences and a skip-gram model with negative sampling places
+// nodes that share walks close together. With Return and InOut at 1 the
+// walks are plain weighted DeepWalk.
+
+// EmbeddingOptions tunes Embed. Zero fields take the defaults.
+type EmbeddingOptions struct {
+	Dimensions   int     `json:"dimensions"`     // Vector length (0 = 64)
+	WalksPerNode int     `json:"walks_per_node"` // Walks started from each node (0 = 10)
+	WalkLength   int     `json:"walk_length"`    // Nodes per walk (0 = 40)
+	Window       int     `json:"window"`         // Nodes either side of a node that count as its context (0 = 5)
+	Return       float64 `json:"p"`              // node2vec p: above 1 makes stepping straight back less likely (0 = 1)
+	InOut        float64 `json:"q"`              // node2vec q: above 1 keeps walks near their start, below 1 sends them outward (0 = 1)
+	Negative     int     `json:"negative"`       // Noise nodes sampled per context pair (0 = 5)
+	Epochs       int     `json:"epochs"`         // Training passes over the walks (0 = 1)
+	LearningRate float64 `json:"learning_rate"`  // Starting step size, decayed linearly to nearly 0 (0 = 0.025)
+	MinWeight    float64 `json:"min_weight"`     // Edges lighter than this are not walked
+	Seed         int64   `json:"seed"`           // The same seed over the same graph gives the same vectors
+
+	Progress func(done, total int) `json:"-"` // Called after each batch of walks is trained, if set
+}
+
+// withDefaults fills in the zero fields
+func (o EmbeddingOptions) withDefaults() EmbeddingOptions {
+	if o.Dimensions <= 0 {
+		o.Dimensions = 64
+	}
+	if o.WalksPerNode <= 0 {
+		o.WalksPerNode = 10
+	}
+	if o.WalkLength <= 0 {
+		o.WalkLength = 40
+	}
+	if o.Window <= 0 {
+		o.Window = 5
+	}
+	if o.Return <= 0 {
+		o.Return = 1
+	}
+	if o.InOut <= 0 {
+		o.InOut = 1
+	}
+	if o.Negative <= 0 {
+		o.Negative = 5
+	}
+	if o.Epochs <= 0 {
+		o.Epochs = 1
+	}
+	if o.LearningRate <= 0 {
+		o.LearningRate = 0.025
+	}
+	return o
+}
+
+// NodeEmbedding is one node's vector
+type NodeEmbedding struct {
+	ID     string    `json:"id"`
+	Name   string    `json:"name,omitempty"`
+	Type   NodeType  `json:"type"`
+	Vector []float32 `json:"vector"`
+}
+
+// Embeddings are the vectors of every node, sorted by node ID
+type Embeddings struct {
+	Created    time.Time        `json:"created"`
+	Dimensions int              `json:"dimensions"`
+	Options    EmbeddingOptions `json:"options"`
+	Nodes      []NodeEmbedding  `json:"nodes"`
+}
+
+// Lookup returns a node's embedding
+func (e *Embeddings) Lookup(id string) (NodeEmbedding, bool) {
+	i := sort.Search(len(e.Nodes), func(i int) bool { return e.Nodes[i].ID >= id })
+	if i < len(e.Nodes) && e.Nodes[i].ID == id {
+		return e.Nodes[i], true
+	}
+	return NodeEmbedding{}, false
+}
+
+// walkNeighbor is an edge as the walks see it: undirected, with the weights
+// of every edge between the pair summed
+type walkNeighbor struct {
+	to     int32
+	weight float64
+}
+
+// walkGraph is the graph snapshot walks run over
+type walkGraph struct {
+	nodes []*Node
+	adj   [][]walkNeighbor // Sorted by to
+	cum   [][]float64      // Running weight totals of adj, for first-order steps
+	bias  []float64        // Scratch running totals for node2vec steps
+}
+
+// walkSnapshot copies what the walks need so the graph is not locked while training
+func (g *Graph) walkSnapshot(minWeight float64) *walkGraph {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	wg := &walkGraph{nodes: make([]*Node, 0, len(g.Nodes))}
+	for _, n := range g.Nodes {
+		wg.nodes = append(wg.nodes, &Node{ID: n.ID, Name: n.Name, Type: n.Type})
+	}
+	sort.Slice(wg.nodes, func(i, j int) bool { return wg.nodes[i].ID < wg.nodes[j].ID })
+	index := make(map[string]int32, len(wg.nodes))
+	for i, n := range wg.nodes {
+		index[n.ID] = int32(i)
+	}
+
+	weights := make([]map[int32]float64, len(wg.nodes))
+	for _, e := range g.Edges {
+		s, ok1 := index[e.SourceID]
+		t, ok2 := index[e.TargetID]
+		if !ok1 || !ok2 || s == t || e.Weight <= 0 || e.Weight < minWeight {
+			continue
+		}
+		for _, p := range [2][2]int32{{s, t}, {t, s}} {
+			if weights[p[0]] == nil {
+				weights[p[0]] = make(map[int32]float64)
+			}
+			weights[p[0]][p[1]] += e.Weight
+		}
+	}
+
+	wg.adj = make([][]walkNeighbor, len(wg.nodes))
+	wg.cum = make([][]float64, len(wg.nodes))
+	for i, m := range weights {
+		list := make([]walkNeighbor, 0, len(m))
+		for to, w := range m {
+			list = append(list, walkNeighbor{to, w})
+		}
+		sort.Slice(list, func(a, b int) bool { return list[a].to < list[b].to })
+		cum := make([]float64, len(list))
+		total := 0.0
+		for j, nb := range list {
+			total += nb.weight
+			cum[j] = total
+		}
+		wg.adj[i], wg.cum[i] = list, cum
+	}
+	return wg
+}
+
+// linked reports whether a and b share an edge
+func (wg *walkGraph) linked(a, b int32) bool {
+	list := wg.adj[a]
+	i := sort.Search(len(list), func(i int) bool { return list[i].to >= b })
+	return i < len(list) && list[i].to == b
+}
+
+// index returns the position of the node with the given ID
+func (wg *walkGraph) index(id string) (int32, bool) {
+	i := sort.Search(len(wg.nodes), func(i int) bool { return wg.nodes[i].ID >= id })
+	if i < len(wg.nodes) && wg.nodes[i].ID == id {
+		return int32(i), true
+	}
+	return 0, false
+}
+
+// step picks a neighbour of cur in proportion to edge weight, or reports a
+// dead end
+func (wg *walkGraph) step(rng *rand.Rand, cur int32) (int32, bool) {
+	list := wg.adj[cur]
+	if len(list) == 0 {
+		return 0, false
+	}
+	cum := wg.cum[cur]
+	r := rng.Float64() * cum[len(cum)-1]
+	return list[min(sort.SearchFloat64s(cum, r), len(list)-1)].to, true
+}
+
+// walk fills buf with a walk from start and returns it, cut short at a dead end
+func (wg *walkGraph) walk(rng *rand.Rand, start int32, opts EmbeddingOptions, buf []int32) []int32 {
+	buf = append(buf[:0], start)
+	firstOrder := opts.Return == 1 && opts.InOut == 1
+	for len(buf) < opts.WalkLength {
+		cur := buf[len(buf)-1]
+		list := wg.adj[cur]
+		if len(list) == 0 {
+			break
+		}
+		if firstOrder || len(buf) == 1 {
+			next, _ := wg.step(rng, cur)
+			buf = append(buf, next)
+			continue
+		}
+		// node2vec: weight the step by where it leads relative to the previous node
+		prev := buf[len(buf)-2]
+		bias := wg.bias[:0]
+		total := 0.0
+		for _, nb := range list {
+			w := nb.weight
+			switch {
+			case nb.to == prev:
+				w /= opts.Return
+			case !wg.linked(prev, nb.to):
+				w /= opts.InOut
+			}
+			total += w
+			bias = append(bias, total)
+		}
+		wg.bias = bias
+		r := rng.Float64() * total
+		buf = append(buf, list[min(sort.SearchFloat64s(bias, r), len(list)-1)].to)
+	}
+	return buf
+}
+
+// embedBatch is how many walks are trained between progress reports and cancellation checks
+const embedBatch = 256
+
+// Embed learns a vector for every node from random walks over the graph (see
+// EmbeddingOptions). Nodes without edges keep their small random starting
+// vector. It returns ctx's error if ctx is done before training finishes.
+func (g *Graph) Embed(ctx context.Context, opts EmbeddingOptions) (*Embeddings, error) {
+	opts = opts.withDefaults()
+	wg := g.walkSnapshot(opts.MinWeight)
+	n, dim := len(wg.nodes), opts.Dimensions
+	if n == 0 {
+		return nil, fmt.Errorf("graph has no nodes to embed")
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	// Walks are generated up front so the noise distribution can follow how
+	// often each node is visited
+	var walks [][]int32
+	buf := make([]int32, 0, opts.WalkLength)
+	counts := make([]float64, n)
+	for r := 0; r < opts.WalksPerNode; r++ {
+		for _, start := range rng.Perm(n) {
+			w := wg.walk(rng, int32(start), opts, buf)
+			if len(w) < 2 {
+				continue
+			}
+			w = append([]int32(nil), w...)
+			for _, v := range w {
+				counts[v]++
+			}
+			walks = append(walks, w)
+		}
+	}
+
+	// Unigram^0.75 noise distribution, as in word2vec
+	noise := make([]float64, n)
+	total := 0.0
+	for i, c := range counts {
+		total += math.Pow(c, 0.75)
+		noise[i] = total
+	}
+
+	in := make([]float32, n*dim)
+	out := make([]float32, n*dim)
+	for i := range in {
+		in[i] = (rng.Float32() - 0.5) / float32(dim)
+	}
+	grad := make([]float32, dim)
+
+	steps := float64(opts.Epochs * len(walks))
+	done := 0
+	for epoch := 0; epoch < opts.Epochs; epoch++ {
+		for wi, walk := range walks {
+			if wi%embedBatch == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if opts.Progress != nil {
+					opts.Progress(done, int(steps))
+				}
+			}
+			lr := float32(opts.LearningRate * max(1-float64(done)/steps, 0.0001))
+			done++
+			for i, center := range walk {
+				b := rng.Intn(opts.Window) + 1
+				for j := max(i-b, 0); j <= min(i+b, len(walk)-1); j++ {
+					if j == i {
+						continue
+					}
+					ctxVec := in[int(walk[j])*dim : int(walk[j]+1)*dim]
+					clear(grad)
+					for d := 0; d <= opts.Negative; d++ {
+						target, label := center, float32(1)
+						if d > 0 {
+							target = int32(min(sort.SearchFloat64s(noise, rng.Float64()*total), n-1))
+							if target == center {
+								continue
+							}
+							label = 0
+						}
+						outVec := out[int(target)*dim : int(target+1)*dim]
+						var f float32
+						for k := range ctxVec {
+							f += ctxVec[k] * outVec[k]
+						}
+						step := (label - sigmoid(f)) * lr
+						for k := range ctxVec {
+							grad[k] += step * outVec[k]
+							outVec[k] += step * ctxVec[k]
+						}
+					}
+					for k := range ctxVec {
+						ctxVec[k] += grad[k]
+					}
+				}
+			}
+		}
+	}
+	if opts.Progress != nil {
+		opts.Progress(int(steps), int(steps))
+	}
+
+	emb := &Embeddings{Created: time.Now().UTC(), Dimensions: dim, Options: opts, Nodes: make([]NodeEmbedding, n)}
+	for i, node := range wg.nodes {
+		emb.Nodes[i] = NodeEmbedding{ID: node.ID, Name: node.Name, Type: node.Type, Vector: in[i*dim : (i+1)*dim : (i+1)*dim]}
+	}
+	return emb, nil
+}
+
+// sigmoid is the logistic function, saturated past |x| = 6 like word2vec
+func sigmoid(x float32) float32 {
+	switch {
+	case x > 6:
+		return 1
+	case x < -6:
+		return 0
+	}
+	return float32(1 / (1 + math.Exp(-float64(x))))
+}
+
+// WriteCSV writes one row per node: id, name, type, then the vector's
+// components as d0, d1, ...
+func (e *Embeddings) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(bufio.NewWriterSize(w, streamBuffer))
+	header := []string{"id", "name", "type"}
+	for i := 0; i < e.Dimensions; i++ {
+		header = append(header, "d"+strconv.Itoa(i))
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(header))
+	for _, n := range e.Nodes {
+		row = append(row[:0], n.ID, n.Name, string(n.Type))
+		for _, v := range n.Vector {
+			row = append(row, strconv.FormatFloat(float64(v), 'g', -1, 32))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Save writes the embeddings to filename, as CSV for .csv files and JSON
+// otherwise. A failed save removes the partial file.
+func (e *Embeddings) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	write := func(w io.Writer) error { return json.NewEncoder(w).Encode(e) }
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		write = e.WriteCSV
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(filename)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(filename)
+		return err
+	}
+	return nil
+}
+
+// LoadEmbeddings reads embeddings saved as JSON
+func LoadEmbeddings(filename string) (*Embeddings, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var e Embeddings
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	sort.Slice(e.Nodes, func(i, j int) bool { return e.Nodes[i].ID < e.Nodes[j].ID })
+	return &e, nil
+}
@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"margraf/clock"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Events (news items, shocks, policy changes) update edges under an event ID
+// that is recorded in each edge's history. Replaying an event - a backfill, a
+// feed reconnect that re-delivers items, a retried job - must not apply it
+// twice, so an update carrying an event ID the edge has already seen is a
+// no-op, and whole events can be claimed once with ClaimEvent.
+
+// ErrEventApplied is returned by UpdateEdgeWeight when the edge's history
+// already holds the update's event ID
+var ErrEventApplied = errors.New("event already applied")
+
+// appliedEventRetention is how long ClaimEvent remembers an event
+const appliedEventRetention = 90 * 24 * time.Hour
+
+var eventSeq atomic.Uint64
+
+// NewEventID returns a fresh event ID with the given prefix, for changes
+// that are not replays of an earlier event (manual shocks, simulations)
+func NewEventID(prefix string) string {
+	return fmt.Sprintf("%s_%d_%d", prefix, time.Now().Unix(), eventSeq.Add(1))
+}
+
+// StableEventID derives an event ID from the event's content (e.g. a headline
+// and its link), so the same event gets the same ID each time it is seen
+func StableEventID(prefix string, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return prefix + "_" + hex.EncodeToString(sum[:6])
+}
+
+// EventApplied reports whether eventID has been claimed
+func (g *Graph) EventApplied(eventID string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, ok := g.AppliedEvents[eventID]
+	return ok
+}
+
+// ClaimEvent marks eventID as applied and reports whether it was new. Callers
+// apply an event only when the claim succeeds.
+func (g *Graph) ClaimEvent(eventID string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.AppliedEvents[eventID]; ok {
+		return false
+	}
+	if g.AppliedEvents == nil {
+		g.AppliedEvents = make(map[string]time.Time)
+	}
+	g.AppliedEvents[intern(eventID)] = clock.Now()
+	return true
+}
+
+// pruneAppliedEventsLocked forgets claims older than the retention window
+// (must be called with lock held)
+func (g *Graph) pruneAppliedEventsLocked() {
+	cutoff := time.Now().Add(-appliedEventRetention)
+	for id, at := range g.AppliedEvents {
+		if at.Before(cutoff) {
+			delete(g.AppliedEvents, id)
+		}
+	}
+}
+
+// edgeSawEventLocked reports whether e's history holds eventID (must be
+// called with lock held). The history's event IDs are indexed on the first
+// lookup and kept up to date by recordEdgeHistory, so the check does not
+// grow with the edge's age.
+func (g *Graph) edgeSawEventLocked(e *Edge, eventID string) bool {
+	history, ok := g.EdgeHistories[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())]
+	if !ok {
+		return false
+	}
+	if history.events == nil {
+		history.events = make(map[string]struct{}, len(history.History))
+		for _, s := range history.History {
+			if s.EventID != "" {
+				history.events[s.EventID] = struct{}{}
+			}
+		}
+	}
+	_, seen := history.events[eventID]
+	return seen
+}
@@ -0,0 +1,168 @@
+package graph
+
+import "strings"
+
+// AttrExchange is the code of the exchange a corporation's ticker is listed
+// on (see Exchanges), set by SetNodeTicker
+const AttrExchange = "exchange"
+
+// Exchange is a stock exchange as Yahoo Finance symbols name it: a listing
+// on it has Suffix appended to its local code (7203.T, SAP.DE, RELIANCE.NS).
+// US listings have no suffix.
+type Exchange struct {
+	Code     string // Short name, stored in AttrExchange
+	Name     string
+	Suffix   string // Including the dot; empty for US listings
+	Country  string // ISO registry name
+	Currency string // ISO 4217 code prices are quoted in
+}
+
+// Exchanges lists the exchanges tickers are resolved against, keyed by suffix
+var Exchanges = map[string]Exchange{
+	"":    {"US", "NYSE / Nasdaq", "", "United States", "USD"},
+	".TO": {"TSX", "Toronto Stock Exchange", ".TO", "Canada", "CAD"},
+	".MX": {"BMV", "Mexican Stock Exchange", ".MX", "Mexico", "MXN"},
+	".SA": {"B3", "B3 (Sao Paulo)", ".SA", "Brazil", "BRL"},
+	".SN": {"BCS", "Santiago Stock Exchange", ".SN", "Chile", "CLP"},
+	".BA": {"BCBA", "Buenos Aires Stock Exchange", ".BA", "Argentina", "ARS"},
+	".L":  {"LSE", "London Stock Exchange", ".L", "United Kingdom", "GBP"},
+	".IR": {"ISE", "Euronext Dublin", ".IR", "Ireland", "EUR"},
+	".PA": {"EPA", "Euronext Paris", ".PA", "France", "EUR"},
+	".DE": {"XETRA", "Xetra (Frankfurt)", ".DE", "Germany", "EUR"},
+	".F":  {"FRA", "Frankfurt Stock Exchange", ".F", "Germany", "EUR"},
+	".MI": {"BIT", "Borsa Italiana", ".MI", "Italy", "EUR"},
+	".MC": {"BME", "Bolsa de Madrid", ".MC", "Spain", "EUR"},
+	".LS": {"ELI", "Euronext Lisbon", ".LS", "Portugal", "EUR"},
+	".AS": {"AMS", "Euronext Amsterdam", ".AS", "Netherlands", "EUR"},
+	".BR": {"EBR", "Euronext Brussels", ".BR", "Belgium", "EUR"},
+	".VI": {"VIE", "Vienna Stock Exchange", ".VI", "Austria", "EUR"},
+	".HE": {"HEL", "Nasdaq Helsinki", ".HE", "Finland", "EUR"},
+	".AT": {"ATH", "Athens Stock Exchange", ".AT", "Greece", "EUR"},
+	".SW": {"SIX", "SIX Swiss Exchange", ".SW", "Switzerland", "CHF"},
+	".ST": {"STO", "Nasdaq Stockholm", ".ST", "Sweden", "SEK"},
+	".OL": {"OSL", "Oslo Bors", ".OL", "Norway", "NOK"},
+	".CO": {"CPH", "Nasdaq Copenhagen", ".CO", "Denmark", "DKK"},
+	".WA": {"WSE", "Warsaw Stock Exchange", ".WA", "Poland", "PLN"},
+	".IS": {"BIST", "Borsa Istanbul", ".IS", "Turkey", "TRY"},
+	".TA": {"TASE", "Tel Aviv Stock Exchange", ".TA", "Israel", "ILS"},
+	".SR": {"TADAWUL", "Saudi Exchange (Tadawul)", ".SR", "Saudi Arabia", "SAR"},
+	".JO": {"JSE", "Johannesburg Stock Exchange", ".JO", "South Africa", "ZAR"},
+	".SS": {"SSE", "Shanghai Stock Exchange", ".SS", "China", "CNY"},
+	".SZ": {"SZSE", "Shenzhen Stock Exchange", ".SZ", "China", "CNY"},
+	".T":  {"TSE", "Tokyo Stock Exchange", ".T", "Japan", "JPY"},
+	".KS": {"KRX", "Korea Exchange (KOSPI)", ".KS", "South Korea", "KRW"},
+	".KQ": {"KOSDAQ", "KOSDAQ", ".KQ", "South Korea", "KRW"},
+	".TW": {"TWSE", "Taiwan Stock Exchange", ".TW", "Taiwan", "TWD"},
+	".HK": {"HKEX", "Hong Kong Stock Exchange", ".HK", "Hong Kong", "HKD"},
+	".NS": {"NSE", "National Stock Exchange of India", ".NS", "India", "INR"},
+	".BO": {"BSE", "BSE (Bombay)", ".BO", "India", "INR"},
+	".JK": {"IDX", "Indonesia Stock Exchange", ".JK", "Indonesia", "IDR"},
+	".BK": {"SET", "Stock Exchange of Thailand", ".BK", "Thailand", "THB"},
+	".KL": {"BURSA", "Bursa Malaysia", ".KL", "Malaysia", "MYR"},
+	".SI": {"SGX", "Singapore Exchange", ".SI", "Singapore", "SGD"},
+	".AX": {"ASX", "Australian Securities Exchange", ".AX", "Australia", "AUD"},
+	".NZ": {"NZX", "New Zealand Exchange", ".NZ", "New Zealand", "NZD"},
+}
+
+// primaryExchanges maps lowercased ISO registry names of countries to the
+// suffix of the exchange most of their companies list on, where it is not
+// the only one for the country
+var primaryExchanges = map[string]string{
+	"germany":     ".DE",
+	"china":       ".SS",
+	"south korea": ".KS",
+	"india":       ".NS",
+}
+
+// ExchangeOf returns the exchange a Yahoo symbol is listed on: the one its
+// suffix names, or the US for a symbol without one. Share classes written
+// with a dot (BRK.B) count as US.
+func ExchangeOf(ticker string) (Exchange, bool) {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	if ticker == "" {
+		return Exchange{}, false
+	}
+	if i := strings.LastIndex(ticker, "."); i > 0 {
+		if ex, ok := Exchanges[ticker[i:]]; ok {
+			return ex, true
+		}
+		if len(ticker)-i > 2 {
+			return Exchange{}, false // An exchange suffix we don't know
+		}
+	}
+	return Exchanges[""], true
+}
+
+// CountryExchange returns the main exchange of a country
+func CountryExchange(country string) (Exchange, bool) {
+	info, ok := LookupCountry(country)
+	if !ok {
+		return Exchange{}, false
+	}
+	name := strings.ToLower(info.Name)
+	if suffix, ok := primaryExchanges[name]; ok {
+		return Exchanges[suffix], true
+	}
+	for _, ex := range Exchanges {
+		if strings.ToLower(ex.Country) == name {
+			return ex, true
+		}
+	}
+	return Exchange{}, false
+}
+
+// QualifyTicker uppercases a symbol and gives a bare local code the suffix
+// of its company's country: "7203" in Japan becomes "7203.T". Symbols with a
+// suffix, and letter codes (which may be US listings or ADRs), are kept.
+func QualifyTicker(ticker, country string) string {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	if ticker == "" || strings.Contains(ticker, ".") {
+		return ticker
+	}
+	for _, r := range ticker {
+		if r < '0' || r > '9' {
+			return ticker
+		}
+	}
+	if info, ok := LookupCountry(country); ok && strings.EqualFold(info.Name, "india") {
+		return ticker + ".BO" // Indian numeric codes are BSE's; NSE uses symbols
+	}
+	if ex, ok := CountryExchange(country); ok && ex.Suffix != "" {
+		if ex.Suffix == ".HK" && len(ticker) < 4 {
+			ticker = strings.Repeat("0", 4-len(ticker)) + ticker // Yahoo pads Hong Kong codes: 0005.HK
+		}
+		return ticker + ex.Suffix
+	}
+	return ticker
+}
+
+// minorUnits are the quote currencies Yahoo gives in hundredths of the
+// currency proper (London in pence, Johannesburg in cents, Tel Aviv in agorot)
+var minorUnits = map[string]string{
+	"GBp": "GBP",
+	"GBX": "GBP",
+	"ZAc": "ZAR",
+	"ZAC": "ZAR",
+	"ILA": "ILS",
+}
+
+// minorQuoted are the suffixes of exchanges quoted in a minor unit
+var minorQuoted = map[string]bool{".L": true, ".JO": true, ".TA": true}
+
+// NormalizeQuote converts a price quoted in a minor unit to its currency
+// proper (1234 GBp becomes 12.34 GBP) and uppercases the code. An empty
+// currency is taken from the ticker's exchange, in the unit it quotes in.
+func NormalizeQuote(ticker string, price float64, currency string) (float64, string) {
+	if major, ok := minorUnits[currency]; ok {
+		return price / 100, major
+	}
+	if currency == "" {
+		if ex, ok := ExchangeOf(ticker); ok {
+			if minorQuoted[ex.Suffix] {
+				return price / 100, ex.Currency
+			}
+			return price, ex.Currency
+		}
+	}
+	return price, strings.ToUpper(currency)
+}
@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"sort"
+	"time"
+)
+
+// An edge explanation gathers what the graph knows about one relationship
+// from the structures that keep it: the edge itself, the confidence model,
+// the weight history and the kept impacts of the events that moved it, and
+// analyst notes. Discovery's evidence lives in the audit log, outside the
+// graph, and is added by the caller.
+
+// maxExplainEvents bounds the recent events an explanation lists
+const maxExplainEvents = 5
+
+// EdgeExplanation is everything the graph holds about one edge
+type EdgeExplanation struct {
+	Edge           Edge          `json:"edge"`
+	Origin         string        `json:"origin,omitempty"`
+	Confidence     float64       `json:"confidence"`
+	Scale          float64       `json:"scale"`          // Share of its propagation factor a shock keeps through it
+	Directionality string        `json:"directionality"` // How shocks cross it, in words
+	FirstSeen      time.Time     `json:"first_seen"`
+	LastEvidence   time.Time     `json:"last_evidence"` // Last change that was not maintenance
+	Changes        int           `json:"changes"`       // Recorded weight changes
+	EventChanges   int           `json:"event_changes"` // Of which news events and shocks
+	MinWeight      float64       `json:"min_weight"`
+	MaxWeight      float64       `json:"max_weight"`
+	Decay          float64       `json:"decay"`  // Weight decay has taken over the history
+	Event          float64       `json:"event"`  // Weight its causes have added over it
+	Events         []WeightPoint `json:"events"` // Most recent event changes, newest first
+	Notes          []Note        `json:"notes,omitempty"`
+}
+
+// ExplainEdges explains every edge from sourceID to targetID, ordered by
+// type and commodity
+func (g *Graph) ExplainEdges(sourceID, targetID string) []EdgeExplanation {
+	g.mu.RLock()
+	var out []EdgeExplanation
+	for _, e := range g.Adjacency[sourceID] {
+		if e.TargetID != targetID {
+			continue
+		}
+		x := EdgeExplanation{
+			Edge:           *e,
+			Origin:         EdgeOrigin(e),
+			Directionality: EdgeDirectionalityDescription(e.Type),
+			FirstSeen:      e.Timestamp,
+			LastEvidence:   g.lastEvidenceLocked(e),
+			MinWeight:      e.Weight,
+			MaxWeight:      e.Weight,
+			Notes:          append([]Note(nil), g.Notes[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())]...),
+		}
+		x.Edge.Attributes = copyAttributes(e.Attributes)
+		if e.Override != nil {
+			o := *e.Override
+			x.Edge.Override = &o
+		}
+		if history, ok := g.EdgeHistories[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())]; ok && len(history.History) > 0 {
+			s := weightSeries(history)
+			x.FirstSeen, x.Changes, x.Decay, x.Event = s.Points[0].Time, len(s.Points)-1, s.Decay, s.Event
+			for i := len(s.Points) - 1; i >= 0; i-- {
+				p := s.Points[i]
+				x.MinWeight, x.MaxWeight = min(x.MinWeight, p.Weight), max(x.MaxWeight, p.Weight)
+				if p.Cause != CauseEvent {
+					continue
+				}
+				x.EventChanges++
+				if len(x.Events) < maxExplainEvents {
+					x.Events = append(x.Events, p)
+				}
+			}
+		}
+		out = append(out, x)
+	}
+	g.mu.RUnlock()
+
+	for i := range out {
+		out[i].Confidence, out[i].Scale = g.EdgeConfidence(&out[i].Edge)
+		g.describeEvents(out[i].Events)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Edge.Type != out[j].Edge.Type {
+			return out[i].Edge.Type < out[j].Edge.Type
+		}
+		return out[i].Edge.Commodity() < out[j].Edge.Commodity()
+	})
+	return out
+}
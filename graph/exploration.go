@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"sort"
+	"time"
+)
+
+// ExplorationState records how far discovery has expanded a node. It is
+// separate from the node existing: a supplier added while exploring a client
+// is in the graph but unexplored.
+type ExplorationState string
+
+const (
+	ExplorationUnexplored ExplorationState = "unexplored" // Added, never expanded
+	ExplorationPartial    ExplorationState = "partial"    // Expansion started but was cut short (error, cancellation)
+	ExplorationComplete   ExplorationState = "complete"   // All of the node's discovery steps finished
+)
+
+// Exploration attribute keys
+const (
+	AttrExploration = "exploration"
+	AttrExploredAt  = "explored_at" // RFC 3339 time the state last changed
+)
+
+// Exploration returns the node's exploration state (unexplored if unset)
+func (n *Node) Exploration() ExplorationState {
+	if s, ok := n.Attributes[AttrExploration].(string); ok && s != "" {
+		return ExplorationState(s)
+	}
+	return ExplorationUnexplored
+}
+
+// ExploredAt returns when the node's exploration state last changed (zero if never)
+func (n *Node) ExploredAt() time.Time {
+	s, _ := n.Attributes[AttrExploredAt].(string)
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+// SetExploration records a node's exploration state, stamped with the current time
+func (g *Graph) SetExploration(id string, state ExplorationState) error {
+	return g.UpdateNodeAttributes(id, map[string]interface{}{
+		AttrExploration: string(state),
+		AttrExploredAt:  time.Now().UTC().Format(time.RFC3339),
+	}, "explore")
+}
+
+// ExplorationCounts returns how many nodes of each type are in each state
+func (g *Graph) ExplorationCounts() map[NodeType]map[ExplorationState]int {
+	counts := make(map[NodeType]map[ExplorationState]int)
+	g.NodesRange(func(n *Node) {
+		if counts[n.Type] == nil {
+			counts[n.Type] = make(map[ExplorationState]int)
+		}
+		counts[n.Type][n.Exploration()]++
+	})
+	return counts
+}
+
+// Frontier returns up to limit nodes of the given types (none = any) that are
+// not fully explored: never-explored nodes first, then partial ones by oldest
+// attempt, so a node that keeps failing does not hold up the rest. limit <= 0
+// returns all of them.
+func (g *Graph) Frontier(limit int, types ...NodeType) []*Node {
+	want := make(map[NodeType]bool, len(types))
+	for _, t := range types {
+		want[t] = true
+	}
+
+	var frontier []*Node
+	g.NodesRange(func(n *Node) {
+		if len(want) > 0 && !want[n.Type] {
+			return
+		}
+		if n.Exploration() != ExplorationComplete {
+			frontier = append(frontier, n)
+		}
+	})
+
+	sort.Slice(frontier, func(i, j int) bool {
+		a, b := frontier[i], frontier[j]
+		if ta, tb := a.ExploredAt(), b.ExploredAt(); !ta.Equal(tb) {
+			return ta.Before(tb)
+		}
+		return a.ID < b.ID
+	})
+	if limit > 0 && len(frontier) > limit {
+		frontier = frontier[:limit]
+	}
+	return frontier
+}
+
+// Stale returns up to limit fully explored nodes of the given types (none =
+// any) last explored before cutoff, oldest first, so discovery can be re-run
+// on parts of the graph that have not been looked at in a while. limit <= 0
+// returns all of them.
+func (g *Graph) Stale(cutoff time.Time, limit int, types ...NodeType) []*Node {
+	want := make(map[NodeType]bool, len(types))
+	for _, t := range types {
+		want[t] = true
+	}
+
+	var stale []*Node
+	g.NodesRange(func(n *Node) {
+		if len(want) > 0 && !want[n.Type] {
+			return
+		}
+		if n.Exploration() == ExplorationComplete && n.ExploredAt().Before(cutoff) {
+			stale = append(stale, n)
+		}
+	})
+
+	sort.Slice(stale, func(i, j int) bool {
+		a, b := stale[i], stale[j]
+		if ta, tb := a.ExploredAt(), b.ExploredAt(); !ta.Equal(tb) {
+			return ta.Before(tb)
+		}
+		return a.ID < b.ID
+	})
+	if limit > 0 && len(stale) > limit {
+		stale = stale[:limit]
+	}
+	return stale
+}
@@ -1,51 +1,160 @@
 package graph
 
 import (
-	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 )
 
+// DOTOptions trims and styles a DOT export. The zero value exports the whole
+// graph with the default styling.
+type DOTOptions struct {
+	NodeTypes     []NodeType // Only these node types (empty = all)
+	MinWeight     float64    // Drop edges lighter than this
+	Center        string     // Only nodes within Hops of this node ID ("" = whole graph)
+	Hops          int        // Neighbourhood radius around Center, following edges either way
+	ColorByHealth bool       // Fill nodes red (failing) to green (normal) instead of by type
+	WeightWidth   bool       // Draw heavier edges thicker
+}
+
 // ToDOT returns the graph in Graphviz DOT format.
 func (g *Graph) ToDOT() string {
+	dot, _ := g.ToDOTWith(DOTOptions{})
+	return dot
+}
+
+// ToDOTWith returns the part of the graph selected by opts in Graphviz DOT
+// format. Edges are kept only when both ends are. It fails if opts.Center is
+// not in the graph.
+func (g *Graph) ToDOTWith(opts DOTOptions) (string, error) {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
+	types := make(map[NodeType]bool, len(opts.NodeTypes))
+	for _, t := range opts.NodeTypes {
+		types[t] = true
+	}
+	keepEdge := func(e *Edge) bool { return e.Weight >= opts.MinWeight }
+	keepNode := func(n *Node) bool { return len(types) == 0 || types[n.Type] }
+
+	var included map[string]bool
+	if opts.Center != "" {
+		center, ok := g.Nodes[opts.Center]
+		if !ok {
+			return "", fmt.Errorf("node %s not found", opts.Center)
+		}
+		included = g.neighbourhoodLocked(center.ID, opts.Hops, keepEdge, keepNode)
+	}
+
+	ids := make([]string, 0, len(g.Nodes))
+	for id, n := range g.Nodes {
+		if (included == nil || included[id]) && (keepNode(n) || id == opts.Center) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	kept := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		kept[id] = true
+	}
+
 	var w strings.Builder
 	w.WriteString("digraph FDKG {\n")
 	w.WriteString("  rankdir=LR;\n")
 	w.WriteString("  node [shape=box, style=filled, fontname=\"Arial\"];\n")
 
 	// Nodes
-	for _, n := range g.Nodes {
-		color := "lightgrey"
-		switch n.Type {
-		case NodeTypeNation:
-			color = "lightblue"
-		case NodeTypeCorporation:
-			color = "salmon"
-		case NodeTypeIndustry:
-			color = "lightyellow"
-		case NodeTypeRawMaterial:
-			color = "lightgreen"
+	for _, id := range ids {
+		n := g.Nodes[id]
+		color := typeColor(n.Type)
+		if opts.ColorByHealth {
+			color = healthColor(n.Health)
 		}
-		
+
 		// Label with Price if available
 		label := fmt.Sprintf("%s\n(%s)\nHealth: %.2f", n.Name, n.Type, n.Health)
 		if n.Price > 0 {
 			label += fmt.Sprintf("\n$%.2f", n.Price)
 		}
 
-		w.WriteString(fmt.Sprintf("  \"%s\" [label=\" %s \", fillcolor=\" %s \"];\n", n.ID, label, color))
+		w.WriteString(fmt.Sprintf("  \"%s\" [label=\" %s \", fillcolor=\"%s\"];\n", n.ID, label, color))
 	}
 
 	// Edges
 	for _, e := range g.Edges {
-		w.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\" %s \", weight=%.2f];\n", e.SourceID, e.TargetID, e.Type, e.Weight))
+		if !keepEdge(e) || !kept[e.SourceID] || !kept[e.TargetID] {
+			continue
+		}
+		label := string(e.Type)
+		if code := e.Commodity(); code != "" {
+			label = fmt.Sprintf("%s[%s]", e.Type, code)
+		}
+		style := ""
+		if opts.WeightWidth {
+			style = fmt.Sprintf(", penwidth=%.2f", 0.5+4*math.Min(math.Max(e.Weight, 0), 1))
+		}
+		w.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\" %s \", weight=%.2f%s];\n", e.SourceID, e.TargetID, label, e.Weight, style))
 	}
 
 	w.WriteString("}\n")
-	return w.String()
+	return w.String(), nil
+}
+
+// neighbourhoodLocked returns the IDs within hops of id, following kept edges
+// in either direction through kept nodes (must be called with lock held)
+func (g *Graph) neighbourhoodLocked(id string, hops int, keepEdge func(*Edge) bool, keepNode func(*Node) bool) map[string]bool {
+	neighbours := make(map[string][]string)
+	for _, e := range g.Edges {
+		if keepEdge(e) {
+			neighbours[e.SourceID] = append(neighbours[e.SourceID], e.TargetID)
+			neighbours[e.TargetID] = append(neighbours[e.TargetID], e.SourceID)
+		}
+	}
+
+	seen := map[string]bool{id: true}
+	frontier := []string{id}
+	for hop := 0; hop < hops && len(frontier) > 0; hop++ {
+		var next []string
+		for _, current := range frontier {
+			for _, nb := range neighbours[current] {
+				if n, ok := g.Nodes[nb]; ok && !seen[nb] && keepNode(n) {
+					seen[nb] = true
+					next = append(next, nb)
+				}
+			}
+		}
+		frontier = next
+	}
+	return seen
+}
+
+// typeColor is a node's default DOT fill color
+func typeColor(t NodeType) string {
+	switch t {
+	case NodeTypeNation:
+		return "lightblue"
+	case NodeTypeCorporation:
+		return "salmon"
+	case NodeTypeIndustry:
+		return "lightyellow"
+	case NodeTypeRawMaterial:
+		return "lightgreen"
+	}
+	if d, ok := declaredNodeType(t); ok && d.Color != "" {
+		return d.Color
+	}
+	return "lightgrey"
+}
+
+// healthColor is a DOT HSV fill running from red at health 0 through yellow
+// to green at 1 (normal), turning teal as health rises to 2 (booming)
+func healthColor(health float64) string {
+	hue := 0.333 * math.Min(math.Max(health, 0), 1)
+	if health > 1 {
+		hue += 0.167 * math.Min(health-1, 1)
+	}
+	return fmt.Sprintf("%.3f 0.45 1.000", hue)
 }
 
 // GraphData represents the graph in a format suitable for D3.js force-directed layouts
@@ -62,54 +171,28 @@ type NodeData struct {
 	Health float64 `json:"health"`
 	Price  float64 `json:"price,omitempty"`
 	Ticker string  `json:"ticker,omitempty"`
+
+	Rollup *IndustryRollup `json:"rollup,omitempty"` // Industry nodes (see industry.go)
+	Notes  []Note          `json:"notes,omitempty"`  // Analyst notes (see notes.go)
 }
 
 // LinkData represents an edge for visualization
 type LinkData struct {
-	Source string  `json:"source"`
-	Target string  `json:"target"`
-	Type   string  `json:"type"`
-	Weight float64 `json:"weight"`
-	Status string  `json:"status"`
+	Source    string  `json:"source"`
+	Target    string  `json:"target"`
+	Type      string  `json:"type"`
+	Weight    float64 `json:"weight"`
+	Status    string  `json:"status"`
+	Commodity string  `json:"commodity,omitempty"` // HS code for commodity-specific trade edges
+	Notes     []Note  `json:"notes,omitempty"`     // Analyst notes (see notes.go)
 }
 
-// ToJSON returns the graph in a JSON format suitable for D3.js force-directed graphs
+// ToJSON returns the graph in a JSON format suitable for D3.js force-directed
+// graphs. Use WriteJSON to export large graphs without building the string.
 func (g *Graph) ToJSON() (string, error) {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
-
-	data := GraphData{
-		Nodes: make([]NodeData, 0, len(g.Nodes)),
-		Links: make([]LinkData, 0, len(g.Edges)),
-	}
-
-	// Convert nodes
-	for _, n := range g.Nodes {
-		data.Nodes = append(data.Nodes, NodeData{
-			ID:     n.ID,
-			Name:   n.Name,
-			Type:   string(n.Type),
-			Health: n.Health,
-			Price:  n.Price,
-			Ticker: n.Ticker,
-		})
-	}
-
-	// Convert edges
-	for _, e := range g.Edges {
-		data.Links = append(data.Links, LinkData{
-			Source: e.SourceID,
-			Target: e.TargetID,
-			Type:   string(e.Type),
-			Weight: e.Weight,
-			Status: e.Status,
-		})
-	}
-
-	jsonBytes, err := json.Marshal(data)
-	if err != nil {
+	var w strings.Builder
+	if err := g.WriteJSON(&w); err != nil {
 		return "", err
 	}
-
-	return string(jsonBytes), nil
+	return w.String(), nil
 }
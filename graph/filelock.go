@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"margraf/logger"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The main app auto-saves its graph file, and the trading CLI and other
+// analysis tools read the same file. A second writer would silently undo the
+// first one's changes, so a writer takes an advisory lock on the file, tools
+// that only read open the graph read-only, and a writer that finds the file
+// changed underneath it stops auto-saving rather than overwrite it.
+
+// ErrLocked is returned (wrapped) by LockFile when another process holds the lock
+var ErrLocked = errors.New("graph file locked")
+
+// ErrReadOnly is returned by Save on a read-only graph
+var ErrReadOnly = errors.New("graph is read-only")
+
+// FileLock is an advisory lock on a graph file, held in "<file>.lock"
+type FileLock struct {
+	path string
+	file *os.File
+}
+
+// LockFile takes the advisory lock on a graph file for this process. It
+// fails with ErrLocked, naming the holder's PID, when another process has
+// it. The lock goes when the process exits, even without Release.
+func LockFile(path string) (*FileLock, error) {
+	lockPath := path + ".lock"
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockExclusive(f); err != nil {
+		holder, _ := os.ReadFile(lockPath)
+		f.Close()
+		if pid := strings.TrimSpace(string(holder)); pid != "" {
+			return nil, fmt.Errorf("%w by pid %s (%s)", ErrLocked, pid, lockPath)
+		}
+		return nil, fmt.Errorf("%w (%s): %v", ErrLocked, lockPath, err)
+	}
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return &FileLock{path: lockPath, file: f}, nil
+}
+
+// Release gives the lock up
+func (l *FileLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	l.file.Truncate(0)
+	unlock(l.file)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// fileStamp is what a graph file looked like when last read or written
+type fileStamp struct {
+	path    string
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// stampFile records a file's current state
+func stampFile(path string) fileStamp {
+	s := fileStamp{path: path}
+	if info, err := os.Stat(path); err == nil {
+		s.modTime, s.size, s.exists = info.ModTime(), info.Size(), true
+	}
+	return s
+}
+
+// changed reports whether the file differs from the stamp
+func (s fileStamp) changed() bool {
+	now := stampFile(s.path)
+	return now.exists != s.exists || !now.modTime.Equal(s.modTime) || now.size != s.size
+}
+
+// SetReadOnly makes the graph read-only: Save fails with ErrReadOnly and
+// auto-save is off. Analysis tools that share a running app's graph file
+// use it so they can never overwrite it.
+func (g *Graph) SetReadOnly() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.readOnly = true
+	g.autoSavePath = ""
+}
+
+// ReadOnly reports whether the graph is read-only
+func (g *Graph) ReadOnly() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.readOnly
+}
+
+// diskChangedWarn reports whether the auto-save file changed on disk since
+// this graph last loaded or saved it, warning the first time it notices
+func (g *Graph) diskChangedWarn() bool {
+	g.diskMu.Lock()
+	defer g.diskMu.Unlock()
+	if g.disk.path == "" || !g.disk.changed() {
+		return false
+	}
+	if !g.diskWarned {
+		g.diskWarned = true
+		logger.Warn(logger.StatusWarn, "%s changed on disk since it was loaded; auto-save paused so it is not overwritten ('load' it, or 'save %s' to overwrite it; 'merge' first to keep both)", g.disk.path, g.disk.path)
+	}
+	return true
+}
+
+// DiskChanged reports whether the auto-save file changed on disk since this
+// graph last loaded or saved it, i.e. someone else wrote it. It warns the
+// first time, as auto-save does.
+func (g *Graph) DiskChanged() bool {
+	return g.diskChangedWarn()
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package graph
+
+import "os"
+
+// Without flock the lock file only records the holder; it does not exclude
+// other processes
+
+func lockExclusive(f *os.File) error {
+	return nil
+}
+
+func unlock(f *os.File) {}
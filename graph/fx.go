@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"strings"
+	"time"
+)
+
+// Prices are stored in the currency they are quoted in (Node.Currency), so
+// anything adding up prices across listings converts them first. Rates are
+// set by the market monitor and kept in memory only; until a currency's rate
+// is known, its prices can't be converted.
+
+// fxRate is the value of one unit of a currency in US dollars
+type fxRate struct {
+	usd     float64
+	updated time.Time
+}
+
+// SetUSDRate records the value of one unit of currency in US dollars
+func (g *Graph) SetUSDRate(currency string, usd float64) {
+	if usd <= 0 {
+		return
+	}
+	g.fxMu.Lock()
+	defer g.fxMu.Unlock()
+	if g.fxRates == nil {
+		g.fxRates = make(map[string]fxRate)
+	}
+	g.fxRates[strings.ToUpper(currency)] = fxRate{usd: usd, updated: time.Now()}
+}
+
+// USDRate returns the value of one unit of currency in US dollars and when
+// it was set. USD, and an empty currency, are always 1.
+func (g *Graph) USDRate(currency string) (float64, time.Time, bool) {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == "USD" {
+		return 1, time.Time{}, true
+	}
+	g.fxMu.RLock()
+	defer g.fxMu.RUnlock()
+	r, ok := g.fxRates[currency]
+	return r.usd, r.updated, ok
+}
+
+// ConvertPrice converts a price between currencies through their US dollar
+// rates, reporting false if either rate is unknown
+func (g *Graph) ConvertPrice(price float64, from, to string) (float64, bool) {
+	if strings.EqualFold(from, to) {
+		return price, true
+	}
+	fromUSD, _, ok := g.USDRate(from)
+	if !ok {
+		return 0, false
+	}
+	toUSD, _, ok := g.USDRate(to)
+	if !ok {
+		return 0, false
+	}
+	return price * fromUSD / toUSD, true
+}
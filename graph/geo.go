@@ -0,0 +1,248 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+)
+
+// Location attribute keys. Companies carry their headquarters; nations their centroid.
+const (
+	AttrLat             = "lat"
+	AttrLon             = "lon"
+	AttrCountry         = "country"
+	AttrRegion          = "region"
+	AttrProductionSites = "production_sites" // Comma-separated countries with known plants/mines
+)
+
+// CountryInfo is the built-in geography for a country. Name is its ISO
+// registry name.
+type CountryInfo struct {
+	Name   string
+	Region string
+	Lat    float64
+	Lon    float64
+}
+
+// countryGeo is a country's region and approximate centroid
+type countryGeo struct {
+	Region string
+	Lat    float64
+	Lon    float64
+}
+
+// countryGeography maps ISO3 codes to their region and approximate centroid
+var countryGeography = map[string]countryGeo{
+	"USA": {"North America", 39.8, -98.6},  // United States
+	"CAN": {"North America", 56.1, -106.3}, // Canada
+	"MEX": {"North America", 23.6, -102.6}, // Mexico
+	"BRA": {"Latin America", -14.2, -51.9}, // Brazil
+	"ARG": {"Latin America", -38.4, -63.6}, // Argentina
+	"CHL": {"Latin America", -35.7, -71.5}, // Chile
+	"PER": {"Latin America", -9.2, -75},    // Peru
+	"COL": {"Latin America", 4.6, -74.3},   // Colombia
+	"VEN": {"Latin America", 6.4, -66.6},   // Venezuela
+	"GBR": {"Europe", 55.4, -3.4},          // United Kingdom
+	"IRL": {"Europe", 53.4, -8.2},          // Ireland
+	"FRA": {"Europe", 46.2, 2.2},           // France
+	"DEU": {"Europe", 51.2, 10.5},          // Germany
+	"ITA": {"Europe", 41.9, 12.6},          // Italy
+	"ESP": {"Europe", 40.5, -3.7},          // Spain
+	"PRT": {"Europe", 39.4, -8.2},          // Portugal
+	"NLD": {"Europe", 52.1, 5.3},           // Netherlands
+	"BEL": {"Europe", 50.5, 4.5},           // Belgium
+	"CHE": {"Europe", 46.8, 8.2},           // Switzerland
+	"AUT": {"Europe", 47.5, 14.6},          // Austria
+	"SWE": {"Europe", 60.1, 18.6},          // Sweden
+	"NOR": {"Europe", 60.5, 8.5},           // Norway
+	"DNK": {"Europe", 56.3, 9.5},           // Denmark
+	"FIN": {"Europe", 61.9, 25.7},          // Finland
+	"POL": {"Europe", 51.9, 19.1},          // Poland
+	"CZE": {"Europe", 49.8, 15.5},          // Czech Republic
+	"HUN": {"Europe", 47.2, 19.5},          // Hungary
+	"ROU": {"Europe", 45.9, 25},            // Romania
+	"GRC": {"Europe", 39.1, 21.8},          // Greece
+	"UKR": {"Europe", 48.4, 31.2},          // Ukraine
+	"RUS": {"Europe", 61.5, 105.3},         // Russia
+	"TUR": {"Middle East", 39, 35.2},       // Turkey
+	"ISR": {"Middle East", 31, 34.9},       // Israel
+	"SAU": {"Middle East", 23.9, 45.1},     // Saudi Arabia
+	"ARE": {"Middle East", 23.4, 53.8},     // United Arab Emirates
+	"QAT": {"Middle East", 25.4, 51.2},     // Qatar
+	"KWT": {"Middle East", 29.3, 47.5},     // Kuwait
+	"IRN": {"Middle East", 32.4, 53.7},     // Iran
+	"IRQ": {"Middle East", 33.2, 43.7},     // Iraq
+	"EGY": {"Africa", 26.8, 30.8},          // Egypt
+	"NGA": {"Africa", 9.1, 8.7},            // Nigeria
+	"ZAF": {"Africa", -30.6, 22.9},         // South Africa
+	"KEN": {"Africa", 0, 37.9},             // Kenya
+	"ETH": {"Africa", 9.1, 40.5},           // Ethiopia
+	"MAR": {"Africa", 31.8, -7.1},          // Morocco
+	"DZA": {"Africa", 28, 1.7},             // Algeria
+	"GHA": {"Africa", 7.9, -1},             // Ghana
+	"COD": {"Africa", -4, 21.8},            // Democratic Republic of the Congo
+	"CHN": {"East Asia", 35.9, 104.2},      // China
+	"JPN": {"East Asia", 36.2, 138.3},      // Japan
+	"KOR": {"East Asia", 35.9, 127.8},      // South Korea
+	"TWN": {"East Asia", 23.7, 121},        // Taiwan
+	"HKG": {"East Asia", 22.4, 114.1},      // Hong Kong
+	"IND": {"South Asia", 20.6, 79},        // India
+	"PAK": {"South Asia", 30.4, 69.3},      // Pakistan
+	"BGD": {"South Asia", 23.7, 90.4},      // Bangladesh
+	"LKA": {"South Asia", 7.9, 80.8},       // Sri Lanka
+	"IDN": {"Southeast Asia", -0.8, 113.9}, // Indonesia
+	"THA": {"Southeast Asia", 15.9, 101},   // Thailand
+	"VNM": {"Southeast Asia", 14.1, 108.3}, // Vietnam
+	"MYS": {"Southeast Asia", 4.2, 102},    // Malaysia
+	"SGP": {"Southeast Asia", 1.35, 103.8}, // Singapore
+	"PHL": {"Southeast Asia", 12.9, 121.8}, // Philippines
+	"KAZ": {"Central Asia", 48, 66.9},      // Kazakhstan
+	"UZB": {"Central Asia", 41.4, 64.6},    // Uzbekistan
+	"AUS": {"Oceania", -25.3, 133.8},       // Australia
+	"NZL": {"Oceania", -40.9, 174.9},       // New Zealand
+}
+
+// LookupCountry returns built-in geography for any name or ISO code
+// ResolveCountry accepts
+func LookupCountry(name string) (CountryInfo, bool) {
+	c, ok := ResolveCountry(name)
+	if !ok {
+		return CountryInfo{}, false
+	}
+	geo, ok := countryGeography[c.Alpha3]
+	if !ok {
+		return CountryInfo{}, false
+	}
+	return CountryInfo{Name: c.Name, Region: geo.Region, Lat: geo.Lat, Lon: geo.Lon}, true
+}
+
+// LocationAttributes builds node attributes for a location, filling the
+// region from the country when it is known. Zero coordinates are omitted.
+func LocationAttributes(lat, lon float64, country string) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	if lat != 0 || lon != 0 {
+		attrs[AttrLat] = lat
+		attrs[AttrLon] = lon
+	}
+	if country != "" {
+		attrs[AttrCountry] = country
+		if info, ok := LookupCountry(country); ok {
+			attrs[AttrCountry] = info.Name
+			attrs[AttrRegion] = info.Region
+		} else if c, ok := ResolveCountry(country); ok {
+			attrs[AttrCountry] = c.Name
+		}
+	}
+	return attrs
+}
+
+// Location returns the node's coordinates, if known
+func (n *Node) Location() (lat, lon float64, ok bool) {
+	lat, okLat := n.Attributes[AttrLat].(float64)
+	lon, okLon := n.Attributes[AttrLon].(float64)
+	return lat, lon, okLat && okLon
+}
+
+// Country returns the node's country: its country attribute, or its own
+// name for nations.
+func (n *Node) Country() string {
+	if c, ok := n.Attributes[AttrCountry].(string); ok && c != "" {
+		return c
+	}
+	if n.Type == NodeTypeNation {
+		return n.Name
+	}
+	return ""
+}
+
+// Regions lists the built-in region names
+func Regions() []string {
+	seen := make(map[string]bool)
+	regions := make([]string, 0)
+	for _, geo := range countryGeography {
+		if !seen[geo.Region] {
+			seen[geo.Region] = true
+			regions = append(regions, geo.Region)
+		}
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// inheritedCountriesLocked maps companies to the nation whose industry lists
+// them, through Nation -> Industry -> Company (must be called with lock held)
+func (g *Graph) inheritedCountriesLocked() map[string]string {
+	inherited := make(map[string]string)
+	for _, n := range g.Nodes {
+		if n.Type != NodeTypeNation {
+			continue
+		}
+		for _, e := range g.Adjacency[n.ID] {
+			if e.Type != EdgeTypeHasIndustry {
+				continue
+			}
+			for _, ce := range g.Adjacency[e.TargetID] {
+				if ce.Type == EdgeTypeHasCompany {
+					if _, claimed := inherited[ce.TargetID]; !claimed {
+						inherited[ce.TargetID] = n.Name
+					}
+				}
+			}
+		}
+	}
+	return inherited
+}
+
+// NodesInRegion returns nodes located in name, which may be a region
+// ("Southeast Asia") or a country ("Taiwan"). A node is located by its country
+// attribute, any of its production sites, or — for industries and companies
+// without one — the nation whose industry lists it. Industry nodes themselves
+// are left out since shocks reach them through their nation.
+func (g *Graph) NodesInRegion(name string) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	target := strings.ToLower(strings.TrimSpace(name))
+	if info, ok := LookupCountry(name); ok {
+		target = strings.ToLower(info.Name)
+	}
+
+	matches := func(country string) bool {
+		if country == "" {
+			return false
+		}
+		if info, ok := LookupCountry(country); ok {
+			return strings.ToLower(info.Name) == target || strings.ToLower(info.Region) == target
+		}
+		return strings.ToLower(strings.TrimSpace(country)) == target
+	}
+
+	inherited := g.inheritedCountriesLocked()
+
+	result := make([]*Node, 0)
+	for _, n := range g.Nodes {
+		if n.Type == NodeTypeIndustry {
+			continue
+		}
+		country := n.Country()
+		if country == "" {
+			country = inherited[n.ID]
+		}
+		located := matches(country)
+		if !located {
+			if sites, ok := n.Attributes[AttrProductionSites].(string); ok {
+				for _, site := range strings.Split(sites, ",") {
+					if matches(site) {
+						located = true
+						break
+					}
+				}
+			}
+		}
+		if located {
+			result = append(result, n)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
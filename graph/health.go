@@ -0,0 +1,196 @@
+package graph
+
+import "fmt"
+
+// HealthInput identifies what is driving a health change
+type HealthInput string
+
+const (
+	InputDirect      HealthInput = "direct"      // Raw delta applied as-is (UpdateNodeHealth)
+	InputShock       HealthInput = "shock"       // Damage to the shocked node itself
+	InputPropagation HealthInput = "propagation" // Damage spreading along edges from a shock
+	InputWinner      HealthInput = "winner"      // Boost to substitutes/competitors of a shocked node
+	InputMarket      HealthInput = "market"      // Daily price change as a fraction (0.05 = +5%)
+	InputSentiment   HealthInput = "sentiment"   // Sentiment score (-1.0 to +1.0)
+	InputMonetary    HealthInput = "monetary"    // Currency/rate move scaled by shock magnitude
+)
+
+// HealthParams configures how one node type responds to health inputs
+type HealthParams struct {
+	Min     float64
+	Max     float64
+	Weights map[HealthInput]float64 // Multiplier per input; missing inputs use 1.0
+	Update  string                  // Name in HealthUpdateFuncs; empty = "linear"
+}
+
+// Weight returns the multiplier for input
+func (p HealthParams) Weight(input HealthInput) float64 {
+	if w, ok := p.Weights[input]; ok {
+		return w
+	}
+	return 1.0
+}
+
+// clamp bounds health to [Min, Max]
+func (p HealthParams) clamp(health float64) float64 {
+	if health < p.Min {
+		return p.Min
+	}
+	if health > p.Max {
+		return p.Max
+	}
+	return health
+}
+
+// HealthUpdateFunc computes a node's new health from its current health and a raw input
+type HealthUpdateFunc func(current, raw float64, input HealthInput, p HealthParams) float64
+
+// HealthUpdateFuncs are the update functions selectable by name in HealthParams.Update
+var HealthUpdateFuncs = map[string]HealthUpdateFunc{
+	"linear": LinearHealthUpdate,
+	"damped": DampedHealthUpdate,
+}
+
+// LinearHealthUpdate adds the weighted input and clamps to bounds
+func LinearHealthUpdate(current, raw float64, input HealthInput, p HealthParams) float64 {
+	return p.clamp(current + raw*p.Weight(input))
+}
+
+// DampedHealthUpdate shrinks changes as health approaches the bound it is
+// moving toward, so nodes resist being pushed to extremes.
+func DampedHealthUpdate(current, raw float64, input HealthInput, p HealthParams) float64 {
+	delta := raw * p.Weight(input)
+	span := p.Max - p.Min
+	if span <= 0 {
+		return p.clamp(current)
+	}
+	if delta < 0 {
+		delta *= (current - p.Min) / span
+	} else {
+		delta *= (p.Max - current) / span
+	}
+	return p.clamp(current + delta)
+}
+
+// HealthModel holds per-node-type health parameters
+type HealthModel struct {
+	Default HealthParams
+	ByType  map[NodeType]HealthParams // Overrides; zero bounds and missing weights fall back to Default
+}
+
+// DefaultHealthModel reproduces the original fixed rules: health in [0.1, 2.0],
+// market moves and sentiment scaled by 0.1, all other inputs unscaled.
+func DefaultHealthModel() *HealthModel {
+	return &HealthModel{
+		Default: HealthParams{
+			Min: 0.1,
+			Max: 2.0,
+			Weights: map[HealthInput]float64{
+				InputMarket:    0.1,
+				InputSentiment: 0.1,
+			},
+		},
+		ByType: make(map[NodeType]HealthParams),
+	}
+}
+
+// Params returns the effective parameters for a node type
+func (m *HealthModel) Params(t NodeType) HealthParams {
+	p := m.Default
+	override, ok := m.ByType[t]
+	if !ok {
+		return p
+	}
+
+	if override.Min != 0 {
+		p.Min = override.Min
+	}
+	if override.Max != 0 {
+		p.Max = override.Max
+	}
+	if override.Update != "" {
+		p.Update = override.Update
+	}
+	if len(override.Weights) > 0 {
+		merged := make(map[HealthInput]float64, len(p.Weights)+len(override.Weights))
+		for k, v := range p.Weights {
+			merged[k] = v
+		}
+		for k, v := range override.Weights {
+			merged[k] = v
+		}
+		p.Weights = merged
+	}
+	return p
+}
+
+// Validate checks bounds and update function names
+func (m *HealthModel) Validate() error {
+	check := func(name string, p HealthParams) error {
+		if p.Min <= 0 || p.Max <= p.Min {
+			return fmt.Errorf("health model %s: invalid bounds [%.2f, %.2f]", name, p.Min, p.Max)
+		}
+		if p.Update != "" {
+			if _, ok := HealthUpdateFuncs[p.Update]; !ok {
+				return fmt.Errorf("health model %s: unknown update function %q", name, p.Update)
+			}
+		}
+		return nil
+	}
+
+	if err := check("default", m.Default); err != nil {
+		return err
+	}
+	for t := range m.ByType {
+		if err := check(string(t), m.Params(t)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply computes the new health for a node of type t
+func (m *HealthModel) apply(t NodeType, current, raw float64, input HealthInput) float64 {
+	p := m.Params(t)
+	update, ok := HealthUpdateFuncs[p.Update]
+	if !ok {
+		update = LinearHealthUpdate
+	}
+	return update(current, raw, input, p)
+}
+
+// SetHealthModel replaces the graph's health model
+func (g *Graph) SetHealthModel(m *HealthModel) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.healthModel = m
+}
+
+// defaultHealthModel is used by graphs without an explicit model
+var defaultHealthModel = DefaultHealthModel()
+
+// model returns the active health model (must be called with lock held)
+func (g *Graph) model() *HealthModel {
+	if g.healthModel == nil {
+		return defaultHealthModel
+	}
+	return g.healthModel
+}
+
+// ApplyHealthInput updates a node's health from a raw input according to the
+// health model for its type, returning the new health.
+func (g *Graph) ApplyHealthInput(id string, input HealthInput, raw float64) (float64, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	node, ok := g.Nodes[id]
+	if !ok {
+		return 0, false
+	}
+
+	node.Health = g.model().apply(node.Type, node.Health, raw, input)
+	g.recordHealth(id, node.Health)
+	g.emit(Delta{Kind: DeltaHealth, NodeID: id, Health: node.Health})
+
+	return node.Health, true
+}
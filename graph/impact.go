@@ -0,0 +1,212 @@
+package graph
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Shocks, news and decay sweeps each move health and edge weights across the
+// graph. An Impact ranks the nodes one event changed most, by health moved
+// and edge weight lost. The latest impacts are kept, so an event can be
+// looked up after the fact instead of read back out of the log.
+
+// ImpactKind is the kind of event an impact was measured for
+type ImpactKind string
+
+const (
+	ImpactShock ImpactKind = "shock" // Simulator.RunShock
+	ImpactNews  ImpactKind = "news"  // A headline, including the shock it caused
+	ImpactDecay ImpactKind = "decay" // A temporal decay sweep (and the prune after it)
+)
+
+// Bounds on what is kept
+const (
+	maxImpacts     = 100 // Latest impacts kept
+	maxImpactNodes = 50  // Ranked nodes kept per impact
+)
+
+// impactEpsilon is the smallest change counted
+const impactEpsilon = 1e-9
+
+// ImpactBaseline is the health and edge weights before an event, to measure
+// its impact against
+type ImpactBaseline struct {
+	time    time.Time
+	health  map[string]float64
+	weights map[string]float64 // Key: edge key (see edgeKey)
+	ends    map[string][2]string
+}
+
+// NodeImpact is how one event changed one node
+type NodeImpact struct {
+	NodeID       string   `json:"node_id"`
+	Name         string   `json:"name"`
+	Type         NodeType `json:"type"`
+	HealthBefore float64  `json:"health_before"`
+	HealthAfter  float64  `json:"health_after"`
+	HealthDelta  float64  `json:"health_delta"`
+	EdgeLoss     float64  `json:"edge_loss"` // Weight its edges lost; a net gain is negative
+	EdgesChanged int      `json:"edges_changed"`
+	Score        float64  `json:"score"` // |health delta| + |edge loss|, the ranking key
+}
+
+// Impact ranks the nodes an event changed, most changed first
+type Impact struct {
+	EventID      string        `json:"event_id"`
+	Kind         ImpactKind    `json:"kind"`
+	Description  string        `json:"description,omitempty"`
+	Time         time.Time     `json:"time"`
+	Duration     time.Duration `json:"duration"`      // From baseline to record
+	NodesChanged int           `json:"nodes_changed"` // All changed nodes, not only those ranked
+	EdgesChanged int           `json:"edges_changed"`
+	HealthDelta  float64       `json:"health_delta"` // Sum over changed nodes
+	EdgeLoss     float64       `json:"edge_loss"`    // Weight lost over changed edges
+	Nodes        []NodeImpact  `json:"nodes"`        // Top 50 by score
+}
+
+// ImpactBaseline records the graph's current health and edge weights
+func (g *Graph) ImpactBaseline() *ImpactBaseline {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	b := &ImpactBaseline{
+		time:    time.Now(),
+		health:  make(map[string]float64, len(g.Nodes)),
+		weights: make(map[string]float64, len(g.Edges)),
+		ends:    make(map[string][2]string, len(g.Edges)),
+	}
+	for id, n := range g.Nodes {
+		b.health[id] = n.Health
+	}
+	for _, e := range g.Edges {
+		key := edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())
+		b.weights[key] = e.Weight
+		b.ends[key] = [2]string{e.SourceID, e.TargetID}
+	}
+	return b
+}
+
+// RecordImpact measures what changed since b and keeps it as the impact of
+// eventID. Changes made meanwhile by anything else (e.g. market prices) are
+// counted too. An event that changed nothing is returned but not kept.
+func (g *Graph) RecordImpact(kind ImpactKind, eventID, description string, b *ImpactBaseline) *Impact {
+	g.mu.RLock()
+	now := time.Now()
+	byNode := make(map[string]*NodeImpact)
+	entry := func(id string) *NodeImpact {
+		if ni, ok := byNode[id]; ok {
+			return ni
+		}
+		ni := &NodeImpact{NodeID: id}
+		if n, ok := g.Nodes[id]; ok {
+			ni.Name, ni.Type, ni.HealthAfter = n.Name, n.Type, n.Health
+			ni.HealthBefore = n.Health
+			if before, ok := b.health[id]; ok {
+				ni.HealthBefore = before
+			}
+		}
+		byNode[id] = ni
+		return ni
+	}
+
+	imp := &Impact{EventID: eventID, Kind: kind, Description: description, Time: now, Duration: now.Sub(b.time)}
+	for id, n := range g.Nodes {
+		before, ok := b.health[id]
+		if ok && math.Abs(n.Health-before) > impactEpsilon {
+			entry(id)
+		}
+	}
+	edgeLoss := func(src, tgt string, loss float64) {
+		imp.EdgesChanged++
+		imp.EdgeLoss += loss
+		for _, id := range []string{src, tgt} {
+			ni := entry(id)
+			ni.EdgeLoss += loss
+			ni.EdgesChanged++
+		}
+	}
+	seen := make(map[string]bool, len(g.Edges))
+	for _, e := range g.Edges {
+		key := edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())
+		seen[key] = true
+		before := b.weights[key] // 0 for an edge added since
+		if math.Abs(before-e.Weight) > impactEpsilon {
+			edgeLoss(e.SourceID, e.TargetID, before-e.Weight)
+		}
+	}
+	for key, before := range b.weights {
+		if !seen[key] {
+			ends := b.ends[key]
+			edgeLoss(ends[0], ends[1], before) // Removed, e.g. pruned
+		}
+	}
+	g.mu.RUnlock()
+
+	ranked := make([]NodeImpact, 0, len(byNode))
+	for _, ni := range byNode {
+		ni.HealthDelta = ni.HealthAfter - ni.HealthBefore
+		ni.Score = math.Abs(ni.HealthDelta) + math.Abs(ni.EdgeLoss)
+		imp.HealthDelta += ni.HealthDelta
+		ranked = append(ranked, *ni)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].NodeID < ranked[j].NodeID
+	})
+	imp.NodesChanged = len(ranked)
+	if len(ranked) > maxImpactNodes {
+		ranked = ranked[:maxImpactNodes]
+	}
+	imp.Nodes = ranked
+
+	if imp.NodesChanged > 0 {
+		g.impactMu.Lock()
+		g.impacts = append(g.impacts, imp)
+		if len(g.impacts) > maxImpacts {
+			g.impacts = append(g.impacts[:0:0], g.impacts[len(g.impacts)-maxImpacts:]...)
+		}
+		g.impactMu.Unlock()
+	}
+	return imp
+}
+
+// Impacts returns the kept impacts, newest first
+func (g *Graph) Impacts() []Impact {
+	g.impactMu.Lock()
+	defer g.impactMu.Unlock()
+
+	out := make([]Impact, 0, len(g.impacts))
+	for i := len(g.impacts) - 1; i >= 0; i-- {
+		out = append(out, *g.impacts[i])
+	}
+	return out
+}
+
+// ImpactOf returns the latest kept impact of eventID
+func (g *Graph) ImpactOf(eventID string) (Impact, bool) {
+	g.impactMu.Lock()
+	defer g.impactMu.Unlock()
+
+	for i := len(g.impacts) - 1; i >= 0; i-- {
+		if g.impacts[i].EventID == eventID {
+			return *g.impacts[i], true
+		}
+	}
+	return Impact{}, false
+}
+
+// LastImpact returns the newest kept impact, optionally of one kind ("" = any)
+func (g *Graph) LastImpact(kind ImpactKind) (Impact, bool) {
+	g.impactMu.Lock()
+	defer g.impactMu.Unlock()
+
+	for i := len(g.impacts) - 1; i >= 0; i-- {
+		if kind == "" || g.impacts[i].Kind == kind {
+			return *g.impacts[i], true
+		}
+	}
+	return Impact{}, false
+}
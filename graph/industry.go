@@ -0,0 +1,209 @@
+package graph
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Industry rollup attributes, set on Industry nodes by RefreshIndustryRollups
+const (
+	AttrSectorHealth          = "sector_health"          // Company health weighted by market cap
+	AttrSupplierConcentration = "supplier_concentration" // Herfindahl index of supplier countries, 0-1
+	AttrTopSupplierCountry    = "top_supplier_country"
+	AttrTopSupplierShare      = "top_supplier_share"
+	AttrRollupCompanies       = "rollup_companies"
+	AttrRollupUpdated         = "rollup_updated" // RFC 3339 time the rollup last changed
+)
+
+// IndustryRollup summarizes an industry's companies so shocks can be read at
+// the sector level
+type IndustryRollup struct {
+	IndustryID string  `json:"industry_id"`
+	Name       string  `json:"name"`
+	Companies  int     `json:"companies"`
+	Health     float64 `json:"health"` // Mean company health weighted by market cap
+	// Concentration is the Herfindahl index of the countries the companies'
+	// suppliers are in, weighted by supply edge weight: 1 = a single country,
+	// near 0 = spread across many. 0 when no supplier has a known country.
+	Concentration float64 `json:"concentration"`
+	TopCountry    string  `json:"top_country,omitempty"` // Country supplying the largest share
+	TopShare      float64 `json:"top_share"`
+}
+
+// IndustryRollup reads the rollup RefreshIndustryRollups stored on an
+// Industry node, reporting false if it has none
+func (n *Node) IndustryRollup() (IndustryRollup, bool) {
+	health, ok := n.Attributes[AttrSectorHealth].(float64)
+	if n.Type != NodeTypeIndustry || !ok {
+		return IndustryRollup{}, false
+	}
+	r := IndustryRollup{IndustryID: n.ID, Name: n.Name, Health: health}
+	companies, _ := n.Attributes[AttrRollupCompanies].(float64)
+	r.Companies = int(companies)
+	r.Concentration, _ = n.Attributes[AttrSupplierConcentration].(float64)
+	r.TopCountry, _ = n.Attributes[AttrTopSupplierCountry].(string)
+	r.TopShare, _ = n.Attributes[AttrTopSupplierShare].(float64)
+	return r, true
+}
+
+// IndustryRollups computes the rollup of every industry with companies,
+// lowest health first
+func (g *Graph) IndustryRollups() []IndustryRollup {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.industryRollupsLocked()
+}
+
+// RefreshIndustryRollups recomputes every industry's rollup and stores it on
+// the Industry node. It returns the rollups, lowest health first, and the
+// number of nodes whose stored rollup changed.
+func (g *Graph) RefreshIndustryRollups() ([]IndustryRollup, int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	rollups := g.industryRollupsLocked()
+	now := time.Now().UTC().Format(time.RFC3339)
+	changed := 0
+	for _, r := range rollups {
+		n, ok := g.Nodes[r.IndustryID]
+		if !ok {
+			continue
+		}
+		attrs := map[string]interface{}{
+			AttrSectorHealth:          round4(r.Health),
+			AttrSupplierConcentration: round4(r.Concentration),
+			AttrTopSupplierCountry:    r.TopCountry,
+			AttrTopSupplierShare:      round4(r.TopShare),
+			AttrRollupCompanies:       float64(r.Companies),
+		}
+		if n.Attributes == nil {
+			n.Attributes = make(map[string]interface{})
+		}
+		same := true
+		for k, v := range attrs {
+			if n.Attributes[k] != v {
+				n.Attributes[k] = v
+				same = false
+			}
+		}
+		if same {
+			continue
+		}
+		n.Attributes[AttrRollupUpdated] = now
+		g.emit(Delta{Kind: DeltaNode, Node: n})
+		changed++
+	}
+	return rollups, changed
+}
+
+// round4 keeps stored rollups from changing on float noise
+func round4(v float64) float64 {
+	return math.Round(v*1e4) / 1e4
+}
+
+// industryRollupsLocked computes IndustryRollups (must be called with lock held)
+func (g *Graph) industryRollupsLocked() []IndustryRollup {
+	inherited := g.inheritedCountriesLocked()
+	countryOf := func(id string) string {
+		n, ok := g.Nodes[id]
+		if !ok {
+			return ""
+		}
+		country := n.Country()
+		if country == "" {
+			country = inherited[id]
+		}
+		if info, ok := LookupCountry(country); ok {
+			return info.Name
+		}
+		return country
+	}
+
+	// Supply edges into each company, by supplier
+	type supply struct {
+		supplierID string
+		weight     float64
+	}
+	suppliers := make(map[string][]supply)
+	for _, e := range g.Edges {
+		switch e.Type {
+		case EdgeTypeSupplies:
+			suppliers[e.TargetID] = append(suppliers[e.TargetID], supply{e.SourceID, e.Weight})
+		case EdgeTypeProcuresFrom:
+			suppliers[e.SourceID] = append(suppliers[e.SourceID], supply{e.TargetID, e.Weight})
+		}
+	}
+
+	var rollups []IndustryRollup
+	for _, ind := range g.Nodes {
+		if ind.Type != NodeTypeIndustry {
+			continue
+		}
+		var companies []*Node
+		seen := make(map[string]bool)
+		for _, e := range g.Adjacency[ind.ID] {
+			if c, ok := g.Nodes[e.TargetID]; ok && e.Type == EdgeTypeHasCompany && c.Type == NodeTypeCorporation && !seen[c.ID] {
+				companies = append(companies, c)
+				seen[c.ID] = true
+			}
+		}
+		if len(companies) == 0 {
+			continue
+		}
+		r := IndustryRollup{IndustryID: ind.ID, Name: ind.Name, Companies: len(companies)}
+
+		// Companies without a market cap weigh as much as the average known one,
+		// or all equally when none is known
+		known, capSum := 0, 0.0
+		for _, c := range companies {
+			if c.MarketCap() > 0 {
+				known++
+				capSum += c.MarketCap()
+			}
+		}
+		fallback := 1.0
+		if known > 0 {
+			fallback = capSum / float64(known)
+		}
+		weightSum := 0.0
+		for _, c := range companies {
+			w := c.MarketCap()
+			if w <= 0 {
+				w = fallback
+			}
+			r.Health += w * c.Health
+			weightSum += w
+		}
+		r.Health /= weightSum
+
+		byCountry := make(map[string]float64)
+		total := 0.0
+		for _, c := range companies {
+			for _, s := range suppliers[c.ID] {
+				country := countryOf(s.supplierID)
+				if country == "" || s.weight <= 0 {
+					continue
+				}
+				byCountry[country] += s.weight
+				total += s.weight
+			}
+		}
+		for country, w := range byCountry {
+			share := w / total
+			r.Concentration += share * share
+			if share > r.TopShare || (share == r.TopShare && country < r.TopCountry) {
+				r.TopCountry, r.TopShare = country, share
+			}
+		}
+		rollups = append(rollups, r)
+	}
+
+	sort.Slice(rollups, func(i, j int) bool {
+		if rollups[i].Health != rollups[j].Health {
+			return rollups[i].Health < rollups[j].Health
+		}
+		return rollups[i].IndustryID < rollups[j].IndustryID
+	})
+	return rollups
+}
@@ -0,0 +1,195 @@
+package graph
+
+import (
+	"fmt"
+	"unique"
+)
+
+// A graph's IDs and enum-like strings (node IDs, edge types, statuses, event
+// IDs, attribute keys) repeat once per edge, history snapshot and map key.
+// Decoded from JSON each copy is its own allocation; interning makes them
+// share one backing array, and compacting stores edges in a single block
+// instead of one allocation per edge. IDs stay strings and edges stay *Edge:
+// integer handles and a pointer-free edge slice would save more, but would
+// change the graph API every package, the JSON format and the WAL rely on.
+
+// intern returns the canonical copy of s. The unique package keeps one copy
+// per distinct string and drops it once nothing refers to it.
+func intern(s string) string {
+	if s == "" {
+		return s
+	}
+	return unique.Make(s).Value()
+}
+
+// internAttrs interns an attribute map's keys and string values
+func internAttrs(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			v = intern(s)
+		}
+		out[intern(k)] = v
+	}
+	return out
+}
+
+// internNode interns a node's strings
+func internNode(n *Node) {
+	n.ID = intern(n.ID)
+	n.Type = NodeType(intern(string(n.Type)))
+	n.Currency = intern(n.Currency)
+	n.Ticker = intern(n.Ticker)
+}
+
+// internEdge interns an edge's strings
+func internEdge(e *Edge) {
+	e.SourceID = intern(e.SourceID)
+	e.TargetID = intern(e.TargetID)
+	e.Type = EdgeType(intern(string(e.Type)))
+	e.Status = EdgeStatus(intern(string(e.Status)))
+	e.Directionality = EdgeDirectionality(intern(string(e.Directionality)))
+}
+
+// CompactStats reports what Compact did
+type CompactStats struct {
+	Nodes     int
+	Edges     int
+	Snapshots int // History snapshots whose strings were interned
+}
+
+func (s CompactStats) String() string {
+	return fmt.Sprintf("%d nodes, %d edges, %d history snapshots", s.Nodes, s.Edges, s.Snapshots)
+}
+
+// Compact interns every string in the graph and trims the spare capacity of
+// adjacency lists and histories. Edges keep their addresses, so it is safe
+// while the graph is in use.
+func (g *Graph) Compact() CompactStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.compactLocked(false)
+}
+
+// compactLocked interns the graph's strings and rebuilds its adjacency lists
+// with exact capacity. With packEdges the edges are also copied into one
+// contiguous block, which is only safe while nobody else holds *Edge pointers
+// into the graph (right after Load or Clone). Must be called with lock held.
+func (g *Graph) compactLocked(packEdges bool) CompactStats {
+	var stats CompactStats
+
+	nodes := make(map[string]*Node, len(g.Nodes))
+	for _, n := range g.Nodes {
+		internNode(n)
+		n.Attributes = internAttrs(n.Attributes)
+		nodes[n.ID] = n
+	}
+	g.Nodes = nodes
+	g.resetRelationsLocked()
+
+	if packEdges {
+		block := make([]Edge, len(g.Edges))
+		for i, e := range g.Edges {
+			block[i] = *e
+			g.Edges[i] = &block[i]
+		}
+	}
+	outDegree := make(map[string]int, len(g.Nodes))
+	for _, e := range g.Edges {
+		internEdge(e)
+		e.Attributes = internAttrs(e.Attributes)
+		outDegree[e.SourceID]++
+	}
+	g.Adjacency = make(map[string][]*Edge, len(outDegree))
+	for _, e := range g.Edges {
+		list := g.Adjacency[e.SourceID]
+		if list == nil {
+			list = make([]*Edge, 0, outDegree[e.SourceID])
+		}
+		g.Adjacency[e.SourceID] = append(list, e)
+	}
+
+	for _, h := range g.EdgeHistories {
+		h.SourceID = intern(h.SourceID)
+		h.TargetID = intern(h.TargetID)
+		h.Type = EdgeType(intern(string(h.Type)))
+		h.Commodity = intern(h.Commodity)
+		h.History = trimmed(h.History)
+		for i := range h.History {
+			h.History[i].Status = EdgeStatus(intern(string(h.History[i].Status)))
+			h.History[i].EventID = intern(h.History[i].EventID)
+		}
+		stats.Snapshots += len(h.History)
+	}
+	for id, h := range g.NodeHistories {
+		h.NodeID = intern(id)
+		h.History = trimmed(h.History)
+		for i := range h.History {
+			h.History[i].Changes = internAttrs(h.History[i].Changes)
+			h.History[i].EventID = intern(h.History[i].EventID)
+		}
+		stats.Snapshots += len(h.History)
+	}
+	g.NodeHistories = rekeyed(g.NodeHistories)
+	for id, h := range g.HealthHistories {
+		h.NodeID = intern(id)
+		h.History = trimmed(h.History)
+		stats.Snapshots += len(h.History)
+	}
+	g.HealthHistories = rekeyed(g.HealthHistories)
+	for id, h := range g.SentimentHistories {
+		h.NodeID = intern(id)
+		h.History = trimmed(h.History)
+		stats.Snapshots += len(h.History)
+	}
+	if g.SentimentHistories != nil {
+		g.SentimentHistories = rekeyed(g.SentimentHistories)
+	}
+	for id, h := range g.MentionHistories {
+		h.NodeID = intern(id)
+		h.Buckets = trimmed(h.Buckets)
+	}
+	if g.MentionHistories != nil {
+		g.MentionHistories = rekeyed(g.MentionHistories)
+	}
+	for id, h := range g.PriceHistories {
+		h.NodeID = intern(id)
+		h.Currency = intern(h.Currency)
+		h.History = trimmed(h.History)
+		stats.Snapshots += len(h.History)
+	}
+	if g.PriceHistories != nil {
+		g.PriceHistories = rekeyed(g.PriceHistories)
+	}
+	for _, pair := range g.CoMentions {
+		pair.A, pair.B = intern(pair.A), intern(pair.B)
+		pair.Items = trimmed(pair.Items)
+	}
+
+	g.pruneAppliedEventsLocked()
+
+	stats.Nodes, stats.Edges = len(g.Nodes), len(g.Edges)
+	return stats
+}
+
+// trimmed returns s without spare capacity
+func trimmed[T any](s []T) []T {
+	if cap(s) == len(s) {
+		return s
+	}
+	out := make([]T, len(s))
+	copy(out, s)
+	return out
+}
+
+// rekeyed rebuilds a node-keyed map so its keys share the interned IDs
+func rekeyed[V any](m map[string]V) map[string]V {
+	out := make(map[string]V, len(m))
+	for k, v := range m {
+		out[intern(k)] = v
+	}
+	return out
+}
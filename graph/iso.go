@@ -0,0 +1,636 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ISOCountry is an ISO 3166-1 country. Name is the common English short name.
+type ISOCountry struct {
+	Alpha2 string
+	Alpha3 string
+	Name   string
+}
+
+// isoCountries is the ISO 3166-1 registry, plus Kosovo (XK), which the World
+// Bank reports under a user-assigned code
+var isoCountries = []ISOCountry{
+	{"AF", "AFG", "Afghanistan"},
+	{"AX", "ALA", "Åland Islands"},
+	{"AL", "ALB", "Albania"},
+	{"DZ", "DZA", "Algeria"},
+	{"AS", "ASM", "American Samoa"},
+	{"AD", "AND", "Andorra"},
+	{"AO", "AGO", "Angola"},
+	{"AI", "AIA", "Anguilla"},
+	{"AQ", "ATA", "Antarctica"},
+	{"AG", "ATG", "Antigua and Barbuda"},
+	{"AR", "ARG", "Argentina"},
+	{"AM", "ARM", "Armenia"},
+	{"AW", "ABW", "Aruba"},
+	{"AU", "AUS", "Australia"},
+	{"AT", "AUT", "Austria"},
+	{"AZ", "AZE", "Azerbaijan"},
+	{"BS", "BHS", "Bahamas"},
+	{"BH", "BHR", "Bahrain"},
+	{"BD", "BGD", "Bangladesh"},
+	{"BB", "BRB", "Barbados"},
+	{"BY", "BLR", "Belarus"},
+	{"BE", "BEL", "Belgium"},
+	{"BZ", "BLZ", "Belize"},
+	{"BJ", "BEN", "Benin"},
+	{"BM", "BMU", "Bermuda"},
+	{"BT", "BTN", "Bhutan"},
+	{"BO", "BOL", "Bolivia"},
+	{"BQ", "BES", "Caribbean Netherlands"},
+	{"BA", "BIH", "Bosnia and Herzegovina"},
+	{"BW", "BWA", "Botswana"},
+	{"BV", "BVT", "Bouvet Island"},
+	{"BR", "BRA", "Brazil"},
+	{"IO", "IOT", "British Indian Ocean Territory"},
+	{"BN", "BRN", "Brunei"},
+	{"BG", "BGR", "Bulgaria"},
+	{"BF", "BFA", "Burkina Faso"},
+	{"BI", "BDI", "Burundi"},
+	{"CV", "CPV", "Cape Verde"},
+	{"KH", "KHM", "Cambodia"},
+	{"CM", "CMR", "Cameroon"},
+	{"CA", "CAN", "Canada"},
+	{"KY", "CYM", "Cayman Islands"},
+	{"CF", "CAF", "Central African Republic"},
+	{"TD", "TCD", "Chad"},
+	{"CL", "CHL", "Chile"},
+	{"CN", "CHN", "China"},
+	{"CX", "CXR", "Christmas Island"},
+	{"CC", "CCK", "Cocos (Keeling) Islands"},
+	{"CO", "COL", "Colombia"},
+	{"KM", "COM", "Comoros"},
+	{"CG", "COG", "Republic of the Congo"},
+	{"CD", "COD", "Democratic Republic of the Congo"},
+	{"CK", "COK", "Cook Islands"},
+	{"CR", "CRI", "Costa Rica"},
+	{"CI", "CIV", "Ivory Coast"},
+	{"HR", "HRV", "Croatia"},
+	{"CU", "CUB", "Cuba"},
+	{"CW", "CUW", "Curaçao"},
+	{"CY", "CYP", "Cyprus"},
+	{"CZ", "CZE", "Czech Republic"},
+	{"DK", "DNK", "Denmark"},
+	{"DJ", "DJI", "Djibouti"},
+	{"DM", "DMA", "Dominica"},
+	{"DO", "DOM", "Dominican Republic"},
+	{"EC", "ECU", "Ecuador"},
+	{"EG", "EGY", "Egypt"},
+	{"SV", "SLV", "El Salvador"},
+	{"GQ", "GNQ", "Equatorial Guinea"},
+	{"ER", "ERI", "Eritrea"},
+	{"EE", "EST", "Estonia"},
+	{"SZ", "SWZ", "Eswatini"},
+	{"ET", "ETH", "Ethiopia"},
+	{"FK", "FLK", "Falkland Islands"},
+	{"FO", "FRO", "Faroe Islands"},
+	{"FJ", "FJI", "Fiji"},
+	{"FI", "FIN", "Finland"},
+	{"FR", "FRA", "France"},
+	{"GF", "GUF", "French Guiana"},
+	{"PF", "PYF", "French Polynesia"},
+	{"TF", "ATF", "French Southern Territories"},
+	{"GA", "GAB", "Gabon"},
+	{"GM", "GMB", "Gambia"},
+	{"GE", "GEO", "Georgia"},
+	{"DE", "DEU", "Germany"},
+	{"GH", "GHA", "Ghana"},
+	{"GI", "GIB", "Gibraltar"},
+	{"GR", "GRC", "Greece"},
+	{"GL", "GRL", "Greenland"},
+	{"GD", "GRD", "Grenada"},
+	{"GP", "GLP", "Guadeloupe"},
+	{"GU", "GUM", "Guam"},
+	{"GT", "GTM", "Guatemala"},
+	{"GG", "GGY", "Guernsey"},
+	{"GN", "GIN", "Guinea"},
+	{"GW", "GNB", "Guinea-Bissau"},
+	{"GY", "GUY", "Guyana"},
+	{"HT", "HTI", "Haiti"},
+	{"HM", "HMD", "Heard Island and McDonald Islands"},
+	{"VA", "VAT", "Vatican City"},
+	{"HN", "HND", "Honduras"},
+	{"HK", "HKG", "Hong Kong"},
+	{"HU", "HUN", "Hungary"},
+	{"IS", "ISL", "Iceland"},
+	{"IN", "IND", "India"},
+	{"ID", "IDN", "Indonesia"},
+	{"IR", "IRN", "Iran"},
+	{"IQ", "IRQ", "Iraq"},
+	{"IE", "IRL", "Ireland"},
+	{"IM", "IMN", "Isle of Man"},
+	{"IL", "ISR", "Israel"},
+	{"IT", "ITA", "Italy"},
+	{"JM", "JAM", "Jamaica"},
+	{"JP", "JPN", "Japan"},
+	{"JE", "JEY", "Jersey"},
+	{"JO", "JOR", "Jordan"},
+	{"KZ", "KAZ", "Kazakhstan"},
+	{"KE", "KEN", "Kenya"},
+	{"KI", "KIR", "Kiribati"},
+	{"KP", "PRK", "North Korea"},
+	{"KR", "KOR", "South Korea"},
+	{"XK", "XKX", "Kosovo"},
+	{"KW", "KWT", "Kuwait"},
+	{"KG", "KGZ", "Kyrgyzstan"},
+	{"LA", "LAO", "Laos"},
+	{"LV", "LVA", "Latvia"},
+	{"LB", "LBN", "Lebanon"},
+	{"LS", "LSO", "Lesotho"},
+	{"LR", "LBR", "Liberia"},
+	{"LY", "LBY", "Libya"},
+	{"LI", "LIE", "Liechtenstein"},
+	{"LT", "LTU", "Lithuania"},
+	{"LU", "LUX", "Luxembourg"},
+	{"MO", "MAC", "Macao"},
+	{"MG", "MDG", "Madagascar"},
+	{"MW", "MWI", "Malawi"},
+	{"MY", "MYS", "Malaysia"},
+	{"MV", "MDV", "Maldives"},
+	{"ML", "MLI", "Mali"},
+	{"MT", "MLT", "Malta"},
+	{"MH", "MHL", "Marshall Islands"},
+	{"MQ", "MTQ", "Martinique"},
+	{"MR", "MRT", "Mauritania"},
+	{"MU", "MUS", "Mauritius"},
+	{"YT", "MYT", "Mayotte"},
+	{"MX", "MEX", "Mexico"},
+	{"FM", "FSM", "Micronesia"},
+	{"MD", "MDA", "Moldova"},
+	{"MC", "MCO", "Monaco"},
+	{"MN", "MNG", "Mongolia"},
+	{"ME", "MNE", "Montenegro"},
+	{"MS", "MSR", "Montserrat"},
+	{"MA", "MAR", "Morocco"},
+	{"MZ", "MOZ", "Mozambique"},
+	{"MM", "MMR", "Myanmar"},
+	{"NA", "NAM", "Namibia"},
+	{"NR", "NRU", "Nauru"},
+	{"NP", "NPL", "Nepal"},
+	{"NL", "NLD", "Netherlands"},
+	{"NC", "NCL", "New Caledonia"},
+	{"NZ", "NZL", "New Zealand"},
+	{"NI", "NIC", "Nicaragua"},
+	{"NE", "NER", "Niger"},
+	{"NG", "NGA", "Nigeria"},
+	{"NU", "NIU", "Niue"},
+	{"NF", "NFK", "Norfolk Island"},
+	{"MK", "MKD", "North Macedonia"},
+	{"MP", "MNP", "Northern Mariana Islands"},
+	{"NO", "NOR", "Norway"},
+	{"OM", "OMN", "Oman"},
+	{"PK", "PAK", "Pakistan"},
+	{"PW", "PLW", "Palau"},
+	{"PS", "PSE", "Palestine"},
+	{"PA", "PAN", "Panama"},
+	{"PG", "PNG", "Papua New Guinea"},
+	{"PY", "PRY", "Paraguay"},
+	{"PE", "PER", "Peru"},
+	{"PH", "PHL", "Philippines"},
+	{"PN", "PCN", "Pitcairn Islands"},
+	{"PL", "POL", "Poland"},
+	{"PT", "PRT", "Portugal"},
+	{"PR", "PRI", "Puerto Rico"},
+	{"QA", "QAT", "Qatar"},
+	{"RE", "REU", "Réunion"},
+	{"RO", "ROU", "Romania"},
+	{"RU", "RUS", "Russia"},
+	{"RW", "RWA", "Rwanda"},
+	{"BL", "BLM", "Saint Barthélemy"},
+	{"SH", "SHN", "Saint Helena"},
+	{"KN", "KNA", "Saint Kitts and Nevis"},
+	{"LC", "LCA", "Saint Lucia"},
+	{"MF", "MAF", "Saint Martin"},
+	{"PM", "SPM", "Saint Pierre and Miquelon"},
+	{"VC", "VCT", "Saint Vincent and the Grenadines"},
+	{"WS", "WSM", "Samoa"},
+	{"SM", "SMR", "San Marino"},
+	{"ST", "STP", "São Tomé and Príncipe"},
+	{"SA", "SAU", "Saudi Arabia"},
+	{"SN", "SEN", "Senegal"},
+	{"RS", "SRB", "Serbia"},
+	{"SC", "SYC", "Seychelles"},
+	{"SL", "SLE", "Sierra Leone"},
+	{"SG", "SGP", "Singapore"},
+	{"SX", "SXM", "Sint Maarten"},
+	{"SK", "SVK", "Slovakia"},
+	{"SI", "SVN", "Slovenia"},
+	{"SB", "SLB", "Solomon Islands"},
+	{"SO", "SOM", "Somalia"},
+	{"ZA", "ZAF", "South Africa"},
+	{"GS", "SGS", "South Georgia and the South Sandwich Islands"},
+	{"SS", "SSD", "South Sudan"},
+	{"ES", "ESP", "Spain"},
+	{"LK", "LKA", "Sri Lanka"},
+	{"SD", "SDN", "Sudan"},
+	{"SR", "SUR", "Suriname"},
+	{"SJ", "SJM", "Svalbard and Jan Mayen"},
+	{"SE", "SWE", "Sweden"},
+	{"CH", "CHE", "Switzerland"},
+	{"SY", "SYR", "Syria"},
+	{"TW", "TWN", "Taiwan"},
+	{"TJ", "TJK", "Tajikistan"},
+	{"TZ", "TZA", "Tanzania"},
+	{"TH", "THA", "Thailand"},
+	{"TL", "TLS", "Timor-Leste"},
+	{"TG", "TGO", "Togo"},
+	{"TK", "TKL", "Tokelau"},
+	{"TO", "TON", "Tonga"},
+	{"TT", "TTO", "Trinidad and Tobago"},
+	{"TN", "TUN", "Tunisia"},
+	{"TR", "TUR", "Turkey"},
+	{"TM", "TKM", "Turkmenistan"},
+	{"TC", "TCA", "Turks and Caicos Islands"},
+	{"TV", "TUV", "Tuvalu"},
+	{"UG", "UGA", "Uganda"},
+	{"UA", "UKR", "Ukraine"},
+	{"AE", "ARE", "United Arab Emirates"},
+	{"GB", "GBR", "United Kingdom"},
+	{"US", "USA", "United States"},
+	{"UM", "UMI", "United States Minor Outlying Islands"},
+	{"UY", "URY", "Uruguay"},
+	{"UZ", "UZB", "Uzbekistan"},
+	{"VU", "VUT", "Vanuatu"},
+	{"VE", "VEN", "Venezuela"},
+	{"VN", "VNM", "Vietnam"},
+	{"VG", "VGB", "British Virgin Islands"},
+	{"VI", "VIR", "U.S. Virgin Islands"},
+	{"WF", "WLF", "Wallis and Futuna"},
+	{"EH", "ESH", "Western Sahara"},
+	{"YE", "YEM", "Yemen"},
+	{"ZM", "ZMB", "Zambia"},
+	{"ZW", "ZWE", "Zimbabwe"},
+}
+
+// isoAliases maps other names to ISO3 codes: official ISO names, the forms
+// the World Bank ("Korea, Rep.") and UN Comtrade ("Rep. of Korea") use, and
+// common native names. They are normalized like lookups, so case, accents
+// and punctuation don't matter.
+var isoAliases = map[string]string{
+	// Official and statistical-agency names
+	"United States of America": "USA",
+	"America":                  "USA",
+	"United Kingdom of Great Britain and Northern Ireland": "GBR",
+	"Great Britain":                         "GBR",
+	"Britain":                               "GBR",
+	"England":                               "GBR",
+	"Scotland":                              "GBR",
+	"Wales":                                 "GBR",
+	"UK":                                    "GBR",
+	"Korea":                                 "KOR",
+	"Republic of Korea":                     "KOR",
+	"Korea, Rep.":                           "KOR",
+	"Korea (South)":                         "KOR",
+	"ROK":                                   "KOR",
+	"Democratic People's Republic of Korea": "PRK",
+	"Korea, Dem. People's Rep.":             "PRK",
+	"Korea (North)":                         "PRK",
+	"DPRK":                                  "PRK",
+	"Russian Federation":                    "RUS",
+	"Iran, Islamic Rep.":                    "IRN",
+	"Islamic Republic of Iran":              "IRN",
+	"Persia":                                "IRN",
+	"Viet Nam":                              "VNM",
+	"Czechia":                               "CZE",
+	"Turkiye":                               "TUR",
+	"Republic of China":                     "TWN",
+	"Chinese Taipei":                        "TWN",
+	"Taiwan, Province of China":             "TWN",
+	"Other Asia, nes":                       "TWN", // UN Comtrade's name for Taiwan
+	"People's Republic of China":            "CHN",
+	"PRC":                                   "CHN",
+	"Mainland China":                        "CHN",
+	"Hong Kong SAR, China":                  "HKG",
+	"China, Hong Kong SAR":                  "HKG",
+	"Macau":                                 "MAC",
+	"Macao SAR, China":                      "MAC",
+	"China, Macao SAR":                      "MAC",
+	"Egypt, Arab Rep.":                      "EGY",
+	"Arab Republic of Egypt":                "EGY",
+	"Congo, Dem. Rep.":                      "COD",
+	"DR Congo":                              "COD",
+	"DRC":                                   "COD",
+	"Democratic Republic of Congo":          "COD",
+	"Zaire":                                 "COD",
+	"Congo":                                 "COG",
+	"Congo, Rep.":                           "COG",
+	"Congo-Brazzaville":                     "COG",
+	"Syrian Arab Republic":                  "SYR",
+	"Lao PDR":                               "LAO",
+	"Lao People's Democratic Republic":      "LAO",
+	"Venezuela, RB":                         "VEN",
+	"Bolivarian Republic of Venezuela":      "VEN",
+	"Plurinational State of Bolivia":        "BOL",
+	"United Republic of Tanzania":           "TZA",
+	"Republic of Moldova":                   "MDA",
+	"Yemen, Rep.":                           "YEM",
+	"Slovak Republic":                       "SVK",
+	"Kyrgyz Republic":                       "KGZ",
+	"Brunei Darussalam":                     "BRN",
+	"Cabo Verde":                            "CPV",
+	"Côte d'Ivoire":                         "CIV",
+	"Swaziland":                             "SWZ",
+	"Macedonia":                             "MKD",
+	"Burma":                                 "MMR",
+	"Micronesia, Fed. Sts.":                 "FSM",
+	"Federated States of Micronesia":        "FSM",
+	"West Bank and Gaza":                    "PSE",
+	"State of Palestine":                    "PSE",
+	"East Timor":                            "TLS",
+	"Holland":                               "NLD",
+	"UAE":                                   "ARE",
+	"Emirates":                              "ARE",
+	"KSA":                                   "SAU",
+	"Holy See":                              "VAT",
+	"Vatican":                               "VAT",
+	"St. Kitts-Nevis":                       "KNA",
+	"Virgin Islands (U.S.)":                 "VIR",
+	"United States Virgin Islands":          "VIR",
+	"Virgin Islands, British":               "VGB",
+
+	// Native names
+	"Deutschland":  "DEU",
+	"España":       "ESP",
+	"Italia":       "ITA",
+	"Nippon":       "JPN",
+	"Nihon":        "JPN",
+	"日本":           "JPN",
+	"Zhongguo":     "CHN",
+	"中国":           "CHN",
+	"Bharat":       "IND",
+	"Brasil":       "BRA",
+	"Schweiz":      "CHE",
+	"Suisse":       "CHE",
+	"Svizzera":     "CHE",
+	"Österreich":   "AUT",
+	"Sverige":      "SWE",
+	"Norge":        "NOR",
+	"Danmark":      "DNK",
+	"Suomi":        "FIN",
+	"Polska":       "POL",
+	"Česko":        "CZE",
+	"Magyarország": "HUN",
+	"Hellas":       "GRC",
+	"Ellada":       "GRC",
+	"Éire":         "IRL",
+	"Nederland":    "NLD",
+	"België":       "BEL",
+	"Belgique":     "BEL",
+	"Rossiya":      "RUS",
+	"Россия":       "RUS",
+	"Україна":      "UKR",
+	"Hanguk":       "KOR",
+	"한국":           "KOR",
+	"대한민국":         "KOR",
+	"Misr":         "EGY",
+	"Hrvatska":     "HRV",
+	"Srbija":       "SRB",
+	"Slovensko":    "SVK",
+	"Lietuva":      "LTU",
+	"Latvija":      "LVA",
+	"Eesti":        "EST",
+	"Ísland":       "ISL",
+	"Sakartvelo":   "GEO",
+	"Hayastan":     "ARM",
+	"Druk Yul":     "BTN",
+	"Al-Maghrib":   "MAR",
+	"Muang Thai":   "THA",
+	"Prathet Thai": "THA",
+	"Pilipinas":    "PHL",
+	"Aotearoa":     "NZL",
+}
+
+// countryStopwords are dropped when matching names by their words, so
+// "Korea, Rep." finds "Korea" and "Bolivia (Plurinational State of)" finds
+// "Bolivia"
+var countryStopwords = map[string]bool{
+	"the": true, "of": true, "and": true, "republic": true, "state": true,
+	"plurinational": true, "bolivarian": true, "islamic": true, "federal": true,
+	"federation": true, "nes": true,
+}
+
+// countryAbbreviations expand the short forms statistical tables use
+var countryAbbreviations = map[string]string{
+	"rep": "republic", "dem": "democratic", "st": "saint", "sts": "states",
+	"fed": "federal", "is": "islands", "isl": "islands", "utd": "united",
+}
+
+// isoIndex looks up registry entries by normalized name
+type isoIndex struct {
+	mu      sync.RWMutex
+	byName  map[string]int // Normalized name or alias
+	byWords map[string]int // Sorted words without stopwords; -1 = ambiguous
+	byCode  map[string]int // Upper-case alpha-2 and alpha-3
+}
+
+var (
+	isoOnce sync.Once
+	iso     *isoIndex
+)
+
+func isoRegistry() *isoIndex {
+	isoOnce.Do(func() {
+		iso = &isoIndex{
+			byName:  make(map[string]int),
+			byWords: make(map[string]int),
+			byCode:  make(map[string]int),
+		}
+		for i, c := range isoCountries {
+			iso.byCode[c.Alpha2] = i
+			iso.byCode[c.Alpha3] = i
+			iso.addLocked(c.Name, i)
+		}
+		for alias, code := range isoAliases {
+			iso.addLocked(alias, iso.byCode[code])
+		}
+	})
+	return iso
+}
+
+// addLocked indexes name for registry entry i (must be called with mu held or
+// before the index is shared)
+func (x *isoIndex) addLocked(name string, i int) {
+	key := NormalizeCountryName(name)
+	if key == "" {
+		return
+	}
+	x.byName[key] = i
+	words := countryWords(key)
+	if prev, ok := x.byWords[words]; ok && prev != i {
+		x.byWords[words] = -1
+		return
+	}
+	x.byWords[words] = i
+}
+
+// NormalizeCountryName folds case, accents, punctuation and abbreviations so
+// "Korea, Rep." and "rep korea" compare equal: a "Name, Qualifier" form is
+// turned around, "Rep." becomes "republic", and spelled-out initials such as
+// "U.S.A." are joined.
+func NormalizeCountryName(name string) string {
+	s := strings.ToLower(strings.TrimSpace(name))
+	if before, after, ok := strings.Cut(s, ","); ok && !strings.Contains(after, ",") {
+		s = after + " " + before
+	}
+	s = strings.NewReplacer("'", "", "’", "", "&", " and ").Replace(s)
+
+	var b strings.Builder
+	for _, r := range s {
+		if folded, ok := accentFold[r]; ok {
+			b.WriteString(folded)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+
+	var words []string
+	initials := ""
+	flush := func() {
+		if initials != "" {
+			words = append(words, initials)
+			initials = ""
+		}
+	}
+	for _, w := range strings.Fields(b.String()) {
+		if len([]rune(w)) == 1 {
+			initials += w
+			continue
+		}
+		flush()
+		if full, ok := countryAbbreviations[w]; ok {
+			w = full
+		}
+		words = append(words, w)
+	}
+	flush()
+	return strings.Join(words, " ")
+}
+
+// accentFold maps accented Latin letters to their base letters
+var accentFold = map[rune]string{
+	'á': "a", 'à': "a", 'â': "a", 'ä': "a", 'ã': "a", 'å': "a", 'ā': "a",
+	'é': "e", 'è': "e", 'ê': "e", 'ë': "e", 'ē': "e",
+	'í': "i", 'ì': "i", 'î': "i", 'ï': "i", 'ı': "i",
+	'ó': "o", 'ò': "o", 'ô': "o", 'ö': "o", 'õ': "o", 'ø': "o", 'ō': "o",
+	'ú': "u", 'ù': "u", 'û': "u", 'ü': "u", 'ū': "u",
+	'ç': "c", 'č': "c", 'ñ': "n", 'š': "s", 'ş': "s", 'ž': "z", 'ğ': "g",
+	'ý': "y", 'ß': "ss", 'æ': "ae", 'œ': "oe",
+}
+
+// countryWords is a normalized name's words without stopwords, sorted
+func countryWords(key string) string {
+	var words []string
+	for _, w := range strings.Fields(key) {
+		if !countryStopwords[w] {
+			words = append(words, w)
+		}
+	}
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// ResolveCountry finds the ISO country a name refers to. It tries, in order:
+// the name or a known alias after normalization, an ISO alpha-2 or alpha-3
+// code, the same words in another order or with "Republic of"-style
+// qualifiers dropped, and finally a near spelling (one typo, two in long
+// names) when only one country is that close.
+func ResolveCountry(name string) (ISOCountry, bool) {
+	x := isoRegistry()
+	key := NormalizeCountryName(name)
+	if key == "" {
+		return ISOCountry{}, false
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if i, ok := x.byName[key]; ok {
+		return isoCountries[i], true
+	}
+	// "IS" before normalization expands it to "islands"; "U.S.A." after
+	for _, code := range []string{strings.ToUpper(strings.TrimSpace(name)), strings.ToUpper(key)} {
+		if i, ok := x.byCode[code]; ok && (len(code) == 2 || len(code) == 3) {
+			return isoCountries[i], true
+		}
+	}
+	if i, ok := x.byWords[countryWords(key)]; ok && i >= 0 {
+		return isoCountries[i], true
+	}
+
+	n := len([]rune(key))
+	if n < 5 {
+		return ISOCountry{}, false
+	}
+	maxDist := 1
+	if n >= 10 {
+		maxDist = 2
+	}
+	best, bestDist, tied := -1, maxDist+1, false
+	for candidate, i := range x.byName {
+		d := editDistance(key, candidate, maxDist)
+		switch {
+		case d < bestDist:
+			best, bestDist, tied = i, d, false
+		case d == bestDist && i != best:
+			tied = true
+		}
+	}
+	if best < 0 || tied {
+		return ISOCountry{}, false
+	}
+	return isoCountries[best], true
+}
+
+// AddCountryAlias makes name resolve to the country target names, which may
+// be an ISO code or any name ResolveCountry accepts
+func AddCountryAlias(name, target string) error {
+	c, ok := ResolveCountry(target)
+	if !ok {
+		return fmt.Errorf("unknown country %q for alias %q", target, name)
+	}
+	x := isoRegistry()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.addLocked(name, x.byCode[c.Alpha3])
+	return nil
+}
+
+// editDistance is the Levenshtein distance between a and b, or limit+1 once
+// it is certain to exceed limit
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
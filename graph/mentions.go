@@ -0,0 +1,138 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Social mention volume is kept as hourly counts per node, so a burst of
+// chatter about a node can be measured against how much it is usually
+// talked about, before anyone has scored what the chatter says.
+
+// maxMentionAge bounds how far back hourly mention counts are kept
+const maxMentionAge = 31 * 24 * time.Hour
+
+// MentionHistory is a node's mention counts, one bucket per hour with any
+type MentionHistory struct {
+	NodeID  string          `json:"node_id"`
+	Buckets []MentionBucket `json:"buckets"` // Oldest first
+}
+
+// MentionBucket counts the mentions of a node within one hour
+type MentionBucket struct {
+	Hour  time.Time `json:"hour"` // Start of the hour
+	Count int       `json:"count"`
+}
+
+// MentionVolume compares a node's mentions in the latest hour with its
+// hourly baseline
+type MentionVolume struct {
+	NodeID  string  `json:"node_id"`
+	Current int     `json:"current"` // Mentions in the hour containing the reference time
+	Mean    float64 `json:"mean"`    // Mean mentions per hour over the baseline
+	StdDev  float64 `json:"std_dev"`
+	Hours   int     `json:"hours"` // Hours the baseline covers (fewer while history builds up)
+}
+
+// RecordMentions adds n mentions of a node at the given time to its hourly
+// counts. Mentions older than maxMentionAge are ignored.
+func (g *Graph) RecordMentions(id string, at time.Time, n int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.Nodes[id]; !ok {
+		return fmt.Errorf("node %s not found", id)
+	}
+	now := time.Now()
+	if n <= 0 || now.Sub(at) > maxMentionAge {
+		return nil
+	}
+	if g.MentionHistories == nil {
+		g.MentionHistories = make(map[string]*MentionHistory)
+	}
+	history, exists := g.MentionHistories[id]
+	if !exists {
+		history = &MentionHistory{NodeID: id}
+		g.MentionHistories[id] = history
+	}
+
+	hour := at.Truncate(time.Hour)
+	i := sort.Search(len(history.Buckets), func(i int) bool { return !history.Buckets[i].Hour.Before(hour) })
+	if i < len(history.Buckets) && history.Buckets[i].Hour.Equal(hour) {
+		history.Buckets[i].Count += n
+	} else {
+		history.Buckets = append(history.Buckets, MentionBucket{})
+		copy(history.Buckets[i+1:], history.Buckets[i:])
+		history.Buckets[i] = MentionBucket{Hour: hour, Count: n}
+	}
+
+	cutoff := now.Add(-maxMentionAge)
+	drop := 0
+	for drop < len(history.Buckets) && history.Buckets[drop].Hour.Before(cutoff) {
+		drop++
+	}
+	if drop > 0 {
+		history.Buckets = append(history.Buckets[:0:0], history.Buckets[drop:]...)
+	}
+	return nil
+}
+
+// MentionVolume measures a node's mentions in the hour containing at against
+// the mean and standard deviation of its hourly counts over the baseline
+// before it. Hours without mentions count as zero, from the node's first
+// recorded mention on.
+func (g *Graph) MentionVolume(id string, at time.Time, baseline time.Duration) MentionVolume {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	v := MentionVolume{NodeID: id}
+	history, ok := g.MentionHistories[id]
+	if !ok || len(history.Buckets) == 0 {
+		return v
+	}
+	hour := at.Truncate(time.Hour)
+	start := hour.Add(-baseline)
+	if first := history.Buckets[0].Hour; first.After(start) {
+		start = first
+	}
+	v.Hours = int(hour.Sub(start) / time.Hour)
+
+	var sum, sumSquares float64
+	for _, b := range history.Buckets {
+		switch {
+		case b.Hour.Equal(hour):
+			v.Current = b.Count
+		case !b.Hour.Before(start) && b.Hour.Before(hour):
+			c := float64(b.Count)
+			sum += c
+			sumSquares += c * c
+		}
+	}
+	if v.Hours > 0 {
+		v.Mean = sum / float64(v.Hours)
+		v.StdDev = math.Sqrt(math.Max(0, sumSquares/float64(v.Hours)-v.Mean*v.Mean))
+	}
+	return v
+}
+
+// MentionCounts returns a node's hourly mention counts for the hours since
+// since, oldest first, with zeros for hours without mentions
+func (g *Graph) MentionCounts(id string, since time.Time) []MentionBucket {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var out []MentionBucket
+	byHour := make(map[int64]int) // Keyed by Unix time, since loaded buckets differ in location
+	if history, ok := g.MentionHistories[id]; ok {
+		for _, b := range history.Buckets {
+			byHour[b.Hour.Unix()] = b.Count
+		}
+	}
+	now := time.Now().Truncate(time.Hour)
+	for hour := since.Truncate(time.Hour); !hour.After(now); hour = hour.Add(time.Hour) {
+		out = append(out, MentionBucket{Hour: hour, Count: byHour[hour.Unix()]})
+	}
+	return out
+}
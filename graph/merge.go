@@ -0,0 +1,444 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Two people seeding the same world end up with overlapping graphs: the same
+// nodes, sometimes under different IDs, and the same edges with different
+// weights. Merge folds another graph into this one instead of replacing it,
+// matching duplicate nodes by name and settling conflicts by a strategy.
+
+// MergeStrategy decides which side of a conflict a merge keeps
+type MergeStrategy string
+
+const (
+	MergePreferNewer      MergeStrategy = "prefer-newer"      // Latest LastUpdated (nodes) or Timestamp (edges)
+	MergePreferConfidence MergeStrategy = "prefer-confidence" // Higher "confidence" attribute, then newer
+	MergeReview           MergeStrategy = "review"            // Keep ours and list the conflict for review
+)
+
+// defaultConfidence is the confidence of an entry without a "confidence" attribute
+const defaultConfidence = 0.5
+
+// ParseMergeStrategy parses a strategy name
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	switch MergeStrategy(s) {
+	case MergePreferNewer, MergePreferConfidence, MergeReview:
+		return MergeStrategy(s), nil
+	case "prefer-higher-confidence":
+		return MergePreferConfidence, nil
+	}
+	return "", fmt.Errorf("unknown merge strategy %q (use %s, %s or %s)", s, MergePreferNewer, MergePreferConfidence, MergeReview)
+}
+
+// MergeConflict is a node or edge both graphs hold with different contents
+type MergeConflict struct {
+	Node   string // Node ID, for node conflicts
+	Edge   string // Edge key ("src|tgt|type[|hs_code]"), for edge conflicts
+	Fields []string
+	Kept   string // "ours" or "theirs"
+	Reason string // Why that side was kept
+}
+
+// MergeReport is what a merge changed
+type MergeReport struct {
+	NodesAdded   int
+	NodesUpdated int
+	EdgesAdded   int
+	EdgesUpdated int
+	Duplicates   map[string]string // Their node ID -> our node ID, for nodes matched by name and type
+	Conflicts    []MergeConflict   // Every conflict, with the side kept; under MergeReview, the review list
+}
+
+// Merge folds other into the graph. Nodes of the same type whose names
+// match (ignoring case, accents and punctuation; nations by ISO code) are
+// one node under our ID, and their edges follow. Nodes and edges only other
+// holds are added. Where both hold a node or edge with different contents,
+// strategy picks the side kept; a node's health and price always come from
+// the side updated last, since they are readings rather than facts.
+// Histories, events and co-mentions only other holds are added too.
+func (g *Graph) Merge(other *Graph, strategy MergeStrategy) MergeReport {
+	report := MergeReport{Duplicates: make(map[string]string)}
+	if other == g {
+		return report
+	}
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	alias := func(id string) string {
+		if ours, ok := report.Duplicates[id]; ok {
+			return ours
+		}
+		return id
+	}
+
+	// Duplicates: their node missing here by ID but present by name and type
+	byName := make(map[string]string, len(g.Nodes))
+	for id, n := range g.Nodes {
+		byName[mergeNameKey(n)] = id
+	}
+	theirIDs := make([]string, 0, len(other.Nodes))
+	for id := range other.Nodes {
+		theirIDs = append(theirIDs, id)
+	}
+	sort.Strings(theirIDs)
+	for _, id := range theirIDs {
+		if _, ok := g.Nodes[id]; ok {
+			continue
+		}
+		if ours, ok := byName[mergeNameKey(other.Nodes[id])]; ok {
+			report.Duplicates[id] = ours
+		}
+	}
+
+	// Nodes
+	for _, id := range theirIDs {
+		theirs := other.Nodes[id]
+		ourID := alias(id)
+		ours, exists := g.Nodes[ourID]
+		if !exists {
+			n := copyNode(theirs)
+			g.Nodes[ourID] = n
+			g.recordHealth(ourID, n.Health)
+			g.emit(Delta{Kind: DeltaNode, Node: n})
+			report.NodesAdded++
+			continue
+		}
+
+		ourConfidence := confidenceOf(ours.Attributes) // Before gaps are filled from theirs
+		changed := fillNodeGaps(ours, theirs)
+		if theirs.LastUpdated.After(ours.LastUpdated) && (theirs.Health != ours.Health || theirs.Price != ours.Price) {
+			ours.Health, ours.Price = theirs.Health, theirs.Price
+			changed = true
+		}
+		if fields := nodeConflicts(ours, theirs); len(fields) > 0 {
+			keepTheirs, reason := mergeDecision(strategy, ours.LastUpdated, theirs.LastUpdated, ourConfidence, confidenceOf(theirs.Attributes))
+			c := MergeConflict{Node: ourID, Fields: fields, Kept: "ours", Reason: reason}
+			if keepTheirs {
+				c.Kept = "theirs"
+				prev := *ours
+				*ours = *copyNode(theirs)
+				ours.ID = ourID
+				ours.Attributes = mergedAttributes(theirs.Attributes, prev.Attributes)
+				if !theirs.LastUpdated.After(prev.LastUpdated) {
+					ours.Health, ours.Price, ours.LastUpdated = prev.Health, prev.Price, prev.LastUpdated
+				}
+				changed = true
+			}
+			report.Conflicts = append(report.Conflicts, c)
+		}
+		if changed {
+			internNode(ours)
+			g.forgetRelationsLocked(ourID)
+			g.emit(Delta{Kind: DeltaNode, Node: ours})
+			report.NodesUpdated++
+		}
+	}
+
+	// Edges
+	existing := make(map[string]*Edge, len(g.Edges))
+	for _, e := range g.Edges {
+		existing[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())] = e
+	}
+	for _, theirs := range other.Edges {
+		src, tgt := alias(theirs.SourceID), alias(theirs.TargetID)
+		if src == tgt {
+			continue // Both ends were duplicates of one node
+		}
+		if _, ok := g.Nodes[src]; !ok {
+			continue
+		}
+		if _, ok := g.Nodes[tgt]; !ok {
+			continue
+		}
+		key := edgeKey(src, tgt, theirs.Type, theirs.Commodity())
+		ours, exists := existing[key]
+		if !exists {
+			e := copyEdge(theirs)
+			e.SourceID, e.TargetID = src, tgt
+			g.addEdgeLocked(e)
+			existing[key] = e
+			report.EdgesAdded++
+			continue
+		}
+		ourConfidence := confidenceOf(ours.Attributes)
+		filled := fillAttributes(&ours.Attributes, theirs.Attributes)
+		fields := edgeConflicts(ours, theirs)
+		if len(fields) == 0 {
+			if filled {
+				g.emit(Delta{Kind: DeltaEdge, Edge: ours})
+				report.EdgesUpdated++
+			}
+			continue
+		}
+		keepTheirs, reason := mergeDecision(strategy, ours.Timestamp, theirs.Timestamp, ourConfidence, confidenceOf(theirs.Attributes))
+		c := MergeConflict{Edge: key, Fields: fields, Kept: "ours", Reason: reason}
+		if keepTheirs {
+			c.Kept = "theirs"
+			ours.Weight = theirs.Weight
+			ours.Status = EdgeStatus(intern(string(theirs.Status)))
+			ours.Override = theirs.Override
+			ours.Timestamp = theirs.Timestamp
+			ours.Attributes = mergedAttributes(theirs.Attributes, ours.Attributes)
+			g.forgetEdgeRelationsLocked(ours)
+			g.recordEdgeHistory(ours, "")
+			g.emit(Delta{Kind: DeltaEdge, Edge: ours})
+			report.EdgesUpdated++
+		} else if filled {
+			g.emit(Delta{Kind: DeltaEdge, Edge: ours})
+			report.EdgesUpdated++
+		}
+		report.Conflicts = append(report.Conflicts, c)
+	}
+
+	g.mergeHistoriesLocked(other, alias)
+	g.triggerAutoSaveN(report.NodesAdded + report.NodesUpdated + report.EdgesAdded + report.EdgesUpdated)
+	return report
+}
+
+// mergeHistoriesLocked adds the histories, applied events, calendar entries
+// and co-mentions only other holds, under our IDs (must be called with both
+// locks held)
+func (g *Graph) mergeHistoriesLocked(other *Graph, alias func(string) string) {
+	for _, h := range other.EdgeHistories {
+		src, tgt := alias(h.SourceID), alias(h.TargetID)
+		key := edgeKey(src, tgt, h.Type, h.Commodity)
+		if _, ok := g.EdgeHistories[key]; !ok {
+			cp := *h
+			cp.SourceID, cp.TargetID = src, tgt
+			cp.events = nil // Built again from the copied history
+			cp.History = append(cp.History[:0:0], h.History...)
+			g.EdgeHistories[key] = &cp
+		}
+	}
+	for id, h := range other.NodeHistories {
+		if _, ok := g.NodeHistories[alias(id)]; !ok {
+			cp := *h
+			cp.NodeID = alias(id)
+			cp.History = append(cp.History[:0:0], h.History...)
+			g.NodeHistories[alias(id)] = &cp
+		}
+	}
+	for id, h := range other.HealthHistories {
+		if _, ok := g.HealthHistories[alias(id)]; !ok {
+			cp := *h
+			cp.NodeID = alias(id)
+			cp.History = append(cp.History[:0:0], h.History...)
+			g.HealthHistories[alias(id)] = &cp
+		}
+	}
+	for id, h := range other.SentimentHistories {
+		if g.SentimentHistories == nil {
+			g.SentimentHistories = make(map[string]*SentimentHistory)
+		}
+		if _, ok := g.SentimentHistories[alias(id)]; !ok {
+			cp := *h
+			cp.NodeID = alias(id)
+			cp.History = append(cp.History[:0:0], h.History...)
+			g.SentimentHistories[alias(id)] = &cp
+		}
+	}
+	for id, h := range other.MentionHistories {
+		if g.MentionHistories == nil {
+			g.MentionHistories = make(map[string]*MentionHistory)
+		}
+		if _, ok := g.MentionHistories[alias(id)]; !ok {
+			cp := *h
+			cp.NodeID = alias(id)
+			cp.Buckets = append(cp.Buckets[:0:0], h.Buckets...)
+			g.MentionHistories[alias(id)] = &cp
+		}
+	}
+	for id, h := range other.PriceHistories {
+		if g.PriceHistories == nil {
+			g.PriceHistories = make(map[string]*PriceHistory)
+		}
+		if _, ok := g.PriceHistories[alias(id)]; !ok {
+			cp := *h
+			cp.NodeID = alias(id)
+			cp.History = append(cp.History[:0:0], h.History...)
+			g.PriceHistories[alias(id)] = &cp
+		}
+	}
+	for _, pair := range other.CoMentions {
+		key, a, b := coMentionKey(alias(pair.A), alias(pair.B))
+		if a == b {
+			continue
+		}
+		if g.CoMentions == nil {
+			g.CoMentions = make(map[string]*CoMention)
+		}
+		if _, ok := g.CoMentions[key]; !ok {
+			g.CoMentions[key] = &CoMention{A: a, B: b, Items: append([]CoMentionItem(nil), pair.Items...)}
+		}
+	}
+	for id, at := range other.AppliedEvents {
+		if g.AppliedEvents == nil {
+			g.AppliedEvents = make(map[string]time.Time)
+		}
+		if _, ok := g.AppliedEvents[id]; !ok {
+			g.AppliedEvents[id] = at
+		}
+	}
+	for id, events := range other.Calendar {
+		if g.Calendar == nil {
+			g.Calendar = make(map[string][]ScheduledEvent)
+		}
+		if _, ok := g.Calendar[alias(id)]; !ok {
+			cp := make([]ScheduledEvent, len(events))
+			for i, ev := range events {
+				ev.NodeID = alias(id)
+				cp[i] = ev
+			}
+			g.Calendar[alias(id)] = cp
+		}
+	}
+}
+
+// mergeDecision reports whether a conflict keeps their side, and why
+func mergeDecision(strategy MergeStrategy, ourTime, theirTime time.Time, ourConfidence, theirConfidence float64) (bool, string) {
+	switch strategy {
+	case MergeReview:
+		return false, string(MergeReview)
+	case MergePreferConfidence:
+		if theirConfidence != ourConfidence {
+			return theirConfidence > ourConfidence, fmt.Sprintf("confidence %.2f vs %.2f", ourConfidence, theirConfidence)
+		}
+	}
+	if theirTime.After(ourTime) {
+		return true, "theirs is newer"
+	}
+	return false, "ours is newer or as new"
+}
+
+// confidenceOf reads a "confidence" attribute, or defaultConfidence
+func confidenceOf(attrs map[string]interface{}) float64 {
+	if c, ok := attrs["confidence"].(float64); ok {
+		return c
+	}
+	return defaultConfidence
+}
+
+// mergeNameKey identifies a node by type and folded name, nations by ISO code
+func mergeNameKey(n *Node) string {
+	if n.Type == NodeTypeNation {
+		if c, ok := ResolveCountry(n.Name); ok {
+			return string(n.Type) + "|" + c.Alpha3
+		}
+	}
+	return string(n.Type) + "|" + NormalizeCountryName(n.Name)
+}
+
+// nodeConflicts lists the descriptive fields on which two nodes differ
+func nodeConflicts(ours, theirs *Node) []string {
+	var fields []string
+	if ours.Type != theirs.Type {
+		fields = append(fields, "type")
+	}
+	if ours.Name != theirs.Name {
+		fields = append(fields, "name")
+	}
+	if ours.Ticker != theirs.Ticker && theirs.Ticker != "" {
+		fields = append(fields, "ticker")
+	}
+	if ours.Currency != theirs.Currency && theirs.Currency != "" {
+		fields = append(fields, "currency")
+	}
+	return append(fields, attributeConflicts(ours.Attributes, theirs.Attributes)...)
+}
+
+// edgeConflicts lists the fields on which two edges differ
+func edgeConflicts(ours, theirs *Edge) []string {
+	var fields []string
+	if ours.Weight != theirs.Weight {
+		fields = append(fields, "weight")
+	}
+	if ours.Status != theirs.Status && theirs.Status != "" {
+		fields = append(fields, "status")
+	}
+	if !reflect.DeepEqual(ours.Override, theirs.Override) {
+		fields = append(fields, "override")
+	}
+	return append(fields, attributeConflicts(ours.Attributes, theirs.Attributes)...)
+}
+
+// attributeConflicts lists the attributes both sides set to different values
+func attributeConflicts(ours, theirs map[string]interface{}) []string {
+	var fields []string
+	for k, v := range theirs {
+		if mine, ok := ours[k]; ok && !reflect.DeepEqual(mine, v) {
+			fields = append(fields, "attributes."+k)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// fillNodeGaps copies onto ours the ticker, currency and attributes only
+// theirs has, reporting whether anything was copied
+func fillNodeGaps(ours, theirs *Node) bool {
+	changed := false
+	if ours.Ticker == "" && theirs.Ticker != "" {
+		ours.Ticker = theirs.Ticker
+		changed = true
+	}
+	if ours.Currency == "" && theirs.Currency != "" {
+		ours.Currency = theirs.Currency
+		changed = true
+	}
+	return fillAttributes(&ours.Attributes, theirs.Attributes) || changed
+}
+
+// fillAttributes copies into *ours the attributes only theirs has, reporting
+// whether any were copied
+func fillAttributes(ours *map[string]interface{}, theirs map[string]interface{}) bool {
+	changed := false
+	for k, v := range theirs {
+		if _, ok := (*ours)[k]; ok {
+			continue
+		}
+		if *ours == nil {
+			*ours = make(map[string]interface{}, len(theirs))
+		}
+		(*ours)[k] = v
+		changed = true
+	}
+	return changed
+}
+
+// mergedAttributes is the winner's attributes plus any only the loser has
+func mergedAttributes(winner, loser map[string]interface{}) map[string]interface{} {
+	if winner == nil && loser == nil {
+		return nil
+	}
+	out := copyAttributes(loser)
+	if out == nil {
+		out = make(map[string]interface{}, len(winner))
+	}
+	for k, v := range winner {
+		out[k] = v
+	}
+	return out
+}
+
+func copyNode(n *Node) *Node {
+	cp := *n
+	cp.Attributes = copyAttributes(n.Attributes)
+	return &cp
+}
+
+func copyEdge(e *Edge) *Edge {
+	cp := *e
+	cp.Attributes = copyAttributes(e.Attributes)
+	if e.Override != nil {
+		o := *e.Override
+		cp.Override = &o
+	}
+	return &cp
+}
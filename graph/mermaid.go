@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ToMermaid returns a company's supply chain as a Mermaid flowchart:
+// suppliers -> company -> clients, with the raw materials it uses and the
+// products it makes. The result can be pasted into Markdown that renders
+// Mermaid (GitHub issues, docs) inside a ```mermaid block.
+func (g *Graph) ToMermaid(companyID string) (string, error) {
+	rel, err := g.GetCompanyRelations(companyID)
+	if err != nil {
+		return "", err
+	}
+
+	var w strings.Builder
+	w.WriteString("flowchart LR\n")
+
+	// Mermaid IDs must be plain words, so nodes get positional IDs
+	ids := make(map[string]string)
+	declare := func(group, title, class string, nodes []*Node) {
+		var fresh []*Node
+		for _, n := range nodes {
+			if _, ok := ids[n.ID]; !ok {
+				fresh = append(fresh, n) // Not listed in an earlier group, e.g. as a supplier that is also a client
+			}
+		}
+		if len(fresh) == 0 {
+			return
+		}
+		sort.Slice(fresh, func(i, j int) bool { return fresh[i].Name < fresh[j].Name })
+		fmt.Fprintf(&w, "  subgraph %s[\"%s\"]\n", group, title)
+		for _, n := range fresh {
+			id := fmt.Sprintf("n%d", len(ids))
+			ids[n.ID] = id
+			fmt.Fprintf(&w, "    %s[\"%s\"]:::%s\n", id, mermaidLabel(n.Name), class)
+		}
+		w.WriteString("  end\n")
+	}
+
+	ids[rel.CompanyID] = "company"
+	fmt.Fprintf(&w, "  company[\"%s\"]:::focus\n", mermaidLabel(rel.CompanyName))
+	declare("materials", "Raw materials", "material", rel.RawMaterials)
+	declare("suppliers", "Suppliers", "corporation", rel.Suppliers)
+	declare("clients", "Clients", "corporation", rel.Clients)
+	declare("products", "Products", "product", rel.Products)
+
+	link := func(nodes []*Node, format string) {
+		sorted := append([]*Node(nil), nodes...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+		for _, n := range sorted {
+			fmt.Fprintf(&w, format, ids[n.ID])
+		}
+	}
+	link(rel.RawMaterials, "  %s -.-> company\n")
+	link(rel.Suppliers, "  %s --> company\n")
+	link(rel.Clients, "  company --> %s\n")
+	link(rel.Products, "  company --- %s\n")
+
+	w.WriteString("  classDef focus fill:#fa8072,stroke:#333,stroke-width:2px\n")
+	w.WriteString("  classDef corporation fill:#fde0dc,stroke:#c0392b\n")
+	w.WriteString("  classDef material fill:#d5f5e3,stroke:#27ae60\n")
+	w.WriteString("  classDef product fill:#fcf3cf,stroke:#b7950b\n")
+	return w.String(), nil
+}
+
+// mermaidLabel escapes a name for a quoted Mermaid label
+func mermaidLabel(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(s)
+}
@@ -3,8 +3,11 @@ package graph
 import (
 	"encoding/json"
 	"fmt"
+	"margraf/audit"
+	"margraf/clock"
 	"margraf/logger"
 	"os"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -13,12 +16,15 @@ import (
 type NodeType string
 
 const (
-	NodeTypeNation      NodeType = "Nation"
-	NodeTypeCorporation NodeType = "Corporation"
-	NodeTypeProduct     NodeType = "Product" // Generic product
-	NodeTypeIndustry    NodeType = "Industry"
-	NodeTypeRawMaterial NodeType = "RawMaterial"
-	NodeTypeCrop        NodeType = "Crop"
+	NodeTypeNation         NodeType = "Nation"
+	NodeTypeCorporation    NodeType = "Corporation"
+	NodeTypeProduct        NodeType = "Product" // Generic product
+	NodeTypeIndustry       NodeType = "Industry"
+	NodeTypeRawMaterial    NodeType = "RawMaterial"
+	NodeTypeCrop           NodeType = "Crop"
+	NodeTypeInfrastructure NodeType = "Infrastructure" // Ports, canals, straits
+	NodeTypeCurrency       NodeType = "Currency"
+	NodeTypeCentralBank    NodeType = "CentralBank"
 )
 
 // EdgeType represents the nature of the relationship.
@@ -41,6 +47,18 @@ const (
 	EdgeTypeProcuresFrom EdgeType = "ProcuresFrom" // Client -> Supplier (for reference, shocks flow reverse)
 	EdgeTypeManufactures EdgeType = "Manufactures" // Company -> Product
 	EdgeTypeConsumes     EdgeType = "Consumes"     // Company -> RawMaterial
+
+	// Ownership Edges (conglomerate structure)
+	EdgeTypeOwns         EdgeType = "Owns"         // Parent -> Subsidiary
+	EdgeTypeSubsidiaryOf EdgeType = "SubsidiaryOf" // Subsidiary -> Parent
+
+	// Logistics Edges
+	EdgeTypeRoutesThrough EdgeType = "RoutesThrough" // Nation -> Infrastructure (trade passes through it)
+
+	// Monetary Edges
+	EdgeTypeIssues       EdgeType = "Issues"       // CentralBank -> Currency
+	EdgeTypeUsesCurrency EdgeType = "UsesCurrency" // Nation -> Currency
+	EdgeTypeReportsIn    EdgeType = "ReportsIn"    // Corporation -> Currency (reporting currency)
 )
 
 // EdgeDirectionality defines how shocks propagate through edge types
@@ -57,6 +75,20 @@ const (
 	DirectionalityReverse EdgeDirectionality = "Reverse"
 )
 
+// EdgeStatus is an edge's operating state. Strong, Active, Weak and Blocked
+// follow from the weight (see weightStatus) unless a manual override pins
+// the status (see status.go).
+type EdgeStatus string
+
+const (
+	EdgeStatusStrong    EdgeStatus = "Strong"    // Weight >= 0.7
+	EdgeStatusActive    EdgeStatus = "Active"    // Weight >= 0.3
+	EdgeStatusWeak      EdgeStatus = "Weak"      // Weight >= 0.1
+	EdgeStatusBlocked   EdgeStatus = "Blocked"   // Weight < 0.1, or embargoed by override
+	EdgeStatusSuspended EdgeStatus = "Suspended" // Override only: paused pending review (e.g. sanctions)
+	EdgeStatusPruned    EdgeStatus = "Pruned"    // Recorded in history when prune removes the edge
+)
+
 // Node represents an entity in the economic ecosystem.
 type Node struct {
 	ID          string                 `json:"id"`
@@ -72,43 +104,143 @@ type Node struct {
 
 // Edge represents a connection between two nodes.
 type Edge struct {
-	SourceID       string             `json:"source_id"`
-	TargetID       string             `json:"target_id"`
-	Type           EdgeType           `json:"type"`
-	Weight         float64            `json:"weight"`         // Represents strength, volume, or influence (0.0 to 1.0 or scalar)
-	Timestamp      time.Time          `json:"timestamp"`      // Temporal Knowledge Graph: Track when edge was created/updated
-	Status         string             `json:"status"`         // Active, Blocked, Suspended, etc.
-	Directionality EdgeDirectionality `json:"directionality"` // How shocks propagate through this edge
+	SourceID       string                 `json:"source_id"`
+	TargetID       string                 `json:"target_id"`
+	Type           EdgeType               `json:"type"`
+	Weight         float64                `json:"weight"`         // Represents strength, volume, or influence (0.0 to 1.0 or scalar)
+	Timestamp      time.Time              `json:"timestamp"`      // Temporal Knowledge Graph: Track when edge was created/updated
+	Status         EdgeStatus             `json:"status"`         // Derived from weight unless Override pins it
+	Directionality EdgeDirectionality     `json:"directionality"` // How shocks propagate through this edge
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+	Override       *StatusOverride        `json:"override,omitempty"` // Manual status that weight changes don't overwrite
+}
+
+// Commodity returns the HS code a commodity-specific edge is keyed on, or "" for aggregate edges.
+func (e *Edge) Commodity() string {
+	if e.Attributes == nil {
+		return ""
+	}
+	code, _ := e.Attributes["hs_code"].(string)
+	return code
+}
+
+// MarketCap returns a corporation's market capitalisation from its
+// fundamentals attributes, or 0 if unknown.
+func (n *Node) MarketCap() float64 {
+	if n.Attributes == nil {
+		return 0
+	}
+	capValue, _ := n.Attributes["market_cap"].(float64)
+	return capValue
+}
+
+// edgeKey builds the EdgeHistories key for an edge. Commodity-specific edges
+// get the HS code appended so they don't share history with the aggregate edge.
+func edgeKey(sourceID, targetID string, edgeType EdgeType, commodity string) string {
+	if commodity == "" {
+		return fmt.Sprintf("%s|%s|%s", sourceID, targetID, edgeType)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s", sourceID, targetID, edgeType, commodity)
 }
 
 // EdgeHistory tracks the temporal evolution of a relationship
 type EdgeHistory struct {
-	SourceID string         `json:"source_id"`
-	TargetID string         `json:"target_id"`
-	Type     EdgeType       `json:"type"`
-	History  []EdgeSnapshot `json:"history"`
+	SourceID  string         `json:"source_id"`
+	TargetID  string         `json:"target_id"`
+	Type      EdgeType       `json:"type"`
+	Commodity string         `json:"commodity,omitempty"`
+	History   []EdgeSnapshot `json:"history"`
+
+	events map[string]struct{} // Event IDs in History, built on the first lookup (see edgeSawEventLocked)
 }
 
 // EdgeSnapshot represents a point-in-time state of an edge
 type EdgeSnapshot struct {
-	Weight    float64   `json:"weight"`
+	Weight    float64    `json:"weight"`
+	Timestamp time.Time  `json:"timestamp"`
+	Status    EdgeStatus `json:"status"`
+	EventID   string     `json:"event_id,omitempty"` // Reference to news event that caused change
+}
+
+// NodeHistory tracks the temporal evolution of a node's attributes
+type NodeHistory struct {
+	NodeID  string         `json:"node_id"`
+	History []NodeSnapshot `json:"history"`
+}
+
+// NodeSnapshot records the attribute values changed at a point in time
+type NodeSnapshot struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Changes   map[string]interface{} `json:"changes"`
+	EventID   string                 `json:"event_id,omitempty"`
+}
+
+// maxHealthHistory bounds the number of health samples kept per node
+const maxHealthHistory = 1000
+
+// HealthHistory is a bounded time series of a node's health
+type HealthHistory struct {
+	NodeID  string           `json:"node_id"`
+	History []HealthSnapshot `json:"history"`
+}
+
+// HealthSnapshot records a node's health at a point in time
+type HealthSnapshot struct {
+	Health    float64   `json:"health"`
 	Timestamp time.Time `json:"timestamp"`
-	Status    string    `json:"status"`
-	EventID   string    `json:"event_id,omitempty"` // Reference to news event that caused change
 }
 
 // Graph represents the FDKG (Financial Dynamic Knowledge Graph).
 type Graph struct {
-	Nodes         map[string]*Node        `json:"nodes"`
-	Edges         []*Edge                 `json:"edges"`
-	EdgeHistories map[string]*EdgeHistory `json:"edge_histories"` // Key: "srcID|tgtID|type"
-	Adjacency     map[string][]*Edge      `json:"-"`              // Cache for O(1) lookup, ignored in JSON
-	mu            sync.RWMutex
+	Nodes              map[string]*Node             `json:"nodes"`
+	Edges              []*Edge                      `json:"edges"`
+	EdgeHistories      map[string]*EdgeHistory      `json:"edge_histories"`                // Key: "srcID|tgtID|type" (plus "|hs_code" for commodity edges)
+	NodeHistories      map[string]*NodeHistory      `json:"node_histories"`                // Key: node ID
+	HealthHistories    map[string]*HealthHistory    `json:"health_histories"`              // Key: node ID
+	SentimentHistories map[string]*SentimentHistory `json:"sentiment_histories,omitempty"` // Key: node ID (see stress.go)
+	MentionHistories   map[string]*MentionHistory   `json:"mention_histories,omitempty"`   // Key: node ID (see mentions.go)
+	PriceHistories     map[string]*PriceHistory     `json:"price_histories,omitempty"`     // Key: node ID (see prices.go)
+	CoMentions         map[string]*CoMention        `json:"co_mentions,omitempty"`         // Key: "lesserID|greaterID" (see comentions.go)
+	AppliedEvents      map[string]time.Time         `json:"applied_events,omitempty"`      // Key: event ID (see events.go)
+	Calendar           map[string][]ScheduledEvent  `json:"calendar,omitempty"`            // Key: node ID (see calendar.go)
+	Notes              map[string][]Note            `json:"notes,omitempty"`               // Key: node ID or edge key (see notes.go)
+	Adjacency          map[string][]*Edge           `json:"-"`                             // Cache for O(1) lookup, ignored in JSON
+	mu                 sync.RWMutex
 
 	// Auto-save configuration
 	autoSavePath         string
 	changesSinceLastSave int
-	autoSaveThreshold    int // Save after N changes
+	autoSaveThreshold    int  // Save after N changes
+	readOnly             bool // Save refused (see filelock.go)
+
+	// The auto-save file as this graph last read or wrote it, to notice
+	// other writers (see filelock.go)
+	diskMu     sync.Mutex
+	disk       fileStamp
+	diskWarned bool
+
+	// Every local change, logged as it happens (see wal.go)
+	wal *WAL
+
+	// Latest event impacts, not saved (see impact.go)
+	impactMu sync.Mutex
+	impacts  []*Impact
+
+	// Currency rates for converting prices, not saved (see fx.go)
+	fxMu    sync.RWMutex
+	fxRates map[string]fxRate
+
+	// Replication (see delta.go)
+	changeHook     func(Delta)
+	applyingRemote bool
+
+	healthModel *HealthModel      // nil = DefaultHealthModel (see health.go)
+	propagation *PropagationModel // nil = DefaultPropagationModel (see propagation.go)
+	confidence  *ConfidenceModel  // nil = DefaultConfidenceModel (see confidence.go)
+
+	// GetCompanyRelations cache (see relations.go)
+	relations   map[string]*CompanyRelations
+	relationsMu sync.Mutex
 }
 
 // NewGraph initializes a new empty graph.
@@ -117,6 +249,8 @@ func NewGraph() *Graph {
 		Nodes:             make(map[string]*Node),
 		Edges:             make([]*Edge, 0),
 		EdgeHistories:     make(map[string]*EdgeHistory),
+		NodeHistories:     make(map[string]*NodeHistory),
+		HealthHistories:   make(map[string]*HealthHistory),
 		Adjacency:         make(map[string][]*Edge),
 		autoSavePath:      "margraf_graph.json",
 		autoSaveThreshold: 10, // Save every 10 changes
@@ -127,16 +261,44 @@ func NewGraph() *Graph {
 func (g *Graph) EnableAutoSave(path string, threshold int) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	if g.readOnly {
+		logger.Warn(logger.StatusWarn, "Graph is read-only; auto-save stays off")
+		return
+	}
 	g.autoSavePath = path
 	g.autoSaveThreshold = threshold
+	g.diskMu.Lock()
+	if g.disk.path != path {
+		g.disk = stampFile(path)
+	}
+	g.diskMu.Unlock()
 	logger.Info(logger.StatusSave, "Auto-save enabled: %s (every %d changes)", path, threshold)
 }
 
+// DisableAutoSave turns off automatic persistence (e.g. on read-only replicas)
+func (g *Graph) DisableAutoSave() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.autoSavePath = ""
+}
+
 // triggerAutoSave saves the graph if threshold is reached (must be called with lock held)
 func (g *Graph) triggerAutoSave() {
-	g.changesSinceLastSave++
+	g.triggerAutoSaveN(1)
+}
+
+// triggerAutoSaveN counts n changes and saves at most once if the threshold is
+// reached (must be called with lock held)
+func (g *Graph) triggerAutoSaveN(n int) {
+	if g.autoSavePath == "" || n == 0 {
+		return
+	}
+	g.changesSinceLastSave += n
 
 	if g.changesSinceLastSave >= g.autoSaveThreshold {
+		if g.diskChangedWarn() {
+			return
+		}
 		// Release lock temporarily for save operation
 		g.mu.Unlock()
 
@@ -155,13 +317,33 @@ func (g *Graph) triggerAutoSave() {
 func (g *Graph) AddNode(n *Node) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	g.addNodeLocked(n)
+
+	// Trigger auto-save if enabled
+	g.triggerAutoSave()
+}
+
+// AddNodes adds several nodes under one lock acquisition, checking the
+// auto-save threshold once for the whole batch.
+func (g *Graph) AddNodes(nodes []*Node) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, n := range nodes {
+		g.addNodeLocked(n)
+	}
+	g.triggerAutoSaveN(len(nodes))
+}
+
+// addNodeLocked stores a node and reports it (must be called with lock held)
+func (g *Graph) addNodeLocked(n *Node) {
 	if n.Health == 0 {
 		n.Health = 1.0 // Default health
 	}
+	internNode(n)
 	g.Nodes[n.ID] = n
-
-	// Trigger auto-save if enabled
-	g.triggerAutoSave()
+	g.forgetRelationsLocked(n.ID)
+	g.recordHealth(n.ID, n.Health)
+	g.emit(Delta{Kind: DeltaNode, Node: n})
 }
 
 // Clear removes all nodes and edges from the graph safely.
@@ -172,38 +354,68 @@ func (g *Graph) Clear() {
 	g.Nodes = make(map[string]*Node)
 	g.Edges = make([]*Edge, 0)
 	g.EdgeHistories = make(map[string]*EdgeHistory)
+	g.NodeHistories = make(map[string]*NodeHistory)
+	g.HealthHistories = make(map[string]*HealthHistory)
+	g.SentimentHistories = make(map[string]*SentimentHistory)
+	g.MentionHistories = nil
+	g.PriceHistories = nil
+	g.CoMentions = nil
+	g.AppliedEvents = nil
+	g.Calendar = nil
+	g.Notes = nil
 	g.Adjacency = make(map[string][]*Edge)
+	g.resetRelationsLocked()
 	g.changesSinceLastSave = 0
 
 	logger.Info(logger.StatusInit, "Graph cleared")
 }
 
-// UpdateNodeHealth safely updates a node's health score.
+// UpdateNodeHealth safely applies a raw health delta, clamped to the
+// health model's bounds for the node's type.
 func (g *Graph) UpdateNodeHealth(id string, delta float64) (float64, bool) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
+	return g.ApplyHealthInput(id, InputDirect, delta)
+}
 
-	node, ok := g.Nodes[id]
-	if !ok {
-		return 0, false
+// recordHealth appends a health sample, dropping the oldest beyond
+// maxHealthHistory (must be called with lock held)
+func (g *Graph) recordHealth(id string, health float64) {
+	if g.HealthHistories == nil {
+		g.HealthHistories = make(map[string]*HealthHistory)
 	}
 
-	// Apply delta (e.g. -0.1 or +0.05)
-	node.Health += delta
+	history, exists := g.HealthHistories[id]
+	if !exists {
+		history = &HealthHistory{NodeID: id, History: make([]HealthSnapshot, 0)}
+		g.HealthHistories[id] = history
+	}
 
-	// Clamp health reasonable bounds (e.g., 0.1 to 2.0)
-	if node.Health < 0.1 {
-		node.Health = 0.1
+	history.History = append(history.History, HealthSnapshot{Health: health, Timestamp: clock.Now()})
+	if excess := len(history.History) - maxHealthHistory; excess > 0 {
+		history.History = append(history.History[:0:0], history.History[excess:]...)
 	}
-	if node.Health > 2.0 {
-		node.Health = 2.0
+}
+
+// GetHealthHistory returns a copy of a node's health samples, oldest first
+func (g *Graph) GetHealthHistory(id string) ([]HealthSnapshot, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if _, ok := g.Nodes[id]; !ok {
+		return nil, fmt.Errorf("node %s not found", id)
 	}
 
-	return node.Health, true
+	history, ok := g.HealthHistories[id]
+	if !ok {
+		return []HealthSnapshot{}, nil
+	}
+	return append([]HealthSnapshot(nil), history.History...), nil
 }
 
-// UpdateNodePrice safely updates a node's price and currency.
-func (g *Graph) UpdateNodePrice(id string, price float64, currency string, ticker string) error {
+// UpdateNodePrice safely updates a node's price and currency, adding the
+// price to its daily history stamped with at, the quote's market time (zero
+// for now). A quote for a session already recorded, as polls over a weekend
+// or holiday return the last close, is not recorded again.
+func (g *Graph) UpdateNodePrice(id string, price float64, currency string, ticker string, at time.Time) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -217,7 +429,14 @@ func (g *Graph) UpdateNodePrice(id string, price float64, currency string, ticke
 	if ticker != "" {
 		node.Ticker = ticker
 	}
-	node.LastUpdated = time.Now()
+	node.LastUpdated = clock.Now()
+	if at.IsZero() {
+		at = node.LastUpdated
+	}
+	if !g.priceRecorded(node, at) {
+		g.recordPrice(node, price, at)
+	}
+	g.emit(Delta{Kind: DeltaNode, Node: node})
 
 	return nil
 }
@@ -235,7 +454,9 @@ func (g *Graph) GetNodeTicker(id string) (string, bool) {
 	return node.Ticker, true
 }
 
-// SetNodeTicker safely sets a node's ticker.
+// SetNodeTicker safely sets a node's ticker. A bare numeric code gets the
+// suffix of the node's country (see QualifyTicker), and the listing's
+// exchange is recorded under AttrExchange.
 func (g *Graph) SetNodeTicker(id string, ticker string) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -245,7 +466,104 @@ func (g *Graph) SetNodeTicker(id string, ticker string) error {
 		return fmt.Errorf("node %s not found", id)
 	}
 
-	node.Ticker = ticker
+	node.Ticker = QualifyTicker(ticker, node.Country())
+	if ex, ok := ExchangeOf(node.Ticker); ok {
+		if node.Attributes == nil {
+			node.Attributes = make(map[string]interface{})
+		}
+		node.Attributes[AttrExchange] = ex.Code
+	}
+	g.emit(Delta{Kind: DeltaNode, Node: node})
+	return nil
+}
+
+// UpdateNodeAttributes merges attrs into a node's attributes and records the
+// values that actually changed in the node's history.
+func (g *Graph) UpdateNodeAttributes(id string, attrs map[string]interface{}, eventID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	node, ok := g.Nodes[id]
+	if !ok {
+		return fmt.Errorf("node %s not found", id)
+	}
+
+	if node.Attributes == nil {
+		node.Attributes = make(map[string]interface{})
+	}
+
+	changes := make(map[string]interface{})
+	for k, v := range attrs {
+		if old, exists := node.Attributes[k]; exists && reflect.DeepEqual(old, v) { // Values may be slices or maps, which == panics on
+			continue
+		}
+		node.Attributes[k] = v
+		changes[k] = v
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	node.LastUpdated = clock.Now()
+	g.recordNodeHistory(id, changes, eventID)
+	g.emit(Delta{Kind: DeltaNode, Node: node})
+	g.triggerAutoSave()
+
+	return nil
+}
+
+// recordNodeHistory stores a snapshot of changed node attributes (must be called with lock held)
+func (g *Graph) recordNodeHistory(id string, changes map[string]interface{}, eventID string) {
+	if g.NodeHistories == nil {
+		g.NodeHistories = make(map[string]*NodeHistory)
+	}
+
+	history, exists := g.NodeHistories[id]
+	if !exists {
+		history = &NodeHistory{NodeID: id, History: make([]NodeSnapshot, 0)}
+		g.NodeHistories[id] = history
+	}
+
+	history.History = append(history.History, NodeSnapshot{
+		Timestamp: clock.Now(),
+		Changes:   changes,
+		EventID:   intern(eventID),
+	})
+}
+
+// RefreshEdge overwrites an edge's weight and attributes with freshly sourced data
+// (e.g. a new year of trade statistics) and records the change in its history.
+// Unlike UpdateEdgeWeight, no decay or sentiment is applied.
+func (g *Graph) RefreshEdge(sourceID, targetID string, edgeType EdgeType, hsCode string, weight float64, attrs map[string]interface{}, eventID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var targetEdge *Edge
+	for _, e := range g.Adjacency[sourceID] {
+		if e.TargetID == targetID && e.Type == edgeType && e.Commodity() == hsCode {
+			targetEdge = e
+			break
+		}
+	}
+
+	if targetEdge == nil {
+		return fmt.Errorf("edge not found: %s -> %s (%s)", sourceID, targetID, edgeType)
+	}
+
+	if targetEdge.Attributes == nil {
+		targetEdge.Attributes = make(map[string]interface{})
+	}
+	for k, v := range attrs {
+		targetEdge.Attributes[k] = v
+	}
+
+	targetEdge.Weight = weight
+	targetEdge.Timestamp = clock.Now()
+	g.recordEdgeHistory(targetEdge, eventID)
+	g.emit(Delta{Kind: DeltaEdge, Edge: targetEdge})
+	g.triggerAutoSave()
+
 	return nil
 }
 
@@ -253,21 +571,41 @@ func (g *Graph) SetNodeTicker(id string, ticker string) error {
 func (g *Graph) AddEdge(e *Edge) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	g.addEdgeLocked(e)
+
+	// Trigger auto-save if enabled
+	g.triggerAutoSave()
+}
+
+// AddEdges adds several edges under one lock acquisition, checking the
+// auto-save threshold once for the whole batch.
+func (g *Graph) AddEdges(edges []*Edge) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, e := range edges {
+		g.addEdgeLocked(e)
+	}
+	g.triggerAutoSaveN(len(edges))
+}
 
+// addEdgeLocked fills edge defaults, indexes the edge and reports it
+// (must be called with lock held)
+func (g *Graph) addEdgeLocked(e *Edge) {
 	// Set timestamp if not already set
 	if e.Timestamp.IsZero() {
-		e.Timestamp = time.Now()
+		e.Timestamp = clock.Now()
 	}
 
 	// Set default status
 	if e.Status == "" {
-		e.Status = "Active"
+		e.Status = EdgeStatusActive
 	}
 
 	// Set directionality based on edge type
 	if e.Directionality == "" {
 		e.Directionality = GetEdgeDirectionality(e.Type)
 	}
+	internEdge(e)
 
 	g.Edges = append(g.Edges, e)
 
@@ -276,17 +614,16 @@ func (g *Graph) AddEdge(e *Edge) {
 		g.Adjacency = make(map[string][]*Edge)
 	}
 	g.Adjacency[e.SourceID] = append(g.Adjacency[e.SourceID], e)
+	g.forgetEdgeRelationsLocked(e)
 
 	// Record in temporal history
 	g.recordEdgeHistory(e, "")
-
-	// Trigger auto-save if enabled
-	g.triggerAutoSave()
+	g.emit(Delta{Kind: DeltaEdge, Edge: e})
 }
 
 // recordEdgeHistory stores a snapshot of the edge state (must be called with lock held)
 func (g *Graph) recordEdgeHistory(e *Edge, eventID string) {
-	key := fmt.Sprintf("%s|%s|%s", e.SourceID, e.TargetID, e.Type)
+	key := edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())
 
 	if g.EdgeHistories == nil {
 		g.EdgeHistories = make(map[string]*EdgeHistory)
@@ -295,10 +632,11 @@ func (g *Graph) recordEdgeHistory(e *Edge, eventID string) {
 	history, exists := g.EdgeHistories[key]
 	if !exists {
 		history = &EdgeHistory{
-			SourceID: e.SourceID,
-			TargetID: e.TargetID,
-			Type:     e.Type,
-			History:  make([]EdgeSnapshot, 0),
+			SourceID:  e.SourceID,
+			TargetID:  e.TargetID,
+			Type:      e.Type,
+			Commodity: e.Commodity(),
+			History:   make([]EdgeSnapshot, 0),
 		}
 		g.EdgeHistories[key] = history
 	}
@@ -307,10 +645,13 @@ func (g *Graph) recordEdgeHistory(e *Edge, eventID string) {
 		Weight:    e.Weight,
 		Timestamp: e.Timestamp,
 		Status:    e.Status,
-		EventID:   eventID,
+		EventID:   intern(eventID),
 	}
 
 	history.History = append(history.History, snapshot)
+	if history.events != nil && snapshot.EventID != "" {
+		history.events[snapshot.EventID] = struct{}{}
+	}
 }
 
 // UpdateEdgeWeight updates an edge's weight using the decay-based formula from Section 5.1.
@@ -319,21 +660,25 @@ func (g *Graph) recordEdgeHistory(e *Edge, eventID string) {
 //   - λ (lambda) is the temporal decay factor (forgetting mechanism)
 //   - S_k is the sentiment score of news event k (range: -1.0 to +1.0)
 //   - R_k is the relevance/credibility score of the source (range: 0.0 to 1.0)
+//
+// An update whose eventID the edge has already seen is skipped with
+// ErrEventApplied, so replaying an event leaves the edge unchanged.
 func (g *Graph) UpdateEdgeWeight(sourceID, targetID string, edgeType EdgeType, sentimentScore, relevanceScore float64, eventID string) error {
+	return g.UpdateCommodityEdgeWeight(sourceID, targetID, edgeType, "", sentimentScore, relevanceScore, eventID)
+}
+
+// UpdateCommodityEdgeWeight is UpdateEdgeWeight for a commodity-specific edge (matched by HS code).
+// An empty hsCode selects the aggregate edge.
+func (g *Graph) UpdateCommodityEdgeWeight(sourceID, targetID string, edgeType EdgeType, hsCode string, sentimentScore, relevanceScore float64, eventID string) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	// Find the edge
-	var targetEdge *Edge
-	for _, e := range g.Adjacency[sourceID] {
-		if e.TargetID == targetID && e.Type == edgeType {
-			targetEdge = e
-			break
-		}
+	targetEdge, err := g.findEdgeLocked(sourceID, targetID, edgeType, hsCode)
+	if err != nil {
+		return err
 	}
-
-	if targetEdge == nil {
-		return fmt.Errorf("edge not found: %s -> %s (%s)", sourceID, targetID, edgeType)
+	if eventID != "" && g.edgeSawEventLocked(targetEdge, eventID) {
+		return ErrEventApplied
 	}
 
 	// Calculate time since last update (for decay)
@@ -366,21 +711,14 @@ func (g *Graph) UpdateEdgeWeight(sourceID, targetID string, edgeType EdgeType, s
 
 	// Update edge
 	targetEdge.Weight = newWeight
-	targetEdge.Timestamp = time.Now()
+	targetEdge.Timestamp = clock.Now()
 
 	// Update status based on weight threshold
-	if newWeight < 0.1 {
-		targetEdge.Status = "Blocked"
-	} else if newWeight < 0.3 {
-		targetEdge.Status = "Weak"
-	} else if newWeight < 0.7 {
-		targetEdge.Status = "Active"
-	} else {
-		targetEdge.Status = "Strong"
-	}
+	g.refreshStatusLocked(targetEdge, targetEdge.Timestamp)
 
 	// Record in history
 	g.recordEdgeHistory(targetEdge, eventID)
+	g.emit(Delta{Kind: DeltaEdge, Edge: targetEdge})
 
 	return nil
 }
@@ -427,6 +765,13 @@ func (g *Graph) String() string {
 	return fmt.Sprintf("Graph(Nodes: %d, Edges: %d)", len(g.Nodes), len(g.Edges))
 }
 
+// Counts returns the number of nodes and edges
+func (g *Graph) Counts() (nodes, edges int) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.Nodes), len(g.Edges)
+}
+
 // NodesRange safely iterates over a copy of nodes to avoid long locks.
 func (g *Graph) NodesRange(f func(*Node)) {
 	g.mu.RLock()
@@ -459,13 +804,41 @@ func (g *Graph) EdgesRange(f func(*Edge)) {
 func (g *Graph) Save(filename string) error {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
+	if g.readOnly {
+		return ErrReadOnly
+	}
+	// Changes are logged under the write lock, so none lands while this marshals
+	var through uint64
+	if g.wal != nil {
+		through = g.wal.Seq()
+	}
 
 	data, err := json.MarshalIndent(g, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filename, data, 0644)
+	// Write beside the file and rename over it, so readers never see half a graph
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if filename == g.autoSavePath {
+		g.diskMu.Lock()
+		g.disk = stampFile(filename)
+		g.diskWarned = false
+		g.diskMu.Unlock()
+		if g.wal != nil {
+			if err := g.wal.Checkpoint(filename, through); err != nil {
+				logger.Warn(logger.StatusWarn, "Write-ahead log checkpoint failed: %v", err)
+			}
+		}
+	}
+	return nil
 }
 
 // expApprox computes e^x using Taylor series approximation
@@ -487,8 +860,20 @@ func expApprox(x float64) float64 {
 	return result
 }
 
-// Load reads a graph from a JSON file.
+// Load reads a graph from a JSON file. It only reads: invalid entries are
+// kept as stored and no relations are derived (call
+// DiscoverSupplyChainRelations for that). Use LoadStrict to reject invalid data.
 func Load(filename string) (*Graph, error) {
+	return load(filename, false)
+}
+
+// LoadStrict reads a graph like Load, but fails with ErrInvalidGraph if the
+// file holds invalid data (see Validate)
+func LoadStrict(filename string) (*Graph, error) {
+	return load(filename, true)
+}
+
+func load(filename string, strict bool) (*Graph, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
@@ -506,26 +891,34 @@ func Load(filename string) (*Graph, error) {
 	if g.EdgeHistories == nil {
 		g.EdgeHistories = make(map[string]*EdgeHistory)
 	}
-	g.Adjacency = make(map[string][]*Edge) // Rebuild cache
+	if g.NodeHistories == nil {
+		g.NodeHistories = make(map[string]*NodeHistory)
+	}
+	if g.HealthHistories == nil {
+		g.HealthHistories = make(map[string]*HealthHistory)
+	}
+
+	if strict {
+		if err := g.validateLocked(); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
 
-	// Populate Adjacency and migrate directionality
+	// Migrate directionality
 	if g.Edges == nil {
 		g.Edges = make([]*Edge, 0)
-	} else {
-		for _, e := range g.Edges {
-			// Migrate: Set directionality for edges that don't have it
-			if e.Directionality == "" {
-				e.Directionality = GetEdgeDirectionality(e.Type)
-			}
-			g.Adjacency[e.SourceID] = append(g.Adjacency[e.SourceID], e)
+	}
+	for _, e := range g.Edges {
+		// Migrate: Set directionality for edges that don't have it, and
+		// follow the declarations of declared types
+		if _, declared := declaredEdgeType(e.Type); declared || e.Directionality == "" {
+			e.Directionality = GetEdgeDirectionality(e.Type)
 		}
 	}
 
-	// Discover and add missing supply chain relationships
-	addedEdges := g.DiscoverSupplyChainRelations()
-	if addedEdges > 0 {
-		fmt.Printf("[DISCOVERY] Added %d supply chain edges from existing relationships\n", addedEdges)
-	}
+	// Share repeated strings, pack the edges and rebuild the Adjacency cache
+	g.compactLocked(true)
+	g.disk = stampFile(filename)
 
 	return &g, nil
 }
@@ -537,12 +930,52 @@ func (g *Graph) Replace(other *Graph) {
 	g.Nodes = other.Nodes
 	g.Edges = other.Edges
 	g.EdgeHistories = other.EdgeHistories
+	g.NodeHistories = other.NodeHistories
+	g.HealthHistories = other.HealthHistories
+	g.SentimentHistories = other.SentimentHistories
+	g.MentionHistories = other.MentionHistories
+	g.PriceHistories = other.PriceHistories
+	g.CoMentions = other.CoMentions
+	g.AppliedEvents = other.AppliedEvents
+	g.Calendar = other.Calendar
+	g.Notes = other.Notes
+	other.diskMu.Lock()
+	if stamp := other.disk; stamp.path != "" && stamp.path == g.autoSavePath {
+		// Reloaded from the auto-save file: what is on disk is ours again
+		g.diskMu.Lock()
+		g.disk, g.diskWarned = stamp, false
+		g.diskMu.Unlock()
+	}
+	other.diskMu.Unlock()
 
 	// Rebuild Adjacency
-	g.Adjacency = make(map[string][]*Edge)
-	for _, e := range other.Edges {
-		g.Adjacency[e.SourceID] = append(g.Adjacency[e.SourceID], e)
+	g.compactLocked(false)
+}
+
+// Clone returns a deep copy of the graph for what-if runs. The copy keeps the
+// health, propagation and confidence models but has no auto-save or change hook, so
+// nothing done to it is persisted or replicated.
+func (g *Graph) Clone() (*Graph, error) {
+	g.mu.RLock()
+	data, err := json.Marshal(g)
+	model := g.healthModel
+	propagation := g.propagation
+	confidence := g.confidence
+	g.mu.RUnlock()
+	if err != nil {
+		return nil, err
 	}
+
+	c := NewGraph()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	c.autoSavePath = ""
+	c.healthModel = model
+	c.propagation = propagation
+	c.confidence = confidence
+	c.compactLocked(true)
+	return c, nil
 }
 
 // ApplyTemporalDecay applies time-based decay to all edges in the graph
@@ -553,6 +986,7 @@ func (g *Graph) ApplyTemporalDecay(lambda float64) int {
 
 	updatedCount := 0
 	now := time.Now()
+	g.expireOverridesLocked(now)
 
 	for _, edge := range g.Edges {
 		// Calculate time since last update (in days)
@@ -581,18 +1015,11 @@ func (g *Graph) ApplyTemporalDecay(lambda float64) int {
 			edge.Timestamp = now
 
 			// Update status based on weight
-			if newWeight < 0.1 {
-				edge.Status = "Blocked"
-			} else if newWeight < 0.3 {
-				edge.Status = "Weak"
-			} else if newWeight < 0.7 {
-				edge.Status = "Active"
-			} else {
-				edge.Status = "Strong"
-			}
+			g.refreshStatusLocked(edge, now)
 
-			// Record in history
+			// Record in history and the change stream (WAL, replicas)
 			g.recordEdgeHistory(edge, "temporal_decay")
+			g.emit(Delta{Kind: DeltaEdge, Edge: edge})
 			updatedCount++
 		}
 	}
@@ -600,21 +1027,6 @@ func (g *Graph) ApplyTemporalDecay(lambda float64) int {
 	return updatedCount
 }
 
-// StartTemporalDecayWorker starts a background goroutine that periodically applies decay
-func (g *Graph) StartTemporalDecayWorker(interval time.Duration, lambda float64) {
-	go func() {
-		ticker := time.NewTicker(interval)
-		for range ticker.C {
-			count := g.ApplyTemporalDecay(lambda)
-			if count > 0 {
-				// Use a simple print to avoid circular imports with logger
-				// In production, you might want to use a callback or channel
-				fmt.Printf("[DECAY] Updated %d edges with temporal decay\n", count)
-			}
-		}
-	}()
-}
-
 // CompanyRelations holds all relationships for a company
 type CompanyRelations struct {
 	CompanyID    string  `json:"company_id"`
@@ -623,6 +1035,8 @@ type CompanyRelations struct {
 	Clients      []*Node `json:"clients"`
 	RawMaterials []*Node `json:"raw_materials"`
 	Products     []*Node `json:"products"`
+	Parents      []*Node `json:"parents"`
+	Subsidiaries []*Node `json:"subsidiaries"`
 }
 
 // GetSuppliers returns all companies that supply to the given company
@@ -689,6 +1103,62 @@ func (g *Graph) GetClients(companyID string) []*Node {
 	return clients
 }
 
+// GetParents returns all companies that own the given company
+func (g *Graph) GetParents(companyID string) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	parents := make([]*Node, 0)
+	seenIDs := make(map[string]bool)
+
+	for _, edge := range g.Edges {
+		parentID := ""
+		if edge.TargetID == companyID && edge.Type == EdgeTypeOwns {
+			parentID = edge.SourceID
+		}
+		if edge.SourceID == companyID && edge.Type == EdgeTypeSubsidiaryOf {
+			parentID = edge.TargetID
+		}
+		if parentID == "" || seenIDs[parentID] {
+			continue
+		}
+		if parent, ok := g.Nodes[parentID]; ok && parent.Type == NodeTypeCorporation {
+			parents = append(parents, parent)
+			seenIDs[parentID] = true
+		}
+	}
+
+	return parents
+}
+
+// GetSubsidiaries returns all companies owned by the given company
+func (g *Graph) GetSubsidiaries(companyID string) []*Node {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	subsidiaries := make([]*Node, 0)
+	seenIDs := make(map[string]bool)
+
+	for _, edge := range g.Edges {
+		subID := ""
+		if edge.SourceID == companyID && edge.Type == EdgeTypeOwns {
+			subID = edge.TargetID
+		}
+		if edge.TargetID == companyID && edge.Type == EdgeTypeSubsidiaryOf {
+			subID = edge.SourceID
+		}
+		if subID == "" || seenIDs[subID] {
+			continue
+		}
+		if sub, ok := g.Nodes[subID]; ok && sub.Type == NodeTypeCorporation {
+			subsidiaries = append(subsidiaries, sub)
+			seenIDs[subID] = true
+		}
+	}
+
+	return subsidiaries
+}
+
 // GetRawMaterials returns all raw materials that the given company uses
 func (g *Graph) GetRawMaterials(companyID string) []*Node {
 	g.mu.RLock()
@@ -735,30 +1205,6 @@ func (g *Graph) GetProducts(companyID string) []*Node {
 	return products
 }
 
-// GetCompanyRelations returns all relationships for a given company
-func (g *Graph) GetCompanyRelations(companyID string) (*CompanyRelations, error) {
-	g.mu.RLock()
-	company, ok := g.Nodes[companyID]
-	g.mu.RUnlock()
-
-	if !ok {
-		return nil, fmt.Errorf("company %s not found", companyID)
-	}
-
-	if company.Type != NodeTypeCorporation {
-		return nil, fmt.Errorf("node %s is not a corporation", companyID)
-	}
-
-	return &CompanyRelations{
-		CompanyID:    companyID,
-		CompanyName:  company.Name,
-		Suppliers:    g.GetSuppliers(companyID),
-		Clients:      g.GetClients(companyID),
-		RawMaterials: g.GetRawMaterials(companyID),
-		Products:     g.GetProducts(companyID),
-	}, nil
-}
-
 // DiscoverSupplyChainRelations analyzes the graph and adds missing supplier/client edges
 // based on existing relationships and patterns
 func (g *Graph) DiscoverSupplyChainRelations() int {
@@ -801,13 +1247,16 @@ func (g *Graph) DiscoverSupplyChainRelations() int {
 						Weight:         edge.Weight,
 						Status:         edge.Status,
 						Directionality: DirectionalityUnidirectional,
+						Attributes:     provenance(edge),
 					}
 					g.Edges = append(g.Edges, newEdge)
 					g.Adjacency[newEdge.SourceID] = append(g.Adjacency[newEdge.SourceID], newEdge)
+					g.forgetEdgeRelationsLocked(newEdge)
 
 					key := fmt.Sprintf("%s|%s|%s", newEdge.SourceID, newEdge.TargetID, newEdge.Type)
 					existingEdges[key] = true
 					addedEdges++
+					auditInferred(newEdge, edge)
 				}
 
 				// Add corresponding ProcuresFrom edge
@@ -819,13 +1268,16 @@ func (g *Graph) DiscoverSupplyChainRelations() int {
 						Weight:         edge.Weight,
 						Status:         edge.Status,
 						Directionality: DirectionalityReverse,
+						Attributes:     provenance(edge),
 					}
 					g.Edges = append(g.Edges, newEdge)
 					g.Adjacency[newEdge.SourceID] = append(g.Adjacency[newEdge.SourceID], newEdge)
+					g.forgetEdgeRelationsLocked(newEdge)
 
 					key := fmt.Sprintf("%s|%s|%s", newEdge.SourceID, newEdge.TargetID, newEdge.Type)
 					existingEdges[key] = true
 					addedEdges++
+					auditInferred(newEdge, edge)
 				}
 			}
 		}
@@ -880,6 +1332,19 @@ func (g *Graph) DiscoverSupplyChainRelations() int {
 	return addedEdges
 }
 
+// auditInferred records an edge DiscoverSupplyChainRelations derived from another
+func auditInferred(e, from *Edge) {
+	audit.Log(audit.Record{
+		Actor:    audit.ActorInference,
+		Action:   audit.ActionAddEdge,
+		SourceID: e.SourceID,
+		TargetID: e.TargetID,
+		EdgeType: string(e.Type),
+		Reason:   fmt.Sprintf("%s inferred from %s", e.Type, from.Type),
+		Evidence: fmt.Sprintf("%s -[%s]-> %s (weight %.2f)", from.SourceID, from.Type, from.TargetID, from.Weight),
+	})
+}
+
 // GetAllCompanies returns a list of all corporations in the graph
 func (g *Graph) GetAllCompanies() []*Node {
 	g.mu.RLock()
@@ -0,0 +1,213 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxKeyCompanies bounds NationRelations.KeyCompanies
+const maxKeyCompanies = 20
+
+// NationRelations holds all relationships for a nation
+type NationRelations struct {
+	NationID           string             `json:"nation_id"`
+	NationName         string             `json:"nation_name"`
+	Industries         []*Node            `json:"industries"`
+	KeyCompanies       []*Node            `json:"key_companies"`       // Largest market cap first
+	TradePartners      []TradePartner     `json:"trade_partners"`      // Largest total trade first
+	Commodities        []*Node            `json:"commodities"`         // What the nation Produces
+	ImportDependencies []ImportDependency `json:"import_dependencies"` // Largest import first
+}
+
+// TradePartner is a nation's trade with one partner, in USD as reported by
+// the Trade edges' trade_value
+type TradePartner struct {
+	Partner *Node   `json:"partner"`
+	Exports float64 `json:"exports"` // To the partner
+	Imports float64 `json:"imports"` // From the partner
+	Weight  float64 `json:"weight"`  // Strongest Trade edge either way
+}
+
+// Total returns exports plus imports
+func (p TradePartner) Total() float64 {
+	return p.Exports + p.Imports
+}
+
+// ImportDependency is a commodity a nation imports and how much of it comes
+// from its largest supplier
+type ImportDependency struct {
+	HSCode      string  `json:"hs_code"`
+	Commodity   string  `json:"commodity,omitempty"`
+	Value       float64 `json:"value"` // USD imported
+	TopSupplier *Node   `json:"top_supplier,omitempty"`
+	TopShare    float64 `json:"top_share"` // Share of Value from TopSupplier
+}
+
+// tradeValue returns a Trade edge's trade_value attribute, or 0
+func tradeValue(e *Edge) float64 {
+	v, _ := e.Attributes["trade_value"].(float64)
+	return v
+}
+
+// GetNationRelations returns the industries, key companies, trade partners,
+// produced commodities and import dependencies of a nation
+func (g *Graph) GetNationRelations(nationID string) (*NationRelations, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	nation, ok := g.Nodes[nationID]
+	if !ok {
+		return nil, fmt.Errorf("nation %s not found", nationID)
+	}
+	if nation.Type != NodeTypeNation {
+		return nil, fmt.Errorf("node %s is not a nation", nationID)
+	}
+
+	rel := &NationRelations{
+		NationID:           nationID,
+		NationName:         nation.Name,
+		Industries:         make([]*Node, 0),
+		KeyCompanies:       make([]*Node, 0),
+		TradePartners:      make([]TradePartner, 0),
+		Commodities:        make([]*Node, 0),
+		ImportDependencies: make([]ImportDependency, 0),
+	}
+
+	// Aggregate Trade edges carry the bilateral total; commodity edges only
+	// count towards a partner when it has no aggregate edge
+	type flows struct {
+		aggregate, commodities float64
+		hasAggregate           bool
+	}
+	exports := make(map[string]*flows)
+	imports := make(map[string]*flows)
+	weights := make(map[string]float64)
+	add := func(m map[string]*flows, partner string, e *Edge) {
+		f := m[partner]
+		if f == nil {
+			f = &flows{}
+			m[partner] = f
+		}
+		if e.Commodity() == "" {
+			f.aggregate += tradeValue(e)
+			f.hasAggregate = true
+		} else {
+			f.commodities += tradeValue(e)
+		}
+		if e.Weight > weights[partner] {
+			weights[partner] = e.Weight
+		}
+	}
+	// Imports by HS code, then by supplier
+	imported := make(map[string]map[string]float64)
+	commodityNames := make(map[string]string)
+
+	companies := make(map[string]*Node)
+	for _, e := range g.Edges {
+		switch {
+		case e.SourceID == nationID && e.Type == EdgeTypeHasIndustry:
+			if n, ok := g.Nodes[e.TargetID]; ok && n.Type == NodeTypeIndustry {
+				rel.Industries = append(rel.Industries, n)
+				for _, ce := range g.Adjacency[n.ID] {
+					if c, ok := g.Nodes[ce.TargetID]; ok && ce.Type == EdgeTypeHasCompany && c.Type == NodeTypeCorporation {
+						companies[c.ID] = c
+					}
+				}
+			}
+		case e.SourceID == nationID && e.Type == EdgeTypeProduces:
+			if n, ok := g.Nodes[e.TargetID]; ok {
+				rel.Commodities = append(rel.Commodities, n)
+			}
+		case e.Type == EdgeTypeTrade && e.SourceID == nationID && e.TargetID != nationID:
+			add(exports, e.TargetID, e)
+		case e.Type == EdgeTypeTrade && e.TargetID == nationID && e.SourceID != nationID:
+			add(imports, e.SourceID, e)
+			if code := e.Commodity(); code != "" {
+				if imported[code] == nil {
+					imported[code] = make(map[string]float64)
+				}
+				imported[code][e.SourceID] += tradeValue(e)
+				if name, _ := e.Attributes["commodity"].(string); name != "" {
+					commodityNames[code] = name
+				}
+			}
+		}
+	}
+
+	// Companies headquartered in the nation but not listed under its industries
+	for _, n := range g.Nodes {
+		if n.Type == NodeTypeCorporation && strings.EqualFold(n.Country(), nation.Name) {
+			companies[n.ID] = n
+		}
+	}
+	for _, c := range companies {
+		rel.KeyCompanies = append(rel.KeyCompanies, c)
+	}
+	sort.Slice(rel.KeyCompanies, func(i, j int) bool {
+		a, b := rel.KeyCompanies[i], rel.KeyCompanies[j]
+		if a.MarketCap() != b.MarketCap() {
+			return a.MarketCap() > b.MarketCap()
+		}
+		return a.Name < b.Name
+	})
+	if len(rel.KeyCompanies) > maxKeyCompanies {
+		rel.KeyCompanies = rel.KeyCompanies[:maxKeyCompanies]
+	}
+
+	value := func(f *flows) float64 {
+		if f == nil {
+			return 0
+		}
+		if f.hasAggregate {
+			return f.aggregate
+		}
+		return f.commodities
+	}
+	for partnerID, weight := range weights {
+		partner, ok := g.Nodes[partnerID]
+		if !ok {
+			continue
+		}
+		rel.TradePartners = append(rel.TradePartners, TradePartner{
+			Partner: partner,
+			Exports: value(exports[partnerID]),
+			Imports: value(imports[partnerID]),
+			Weight:  weight,
+		})
+	}
+	sort.Slice(rel.TradePartners, func(i, j int) bool {
+		a, b := rel.TradePartners[i], rel.TradePartners[j]
+		if a.Total() != b.Total() {
+			return a.Total() > b.Total()
+		}
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		return a.Partner.ID < b.Partner.ID
+	})
+
+	for code, suppliers := range imported {
+		dep := ImportDependency{HSCode: code, Commodity: commodityNames[code]}
+		top := 0.0
+		for supplierID, v := range suppliers {
+			dep.Value += v
+			if n, ok := g.Nodes[supplierID]; ok && (dep.TopSupplier == nil || v > top || (v == top && supplierID < dep.TopSupplier.ID)) {
+				dep.TopSupplier, top = n, v
+			}
+		}
+		if dep.Value > 0 {
+			dep.TopShare = top / dep.Value
+		}
+		rel.ImportDependencies = append(rel.ImportDependencies, dep)
+	}
+	sort.Slice(rel.ImportDependencies, func(i, j int) bool {
+		a, b := rel.ImportDependencies[i], rel.ImportDependencies[j]
+		if a.Value != b.Value {
+			return a.Value > b.Value
+		}
+		return a.HSCode < b.HSCode
+	})
+
+	return rel, nil
+}
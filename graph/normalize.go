@@ -0,0 +1,201 @@
+package graph
+
+import (
+	"fmt"
+	"margraf/clock"
+	"math"
+	"sort"
+	"time"
+)
+
+// NormalizeMethod is how NormalizeWeights rescales an edge type's weights
+type NormalizeMethod string
+
+const (
+	// NormalizeRank maps each weight to its percentile within its edge type,
+	// so log-scaled trade values, LLM defaults and decayed remnants end up on
+	// the same footing. Ties share a percentile.
+	NormalizeRank NormalizeMethod = "rank"
+	// NormalizeMinMax stretches each edge type's weights linearly so its
+	// weakest edge gets the floor and its strongest 1.0
+	NormalizeMinMax NormalizeMethod = "minmax"
+)
+
+// weightBuckets is the number of histogram bins over [0, 1]
+const weightBuckets = 10
+
+// WeightPolicy configures NormalizeWeights
+type WeightPolicy struct {
+	Method NormalizeMethod
+	Floor  float64    // Lowest normalized weight, in [0, 1); raised to 0.1 at least
+	Types  []EdgeType // Edge types to normalize (empty = all)
+}
+
+// WeightStats describes the weight distribution of one edge type
+type WeightStats struct {
+	Type      EdgeType           `json:"type"`
+	Count     int                `json:"count"`
+	Min       float64            `json:"min"`
+	Max       float64            `json:"max"`
+	Mean      float64            `json:"mean"`
+	Median    float64            `json:"median"`
+	StdDev    float64            `json:"std_dev"`
+	Histogram [weightBuckets]int `json:"histogram"` // Counts in [0, 0.1), [0.1, 0.2), ... [0.9, 1.0]; above 1 counts as 1
+}
+
+// NormalizeReport is the weight distribution per edge type before and after
+// a normalization pass
+type NormalizeReport struct {
+	Method    NormalizeMethod `json:"method"`
+	Before    []WeightStats   `json:"before"`
+	After     []WeightStats   `json:"after"`
+	Changed   int             `json:"changed"` // Edges whose weight moved
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// WeightStats returns the weight distribution of every edge type, sorted by type
+func (g *Graph) WeightStats() []WeightStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return weightStatsLocked(g.Edges)
+}
+
+func weightStatsLocked(edges []*Edge) []WeightStats {
+	byType := make(map[EdgeType][]float64)
+	for _, e := range edges {
+		byType[e.Type] = append(byType[e.Type], e.Weight)
+	}
+
+	stats := make([]WeightStats, 0, len(byType))
+	for t, weights := range byType {
+		stats = append(stats, describeWeights(t, weights))
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Type < stats[j].Type })
+	return stats
+}
+
+func describeWeights(t EdgeType, weights []float64) WeightStats {
+	sorted := append([]float64(nil), weights...)
+	sort.Float64s(sorted)
+
+	s := WeightStats{Type: t, Count: len(sorted), Min: sorted[0], Max: sorted[len(sorted)-1]}
+	for _, w := range sorted {
+		s.Mean += w
+		bucket := int(w * weightBuckets)
+		s.Histogram[min(max(bucket, 0), weightBuckets-1)]++
+	}
+	s.Mean /= float64(len(sorted))
+	for _, w := range sorted {
+		s.StdDev += (w - s.Mean) * (w - s.Mean)
+	}
+	s.StdDev = math.Sqrt(s.StdDev / float64(len(sorted)))
+
+	mid := len(sorted) / 2
+	s.Median = sorted[mid]
+	if len(sorted)%2 == 0 {
+		s.Median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return s
+}
+
+// NormalizeWeights rescales edge weights within each edge type so types fed
+// by different sources stay comparable. Blocked edges keep their weight, so a
+// shock's damage is not undone. Edge timestamps are left alone; the pass is
+// not a new observation and does not reset decay. Each change is recorded in
+// the edge's history as "normalize".
+func (g *Graph) NormalizeWeights(policy WeightPolicy) (*NormalizeReport, error) {
+	switch policy.Method {
+	case NormalizeRank, NormalizeMinMax:
+	case "":
+		policy.Method = NormalizeRank
+	default:
+		return nil, fmt.Errorf("unknown normalization method %q (rank or minmax)", policy.Method)
+	}
+	if policy.Floor < 0 || policy.Floor >= 1 {
+		return nil, fmt.Errorf("normalization floor %.2f must be in [0, 1)", policy.Floor)
+	}
+	// Below it the weakest edges would turn Blocked, which later passes skip,
+	// so they would stay demoted for good
+	policy.Floor = max(policy.Floor, blockedWeight)
+	only := make(map[EdgeType]bool, len(policy.Types))
+	for _, t := range policy.Types {
+		only[t] = true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	report := &NormalizeReport{Method: policy.Method, Before: weightStatsLocked(g.Edges), Timestamp: clock.Now()}
+
+	byType := make(map[EdgeType][]*Edge)
+	for _, e := range g.Edges {
+		if e.Status == EdgeStatusBlocked || (len(only) > 0 && !only[e.Type]) {
+			continue
+		}
+		byType[e.Type] = append(byType[e.Type], e)
+	}
+
+	for _, edges := range byType {
+		scaled := rescale(edges, policy)
+		if scaled == nil {
+			continue // Every weight is the same; there is nothing to compare
+		}
+		for i, e := range edges {
+			if math.Abs(scaled[i]-e.Weight) < 1e-9 {
+				continue
+			}
+			e.Weight = scaled[i]
+			g.refreshStatusLocked(e, report.Timestamp)
+			g.recordEdgeHistory(e, "normalize")
+			g.emit(Delta{Kind: DeltaEdge, Edge: e})
+			report.Changed++
+		}
+	}
+
+	report.After = weightStatsLocked(g.Edges)
+	if report.Changed > 0 {
+		g.triggerAutoSave()
+	}
+	return report, nil
+}
+
+// rescale returns the normalized weights of edges (same order), or nil when
+// they are all equal
+func rescale(edges []*Edge, policy WeightPolicy) []float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, e := range edges {
+		lo, hi = math.Min(lo, e.Weight), math.Max(hi, e.Weight)
+	}
+	if hi-lo < 1e-9 {
+		return nil
+	}
+	span := 1 - policy.Floor
+	scaled := make([]float64, len(edges))
+
+	if policy.Method == NormalizeMinMax {
+		for i, e := range edges {
+			scaled[i] = policy.Floor + span*(e.Weight-lo)/(hi-lo)
+		}
+		return scaled
+	}
+
+	// Rank: percentile of each weight, ties sharing their mean rank
+	order := make([]int, len(edges))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return edges[order[a]].Weight < edges[order[b]].Weight })
+	last := float64(len(edges) - 1)
+	for start := 0; start < len(order); {
+		end := start
+		for end+1 < len(order) && edges[order[end+1]].Weight == edges[order[start]].Weight {
+			end++
+		}
+		rank := float64(start+end) / 2
+		for _, i := range order[start : end+1] {
+			scaled[i] = policy.Floor + span*rank/last
+		}
+		start = end + 1
+	}
+	return scaled
+}
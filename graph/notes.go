@@ -0,0 +1,235 @@
+package graph
+
+import (
+	"fmt"
+	"margraf/clock"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Analyst notes are free-text context people attach to nodes and edges: why
+// a supplier link matters, what an earnings call revealed. They are saved,
+// logged and replicated with the graph like any other change, and included in
+// JSON and GraphML exports and in digests. A note on an edge outlives the
+// edge being pruned.
+
+// MaxNoteLength bounds a note's text in bytes
+const MaxNoteLength = 4000
+
+// Note is an analyst's note on a node (NodeID set) or an edge (SourceID,
+// TargetID and EdgeType set)
+type Note struct {
+	ID        string    `json:"id"`
+	NodeID    string    `json:"node_id,omitempty"`
+	SourceID  string    `json:"source_id,omitempty"`
+	TargetID  string    `json:"target_id,omitempty"`
+	EdgeType  EdgeType  `json:"edge_type,omitempty"`
+	Commodity string    `json:"commodity,omitempty"` // HS code of a commodity-specific edge
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	Tags      []string  `json:"tags,omitempty"` // Lower case, without "#"
+	Time      time.Time `json:"time"`
+}
+
+// OnEdge reports whether the note is on an edge rather than a node
+func (n Note) OnEdge() bool {
+	return n.NodeID == ""
+}
+
+// Subject names what the note is on: a node ID or "src -[Type]-> tgt"
+func (n Note) Subject() string {
+	if !n.OnEdge() {
+		return n.NodeID
+	}
+	if n.Commodity != "" {
+		return fmt.Sprintf("%s -[%s/%s]-> %s", n.SourceID, n.EdgeType, n.Commodity, n.TargetID)
+	}
+	return fmt.Sprintf("%s -[%s]-> %s", n.SourceID, n.EdgeType, n.TargetID)
+}
+
+// HasTag reports whether the note carries tag (with or without "#")
+func (n Note) HasTag(tag string) bool {
+	tag = normalizeTag(tag)
+	for _, t := range n.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// key is the Notes key: the node ID, or the edge's EdgeHistories key
+func (n Note) key() string {
+	if !n.OnEdge() {
+		return n.NodeID
+	}
+	return edgeKey(n.SourceID, n.TargetID, n.EdgeType, n.Commodity)
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// ParseNoteText splits "#tags" out of a note's text, so "fab delayed #capex
+// #risk" is the text "fab delayed" tagged capex and risk
+func ParseNoteText(s string) (text string, tags []string) {
+	var words []string
+	for _, w := range strings.Fields(s) {
+		if len(w) > 1 && strings.HasPrefix(w, "#") {
+			tags = append(tags, w)
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " "), tags
+}
+
+// AddNote attaches a note to the node or edge it names, filling in its ID and
+// time, and returns it. The node or edge must exist.
+func (g *Graph) AddNote(n Note) (Note, error) {
+	n.Text = strings.TrimSpace(n.Text)
+	n.Author = strings.TrimSpace(n.Author)
+	switch {
+	case n.Text == "":
+		return Note{}, fmt.Errorf("note text is empty")
+	case len(n.Text) > MaxNoteLength:
+		return Note{}, fmt.Errorf("note text is over %d bytes", MaxNoteLength)
+	case n.Author == "":
+		return Note{}, fmt.Errorf("note author is empty")
+	case n.NodeID == "" && n.SourceID == "":
+		return Note{}, fmt.Errorf("a note needs a node or an edge")
+	case n.NodeID != "" && n.SourceID != "":
+		return Note{}, fmt.Errorf("a note is on a node or an edge, not both")
+	}
+	seen := make(map[string]bool, len(n.Tags))
+	tags := make([]string, 0, len(n.Tags))
+	for _, t := range n.Tags {
+		if t = normalizeTag(t); t != "" && !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	n.Tags = tags
+	if len(n.Tags) == 0 {
+		n.Tags = nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n.OnEdge() {
+		if _, err := g.findEdgeLocked(n.SourceID, n.TargetID, n.EdgeType, n.Commodity); err != nil {
+			return Note{}, err
+		}
+	} else if _, ok := g.Nodes[n.NodeID]; !ok {
+		return Note{}, fmt.Errorf("node %s not found", n.NodeID)
+	}
+
+	n.Time = clock.Now()
+	n.ID = fmt.Sprintf("n%x", n.Time.UnixNano())
+	for {
+		if _, _, taken := g.findNoteLocked(n.ID); !taken {
+			break
+		}
+		n.Time = n.Time.Add(time.Nanosecond)
+		n.ID = fmt.Sprintf("n%x", n.Time.UnixNano())
+	}
+	g.addNoteLocked(n)
+	g.emit(Delta{Kind: DeltaNote, Note: &n})
+	g.triggerAutoSave()
+	return n, nil
+}
+
+// addNoteLocked stores a note (must be called with lock held)
+func (g *Graph) addNoteLocked(n Note) {
+	if g.Notes == nil {
+		g.Notes = make(map[string][]Note)
+	}
+	key := n.key()
+	g.Notes[key] = append(g.Notes[key], n)
+}
+
+// findNoteLocked returns the Notes key and index of note id (must be called
+// with lock held)
+func (g *Graph) findNoteLocked(id string) (string, int, bool) {
+	for key, list := range g.Notes {
+		for i, n := range list {
+			if n.ID == id {
+				return key, i, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// DeleteNote removes a note by ID and returns it
+func (g *Graph) DeleteNote(id string) (Note, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n, ok := g.deleteNoteLocked(id)
+	if !ok {
+		return Note{}, fmt.Errorf("note %s not found", id)
+	}
+	g.emit(Delta{Kind: DeltaNoteRemoved, Note: &n})
+	g.triggerAutoSave()
+	return n, nil
+}
+
+// deleteNoteLocked removes a note (must be called with lock held)
+func (g *Graph) deleteNoteLocked(id string) (Note, bool) {
+	key, i, ok := g.findNoteLocked(id)
+	if !ok {
+		return Note{}, false
+	}
+	list := g.Notes[key]
+	n := list[i]
+	list = append(list[:i:i], list[i+1:]...)
+	if len(list) == 0 {
+		delete(g.Notes, key)
+	} else {
+		g.Notes[key] = list
+	}
+	return n, true
+}
+
+// NodeNotes returns the notes on a node and on the edges to and from it,
+// oldest first
+func (g *Graph) NodeNotes(nodeID string) []Note {
+	return g.FindNotes(func(n Note) bool {
+		return n.NodeID == nodeID || n.SourceID == nodeID || n.TargetID == nodeID
+	})
+}
+
+// EdgeNotes returns the notes on an edge, oldest first
+func (g *Graph) EdgeNotes(e *Edge) []Note {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]Note(nil), g.Notes[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())]...)
+}
+
+// FindNotes returns the notes match accepts (nil = all), oldest first
+func (g *Graph) FindNotes(match func(Note) bool) []Note {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var notes []Note
+	for _, list := range g.Notes {
+		for _, n := range list {
+			if match == nil || match(n) {
+				notes = append(notes, n)
+			}
+		}
+	}
+	SortNotes(notes)
+	return notes
+}
+
+// SortNotes orders notes oldest first
+func SortNotes(notes []Note) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if !notes[i].Time.Equal(notes[j].Time) {
+			return notes[i].Time.Before(notes[j].Time)
+		}
+		return notes[i].ID < notes[j].ID
+	})
+}
@@ -0,0 +1,328 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Holding is a position in a portfolio
+type Holding struct {
+	Ticker   string  `json:"ticker"`
+	Quantity float64 `json:"quantity"`
+}
+
+// Portfolio is the set of holdings exposure is computed for
+type Portfolio struct {
+	Holdings []Holding `json:"holdings"`
+}
+
+// LoadPortfolio reads a portfolio written by Save. A missing file is an
+// empty portfolio.
+func LoadPortfolio(path string) (Portfolio, error) {
+	var p Portfolio
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the portfolio to path
+func (p Portfolio) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Set adds a holding or replaces the quantity of an existing one
+func (p *Portfolio) Set(ticker string, quantity float64) {
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	for i := range p.Holdings {
+		if p.Holdings[i].Ticker == ticker {
+			p.Holdings[i].Quantity = quantity
+			return
+		}
+	}
+	p.Holdings = append(p.Holdings, Holding{Ticker: ticker, Quantity: quantity})
+}
+
+// Remove drops a holding, reporting whether it existed
+func (p *Portfolio) Remove(ticker string) bool {
+	for i, h := range p.Holdings {
+		if strings.EqualFold(h.Ticker, ticker) {
+			p.Holdings = append(p.Holdings[:i], p.Holdings[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Exposure kinds
+const (
+	ExposureNation      = "nation"
+	ExposureSupplier    = "supplier"
+	ExposureRawMaterial = "raw_material"
+)
+
+// PositionRisk is one holding as mapped onto the graph
+type PositionRisk struct {
+	Ticker   string  `json:"ticker"`
+	NodeID   string  `json:"node_id,omitempty"` // Empty when no company has the ticker
+	Name     string  `json:"name,omitempty"`
+	Quantity float64 `json:"quantity"`
+	Value    float64 `json:"value"`  // Quantity * price in US dollars, or the average position when the price or its currency's rate is unknown
+	Weight   float64 `json:"weight"` // Share of the mapped portfolio's value
+	Health   float64 `json:"health,omitempty"`
+}
+
+// Exposure is how much of a portfolio depends on one nation, supplier or raw
+// material, directly or through the supply chain
+type Exposure struct {
+	NodeID   string   `json:"node_id,omitempty"` // Empty for countries without a Nation node
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"`
+	Share    float64  `json:"share"`    // Portfolio weight depending on it, each holding scaled by its strongest supply path
+	Direct   float64  `json:"direct"`   // Portfolio weight held in it, or headquartered in it for nations
+	Holdings []string `json:"holdings"` // Tickers that depend on it
+	Hops     int      `json:"hops"`     // Shortest supply chain distance from a holding
+	Health   float64  `json:"health,omitempty"`
+	// Concentrated is set when Share reaches the threshold and more of the
+	// portfolio depends on it than is held in it directly: a hidden exposure
+	Concentrated bool `json:"concentrated"`
+}
+
+// PortfolioRisk is a portfolio's exposure to the graph
+type PortfolioRisk struct {
+	Positions []PositionRisk `json:"positions"`
+	Exposures []Exposure     `json:"exposures"` // Largest share first
+	Unmapped  []string       `json:"unmapped,omitempty"`
+	Health    float64        `json:"health"` // Holdings' health weighted by value
+}
+
+// Concentrated returns the hidden exposures at or above the threshold
+func (r *PortfolioRisk) Concentrated() []Exposure {
+	var out []Exposure
+	for _, e := range r.Exposures {
+		if e.Concentrated {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// PortfolioRisk maps holdings to companies by ticker and follows each one's
+// suppliers and raw materials up to depth hops upstream. A holding depends on
+// what it reaches as strongly as the product of the edge weights along its
+// strongest path, so a sole supplier counts fully and a minor one two hops
+// away barely. Nations count through the companies located in them.
+// Exposures at or above threshold that exceed the direct holding are flagged
+// as concentrated.
+func (g *Graph) PortfolioRisk(p Portfolio, depth int, threshold float64) *PortfolioRisk {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	byTicker := make(map[string]*Node)
+	for _, n := range g.Nodes {
+		if n.Ticker != "" && (n.Type == NodeTypeCorporation || byTicker[strings.ToUpper(n.Ticker)] == nil) {
+			byTicker[strings.ToUpper(n.Ticker)] = n
+		}
+	}
+
+	risk := &PortfolioRisk{Positions: make([]PositionRisk, 0), Exposures: make([]Exposure, 0)}
+	var nodes []*Node
+	known, valueSum := 0, 0.0
+	for _, h := range p.Holdings {
+		n, ok := byTicker[strings.ToUpper(h.Ticker)]
+		if !ok {
+			n, ok = g.Nodes[strings.ToLower(h.Ticker)]
+		}
+		if !ok || h.Quantity <= 0 {
+			risk.Unmapped = append(risk.Unmapped, h.Ticker)
+			continue
+		}
+		pos := PositionRisk{Ticker: h.Ticker, NodeID: n.ID, Name: n.Name, Quantity: h.Quantity, Health: n.Health}
+		if price, ok := g.ConvertPrice(n.Price, n.Currency, "USD"); ok && price > 0 {
+			pos.Value = h.Quantity * price
+			known++
+			valueSum += pos.Value
+		}
+		risk.Positions = append(risk.Positions, pos)
+		nodes = append(nodes, n)
+	}
+	if len(risk.Positions) == 0 {
+		return risk
+	}
+
+	// Positions without a price weigh as much as the average priced one, or
+	// all equally when none is priced
+	fallback := 1.0
+	if known > 0 {
+		fallback = valueSum / float64(known)
+	}
+	total := 0.0
+	for i := range risk.Positions {
+		if risk.Positions[i].Value <= 0 {
+			risk.Positions[i].Value = fallback
+		}
+		total += risk.Positions[i].Value
+	}
+	for i := range risk.Positions {
+		risk.Positions[i].Weight = risk.Positions[i].Value / total
+		risk.Health += risk.Positions[i].Weight * risk.Positions[i].Health
+	}
+
+	// What each node depends on, and how strongly: suppliers through
+	// Supplies / ProcuresFrom, raw materials through Requires / Consumes
+	type dependency struct {
+		id     string
+		weight float64
+	}
+	upstream := make(map[string][]dependency)
+	for _, e := range g.Edges {
+		w := clampUnit(e.Weight)
+		switch e.Type {
+		case EdgeTypeSupplies:
+			upstream[e.TargetID] = append(upstream[e.TargetID], dependency{e.SourceID, w})
+		case EdgeTypeProcuresFrom, EdgeTypeRequires, EdgeTypeConsumes:
+			upstream[e.SourceID] = append(upstream[e.SourceID], dependency{e.TargetID, w})
+		}
+	}
+	inherited := g.inheritedCountriesLocked()
+	keys := make(map[string]string) // Country name -> countryKey, as resolving may be slow
+	keyOf := func(country string) string {
+		key, ok := keys[country]
+		if !ok {
+			key = countryKey(country)
+			keys[country] = key
+		}
+		return key
+	}
+	nations := make(map[string]*Node)
+	for _, n := range g.Nodes {
+		if n.Type == NodeTypeNation {
+			nations[keyOf(n.Name)] = n
+		}
+	}
+
+	// reach is one holding's dependency on one exposure
+	type reach struct {
+		Exposure         // Identity only
+		strength float64 // Product of edge weights along the strongest path
+	}
+	exposures := make(map[string]*Exposure)
+	for i, start := range nodes {
+		pos := risk.Positions[i]
+		reached := make(map[string]*reach)
+		depend := func(key, nodeID, name, kind string, hops int, strength float64) {
+			r, ok := reached[key]
+			if !ok {
+				r = &reach{Exposure: Exposure{NodeID: nodeID, Name: name, Kind: kind, Hops: hops}}
+				reached[key] = r
+			}
+			r.Hops = min(r.Hops, hops)
+			r.strength = math.Max(r.strength, strength)
+		}
+		dependCountry := func(n *Node, hops int, strength float64) {
+			country := n.Country()
+			if country == "" {
+				country = inherited[n.ID]
+			}
+			if country == "" {
+				return
+			}
+			key := keyOf(country)
+			if nation, ok := nations[key]; ok {
+				depend(ExposureNation+":"+nation.ID, nation.ID, nation.Name, ExposureNation, hops, strength)
+				return
+			}
+			depend(ExposureNation+":"+key, "", country, ExposureNation, hops, strength)
+		}
+
+		dependCountry(start, 0, 1)
+		if start.Type == NodeTypeCorporation {
+			depend(ExposureSupplier+":"+start.ID, start.ID, start.Name, ExposureSupplier, 0, 1)
+		}
+		// Relax layer by layer so a stronger path found later still counts
+		best := map[string]float64{start.ID: 1}
+		frontier := map[string]float64{start.ID: 1}
+		for hops := 1; hops <= depth && len(frontier) > 0; hops++ {
+			next := make(map[string]float64)
+			for id, strength := range frontier {
+				for _, dep := range upstream[id] {
+					s := strength * dep.weight
+					up, ok := g.Nodes[dep.id]
+					if !ok || s <= best[dep.id] {
+						continue
+					}
+					switch up.Type {
+					case NodeTypeCorporation:
+						depend(ExposureSupplier+":"+up.ID, up.ID, up.Name, ExposureSupplier, hops, s)
+						dependCountry(up, hops, s)
+					case NodeTypeRawMaterial, NodeTypeCrop:
+						depend(ExposureRawMaterial+":"+up.ID, up.ID, up.Name, ExposureRawMaterial, hops, s)
+					default:
+						continue
+					}
+					best[dep.id] = s
+					next[dep.id] = s
+				}
+			}
+			frontier = next
+		}
+
+		for key, r := range reached {
+			e, ok := exposures[key]
+			if !ok {
+				e = &Exposure{NodeID: r.NodeID, Name: r.Name, Kind: r.Kind, Hops: r.Hops, Holdings: make([]string, 0)}
+				if n, ok := g.Nodes[r.NodeID]; ok {
+					e.Health = n.Health
+				}
+				exposures[key] = e
+			}
+			e.Hops = min(e.Hops, r.Hops)
+			e.Holdings = append(e.Holdings, pos.Ticker)
+			e.Share += pos.Weight * r.strength
+			if r.Hops == 0 {
+				e.Direct += pos.Weight
+			}
+		}
+	}
+
+	for _, e := range exposures {
+		sort.Strings(e.Holdings)
+		e.Concentrated = e.Share >= threshold && e.Share > e.Direct+1e-9
+		risk.Exposures = append(risk.Exposures, *e)
+	}
+	sort.Slice(risk.Exposures, func(i, j int) bool {
+		a, b := risk.Exposures[i], risk.Exposures[j]
+		if a.Share != b.Share {
+			return a.Share > b.Share
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return risk
+}
+
+// countryKey matches a country name to a nation however either is spelled
+func countryKey(name string) string {
+	if c, ok := ResolveCountry(name); ok {
+		return c.Alpha3
+	}
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -0,0 +1,142 @@
+package graph
+
+import (
+	"fmt"
+	"margraf/clock"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Price history keeps the quotes the market monitor collects, one close per
+// day per node, so analyses that need a price series (pair correlations,
+// hedge ratios, backtests) can run on what margraf already gathered instead
+// of fetching it again.
+
+// maxPriceDays bounds the daily closes kept per node
+const maxPriceDays = 1000
+
+// PriceHistory is a node's daily closes in one currency
+type PriceHistory struct {
+	NodeID   string          `json:"node_id"`
+	Ticker   string          `json:"ticker,omitempty"`
+	Currency string          `json:"currency,omitempty"`
+	History  []PriceSnapshot `json:"history"` // Oldest first, one per UTC day
+}
+
+// PriceSnapshot is the last price recorded for a node on one day
+type PriceSnapshot struct {
+	Price     float64   `json:"price"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RecordPrice records a node's price at the given time, replacing any price
+// recorded later the same day. Earlier days may be backfilled. A change of
+// currency starts the history over, since the closes would not compare.
+func (g *Graph) RecordPrice(id string, price float64, at time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	node, ok := g.Nodes[id]
+	if !ok {
+		return fmt.Errorf("node %s not found", id)
+	}
+	g.recordPrice(node, price, at)
+	return nil
+}
+
+// recordPrice adds a price sample for node (must be called with lock held)
+func (g *Graph) recordPrice(node *Node, price float64, at time.Time) {
+	if price <= 0 {
+		return
+	}
+	if at.IsZero() {
+		at = clock.Now()
+	}
+	if g.PriceHistories == nil {
+		g.PriceHistories = make(map[string]*PriceHistory)
+	}
+	history, exists := g.PriceHistories[node.ID]
+	if !exists || history.Currency != node.Currency {
+		history = &PriceHistory{NodeID: node.ID, Currency: node.Currency}
+		g.PriceHistories[node.ID] = history
+	}
+	if node.Ticker != "" {
+		history.Ticker = node.Ticker
+	}
+
+	day := at.UTC().Truncate(24 * time.Hour)
+	i := sort.Search(len(history.History), func(i int) bool {
+		return !history.History[i].Timestamp.UTC().Truncate(24 * time.Hour).Before(day)
+	})
+	snap := PriceSnapshot{Price: price, Timestamp: at}
+	if i < len(history.History) && history.History[i].Timestamp.UTC().Truncate(24*time.Hour).Equal(day) {
+		if !at.Before(history.History[i].Timestamp) {
+			history.History[i] = snap
+		}
+	} else {
+		history.History = append(history.History, PriceSnapshot{})
+		copy(history.History[i+1:], history.History[i:])
+		history.History[i] = snap
+	}
+	if excess := len(history.History) - maxPriceDays; excess > 0 {
+		history.History = append(history.History[:0:0], history.History[excess:]...)
+	}
+}
+
+// priceRecorded reports whether node's history already holds a price for
+// at's day quoted at or after at (must be called with lock held)
+func (g *Graph) priceRecorded(node *Node, at time.Time) bool {
+	history, ok := g.PriceHistories[node.ID]
+	if !ok || history.Currency != node.Currency || len(history.History) == 0 {
+		return false
+	}
+	day := at.UTC().Truncate(24 * time.Hour)
+	for i := len(history.History) - 1; i >= 0; i-- {
+		ts := history.History[i].Timestamp
+		if ts.UTC().Truncate(24 * time.Hour).Equal(day) {
+			return !ts.Before(at)
+		}
+		if ts.Before(day) {
+			break
+		}
+	}
+	return false
+}
+
+// GetPriceHistory returns a copy of a node's daily closes since since,
+// oldest first (all of them for a zero since)
+func (g *Graph) GetPriceHistory(id string, since time.Time) []PriceSnapshot {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	history, ok := g.PriceHistories[id]
+	if !ok {
+		return nil
+	}
+	i := sort.Search(len(history.History), func(i int) bool { return !history.History[i].Timestamp.Before(since) })
+	return append([]PriceSnapshot(nil), history.History[i:]...)
+}
+
+// PriceHistoryByTicker returns the daily closes recorded under a ticker since
+// since, oldest first, and the ID of the node they belong to. Tickers compare
+// case-insensitively; when several nodes share one, the longest history wins.
+func (g *Graph) PriceHistoryByTicker(ticker string, since time.Time) (string, []PriceSnapshot) {
+	g.mu.RLock()
+	var best *PriceHistory
+	for _, history := range g.PriceHistories {
+		if !strings.EqualFold(history.Ticker, ticker) {
+			continue
+		}
+		if best == nil || len(history.History) > len(best.History) ||
+			len(history.History) == len(best.History) && history.NodeID < best.NodeID {
+			best = history
+		}
+	}
+	g.mu.RUnlock()
+
+	if best == nil {
+		return "", nil
+	}
+	return best.NodeID, g.GetPriceHistory(best.NodeID, since)
+}
@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProjectionLevel selects how nodes are grouped in a projection
+type ProjectionLevel string
+
+const (
+	ProjectionNation   ProjectionLevel = "nation"   // Nation + its industries and companies
+	ProjectionIndustry ProjectionLevel = "industry" // Industries of the same name across nations + their companies
+)
+
+// Projection is a summary graph collapsing the full graph into groups
+type Projection struct {
+	Level ProjectionLevel  `json:"level"`
+	Nodes []ProjectionNode `json:"nodes"`
+	Links []ProjectionLink `json:"links"`
+}
+
+// ProjectionNode aggregates the members of one group
+type ProjectionNode struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Health        float64 `json:"health"`         // Mean health of all members
+	CompanyHealth float64 `json:"company_health"` // Mean health of member corporations (0 if none)
+	Members       int     `json:"members"`
+	Companies     int     `json:"companies"`
+	Stressed      int     `json:"stressed"` // Members with health below 1.0
+	Exposure      float64 `json:"exposure"` // Mean shortfall below 1.0 health (0 = unshocked)
+}
+
+// ProjectionLink aggregates edges between two groups
+type ProjectionLink struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Type   string  `json:"type"`
+	Weight float64 `json:"weight"` // Sum of member edge weights
+	Count  int     `json:"count"`  // Number of member edges
+}
+
+// Project collapses the graph into a nation- or industry-level summary.
+// Nodes that don't belong to any group (raw materials, products, orphaned
+// companies) are left out, as are structural HasIndustry/HasCompany edges
+// and commodity-specific trade edges (already counted in the aggregate edge).
+func (g *Graph) Project(level ProjectionLevel) (*Projection, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	groupOf := make(map[string]string) // node ID -> group ID
+	names := make(map[string]string)   // group ID -> display name
+
+	switch level {
+	case ProjectionNation:
+		for _, n := range g.Nodes {
+			if n.Type != NodeTypeNation {
+				continue
+			}
+			groupOf[n.ID] = n.ID
+			names[n.ID] = n.Name
+			for _, e := range g.Adjacency[n.ID] {
+				if e.Type != EdgeTypeHasIndustry {
+					continue
+				}
+				g.assignGroup(groupOf, e.TargetID, n.ID)
+				for _, ce := range g.Adjacency[e.TargetID] {
+					if ce.Type == EdgeTypeHasCompany {
+						g.assignGroup(groupOf, ce.TargetID, n.ID)
+					}
+				}
+			}
+		}
+
+	case ProjectionIndustry:
+		for _, n := range g.Nodes {
+			if n.Type != NodeTypeIndustry {
+				continue
+			}
+			groupID := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(n.Name), " ", "_"))
+			if _, ok := names[groupID]; !ok {
+				names[groupID] = n.Name
+			}
+			g.assignGroup(groupOf, n.ID, groupID)
+			for _, e := range g.Adjacency[n.ID] {
+				if e.Type == EdgeTypeHasCompany {
+					g.assignGroup(groupOf, e.TargetID, groupID)
+				}
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown projection level %q", level)
+	}
+
+	// Aggregate node health per group
+	nodes := make(map[string]*ProjectionNode, len(names))
+	for groupID, name := range names {
+		nodes[groupID] = &ProjectionNode{ID: groupID, Name: name}
+	}
+	for nodeID, groupID := range groupOf {
+		n := g.Nodes[nodeID]
+		pn := nodes[groupID]
+		pn.Members++
+		pn.Health += n.Health
+		if n.Type == NodeTypeCorporation {
+			pn.Companies++
+			pn.CompanyHealth += n.Health
+		}
+		if n.Health < 1.0 {
+			pn.Stressed++
+			pn.Exposure += 1.0 - n.Health
+		}
+	}
+
+	proj := &Projection{Level: level, Nodes: make([]ProjectionNode, 0, len(nodes))}
+	for _, pn := range nodes {
+		if pn.Members > 0 {
+			pn.Health /= float64(pn.Members)
+			pn.Exposure /= float64(pn.Members)
+		}
+		if pn.Companies > 0 {
+			pn.CompanyHealth /= float64(pn.Companies)
+		}
+		proj.Nodes = append(proj.Nodes, *pn)
+	}
+	sort.Slice(proj.Nodes, func(i, j int) bool { return proj.Nodes[i].ID < proj.Nodes[j].ID })
+
+	// Aggregate edges between different groups
+	links := make(map[string]*ProjectionLink)
+	for _, e := range g.Edges {
+		if e.Type == EdgeTypeHasIndustry || e.Type == EdgeTypeHasCompany || e.Commodity() != "" {
+			continue
+		}
+		src, okSrc := groupOf[e.SourceID]
+		tgt, okTgt := groupOf[e.TargetID]
+		if !okSrc || !okTgt || src == tgt {
+			continue
+		}
+		key := src + "|" + tgt + "|" + string(e.Type)
+		link, ok := links[key]
+		if !ok {
+			link = &ProjectionLink{Source: src, Target: tgt, Type: string(e.Type)}
+			links[key] = link
+		}
+		link.Weight += e.Weight
+		link.Count++
+	}
+
+	proj.Links = make([]ProjectionLink, 0, len(links))
+	for _, l := range links {
+		proj.Links = append(proj.Links, *l)
+	}
+	sort.Slice(proj.Links, func(i, j int) bool {
+		a, b := proj.Links[i], proj.Links[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Type < b.Type
+	})
+
+	return proj, nil
+}
+
+// assignGroup maps nodeID to groupID unless it's already claimed by another
+// group (companies listed under several nations count toward only one).
+func (g *Graph) assignGroup(groupOf map[string]string, nodeID, groupID string) {
+	if _, ok := g.Nodes[nodeID]; !ok {
+		return
+	}
+	if _, claimed := groupOf[nodeID]; !claimed {
+		groupOf[nodeID] = groupID
+	}
+}
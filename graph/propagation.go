@@ -0,0 +1,332 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// defaultPropagationFactor applies to edge types missing from the table
+const defaultPropagationFactor = 0.5
+
+// defaultPropagationFactors is the built-in share of shock energy each edge
+// type passes on
+var defaultPropagationFactors = map[EdgeType]float64{
+	// Strong propagation - direct supply chain relationships
+	EdgeTypeSupplies:     0.9, // 90% of shock propagates downstream
+	EdgeTypeManufactures: 0.9,
+	EdgeTypeProduces:     0.8,
+	EdgeTypeProcuresFrom: 0.7, // Upstream propagation slightly weaker
+	EdgeTypeConsumes:     0.7,
+	EdgeTypeRequires:     0.7,
+	EdgeTypeDependsOn:    0.8,
+
+	// Ownership - parent and subsidiaries share balance sheets
+	EdgeTypeOwns:         0.7,
+	EdgeTypeSubsidiaryOf: 0.7,
+
+	// Logistics - a closed chokepoint delays rather than severs trade
+	EdgeTypeRoutesThrough: 0.6,
+
+	// Monetary - currency moves pass through partially
+	EdgeTypeIssues:       0.8,
+	EdgeTypeUsesCurrency: 0.5,
+	EdgeTypeReportsIn:    0.4,
+
+	// Medium propagation - trade and capital
+	EdgeTypeTrade:   0.6,
+	EdgeTypeCapital: 0.5,
+
+	// Weak propagation - indirect relationships
+	EdgeTypeCompetesWith:  0.3, // Competitors less directly affected
+	EdgeTypeSubstituteFor: 0.4,
+	EdgeTypeRegulatory:    0.4,
+	EdgeTypeHasIndustry:   0.6,
+	EdgeTypeHasCompany:    0.5,
+}
+
+// PropagationRule overrides an edge type's factor between particular node
+// types, e.g. Supplies edges from a Corporation into a Nation. An empty
+// Source or Target matches any node type.
+type PropagationRule struct {
+	EdgeType EdgeType `json:"edge_type"`
+	Source   NodeType `json:"source,omitempty"`
+	Target   NodeType `json:"target,omitempty"`
+	Factor   float64  `json:"factor"`
+}
+
+// specificity ranks rules so a rule naming both node types beats one naming one
+func (r PropagationRule) specificity() int {
+	n := 0
+	if r.Source != "" {
+		n++
+	}
+	if r.Target != "" {
+		n++
+	}
+	return n
+}
+
+func (r PropagationRule) matches(edgeType EdgeType, source, target NodeType) bool {
+	return r.EdgeType == edgeType &&
+		(r.Source == "" || r.Source == source) &&
+		(r.Target == "" || r.Target == target)
+}
+
+func (r PropagationRule) sameKey(o PropagationRule) bool {
+	return r.EdgeType == o.EdgeType && r.Source == o.Source && r.Target == o.Target
+}
+
+// PropagationModel holds the share of shock energy each edge passes on
+type PropagationModel struct {
+	Default    float64              // Edge types missing from ByEdgeType
+	ByEdgeType map[EdgeType]float64 // Per edge type
+	Rules      []PropagationRule    // Node-type pair overrides; the most specific match wins
+}
+
+// DefaultPropagationModel returns the built-in factors, with those of the
+// declared edge types that set one (see DeclareTypes)
+func DefaultPropagationModel() *PropagationModel {
+	m := &PropagationModel{
+		Default:    defaultPropagationFactor,
+		ByEdgeType: make(map[EdgeType]float64, len(defaultPropagationFactors)),
+	}
+	for t, f := range defaultPropagationFactors {
+		m.ByEdgeType[t] = f
+	}
+	for _, d := range DeclaredEdgeTypes() {
+		if d.Propagation > 0 {
+			m.ByEdgeType[d.Name] = d.Propagation
+		}
+	}
+	return m
+}
+
+// builtinPropagation backs GetShockPropagationFactor and graphs without a model
+var builtinPropagation = DefaultPropagationModel()
+
+// Factor returns the factor for an edge of edgeType from a source to a target
+// node type
+func (m *PropagationModel) Factor(edgeType EdgeType, source, target NodeType) float64 {
+	best := -1
+	factor := 0.0
+	for _, r := range m.Rules {
+		if r.matches(edgeType, source, target) && r.specificity() > best {
+			best, factor = r.specificity(), r.Factor
+		}
+	}
+	if best >= 0 {
+		return factor
+	}
+	if f, ok := m.ByEdgeType[edgeType]; ok {
+		return f
+	}
+	if d, ok := declaredEdgeType(edgeType); ok && d.Propagation > 0 {
+		return d.Propagation // Declared after the model was built
+	}
+	return m.Default
+}
+
+// Apply layers o's factors over the model
+func (m *PropagationModel) Apply(o PropagationOverrides) {
+	for t, f := range o.EdgeTypes {
+		m.ByEdgeType[t] = f
+	}
+	for _, r := range o.Rules {
+		m.setRule(r)
+	}
+}
+
+// setRule adds r, replacing any rule for the same edge and node types
+func (m *PropagationModel) setRule(r PropagationRule) {
+	for i := range m.Rules {
+		if m.Rules[i].sameKey(r) {
+			m.Rules[i] = r
+			return
+		}
+	}
+	m.Rules = append(m.Rules, r)
+}
+
+// Validate checks every factor is between 0 and 1
+func (m *PropagationModel) Validate() error {
+	check := func(name string, f float64) error {
+		if f < 0 || f > 1 {
+			return fmt.Errorf("propagation factor %s: %.2f is outside [0, 1]", name, f)
+		}
+		return nil
+	}
+	if err := check("default", m.Default); err != nil {
+		return err
+	}
+	for t, f := range m.ByEdgeType {
+		if err := check(string(t), f); err != nil {
+			return err
+		}
+	}
+	for _, r := range m.Rules {
+		if r.EdgeType == "" {
+			return errors.New("propagation rule without edge type")
+		}
+		if err := check(r.String(), r.Factor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String names the rule as "EdgeType Source->Target", "*" for any node type
+func (r PropagationRule) String() string {
+	orAny := func(t NodeType) string {
+		if t == "" {
+			return "*"
+		}
+		return string(t)
+	}
+	return fmt.Sprintf("%s %s->%s", r.EdgeType, orAny(r.Source), orAny(r.Target))
+}
+
+// clone returns a deep copy of the model
+func (m *PropagationModel) clone() *PropagationModel {
+	c := &PropagationModel{
+		Default:    m.Default,
+		ByEdgeType: make(map[EdgeType]float64, len(m.ByEdgeType)),
+		Rules:      append([]PropagationRule(nil), m.Rules...),
+	}
+	for t, f := range m.ByEdgeType {
+		c.ByEdgeType[t] = f
+	}
+	return c
+}
+
+// EdgeTypes returns the edge types with a factor, sorted
+func (m *PropagationModel) EdgeTypes() []EdgeType {
+	types := make([]EdgeType, 0, len(m.ByEdgeType))
+	for t := range m.ByEdgeType {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// SetPropagationModel replaces the graph's propagation factors
+func (g *Graph) SetPropagationModel(m *PropagationModel) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.propagation = m
+}
+
+// PropagationModel returns a copy of the graph's propagation factors
+func (g *Graph) PropagationModel() *PropagationModel {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.propagation == nil {
+		return builtinPropagation.clone()
+	}
+	return g.propagation.clone()
+}
+
+// ShockPropagationFactor returns how much of a shock e passes on, given the
+// types of the nodes it joins
+func (g *Graph) ShockPropagationFactor(e *Edge) float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	m := g.propagation
+	if m == nil {
+		m = builtinPropagation
+	}
+	var source, target NodeType
+	if n, ok := g.Nodes[e.SourceID]; ok {
+		source = n.Type
+	}
+	if n, ok := g.Nodes[e.TargetID]; ok {
+		target = n.Type
+	}
+	return m.Factor(e.Type, source, target)
+}
+
+// PropagationOverrides are factors tuned at runtime, saved so they outlive a
+// restart. They are layered over the built-in and configured factors.
+type PropagationOverrides struct {
+	EdgeTypes map[EdgeType]float64 `json:"edge_types,omitempty"`
+	Rules     []PropagationRule    `json:"rules,omitempty"`
+}
+
+// LoadPropagationOverrides reads overrides saved by Save. A missing file
+// holds no overrides.
+func LoadPropagationOverrides(path string) (PropagationOverrides, error) {
+	var o PropagationOverrides
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return o, nil
+	}
+	if err != nil {
+		return o, err
+	}
+	if err := json.Unmarshal(data, &o); err != nil {
+		return o, fmt.Errorf("%s: %w", path, err)
+	}
+	return o, nil
+}
+
+// Save writes the overrides to path
+func (o PropagationOverrides) Save(path string) error {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Set overrides a factor: the edge type's own when r names no node types,
+// otherwise the rule for that node-type pair
+func (o *PropagationOverrides) Set(r PropagationRule) {
+	if r.Source == "" && r.Target == "" {
+		if o.EdgeTypes == nil {
+			o.EdgeTypes = make(map[EdgeType]float64)
+		}
+		o.EdgeTypes[r.EdgeType] = r.Factor
+		return
+	}
+	for i := range o.Rules {
+		if o.Rules[i].sameKey(r) {
+			o.Rules[i] = r
+			return
+		}
+	}
+	o.Rules = append(o.Rules, r)
+}
+
+// Unset removes the override Set(r) would change, reporting whether one existed
+func (o *PropagationOverrides) Unset(r PropagationRule) bool {
+	if r.Source == "" && r.Target == "" {
+		if _, ok := o.EdgeTypes[r.EdgeType]; ok {
+			delete(o.EdgeTypes, r.EdgeType)
+			return true
+		}
+		return false
+	}
+	for i := range o.Rules {
+		if o.Rules[i].sameKey(r) {
+			o.Rules = append(o.Rules[:i], o.Rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether the factor for r is overridden
+func (o PropagationOverrides) Has(r PropagationRule) bool {
+	if r.Source == "" && r.Target == "" {
+		_, ok := o.EdgeTypes[r.EdgeType]
+		return ok
+	}
+	for _, x := range o.Rules {
+		if x.sameKey(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maintenanceEvents are history events that touch an edge without new
+// evidence for the relationship, so they don't count towards its age
+var maintenanceEvents = map[string]bool{
+	"temporal_decay":     true,
+	"normalize":          true,
+	eventOverrideSet:     true,
+	eventOverrideCleared: true,
+	eventOverrideExpired: true,
+}
+
+// PruneCriteria selects dead edges. An edge must meet every criterion that is set.
+type PruneCriteria struct {
+	OlderThan   time.Duration // Last evidence at least this old (0 = any age)
+	WeightBelow float64       // Weight strictly below this (0 = any weight)
+	Types       []EdgeType    // Edge types considered (empty = all)
+}
+
+// EdgeAge is an edge with the time of the last event that was evidence for it
+type EdgeAge struct {
+	SourceID     string        `json:"source_id"`
+	TargetID     string        `json:"target_id"`
+	Type         EdgeType      `json:"type"`
+	Commodity    string        `json:"commodity,omitempty"`
+	Weight       float64       `json:"weight"`
+	Status       EdgeStatus    `json:"status"`
+	LastEvidence time.Time     `json:"last_evidence"`
+	Age          time.Duration `json:"age"`
+}
+
+// AgeBucket summarizes the edges whose last evidence falls in an age range
+type AgeBucket struct {
+	Label      string        `json:"label"`
+	MaxAge     time.Duration `json:"max_age"` // 0 = unbounded
+	Count      int           `json:"count"`
+	MeanWeight float64       `json:"mean_weight"`
+	Weak       int           `json:"weak"` // Weight below 0.3 (Weak or Blocked)
+}
+
+// ageBuckets are the ranges of the aging report
+var ageBuckets = []struct {
+	label  string
+	maxAge time.Duration
+}{
+	{"< 7d", 7 * 24 * time.Hour},
+	{"7-30d", 30 * 24 * time.Hour},
+	{"30-90d", 90 * 24 * time.Hour},
+	{"90-365d", 365 * 24 * time.Hour},
+	{"> 365d", 0},
+}
+
+// lastEvidenceLocked returns when an edge was last created, refreshed or
+// moved by news or a shock; decay and normalization don't count (must be
+// called with lock held)
+func (g *Graph) lastEvidenceLocked(e *Edge) time.Time {
+	if history, ok := g.EdgeHistories[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())]; ok {
+		for i := len(history.History) - 1; i >= 0; i-- {
+			if snap := history.History[i]; !maintenanceEvents[snap.EventID] {
+				return snap.Timestamp
+			}
+		}
+	}
+	return e.Timestamp
+}
+
+func (g *Graph) edgeAgeLocked(e *Edge, now time.Time) EdgeAge {
+	last := g.lastEvidenceLocked(e)
+	return EdgeAge{
+		SourceID:     e.SourceID,
+		TargetID:     e.TargetID,
+		Type:         e.Type,
+		Commodity:    e.Commodity(),
+		Weight:       e.Weight,
+		Status:       e.Status,
+		LastEvidence: last,
+		Age:          now.Sub(last),
+	}
+}
+
+// EdgeAging groups edges by the age of their last evidence
+func (g *Graph) EdgeAging() []AgeBucket {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	now := time.Now()
+	buckets := make([]AgeBucket, len(ageBuckets))
+	for i, b := range ageBuckets {
+		buckets[i] = AgeBucket{Label: b.label, MaxAge: b.maxAge}
+	}
+	for _, e := range g.Edges {
+		age := now.Sub(g.lastEvidenceLocked(e))
+		i := len(buckets) - 1
+		for j, b := range ageBuckets {
+			if b.maxAge > 0 && age < b.maxAge {
+				i = j
+				break
+			}
+		}
+		buckets[i].Count++
+		buckets[i].MeanWeight += e.Weight
+		if e.Weight < 0.3 {
+			buckets[i].Weak++
+		}
+	}
+	for i := range buckets {
+		if buckets[i].Count > 0 {
+			buckets[i].MeanWeight /= float64(buckets[i].Count)
+		}
+	}
+	return buckets
+}
+
+func (c PruneCriteria) validate() error {
+	if c.OlderThan <= 0 && c.WeightBelow <= 0 {
+		return fmt.Errorf("prune needs --older-than and/or --weight-below")
+	}
+	return nil
+}
+
+func (c PruneCriteria) matches(e *Edge, age EdgeAge, types map[EdgeType]bool) bool {
+	if len(types) > 0 && !types[e.Type] {
+		return false
+	}
+	if c.OlderThan > 0 && age.Age < c.OlderThan {
+		return false
+	}
+	return c.WeightBelow <= 0 || e.Weight < c.WeightBelow
+}
+
+// PruneCandidates returns the edges PruneEdges would remove, oldest first,
+// without changing the graph
+func (g *Graph) PruneCandidates(c PruneCriteria) ([]EdgeAge, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	types := edgeTypeSet(c.Types)
+	now := time.Now()
+	var candidates []EdgeAge
+	for _, e := range g.Edges {
+		if age := g.edgeAgeLocked(e, now); c.matches(e, age, types) {
+			candidates = append(candidates, age)
+		}
+	}
+	sortByAge(candidates)
+	return candidates, nil
+}
+
+// PruneEdges removes the edges matching c and returns them, oldest first.
+// Each removal is recorded in the edge's history with status Pruned and the
+// given eventID, so the relationship's past stays queryable, and is reported
+// to the change hook as an edge_removed delta.
+func (g *Graph) PruneEdges(c PruneCriteria, eventID string) ([]EdgeAge, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	types := edgeTypeSet(c.Types)
+	now := time.Now()
+	var pruned []EdgeAge
+	remove := make(map[*Edge]bool)
+	for _, e := range g.Edges {
+		age := g.edgeAgeLocked(e, now)
+		if !c.matches(e, age, types) {
+			continue
+		}
+		pruned = append(pruned, age)
+		remove[e] = true
+
+		e.Status = EdgeStatusPruned
+		e.Timestamp = now
+		g.recordEdgeHistory(e, eventID)
+		g.emit(Delta{Kind: DeltaEdgeRemoved, Edge: e})
+	}
+	if len(remove) == 0 {
+		return nil, nil
+	}
+	g.removeEdgesLocked(remove)
+	g.triggerAutoSave()
+
+	sortByAge(pruned)
+	return pruned, nil
+}
+
+// removeEdgesLocked drops edges from the edge list and adjacency index (must
+// be called with lock held)
+func (g *Graph) removeEdgesLocked(remove map[*Edge]bool) {
+	kept := g.Edges[:0]
+	for _, e := range g.Edges {
+		if !remove[e] {
+			kept = append(kept, e)
+		}
+	}
+	for i := len(kept); i < len(g.Edges); i++ {
+		g.Edges[i] = nil
+	}
+	g.Edges = kept
+
+	for e := range remove {
+		g.forgetEdgeRelationsLocked(e)
+		list := g.Adjacency[e.SourceID]
+		for i, candidate := range list {
+			if candidate == e {
+				g.Adjacency[e.SourceID] = append(list[:i:i], list[i+1:]...)
+				break
+			}
+		}
+		if len(g.Adjacency[e.SourceID]) == 0 {
+			delete(g.Adjacency, e.SourceID)
+		}
+	}
+}
+
+func edgeTypeSet(types []EdgeType) map[EdgeType]bool {
+	set := make(map[EdgeType]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return set
+}
+
+func sortByAge(edges []EdgeAge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Age != edges[j].Age {
+			return edges[i].Age > edges[j].Age
+		}
+		return edgeKey(edges[i].SourceID, edges[i].TargetID, edges[i].Type, edges[i].Commodity) <
+			edgeKey(edges[j].SourceID, edges[j].TargetID, edges[j].Type, edges[j].Commodity)
+	})
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// PyGSuffix marks exports written by WritePyG
+const PyGSuffix = ".pyg.json"
+
+// PyGData is the graph as PyTorch Geometric tensors: node i is NodeIDs[i],
+// and each list loads with torch.tensor, e.g.
+//
+//	Data(x=torch.tensor(d["x"]), edge_index=torch.tensor(d["edge_index"]),
+//	     edge_weight=torch.tensor(d["edge_weight"]))
+type PyGData struct {
+	NumNodes   int         `json:"num_nodes"`
+	NodeIDs    []string    `json:"node_ids"`   // Sorted
+	NodeTypes  []string    `json:"node_types"` // Names of the indices in node_type
+	NodeType   []int       `json:"node_type"`
+	X          [][]float64 `json:"x"`          // Node features, named by x_names
+	XNames     []string    `json:"x_names"`    // health, then a one-hot column per node type
+	EdgeIndex  [2][]int    `json:"edge_index"` // Sources, then targets, as the edges point
+	EdgeTypes  []string    `json:"edge_types"` // Names of the indices in edge_type
+	EdgeType   []int       `json:"edge_type"`
+	EdgeWeight []float64   `json:"edge_weight"`
+}
+
+// PyG returns the graph as PyTorch Geometric tensors
+func (g *Graph) PyG() *PyGData {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	d := &PyGData{NumNodes: len(g.Nodes), NodeIDs: make([]string, 0, len(g.Nodes))}
+	for id := range g.Nodes {
+		d.NodeIDs = append(d.NodeIDs, id)
+	}
+	sort.Strings(d.NodeIDs)
+	index := make(map[string]int, len(d.NodeIDs))
+	nodeTypes := make(map[string]bool)
+	for i, id := range d.NodeIDs {
+		index[id] = i
+		nodeTypes[string(g.Nodes[id].Type)] = true
+	}
+	d.NodeTypes = sortedSet(nodeTypes)
+	typeIndex := make(map[string]int, len(d.NodeTypes))
+	d.XNames = []string{"health"}
+	for i, t := range d.NodeTypes {
+		typeIndex[t] = i
+		d.XNames = append(d.XNames, "type="+t)
+	}
+
+	d.NodeType = make([]int, len(d.NodeIDs))
+	d.X = make([][]float64, len(d.NodeIDs))
+	for i, id := range d.NodeIDs {
+		n := g.Nodes[id]
+		t := typeIndex[string(n.Type)]
+		d.NodeType[i] = t
+		x := make([]float64, 1+len(d.NodeTypes))
+		x[0] = n.Health
+		x[1+t] = 1
+		d.X[i] = x
+	}
+
+	edgeTypes := make(map[string]bool)
+	for _, e := range g.Edges {
+		edgeTypes[string(e.Type)] = true
+	}
+	d.EdgeTypes = sortedSet(edgeTypes)
+	edgeIndex := make(map[string]int, len(d.EdgeTypes))
+	for i, t := range d.EdgeTypes {
+		edgeIndex[t] = i
+	}
+	d.EdgeIndex = [2][]int{{}, {}}
+	d.EdgeType, d.EdgeWeight = []int{}, []float64{}
+	for _, e := range g.Edges {
+		s, ok1 := index[e.SourceID]
+		t, ok2 := index[e.TargetID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		d.EdgeIndex[0] = append(d.EdgeIndex[0], s)
+		d.EdgeIndex[1] = append(d.EdgeIndex[1], t)
+		d.EdgeType = append(d.EdgeType, edgeIndex[string(e.Type)])
+		d.EdgeWeight = append(d.EdgeWeight, e.Weight)
+	}
+	return d
+}
+
+// WritePyG writes the graph to w as PyTorch Geometric tensors (see PyGData)
+func (g *Graph) WritePyG(w io.Writer) error {
+	bw := bufio.NewWriterSize(w, streamBuffer)
+	if err := json.NewEncoder(bw).Encode(g.PyG()); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// isPyG reports whether filename names a PyTorch Geometric export
+func isPyG(filename string) bool {
+	return strings.HasSuffix(strings.ToLower(filename), PyGSuffix)
+}
+
+// sortedSet returns the keys of set in order
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
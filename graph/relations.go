@@ -0,0 +1,197 @@
+package graph
+
+import "fmt"
+
+// relationEdgeTypes are the edge types GetCompanyRelations reads
+var relationEdgeTypes = map[EdgeType]bool{
+	EdgeTypeSupplies:     true,
+	EdgeTypeProcuresFrom: true,
+	EdgeTypeOwns:         true,
+	EdgeTypeSubsidiaryOf: true,
+	EdgeTypeRequires:     true,
+	EdgeTypeConsumes:     true,
+	EdgeTypeManufactures: true,
+}
+
+// IsRelationEdge reports whether edges of type t count towards company relations
+func IsRelationEdge(t EdgeType) bool {
+	return relationEdgeTypes[t]
+}
+
+// RelationsAffected returns the nodes whose company relations a change may
+// alter: both ends of an added or removed relationship edge. Weight and
+// status changes don't alter relations but are reported too, as a Delta
+// doesn't say whether its edge is new.
+func RelationsAffected(d Delta) []string {
+	if (d.Kind != DeltaEdge && d.Kind != DeltaEdgeRemoved) || d.Edge == nil || !IsRelationEdge(d.Edge.Type) {
+		return nil
+	}
+	if d.Edge.SourceID == d.Edge.TargetID {
+		return []string{d.Edge.SourceID}
+	}
+	return []string{d.Edge.SourceID, d.Edge.TargetID}
+}
+
+// GetCompanyRelations returns all relationships for a given company. Results
+// are cached until a relationship edge of the company is added or removed, so
+// the returned value is shared and must not be modified.
+func (g *Graph) GetCompanyRelations(companyID string) (*CompanyRelations, error) {
+	g.relationsMu.Lock()
+	cached, ok := g.relations[companyID]
+	g.relationsMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	rel, err := g.companyRelationsLocked(companyID)
+	if err != nil {
+		return nil, err
+	}
+	// Stored under the read lock so no writer can invalidate it in between
+	g.relationsMu.Lock()
+	if g.relations == nil {
+		g.relations = make(map[string]*CompanyRelations)
+	}
+	g.relations[companyID] = rel
+	g.relationsMu.Unlock()
+	return rel, nil
+}
+
+// companyRelationsLocked collects a company's relations in one pass over the
+// edges (must be called with lock held)
+func (g *Graph) companyRelationsLocked(companyID string) (*CompanyRelations, error) {
+	company, ok := g.Nodes[companyID]
+	if !ok {
+		return nil, fmt.Errorf("company %s not found", companyID)
+	}
+	if company.Type != NodeTypeCorporation {
+		return nil, fmt.Errorf("node %s is not a corporation", companyID)
+	}
+
+	rel := &CompanyRelations{
+		CompanyID:    companyID,
+		CompanyName:  company.Name,
+		Suppliers:    make([]*Node, 0),
+		Clients:      make([]*Node, 0),
+		RawMaterials: make([]*Node, 0),
+		Products:     make([]*Node, 0),
+		Parents:      make([]*Node, 0),
+		Subsidiaries: make([]*Node, 0),
+	}
+	add := func(list *[]*Node, id string, types ...NodeType) {
+		n, ok := g.Nodes[id]
+		if !ok {
+			return
+		}
+		for _, listed := range *list {
+			if listed.ID == id {
+				return
+			}
+		}
+		for _, t := range types {
+			if n.Type == t {
+				*list = append(*list, n)
+				return
+			}
+		}
+	}
+
+	for _, e := range g.Edges {
+		out, in := e.SourceID == companyID, e.TargetID == companyID
+		if (!out && !in) || !relationEdgeTypes[e.Type] {
+			continue
+		}
+		switch e.Type {
+		case EdgeTypeSupplies:
+			if in {
+				add(&rel.Suppliers, e.SourceID, NodeTypeCorporation)
+			}
+			if out {
+				add(&rel.Clients, e.TargetID, NodeTypeCorporation)
+			}
+		case EdgeTypeProcuresFrom:
+			if out {
+				add(&rel.Suppliers, e.TargetID, NodeTypeCorporation)
+			}
+			if in {
+				add(&rel.Clients, e.SourceID, NodeTypeCorporation)
+			}
+		case EdgeTypeOwns:
+			if in {
+				add(&rel.Parents, e.SourceID, NodeTypeCorporation)
+			}
+			if out {
+				add(&rel.Subsidiaries, e.TargetID, NodeTypeCorporation)
+			}
+		case EdgeTypeSubsidiaryOf:
+			if out {
+				add(&rel.Parents, e.TargetID, NodeTypeCorporation)
+			}
+			if in {
+				add(&rel.Subsidiaries, e.SourceID, NodeTypeCorporation)
+			}
+		case EdgeTypeRequires, EdgeTypeConsumes:
+			if out {
+				add(&rel.RawMaterials, e.TargetID, NodeTypeRawMaterial, NodeTypeCrop)
+			}
+		case EdgeTypeManufactures:
+			if out {
+				add(&rel.Products, e.TargetID, NodeTypeProduct)
+			}
+		}
+	}
+	return rel, nil
+}
+
+// forgetRelationsLocked drops cached relations of the given nodes and of any
+// company listing them (must be called with lock held)
+func (g *Graph) forgetRelationsLocked(ids ...string) {
+	g.relationsMu.Lock()
+	defer g.relationsMu.Unlock()
+	if len(g.relations) == 0 {
+		return
+	}
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+		delete(g.relations, id)
+	}
+	for company, rel := range g.relations {
+		if rel.lists(drop) {
+			delete(g.relations, company)
+		}
+	}
+}
+
+// forgetEdgeRelationsLocked drops cached relations an added or removed edge
+// changes (must be called with lock held)
+func (g *Graph) forgetEdgeRelationsLocked(e *Edge) {
+	if !relationEdgeTypes[e.Type] {
+		return
+	}
+	g.relationsMu.Lock()
+	defer g.relationsMu.Unlock()
+	delete(g.relations, e.SourceID)
+	delete(g.relations, e.TargetID)
+}
+
+// resetRelationsLocked drops every cached relation (must be called with lock held)
+func (g *Graph) resetRelationsLocked() {
+	g.relationsMu.Lock()
+	defer g.relationsMu.Unlock()
+	g.relations = nil
+}
+
+// lists reports whether any of the nodes in ids appears in the relations
+func (r *CompanyRelations) lists(ids map[string]bool) bool {
+	for _, list := range [][]*Node{r.Suppliers, r.Clients, r.RawMaterials, r.Products, r.Parents, r.Subsidiaries} {
+		for _, n := range list {
+			if ids[n.ID] {
+				return true
+			}
+		}
+	}
+	return false
+}
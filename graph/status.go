@@ -0,0 +1,169 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A StatusOverride pins an edge's status for known events the weight can't
+// express, e.g. "blocked until 2027-01-01" for an announced embargo. News
+// updates, decay and normalization keep adjusting the weight underneath, so
+// once the override lapses the edge resumes at the status the latest signals
+// imply.
+type StatusOverride struct {
+	Status EdgeStatus `json:"status"`
+	Until  time.Time  `json:"until,omitempty"` // Zero = until cleared
+	Reason string     `json:"reason,omitempty"`
+	SetAt  time.Time  `json:"set_at"`
+}
+
+// Active reports whether the override still applies at t
+func (o *StatusOverride) Active(t time.Time) bool {
+	return o != nil && (o.Until.IsZero() || t.Before(o.Until))
+}
+
+// Event IDs recorded in edge history for override changes
+const (
+	eventOverrideSet     = "status_override"
+	eventOverrideCleared = "status_override_cleared"
+	eventOverrideExpired = "status_override_expired"
+)
+
+// ParseEdgeStatus accepts a status name in any case
+func ParseEdgeStatus(s string) (EdgeStatus, error) {
+	for _, st := range []EdgeStatus{EdgeStatusStrong, EdgeStatusActive, EdgeStatusWeak, EdgeStatusBlocked, EdgeStatusSuspended} {
+		if strings.EqualFold(s, string(st)) {
+			return st, nil
+		}
+	}
+	return "", fmt.Errorf("unknown edge status %q (use Strong, Active, Weak, Blocked or Suspended)", s)
+}
+
+// blockedWeight is the weight below which an edge is Blocked
+const blockedWeight = 0.1
+
+// weightStatus is the status an edge's weight implies
+func weightStatus(w float64) EdgeStatus {
+	switch {
+	case w < blockedWeight:
+		return EdgeStatusBlocked
+	case w < 0.3:
+		return EdgeStatusWeak
+	case w < 0.7:
+		return EdgeStatusActive
+	default:
+		return EdgeStatusStrong
+	}
+}
+
+// refreshStatusLocked sets e's status from its override while that applies,
+// otherwise from its weight, dropping a lapsed override. Reports whether the
+// override lapsed (must be called with lock held).
+func (g *Graph) refreshStatusLocked(e *Edge, now time.Time) bool {
+	if e.Override.Active(now) {
+		e.Status = e.Override.Status
+		return false
+	}
+	lapsed := e.Override != nil
+	e.Override = nil
+	e.Status = weightStatus(e.Weight)
+	return lapsed
+}
+
+// expireOverridesLocked hands lapsed overrides' edges back to their weights
+// (must be called with lock held)
+func (g *Graph) expireOverridesLocked(now time.Time) int {
+	expired := 0
+	for _, e := range g.Edges {
+		if e.Override != nil && g.refreshStatusLocked(e, now) {
+			g.recordEdgeHistory(e, eventOverrideExpired)
+			g.emit(Delta{Kind: DeltaEdge, Edge: e})
+			expired++
+		}
+	}
+	return expired
+}
+
+// findEdgeLocked returns the edge matching the key fields (must be called with lock held)
+func (g *Graph) findEdgeLocked(sourceID, targetID string, edgeType EdgeType, hsCode string) (*Edge, error) {
+	for _, e := range g.Adjacency[sourceID] {
+		if e.TargetID == targetID && e.Type == edgeType && e.Commodity() == hsCode {
+			return e, nil
+		}
+	}
+	if hsCode != "" {
+		return nil, fmt.Errorf("edge not found: %s -> %s (%s, hs %s)", sourceID, targetID, edgeType, hsCode)
+	}
+	return nil, fmt.Errorf("edge not found: %s -> %s (%s)", sourceID, targetID, edgeType)
+}
+
+// SetEdgeStatus pins an edge's status until the given time (zero = until
+// ClearEdgeStatus). An empty hsCode selects the aggregate edge.
+func (g *Graph) SetEdgeStatus(sourceID, targetID string, edgeType EdgeType, hsCode string, status EdgeStatus, until time.Time, reason string) error {
+	status, err := ParseEdgeStatus(string(status))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !until.IsZero() && !until.After(now) {
+		return fmt.Errorf("override end %s is in the past", until.Format("2006-01-02"))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	e, err := g.findEdgeLocked(sourceID, targetID, edgeType, hsCode)
+	if err != nil {
+		return err
+	}
+	e.Override = &StatusOverride{Status: status, Until: until, Reason: reason, SetAt: now}
+	g.refreshStatusLocked(e, now)
+	g.recordEdgeHistory(e, eventOverrideSet)
+	g.emit(Delta{Kind: DeltaEdge, Edge: e})
+	return nil
+}
+
+// ClearEdgeStatus removes an edge's override, returning its status to the
+// one its weight implies
+func (g *Graph) ClearEdgeStatus(sourceID, targetID string, edgeType EdgeType, hsCode string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	e, err := g.findEdgeLocked(sourceID, targetID, edgeType, hsCode)
+	if err != nil {
+		return err
+	}
+	if e.Override == nil {
+		return fmt.Errorf("edge %s -> %s (%s) has no status override", sourceID, targetID, edgeType)
+	}
+	e.Override = nil
+	g.refreshStatusLocked(e, time.Now())
+	g.recordEdgeHistory(e, eventOverrideCleared)
+	g.emit(Delta{Kind: DeltaEdge, Edge: e})
+	return nil
+}
+
+// StatusOverrides returns copies of the edges with a status override,
+// soonest to lapse first (open-ended overrides last)
+func (g *Graph) StatusOverrides() []Edge {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var out []Edge
+	for _, e := range g.Edges {
+		if e.Override != nil {
+			c := *e
+			o := *e.Override
+			c.Override = &o
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].Override.Until, out[j].Override.Until
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		return a.Before(b)
+	})
+	return out
+}
@@ -0,0 +1,193 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// streamBuffer is the chunk size streaming exports write in
+const streamBuffer = 64 * 1024
+
+// WriteJSON streams the graph to w in the same format as ToJSON, one node or
+// link at a time, so exporting a large graph needs no second copy of it in
+// memory. The graph is read-locked until the export finishes.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	bw := bufio.NewWriterSize(w, streamBuffer)
+	write := func(i int, v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		_, err = bw.Write(data)
+		return err
+	}
+
+	bw.WriteString(`{"nodes":[`)
+	i := 0
+	for _, n := range g.Nodes {
+		data := NodeData{
+			ID:     n.ID,
+			Name:   n.Name,
+			Type:   string(n.Type),
+			Health: n.Health,
+			Price:  n.Price,
+			Ticker: n.Ticker,
+		}
+		if r, ok := n.IndustryRollup(); ok {
+			data.Rollup = &r
+		}
+		data.Notes = g.Notes[n.ID]
+		if err := write(i, data); err != nil {
+			return err
+		}
+		i++
+	}
+
+	bw.WriteString(`],"links":[`)
+	for i, e := range g.Edges {
+		if err := write(i, LinkData{
+			Source:    e.SourceID,
+			Target:    e.TargetID,
+			Type:      string(e.Type),
+			Weight:    e.Weight,
+			Status:    string(e.Status),
+			Commodity: e.Commodity(),
+			Notes:     g.Notes[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())],
+		}); err != nil {
+			return err
+		}
+	}
+	bw.WriteString("]}")
+	return bw.Flush()
+}
+
+// graphMLKeys are the attributes GraphML exports declare: id, element, name, type
+var graphMLKeys = [][4]string{
+	{"name", "node", "name", "string"},
+	{"type", "node", "type", "string"},
+	{"health", "node", "health", "double"},
+	{"price", "node", "price", "double"},
+	{"ticker", "node", "ticker", "string"},
+	{"country", "node", "country", "string"},
+	{"notes", "node", "notes", "string"},
+	{"etype", "edge", "type", "string"},
+	{"weight", "edge", "weight", "double"},
+	{"status", "edge", "status", "string"},
+	{"commodity", "edge", "commodity", "string"},
+	{"enotes", "edge", "notes", "string"},
+}
+
+// WriteGraphML streams the graph to w as GraphML (nodes with name, type,
+// health, price, ticker, country and notes; edges with type, weight, status,
+// commodity and notes), readable by Gephi, yEd, Cytoscape and networkx. Like WriteJSON
+// it writes element by element and holds the read lock until done.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	bw := bufio.NewWriterSize(w, streamBuffer)
+	bw.WriteString(xml.Header)
+	bw.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	for _, k := range graphMLKeys {
+		fmt.Fprintf(bw, "  <key id=%q for=%q attr.name=%q attr.type=%q/>\n", k[0], k[1], k[2], k[3])
+	}
+	bw.WriteString(`  <graph id="margraf" edgedefault="directed">` + "\n")
+
+	data := func(key, value string) {
+		if value == "" {
+			return
+		}
+		fmt.Fprintf(bw, "      <data key=%q>", key)
+		xml.EscapeText(bw, []byte(value))
+		bw.WriteString("</data>\n")
+	}
+	float := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+
+	for _, n := range g.Nodes {
+		bw.WriteString(`    <node id="`)
+		xml.EscapeText(bw, []byte(n.ID))
+		bw.WriteString("\">\n")
+		data("name", n.Name)
+		data("type", string(n.Type))
+		data("health", float(n.Health))
+		if n.Price > 0 {
+			data("price", float(n.Price))
+		}
+		data("ticker", n.Ticker)
+		data("country", n.Country())
+		data("notes", notesText(g.Notes[n.ID]))
+		bw.WriteString("    </node>\n")
+	}
+
+	for i, e := range g.Edges {
+		fmt.Fprintf(bw, `    <edge id="e%d" source="`, i)
+		xml.EscapeText(bw, []byte(e.SourceID))
+		bw.WriteString(`" target="`)
+		xml.EscapeText(bw, []byte(e.TargetID))
+		bw.WriteString("\">\n")
+		data("etype", string(e.Type))
+		data("weight", float(e.Weight))
+		data("status", string(e.Status))
+		data("commodity", e.Commodity())
+		data("enotes", notesText(g.Notes[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())]))
+		if _, err := bw.WriteString("    </edge>\n"); err != nil {
+			return err // Stop early if the destination failed
+		}
+	}
+
+	bw.WriteString("  </graph>\n</graphml>\n")
+	return bw.Flush()
+}
+
+// notesText flattens notes into one GraphML value, a line per note:
+// "2026-01-02 author: text #tag"
+func notesText(notes []Note) string {
+	lines := make([]string, len(notes))
+	for i, n := range notes {
+		lines[i] = fmt.Sprintf("%s %s: %s", n.Time.UTC().Format("2006-01-02"), n.Author, n.Text)
+		for _, t := range n.Tags {
+			lines[i] += " #" + t
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ExportFile streams the graph to filename, as GraphML for .graphml files,
+// PyTorch Geometric tensors for .pyg.json files and JSON (the dashboard
+// format) otherwise. A failed export removes the partial file.
+func (g *Graph) ExportFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	write := g.WriteJSON
+	switch {
+	case strings.EqualFold(filepath.Ext(filename), ".graphml"):
+		write = g.WriteGraphML
+	case isPyG(filename):
+		write = g.WritePyG
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(filename)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(filename)
+		return err
+	}
+	return nil
+}
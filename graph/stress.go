@@ -0,0 +1,222 @@
+package graph
+
+import (
+	"fmt"
+	"margraf/clock"
+	"sort"
+	"time"
+)
+
+// SentimentSource identifies where a sentiment reading came from
+type SentimentSource string
+
+const (
+	SentimentNews   SentimentSource = "news"
+	SentimentSocial SentimentSource = "social"
+)
+
+// maxSentimentHistory bounds the number of sentiment samples kept per node
+const maxSentimentHistory = 200
+
+// SentimentHistory is a bounded time series of sentiment readings about a node
+type SentimentHistory struct {
+	NodeID  string              `json:"node_id"`
+	History []SentimentSnapshot `json:"history"`
+}
+
+// SentimentSnapshot is one sentiment reading (-1.0 to +1.0)
+type SentimentSnapshot struct {
+	Source    SentimentSource `json:"source"`
+	Score     float64         `json:"score"`
+	Timestamp time.Time       `json:"timestamp"`
+	Topics    []string        `json:"topics,omitempty"` // Sub-topics that dominated the reading
+}
+
+// RecordSentiment stores a news or social sentiment reading about a node,
+// with the sub-topics behind it if known
+func (g *Graph) RecordSentiment(id string, source SentimentSource, score float64, topics ...string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.Nodes[id]; !ok {
+		return fmt.Errorf("node %s not found", id)
+	}
+	if g.SentimentHistories == nil {
+		g.SentimentHistories = make(map[string]*SentimentHistory)
+	}
+
+	history, exists := g.SentimentHistories[id]
+	if !exists {
+		history = &SentimentHistory{NodeID: id, History: make([]SentimentSnapshot, 0)}
+		g.SentimentHistories[id] = history
+	}
+
+	history.History = append(history.History, SentimentSnapshot{Source: source, Score: score, Timestamp: clock.Now(), Topics: topics})
+	if excess := len(history.History) - maxSentimentHistory; excess > 0 {
+		history.History = append(history.History[:0:0], history.History[excess:]...)
+	}
+	return nil
+}
+
+// StressWeights sets how much each warning signal contributes to the stress index
+type StressWeights struct {
+	News   float64 `json:"news"`
+	Social float64 `json:"social"`
+	Edges  float64 `json:"edges"`
+	Health float64 `json:"health"`
+}
+
+// DefaultStressWeights weighs structural signals (edges, health) above sentiment
+func DefaultStressWeights() StressWeights {
+	return StressWeights{News: 0.2, Social: 0.2, Edges: 0.3, Health: 0.3}
+}
+
+// StressComponents are the individual warning signals, each from 0 (calm) to 1 (severe)
+type StressComponents struct {
+	News   float64 `json:"news"`   // Negative news sentiment
+	Social float64 `json:"social"` // Negative social sentiment
+	Edges  float64 `json:"edges"`  // Largest weight drop on the node's edges
+	Health float64 `json:"health"` // Health decline from its recent peak
+}
+
+// NodeStress is a node's composite early-warning score
+type NodeStress struct {
+	NodeID     string           `json:"node_id"`
+	Name       string           `json:"name"`
+	Type       NodeType         `json:"type"`
+	Score      float64          `json:"score"` // Weighted mean of Components, 0 to 1
+	Components StressComponents `json:"components"`
+}
+
+// StressIndex scores every node from the signals seen within window and
+// returns the stressed ones, most stressed first.
+func (g *Graph) StressIndex(window time.Duration, w StressWeights) []NodeStress {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	since := time.Now().Add(-window)
+	components := make(map[string]*StressComponents)
+	get := func(id string) *StressComponents {
+		c, ok := components[id]
+		if !ok {
+			c = &StressComponents{}
+			components[id] = c
+		}
+		return c
+	}
+
+	for id, history := range g.SentimentHistories {
+		var newsSum, socialSum float64
+		var newsCount, socialCount int
+		for _, s := range history.History {
+			if s.Timestamp.Before(since) {
+				continue
+			}
+			switch s.Source {
+			case SentimentNews:
+				newsSum += s.Score
+				newsCount++
+			case SentimentSocial:
+				socialSum += s.Score
+				socialCount++
+			}
+		}
+		if newsCount > 0 && newsSum < 0 {
+			get(id).News = clampUnit(-newsSum / float64(newsCount))
+		}
+		if socialCount > 0 && socialSum < 0 {
+			get(id).Social = clampUnit(-socialSum / float64(socialCount))
+		}
+	}
+
+	for _, e := range g.Edges {
+		history, ok := g.EdgeHistories[edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())]
+		if !ok {
+			continue
+		}
+		drop := peakSince(since, len(history.History), func(i int) (float64, time.Time) {
+			return history.History[i].Weight, history.History[i].Timestamp
+		}) - e.Weight
+		if drop <= 0 {
+			continue
+		}
+		drop = clampUnit(drop)
+		for _, id := range []string{e.SourceID, e.TargetID} {
+			if c := get(id); drop > c.Edges {
+				c.Edges = drop
+			}
+		}
+	}
+
+	for id, history := range g.HealthHistories {
+		node, ok := g.Nodes[id]
+		if !ok {
+			continue
+		}
+		peak := peakSince(since, len(history.History), func(i int) (float64, time.Time) {
+			return history.History[i].Health, history.History[i].Timestamp
+		})
+		if peak > 0 && node.Health < peak {
+			get(id).Health = clampUnit((peak - node.Health) / peak)
+		}
+	}
+
+	total := w.News + w.Social + w.Edges + w.Health
+	if total <= 0 {
+		return nil
+	}
+
+	var result []NodeStress
+	for id, c := range components {
+		node, ok := g.Nodes[id]
+		if !ok {
+			continue
+		}
+		score := (w.News*c.News + w.Social*c.Social + w.Edges*c.Edges + w.Health*c.Health) / total
+		if score <= 0 {
+			continue
+		}
+		result = append(result, NodeStress{
+			NodeID:     id,
+			Name:       node.Name,
+			Type:       node.Type,
+			Score:      score,
+			Components: *c,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].NodeID < result[j].NodeID
+	})
+	return result
+}
+
+// peakSince returns the highest value in a time series since a point in time,
+// including the last value before it (the level the window started at).
+// Samples must be oldest first.
+func peakSince(since time.Time, n int, sample func(i int) (float64, time.Time)) float64 {
+	var peak float64
+	for i := n - 1; i >= 0; i-- {
+		v, ts := sample(i)
+		if v > peak {
+			peak = v
+		}
+		if ts.Before(since) {
+			break
+		}
+	}
+	return peak
+}
+
+func clampUnit(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,171 @@
+package graph
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// syntheticSectors is the value chain synthetic graphs are layered on: each
+// sector's companies supply the next sector's, and each sector requires one
+// raw material
+var syntheticSectors = []struct {
+	name, prefix, material string
+}{
+	{"Mining", "MIN", "Iron Ore"},
+	{"Chemicals", "CHM", "Natural Gas"},
+	{"Semiconductors", "SEM", "Silicon"},
+	{"Electronics", "ELC", "Copper"},
+	{"Machinery", "MCH", "Steel"},
+	{"Automotive", "AUT", "Lithium"},
+	{"Pharmaceuticals", "PHR", "Crude Oil"},
+	{"Retail", "RTL", "Cotton"},
+}
+
+// syntheticNations are used first, in order, so small graphs get the major economies
+var syntheticNations = []string{
+	"United States", "China", "Germany", "Japan", "India", "United Kingdom",
+	"France", "South Korea", "Taiwan", "Brazil", "Canada", "Mexico",
+	"Italy", "Netherlands", "Australia", "Saudi Arabia", "Indonesia", "Turkey",
+}
+
+// GenerateSynthetic builds a layered supply chain without any external API:
+// nations -> HasIndustry -> industries -> HasCompany -> listed companies with
+// tickers, prices and headquarters, Supplies / ProcuresFrom pairs from each
+// sector to the next along the value chain, Requires / Produces edges to raw
+// materials and Trade edges between nations. edgeDensity (0-1) is the chance
+// that a company supplies a given company of the next sector, and that two
+// nations trade. The same seed always gives the same graph.
+//
+// The returned graph has auto-save disabled.
+func GenerateSynthetic(nations, industries, companiesPerIndustry int, edgeDensity float64, seed int64) *Graph {
+	rng := rand.New(rand.NewSource(seed))
+	edgeDensity = max(0, min(edgeDensity, 1))
+
+	g := NewGraph()
+	g.autoSavePath = ""
+	batch := g.NewBatch(0)
+
+	nationInfo := pickNations(nations)
+	sectors := make([]string, industries)
+	prefixes := make([]string, industries)
+	materials := make([]string, industries)
+	for i := range sectors {
+		if i < len(syntheticSectors) {
+			sectors[i], prefixes[i], materials[i] = syntheticSectors[i].name, syntheticSectors[i].prefix, syntheticSectors[i].material
+		} else {
+			sectors[i], prefixes[i] = fmt.Sprintf("Industry %d", i+1), fmt.Sprintf("I%02d", i+1)
+		}
+	}
+
+	for _, m := range materials {
+		if m != "" {
+			batch.AddNode(&Node{ID: syntheticID(m), Type: NodeTypeRawMaterial, Name: m})
+		}
+	}
+
+	// tiers[i] holds the companies of sector i across all nations
+	tiers := make([][]string, industries)
+	for _, info := range nationInfo {
+		nationID := syntheticID(info.Name)
+		batch.AddNode(&Node{
+			ID:         nationID,
+			Type:       NodeTypeNation,
+			Name:       info.Name,
+			Attributes: LocationAttributes(info.Lat, info.Lon, info.Name),
+		})
+		for i, sector := range sectors {
+			indID := syntheticID(info.Name + "_" + sector)
+			batch.AddNode(&Node{ID: indID, Type: NodeTypeIndustry, Name: sector})
+			batch.AddEdge(&Edge{SourceID: nationID, TargetID: indID, Type: EdgeTypeHasIndustry, Weight: 1.0})
+			if materials[i] != "" {
+				batch.AddEdge(&Edge{SourceID: indID, TargetID: syntheticID(materials[i]), Type: EdgeTypeRequires, Weight: 1.0})
+				if rng.Float64() < 1.0/3 {
+					batch.AddEdge(&Edge{SourceID: nationID, TargetID: syntheticID(materials[i]), Type: EdgeTypeProduces, Weight: 0.2 + 0.8*rng.Float64()})
+				}
+			}
+
+			for c := 0; c < companiesPerIndustry; c++ {
+				ticker := fmt.Sprintf("%s%04d", prefixes[i], len(tiers[i])+1)
+				compID := strings.ToLower(ticker)
+				attrs := LocationAttributes(info.Lat, info.Lon, info.Name)
+				attrs["sector"] = sector
+				attrs["source"] = "synthetic"
+				attrs["market_cap"] = float64(int64(1e9 * (1 + 499*rng.Float64())))
+				batch.AddNode(&Node{
+					ID:         compID,
+					Type:       NodeTypeCorporation,
+					Name:       fmt.Sprintf("%s %s %d", info.Name, sector, c+1),
+					Ticker:     ticker,
+					Price:      float64(int(1000+49000*rng.Float64())) / 100,
+					Currency:   "USD",
+					Attributes: attrs,
+				})
+				batch.AddEdge(&Edge{SourceID: indID, TargetID: compID, Type: EdgeTypeHasCompany, Weight: 1.0})
+				tiers[i] = append(tiers[i], compID)
+			}
+		}
+	}
+
+	for i := 1; i < len(tiers); i++ {
+		for _, client := range tiers[i] {
+			for _, supplier := range tiers[i-1] {
+				if rng.Float64() >= edgeDensity {
+					continue
+				}
+				weight := 0.2 + 0.8*rng.Float64()
+				batch.AddEdge(&Edge{SourceID: supplier, TargetID: client, Type: EdgeTypeSupplies, Weight: weight, Directionality: DirectionalityUnidirectional})
+				batch.AddEdge(&Edge{SourceID: client, TargetID: supplier, Type: EdgeTypeProcuresFrom, Weight: weight, Directionality: DirectionalityReverse})
+			}
+		}
+	}
+
+	for _, a := range nationInfo {
+		for _, b := range nationInfo {
+			if a.Name != b.Name && rng.Float64() < edgeDensity {
+				batch.AddEdge(&Edge{SourceID: syntheticID(a.Name), TargetID: syntheticID(b.Name), Type: EdgeTypeTrade, Weight: 0.1 + 0.9*rng.Float64()})
+			}
+		}
+	}
+
+	batch.Flush()
+	return g
+}
+
+// pickNations returns n nations: syntheticNations first, then the rest of
+// countryGeography by name, then numbered placeholders without geography
+func pickNations(n int) []CountryInfo {
+	names := make([]string, 0, len(countryGeography))
+	seen := make(map[string]bool)
+	for _, name := range syntheticNations {
+		names = append(names, name)
+		seen[name] = true
+	}
+	rest := make([]string, 0, len(countryGeography))
+	for code := range countryGeography {
+		if c, ok := ResolveCountry(code); ok && !seen[c.Name] {
+			seen[c.Name] = true
+			rest = append(rest, c.Name)
+		}
+	}
+	sort.Strings(rest)
+	names = append(names, rest...)
+
+	out := make([]CountryInfo, 0, n)
+	for i := 0; i < n; i++ {
+		if i < len(names) {
+			if info, ok := LookupCountry(names[i]); ok {
+				out = append(out, info)
+				continue
+			}
+		}
+		out = append(out, CountryInfo{Name: fmt.Sprintf("Nation %d", i+1)})
+	}
+	return out
+}
+
+// syntheticID turns a name into a node ID the way the seeders do
+func syntheticID(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", "_"))
+}
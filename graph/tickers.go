@@ -0,0 +1,143 @@
+package graph
+
+import "sort"
+
+// Price-driven features (market health, correlations, backtests, paper
+// trading) only see corporations with a ticker. TickerCoverage shows where
+// they are missing, and MissingTickers orders the gaps by how central each
+// company is, so lookups go to the companies shocks reach most first.
+
+// IndustryCoverage counts one industry's companies with and without a ticker
+type IndustryCoverage struct {
+	IndustryID string `json:"industry_id"` // Empty for companies in no industry
+	Name       string `json:"name"`
+	Companies  int    `json:"companies"`
+	WithTicker int    `json:"with_ticker"`
+}
+
+// Missing is the number of companies without a ticker
+func (c IndustryCoverage) Missing() int {
+	return c.Companies - c.WithTicker
+}
+
+// Share is the fraction of companies with a ticker
+func (c IndustryCoverage) Share() float64 {
+	if c.Companies == 0 {
+		return 0
+	}
+	return float64(c.WithTicker) / float64(c.Companies)
+}
+
+// TickerCoverage counts corporations with and without a ticker
+type TickerCoverage struct {
+	IndustryCoverage                    // Every corporation
+	Industries       []IndustryCoverage `json:"industries"` // Most missing first
+}
+
+// TickerCandidate is a corporation without a ticker
+type TickerCandidate struct {
+	NodeID     string  `json:"node_id"`
+	Name       string  `json:"name"`
+	Country    string  `json:"country,omitempty"` // Picks the exchange the lookup prefers
+	Degree     int     `json:"degree"`            // Edges in and out, industry membership aside
+	Centrality float64 `json:"centrality"`        // Summed weight of those edges
+}
+
+// TickerCoverage counts corporations with and without a ticker, overall and
+// per industry. A company in several industries counts in each.
+func (g *Graph) TickerCoverage() TickerCoverage {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	cov := TickerCoverage{IndustryCoverage: IndustryCoverage{Name: "All corporations"}}
+	byIndustry := make(map[string]*IndustryCoverage)
+	inIndustry := make(map[string]bool)
+	count := func(c *IndustryCoverage, n *Node) {
+		c.Companies++
+		if n.Ticker != "" {
+			c.WithTicker++
+		}
+	}
+	for _, e := range g.Edges {
+		ind, okInd := g.Nodes[e.SourceID]
+		c, okC := g.Nodes[e.TargetID]
+		if e.Type != EdgeTypeHasCompany || !okInd || !okC || ind.Type != NodeTypeIndustry || c.Type != NodeTypeCorporation {
+			continue
+		}
+		ic, ok := byIndustry[ind.ID]
+		if !ok {
+			ic = &IndustryCoverage{IndustryID: ind.ID, Name: ind.Name}
+			byIndustry[ind.ID] = ic
+		}
+		count(ic, c)
+		inIndustry[c.ID] = true
+	}
+	none := &IndustryCoverage{Name: "(no industry)"}
+	for _, n := range g.Nodes {
+		if n.Type != NodeTypeCorporation {
+			continue
+		}
+		count(&cov.IndustryCoverage, n)
+		if !inIndustry[n.ID] {
+			count(none, n)
+		}
+	}
+	if none.Companies > 0 {
+		byIndustry[""] = none
+	}
+
+	for _, ic := range byIndustry {
+		cov.Industries = append(cov.Industries, *ic)
+	}
+	sort.Slice(cov.Industries, func(i, j int) bool {
+		a, b := cov.Industries[i], cov.Industries[j]
+		if a.Missing() != b.Missing() {
+			return a.Missing() > b.Missing()
+		}
+		return a.Name < b.Name
+	})
+	return cov
+}
+
+// MissingTickers returns up to limit corporations without a ticker (0 = all),
+// most central first
+func (g *Graph) MissingTickers(limit int) []TickerCandidate {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	byID := make(map[string]*TickerCandidate)
+	for _, n := range g.Nodes {
+		if n.Type == NodeTypeCorporation && n.Ticker == "" {
+			byID[n.ID] = &TickerCandidate{NodeID: n.ID, Name: n.Name, Country: n.Country()}
+		}
+	}
+	for _, e := range g.Edges {
+		if e.Type == EdgeTypeHasCompany {
+			continue // Industry membership, not business ties
+		}
+		for _, id := range []string{e.SourceID, e.TargetID} {
+			if c, ok := byID[id]; ok {
+				c.Degree++
+				c.Centrality += e.Weight
+			}
+		}
+	}
+
+	out := make([]TickerCandidate, 0, len(byID))
+	for _, c := range byID {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Centrality != out[j].Centrality {
+			return out[i].Centrality > out[j].Centrality
+		}
+		if out[i].Degree != out[j].Degree {
+			return out[i].Degree > out[j].Degree
+		}
+		return out[i].NodeID < out[j].NodeID
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
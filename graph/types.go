@@ -0,0 +1,312 @@
+package graph
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The built-in node and edge types cover trade, finance and supply chains.
+// Other domains (shipping routes, labor unions, ...) declare their own in
+// config, with how shocks cross their edges; everything that handles types
+// by name (export, propagation, the server) then treats them like the
+// built-in ones.
+
+// builtinNodeTypes lists the node types the graph ships with
+var builtinNodeTypes = []NodeType{
+	NodeTypeNation, NodeTypeCorporation, NodeTypeProduct, NodeTypeIndustry, NodeTypeRawMaterial,
+	NodeTypeCrop, NodeTypeInfrastructure, NodeTypeCurrency, NodeTypeCentralBank,
+}
+
+// builtinEdgeTypes lists the edge types the graph ships with
+var builtinEdgeTypes = []EdgeType{
+	EdgeTypeSupplies, EdgeTypeProcuresFrom, EdgeTypeManufactures, EdgeTypeConsumes, EdgeTypeProduces,
+	EdgeTypeDependsOn, EdgeTypeRequires, EdgeTypeTrade, EdgeTypeCapital, EdgeTypeCompetesWith,
+	EdgeTypeSubstituteFor, EdgeTypeRegulatory, EdgeTypeHasIndustry, EdgeTypeHasCompany, EdgeTypeOwns,
+	EdgeTypeSubsidiaryOf, EdgeTypeRoutesThrough, EdgeTypeIssues, EdgeTypeUsesCurrency, EdgeTypeReportsIn,
+}
+
+// typeName is the form type names take, like the built-in ones
+var typeName = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// typeColorName is a hex color or a color name, which CSS and DOT share
+var typeColorName = regexp.MustCompile(`^(#[0-9A-Fa-f]{6}|[a-z]+)$`)
+
+// NodeTypeDef declares a node type beyond the built-in ones
+type NodeTypeDef struct {
+	Name        NodeType `json:"name"`
+	Color       string   `json:"color,omitempty"` // CSS color used by the dashboard and DOT export (empty = grey)
+	Description string   `json:"description,omitempty"`
+}
+
+// EdgeTypeDef declares an edge type beyond the built-in ones
+type EdgeTypeDef struct {
+	Name           EdgeType           `json:"name"`
+	Directionality EdgeDirectionality `json:"directionality"`        // How shocks cross it (empty = Bidirectional)
+	Propagation    float64            `json:"propagation,omitempty"` // Share of a shock it passes on (0 = the propagation model's default)
+	Description    string             `json:"description,omitempty"`
+}
+
+// customTypes holds the declared types
+var customTypes struct {
+	mu    sync.RWMutex
+	nodes []NodeTypeDef
+	edges []EdgeTypeDef
+}
+
+// DeclareTypes replaces the declared node and edge types. Every declaration
+// is checked before any applies: names must look like the built-in ones
+// (e.g. "ShippingRoute"), be unique and not shadow a built-in type, colors
+// must be "#rrggbb" or a name, and factors must be between 0 and 1.
+func DeclareTypes(nodes []NodeTypeDef, edges []EdgeTypeDef) error {
+	seen := make(map[string]bool)
+	for _, t := range builtinNodeTypes {
+		seen[string(t)] = true
+	}
+	for _, t := range builtinEdgeTypes {
+		seen[string(t)] = true
+	}
+	check := func(kind, name string) error {
+		switch {
+		case !typeName.MatchString(name):
+			return fmt.Errorf("%s type %q: names start with a capital letter and hold only letters and digits", kind, name)
+		case seen[name]:
+			return fmt.Errorf("%s type %q is already defined", kind, name)
+		}
+		seen[name] = true
+		return nil
+	}
+
+	nodes = append([]NodeTypeDef(nil), nodes...)
+	for _, d := range nodes {
+		if err := check("node", string(d.Name)); err != nil {
+			return err
+		}
+		if d.Color != "" && !typeColorName.MatchString(d.Color) {
+			return fmt.Errorf("node type %s: color %q is not #rrggbb or a color name", d.Name, d.Color)
+		}
+	}
+	edges = append([]EdgeTypeDef(nil), edges...)
+	for i := range edges {
+		d := &edges[i]
+		if err := check("edge", string(d.Name)); err != nil {
+			return err
+		}
+		dir, err := ParseDirectionality(string(d.Directionality))
+		if err != nil {
+			return fmt.Errorf("edge type %s: %w", d.Name, err)
+		}
+		d.Directionality = dir
+		if d.Propagation < 0 || d.Propagation > 1 {
+			return fmt.Errorf("edge type %s: propagation %.2f is outside [0, 1]", d.Name, d.Propagation)
+		}
+	}
+
+	customTypes.mu.Lock()
+	defer customTypes.mu.Unlock()
+	customTypes.nodes, customTypes.edges = nodes, edges
+	return nil
+}
+
+// ParseDirectionality reads a directionality by name, case-insensitively
+// ("" = Bidirectional)
+func ParseDirectionality(s string) (EdgeDirectionality, error) {
+	for _, d := range []EdgeDirectionality{DirectionalityUnidirectional, DirectionalityReverse, DirectionalityBidirectional} {
+		if strings.EqualFold(s, string(d)) {
+			return d, nil
+		}
+	}
+	if s == "" {
+		return DirectionalityBidirectional, nil
+	}
+	return "", fmt.Errorf("unknown directionality %q (want Unidirectional, Reverse or Bidirectional)", s)
+}
+
+// NodeTypes returns the built-in node types, then the declared ones
+func NodeTypes() []NodeType {
+	customTypes.mu.RLock()
+	defer customTypes.mu.RUnlock()
+	types := append([]NodeType(nil), builtinNodeTypes...)
+	for _, d := range customTypes.nodes {
+		types = append(types, d.Name)
+	}
+	return types
+}
+
+// EdgeTypes returns the built-in edge types, then the declared ones
+func EdgeTypes() []EdgeType {
+	customTypes.mu.RLock()
+	defer customTypes.mu.RUnlock()
+	types := append([]EdgeType(nil), builtinEdgeTypes...)
+	for _, d := range customTypes.edges {
+		types = append(types, d.Name)
+	}
+	return types
+}
+
+// DeclaredNodeTypes returns the declared node types
+func DeclaredNodeTypes() []NodeTypeDef {
+	customTypes.mu.RLock()
+	defer customTypes.mu.RUnlock()
+	return append([]NodeTypeDef(nil), customTypes.nodes...)
+}
+
+// DeclaredEdgeTypes returns the declared edge types
+func DeclaredEdgeTypes() []EdgeTypeDef {
+	customTypes.mu.RLock()
+	defer customTypes.mu.RUnlock()
+	return append([]EdgeTypeDef(nil), customTypes.edges...)
+}
+
+// KnownNodeType reports whether t is a built-in or declared node type
+func KnownNodeType(t NodeType) bool {
+	for _, known := range NodeTypes() {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
+
+// KnownEdgeType reports whether t is a built-in or declared edge type
+func KnownEdgeType(t EdgeType) bool {
+	for _, known := range EdgeTypes() {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
+
+// declaredNodeType returns the declaration of a node type, if declared
+func declaredNodeType(t NodeType) (NodeTypeDef, bool) {
+	customTypes.mu.RLock()
+	defer customTypes.mu.RUnlock()
+	for _, d := range customTypes.nodes {
+		if d.Name == t {
+			return d, true
+		}
+	}
+	return NodeTypeDef{}, false
+}
+
+// declaredEdgeType returns the declaration of an edge type, if declared
+func declaredEdgeType(t EdgeType) (EdgeTypeDef, bool) {
+	customTypes.mu.RLock()
+	defer customTypes.mu.RUnlock()
+	for _, d := range customTypes.edges {
+		if d.Name == t {
+			return d, true
+		}
+	}
+	return EdgeTypeDef{}, false
+}
+
+// ApplyDeclaredDirectionality gives every edge of a declared type its
+// declaration's directionality, so edges saved before a type was declared (or
+// redeclared) carry shocks as declared. It returns how many edges changed.
+func (g *Graph) ApplyDeclaredDirectionality() int {
+	declared := DeclaredEdgeTypes()
+	if len(declared) == 0 {
+		return 0
+	}
+	byType := make(map[EdgeType]EdgeDirectionality, len(declared))
+	for _, d := range declared {
+		byType[d.Name] = d.Directionality
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	changed := 0
+	for _, e := range g.Edges {
+		if dir, ok := byType[e.Type]; ok && e.Directionality != dir {
+			e.Directionality = dir
+			changed++
+		}
+	}
+	return changed
+}
+
+// Type origins
+const (
+	TypeBuiltin    = "builtin"
+	TypeDeclared   = "declared"
+	TypeUndeclared = "undeclared" // Only seen in the graph, e.g. added by a data source
+)
+
+// TypeInfo describes a node or edge type and how many nodes or edges have it
+type TypeInfo struct {
+	Name           string             `json:"name"`
+	Origin         string             `json:"origin"`
+	Count          int                `json:"count"`
+	Color          string             `json:"color,omitempty"`          // Node types
+	Directionality EdgeDirectionality `json:"directionality,omitempty"` // Edge types
+	Propagation    float64            `json:"propagation,omitempty"`    // Edge types: the graph's factor, between any node types
+	Description    string             `json:"description,omitempty"`
+}
+
+// NodeTypeInfo describes every built-in and declared node type, then those
+// only seen in the graph
+func (g *Graph) NodeTypeInfo() []TypeInfo {
+	counts := make(map[string]int)
+	g.NodesRange(func(n *Node) { counts[string(n.Type)]++ })
+
+	var out []TypeInfo
+	for _, t := range builtinNodeTypes {
+		out = append(out, TypeInfo{Name: string(t), Origin: TypeBuiltin, Count: counts[string(t)]})
+	}
+	for _, d := range DeclaredNodeTypes() {
+		out = append(out, TypeInfo{Name: string(d.Name), Origin: TypeDeclared, Count: counts[string(d.Name)], Color: d.Color, Description: d.Description})
+	}
+	return appendUndeclared(out, counts, nil)
+}
+
+// EdgeTypeInfo describes every built-in and declared edge type, then those
+// only seen in the graph
+func (g *Graph) EdgeTypeInfo() []TypeInfo {
+	counts := make(map[string]int)
+	g.EdgesRange(func(e *Edge) { counts[string(e.Type)]++ })
+	model := g.PropagationModel()
+	describe := func(t EdgeType) TypeInfo {
+		return TypeInfo{Name: string(t), Count: counts[string(t)], Directionality: GetEdgeDirectionality(t), Propagation: model.Factor(t, "", "")}
+	}
+
+	var out []TypeInfo
+	for _, t := range builtinEdgeTypes {
+		info := describe(t)
+		info.Origin = TypeBuiltin
+		out = append(out, info)
+	}
+	for _, d := range DeclaredEdgeTypes() {
+		info := describe(d.Name)
+		info.Origin, info.Description = TypeDeclared, d.Description
+		out = append(out, info)
+	}
+	return appendUndeclared(out, counts, func(name string) TypeInfo { return describe(EdgeType(name)) })
+}
+
+// appendUndeclared adds the counted types missing from out, sorted by name
+func appendUndeclared(out []TypeInfo, counts map[string]int, describe func(string) TypeInfo) []TypeInfo {
+	listed := make(map[string]bool, len(out))
+	for _, info := range out {
+		listed[info.Name] = true
+	}
+	var names []string
+	for name := range counts {
+		if !listed[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		info := TypeInfo{Name: name, Count: counts[name]}
+		if describe != nil {
+			info = describe(name)
+		}
+		info.Origin = TypeUndeclared
+		out = append(out, info)
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidGraph is wrapped by the error LoadStrict and Validate return
+var ErrInvalidGraph = errors.New("invalid graph")
+
+// maxReportedProblems bounds the problems listed in a validation error
+const maxReportedProblems = 10
+
+// Validate checks the graph for data a saved file shouldn't contain: nodes
+// without IDs or filed under another ID, edges without endpoints or type,
+// edges whose endpoints are missing, non-finite or negative weights and
+// health, unknown statuses, and duplicate edges. It returns nil if the graph
+// is valid.
+func (g *Graph) Validate() error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.validateLocked()
+}
+
+// validateLocked is Validate (must be called with lock held)
+func (g *Graph) validateLocked() error {
+	var problems []string
+	for key, n := range g.Nodes {
+		switch {
+		case n == nil:
+			problems = append(problems, fmt.Sprintf("node %q is null", key))
+		case n.ID == "":
+			problems = append(problems, fmt.Sprintf("node %q has no id", key))
+		case n.ID != key:
+			problems = append(problems, fmt.Sprintf("node %q is stored under %q", n.ID, key))
+		case math.IsNaN(n.Health) || math.IsInf(n.Health, 0):
+			problems = append(problems, fmt.Sprintf("node %q has health %v", n.ID, n.Health))
+		}
+	}
+	sort.Strings(problems) // Map order; keep reports stable
+
+	seen := make(map[string]bool, len(g.Edges))
+	for i, e := range g.Edges {
+		if e == nil {
+			problems = append(problems, fmt.Sprintf("edge %d is null", i))
+			continue
+		}
+		name := fmt.Sprintf("edge %d (%s -[%s]-> %s)", i, e.SourceID, e.Type, e.TargetID)
+		if e.SourceID == "" || e.TargetID == "" || e.Type == "" {
+			problems = append(problems, name+" is missing an endpoint or type")
+			continue
+		}
+		if _, ok := g.Nodes[e.SourceID]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: source node %q does not exist", name, e.SourceID))
+		}
+		if _, ok := g.Nodes[e.TargetID]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: target node %q does not exist", name, e.TargetID))
+		}
+		if math.IsNaN(e.Weight) || math.IsInf(e.Weight, 0) || e.Weight < 0 {
+			problems = append(problems, fmt.Sprintf("%s has weight %v", name, e.Weight))
+		}
+		if e.Status != "" && e.Status != EdgeStatusPruned {
+			if _, err := ParseEdgeStatus(string(e.Status)); err != nil {
+				problems = append(problems, fmt.Sprintf("%s has status %q", name, e.Status))
+			}
+		}
+		key := edgeKey(e.SourceID, e.TargetID, e.Type, e.Commodity())
+		if seen[key] {
+			problems = append(problems, name+" is a duplicate")
+		}
+		seen[key] = true
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	more := ""
+	if len(problems) > maxReportedProblems {
+		more = fmt.Sprintf("; and %d more", len(problems)-maxReportedProblems)
+		problems = problems[:maxReportedProblems]
+	}
+	return fmt.Errorf("%w: %s%s", ErrInvalidGraph, strings.Join(problems, "; "), more)
+}
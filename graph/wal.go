@@ -0,0 +1,208 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"margraf/clock"
+	"os"
+	"sync"
+	"time"
+)
+
+// Auto-save writes the whole graph every few changes, so a crash loses the
+// changes since the last save. The write-ahead log appends every change as
+// it happens, one JSON line each, numbered in order. After a crash the graph
+// is rebuilt by replaying the entries after the last save; other systems can
+// follow the same file as an ordered change stream.
+
+// DefaultWALMaxBytes is the size past which the log is rotated at the next save
+const DefaultWALMaxBytes = 64 << 20
+
+// WALEntry is one line of the write-ahead log: a change, or a checkpoint
+// recording that a save covered every change up to Through
+type WALEntry struct {
+	Seq        uint64    `json:"seq"`
+	Time       time.Time `json:"time"`
+	Delta      *Delta    `json:"delta,omitempty"`
+	Checkpoint string    `json:"checkpoint,omitempty"` // File saved
+	Through    uint64    `json:"through,omitempty"`    // Last change the saved file holds
+}
+
+// WAL is an append-only log of graph changes
+type WAL struct {
+	Path     string
+	MaxBytes int64 // Rotate to Path+".1" at a checkpoint past this size (0 = never)
+
+	mu   sync.Mutex
+	file *os.File
+	seq  uint64
+	size int64
+}
+
+// OpenWAL opens the log at path for appending, continuing its numbering
+func OpenWAL(path string) (*WAL, error) {
+	w := &WAL{Path: path, MaxBytes: DefaultWALMaxBytes}
+	for _, p := range []string{path, path + ".1"} {
+		err := ReadWAL(p, 0, func(e WALEntry) error {
+			w.seq = e.Seq
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if w.seq > 0 {
+			break // The current file continues the rotated one
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err == nil {
+		w.size = info.Size()
+	}
+	w.file = f
+	return w, nil
+}
+
+// Seq is the number of the last entry written
+func (w *WAL) Seq() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.seq
+}
+
+// Append writes a change. Each entry is one write to the file, so a crash of
+// the process loses nothing written; a power cut may lose what the OS had
+// not flushed.
+func (w *WAL) Append(d Delta) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeLocked(WALEntry{Delta: &d})
+}
+
+// Checkpoint records that file was saved with every change up to through,
+// rotating the log first when it has grown past MaxBytes
+func (w *WAL) Checkpoint(file string, through uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return fmt.Errorf("write-ahead log closed")
+	}
+	if w.MaxBytes > 0 && w.size > w.MaxBytes {
+		w.file.Close()
+		w.file = nil
+		if err := os.Rename(w.Path, w.Path+".1"); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(w.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		w.file, w.size = f, 0
+	}
+	if err := w.writeLocked(WALEntry{Checkpoint: file, Through: through}); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+func (w *WAL) writeLocked(e WALEntry) error {
+	if w.file == nil {
+		return fmt.Errorf("write-ahead log closed")
+	}
+	e.Seq = w.seq + 1
+	e.Time = clock.Now()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	n, err := w.file.Write(append(data, '\n'))
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	w.seq = e.Seq
+	return nil
+}
+
+// Close flushes and closes the log
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	w.file.Sync()
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// ReadWAL calls fn with each entry of the log at path numbered after after,
+// in order. A line cut short by a crash ends the log.
+func ReadWAL(path string, after uint64, fn func(WALEntry) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e WALEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			break
+		}
+		if e.Seq <= after {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// SetWAL logs every local change to w from now on, and checkpoints it when
+// the graph is saved to its auto-save file (nil stops logging)
+func (g *Graph) SetWAL(w *WAL) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.wal = w
+}
+
+// ReplayWAL applies the changes logged at path after the last checkpoint of
+// file, the graph file this graph was loaded from, and returns how many it
+// applied. Changes are applied as ApplyDelta does, so a change the file
+// already holds is applied again without harm.
+func (g *Graph) ReplayWAL(path, file string) (int, error) {
+	var pending []Delta
+	collect := func(e WALEntry) error {
+		switch {
+		case e.Checkpoint == file:
+			pending = pending[:0]
+		case e.Delta != nil:
+			pending = append(pending, *e.Delta)
+		}
+		return nil
+	}
+	// The rotated file holds the entries before the current one
+	if err := ReadWAL(path+".1", 0, collect); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	if err := ReadWAL(path, 0, collect); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	applied := 0
+	for _, d := range pending {
+		if err := g.ApplyDelta(d); err != nil {
+			continue // e.g. a health change for a node a later entry adds
+		}
+		applied++
+	}
+	return applied, nil
+}
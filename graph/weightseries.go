@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A weight series lays an edge's history out for plotting: one point per
+// recorded change, with the change split into the part decay explains and
+// the part its cause (a news event, a shock, normalization) added, so a chart
+// can show how a relationship strengthened or eroded without replaying the
+// decay formula itself.
+
+// Weight point causes
+const (
+	CauseCreated   = "created"   // The edge was added
+	CauseEvent     = "event"     // A news event or shock, named by EventID
+	CauseUpdate    = "update"    // Re-added or merged without an event
+	CauseDecay     = "decay"     // A temporal decay sweep
+	CauseNormalize = "normalize" // A normalization pass
+	CauseOverride  = "override"  // A status override was set, cleared or expired
+	CausePrune     = "prune"     // The edge was pruned
+)
+
+// seriesLambda is the daily decay UpdateEdgeWeight applies before an event
+const seriesLambda = 0.05
+
+// WeightPoint is one change in an edge's weight
+type WeightPoint struct {
+	Time        time.Time  `json:"time"`
+	Weight      float64    `json:"weight"`
+	Status      EdgeStatus `json:"status"`
+	Cause       string     `json:"cause"`
+	EventID     string     `json:"event_id,omitempty"`
+	Description string     `json:"description,omitempty"` // The event's headline or shock, while its impact is kept
+	Change      float64    `json:"change"`                // Weight less the previous point's
+	Decay       float64    `json:"decay"`                 // Part of Change from decay since the previous point
+	Event       float64    `json:"event"`                 // Rest of Change, from the cause
+	DecayOnly   float64    `json:"decay_only"`            // The first weight decayed to this time, as if nothing else had happened
+}
+
+// WeightSeries is an edge's weight history as a plottable series
+type WeightSeries struct {
+	SourceID  string        `json:"source_id"`
+	TargetID  string        `json:"target_id"`
+	Type      EdgeType      `json:"type"`
+	Commodity string        `json:"commodity,omitempty"`
+	Weight    float64       `json:"weight"` // Latest
+	Decay     float64       `json:"decay"`  // Sum of the points' Decay
+	Event     float64       `json:"event"`  // Sum of the points' Event
+	Points    []WeightPoint `json:"points"` // Oldest first
+}
+
+// WeightSeries returns the weight series of every edge from sourceID to
+// targetID that has a history, pruned edges included, ordered by type and
+// commodity
+func (g *Graph) WeightSeries(sourceID, targetID string) []WeightSeries {
+	g.mu.RLock()
+	var out []WeightSeries
+	for _, history := range g.EdgeHistories {
+		if history.SourceID != sourceID || history.TargetID != targetID || len(history.History) == 0 {
+			continue
+		}
+		out = append(out, weightSeries(history))
+	}
+	g.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return out[i].Type < out[j].Type
+		}
+		return out[i].Commodity < out[j].Commodity
+	})
+	for _, s := range out {
+		g.describeEvents(s.Points)
+	}
+	return out
+}
+
+// weightSeries builds one series (must be called with lock held)
+func weightSeries(history *EdgeHistory) WeightSeries {
+	s := WeightSeries{
+		SourceID:  history.SourceID,
+		TargetID:  history.TargetID,
+		Type:      history.Type,
+		Commodity: history.Commodity,
+		Points:    make([]WeightPoint, 0, len(history.History)),
+	}
+	first := history.History[0]
+	for i, snap := range history.History {
+		p := WeightPoint{
+			Time:      snap.Timestamp,
+			Weight:    snap.Weight,
+			Status:    snap.Status,
+			Cause:     weightCause(snap, i == 0),
+			EventID:   snap.EventID,
+			DecayOnly: first.Weight * math.Exp(-seriesLambda*days(snap.Timestamp.Sub(first.Timestamp))),
+		}
+		if p.Cause != CauseEvent && p.Cause != CausePrune {
+			p.EventID = "" // Maintenance passes record a fixed name, not an event
+		}
+		if i > 0 {
+			prev := history.History[i-1]
+			p.Change = snap.Weight - prev.Weight
+			switch p.Cause {
+			case CauseDecay:
+				p.Decay = p.Change
+			case CauseEvent:
+				// UpdateEdgeWeight decays the old weight over the gap, then adds the event
+				expected := prev.Weight * math.Exp(-seriesLambda*days(snap.Timestamp.Sub(prev.Timestamp)))
+				p.Decay = expected - prev.Weight
+				p.Event = snap.Weight - expected
+			default:
+				p.Event = p.Change
+			}
+		}
+		s.Decay += p.Decay
+		s.Event += p.Event
+		s.Points = append(s.Points, p)
+	}
+	s.Weight = s.Points[len(s.Points)-1].Weight
+	return s
+}
+
+// weightCause names what made a history snapshot
+func weightCause(snap EdgeSnapshot, first bool) string {
+	switch {
+	case snap.Status == EdgeStatusPruned:
+		return CausePrune
+	case snap.EventID == "temporal_decay":
+		return CauseDecay
+	case snap.EventID == "normalize":
+		return CauseNormalize
+	case snap.EventID == eventOverrideSet, snap.EventID == eventOverrideCleared, snap.EventID == eventOverrideExpired:
+		return CauseOverride
+	case first:
+		return CauseCreated
+	case snap.EventID == "":
+		return CauseUpdate
+	}
+	return CauseEvent
+}
+
+// days converts a duration to days, never negative
+func days(d time.Duration) float64 {
+	return math.Max(0, d.Hours()/24)
+}
+
+// describeEvents names the event behind each event point from the kept
+// impacts. Shock propagation records derived IDs ("<id>_reverse",
+// "<id>_2nd_<node>", ...), which take their event's description.
+func (g *Graph) describeEvents(points []WeightPoint) {
+	descriptions := make(map[string]string)
+	for _, imp := range g.Impacts() {
+		if _, ok := descriptions[imp.EventID]; !ok && imp.Description != "" {
+			descriptions[imp.EventID] = imp.Description
+		}
+	}
+	if len(descriptions) == 0 {
+		return
+	}
+	for i := range points {
+		p := &points[i]
+		if p.Cause != CauseEvent {
+			continue
+		}
+		if d, ok := descriptions[p.EventID]; ok {
+			p.Description = d
+			continue
+		}
+		best := ""
+		for id, d := range descriptions {
+			if len(id) > len(best) && strings.HasPrefix(p.EventID, id+"_") {
+				best, p.Description = id, d
+			}
+		}
+	}
+}
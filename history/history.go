@@ -0,0 +1,394 @@
+// Package history keeps an end-of-day record of the graph: each node's
+// health, price and stress score and each edge's weight and status, one row
+// per day. The live graph only holds current values (and short per-item
+// histories), so this is the longitudinal dataset for calibrating models,
+// event studies and dashboards.
+//
+// Each day is a directory named for its date holding two CSV files,
+// nodes.csv and edges.csv, whose first column is the day. A day appears
+// whole or not at all, is never rewritten, and queries read only the days
+// they cover. CSV rather than SQLite or Parquet keeps margraf free of cgo
+// and of another dependency; the files load directly into DuckDB (which
+// also writes them out as Parquet), SQLite (".import --csv") or pandas.
+package history
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"margraf/clock"
+	"margraf/graph"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DateLayout is the form of the date column, a day in the display timezone
+const DateLayout = "2006-01-02"
+
+// File names within a day's directory
+const (
+	nodesFile = "nodes.csv"
+	edgesFile = "edges.csv"
+	dayFile   = "day.json" // The day's Day, with its row counts
+)
+
+var (
+	nodeHeader = []string{"date", "node_id", "name", "type", "health", "price", "currency", "stress"}
+	edgeHeader = []string{"date", "source_id", "target_id", "type", "hs_code", "weight", "status"}
+)
+
+// ErrRecorded is returned by Append for a day already in the store
+var ErrRecorded = errors.New("day already recorded")
+
+// NodeRow is a node's end-of-day state
+type NodeRow struct {
+	Date     string         `json:"date"`
+	NodeID   string         `json:"node_id"`
+	Name     string         `json:"name"`
+	Type     graph.NodeType `json:"type"`
+	Health   float64        `json:"health"`
+	Price    float64        `json:"price,omitempty"` // 0 = no quote
+	Currency string         `json:"currency,omitempty"`
+	Stress   float64        `json:"stress"` // Early-warning score, 0 to 1
+}
+
+// EdgeRow is an edge's end-of-day state
+type EdgeRow struct {
+	Date     string           `json:"date"`
+	SourceID string           `json:"source_id"`
+	TargetID string           `json:"target_id"`
+	Type     graph.EdgeType   `json:"type"`
+	HSCode   string           `json:"hs_code,omitempty"` // Commodity edges only
+	Weight   float64          `json:"weight"`
+	Status   graph.EdgeStatus `json:"status"`
+}
+
+// Capture reads every node and edge of g as rows for the day at falls on.
+// stress holds the nodes' stress scores; nodes missing from it score 0.
+func Capture(g *graph.Graph, stress map[string]float64, at time.Time) ([]NodeRow, []EdgeRow) {
+	date := clock.Format(at, DateLayout)
+	var nodes []NodeRow
+	g.NodesRange(func(n *graph.Node) {
+		nodes = append(nodes, NodeRow{
+			Date:     date,
+			NodeID:   n.ID,
+			Name:     n.Name,
+			Type:     n.Type,
+			Health:   n.Health,
+			Price:    n.Price,
+			Currency: n.Currency,
+			Stress:   stress[n.ID],
+		})
+	})
+	var edges []EdgeRow
+	g.EdgesRange(func(e *graph.Edge) {
+		edges = append(edges, EdgeRow{
+			Date:     date,
+			SourceID: e.SourceID,
+			TargetID: e.TargetID,
+			Type:     e.Type,
+			HSCode:   e.Commodity(),
+			Weight:   e.Weight,
+			Status:   e.Status,
+		})
+	})
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].NodeID < nodes[j].NodeID })
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.SourceID != b.SourceID {
+			return a.SourceID < b.SourceID
+		}
+		if a.TargetID != b.TargetID {
+			return a.TargetID < b.TargetID
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.HSCode < b.HSCode
+	})
+	return nodes, edges
+}
+
+// Store is a directory of end-of-day rows
+type Store struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewStore creates a store in dir, which is made on the first Append
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// Append adds one day's rows. Every row must carry the same date, later than
+// any already recorded; a day already in the store returns ErrRecorded. The
+// day's files are written to a temporary directory renamed into place last,
+// so a run cut short leaves nothing behind and the next run records the day.
+func (s *Store) Append(nodes []NodeRow, edges []EdgeRow) (string, error) {
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("no nodes to record")
+	}
+	date := nodes[0].Date
+	if _, err := time.Parse(DateLayout, date); err != nil {
+		return "", fmt.Errorf("invalid date %q", date)
+	}
+	for _, r := range nodes {
+		if r.Date != date {
+			return "", fmt.Errorf("rows of several days (%s and %s)", date, r.Date)
+		}
+	}
+	for _, r := range edges {
+		if r.Date != date {
+			return "", fmt.Errorf("rows of several days (%s and %s)", date, r.Date)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", err
+	}
+	dates, err := s.dates()
+	if err != nil {
+		return "", err
+	}
+	if n := len(dates); n > 0 && date <= dates[n-1] {
+		if date == dates[n-1] {
+			return "", fmt.Errorf("%s: %w", date, ErrRecorded)
+		}
+		return "", fmt.Errorf("%s is before the last day recorded, %s", date, dates[n-1])
+	}
+
+	tmp := filepath.Join(s.Dir, "."+date+".tmp")
+	if err := os.RemoveAll(tmp); err != nil { // Left by a failed run
+		return "", err
+	}
+	if err := os.Mkdir(tmp, 0755); err != nil {
+		return "", err
+	}
+	nodeRecords := make([][]string, len(nodes))
+	for i, r := range nodes {
+		nodeRecords[i] = []string{r.Date, r.NodeID, r.Name, string(r.Type), formatFloat(r.Health), formatFloat(r.Price), r.Currency, formatFloat(r.Stress)}
+	}
+	edgeRecords := make([][]string, len(edges))
+	for i, r := range edges {
+		edgeRecords[i] = []string{r.Date, r.SourceID, r.TargetID, string(r.Type), r.HSCode, formatFloat(r.Weight), string(r.Status)}
+	}
+	day, _ := json.Marshal(Day{Date: date, Nodes: len(nodes), Edges: len(edges)})
+	err = writeCSV(filepath.Join(tmp, nodesFile), nodeHeader, nodeRecords)
+	if err == nil {
+		err = writeCSV(filepath.Join(tmp, edgesFile), edgeHeader, edgeRecords)
+	}
+	if err == nil {
+		err = writeFile(filepath.Join(tmp, dayFile), day)
+	}
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(s.Dir, date))
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	return date, nil
+}
+
+// Query selects rows. Empty fields match everything; Since and Until are
+// inclusive dates in DateLayout.
+type Query struct {
+	NodeID   string // Nodes: this node. Edges: either end.
+	SourceID string // Edges only
+	TargetID string // Edges only
+	Since    string
+	Until    string
+}
+
+// inRange reports whether date falls within the query's dates
+func (q Query) inRange(date string) bool {
+	return (q.Since == "" || date >= q.Since) && (q.Until == "" || date <= q.Until)
+}
+
+// Nodes returns the node rows matching q, oldest first
+func (s *Store) Nodes(q Query) ([]NodeRow, error) {
+	var rows []NodeRow
+	err := s.scan(q, nodesFile, len(nodeHeader), func(rec []string) {
+		if !q.inRange(rec[0]) || q.NodeID != "" && rec[1] != q.NodeID {
+			return
+		}
+		rows = append(rows, NodeRow{
+			Date:     rec[0],
+			NodeID:   rec[1],
+			Name:     rec[2],
+			Type:     graph.NodeType(rec[3]),
+			Health:   parseFloat(rec[4]),
+			Price:    parseFloat(rec[5]),
+			Currency: rec[6],
+			Stress:   parseFloat(rec[7]),
+		})
+	})
+	return rows, err
+}
+
+// Edges returns the edge rows matching q, oldest first
+func (s *Store) Edges(q Query) ([]EdgeRow, error) {
+	var rows []EdgeRow
+	err := s.scan(q, edgesFile, len(edgeHeader), func(rec []string) {
+		switch {
+		case !q.inRange(rec[0]),
+			q.NodeID != "" && rec[1] != q.NodeID && rec[2] != q.NodeID,
+			q.SourceID != "" && rec[1] != q.SourceID,
+			q.TargetID != "" && rec[2] != q.TargetID:
+			return
+		}
+		rows = append(rows, EdgeRow{
+			Date:     rec[0],
+			SourceID: rec[1],
+			TargetID: rec[2],
+			Type:     graph.EdgeType(rec[3]),
+			HSCode:   rec[4],
+			Weight:   parseFloat(rec[5]),
+			Status:   graph.EdgeStatus(rec[6]),
+		})
+	})
+	return rows, err
+}
+
+// Day is one recorded day and how many rows it holds
+type Day struct {
+	Date  string `json:"date"`
+	Nodes int    `json:"nodes"`
+	Edges int    `json:"edges"`
+}
+
+// Days lists the recorded days, oldest first
+func (s *Store) Days() ([]Day, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dates, err := s.dates()
+	if err != nil {
+		return nil, err
+	}
+	days := make([]Day, 0, len(dates))
+	for _, date := range dates {
+		data, err := os.ReadFile(filepath.Join(s.Dir, date, dayFile))
+		if err != nil {
+			return nil, err
+		}
+		var d Day
+		if err := json.Unmarshal(data, &d); err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", date, dayFile, err)
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// dates returns the recorded days, oldest first (must be called with mu
+// held). A missing directory has none.
+func (s *Store) dates() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var dates []string
+	for _, e := range entries { // Sorted by name, so by date
+		if _, err := time.Parse(DateLayout, e.Name()); e.IsDir() && err == nil {
+			dates = append(dates, e.Name())
+		}
+	}
+	return dates, nil
+}
+
+// scan calls fn with each record of a store file over the days q covers,
+// oldest first, skipping the headers
+func (s *Store) scan(q Query, name string, fields int, fn func([]string)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dates, err := s.dates()
+	if err != nil {
+		return err
+	}
+	for _, date := range dates {
+		if !q.inRange(date) {
+			continue
+		}
+		if err := scanFile(filepath.Join(s.Dir, date, name), fields, fn); err != nil {
+			return fmt.Errorf("%s/%s: %w", date, name, err)
+		}
+	}
+	return nil
+}
+
+// scanFile calls fn with each record of the CSV file at path but the header
+func scanFile(path string, fields int, fn func([]string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = fields
+	r.ReuseRecord = true
+	for first := true; ; first = false {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !first {
+			fn(rec)
+		}
+	}
+}
+
+// writeCSV writes header and records to a new file at path
+func writeCSV(path string, header []string, records [][]string) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(header)
+	w.WriteAll(records) // Flushes
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return writeFile(path, buf.Bytes())
+}
+
+// writeFile writes data to a new file at path and syncs it to disk, so the
+// rename that commits a day never exposes a file still in the page cache
+func writeFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
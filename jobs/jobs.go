@@ -0,0 +1,227 @@
+// Package jobs runs maintenance on a cron-like schedule: nightly decay and
+// pruning, weekly data refreshes, monthly re-seeding, a daily digest. Each run
+// is a background task (see package task) and is recorded with its outcome
+// and duration.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"margraf/clock"
+	"margraf/logger"
+	"margraf/task"
+	"sync"
+	"time"
+)
+
+// maxRuns bounds how many past runs are kept per job
+const maxRuns = 20
+
+// Func does a job's work and returns a one-line summary of what it did
+type Func func(ctx context.Context, t *task.Task) (string, error)
+
+// Run is one finished run of a job
+type Run struct {
+	Trigger  string        `json:"trigger"` // "schedule" or "manual"
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	State    task.State    `json:"state"`
+	Summary  string        `json:"summary,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Status is a snapshot of a job, safe to serialize
+type Status struct {
+	Name    string    `json:"name"`
+	Spec    string    `json:"spec"`          // Empty = manual only
+	Next    time.Time `json:"next,omitzero"` // Zero when manual only
+	Running bool      `json:"running"`
+	Runs    []Run     `json:"runs,omitempty"` // Oldest first
+}
+
+// Last returns the job's latest run, if it has run
+func (s Status) Last() (Run, bool) {
+	if len(s.Runs) == 0 {
+		return Run{}, false
+	}
+	return s.Runs[len(s.Runs)-1], true
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	timeout  time.Duration
+	fn       Func
+	next     time.Time
+	running  bool
+	runs     []Run
+}
+
+// Scheduler runs registered jobs when their schedule comes due. A job still
+// running when it comes due again is skipped rather than run twice.
+type Scheduler struct {
+	Enabled func(name string) bool // Consulted before scheduled runs (optional)
+
+	mu   sync.Mutex
+	jobs []*job
+}
+
+// NewScheduler creates an empty scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add registers a job. An empty spec (or "off") registers it for manual
+// runs only.
+func (s *Scheduler) Add(name, spec string, timeout time.Duration, fn Func) error {
+	j := &job{name: name, timeout: timeout, fn: fn}
+	if spec != "" && spec != "off" {
+		schedule, err := Parse(spec)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", name, err)
+		}
+		j.spec, j.schedule = spec, schedule
+		j.next = schedule.Next(clock.Local(time.Now()))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.jobs {
+		if other.name == name {
+			return fmt.Errorf("job %s already registered", name)
+		}
+	}
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// Start checks for due jobs every 30 seconds until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.runDue(now)
+			}
+		}
+	}()
+}
+
+// runDue starts every job whose next run is at or before now
+func (s *Scheduler) runDue(now time.Time) {
+	s.mu.Lock()
+	var due []*job
+	for _, j := range s.jobs {
+		if j.spec == "" || j.next.IsZero() || now.Before(j.next) {
+			continue
+		}
+		j.next = j.schedule.Next(clock.Local(now))
+		if j.running {
+			logger.Warn(logger.StatusWarn, "Job %s is still running; skipping this run", j.name)
+			continue
+		}
+		due = append(due, j)
+	}
+	s.mu.Unlock()
+
+	for _, j := range due {
+		if s.Enabled != nil && !s.Enabled(j.name) {
+			continue
+		}
+		s.start(j, "schedule")
+	}
+}
+
+// RunNow starts a job at once, whatever its schedule
+func (s *Scheduler) RunNow(name string) (*task.Task, error) {
+	s.mu.Lock()
+	var found *job
+	for _, j := range s.jobs {
+		if j.name == name {
+			found = j
+		}
+	}
+	s.mu.Unlock()
+	if found == nil {
+		return nil, fmt.Errorf("job %s not found", name)
+	}
+	t := s.start(found, "manual")
+	if t == nil {
+		return nil, fmt.Errorf("job %s is already running", name)
+	}
+	return t, nil
+}
+
+// start runs j as a task and records the run, unless it is already running
+func (s *Scheduler) start(j *job, trigger string) *task.Task {
+	s.mu.Lock()
+	if j.running {
+		s.mu.Unlock()
+		return nil
+	}
+	j.running = true
+	s.mu.Unlock()
+
+	logger.Info(logger.StatusInit, "Job %s started (%s)", j.name, trigger)
+	return task.StartTimeout("job "+j.name, j.timeout, func(ctx context.Context, t *task.Task) error {
+		run := Run{Trigger: trigger, Started: clock.Now()}
+		summary, err := j.fn(ctx, t)
+		run.Duration = time.Since(run.Started)
+		run.Summary = summary
+
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			run.State = task.StateFailed
+			run.Error = fmt.Sprintf("timed out after %v", j.timeout)
+		case ctx.Err() != nil:
+			run.State = task.StateCancelled
+		case err != nil:
+			run.State = task.StateFailed
+			run.Error = err.Error()
+		default:
+			run.State = task.StateDone
+		}
+
+		s.mu.Lock()
+		j.running = false
+		j.runs = append(j.runs, run)
+		if len(j.runs) > maxRuns {
+			j.runs = j.runs[len(j.runs)-maxRuns:]
+		}
+		s.mu.Unlock()
+
+		switch run.State {
+		case task.StateDone:
+			logger.Success("Job %s done in %v: %s", j.name, run.Duration.Round(time.Second), summary)
+		case task.StateFailed:
+			logger.Warn(logger.StatusWarn, "Job %s failed after %v: %s", j.name, run.Duration.Round(time.Second), run.Error)
+		default:
+			logger.Warn(logger.StatusWarn, "Job %s %s after %v", j.name, run.State, run.Duration.Round(time.Second))
+		}
+		return err
+	})
+}
+
+// List returns every job in registration order
+func (s *Scheduler) List() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		out = append(out, Status{
+			Name:    j.name,
+			Spec:    j.spec,
+			Next:    j.next,
+			Running: j.running,
+			Runs:    append([]Run(nil), j.runs...),
+		})
+	}
+	return out
+}
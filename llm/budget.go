@@ -0,0 +1,221 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"margraf/clock"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Consumers of the LLM, each with its own daily budget. Calls are tagged with
+// WithConsumer; untagged calls count against ConsumerOther.
+const (
+	ConsumerSeeder  = "seeder"  // Seeding, relationship discovery, nation expansion
+	ConsumerNews    = "news"    // Headline analysis
+	ConsumerSocial  = "social"  // Social post sentiment
+	ConsumerQueries = "queries" // Node descriptions and other on-demand questions
+	ConsumerOther   = "other"   // Anything untagged, e.g. climate estimates
+)
+
+// What a consumer does once its budget is spent
+const (
+	OnExhaustedDegrade = "degrade" // Fail fast; the caller falls back (cached answer, lexicon, ...)
+	OnExhaustedQueue   = "queue"   // Wait for the next day's budget, or until ctx ends
+)
+
+// ErrBudgetExhausted is returned (wrapped) when a consumer has used its
+// daily budget and no cached answer exists for the prompt
+var ErrBudgetExhausted = errors.New("daily LLM budget exhausted")
+
+// maxCachedAnswers bounds the answers kept for exhausted consumers
+const maxCachedAnswers = 500
+
+// Limit is one consumer's daily allowance (0 = unlimited)
+type Limit struct {
+	Requests    int
+	Tokens      int    // Prompt and reply, estimated at four characters per token
+	OnExhausted string // OnExhaustedDegrade (default) or OnExhaustedQueue
+}
+
+// BudgetUsage is one consumer's use of today's budget
+type BudgetUsage struct {
+	Consumer string `json:"consumer"`
+	Requests int    `json:"requests"`
+	Tokens   int    `json:"tokens"`
+	Limit    Limit  `json:"limit"`
+	Denied   int    `json:"denied"` // Calls refused or served from cache
+	Queued   int    `json:"queued"` // Calls waiting for the next day
+}
+
+// Exhausted reports whether the consumer has no requests or tokens left
+func (u BudgetUsage) Exhausted() bool {
+	return (u.Limit.Requests > 0 && u.Requests >= u.Limit.Requests) ||
+		(u.Limit.Tokens > 0 && u.Tokens >= u.Limit.Tokens)
+}
+
+// Budget allocates daily LLM requests and tokens per consumer. Budgets reset
+// at local midnight. Answers to recent prompts are kept so an exhausted
+// consumer can still get a reply it has seen before.
+type Budget struct {
+	mu      sync.Mutex
+	limits  map[string]Limit
+	usage   map[string]*BudgetUsage
+	day     string
+	answers map[[32]byte]string
+	order   [][32]byte // Cached prompts, oldest first
+}
+
+// NewBudget creates a budget with the given limits, keyed by consumer;
+// consumers not listed are unlimited
+func NewBudget(limits map[string]Limit) *Budget {
+	return &Budget{
+		limits:  limits,
+		usage:   make(map[string]*BudgetUsage),
+		answers: make(map[[32]byte]string),
+	}
+}
+
+var (
+	sharedMu sync.RWMutex
+	shared   *Budget
+)
+
+// SetBudget makes b the budget of clients NewClient creates from now on, so
+// every client in the process draws on the same allowance
+func SetBudget(b *Budget) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	shared = b
+}
+
+// CurrentBudget returns the budget set with SetBudget (nil = unlimited)
+func CurrentBudget() *Budget {
+	sharedMu.RLock()
+	defer sharedMu.RUnlock()
+	return shared
+}
+
+type consumerKey struct{}
+
+// WithConsumer tags ctx so LLM calls made with it count against consumer
+func WithConsumer(ctx context.Context, consumer string) context.Context {
+	return context.WithValue(ctx, consumerKey{}, consumer)
+}
+
+// ConsumerOf returns the consumer ctx is tagged with
+func ConsumerOf(ctx context.Context) string {
+	if c, ok := ctx.Value(consumerKey{}).(string); ok && c != "" {
+		return c
+	}
+	return ConsumerOther
+}
+
+// EstimateTokens approximates the tokens in s
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// usageLocked returns consumer's usage for today, starting a new day when
+// the date has changed (must be called with mu held)
+func (b *Budget) usageLocked(consumer string) *BudgetUsage {
+	if today := clock.Now().Format("2006-01-02"); today != b.day {
+		b.day = today
+		b.usage = make(map[string]*BudgetUsage)
+	}
+	u, ok := b.usage[consumer]
+	if !ok {
+		u = &BudgetUsage{Consumer: consumer, Limit: b.limits[consumer]}
+		b.usage[consumer] = u
+	}
+	return u
+}
+
+// Reserve books one request for the consumer ctx is tagged with. When its
+// budget is spent it returns a cached answer for prompt if there is one,
+// waits for the next day if the consumer queues, or fails with
+// ErrBudgetExhausted.
+func (b *Budget) Reserve(ctx context.Context, prompt string) (cached string, ok bool, err error) {
+	consumer := ConsumerOf(ctx)
+	var waiting *BudgetUsage // The usage counting this call as queued
+	defer func() {
+		if waiting != nil {
+			b.mu.Lock()
+			waiting.Queued--
+			b.mu.Unlock()
+		}
+	}()
+	for {
+		b.mu.Lock()
+		u := b.usageLocked(consumer)
+		if !u.Exhausted() {
+			u.Requests++
+			u.Tokens += EstimateTokens(prompt)
+			b.mu.Unlock()
+			return "", false, nil
+		}
+		if answer, hit := b.answers[sha256.Sum256([]byte(prompt))]; hit {
+			u.Denied++
+			b.mu.Unlock()
+			return answer, true, nil
+		}
+		if u.Limit.OnExhausted != OnExhaustedQueue {
+			u.Denied++
+			b.mu.Unlock()
+			return "", false, fmt.Errorf("%w for %s (%d requests, %d tokens)", ErrBudgetExhausted, consumer, u.Requests, u.Tokens)
+		}
+		if waiting != u {
+			if waiting != nil {
+				waiting.Queued--
+			}
+			u.Queued++
+			waiting = u
+		}
+		b.mu.Unlock()
+
+		now := clock.Now()
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		select {
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		case <-time.After(time.Until(midnight)):
+		}
+	}
+}
+
+// Record adds a reply's tokens to the consumer's usage and caches it as the
+// answer to prompt
+func (b *Budget) Record(ctx context.Context, prompt, reply string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.usageLocked(ConsumerOf(ctx)).Tokens += EstimateTokens(reply)
+
+	key := sha256.Sum256([]byte(prompt))
+	if _, ok := b.answers[key]; !ok {
+		b.order = append(b.order, key)
+		if len(b.order) > maxCachedAnswers {
+			delete(b.answers, b.order[0])
+			b.order = b.order[1:]
+		}
+	}
+	b.answers[key] = reply
+}
+
+// Usage returns today's usage of every consumer with a limit or any calls,
+// sorted by consumer
+func (b *Budget) Usage() []BudgetUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for consumer := range b.limits {
+		b.usageLocked(consumer)
+	}
+	usage := make([]BudgetUsage, 0, len(b.usage))
+	for _, u := range b.usage {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Consumer < usage[j].Consumer })
+	return usage
+}
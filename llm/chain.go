@@ -0,0 +1,252 @@
+package llm
+
+import (
+	"fmt"
+	"margraf/config"
+	"margraf/logger"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider kinds
+const (
+	ProviderOpenRouter = "openrouter" // OpenRouter, or any OpenAI-compatible chat completions API
+	ProviderGemini     = "gemini"     // Google Gemini
+	ProviderOllama     = "ollama"     // A local Ollama server, through its OpenAI-compatible API
+)
+
+// Provider health
+const (
+	healthWindow = 20               // Recent calls a provider's failure rate covers
+	healthTTL    = 10 * time.Minute // Calls older than this no longer count
+)
+
+// chain is an ordered list of providers that take over from one another.
+// Completions go to the provider with the lowest recent failure rate; ties
+// keep the configured order, so the first configured provider leads while
+// it is healthy and regains the lead once its failures age out.
+type chain struct {
+	mu         sync.Mutex
+	configured []*Client
+	order      []*Client
+	outcomes   map[*Client][]outcome // Recent calls per provider, oldest first
+}
+
+// outcome is one call's result
+type outcome struct {
+	at time.Time
+	ok bool
+}
+
+// ProviderHealth is one provider's standing in the chain
+type ProviderHealth struct {
+	Provider    string  `json:"provider"`
+	Model       string  `json:"model"`
+	Calls       int     `json:"calls"` // Within the health window
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failure_rate"`
+	CircuitOpen bool    `json:"circuit_open"`
+}
+
+func newChain(providers []*Client) *chain {
+	ch := &chain{
+		configured: providers,
+		order:      append([]*Client(nil), providers...),
+		outcomes:   make(map[*Client][]outcome),
+	}
+	for _, p := range providers {
+		p.chain = ch
+	}
+	return ch
+}
+
+// ordered returns the providers healthiest first
+func (ch *chain) ordered() []*Client {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.reorderLocked(time.Now())
+	return append([]*Client(nil), ch.order...)
+}
+
+// record adds a call's result to p's health
+func (ch *chain) record(p *Client, ok bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	list := append(ch.outcomes[p], outcome{at: time.Now(), ok: ok})
+	if len(list) > healthWindow {
+		list = list[len(list)-healthWindow:]
+	}
+	ch.outcomes[p] = list
+	ch.reorderLocked(time.Now())
+}
+
+// failureRateLocked is p's share of failed calls within healthTTL, dropping
+// older ones (must be called with mu held)
+func (ch *chain) failureRateLocked(p *Client, now time.Time) (calls, failures int) {
+	list := ch.outcomes[p]
+	for len(list) > 0 && now.Sub(list[0].at) > healthTTL {
+		list = list[1:]
+	}
+	ch.outcomes[p] = list
+	for _, o := range list {
+		if !o.ok {
+			failures++
+		}
+	}
+	return len(list), failures
+}
+
+// reorderLocked sorts the providers by failure rate, logging a change of
+// leader (must be called with mu held)
+func (ch *chain) reorderLocked(now time.Time) {
+	rates := make(map[*Client]float64, len(ch.configured))
+	for _, p := range ch.configured {
+		if calls, failures := ch.failureRateLocked(p, now); calls > 0 {
+			rates[p] = float64(failures) / float64(calls)
+		}
+	}
+	leader := ch.order[0]
+	ch.order = append(ch.order[:0], ch.configured...)
+	sort.SliceStable(ch.order, func(i, j int) bool { return rates[ch.order[i]] < rates[ch.order[j]] })
+	if ch.order[0] != leader {
+		logger.Warn(logger.StatusWarn, "LLM failover: %s now leads (%.0f%% recent failures), %s at %.0f%%",
+			ch.order[0].name(), rates[ch.order[0]]*100, leader.name(), rates[leader]*100)
+	}
+}
+
+// health reports every provider, healthiest first
+func (ch *chain) health() []ProviderHealth {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	now := time.Now()
+	ch.reorderLocked(now)
+	out := make([]ProviderHealth, 0, len(ch.order))
+	for _, p := range ch.order {
+		calls, failures := ch.failureRateLocked(p, now)
+		h := ProviderHealth{Provider: p.Provider, Model: p.Model, Calls: calls, Failures: failures, CircuitOpen: p.circuitOpen}
+		if calls > 0 {
+			h.FailureRate = float64(failures) / float64(calls)
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// providers returns the clients to try in turn: the chain, healthiest first,
+// or c alone
+func (c *Client) providers() []*Client {
+	if c.chain == nil {
+		return []*Client{c}
+	}
+	return c.chain.ordered()
+}
+
+// recordOutcome adds a call's result to the chain's health scores
+func (c *Client) recordOutcome(ok bool) {
+	if c.chain != nil {
+		c.chain.record(c, ok)
+	}
+}
+
+// Providers reports the health of each provider in the failover chain, in
+// the order they are tried
+func (c *Client) Providers() []ProviderHealth {
+	if c.chain == nil {
+		return []ProviderHealth{{Provider: c.Provider, Model: c.Model, CircuitOpen: c.circuitOpen}}
+	}
+	return c.chain.health()
+}
+
+// name identifies a provider in logs
+func (c *Client) name() string {
+	return c.Provider + " (" + c.Model + ")"
+}
+
+// providerDefaults are each kind's model, endpoint, key variable and
+// embedding model when the config leaves them out
+var providerDefaults = map[string]config.LLMProvider{
+	ProviderOpenRouter: {
+		Model:      "x-ai/grok-beta",
+		BaseURL:    "https://openrouter.ai/api/v1/chat/completions",
+		APIKeyEnv:  "OPENROUTER_API_KEY",
+		EmbedModel: "openai/text-embedding-3-small",
+	},
+	ProviderGemini: {
+		Model:      "gemini-1.5-flash",
+		BaseURL:    "https://generativelanguage.googleapis.com/v1beta/models",
+		APIKeyEnv:  "GEMINI_API_KEY",
+		EmbedModel: "text-embedding-004",
+	},
+	ProviderOllama: {
+		Model:      "llama3.1",
+		BaseURL:    "http://localhost:11434/v1/chat/completions",
+		APIKeyEnv:  "OLLAMA_API_KEY",
+		EmbedModel: "nomic-embed-text",
+	},
+}
+
+// envProviders is the chain used when llm.providers is empty: OpenRouter then
+// Gemini, each when its API key is set, with models from the environment
+func envProviders() []config.LLMProvider {
+	var out []config.LLMProvider
+	if os.Getenv("OPENROUTER_API_KEY") != "" {
+		out = append(out, config.LLMProvider{
+			Kind:       ProviderOpenRouter,
+			Model:      os.Getenv("OPENROUTER_MODEL"),
+			EmbedModel: os.Getenv("OPENROUTER_EMBED_MODEL"),
+		})
+	}
+	if os.Getenv("GEMINI_API_KEY") != "" {
+		out = append(out, config.LLMProvider{
+			Kind:       ProviderGemini,
+			Model:      os.Getenv("GEMINI_MODEL"),
+			EmbedModel: os.Getenv("GEMINI_EMBED_MODEL"),
+		})
+	}
+	return out
+}
+
+// newProvider builds one provider's client, or fails if the kind is unknown
+// or its API key is unset. Ollama needs no key.
+func newProvider(cfg config.LLMProvider, timeout time.Duration) (*Client, error) {
+	kind := strings.ToLower(cfg.Kind)
+	def, ok := providerDefaults[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown LLM provider kind %q (want openrouter, gemini or ollama)", cfg.Kind)
+	}
+	pick := func(v, fallback string) string {
+		if v == "" {
+			return fallback
+		}
+		return v
+	}
+	keyEnv := pick(cfg.APIKeyEnv, def.APIKeyEnv)
+	key := os.Getenv(keyEnv)
+	if key == "" {
+		if kind != ProviderOllama {
+			return nil, fmt.Errorf("%s: %s not set", kind, keyEnv)
+		}
+		key = ProviderOllama // Ollama ignores the key, but clients without one count as unconfigured
+	}
+	embedModel := pick(cfg.EmbedModel, def.EmbedModel)
+	if embedModel == "none" {
+		embedModel = ""
+	}
+	rpm := cfg.RequestsPerMinute
+	if rpm <= 0 {
+		rpm = 60
+	}
+	return &Client{
+		ApiKey:               key,
+		Model:                pick(cfg.Model, def.Model),
+		EmbedModel:           embedModel,
+		Provider:             kind,
+		BaseURL:              pick(cfg.BaseURL, def.BaseURL),
+		Timeout:              timeout,
+		maxRequestsPerMinute: rpm,
+		windowStart:          time.Now(),
+	}, nil
+}
@@ -2,13 +2,16 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"margraf/config"
 	"margraf/logger"
+	"margraf/retry"
+	"margraf/syserr"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 )
@@ -16,8 +19,13 @@ import (
 type Client struct {
 	ApiKey   string
 	Model    string
-	Provider string // "gemini" or "openrouter"
+	Provider string // ProviderOpenRouter, ProviderGemini or ProviderOllama
 	BaseURL  string
+	Timeout  time.Duration // Per completion, including retries (0 = none)
+
+	EmbedModel string // Embedding model for Embed (empty = LocalEmbed)
+
+	Budget *Budget // Daily allowance per consumer (nil = unlimited; see budget.go)
 
 	// Circuit Breaker State
 	failureCount    int
@@ -29,61 +37,56 @@ type Client struct {
 	windowStart     time.Time
 	maxRequestsPerMinute int
 
-	// Fallback Client
-	fallback *Client
+	// Failover chain this provider belongs to (nil = no failover)
+	chain *chain
 }
 
+// Errors returned (wrapped in *syserr.Error) when no provider can be called
+var (
+	ErrNoAPIKey    = errors.New("API_KEY not set (OPENROUTER_API_KEY or GEMINI_API_KEY)")
+	ErrCircuitOpen = errors.New("circuit breaker open: too many API failures")
+	ErrRateLimited = errors.New("rate limit exceeded")
+)
+
+// defaultTimeout bounds one completion when timeouts.llm is unset
+const defaultTimeout = 2 * time.Minute
+
+// NewClient builds the provider chain from llm.providers, or from the
+// OpenRouter and Gemini API keys when none are configured. The returned client
+// is the first provider; its calls fail over along the chain.
 func NewClient() *Client {
-	var primary *Client
-	var fallback *Client
-
-	// 1. Primary: OpenRouter with Grok-4.1-fast
-	if key := os.Getenv("OPENROUTER_API_KEY"); key != "" {
-		model := os.Getenv("OPENROUTER_MODEL")
-		if model == "" {
-			model = "x-ai/grok-beta" // Grok-4.1-fast free tier
-		}
-		logger.Info(logger.StatusOK, "Primary LLM: OpenRouter (%s)", model)
-		primary = &Client{
-			ApiKey:               key,
-			Model:                model,
-			Provider:             "openrouter",
-			BaseURL:              "https://openrouter.ai/api/v1/chat/completions",
-			maxRequestsPerMinute: 60,
-			windowStart:          time.Now(),
-		}
-	}
+	timeout := config.Timeout(config.Global.Timeouts.LLM, defaultTimeout)
 
-	// 2. Fallback: Gemini Direct
-	if geminiKey := os.Getenv("GEMINI_API_KEY"); geminiKey != "" {
-		model := os.Getenv("GEMINI_MODEL")
-		if model == "" {
-			model = "gemini-1.5-flash"
+	configured := config.Global.LLM.Providers
+	if len(configured) == 0 {
+		configured = envProviders()
+	}
+	var providers []*Client
+	for _, cfg := range configured {
+		p, err := newProvider(cfg, timeout)
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "Skipping LLM provider: %v", err)
+			syserr.Report(syserr.ModuleLLM, "configure", err)
+			continue
 		}
-		logger.Info(logger.StatusOK, "Fallback LLM: Google Gemini (%s)", model)
-		fallback = &Client{
-			ApiKey:               geminiKey,
-			Model:                model,
-			Provider:             "gemini",
-			BaseURL:              "https://generativelanguage.googleapis.com/v1beta/models",
-			maxRequestsPerMinute: 60,
-			windowStart:          time.Now(),
+		redactSecret(p.ApiKey)
+		if len(providers) == 0 {
+			logger.Info(logger.StatusOK, "Primary LLM: %s", p.name())
+		} else {
+			logger.Info(logger.StatusOK, "Fallback LLM %d: %s", len(providers), p.name())
 		}
+		providers = append(providers, p)
 	}
 
-	// Link fallback to primary
-	if primary != nil {
-		primary.fallback = fallback
-		return primary
-	}
-
-	// If no OpenRouter key, use Gemini as primary
-	if fallback != nil {
-		return fallback
+	if len(providers) > 0 {
+		newChain(providers)
+		providers[0].Budget = CurrentBudget()
+		return providers[0]
 	}
 
 	// No API keys configured
 	logger.Error(logger.StatusErr, "No API keys configured (OPENROUTER_API_KEY or GEMINI_API_KEY)")
+	syserr.Report(syserr.ModuleLLM, "configure", unavailable("configure", ErrNoAPIKey, false))
 	return &Client{
 		ApiKey: "",
 		Model:  "",
@@ -91,6 +94,21 @@ func NewClient() *Client {
 	}
 }
 
+// NewEndpointClient returns a client for an OpenAI-compatible chat
+// completions endpoint, such as a local model server or a test double
+func NewEndpointClient(baseURL, apiKey, model string) *Client {
+	redactSecret(apiKey)
+	return &Client{
+		ApiKey:               apiKey,
+		Model:                model,
+		Provider:             ProviderOpenRouter,
+		BaseURL:              baseURL,
+		Timeout:              config.Timeout(config.Global.Timeouts.LLM, defaultTimeout),
+		maxRequestsPerMinute: 60,
+		windowStart:          time.Now(),
+	}
+}
+
 // --- Gemini Types ---
 type Part struct {
 	Text string `json:"text"`
@@ -124,6 +142,7 @@ type ChatMessage struct {
 type ChatRequest struct {
 	Model    string        `json:"model"`
 	Messages []ChatMessage `json:"messages"`
+	Stream   bool          `json:"stream,omitempty"` // Reply as server-sent events (see Stream)
 }
 type ChatResponse struct {
 	Choices []struct {
@@ -148,7 +167,7 @@ func (c *Client) checkCircuitBreaker() error {
 			c.circuitOpen = false
 			c.failureCount = 0
 		} else {
-			return fmt.Errorf("circuit breaker OPEN - too many API failures. Retry after %v", cooldownPeriod-time.Since(c.lastFailureTime))
+			return fmt.Errorf("%w (retry after %v)", ErrCircuitOpen, (cooldownPeriod - time.Since(c.lastFailureTime)).Round(time.Second))
 		}
 	}
 
@@ -163,6 +182,7 @@ func (c *Client) recordFailure() {
 	if c.failureCount >= 5 {
 		c.circuitOpen = true
 		logger.WarnDepth(1, logger.StatusWarn, "CIRCUIT BREAKER OPENED after %d consecutive failures", c.failureCount)
+		syserr.Report(syserr.ModuleLLM, c.Provider, unavailable(c.Provider, fmt.Errorf("%w after %d consecutive failures", ErrCircuitOpen, c.failureCount), true))
 	}
 }
 
@@ -188,64 +208,131 @@ func (c *Client) enforceRateLimit() error {
 	// Check if we've exceeded rate limit
 	if c.requestCount >= c.maxRequestsPerMinute {
 		waitTime := time.Minute - now.Sub(c.windowStart)
-		return fmt.Errorf("rate limit exceeded (%d requests/min). Wait %v", c.maxRequestsPerMinute, waitTime)
+		return fmt.Errorf("%w (%d requests/min). Wait %v", ErrRateLimited, c.maxRequestsPerMinute, waitTime)
 	}
 
 	c.requestCount++
 	return nil
 }
 
-func (c *Client) Complete(prompt string) (string, error) {
+// Complete sends a prompt and returns the model's reply, failing over along
+// the provider chain. The call gives up when ctx is cancelled or a provider's
+// Timeout elapses; each provider tried gets its own Timeout. With a Budget, the call counts against the consumer
+// ctx is tagged with (see WithConsumer).
+func (c *Client) Complete(ctx context.Context, prompt string) (string, error) {
 	if c.ApiKey == "" {
-		return "", errors.New("API_KEY not set (OPENROUTER_API_KEY or GEMINI_API_KEY)")
+		return "", unavailable("complete", ErrNoAPIKey, false)
 	}
-
-	// Check circuit breaker
-	if err := c.checkCircuitBreaker(); err != nil {
-		// If circuit is open and we have a fallback, try fallback
-		if c.fallback != nil {
-			logger.Warn(logger.StatusWarn, "Primary LLM circuit open, using fallback (%s)", c.fallback.Provider)
-			return c.fallback.Complete(prompt)
+	if c.Budget == nil {
+		return c.complete(ctx, prompt)
+	}
+	if cached, ok, err := c.Budget.Reserve(ctx, prompt); err != nil {
+		if errors.Is(err, ErrBudgetExhausted) {
+			return "", syserr.Warning(syserr.ModuleLLM, "budget "+ConsumerOf(ctx), err)
 		}
 		return "", err
+	} else if ok {
+		return cached, nil
 	}
+	result, err := c.complete(ctx, prompt)
+	if err == nil {
+		c.Budget.Record(ctx, prompt, result)
+	}
+	return result, err
+}
 
-	// Enforce rate limiting
-	if err := c.enforceRateLimit(); err != nil {
-		// If rate limited and we have a fallback, try fallback
-		if c.fallback != nil {
-			logger.Warn(logger.StatusWarn, "Primary LLM rate limited, using fallback (%s)", c.fallback.Provider)
-			return c.fallback.Complete(prompt)
+// CleanJSON strips the Markdown code fence models often wrap a JSON reply
+// in, so the reply can be unmarshalled
+func CleanJSON(reply string) string {
+	s := strings.TrimSpace(reply)
+	s = strings.TrimPrefix(s, "```json")
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimSuffix(s, "```")
+	return strings.TrimSpace(s)
+}
+
+// complete is Complete without the budget, trying each provider in the
+// chain, healthiest first, until one answers
+func (c *Client) complete(ctx context.Context, prompt string) (string, error) {
+	providers := c.providers()
+	var lastErr error
+	for i, p := range providers {
+		result, err := p.attempt(ctx, prompt)
+		if err == nil {
+			return result, nil
 		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		lastErr = err
+		if i+1 < len(providers) {
+			logger.Warn(logger.StatusWarn, "LLM %s failed (%v), trying %s", p.name(), err, providers[i+1].name())
+		}
+	}
+	return "", lastErr
+}
+
+// attempt sends a prompt to this provider alone
+func (c *Client) attempt(ctx context.Context, prompt string) (string, error) {
+	if c.ApiKey == "" {
+		return "", unavailable("complete", ErrNoAPIKey, false)
+	}
+	if err := ctx.Err(); err != nil {
 		return "", err
 	}
+	if err := c.checkCircuitBreaker(); err != nil {
+		return "", unavailable(c.Provider, err, true)
+	}
+	if err := c.enforceRateLimit(); err != nil {
+		return "", syserr.Warning(syserr.ModuleLLM, c.Provider, err)
+	}
+
+	callCtx := ctx
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
 
 	var result string
 	var err error
 
-	if c.Provider == "openrouter" {
-		result, err = c.completeOpenRouter(prompt)
+	started := time.Now()
+	if c.Provider == ProviderGemini {
+		result, err = c.completeGemini(callCtx, prompt)
 	} else {
-		result, err = c.completeGemini(prompt)
+		result, err = c.completeOpenRouter(callCtx, prompt)
+	}
+	c.logExchange(ctx, prompt, result, started, err)
+
+	// The caller gave up; that says nothing about the provider's health
+	if ctx.Err() != nil {
+		return "", ctx.Err()
 	}
 
-	// Update circuit breaker state
+	// Update circuit breaker state and health score
+	c.recordOutcome(err == nil)
 	if err != nil {
 		c.recordFailure()
-
-		// If primary failed and we have a fallback, try fallback
-		if c.fallback != nil {
-			logger.Warn(logger.StatusWarn, "Primary LLM failed (%v), trying fallback (%s)", err, c.fallback.Provider)
-			return c.fallback.Complete(prompt)
-		}
-	} else {
-		c.recordSuccess()
+		return "", syserr.New(syserr.ModuleLLM, c.Provider, err)
 	}
 
-	return result, err
+	c.recordSuccess()
+	return result, nil
+}
+
+// unavailable marks an LLM failure that blocks every completion
+func unavailable(op string, err error, retryable bool) *syserr.Error {
+	return &syserr.Error{
+		Module:    syserr.ModuleLLM,
+		Op:        op,
+		Severity:  syserr.SeverityCritical,
+		Retryable: retryable,
+		Err:       err,
+	}
 }
 
-func (c *Client) completeOpenRouter(prompt string) (string, error) {
+func (c *Client) completeOpenRouter(ctx context.Context, prompt string) (string, error) {
 	reqBody := ChatRequest{
 		Model: c.Model,
 		Messages: []ChatMessage{
@@ -254,47 +341,49 @@ func (c *Client) completeOpenRouter(prompt string) (string, error) {
 	}
 	jsonData, _ := json.Marshal(reqBody)
 
-	// Simple retry loop for OpenRouter too
-	maxRetries := 3
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		req, _ := http.NewRequest("POST", c.BaseURL, bytes.NewBuffer(jsonData))
+	var body []byte
+	err := retry.Do(ctx, c.retryPolicy("OpenRouter"), func() error {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return retry.Permanent(err)
+		}
 		req.Header.Set("Authorization", "Bearer "+c.ApiKey)
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("HTTP-Referer", "https://margraf.app") // Required by OpenRouter
 		req.Header.Set("X-Title", "Margraf FDKG")
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
-			return "", err
+			return err
 		}
 		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
+		body, _ = io.ReadAll(resp.Body)
 
-		if resp.StatusCode == 200 {
-			var chatResp ChatResponse
-			if err := json.Unmarshal(body, &chatResp); err != nil {
-				return "", err
-			}
-			if len(chatResp.Choices) > 0 {
-				return chatResp.Choices[0].Message.Content, nil
-			}
-			return "", errors.New("no content in OpenRouter response")
+		if resp.StatusCode != 200 {
+			return &retry.StatusError{Code: resp.StatusCode, Body: string(body)}
 		}
-
-		if resp.StatusCode == 429 {
-			logger.InfoDepth(2, logger.StatusWait, "OpenRouter Rate Limit. Retrying in 5s...")
-			time.Sleep(5 * time.Second)
-			continue
+		return nil
+	})
+	if err != nil {
+		var status *retry.StatusError
+		if errors.As(err, &status) {
+			return "", fmt.Errorf("OpenRouter error: %w", status)
 		}
-		
-		return "", fmt.Errorf("OpenRouter error %d: %s", resp.StatusCode, string(body))
+		return "", err
+	}
+
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return "", err
+	}
+	if len(chatResp.Choices) > 0 {
+		return chatResp.Choices[0].Message.Content, nil
 	}
-	return "", errors.New("max retries exceeded")
+	return "", errors.New("no content in OpenRouter response")
 }
 
-func (c *Client) completeGemini(prompt string) (string, error) {
-	url := fmt.Sprintf("%s/%s:generateContent?key=%s", c.BaseURL, c.Model, c.ApiKey)
+func (c *Client) completeGemini(ctx context.Context, prompt string) (string, error) {
+	url := fmt.Sprintf("%s/%s:generateContent", c.BaseURL, c.Model)
 
 	reqBody := GenerateRequest{
 		Contents: []Content{
@@ -311,59 +400,35 @@ func (c *Client) completeGemini(prompt string) (string, error) {
 		return "", err
 	}
 
-	maxRetries := 5
 	var body []byte
-	var resp *http.Response
-
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		resp, err = http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	err = retry.Do(ctx, c.retryPolicy("Gemini"), func() error {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 		if err != nil {
-			return "", err
+			return retry.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-goog-api-key", c.ApiKey) // Not ?key=, which transport errors would quote
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
 		}
 		defer resp.Body.Close()
-
 		body, _ = io.ReadAll(resp.Body)
 
-		if resp.StatusCode == 200 {
-			break
+		if resp.StatusCode != 200 {
+			return &retry.StatusError{Code: resp.StatusCode, Body: string(body), RetryAfter: geminiRetryDelay(body)}
 		}
-
-		if resp.StatusCode == 429 || resp.StatusCode == 503 {
-			if attempt == maxRetries {
-				break 
-			}
-
-			delay := time.Duration(5*(1<<attempt)) * time.Second 
-
-			var apiErr struct {
-				Error struct {
-					Details []ErrorDetail `json:"details"`
-				} `json:"error"`
-			}
-			if json.Unmarshal(body, &apiErr) == nil {
-				for _, detail := range apiErr.Error.Details {
-					if strings.Contains(detail.Type, "RetryInfo") && detail.RetryDelay != "" {
-						if d, err := time.ParseDuration(detail.RetryDelay); err == nil {
-							delay = d + 500*time.Millisecond
-						}
-					}
-				}
-			}
-
-			logger.InfoDepth(2, logger.StatusWait, "Rate limit (%d). Retrying in %v...", resp.StatusCode, delay)
-			time.Sleep(delay)
-			continue
+		return nil
+	})
+	if err != nil {
+		var status *retry.StatusError
+		if !errors.As(err, &status) {
+			return "", err
 		}
-
-		msg := fmt.Sprintf("API request failed with status %d: %s", resp.StatusCode, string(body))
-		if resp.StatusCode == 404 {
-			msg += fmt.Sprintf("\n[Hint] Model '%s' not found.", c.Model)
+		if status.Code == 404 {
+			return "", fmt.Errorf("API request failed with %w\n[Hint] Model '%s' not found.", status, c.Model)
 		}
-		return "", errors.New(msg)
-	}
-
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("API request failed after retries with status %d: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("API request failed with %w", status)
 	}
 
 	var genResp GenerateResponse
@@ -381,3 +446,37 @@ func (c *Client) completeGemini(prompt string) (string, error) {
 
 	return genResp.Candidates[0].Content.Parts[0].Text, nil
 }
+
+// retryPolicy is the shared LLM policy, logging each wait
+func (c *Client) retryPolicy(provider string) retry.Policy {
+	p := retry.LLM
+	p.OnRetry = func(attempt int, delay time.Duration, err error) {
+		var status *retry.StatusError
+		if errors.As(err, &status) {
+			logger.InfoDepth(2, logger.StatusWait, "%s status %d. Retrying in %v...", provider, status.Code, delay.Round(time.Millisecond))
+			return
+		}
+		logger.InfoDepth(2, logger.StatusWait, "%s request failed (%v). Retrying in %v...", provider, err, delay.Round(time.Millisecond))
+	}
+	return p
+}
+
+// geminiRetryDelay reads the server-requested delay from a Gemini RetryInfo error detail
+func geminiRetryDelay(body []byte) time.Duration {
+	var apiErr struct {
+		Error struct {
+			Details []ErrorDetail `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) != nil {
+		return 0
+	}
+	for _, detail := range apiErr.Error.Details {
+		if strings.Contains(detail.Type, "RetryInfo") && detail.RetryDelay != "" {
+			if d, err := time.ParseDuration(detail.RetryDelay); err == nil {
+				return d + 500*time.Millisecond
+			}
+		}
+	}
+	return 0
+}
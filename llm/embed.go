@@ -0,0 +1,272 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"margraf/retry"
+	"margraf/syserr"
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// LocalEmbedModel names the built-in hashed embedding used when no provider
+// key is configured. It needs no network and matches on shared words and
+// word fragments rather than meaning.
+const LocalEmbedModel = "local-hash"
+
+const (
+	localEmbedDims = 256 // Buckets in a local embedding
+	embedBatch     = 100 // Texts per provider request (Gemini's batch limit)
+)
+
+// --- Embedding Types ---
+type EmbedContentRequest struct {
+	Model   string  `json:"model"`
+	Content Content `json:"content"`
+}
+type BatchEmbedRequest struct {
+	Requests []EmbedContentRequest `json:"requests"`
+}
+type BatchEmbedResponse struct {
+	Embeddings []struct {
+		Values []float64 `json:"values"`
+	} `json:"embeddings"`
+}
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+type EmbeddingResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+}
+
+// embedder picks the client that computes embeddings: the first configured
+// Gemini provider, else the first with an embedding model. Vectors from
+// different models are not comparable, so there is no failover between
+// providers, and the choice ignores their health.
+func (c *Client) embedder() *Client {
+	candidates := []*Client{c}
+	if c.chain != nil {
+		candidates = c.chain.configured
+	}
+	for _, candidate := range candidates {
+		if candidate.ApiKey != "" && candidate.Provider == ProviderGemini && candidate.EmbedModel != "" {
+			return candidate
+		}
+	}
+	for _, candidate := range candidates {
+		if candidate.ApiKey != "" && candidate.EmbedModel != "" {
+			return candidate
+		}
+	}
+	if c.ApiKey != "" {
+		return c
+	}
+	return nil
+}
+
+// EmbeddingModel names the model Embed uses, e.g. "gemini:text-embedding-004"
+// or LocalEmbedModel. Vectors are only comparable within one model.
+func (c *Client) EmbeddingModel() string {
+	e := c.embedder()
+	if e == nil || e.EmbedModel == "" {
+		return LocalEmbedModel
+	}
+	return e.Provider + ":" + e.EmbedModel
+}
+
+// Embed returns one embedding vector per text, from the configured provider
+// or, without an API key, from LocalEmbed. Provider vectors are returned as
+// the API gives them; compare them with cosine similarity.
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	e := c.embedder()
+	if e == nil || e.EmbedModel == "" {
+		vectors := make([][]float64, len(texts))
+		for i, text := range texts {
+			vectors[i] = LocalEmbed(text)
+		}
+		return vectors, nil
+	}
+	if err := e.checkCircuitBreaker(); err != nil {
+		return nil, unavailable(e.Provider, err, true)
+	}
+
+	callCtx := ctx
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += embedBatch {
+		end := min(start+embedBatch, len(texts))
+		if err := e.enforceRateLimit(); err != nil {
+			return nil, syserr.Warning(syserr.ModuleLLM, e.Provider+" embed", err)
+		}
+
+		var batch [][]float64
+		var err error
+		if e.Provider == ProviderGemini {
+			batch, err = e.embedGemini(callCtx, texts[start:end])
+		} else {
+			batch, err = e.embedOpenRouter(callCtx, texts[start:end])
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			e.recordFailure()
+			return nil, syserr.New(syserr.ModuleLLM, e.Provider+" embed", err)
+		}
+		if len(batch) != end-start {
+			return nil, syserr.New(syserr.ModuleLLM, e.Provider+" embed", fmt.Errorf("got %d embeddings for %d texts", len(batch), end-start))
+		}
+		vectors = append(vectors, batch...)
+	}
+	e.recordSuccess()
+	return vectors, nil
+}
+
+func (c *Client) embedGemini(ctx context.Context, texts []string) ([][]float64, error) {
+	url := fmt.Sprintf("%s/%s:batchEmbedContents", c.BaseURL, c.EmbedModel)
+
+	reqBody := BatchEmbedRequest{Requests: make([]EmbedContentRequest, len(texts))}
+	for i, text := range texts {
+		reqBody.Requests[i] = EmbedContentRequest{
+			Model:   "models/" + c.EmbedModel,
+			Content: Content{Parts: []Part{{Text: text}}},
+		}
+	}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.postEmbedding(ctx, "Gemini", url, jsonData, map[string]string{"x-goog-api-key": c.ApiKey})
+	if err != nil {
+		return nil, err
+	}
+	var embedResp BatchEmbedResponse
+	if err := json.Unmarshal(body, &embedResp); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float64, len(embedResp.Embeddings))
+	for i, e := range embedResp.Embeddings {
+		vectors[i] = e.Values
+	}
+	return vectors, nil
+}
+
+func (c *Client) embedOpenRouter(ctx context.Context, texts []string) ([][]float64, error) {
+	url := strings.TrimSuffix(c.BaseURL, "/chat/completions") + "/embeddings"
+	jsonData, err := json.Marshal(EmbeddingRequest{Model: c.EmbedModel, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.postEmbedding(ctx, "OpenRouter", url, jsonData, map[string]string{
+		"Authorization": "Bearer " + c.ApiKey,
+		"HTTP-Referer":  "https://margraf.app",
+		"X-Title":       "Margraf FDKG",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var embedResp EmbeddingResponse
+	if err := json.Unmarshal(body, &embedResp); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float64, len(embedResp.Data))
+	for _, d := range embedResp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// postEmbedding sends an embedding request under the shared LLM retry policy
+func (c *Client) postEmbedding(ctx context.Context, provider, url string, jsonData []byte, headers map[string]string) ([]byte, error) {
+	var body []byte
+	err := retry.Do(ctx, c.retryPolicy(provider), func() error {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ = io.ReadAll(resp.Body)
+
+		if resp.StatusCode != 200 {
+			return &retry.StatusError{Code: resp.StatusCode, Body: string(body), RetryAfter: geminiRetryDelay(body)}
+		}
+		return nil
+	})
+	if err != nil {
+		var status *retry.StatusError
+		if errors.As(err, &status) {
+			return nil, fmt.Errorf("%s embedding error: %w", provider, status)
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+// LocalEmbed hashes the words and three-letter word fragments of text into a
+// fixed-size unit vector, so "Samsung" lands near "Samsung Electronics"
+// without any API call.
+func LocalEmbed(text string) []float64 {
+	vector := make([]float64, localEmbedDims)
+	add := func(feature string, weight float64) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		sign := 1.0
+		if sum&(1<<31) != 0 {
+			sign = -1.0
+		}
+		vector[sum%localEmbedDims] += sign * weight
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		add("w:"+word, 1.0)
+		padded := []rune("_" + word + "_")
+		for i := 0; i+3 <= len(padded); i++ {
+			add("g:"+string(padded[i:i+3]), 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] /= norm
+		}
+	}
+	return vector
+}
@@ -0,0 +1,348 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"margraf/syserr"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The exchange log keeps every prompt sent to a provider and the reply it
+// gave, so a node or edge the LLM made up can be traced to the exchange that
+// produced it. Records are appended to a JSON Lines file that is rotated when
+// it grows past its size limit, or kept in memory when no file is open.
+
+// Exchange is one prompt/response pair
+type Exchange struct {
+	Time         time.Time `json:"time"`
+	Key          string    `json:"key"` // ExchangeKey of the prompt; identical prompts share it
+	Consumer     string    `json:"consumer"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	LatencyMs    int64     `json:"latency_ms"`
+	PromptTokens int       `json:"prompt_tokens"` // Estimated, as for budgets
+	ReplyTokens  int       `json:"reply_tokens"`
+	Prompt       string    `json:"prompt"`
+	Response     string    `json:"response,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Entities     []string  `json:"entities,omitempty"` // Names found in a JSON reply
+}
+
+// Mentions reports whether the exchange's key starts with ref (at least six
+// characters) or it extracted an entity containing ref (case-insensitive)
+func (x Exchange) Mentions(ref string) bool {
+	if ref == "" {
+		return false
+	}
+	if len(ref) >= 6 && strings.HasPrefix(x.Key, ref) {
+		return true
+	}
+	ref = strings.ToLower(strings.ReplaceAll(ref, "_", " "))
+	for _, e := range x.Entities {
+		if strings.Contains(strings.ToLower(e), ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExchangeKey identifies a prompt in the exchange log
+func ExchangeKey(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ExchangeLogOptions configures OpenExchangeLog
+type ExchangeLogOptions struct {
+	MaxBytes int64 // Rotate once the file reaches this size (0 = 10 MB)
+	Backups  int   // Rotated files kept as path.1 ... path.N (0 = 3)
+	Redact   bool  // Replace API keys and bearer tokens with [REDACTED]
+}
+
+// maxExchangeMemory bounds the exchanges kept when no file is open
+const maxExchangeMemory = 1000
+
+var (
+	exchangeMu     sync.Mutex
+	exchangeFile   *os.File
+	exchangePath   string
+	exchangeSize   int64
+	exchangeOpts   ExchangeLogOptions
+	exchangeMemory []Exchange
+	exchangeKeys   []string // Secrets the clients hold, redacted verbatim
+)
+
+// OpenExchangeLog appends exchanges to the file at path from now on;
+// exchanges kept in memory so far are written to it first
+func OpenExchangeLog(path string, opts ExchangeLogOptions) error {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 10 << 20
+	}
+	if opts.Backups <= 0 {
+		opts.Backups = 3
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	exchangeMu.Lock()
+	defer exchangeMu.Unlock()
+	if exchangeFile != nil {
+		exchangeFile.Close()
+	}
+	exchangeFile, exchangePath, exchangeSize, exchangeOpts = f, path, info.Size(), opts
+	for _, x := range exchangeMemory {
+		if err := writeExchangeLocked(x); err != nil {
+			return err
+		}
+	}
+	exchangeMemory = nil
+	return nil
+}
+
+// CloseExchangeLog stops writing to the file; later exchanges are kept in memory
+func CloseExchangeLog() error {
+	exchangeMu.Lock()
+	defer exchangeMu.Unlock()
+	if exchangeFile == nil {
+		return nil
+	}
+	err := exchangeFile.Close()
+	exchangeFile, exchangePath = nil, ""
+	return err
+}
+
+// redactSecret adds a key to redact verbatim, whatever its format
+func redactSecret(secret string) {
+	if len(secret) < 8 {
+		return
+	}
+	exchangeMu.Lock()
+	defer exchangeMu.Unlock()
+	for _, k := range exchangeKeys {
+		if k == secret {
+			return
+		}
+	}
+	exchangeKeys = append(exchangeKeys, secret)
+}
+
+// secretPatterns match credentials that can end up in prompts, replies and
+// error messages (provider keys, bearer tokens, key= query parameters)
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_\-]{16,}`),
+	regexp.MustCompile(`AIza[0-9A-Za-z_\-]{30,}`),
+	regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._\-]{16,}`),
+	regexp.MustCompile(`(?i)((?:api_?key|key|token)=)[^&\s"]+`),
+}
+
+// System error events quote failed requests, so they are scrubbed like the log
+func init() {
+	syserr.SetRedactor(Redact)
+}
+
+// Redact replaces the provider keys and other credentials in s with
+// [REDACTED], as the exchange log does
+func Redact(s string) string {
+	exchangeMu.Lock()
+	defer exchangeMu.Unlock()
+	return redactLocked(s)
+}
+
+// redactLocked replaces secrets in s (must be called with exchangeMu held)
+func redactLocked(s string) string {
+	for _, k := range exchangeKeys {
+		s = strings.ReplaceAll(s, k, "[REDACTED]")
+	}
+	for _, re := range secretPatterns {
+		s = re.ReplaceAllString(s, "${1}[REDACTED]")
+	}
+	return s
+}
+
+// logExchange records one provider call
+func (c *Client) logExchange(ctx context.Context, prompt, reply string, started time.Time, err error) {
+	x := Exchange{
+		Time:         started.UTC(),
+		Key:          ExchangeKey(prompt),
+		Consumer:     ConsumerOf(ctx),
+		Provider:     c.Provider,
+		Model:        c.Model,
+		LatencyMs:    time.Since(started).Milliseconds(),
+		PromptTokens: EstimateTokens(prompt),
+		Prompt:       prompt,
+	}
+	if err != nil {
+		x.Error = err.Error()
+	} else {
+		x.Response = reply
+		x.ReplyTokens = EstimateTokens(reply)
+		x.Entities = extractEntities(reply)
+	}
+
+	exchangeMu.Lock()
+	defer exchangeMu.Unlock()
+	if exchangeOpts.Redact {
+		x.Prompt, x.Response, x.Error = redactLocked(x.Prompt), redactLocked(x.Response), redactLocked(x.Error)
+	}
+	if exchangeFile != nil {
+		if err := writeExchangeLocked(x); err == nil {
+			return
+		}
+		// Keep the exchange rather than lose it if the file is unwritable
+	}
+	exchangeMemory = append(exchangeMemory, x)
+	if len(exchangeMemory) > maxExchangeMemory {
+		exchangeMemory = append(exchangeMemory[:0:0], exchangeMemory[len(exchangeMemory)-maxExchangeMemory:]...)
+	}
+}
+
+func writeExchangeLocked(x Exchange) error {
+	data, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	if exchangeSize+int64(len(data))+1 > exchangeOpts.MaxBytes && exchangeSize > 0 {
+		if err := rotateExchangeLocked(); err != nil {
+			return err
+		}
+	}
+	n, err := exchangeFile.Write(append(data, '\n'))
+	exchangeSize += int64(n)
+	return err
+}
+
+// rotateExchangeLocked shifts path.N-1 to path.N, ..., path to path.1, and
+// starts a new file, dropping the oldest
+func rotateExchangeLocked() error {
+	exchangeFile.Close()
+	for i := exchangeOpts.Backups; i > 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", exchangePath, i-1), fmt.Sprintf("%s.%d", exchangePath, i))
+	}
+	if err := os.Rename(exchangePath, exchangePath+".1"); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(exchangePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		exchangeFile = nil
+		return err
+	}
+	exchangeFile, exchangeSize = f, 0
+	return nil
+}
+
+// FindExchanges returns the exchanges whose key starts with ref or that
+// extracted an entity matching it, oldest first, searching rotated files too
+func FindExchanges(ref string) ([]Exchange, error) {
+	exchangeMu.Lock()
+	path, backups := exchangePath, exchangeOpts.Backups
+	var inMemory []Exchange
+	for _, x := range exchangeMemory {
+		if x.Mentions(ref) {
+			inMemory = append(inMemory, x)
+		}
+	}
+	exchangeMu.Unlock()
+	if path == "" {
+		return inMemory, nil
+	}
+
+	var out []Exchange
+	files := []string{path}
+	for i := 1; i <= backups; i++ {
+		files = append(files, fmt.Sprintf("%s.%d", path, i))
+	}
+	for i := len(files) - 1; i >= 0; i-- { // Oldest file first
+		found, err := readExchanges(files[i], ref)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		out = append(out, found...)
+	}
+	return append(out, inMemory...), nil
+}
+
+func readExchanges(path, ref string) ([]Exchange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Exchange
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var x Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &x); err != nil {
+			continue // A line cut short by a crash
+		}
+		if x.Mentions(ref) {
+			out = append(out, x)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// entityFields are the JSON fields the pipelines' prompts ask for names in
+var entityFields = map[string]bool{
+	"entity": true, "name": true, "source": true, "target": true,
+	"related_entities": true, "suppliers": true, "clients": true,
+	"parents": true, "subsidiaries": true,
+}
+
+// extractEntities collects the names in a JSON reply: a top-level list of
+// strings, or the entityFields of objects at any depth
+func extractEntities(reply string) []string {
+	var v interface{}
+	if json.Unmarshal([]byte(CleanJSON(reply)), &v) != nil {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	var walk func(v interface{}, named bool)
+	walk = func(v interface{}, named bool) {
+		switch t := v.(type) {
+		case string:
+			if named {
+				add(t)
+			}
+		case []interface{}:
+			for _, item := range t {
+				walk(item, named)
+			}
+		case map[string]interface{}:
+			for k, item := range t {
+				walk(item, entityFields[k])
+			}
+		}
+	}
+	_, topList := v.([]interface{})
+	walk(v, topList)
+	return names
+}
@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// lexicon scores common financial news words, for sentiment without a model
+// (e.g. once a consumer's budget is spent)
+var lexicon = map[string]float64{
+	"beat": 0.6, "beats": 0.6, "boom": 0.8, "booming": 0.8, "boost": 0.5, "boosts": 0.5,
+	"expand": 0.4, "expands": 0.4, "expansion": 0.4, "gain": 0.5, "gains": 0.5,
+	"growth": 0.5, "grows": 0.5, "jump": 0.5, "jumps": 0.5, "profit": 0.4, "profits": 0.4,
+	"rally": 0.6, "rallies": 0.6, "record": 0.4, "recover": 0.4, "recovers": 0.4,
+	"recovery": 0.4, "rise": 0.4, "rises": 0.4, "soar": 0.7, "soars": 0.7, "strong": 0.4,
+	"surge": 0.7, "surges": 0.7, "upgrade": 0.5, "upgrades": 0.5, "deal": 0.3, "approval": 0.4,
+	"bankrupt": -0.9, "bankruptcy": -0.9, "ban": -0.6, "bans": -0.6, "collapse": -0.9,
+	"collapses": -0.9, "crash": -0.8, "crashes": -0.8, "crisis": -0.8, "cut": -0.4, "cuts": -0.4,
+	"decline": -0.5, "declines": -0.5, "default": -0.8, "disruption": -0.6, "downgrade": -0.5,
+	"drop": -0.5, "drops": -0.5, "embargo": -0.7, "fall": -0.4, "falls": -0.4, "fine": -0.4,
+	"fraud": -0.8, "layoffs": -0.6, "loss": -0.5, "losses": -0.5, "miss": -0.5, "misses": -0.5,
+	"plunge": -0.8, "plunges": -0.8, "recall": -0.5, "recession": -0.8, "sanction": -0.7,
+	"sanctions": -0.7, "shortage": -0.6, "slump": -0.6, "slumps": -0.6, "strike": -0.5,
+	"tariff": -0.4, "tariffs": -0.4, "war": -0.8, "weak": -0.4, "shutdown": -0.7, "outage": -0.6,
+}
+
+// negators flip the score of the word after them
+var negators = map[string]bool{"no": true, "not": true, "never": true, "without": true, "avoids": true}
+
+// LexiconSentiment scores text from -1.0 to 1.0 by averaging the lexicon
+// words it contains, 0 when none appear
+func LexiconSentiment(text string) float64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	var sum float64
+	var n int
+	for i, w := range words {
+		score, ok := lexicon[w]
+		if !ok {
+			continue
+		}
+		if i > 0 && negators[words[i-1]] {
+			score = -score
+		}
+		sum += score
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
@@ -0,0 +1,219 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"margraf/logger"
+	"margraf/syserr"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Stream sends a prompt like Complete, but hands the reply to onChunk piece
+// by piece as the provider produces it, and returns the whole reply once it
+// ends. The next provider in the chain takes over only if one fails before
+// sending anything; a stream cut short returns the error with what arrived.
+// Budget, timeout and exchange log apply as for Complete.
+func (c *Client) Stream(ctx context.Context, prompt string, onChunk func(string)) (string, error) {
+	if c.ApiKey == "" {
+		return "", unavailable("stream", ErrNoAPIKey, false)
+	}
+	if c.Budget != nil {
+		cached, ok, err := c.Budget.Reserve(ctx, prompt)
+		if err != nil {
+			if errors.Is(err, ErrBudgetExhausted) {
+				return "", syserr.Warning(syserr.ModuleLLM, "budget "+ConsumerOf(ctx), err)
+			}
+			return "", err
+		}
+		if ok {
+			onChunk(cached)
+			return cached, nil
+		}
+	}
+	result, err := c.stream(ctx, prompt, onChunk)
+	if err == nil && c.Budget != nil {
+		c.Budget.Record(ctx, prompt, result)
+	}
+	return result, err
+}
+
+// stream is Stream without the budget, trying each provider in the chain,
+// healthiest first, until one answers or one has started answering
+func (c *Client) stream(ctx context.Context, prompt string, onChunk func(string)) (string, error) {
+	providers := c.providers()
+	var lastErr error
+	for i, p := range providers {
+		result, started, err := p.attemptStream(ctx, prompt, onChunk)
+		if err == nil || started || ctx.Err() != nil {
+			return result, err
+		}
+		lastErr = err
+		if i+1 < len(providers) {
+			logger.Warn(logger.StatusWarn, "LLM %s stream failed (%v), trying %s", p.name(), err, providers[i+1].name())
+		}
+	}
+	return "", lastErr
+}
+
+// attemptStream streams a prompt from this provider alone, reporting whether
+// any of the reply reached onChunk
+func (c *Client) attemptStream(ctx context.Context, prompt string, onChunk func(string)) (string, bool, error) {
+	if c.ApiKey == "" {
+		return "", false, unavailable("stream", ErrNoAPIKey, false)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	if err := c.checkCircuitBreaker(); err != nil {
+		return "", false, unavailable(c.Provider, err, true)
+	}
+	if err := c.enforceRateLimit(); err != nil {
+		return "", false, syserr.Warning(syserr.ModuleLLM, c.Provider, err)
+	}
+
+	callCtx := ctx
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var reply strings.Builder
+	emit := func(text string) {
+		if text != "" {
+			reply.WriteString(text)
+			onChunk(text)
+		}
+	}
+
+	started := time.Now()
+	var err error
+	if c.Provider == ProviderGemini {
+		err = c.streamGemini(callCtx, prompt, emit)
+	} else {
+		err = c.streamOpenRouter(callCtx, prompt, emit)
+	}
+	c.logExchange(ctx, prompt, reply.String(), started, err)
+
+	if ctx.Err() != nil {
+		return reply.String(), reply.Len() > 0, ctx.Err()
+	}
+	c.recordOutcome(err == nil)
+	if err != nil {
+		c.recordFailure()
+		return reply.String(), reply.Len() > 0, syserr.New(syserr.ModuleLLM, c.Provider, err)
+	}
+	c.recordSuccess()
+	return reply.String(), true, nil
+}
+
+func (c *Client) streamOpenRouter(ctx context.Context, prompt string, emit func(string)) error {
+	jsonData, err := json.Marshal(ChatRequest{
+		Model:    c.Model,
+		Messages: []ChatMessage{{Role: "user", Content: prompt}},
+		Stream:   true,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.ApiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("HTTP-Referer", "https://margraf.app") // Required by OpenRouter
+	req.Header.Set("X-Title", "Margraf FDKG")
+
+	return readEvents(req, "OpenRouter", func(data []byte) error {
+		var chunk struct {
+			Choices []struct {
+				Delta ChatMessage `json:"delta"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil // Keep-alive comments and the like
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("OpenRouter error: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) > 0 {
+			emit(chunk.Choices[0].Delta.Content)
+		}
+		return nil
+	})
+}
+
+func (c *Client) streamGemini(ctx context.Context, prompt string, emit func(string)) error {
+	url := fmt.Sprintf("%s/%s:streamGenerateContent?alt=sse", c.BaseURL, c.Model)
+	jsonData, err := json.Marshal(GenerateRequest{
+		Contents: []Content{{Parts: []Part{{Text: prompt}}}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.ApiKey)
+
+	return readEvents(req, "Gemini", func(data []byte) error {
+		var chunk GenerateResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+		for _, cand := range chunk.Candidates {
+			for _, part := range cand.Content.Parts {
+				emit(part.Text)
+			}
+		}
+		return nil
+	})
+}
+
+// readEvents sends req and passes the data of each server-sent event to
+// handle until the stream ends or sends [DONE]
+func readEvents(req *http.Request, provider string, handle func(data []byte) error) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s stream failed with status %d: %s", provider, resp.StatusCode, body)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data:"))
+		if !ok {
+			continue // Event names, comments and blank separators
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			return nil
+		}
+		if err := handle(data); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -3,6 +3,7 @@ package logger
 import (
 	"fmt"
 	"io"
+	"margraf/clock"
 	"os"
 	"strings"
 	"sync"
@@ -48,6 +49,7 @@ const (
 	StatusSave   StatusCode = "SAVE"   // Persistence
 	StatusWait   StatusCode = "WAIT"   // Rate limiting
 	StatusTrend  StatusCode = "TREND↓" // Negative trends
+	StatusWatch  StatusCode = "WATCH"  // Changes to watched nodes
 )
 
 // ANSI color codes
@@ -79,6 +81,12 @@ type TUIWriter interface {
 	Log(message string)
 }
 
+// PartialWriter is an output that can continue its last line, such as the
+// TUI's log view
+type PartialWriter interface {
+	WritePartial(text string)
+}
+
 // Init initializes the global logger
 func Init(level string, enableColors bool) {
 	once.Do(func() {
@@ -179,7 +187,7 @@ func (l *Logger) getStatusColor(status StatusCode) string {
 		return colorYellow
 	case StatusData, StatusNews, StatusChk, StatusMon, StatusSoc:
 		return colorBlue
-	case StatusGlob, StatusLink, StatusRec, StatusHlth, StatusRipple:
+	case StatusGlob, StatusLink, StatusRec, StatusHlth, StatusRipple, StatusWatch:
 		return colorCyan
 	default:
 		return colorWhite
@@ -188,7 +196,7 @@ func (l *Logger) getStatusColor(status StatusCode) string {
 
 // formatMessage builds the log message with timestamp and status
 func (l *Logger) formatMessage(depth int, status StatusCode, format string, args ...interface{}) string {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	timestamp := clock.Format(time.Now(), "2006-01-02 15:04:05")
 	message := fmt.Sprintf(format, args...)
 
 	var statusStr string
@@ -271,6 +279,19 @@ func Plain(format string, args ...interface{}) {
 	fmt.Fprintf(l.out, format+"\n", args...)
 }
 
+// Partial writes text without ending the line, for output that arrives in
+// pieces such as a streamed LLM answer. Newlines in text start new lines.
+func Partial(text string) {
+	l := GetLogger()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if pw, ok := l.out.(PartialWriter); ok {
+		pw.WritePartial(text)
+		return
+	}
+	fmt.Fprint(l.out, text)
+}
+
 // Separator prints a visual separator line
 func Separator() {
 	Plain("==================================================")
@@ -2,32 +2,131 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"margraf/audit"
+	"margraf/bus"
+	"margraf/clock"
 	"margraf/config"
+	"margraf/datasources"
 	"margraf/discovery"
 	"margraf/graph"
+	"margraf/history"
+	"margraf/jobs"
 	"margraf/llm"
 	"margraf/logger"
 	"margraf/news"
+	"margraf/pipeline"
+	"margraf/rag"
+	"margraf/replay"
+	"margraf/script"
 	"margraf/server"
 	"margraf/simulation"
 	"margraf/social"
+	"margraf/syserr"
+	"margraf/task"
+	"margraf/trading"
 	"margraf/tui"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// healthWindow is how long a subsystem failure stays on the TUI health pane
+const healthWindow = 15 * time.Minute
+
+// decayLambda is the temporal decay rate applied by the decay_prune job and
+// the admin decay action
+const decayLambda = 0.05
+
 func main() {
-	loadEnv()
+	offline := flag.Bool("offline", false, "Serve all external API calls from recorded fixtures")
+	record := flag.Bool("record", false, "Record external API responses as fixtures")
+	fixtureDir := flag.String("fixtures", replay.DefaultDir, "Fixture directory for -offline / -record")
+	starter := flag.Bool("starter", false, "Seed an empty graph from the starter dataset instead of LLM discovery")
+	starterBundle := flag.String("starter-bundle", "", "Starter dataset file or URL for -starter (default: built-in)")
+	synthetic := flag.String("synthetic", "", "Seed an empty graph with a generated one: nations,industries,companies[,density[,seed]] (e.g. 8,6,10,0.05)")
+	strict := flag.Bool("strict", false, "Refuse to start if the saved graph holds invalid data instead of loading it as is")
+	discoverOnLoad := flag.Bool("discover", false, "Derive missing supplier/client edges from existing relations after loading the graph")
+	readOnly := flag.Bool("readonly", false, "Open the graph file read-only: no saving, no auto-save and no file lock (for analysis alongside a running instance)")
+	runScript := flag.String("run", "", "Run a file of console commands once the graph is loaded (e.g. demo.margraf)")
+	configFile := flag.String("config", "", "Config file (default: ./config.yaml, else $XDG_CONFIG_HOME/margraf/config.yaml); .env is read from its directory")
+	dataDirFlag := flag.String("data-dir", "", "Directory for the graph, logs and other saved files (default: the working directory if it holds a graph, else $XDG_DATA_HOME/margraf)")
+	flag.Parse()
+
+	loadEnv(filepath.Join(filepath.Dir(config.FindConfig(*configFile)), ".env"))
+
+	// Record/replay must be installed before any HTTP client is used
+	replayMode := replay.ModeLive
+	if *offline {
+		replayMode = replay.ModeReplay
+	} else if *record {
+		replayMode = replay.ModeRecord
+	}
+	if err := replay.Install(replayMode, *fixtureDir); err != nil {
+		fmt.Printf("Error setting up fixtures: %v\n", err)
+		os.Exit(1)
+	}
+	if replay.Offline() && os.Getenv("OPENROUTER_API_KEY") == "" && os.Getenv("GEMINI_API_KEY") == "" {
+		// Keys are stripped from fixture lookups, so any placeholder replays recorded LLM calls
+		os.Setenv("GEMINI_API_KEY", "offline")
+	}
 
 	if err := config.Load(); err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	dataDir, err := config.SetDataDir(*dataDirFlag)
+	if err != nil {
+		fmt.Printf("Error setting up data directory: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Initialize logger with config settings
 	logger.Init(config.Global.Logging.Level, config.Global.Logging.EnableColors)
+	if err := clock.SetDisplay(config.Global.Timezone); err != nil {
+		logger.Warn(logger.StatusWarn, "Showing times in the server's zone: %v", err)
+	}
+
+	if err := pipeline.Configure(config.Global.Pipelines); err != nil {
+		fmt.Printf("Error in pipelines config: %v\n", err)
+		os.Exit(1)
+	}
+	// Before the graph loads, so edges of declared types carry shocks as declared
+	if err := declareTypesFromConfig(); err != nil {
+		fmt.Printf("Error in types config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Record discovery changes from the first graph load onwards
+	auditPath := config.Global.Audit.Path
+	if auditPath == "" {
+		auditPath = "margraf_audit.jsonl"
+	}
+	if err := audit.Open(config.DataPath(auditPath)); err != nil {
+		fmt.Printf("Error opening audit log: %v\n", err)
+		os.Exit(1)
+	}
+	defer audit.Close()
+
+	// Keep every LLM exchange, so LLM-sourced graph data can be traced
+	if err := openLLMLog(); err != nil {
+		fmt.Printf("Error opening LLM log: %v\n", err)
+		os.Exit(1)
+	}
+	defer llm.CloseExchangeLog()
+
+	// Engines stop when ctx is cancelled on exit
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	timeouts := config.Global.Timeouts
 
 	// Initialize TUI
 	tuiApp := tui.New()
@@ -49,16 +148,29 @@ func main() {
 
 	logger.Info(logger.StatusInit, "%s v%s", config.Global.App.Name, config.Global.App.Version)
 	logger.Info(logger.StatusInit, "Financial Dynamic Knowledge Graph - Real-time Trade Disruption Analysis")
+	logger.Info(logger.StatusInit, "Config: %s, data: %s", config.ConfigPath(), dataDir)
+	if replayMode != replay.ModeLive {
+		logger.Info(logger.StatusInit, "External APIs in %s mode (fixtures: %s)", replayMode, *fixtureDir)
+	}
 
 	// 1. Setup
 	var g *graph.Graph
-	graphFile := "margraf_graph.json"
+	graphFile := config.DataPath(config.GraphFile)
 
 	// Try to load existing graph first
 	if _, err := os.Stat(graphFile); err == nil {
 		logger.Info(logger.StatusInit, "Found existing graph file: %s", graphFile)
 		logger.Info(logger.StatusInit, "Loading saved graph...")
-		loadedGraph, err := graph.Load(graphFile)
+		loadGraph := graph.Load
+		if *strict {
+			loadGraph = graph.LoadStrict
+		}
+		loadedGraph, err := loadGraph(graphFile)
+		if errors.Is(err, graph.ErrInvalidGraph) {
+			tuiApp.Stop()
+			fmt.Printf("Error loading graph: %v\n", err)
+			os.Exit(1)
+		}
 		if err != nil {
 			logger.Warn(logger.StatusWarn, "Failed to load graph: %v", err)
 			logger.Info(logger.StatusInit, "Creating new graph instead...")
@@ -66,6 +178,11 @@ func main() {
 		} else {
 			g = loadedGraph
 			logger.Success("Graph loaded successfully: %s", g.String())
+			if *discoverOnLoad {
+				if added := g.DiscoverSupplyChainRelations(); added > 0 {
+					logger.Success("Discovered %d supply chain edges from existing relationships", added)
+				}
+			}
 			logger.Info(logger.StatusInit, "Tip: Use 'show' to see loaded nodes and edges")
 		}
 	} else {
@@ -73,26 +190,227 @@ func main() {
 		g = graph.NewGraph()
 	}
 
-	g.EnableAutoSave(graphFile, 10) // Auto-save every 10 changes
+	healthModel, err := healthModelFromConfig()
+	if err != nil {
+		fmt.Printf("Error in health model config: %v\n", err)
+		os.Exit(1)
+	}
+	g.SetHealthModel(healthModel)
+
+	propagationModel, err := propagationModelFromConfig()
+	if err != nil {
+		fmt.Printf("Error in propagation config: %v\n", err)
+		os.Exit(1)
+	}
+	g.SetPropagationModel(propagationModel)
+	confidenceModel, err := confidenceModelFromConfig()
+	if err != nil {
+		fmt.Printf("Error in confidence config: %v\n", err)
+		os.Exit(1)
+	}
+	g.SetConfidenceModel(confidenceModel)
+	applyCountryAliases()
+
+	// Multi-instance coordination: the writer seeds, runs engines and persists;
+	// replicas apply its graph deltas and serve clients.
+	busCfg := config.Global.Bus
+	hostname, _ := os.Hostname()
+	msgBus, err := bus.Open(busCfg.URL, busCfg.Channel, fmt.Sprintf("%s-%d", hostname, os.Getpid()))
+	if err != nil {
+		fmt.Printf("Error connecting to message bus: %v\n", err)
+		os.Exit(1)
+	}
+	replica := msgBus != nil && !busCfg.Writer
+
+	// Only one writer per graph file: another instance auto-saving it would
+	// undo this one's changes
+	if !replica && !*readOnly {
+		lock, err := graph.LockFile(graphFile)
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "%v; opening the graph read-only", err)
+			*readOnly = true
+		} else {
+			defer lock.Release()
+		}
+	}
+
+	switch {
+	case replica:
+		g.DisableAutoSave()
+		logger.Info(logger.StatusInit, "Running as replica (bus: %s); graph changes come from the writer", busCfg.URL)
+	case *readOnly:
+		g.SetReadOnly()
+		logger.Info(logger.StatusInit, "Graph is read-only: changes stay in memory and are not saved to %s", graphFile)
+	default:
+		// Changes since the last save are replayed from the write-ahead log
+		wal, err := openWAL(g, graphFile)
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "Write-ahead log unavailable: %v", err)
+		} else {
+			defer wal.Close()
+		}
+		g.EnableAutoSave(graphFile, 10) // Auto-save every 10 changes
+		go func() {
+			// Warn as soon as someone else writes the file, not only at the next auto-save
+			for range time.Tick(time.Minute) {
+				g.DiskChanged()
+			}
+		}()
+	}
+	hub := server.NewHub()
+	publishDelta := msgBus.Async(bus.TopicGraphDelta, 1024) // No-op without a bus
+	msgBus.Subscribe(bus.TopicGraphDelta, func(m bus.Message) {
+		var d graph.Delta
+		if err := json.Unmarshal(m.Payload, &d); err != nil {
+			logger.Warn(logger.StatusWarn, "Bus: bad graph delta from %s: %v", m.Origin, err)
+			return
+		}
+		if err := g.ApplyDelta(d); err != nil {
+			logger.Warn(logger.StatusWarn, "Bus: could not apply graph delta: %v", err)
+			return
+		}
+		hub.NotifyDelta(d)
+	})
+	llm.SetBudget(llmBudgetFromConfig())
 	client := llm.NewClient()
 	seeder := discovery.NewSeeder(client)
 
+	// Climate exposure scores (dataset, then optional LLM estimates) run with the other data sources
+	climateCfg := config.Global.DataSources.Climate
+	var climateClient *llm.Client
+	if climateCfg.LLMEstimate {
+		climateClient = client
+	}
+	climateSource := datasources.NewClimateSource(climateCfg.Dataset, climateClient, climateCfg.NodeTypes)
+	if climateCfg.Dataset != "" || climateCfg.LLMEstimate {
+		datasources.Register(climateSource)
+	}
+
 	// 1b. Setup Websocket Server & Social Monitor
-	hub := server.NewHub()
 	hub.SetGraph(g) // Set graph reference for handling company relations requests
+	hub.SetBus(msgBus)
+	limits := config.Global.Server.RateLimit
+	hub.SetRateLimits(
+		server.NewRateLimiter("http", limits.RequestsPerMinute, limits.Burst),
+		server.NewRateLimiter("ws", limits.WSMessagesPerMinute, limits.Burst),
+	)
+	sessions, err := server.LoadSessions(config.DataPath(config.Global.Server.Sessions))
+	if err != nil {
+		logger.Warn(logger.StatusWarn, "Could not load client sessions, starting empty: %v", err)
+		sessions, _ = server.LoadSessions("")
+	}
+	hub.SetSessions(sessions)
+	hub.SetTimeline(config.Global.Server.Timeline.Entries, config.Global.Server.Timeline.MarketMove)
+	hub.SetEmbeddings(embeddingsFile())
+	hub.SetAnalytics(analyticsOptions)
+	histories := history.NewStore(historyDir())
+	hub.SetHistory(histories)
 	go hub.Run()
 	server.StartServer(hub, config.Global.Server.Port)
 
+	// Early-warning stress index, recomputed on every graph change
+	stressMonitor := stressMonitorFromConfig(g, hub)
+	anomalyMonitor := anomalyMonitorFromConfig(g, hub)
+	portfolioMonitor, err := portfolioMonitorFromConfig(g, hub)
+	if err != nil {
+		fmt.Printf("Error loading portfolio: %v\n", err)
+		os.Exit(1)
+	}
+	g.SetChangeHook(func(d graph.Delta) {
+		publishDelta(d)
+		stressMonitor.Notify()
+		anomalyMonitor.Notify()
+		portfolioMonitor.Notify()
+		hub.NotifyDelta(d)
+	})
+
+	// Events for nodes watched with "watch" go to the console. The hook runs
+	// under the graph lock, so printing happens on its own goroutine.
+	watchEvents := make(chan server.WatchEventPayload, 256)
+	hub.SetWatchHook(func(e server.WatchEventPayload) {
+		select {
+		case watchEvents <- e:
+		default: // Console is behind; drop rather than stall the graph
+		}
+	})
+	go func() {
+		for e := range watchEvents {
+			logger.Info(logger.StatusWatch, "%s %-8s %s", e.NodeID, e.Kind, e.Message)
+		}
+	}()
+	stressInterval := time.Duration(config.Global.Stress.Interval) * time.Second
+	if stressInterval <= 0 {
+		stressInterval = time.Minute
+	}
+	go stressMonitor.Start(ctx, stressInterval)
+	anomalyInterval := time.Duration(config.Global.Anomalies.Interval) * time.Second
+	if anomalyInterval <= 0 {
+		anomalyInterval = 5 * time.Minute
+	}
+	go anomalyMonitor.Start(ctx, anomalyInterval)
+	portfolioInterval := time.Duration(config.Global.Portfolio.Interval) * time.Second
+	if portfolioInterval <= 0 {
+		portfolioInterval = 5 * time.Minute
+	}
+	go portfolioMonitor.Start(ctx, portfolioInterval)
+	paperMonitor, err := paperMonitorFromConfig(g, hub)
+	if err != nil {
+		fmt.Printf("Error loading paper ledger: %v\n", err)
+		os.Exit(1)
+	}
+	hub.SetPaper(paperMonitor.Trader.Performance)
+	paperInterval := time.Duration(config.Global.Paper.Interval) * time.Second
+	if paperInterval <= 0 {
+		paperInterval = time.Minute
+	}
+	go paperMonitor.Start(ctx, paperInterval)
+
+	// Daily z-scores of the graph's most connected pairs, alerting on entries
+	pairMonitor := pairMonitorFromConfig(g, hub)
+	pairInterval := time.Duration(config.Global.Pairs.Interval) * time.Hour
+	if pairInterval <= 0 {
+		pairInterval = 24 * time.Hour
+	}
+	go pairMonitor.Start(ctx, pairInterval)
+
+	// Background task progress goes to dashboards as task_update
+	task.SetHook(func(info task.Info) {
+		hub.Broadcast(server.TypeTaskUpdate, info)
+	})
+
+	// Subsystem failures go to dashboards as system_error
+	syserr.SetHook(func(e syserr.Event) {
+		hub.Broadcast(server.TypeSystemError, e)
+	})
+
 	socialMonitor := social.NewMonitor(client, hub, g)
 	marketMonitor := simulation.NewMarketMonitor(g, hub)
 
 	// 2. Discovery Phase - Only run seeder if graph is empty or user wants to reseed
-	if len(g.Nodes) == 0 {
-		logger.Info(logger.StatusInit, "Empty graph detected. Initializing via LLM/API...")
-		if err := seeder.Seed(g); err != nil {
-			logger.Error(logger.StatusErr, "Error seeding graph: %v", err)
+	if replica {
+		logger.Info(logger.StatusInit, "Replica: skipping discovery (%d nodes loaded)", len(g.Nodes))
+	} else if len(g.Nodes) == 0 && *synthetic != "" {
+		logger.Info(logger.StatusInit, "Empty graph detected. Generating a synthetic graph...")
+		if err := seedSynthetic(g, *synthetic); err != nil {
+			logger.Error(logger.StatusErr, "Error generating synthetic graph: %v", err)
 		}
-		logger.Success("Graph Ready: %s", g.String())
+	} else if len(g.Nodes) == 0 && *starter {
+		logger.Info(logger.StatusInit, "Empty graph detected. Seeding from the starter dataset...")
+		if err := seedStarter(ctx, g, *starterBundle); err != nil {
+			logger.Error(logger.StatusErr, "Error loading starter dataset: %v", err)
+			syserr.Report(syserr.ModuleDiscovery, "seed starter", err)
+		}
+	} else if len(g.Nodes) == 0 {
+		logger.Info(logger.StatusInit, "Empty graph detected. Initializing via LLM/API in the background ('tasks' to follow progress)...")
+		task.StartTimeout("seed", config.Timeout(timeouts.Seed, 2*time.Hour), func(ctx context.Context, t *task.Task) error {
+			if err := seeder.Seed(ctx, g); err != nil {
+				logger.Error(logger.StatusErr, "Error seeding graph: %v", err)
+				syserr.Report(syserr.ModuleDiscovery, "seed", err)
+				return err
+			}
+			logger.Success("Graph Ready: %s", g.String())
+			return nil
+		})
 	} else {
 		logger.Success("Using existing graph with %d nodes and %d edges", len(g.Nodes), len(g.Edges))
 		logger.Info(logger.StatusInit, "Use 'reseed' command to rebuild graph from scratch")
@@ -112,29 +430,187 @@ func main() {
 
 	// 3. Setup simulator
 	sim := simulation.NewSimulator(g)
+	sim.Hedger = hedgerFromConfig(portfolioMonitor)
+	if config.Global.Simulation.ShockImpact != 0 {
+		sim.ShockDamage = config.Global.Simulation.ShockImpact
+	}
+	if config.Global.Simulation.Traces.Enabled {
+		sim.OnTrace = func(t *simulation.Trace) {
+			if _, err := simulation.SaveTrace(traceDir(), t); err != nil {
+				logger.Warn(logger.StatusWarn, "Saving shock trace failed: %v", err)
+			}
+			hub.Broadcast(server.TypeShockTrace, t)
+		}
+	}
+
+	// Shared scenario files (team stress tests such as "Taiwan blockade v2")
+	if n, err := simulation.LoadLibrary(scenarioLibrary()); err != nil {
+		logger.Warn(logger.StatusWarn, "Failed to load scenario library: %v", err)
+	} else if n > 0 {
+		logger.Info(logger.StatusInit, "Loaded %d scenarios from %s", n, scenarioLibrary())
+	}
 
 	// 4. Start Engines
 	newsEngine := news.NewEngine(g, client, seeder, sim, hub, socialMonitor)
 
+	// Node descriptions and embeddings for entity linking ('describe' builds them)
+	ragIndex, err := rag.NewIndex(g, client, ragStore())
+	if err != nil {
+		logger.Warn(logger.StatusWarn, "%v (starting with an empty vector store)", err)
+		ragIndex = &rag.Index{Graph: g, Client: client, Store: rag.NewStore(), Path: ragStore()}
+	} else if n := ragIndex.Store.Len(); n > 0 {
+		logger.Info(logger.StatusInit, "Loaded %d node descriptions from %s (%s)", n, ragStore(), client.EmbeddingModel())
+	}
+	ragIndex.LinkThreshold = config.Global.RAG.LinkThreshold
+	newsEngine.Index = ragIndex
+
+	// Edges proposed from nodes named together in headlines and posts
+	coMentions := discovery.NewCoMentionLearner(g)
+	coMentions.Notify = func(p discovery.Proposal, created bool) {
+		verb := "Proposed"
+		if created {
+			verb = "Added"
+		}
+		hub.Broadcast(server.TypeGraphNotice, server.GraphNoticePayload{
+			NodeID:  p.Edge.SourceID,
+			Message: fmt.Sprintf("%s edge %s -[%s]-> %s from %d co-mentions", verb, p.Edge.SourceID, p.Edge.Type, p.Edge.TargetID, len(p.Items)),
+		})
+	}
+	newsEngine.CoMentions = coMentions
+	socialMonitor.CoMentions = coMentions
+	hub.SetAsk(ragIndex.Ask)
+	switch window := config.Global.Calendar.Window; {
+	case window < 0:
+		newsEngine.EventWindow = 0
+	case window > 0:
+		newsEngine.EventWindow = time.Duration(window) * time.Hour
+	}
+
 	newsInterval := time.Duration(config.Global.News.PollInterval) * time.Second
 	marketInterval := time.Duration(config.Global.Market.PollInterval) * time.Second
 
-	// Start temporal decay worker (applies decay every 30 minutes with lambda=0.05)
-	g.StartTemporalDecayWorker(30*time.Minute, 0.05)
-	logger.Info(logger.StatusInit, "Temporal decay worker started (λ=0.05, interval=30min)")
+	// Ongoing per-edge-type weight normalization
+	if hours := config.Global.Weights.Normalize.Interval; hours > 0 && !replica {
+		go func() {
+			ticker := time.NewTicker(time.Duration(hours) * time.Hour)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				if !pipeline.Enabled(pipeline.Normalize) {
+					continue
+				}
+				report, err := g.NormalizeWeights(weightPolicyFromConfig())
+				if err != nil {
+					logger.Warn(logger.StatusWarn, "Weight normalization failed: %v", err)
+					continue
+				}
+				if report.Changed > 0 {
+					logger.Info(logger.StatusOK, "Normalized %d edge weights (%s)", report.Changed, report.Method)
+				}
+			}
+		}()
+		logger.Info(logger.StatusInit, "Weight normalization worker started (interval=%dh)", hours)
+	}
+
+	if !replica {
+		go newsEngine.Monitor(ctx, newsInterval)
+		go marketMonitor.Start(ctx, marketInterval)
+	}
+
+	// Sector health and supplier concentration on Industry nodes; replicas
+	// receive them as node changes
+	if !replica {
+		rollupInterval := time.Duration(config.Global.Industries.RollupInterval) * time.Second
+		if rollupInterval <= 0 {
+			rollupInterval = 5 * time.Minute
+		}
+		g.RefreshIndustryRollups()
+		go func() {
+			ticker := time.NewTicker(rollupInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				g.RefreshIndustryRollups()
+			}
+		}()
+	}
+
+	// Datasource refresh worker (World Bank / Comtrade attributes go stale after seeding)
+	refresher := datasources.NewRefreshWorker(g, seeder.WorldBankClient, seeder.ComtradeClient)
+	refresher.Year = config.Global.DataSources.Year
+	if config.Global.DataSources.RefreshInterval > 0 && !replica {
+		refreshInterval := time.Duration(config.Global.DataSources.RefreshInterval) * time.Hour
+		refresher.Start(ctx, refreshInterval)
+		logger.Info(logger.StatusInit, "Data refresh worker started (interval=%v)", refreshInterval)
+	}
+
+	// Earnings and economic calendar, attached to nodes as scheduled events.
+	// The calendar is not replicated, so every instance keeps its own.
+	calendar := datasources.NewCalendarWorker(g)
+	calendar.EconomicFile = config.Global.Calendar.Economic
+	if !config.Global.Calendar.Earnings {
+		calendar.Earnings = nil
+	}
+	if hours := config.Global.Calendar.Interval; hours >= 0 {
+		if hours == 0 {
+			hours = 12
+		}
+		calendar.Start(ctx, time.Duration(hours)*time.Hour)
+		logger.Info(logger.StatusInit, "Calendar worker started (interval=%dh)", hours)
+	}
+
+	// Scheduled maintenance (decay and prune, refresh, re-seeding, ticker
+	// lookups, digest, end-of-day history); replicas receive its graph
+	// changes from the writer
+	scheduler := newScheduler(g, seeder, refresher, marketMonitor, stressMonitor, histories)
+	if !replica {
+		scheduler.Start(ctx)
+		logger.Info(logger.StatusInit, "Job scheduler started (%d jobs; 'jobs' to list)", len(scheduler.List()))
+	}
 
-	go newsEngine.Monitor(newsInterval)
-	go marketMonitor.Start(marketInterval)
+	// Operational commands for headless deployments, over /admin/* and WS
+	adminToken := os.Getenv("MARGRAF_ADMIN_TOKEN")
+	if adminToken == "" {
+		adminToken = config.Global.Server.AdminToken
+	}
+	if adminToken != "" {
+		hub.SetAdmin(adminToken, adminActions(g, seeder, ragIndex, scheduler, graphFile, replica))
+		logger.Info(logger.StatusInit, "Admin actions enabled at /admin/<action>")
+	}
 
 	// Active Graph Expansion - Periodically discover new relationships and expand nodes
 	go func() {
+		if replica {
+			return
+		}
+
 		// Wait a bit before starting expansion to let initial graph stabilize
-		time.Sleep(30 * time.Second)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(30 * time.Second):
+		}
 
 		ticker := time.NewTicker(5 * time.Minute) // Expand every 5 minutes
 		defer ticker.Stop()
 
-		for range ticker.C {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if !pipeline.Enabled(pipeline.Expansion) {
+				continue
+			}
 			logger.Info(logger.StatusInit, "Running periodic graph expansion...")
 
 			// Discover supply chain relationships
@@ -142,31 +618,26 @@ func main() {
 			if addedEdges > 0 {
 				logger.Success("Discovered %d new supply chain relationships", addedEdges)
 			}
+			if routed := g.RouteTradeThroughChokepoints(); routed > 0 {
+				logger.Success("Routed new trade flows through chokepoints (%d links)", routed)
+			}
+			if linked := g.LinkCurrencies(); linked > 0 {
+				logger.Success("Linked %d nations/companies to currencies", linked)
+			}
 
-			// Expand a random underexplored nation
-			go func() {
-				var targetNode *graph.Node
-				minEdgeCount := 999
-
-				// Find nation with fewest edges (underexplored)
-				g.NodesRange(func(n *graph.Node) {
-					if n.Type != graph.NodeTypeNation {
-						return
-					}
-					edgeCount := len(g.GetOutgoingEdges(n.ID)) + len(g.GetIncomingEdges(n.ID))
-					if edgeCount < minEdgeCount && edgeCount < 5 {
-						minEdgeCount = edgeCount
-						targetNode = n
+			// Expand the nation that has waited longest for a full exploration
+			if frontier := g.Frontier(1, graph.NodeTypeNation); len(frontier) > 0 {
+				name := frontier[0].Name
+				task.StartTimeout("expand "+name, config.Timeout(timeouts.Expansion, 30*time.Minute), func(ctx context.Context, t *task.Task) error {
+					logger.Info(logger.StatusChk, "Expanding underexplored nation: %s", name)
+					if err := seeder.ProcessNation(ctx, g, name, 0); err != nil {
+						logger.Warn(logger.StatusWarn, "Failed to expand %s: %v", name, err)
+						syserr.Report(syserr.ModuleDiscovery, "expand "+name, err)
+						return err
 					}
+					return nil
 				})
-
-				if targetNode != nil {
-					logger.Info(logger.StatusChk, "Expanding underexplored nation: %s", targetNode.Name)
-					if err := seeder.ProcessNation(g, targetNode.Name, 0); err != nil {
-						logger.Warn(logger.StatusWarn, "Failed to expand %s: %v", targetNode.Name, err)
-					}
-				}
-			}()
+			}
 		}
 	}()
 
@@ -191,7 +662,7 @@ func main() {
 					logger.Warn(logger.StatusWarn, "Error converting graph to JSON: %v", err)
 					continue
 				}
-				hub.Broadcast("graph_update", graphJSON)
+				hub.Broadcast(server.TypeGraphUpdate, json.RawMessage(graphJSON))
 
 				lastNodeCount = currentNodeCount
 				lastEdgeCount = currentEdgeCount
@@ -202,9 +673,13 @@ func main() {
 
 	// AutoSave (Every 5 mins)
 	go func() {
+		if replica || g.ReadOnly() {
+			return
+		}
 		for range time.Tick(5 * time.Minute) {
-			if err := g.Save("margraf_autosave.json"); err != nil {
+			if err := g.Save(config.DataPath("margraf_autosave.json")); err != nil {
 				logger.Error(logger.StatusErr, "AutoSave Failed: %v", err)
+				syserr.Report(syserr.ModuleStorage, "autosave", err)
 			}
 		}
 	}()
@@ -212,18 +687,50 @@ func main() {
 	// Update TUI stats periodically
 	go func() {
 		for range time.Tick(2 * time.Second) {
-			tuiApp.UpdateStats(len(g.Nodes), len(g.Edges))
+			tuiApp.UpdateStats(len(g.Nodes), len(g.Edges), pipeline.All())
+			tuiApp.UpdateTasks(task.List())
+			tuiApp.UpdateHealth(syserr.Health(healthWindow))
+			if budget := llm.CurrentBudget(); budget != nil {
+				tuiApp.UpdateBudget(budget.Usage())
+			}
 		}
 	}()
 
-	// Process commands from TUI
+	// Console input may chain commands with ";", run macros and command files
+	macroFile := config.Global.Console.Macros
+	if macroFile == "" {
+		macroFile = "margraf_macros.json"
+	}
+	macros, err := script.LoadMacros(config.DataPath(macroFile))
+	if err != nil {
+		logger.Warn(logger.StatusWarn, "Could not load macros, starting empty: %v", err)
+		macros, _ = script.LoadMacros("")
+	}
+	runner := &script.Runner{Macros: macros, Exec: func(command string) {
+		if command == "macros" {
+			printMacros(macros.List())
+			return
+		}
+		handleCommand(command, g, sim, hub, newsEngine, socialMonitor, marketMonitor, stressMonitor, portfolioMonitor, paperMonitor, pairMonitor, refresher, calendar, scheduler, histories, graphFile, tuiApp)
+	}}
+	if *runScript != "" {
+		if err := runner.RunFile(*runScript); err != nil {
+			logger.Error(logger.StatusErr, "Script stopped: %v", err)
+		}
+	}
+
 	// Handle commands from TUI (blocks until TUI exits)
 	for input := range tuiApp.GetCommandChannel() {
-		handleCommand(input, g, sim, hub, newsEngine, socialMonitor, graphFile, tuiApp)
+		if err := runner.Run(input); err != nil {
+			logger.Error(logger.StatusErr, "%v", err)
+		}
+	}
+	if err := sessions.Flush(); err != nil {
+		logger.Warn(logger.StatusWarn, "Failed to save client sessions: %v", err)
 	}
 }
 
-func handleCommand(input string, g *graph.Graph, sim *simulation.Simulator, hub *server.Hub, newsEngine *news.Engine, socialMon *social.SocialMonitor, graphFile string, tuiApp *tui.TUI) {
+func handleCommand(input string, g *graph.Graph, sim *simulation.Simulator, hub *server.Hub, newsEngine *news.Engine, socialMon *social.SocialMonitor, marketMon *simulation.MarketMonitor, stressMon *simulation.StressMonitor, portfolioMon *simulation.PortfolioMonitor, paperMon *simulation.PaperMonitor, pairMon *simulation.PairMonitor, refresher *datasources.RefreshWorker, calendar *datasources.CalendarWorker, scheduler *jobs.Scheduler, histories *history.Store, graphFile string, tuiApp *tui.TUI) {
 	parts := strings.Split(strings.TrimSpace(input), " ")
 	if len(parts) == 0 {
 		return
@@ -234,9 +741,19 @@ func handleCommand(input string, g *graph.Graph, sim *simulation.Simulator, hub
 		printGraph(g)
 	case "edges":
 		printEdgeDirectionality()
+	case "types":
+		printTypes(g)
 	case "discover":
 		logger.Info(logger.StatusInit, "Discovering supplier/client relationships...")
 		addedEdges := g.DiscoverSupplyChainRelations()
+		if routed := g.RouteTradeThroughChokepoints(); routed > 0 {
+			logger.Success("Added %d chokepoint routing edges", routed)
+			addedEdges += routed
+		}
+		if linked := g.LinkCurrencies(); linked > 0 {
+			logger.Success("Added %d currency edges", linked)
+			addedEdges += linked
+		}
 		if addedEdges > 0 {
 			logger.Success("Added %d supply chain edges", addedEdges)
 			if err := g.Save(graphFile); err != nil {
@@ -260,7 +777,16 @@ func handleCommand(input string, g *graph.Graph, sim *simulation.Simulator, hub
 		}
 	case "relations":
 		if len(parts) < 2 {
-			logger.Warn(logger.StatusWarn, "Usage: relations <CompanyID>")
+			logger.Warn(logger.StatusWarn, "Usage: relations <CompanyID|NationID>")
+			return
+		}
+		if n, ok := g.GetNode(parts[1]); ok && n.Type == graph.NodeTypeNation {
+			relations, err := g.GetNationRelations(parts[1])
+			if err != nil {
+				logger.Error(logger.StatusErr, "Error: %v", err)
+				return
+			}
+			printNationRelations(relations)
 			return
 		}
 		companyID := parts[1]
@@ -270,154 +796,3519 @@ func handleCommand(input string, g *graph.Graph, sim *simulation.Simulator, hub
 			return
 		}
 		printCompanyRelations(relations)
+	case "mermaid":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: mermaid <CompanyID> [file.mmd|file.md]")
+			return
+		}
+		chart, err := g.ToMermaid(parts[1])
+		if err != nil {
+			logger.Error(logger.StatusErr, "Error: %v", err)
+			return
+		}
+		if len(parts) < 3 {
+			logger.Plain("")
+			for _, line := range strings.Split(strings.TrimRight(chart, "\n"), "\n") {
+				logger.Plain("%s", line)
+			}
+			return
+		}
+		if strings.HasSuffix(parts[2], ".md") {
+			chart = "```mermaid\n" + chart + "```\n"
+		}
+		if err := os.WriteFile(parts[2], []byte(chart), 0644); err != nil {
+			logger.Error(logger.StatusErr, "Error exporting Mermaid: %v", err)
+		} else {
+			logger.Success("Supply chain of %s exported to %s", parts[1], parts[2])
+		}
 	case "migrate":
 		migrateEdges(g, graphFile)
 	case "shock":
 		if len(parts) < 2 {
-			logger.Warn(logger.StatusWarn, "Usage: shock <NodeID> (e.g., shock india)")
+			logger.Warn(logger.StatusWarn, "Usage: shock <NodeID> [hs_code] (e.g., shock india 27)")
+			return
+		}
+		if parts[1] == "region" {
+			if len(parts) < 3 {
+				logger.Warn(logger.StatusWarn, "Usage: shock region <Region|Country> (regions: %s)", strings.Join(graph.Regions(), ", "))
+				return
+			}
+			region := strings.Join(parts[2:], " ")
+			shocked := sim.RunRegionShock(region, "Regional Disaster / Conflict", 0.1)
+			for _, id := range shocked {
+				updateEdgesForTest(g, id, -0.8, "Regional shock simulation")
+			}
+			if len(shocked) > 0 {
+				hub.Broadcast(server.TypeShockEvent, server.ShockPayload{
+					Kind:    server.ShockRegion,
+					Region:  region,
+					Targets: shocked,
+					Impact:  0.1,
+				})
+			}
 			return
 		}
 		targetID := parts[1]
+		commodity := ""
+		if len(parts) > 2 {
+			commodity = parts[2]
+		}
 		sim.RunShock(simulation.ShockEvent{
 			TargetNodeID: targetID,
 			Description:  "Trade Ban / Supply Chain Failure",
 			ImpactFactor: 0.1, // 90% reduction
+			Commodity:    commodity,
 		})
 		// Also update edge weights negatively
 		updateEdgesForTest(g, targetID, -0.8, "Negative shock simulation")
-	case "boost":
-		if len(parts) < 2 {
-			logger.Warn(logger.StatusWarn, "Usage: boost <NodeID> (e.g., boost india)")
+	case "monetary":
+		if len(parts) < 4 {
+			logger.Warn(logger.StatusWarn, "Usage: monetary <Currency> <devalue|hike|cut> <magnitude> (e.g., monetary TRY devalue 0.3)")
 			return
 		}
-		targetID := parts[1]
-		sim.RunShock(simulation.ShockEvent{
-			TargetNodeID: targetID,
-			Description:  "Positive Economic Boom / Trade Agreement",
-			ImpactFactor: 1.5, // 50% increase
-		})
-		hub.Broadcast("shock_event", map[string]interface{}{
-			"type":   "boost",
-			"target": targetID,
-			"impact": 1.5,
-		})
-		// Update edge weights positively
-		updateEdgesForTest(g, targetID, 0.8, "Positive boost simulation")
-	case "simulate":
-		if len(parts) < 3 {
-			logger.Warn(logger.StatusWarn, "Usage: simulate <NodeID> <sentiment> (e.g., simulate india 0.5)")
-			return
+		magnitude := 0.0
+		fmt.Sscanf(parts[3], "%f", &magnitude)
+		shock := simulation.MonetaryShock{
+			Currency:  parts[1],
+			Kind:      simulation.MonetaryKind(parts[2]),
+			Magnitude: magnitude,
 		}
-		targetID := parts[1]
-		sentiment := 0.0
-		fmt.Sscanf(parts[2], "%f", &sentiment)
-		if sentiment < -1.0 || sentiment > 1.0 {
-			logger.Warn(logger.StatusWarn, "Sentiment must be between -1.0 and 1.0")
+		if err := sim.RunMonetaryShock(shock); err != nil {
+			logger.Error(logger.StatusErr, "Monetary shock failed: %v", err)
 			return
 		}
-
-		// Apply to node health
-		impactFactor := 1.0 + sentiment
-		sim.RunShock(simulation.ShockEvent{
-			TargetNodeID: targetID,
-			Description:  fmt.Sprintf("Simulated news event (sentiment: %.2f)", sentiment),
-			ImpactFactor: impactFactor,
+		hub.Broadcast(server.TypeShockEvent, server.ShockPayload{
+			Kind:   server.ShockMonetary,
+			Target: graph.CurrencyNodeID(shock.Currency),
+			Move:   string(shock.Kind),
+			Impact: magnitude,
 		})
-
-		// Update edge weights
-		updateEdgesForTest(g, targetID, sentiment, fmt.Sprintf("Test simulation (%.2f)", sentiment))
-		logger.Success("Simulated news event for %s with sentiment %.2f", targetID, sentiment)
-	case "news":
-		newsEngine.FetchAndProcess()
-	case "reseed":
-		logger.Warn(logger.StatusWarn, "WARNING: Reseeding will clear current graph and rebuild from scratch!")
-		logger.Info(logger.StatusInit, "Starting reseed process...")
-
-		// Clear the graph safely
-		g.Clear()
-
-		logger.Success("Graph cleared. Starting discovery...")
-
-		// Run seeder in background
-		go func() {
-			client := llm.NewClient()
-			seeder := discovery.NewSeeder(client)
-			if err := seeder.Seed(g); err != nil {
-				logger.Error(logger.StatusErr, "Error seeding graph: %v", err)
-			} else {
-				logger.Success("Graph reseeded successfully: %s", g.String())
-				// Save the new graph
-				if err := g.Save(graphFile); err != nil {
-					logger.Warn(logger.StatusWarn, "Failed to save reseeded graph: %v", err)
-				}
+	case "pipeline", "pipelines":
+		if len(parts) < 3 || (parts[1] != "start" && parts[1] != "stop") {
+			printPipelines()
+			if len(parts) > 1 && parts[1] != "list" {
+				logger.Plain("Usage: pipeline start|stop <name>")
 			}
-		}()
-	case "social":
-		if len(parts) < 2 {
-			logger.Warn(logger.StatusWarn, "Usage: social <Topic>")
 			return
 		}
-		topic := strings.Join(parts[1:], " ")
-		go socialMon.CrawlReal(topic)
-	case "save":
-		if len(parts) < 2 {
-			logger.Warn(logger.StatusWarn, "Usage: save <filename.json>")
+		if err := pipeline.Set(parts[2], parts[1] == "start"); err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
 			return
 		}
-		if err := g.Save(parts[1]); err != nil {
-			logger.Error(logger.StatusErr, "Error saving graph: %v", err)
+		if parts[1] == "start" {
+			logger.Success("Pipeline %s started", parts[2])
 		} else {
-			logger.Success("Graph saved to %s", parts[1])
+			logger.Info(logger.StatusOK, "Pipeline %s stopped", parts[2])
 		}
-	case "load":
-		if len(parts) < 2 {
-			logger.Warn(logger.StatusWarn, "Usage: load <filename.json>")
-			return
+		tuiApp.UpdateStats(len(g.Nodes), len(g.Edges), pipeline.All())
+	case "tasks":
+		printTasks(task.List())
+	case "jobs":
+		switch {
+		case len(parts) == 1:
+			printJobs(scheduler.List())
+		case parts[1] == "run" && len(parts) > 2:
+			t, err := scheduler.RunNow(parts[2])
+			if err != nil {
+				logger.Warn(logger.StatusWarn, "%v", err)
+				return
+			}
+			logger.Info(logger.StatusOK, "Job %s running as task %s", parts[2], t.Info().ID)
+		default:
+			printJobRuns(scheduler.List(), parts[1])
 		}
-		newG, err := graph.Load(parts[1])
+	case "history":
+		switch len(parts) {
+		case 1:
+			printHistoryDays(histories)
+		case 2:
+			if parts[1] == "snapshot" {
+				t, err := scheduler.RunNow("history")
+				if err != nil {
+					logger.Warn(logger.StatusWarn, "%v", err)
+					return
+				}
+				logger.Info(logger.StatusOK, "Job history running as task %s", t.Info().ID)
+				return
+			}
+			printNodeHistory(histories, parts[1])
+		default:
+			printEdgeHistory(histories, parts[1], parts[2])
+		}
+	case "errors":
+		printErrors(syserr.Recent())
+	case "cancel":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: cancel <taskID>")
+			return
+		}
+		if err := task.Cancel(parts[1]); err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
+		} else {
+			logger.Info(logger.StatusOK, "Cancelling task %s...", parts[1])
+		}
+	case "stress":
+		if len(parts) == 1 {
+			printStress(stressMon.Update())
+			return
+		}
+		opts, err := parseStressOptions(parts[1:])
 		if err != nil {
-			logger.Error(logger.StatusErr, "Error loading graph: %v", err)
+			logger.Warn(logger.StatusWarn, "%v", err)
+			logger.Warn(logger.StatusWarn, "Usage: stress --type <NodeType> [--impact 0.3] [--affected Corporation] [--top N]")
+			return
+		}
+		task.Start("stress "+string(opts.Type), func(ctx context.Context, t *task.Task) error {
+			opts.Progress = func(done, total int, nodeID string) { t.Progress(done, total, nodeID) }
+			report, err := sim.StressType(ctx, opts)
+			if err != nil {
+				logger.Error(logger.StatusErr, "Stress test failed: %v", err)
+				return err
+			}
+			printVulnerabilities(report)
+			return nil
+		})
+	case "anomalies":
+		monitor := anomalyMonitorFromConfig(g, nil)
+		window := monitor.Window
+		if len(parts) > 1 {
+			hours, err := strconv.Atoi(parts[1])
+			if err != nil || hours <= 0 {
+				logger.Warn(logger.StatusWarn, "Usage: anomalies [hours]")
+				return
+			}
+			window = time.Duration(hours) * time.Hour
+		}
+		opts := monitor.Options
+		opts.Since = time.Now().Add(-window)
+		printAnomalies(g, g.EdgeAnomalies(opts), window)
+	case "industries":
+		printIndustryRollups(g.IndustryRollups())
+	case "portfolio":
+		handlePortfolio(portfolioMon, parts[1:])
+	case "paper":
+		printPaper(paperMon.Trader.Performance())
+	case "pairs":
+		if len(parts) > 1 && parts[1] == "scan" {
+			monitor := pairMon
+			if len(parts) > 2 {
+				// A one-off scan on other prices, leaving the monitor's readings and alerts alone
+				days := config.Global.Pairs.Days
+				if days <= 0 {
+					days = 90
+				}
+				prices, err := trading.NewPriceSource(strings.ToLower(parts[2]), g, days)
+				if err != nil {
+					logger.Warn(logger.StatusWarn, "Usage: pairs scan [yahoo|graph]")
+					return
+				}
+				monitor = pairMonitorFromConfig(g, nil)
+				monitor.Prices = prices
+			}
+			task.Start("pairs", func(ctx context.Context, t *task.Task) error {
+				printPairs(monitor.Scan(ctx), monitor.Entry)
+				return nil
+			})
+			return
+		}
+		printPairs(pairMon.Latest(), pairMon.Entry)
+	case "budget":
+		printBudget(llm.CurrentBudget())
+	case "providers":
+		printProviders(newsEngine.Client)
+	case "calendar":
+		if len(parts) > 1 && parts[1] == "refresh" {
+			task.StartTimeout("calendar", calendar.Timeout, func(ctx context.Context, t *task.Task) error {
+				if _, err := calendar.Refresh(ctx); err != nil {
+					logger.Warn(logger.StatusWarn, "Calendar refresh skipped: %v", err)
+					return err
+				}
+				return nil
+			})
+			return
+		}
+		nodeID, days := "", 14
+		for _, arg := range parts[1:] {
+			if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+				days = n
+			} else {
+				nodeID = arg
+			}
+		}
+		printCalendar(g, nodeID, days)
+	case "countries":
+		printUnresolvedCountries(datasources.UnresolvedCountries())
+	case "climate":
+		src, ok := datasources.Lookup("climate")
+		if !ok {
+			logger.Warn(logger.StatusWarn, "Climate scoring disabled (set datasources.climate.dataset or llm_estimate)")
+			return
+		}
+		task.Start("climate", func(ctx context.Context, t *task.Task) error {
+			_, enriched, err := datasources.Apply(ctx, g, src)
+			if err != nil {
+				logger.Warn(logger.StatusWarn, "Climate scoring failed: %v", err)
+				syserr.Report(syserr.ModuleDataSource, "climate", err)
+				return err
+			}
+			logger.Success("Scored climate exposure for %d nodes", enriched)
+			return nil
+		})
+	case "scenario":
+		if len(parts) < 2 || parts[1] == "list" {
+			printScenarios()
+			return
+		}
+		switch parts[1] {
+		case "export":
+			if len(parts) < 4 {
+				logger.Warn(logger.StatusWarn, "Usage: scenario export <name> <file.json|file.yaml>")
+				return
+			}
+			sc, ok := simulation.LookupScenario(parts[2])
+			if !ok {
+				logger.Warn(logger.StatusWarn, "Unknown scenario %s (try 'scenario list')", parts[2])
+				return
+			}
+			if err := simulation.ExportScenario(sc, parts[3]); err != nil {
+				logger.Error(logger.StatusErr, "Error exporting scenario: %v", err)
+			} else {
+				logger.Success("Scenario %s exported to %s", sc.Name, parts[3])
+			}
+			return
+		case "import":
+			if len(parts) < 3 {
+				logger.Warn(logger.StatusWarn, "Usage: scenario import <file>")
+				return
+			}
+			sc, err := importScenario(parts[2])
+			if err != nil {
+				logger.Error(logger.StatusErr, "Error importing scenario: %v", err)
+			} else {
+				logger.Success("Scenario %s imported (run it with 'scenario run %s')", sc.Name, simulation.ScenarioKey(sc.Name))
+			}
+			return
+		case "compare":
+			if len(parts) < 3 {
+				logger.Warn(logger.StatusWarn, "Usage: scenario compare <name> [Region|Country]")
+				return
+			}
+			sc, ok := simulation.LookupScenario(parts[2])
+			if !ok {
+				logger.Warn(logger.StatusWarn, "Unknown scenario %s (try 'scenario list')", parts[2])
+				return
+			}
+			if len(parts) > 3 {
+				sc = sc.WithRegion(strings.Join(parts[3:], " "))
+			}
+			task.Start("compare: "+sc.Name, func(ctx context.Context, t *task.Task) error {
+				report, err := sim.Compare(sc, stressMon.Window, stressMon.Weights, 10)
+				if err != nil {
+					logger.Error(logger.StatusErr, "Scenario comparison failed: %v", err)
+					return err
+				}
+				hub.Broadcast(server.TypeScenarioComparison, report)
+				printComparison(report)
+				return nil
+			})
+			return
+		case "reload":
+			n, err := simulation.LoadLibrary(scenarioLibrary())
+			if err != nil {
+				logger.Error(logger.StatusErr, "Error loading scenario library: %v", err)
+			} else {
+				logger.Success("Loaded %d scenarios from %s", n, scenarioLibrary())
+			}
+			return
+		}
+		if parts[1] != "run" || len(parts) < 3 {
+			logger.Warn(logger.StatusWarn, "Usage: scenario run <name> [Region|Country]")
+			return
+		}
+		sc, ok := simulation.LookupScenario(parts[2])
+		if !ok {
+			logger.Warn(logger.StatusWarn, "Unknown scenario %s (try 'scenario list')", parts[2])
+			return
+		}
+		if len(parts) > 3 {
+			sc = sc.WithRegion(strings.Join(parts[3:], " "))
+		}
+		result, err := sim.RunScenario(sc)
+		if err != nil {
+			logger.Error(logger.StatusErr, "Scenario failed: %v", err)
+		}
+		if result != nil && len(result.Shocked) > 0 {
+			hub.Broadcast(server.TypeShockEvent, server.ShockPayload{
+				Kind:     server.ShockScenario,
+				Scenario: result.Scenario,
+				Targets:  result.Shocked,
+			})
+		}
+	case "boost":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: boost <NodeID> (e.g., boost india)")
+			return
+		}
+		targetID := parts[1]
+		sim.RunShock(simulation.ShockEvent{
+			TargetNodeID: targetID,
+			Description:  "Positive Economic Boom / Trade Agreement",
+			ImpactFactor: 1.5, // 50% increase
+		})
+		hub.Broadcast(server.TypeShockEvent, server.ShockPayload{
+			Kind:   server.ShockBoost,
+			Target: targetID,
+			Impact: 1.5,
+		})
+		// Update edge weights positively
+		updateEdgesForTest(g, targetID, 0.8, "Positive boost simulation")
+	case "simulate":
+		if len(parts) < 3 {
+			logger.Warn(logger.StatusWarn, "Usage: simulate <NodeID> <sentiment> (e.g., simulate india 0.5)")
+			return
+		}
+		targetID := parts[1]
+		sentiment := 0.0
+		fmt.Sscanf(parts[2], "%f", &sentiment)
+		if sentiment < -1.0 || sentiment > 1.0 {
+			logger.Warn(logger.StatusWarn, "Sentiment must be between -1.0 and 1.0")
+			return
+		}
+
+		// Apply to node health
+		impactFactor := 1.0 + sentiment
+		sim.RunShock(simulation.ShockEvent{
+			TargetNodeID: targetID,
+			Description:  fmt.Sprintf("Simulated news event (sentiment: %.2f)", sentiment),
+			ImpactFactor: impactFactor,
+		})
+
+		// Update edge weights
+		updateEdgesForTest(g, targetID, sentiment, fmt.Sprintf("Test simulation (%.2f)", sentiment))
+		logger.Success("Simulated news event for %s with sentiment %.2f", targetID, sentiment)
+	case "tickers":
+		switch {
+		case len(parts) == 1 || parts[1] == "coverage":
+			printTickerCoverage(g.TickerCoverage())
+		case parts[1] == "missing":
+			limit := 20
+			if len(parts) > 2 {
+				if n, err := strconv.Atoi(parts[2]); err == nil && n > 0 {
+					limit = n
+				}
+			}
+			printMissingTickers(g.MissingTickers(limit))
+		case parts[1] == "enrich":
+			limit := 50
+			if len(parts) > 2 {
+				if n, err := strconv.Atoi(parts[2]); err == nil && n > 0 {
+					limit = n
+				}
+			}
+			task.StartTimeout("tickers", time.Hour, func(ctx context.Context, t *task.Task) error {
+				report, err := marketMon.EnrichTickers(ctx, limit, t)
+				logger.Success("Ticker lookups: %d found, %d failed, %d skipped (failed in the last week)", report.Found, report.Failed, report.Skipped)
+				return err
+			})
+		default:
+			logger.Warn(logger.StatusWarn, "Usage: tickers [coverage] | tickers missing [N] | tickers enrich [N]")
+		}
+	case "impact":
+		switch {
+		case len(parts) == 1 || parts[1] == "list":
+			printImpacts(g.Impacts())
+		case parts[1] == "last":
+			kind := graph.ImpactKind("")
+			if len(parts) > 2 {
+				kind = graph.ImpactKind(parts[2])
+			}
+			if imp, ok := g.LastImpact(kind); ok {
+				printImpact(imp)
+			} else {
+				logger.Plain("No impacts recorded yet")
+			}
+		default:
+			if imp, ok := g.ImpactOf(parts[1]); ok {
+				printImpact(imp)
+			} else {
+				logger.Warn(logger.StatusWarn, "No impact kept for event %s ('impact list' shows the latest)", parts[1])
+			}
+		}
+	case "trace":
+		id := ""
+		if len(parts) > 1 {
+			id = parts[1]
+		}
+		printTrace(g, id)
+	case "describe":
+		limit := 0
+		if len(parts) > 1 {
+			n, err := strconv.Atoi(parts[1])
+			if err != nil || n < 0 {
+				logger.Warn(logger.StatusWarn, "Usage: describe [N]")
+				return
+			}
+			limit = n
+		}
+		index := newsEngine.Index
+		task.Start("describe nodes", func(ctx context.Context, t *task.Task) error {
+			n, err := index.Describe(ctx, limit)
+			if err != nil {
+				logger.Error(logger.StatusErr, "Describing nodes failed after %d: %v", n, err)
+				return err
+			}
+			logger.Success("Described %d nodes (%d indexed, %d pending)", n, index.Store.Len(), len(index.Pending()))
+			return nil
+		})
+	case "ask":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: ask <question>")
+			return
+		}
+		question := strings.Join(parts[1:], " ")
+		index := newsEngine.Index
+		task.Start("ask", func(ctx context.Context, t *task.Task) error {
+			logger.Plain("")
+			logger.Plain("Q: %s", question)
+			logger.Partial("A: ")
+			_, err := index.Ask(ctx, question, logger.Partial)
+			logger.Partial("\n")
+			if err != nil {
+				logger.Error(logger.StatusErr, "Answer failed: %v", err)
+			}
+			return err
+		})
+	case "search":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: search <text>")
+			return
+		}
+		query := strings.Join(parts[1:], " ")
+		matches, err := newsEngine.Index.Retrieve(context.Background(), query, 10)
+		if err != nil {
+			logger.Error(logger.StatusErr, "Search failed: %v", err)
+			return
+		}
+		printMatches(g, query, matches)
+	case "news":
+		if len(parts) > 1 && parts[1] == "status" {
+			printNewsStatus(newsEngine.Status())
+			return
+		}
+		task.Start("news", func(ctx context.Context, t *task.Task) error {
+			newsEngine.FetchAndProcess(ctx)
+			return nil
+		})
+	case "refresh":
+		task.StartTimeout("refresh", refresher.Timeout, func(ctx context.Context, t *task.Task) error {
+			if _, err := refresher.Refresh(ctx); err != nil {
+				logger.Warn(logger.StatusWarn, "Refresh skipped: %v", err)
+				return err
+			}
+			return nil
+		})
+	case "seed":
+		if len(parts) < 2 || parts[1] != "--starter" {
+			logger.Warn(logger.StatusWarn, "Usage: seed --starter [file|url] (use 'reseed' for LLM discovery)")
+			return
+		}
+		source := ""
+		if len(parts) > 2 {
+			source = parts[2]
+		}
+		task.StartTimeout("seed starter", config.Timeout(config.Global.Timeouts.Seed, 2*time.Hour), func(ctx context.Context, t *task.Task) error {
+			if err := seedStarter(ctx, g, source); err != nil {
+				logger.Error(logger.StatusErr, "Error loading starter dataset: %v", err)
+				syserr.Report(syserr.ModuleDiscovery, "seed starter", err)
+				return err
+			}
+			return nil
+		})
+	case "expand":
+		limit := 5
+		var types []graph.NodeType
+		for _, arg := range parts[1:] {
+			if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+				limit = n
+			} else {
+				types = append(types, graph.NodeType(arg))
+			}
+		}
+		seeder := newsEngine.Seeder
+		task.StartTimeout("expand frontier", config.Timeout(config.Global.Timeouts.Seed, 2*time.Hour), func(ctx context.Context, t *task.Task) error {
+			n, err := seeder.ExpandFrontier(ctx, g, limit, types...)
+			if err != nil {
+				logger.Error(logger.StatusErr, "Frontier expansion failed: %v", err)
+				syserr.Report(syserr.ModuleDiscovery, "expand frontier", err)
+				return err
+			}
+			logger.Success("Expanded %d frontier nodes: %s", n, g.String())
+			return nil
+		})
+	case "weights":
+		if len(parts) < 2 || parts[1] == "report" {
+			printWeightStats("Edge Weights", g.WeightStats())
+			return
+		}
+		if parts[1] != "normalize" {
+			logger.Warn(logger.StatusWarn, "Usage: weights [report] | weights normalize [rank|minmax] [--dry-run]")
+			return
+		}
+		policy := weightPolicyFromConfig()
+		target, dryRun := g, false
+		for _, arg := range parts[2:] {
+			if arg == "--dry-run" {
+				dryRun = true
+			} else {
+				policy.Method = graph.NormalizeMethod(arg)
+			}
+		}
+		if dryRun {
+			copied, err := g.Clone()
+			if err != nil {
+				logger.Error(logger.StatusErr, "Error copying graph: %v", err)
+				return
+			}
+			target = copied
+		}
+		report, err := target.NormalizeWeights(policy)
+		if err != nil {
+			logger.Error(logger.StatusErr, "Weight normalization failed: %v", err)
+			return
+		}
+		printWeightStats("Before", report.Before)
+		printWeightStats("After ("+string(report.Method)+")", report.After)
+		if dryRun {
+			logger.Info(logger.StatusOK, "Dry run: %d edge weights would change", report.Changed)
 		} else {
-			g.Replace(newG)
-			logger.Success("Graph loaded from %s (%s)", parts[1], g.String())
+			logger.Success("Normalized %d edge weights", report.Changed)
+		}
+	case "compact":
+		before := heapMB()
+		stats := g.Compact()
+		logger.Success("Compacted graph: %s (heap %.1f MB -> %.1f MB)", stats, before, heapMB())
+	case "aging":
+		printAging(g.EdgeAging())
+	case "prune":
+		criteria, dryRun, err := parsePrune(parts[1:])
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
+			logger.Warn(logger.StatusWarn, "Usage: prune --older-than 90d --weight-below 0.05 [--type T] [--dry-run]")
+			return
+		}
+		if dryRun {
+			candidates, err := g.PruneCandidates(criteria)
+			if err != nil {
+				logger.Error(logger.StatusErr, "Prune failed: %v", err)
+				return
+			}
+			printPruned(g, "Prune Preview", candidates)
+			logger.Info(logger.StatusOK, "Dry run: %d edges would be removed", len(candidates))
+			return
+		}
+		eventID := fmt.Sprintf("prune_%d", time.Now().Unix())
+		pruned, err := g.PruneEdges(criteria, eventID)
+		if err != nil {
+			logger.Error(logger.StatusErr, "Prune failed: %v", err)
+			return
+		}
+		printPruned(g, "Pruned", pruned)
+		logger.Success("Pruned %d edges (history event %s): %s", len(pruned), eventID, g.String())
+	case "watch":
+		if len(parts) < 2 {
+			watching := hub.Watching()
+			if len(watching) == 0 {
+				logger.Plain("Not watching any nodes. Usage: watch <nodeID>")
+				return
+			}
+			logger.Plain("Watching: %s", strings.Join(watching, ", "))
+			return
+		}
+		node, exists := g.GetNode(parts[1])
+		if !exists {
+			logger.Warn(logger.StatusWarn, "Node not found: %s", parts[1])
+			return
+		}
+		hub.Watch(node.ID)
+		logger.Success("Watching %s (health %.3f); 'unwatch %s' to stop", node.ID, node.Health, node.ID)
+	case "unwatch":
+		nodeID := ""
+		if len(parts) > 1 {
+			nodeID = parts[1]
+		}
+		removed := hub.Unwatch(nodeID)
+		switch {
+		case !removed && nodeID == "":
+			logger.Warn(logger.StatusWarn, "Not watching any nodes")
+		case !removed:
+			logger.Warn(logger.StatusWarn, "Not watching %s", nodeID)
+		case nodeID == "":
+			logger.Success("Stopped watching all nodes")
+		default:
+			logger.Success("Stopped watching %s", nodeID)
+		}
+	case "propagation":
+		handlePropagation(g, parts[1:])
+	case "llmlog":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: llmlog <entity|key>")
+			return
+		}
+		printLLMLog(strings.Join(parts[1:], " "))
+	case "audit":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: audit <nodeID>")
+			return
+		}
+		printAudit(parts[1])
+	case "explain":
+		if len(parts) < 3 {
+			logger.Warn(logger.StatusWarn, "Usage: explain <SRC> <TGT> [Type]")
+			return
+		}
+		var edgeType graph.EdgeType
+		if len(parts) > 3 {
+			edgeType = graph.EdgeType(parts[3])
+		}
+		printExplain(g, parts[1], parts[2], edgeType)
+	case "wal":
+		n := 20
+		if len(parts) > 1 {
+			if v, err := strconv.Atoi(parts[1]); err == nil && v > 0 {
+				n = v
+			}
+		}
+		printWAL(n)
+	case "status":
+		if len(parts) < 2 || parts[1] == "list" {
+			printStatusOverrides(g)
+			return
+		}
+		usage := "Usage: status <SRC> <TGT> <Type> <Strong|Active|Weak|Blocked|Suspended> [--until YYYY-MM-DD] [--hs CODE] [reason] | status clear <SRC> <TGT> <Type> [--hs CODE]"
+		clearing := parts[1] == "clear"
+		args := parts[1:]
+		if clearing {
+			args = parts[2:]
+		}
+		edge, rest, err := parseStatusArgs(args)
+		if err != nil || len(edge.positional) < 3 || (!clearing && len(edge.positional) < 4) {
+			if err != nil {
+				logger.Warn(logger.StatusWarn, "%v", err)
+			}
+			logger.Warn(logger.StatusWarn, "%s", usage)
+			return
+		}
+		src, tgt, edgeType := edge.positional[0], edge.positional[1], graph.EdgeType(edge.positional[2])
+		if clearing {
+			if err := g.ClearEdgeStatus(src, tgt, edgeType, edge.hsCode); err != nil {
+				logger.Error(logger.StatusErr, "%v", err)
+				return
+			}
+			logger.Success("Cleared status override on %s -[%s]-> %s", src, edgeType, tgt)
+			return
+		}
+		status, err := graph.ParseEdgeStatus(edge.positional[3])
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
+			return
+		}
+		reason := strings.Join(append(edge.positional[4:], rest...), " ")
+		if err := g.SetEdgeStatus(src, tgt, edgeType, edge.hsCode, status, edge.until, reason); err != nil {
+			logger.Error(logger.StatusErr, "%v", err)
+			return
+		}
+		until := "until cleared"
+		if !edge.until.IsZero() {
+			until = "until " + clock.Format(edge.until, "2006-01-02")
+		}
+		logger.Success("%s -[%s]-> %s marked %s %s", src, edgeType, tgt, status, until)
+	case "note":
+		usage := "Usage: note <nodeID> <text> [#tag ...] | note edge <SRC> <TGT> <Type> [--hs CODE] <text> [#tag ...] | note rm <noteID>"
+		if len(parts) < 3 {
+			logger.Warn(logger.StatusWarn, "%s", usage)
+			return
+		}
+		if parts[1] == "rm" {
+			note, err := g.DeleteNote(parts[2])
+			if err != nil {
+				logger.Error(logger.StatusErr, "%v", err)
+				return
+			}
+			logger.Success("Removed note %s on %s", note.ID, note.Subject())
+			return
+		}
+		note := graph.Note{NodeID: parts[1], Author: consoleAuthor()}
+		words := parts[2:]
+		if parts[1] == "edge" {
+			edge, rest, err := parseStatusArgs(parts[2:])
+			if err != nil || len(edge.positional) < 3 {
+				if err != nil {
+					logger.Warn(logger.StatusWarn, "%v", err)
+				}
+				logger.Warn(logger.StatusWarn, "%s", usage)
+				return
+			}
+			note = graph.Note{
+				SourceID:  edge.positional[0],
+				TargetID:  edge.positional[1],
+				EdgeType:  graph.EdgeType(edge.positional[2]),
+				Commodity: edge.hsCode,
+				Author:    note.Author,
+			}
+			words = append(edge.positional[3:], rest...)
+		}
+		note.Text, note.Tags = graph.ParseNoteText(strings.Join(words, " "))
+		note, err := g.AddNote(note)
+		if err != nil {
+			logger.Error(logger.StatusErr, "%v", err)
+			return
+		}
+		logger.Success("Note %s added to %s", note.ID, note.Subject())
+	case "notes":
+		var notes []graph.Note
+		title := "Analyst Notes"
+		switch {
+		case len(parts) < 2:
+			notes = g.FindNotes(nil)
+		case strings.HasPrefix(parts[1], "#"):
+			notes = g.FindNotes(func(n graph.Note) bool { return n.HasTag(parts[1]) })
+			title += ": " + parts[1]
+		default:
+			notes = g.NodeNotes(parts[1])
+			title += ": " + parts[1]
+		}
+		printNotes(title, notes)
+	case "exploration":
+		printExploration(g)
+	case "reseed":
+		logger.Warn(logger.StatusWarn, "WARNING: Reseeding will clear current graph and rebuild from scratch!")
+		logger.Info(logger.StatusInit, "Starting reseed process...")
+
+		// Clear the graph safely
+		g.Clear()
+
+		logger.Success("Graph cleared. Starting discovery...")
+
+		// Run seeder in background
+		task.StartTimeout("reseed", config.Timeout(config.Global.Timeouts.Seed, 2*time.Hour), func(ctx context.Context, t *task.Task) error {
+			client := llm.NewClient()
+			seeder := discovery.NewSeeder(client)
+			if err := seeder.Seed(ctx, g); err != nil {
+				logger.Error(logger.StatusErr, "Error seeding graph: %v", err)
+				syserr.Report(syserr.ModuleDiscovery, "seed", err)
+				return err
+			}
+			logger.Success("Graph reseeded successfully: %s", g.String())
+			// Save the new graph
+			if err := g.Save(graphFile); err != nil {
+				logger.Warn(logger.StatusWarn, "Failed to save reseeded graph: %v", err)
+			}
+			return nil
+		})
+	case "social":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: social <Topic>")
+			return
+		}
+		topic := strings.Join(parts[1:], " ")
+		if socialMon.Enqueue(social.CrawlJob{Topic: topic}, true) {
+			logger.Info(logger.StatusSoc, "Crawl of '%s' queued (%d waiting)", topic, len(socialMon.QueuedCrawls()))
+		}
+	case "mentions":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: mentions <nodeID>")
+			return
+		}
+		printMentions(g, parts[1], socialMon.Volume)
+	case "comentions":
+		learner := newsEngine.CoMentions
+		if learner == nil {
+			logger.Warn(logger.StatusWarn, "Co-mention learning is not running")
+			return
+		}
+		if len(parts) < 2 {
+			printCoMentions(learner)
+			return
+		}
+		if parts[1] != "accept" || len(parts) < 4 {
+			logger.Warn(logger.StatusWarn, "Usage: comentions [accept <ID1> <ID2>]")
+			return
+		}
+		edge, err := learner.Accept(parts[2], parts[3])
+		if err != nil {
+			logger.Error(logger.StatusErr, "%v", err)
+			return
+		}
+		logger.Success("Added %s -[%s]-> %s (weight %.2f)", edge.SourceID, edge.Type, edge.TargetID, edge.Weight)
+	case "save":
+		if len(parts) < 2 {
+			logger.Warn(logger.StatusWarn, "Usage: save <filename.json>")
+			return
+		}
+		if err := g.Save(parts[1]); err != nil {
+			logger.Error(logger.StatusErr, "Error saving graph: %v", err)
+		} else {
+			logger.Success("Graph saved to %s", parts[1])
+		}
+	case "load":
+		if len(parts) < 2 || strings.HasPrefix(parts[1], "--") {
+			logger.Warn(logger.StatusWarn, "Usage: load <filename.json> [--strict] [--discover]")
+			return
+		}
+		loadGraph, discover := graph.Load, false
+		for _, opt := range parts[2:] {
+			switch opt {
+			case "--strict":
+				loadGraph = graph.LoadStrict
+			case "--discover":
+				discover = true
+			default:
+				logger.Warn(logger.StatusWarn, "Unknown load option %s (use --strict, --discover)", opt)
+				return
+			}
+		}
+		newG, err := loadGraph(parts[1])
+		if err != nil {
+			logger.Error(logger.StatusErr, "Error loading graph: %v", err)
+			return
+		}
+		g.Replace(newG)
+		logger.Success("Graph loaded from %s (%s)", parts[1], g.String())
+		if discover {
+			added := g.DiscoverSupplyChainRelations()
+			logger.Success("Discovered %d supply chain edges from existing relationships", added)
+		}
+	case "merge":
+		usage := "Usage: merge <filename.json> [--strategy prefer-newer|prefer-confidence|review]"
+		if len(parts) < 2 || strings.HasPrefix(parts[1], "--") {
+			logger.Warn(logger.StatusWarn, "%s", usage)
+			return
+		}
+		strategy := graph.MergePreferNewer
+		for i := 2; i < len(parts); i++ {
+			if parts[i] != "--strategy" || i+1 >= len(parts) {
+				logger.Warn(logger.StatusWarn, "%s", usage)
+				return
+			}
+			parsed, err := graph.ParseMergeStrategy(parts[i+1])
+			if err != nil {
+				logger.Warn(logger.StatusWarn, "%v", err)
+				return
+			}
+			strategy = parsed
+			i++
+		}
+		other, err := graph.Load(parts[1])
+		if err != nil {
+			logger.Error(logger.StatusErr, "Error loading graph: %v", err)
+			return
+		}
+		printMerge(parts[1], g.Merge(other, strategy), strategy)
+	case "export":
+		if len(parts) < 2 || strings.HasPrefix(parts[1], "--") {
+			logger.Warn(logger.StatusWarn, "Usage: export <filename.dot> [--type T] [--min-weight W] [--around ID] [--hops K] [--color-health] [--weight-width] | export <filename.json|filename.graphml>")
+			return
+		}
+		switch strings.ToLower(filepath.Ext(parts[1])) {
+		case ".json", ".graphml":
+			if len(parts) > 2 {
+				logger.Warn(logger.StatusWarn, "Filters and styling only apply to DOT exports")
+				return
+			}
+			if err := g.ExportFile(parts[1]); err != nil {
+				logger.Error(logger.StatusErr, "Error exporting graph: %v", err)
+			} else {
+				logger.Success("Graph exported to %s", parts[1])
+			}
+			return
+		}
+		opts, err := parseDOTOptions(parts[2:])
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
+			return
+		}
+		dot, err := g.ToDOTWith(opts)
+		if err != nil {
+			logger.Error(logger.StatusErr, "Error exporting DOT: %v", err)
+			return
+		}
+		if err := os.WriteFile(parts[1], []byte(dot), 0644); err != nil {
+			logger.Error(logger.StatusErr, "Error exporting DOT: %v", err)
+		} else {
+			logger.Success("Graph exported to %s", parts[1])
+		}
+	case "embed":
+		file, args := embeddingsFile(), parts[1:]
+		if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+			file, args = args[0], args[1:]
+		}
+		opts, err := parseEmbedOptions(args, embeddingOptions())
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
+			logger.Warn(logger.StatusWarn, "Usage: embed [file.json|file.csv] [--dim D] [--walks N] [--length L] [--window W] [--p P] [--q Q] [--epochs E] [--seed S]")
+			return
+		}
+		task.Start("embed", func(ctx context.Context, t *task.Task) error {
+			summary, err := saveEmbeddings(ctx, g, opts, file, t)
+			if err != nil {
+				logger.Error(logger.StatusErr, "Embedding failed: %v", err)
+				return err
+			}
+			logger.Success("Embedded %s", summary)
+			return nil
+		})
+	case "central":
+		top, args := 20, parts[1:]
+		if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n <= 0 {
+				logger.Warn(logger.StatusWarn, "Usage: central [N] [--exact|--sample] [--sources K] [--seed S]")
+				return
+			}
+			top, args = n, args[1:]
+		}
+		opts, err := parseSamplingOptions(args, analyticsOptions())
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
+			logger.Warn(logger.StatusWarn, "Usage: central [N] [--exact|--sample] [--sources K] [--seed S]")
+			return
+		}
+		task.Start("central", func(ctx context.Context, t *task.Task) error {
+			report, err := g.Betweenness(ctx, opts, top)
+			if err != nil {
+				logger.Error(logger.StatusErr, "Centrality failed: %v", err)
+				return err
+			}
+			printBetweenness(report)
+			return nil
+		})
+	case "hitting":
+		if len(parts) < 3 {
+			logger.Warn(logger.StatusWarn, "Usage: hitting <FROM> <TO> [--exact|--sample] [--walks N] [--seed S]")
+			return
+		}
+		opts, err := parseSamplingOptions(parts[3:], analyticsOptions())
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
+			logger.Warn(logger.StatusWarn, "Usage: hitting <FROM> <TO> [--exact|--sample] [--walks N] [--seed S]")
+			return
+		}
+		from, to := parts[1], parts[2]
+		task.Start("hitting "+from, func(ctx context.Context, t *task.Task) error {
+			h, err := g.HittingTime(ctx, from, to, opts)
+			if err != nil {
+				logger.Error(logger.StatusErr, "Hitting time failed: %v", err)
+				return err
+			}
+			printHittingTime(h)
+			return nil
+		})
+	case "exit", "quit", "q":
+		logger.Info(logger.StatusOK, "Shutting down...")
+		task.CancelAll()
+		tuiApp.Stop()
+	case "help", "?":
+		logger.Plain("")
+		logger.Section("Available Commands")
+		logger.Plain("  show          - Show all nodes and edges")
+		logger.Plain("  edges         - Show edge directionality rules")
+		logger.Plain("  types         - List node and edge types, built-in and declared under types in config.yaml, with counts")
+		logger.Plain("  discover      - Discover supplier/client relationships and chokepoint routes")
+		logger.Plain("  companies     - List all companies in the graph")
+		logger.Plain("  relations <ID>- Show supplier/client/ownership relations for a company, or trade/industry relations for a nation")
+		logger.Plain("  mermaid <ID> [F] - Mermaid flowchart of a company's supply chain (printed, or saved to F; .md adds a code fence)")
+		logger.Plain("  shock <ID> [HS] - Simulate a trade ban/shock on a Node ID (optionally one commodity)")
+		logger.Plain("  shock region <R> - Shock every node in a region or country (e.g., shock region Southeast Asia)")
+		logger.Plain("  trace [event] - Show how the last shock (or shock event) spread, hop by hop")
+		logger.Plain("  tickers [missing [N] | enrich [N]] - Ticker coverage by industry; look up missing ones, most central first")
+		logger.Plain("  impact last [kind] | impact <event> | impact list - Rank the nodes an event hit hardest")
+		logger.Plain("  monetary <CUR> <devalue|hike|cut> <M> - Currency devaluation or rate move (e.g., monetary TRY devalue 0.3)")
+		logger.Plain("  scenario list - List built-in and library scenarios (drought, carbon_tax, ...)")
+		logger.Plain("  scenario run <name> [R] - Run a scenario, optionally limited to a region/country")
+		logger.Plain("  scenario compare <name> [R] - Run a scenario on a copy of the graph and report baseline vs scenario")
+		logger.Plain("  scenario export <name> <F> - Save a scenario to a shareable .json/.yaml file")
+		logger.Plain("  scenario import <F> - Add a scenario file to the library")
+		logger.Plain("  scenario reload - Rescan the scenario library directory")
+		logger.Plain("  climate       - Score climate exposure for unscored nodes")
+		logger.Plain("  seed --starter [F] - Merge the starter dataset (built-in, or file/URL F) into the graph")
+		logger.Plain("  weights [report] - Show the weight distribution of each edge type")
+		logger.Plain("  weights normalize [rank|minmax] [--dry-run] - Rescale weights within each edge type, with a before/after report")
+		logger.Plain("  compact       - Share repeated strings and trim spare capacity to cut graph memory")
+		logger.Plain("  aging         - Count edges by age of their last supporting evidence")
+		logger.Plain("  prune --older-than 90d --weight-below 0.05 [--type T] [--dry-run] - Remove dead edges (preview with --dry-run)")
+		logger.Plain("  status [list] - List edges whose status is manually overridden")
+		logger.Plain("  status <SRC> <TGT> <Type> <Status> [--until YYYY-MM-DD] [--hs CODE] [reason] - Pin an edge's status (e.g., a known embargo)")
+		logger.Plain("  status clear <SRC> <TGT> <Type> [--hs CODE] - Return an edge's status to its weight")
+		logger.Plain("  note <nodeID> <text> [#tag ...] - Attach an analyst note to a node")
+		logger.Plain("  note edge <SRC> <TGT> <Type> [--hs CODE] <text> [#tag ...] - Attach an analyst note to an edge")
+		logger.Plain("  note rm <noteID> - Delete a note")
+		logger.Plain("  notes [nodeID|#tag] - List analyst notes on a node and its edges, or with a tag (default: all)")
+		logger.Plain("  watch [nodeID] - Stream every change touching a node (edges, health, news, sentiment); no ID lists watched nodes")
+		logger.Plain("  unwatch [nodeID] - Stop watching a node, or all nodes")
+		logger.Plain("  propagation [show] - Show the share of a shock each edge type passes on")
+		logger.Plain("  propagation set <EdgeType> <factor> [SrcType TgtType] - Tune a factor, optionally between node types (* = any); saved across restarts")
+		logger.Plain("  propagation reset <EdgeType> [SrcType TgtType] | propagation reset all - Drop tuned factors")
+		logger.Plain("  audit <nodeID> - Show who added a node and its relationships, when, why and on what evidence")
+		logger.Plain("  explain <SRC> <TGT> [Type] - Explain an edge: weight, direction, confidence, evidence, history and the events that moved it")
+		logger.Plain("  wal [N] - Show the last N graph changes in the write-ahead log (default 20)")
+		logger.Plain("  llmlog <entity|key> - Show the LLM exchanges that named an entity, or one exchange by key")
+		logger.Plain("  exploration   - Count nodes by exploration state (unexplored / partial / complete)")
+		logger.Plain("  expand [N] [Type] - Explore N (default 5) nodes earlier runs left unexplored, e.g. expand 3 Nation")
+		logger.Plain("  tasks         - List background tasks (seeding, crawls) with progress")
+		logger.Plain("  cancel <T>    - Cancel background task T (e.g., cancel t3)")
+		logger.Plain("  jobs [J|run J] - List scheduled jobs, show job J's recent runs, or run J now")
+		logger.Plain("  history [snapshot|<ID>|<SRC> <TGT>] - List recorded days, record today now, or show a node's or edge's daily history")
+		logger.Plain("  errors        - Recent subsystem failures (LLM, news, data sources, ...)")
+		logger.Plain("  stress        - Show the early-warning stress ranking")
+		logger.Plain("  stress --type <NodeType> [--impact 0.3] [--affected Corporation] [--top N] - Shock every node of a type in turn on a copy of the graph and rank the nodes hit most")
+		logger.Plain("  anomalies [H] - Edge weight collapses and spikes against each edge's history in the last H hours")
+		logger.Plain("  industries    - Show sector health (market-cap weighted) and supplier-country concentration per industry")
+		logger.Plain("  portfolio [add <TICKER> <qty>|remove <TICKER>] - Show or edit holdings and their exposure to nations, suppliers and raw materials")
+		logger.Plain("  paper         - Show the paper trader's PnL, open risk, signal hit rate and daily NAV")
+		logger.Plain("  pairs [scan [yahoo|graph]] - Show the monitored pairs' z-scores from the last scan, or rescan now (on Yahoo or recorded prices)")
+		logger.Plain("  calendar [nodeID] [days] - Show scheduled earnings and economic events (default: all nodes, 14 days)")
+		logger.Plain("  calendar refresh - Refetch earnings dates and reload the economic calendar")
+		logger.Plain("  providers     - Show the LLM failover chain in the order it is tried, with recent failure rates")
+		logger.Plain("  budget        - Show today's LLM requests and tokens per consumer against llm.budgets")
+		logger.Plain("  countries     - List country names with no ISO code, to map under countries.aliases")
+		logger.Plain("  pipeline [start|stop <P>] - List or toggle background engines (news, social, market, ...)")
+		logger.Plain("  boost <ID>    - Simulate positive news boost for a Node ID")
+		logger.Plain("  describe [N]  - Write and embed descriptions for up to N undescribed nodes (all by default)")
+		logger.Plain("  ask <question> - Answer a question about the graph, streaming the answer as it is written")
+		logger.Plain("  search <text> - Find nodes whose descriptions match text (e.g., search chip foundry)")
+		logger.Plain("  news          - Force check for latest news")
+		logger.Plain("  news status   - Show each feed's interval, quiet hours and next poll")
+		logger.Plain("  refresh       - Re-pull World Bank / Comtrade data for nations")
+		logger.Plain("  simulate <ID> <sentiment> - Test news impact (sentiment: -1.0 to 1.0)")
+		logger.Plain("  social <T>    - Crawl real social media for Topic T")
+		logger.Plain("  mentions <ID> - Show a node's social mentions per hour over the last day against its baseline")
+		logger.Plain("  comentions [accept <ID1> <ID2>] - List edges proposed from nodes named together in news and posts, or add one")
+		logger.Plain("  save <F>      - Save graph to file F")
+		logger.Plain("  load <F> [--strict] [--discover] - Load graph from file F; --strict rejects invalid data, --discover derives supplier/client edges")
+		logger.Plain("  merge <F> [--strategy S] - Merge graph file F into this one, matching duplicate nodes (S: prefer-newer, prefer-confidence, review)")
+		logger.Plain("  export <F>    - Export graph to file F: DOT, or streamed JSON/GraphML for .json/.graphml")
+		logger.Plain("  export <F> [--type T] [--min-weight W] [--around ID] [--hops K] [--color-health] [--weight-width] - Export part of the graph")
+		logger.Plain("  export <F.pyg.json> - Export node features and edge index as PyTorch Geometric tensors")
+		logger.Plain("  embed [F] [--dim D] [--walks N] [--length L] [--window W] [--p P] [--q Q] [--epochs E] [--seed S] - Learn node2vec node vectors, saved to F (.json or .csv; default embeddings.file)")
+		logger.Plain("  central [N] [--exact|--sample] [--sources K] - Rank the N nodes most shortest paths run through; sampled above analytics.threshold nodes")
+		logger.Plain("  hitting <FROM> <TO> [--exact|--sample] [--walks N] - Expected steps a weighted random walk takes from FROM to reach TO")
+		logger.Plain("  run <F>       - Run the commands in file F, one line at a time (# starts a comment)")
+		logger.Plain("  <name> = <cmd>; <cmd> - Define a macro ($1.. and $* are its arguments); \"<name> =\" removes it")
+		logger.Plain("  macros        - List the defined macros")
+		logger.Plain("  <cmd>; <cmd>  - Run several commands in turn; sleep <duration> pauses between them")
+		logger.Plain("  exit          - Quit")
+	default:
+		logger.Warn(logger.StatusWarn, "Unknown command: %s (type 'help' for commands)", parts[0])
+	}
+}
+
+// adminActions are the operational commands served at /admin/<action>.
+// Actions that change the graph are refused on replicas, whose graph
+// follows the writer.
+func adminActions(g *graph.Graph, seeder *discovery.Seeder, ragIndex *rag.Index, scheduler *jobs.Scheduler, graphFile string, replica bool) map[string]server.AdminAction {
+	started := time.Now()
+	writerOnly := func(action server.AdminAction) server.AdminAction {
+		return func(ctx context.Context, args map[string]string) (interface{}, error) {
+			if replica {
+				return nil, fmt.Errorf("this instance is a replica; run it on the writer")
+			}
+			return action(ctx, args)
+		}
+	}
+
+	return map[string]server.AdminAction{
+		"save": writerOnly(func(ctx context.Context, args map[string]string) (interface{}, error) {
+			if err := g.Save(graphFile); err != nil {
+				return nil, err
+			}
+			logger.Success("Graph saved to %s (admin)", graphFile)
+			return map[string]interface{}{"file": graphFile, "nodes": len(g.Nodes), "edges": len(g.Edges)}, nil
+		}),
+		"decay": writerOnly(func(ctx context.Context, args map[string]string) (interface{}, error) {
+			lambda := decayLambda
+			if v, ok := args["lambda"]; ok {
+				l, err := strconv.ParseFloat(v, 64)
+				if err != nil || l <= 0 {
+					return nil, fmt.Errorf("invalid lambda %q", v)
+				}
+				lambda = l
+			}
+			n := g.ApplyTemporalDecay(lambda)
+			logger.Info(logger.StatusOK, "Temporal decay updated %d edges (admin, λ=%.3f)", n, lambda)
+			return map[string]interface{}{"updated": n, "lambda": lambda}, nil
+		}),
+		"discover": writerOnly(func(ctx context.Context, args map[string]string) (interface{}, error) {
+			id := args["node"]
+			if _, ok := g.GetNode(id); !ok {
+				return nil, fmt.Errorf("node %q not found (pass ?node=<id>)", id)
+			}
+			t := task.StartTimeout("discover "+id, config.Timeout(config.Global.Timeouts.Expansion, 30*time.Minute), func(ctx context.Context, t *task.Task) error {
+				if err := seeder.ExpandNode(ctx, g, id); err != nil {
+					logger.Error(logger.StatusErr, "Discovery for %s failed: %v", id, err)
+					syserr.Report(syserr.ModuleDiscovery, "discover "+id, err)
+					return err
+				}
+				logger.Success("Discovery for %s finished: %s", id, g.String())
+				return nil
+			})
+			return t.Info(), nil
+		}),
+		"reload": func(ctx context.Context, args map[string]string) (interface{}, error) {
+			if err := config.Reload(); err != nil {
+				return nil, fmt.Errorf("%s: %w", config.ConfigPath(), err)
+			}
+			if err := pipeline.Configure(config.Global.Pipelines); err != nil {
+				return nil, err
+			}
+			if err := declareTypesFromConfig(); err != nil {
+				return nil, err
+			}
+			g.ApplyDeclaredDirectionality()
+			healthModel, err := healthModelFromConfig()
+			if err != nil {
+				return nil, err
+			}
+			g.SetHealthModel(healthModel)
+			propagationModel, err := propagationModelFromConfig()
+			if err != nil {
+				return nil, err
+			}
+			g.SetPropagationModel(propagationModel)
+			confidenceModel, err := confidenceModelFromConfig()
+			if err != nil {
+				return nil, err
+			}
+			g.SetConfidenceModel(confidenceModel)
+			applyCountryAliases()
+			ragIndex.LinkThreshold = config.Global.RAG.LinkThreshold
+			logger.Success("Configuration reloaded (admin)")
+			return map[string]interface{}{"reloaded": true, "note": "port, bus and worker intervals apply after a restart"}, nil
+		},
+		"diagnostics": func(ctx context.Context, args map[string]string) (interface{}, error) {
+			var mem runtime.MemStats
+			runtime.ReadMemStats(&mem)
+			return map[string]interface{}{
+				"version":     config.Global.App.Version,
+				"uptime":      time.Since(started).Round(time.Second).String(),
+				"replica":     replica,
+				"nodes":       len(g.Nodes),
+				"edges":       len(g.Edges),
+				"exploration": g.ExplorationCounts(),
+				"vectors":     ragIndex.Store.Len(),
+				"goroutines":  runtime.NumGoroutine(),
+				"heap_mb":     float64(mem.HeapAlloc) / (1 << 20),
+				"sys_mb":      float64(mem.Sys) / (1 << 20),
+				"gc_runs":     mem.NumGC,
+				"pipelines":   pipeline.All(),
+				"tasks":       task.List(),
+				"jobs":        scheduler.List(),
+				"errors":      syserr.Recent(),
+			}, nil
+		},
+	}
+}
+
+// heapMB returns the live heap after a garbage collection, in MB
+func heapMB() float64 {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return float64(mem.HeapAlloc) / (1 << 20)
+}
+
+// portfolioMonitorFromConfig builds the portfolio monitor from the portfolio
+// section, loading the saved holdings
+func portfolioMonitorFromConfig(g *graph.Graph, hub *server.Hub) (*simulation.PortfolioMonitor, error) {
+	cfg := config.Global.Portfolio
+	path := cfg.File
+	if path == "" {
+		path = "margraf_portfolio.json"
+	}
+	m, err := simulation.NewPortfolioMonitor(g, hub, config.DataPath(path))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Depth > 0 {
+		m.Depth = cfg.Depth
+	}
+	if cfg.Threshold > 0 {
+		m.Threshold = cfg.Threshold
+	}
+	return m, nil
+}
+
+// openLLMLog opens the LLM exchange log from llm.log
+func openLLMLog() error {
+	cfg := config.Global.LLM.Log
+	path := cfg.Path
+	if path == "" {
+		path = "margraf_llm.jsonl"
+	}
+	if path == "off" {
+		return nil
+	}
+	return llm.OpenExchangeLog(config.DataPath(path), llm.ExchangeLogOptions{
+		MaxBytes: int64(cfg.MaxSizeMB) << 20,
+		Backups:  cfg.Backups,
+		Redact:   cfg.Redact,
+	})
+}
+
+// llmBudgetFromConfig builds the shared LLM budget from llm.budgets, or
+// returns nil when no consumer is limited
+func llmBudgetFromConfig() *llm.Budget {
+	if len(config.Global.LLM.Budgets) == 0 {
+		return nil
+	}
+	limits := make(map[string]llm.Limit, len(config.Global.LLM.Budgets))
+	for consumer, b := range config.Global.LLM.Budgets {
+		limits[consumer] = llm.Limit{Requests: b.Requests, Tokens: b.Tokens, OnExhausted: strings.ToLower(b.OnExhausted)}
+	}
+	return llm.NewBudget(limits)
+}
+
+// hedgerFromConfig builds the scenario report's hedger, fetching
+// portfolio.hedge_days of prices for pair hedges
+func hedgerFromConfig(m *simulation.PortfolioMonitor) *simulation.Hedger {
+	days := config.Global.Portfolio.HedgeDays
+	if days < 0 {
+		return simulation.NewHedger(m, nil)
+	}
+	if days == 0 {
+		days = 90
+	}
+	return simulation.NewHedger(m, priceSourceFromConfig("portfolio.hedge_prices", config.Global.Portfolio.HedgePrices, m.Graph, days))
+}
+
+// priceSourceFromConfig builds the price history source a config key names,
+// falling back to Yahoo when it names none
+func priceSourceFromConfig(key, source string, g *graph.Graph, days int) trading.PriceSource {
+	prices, err := trading.NewPriceSource(strings.ToLower(source), g, days)
+	if err != nil {
+		logger.Warn(logger.StatusWarn, "%s: %v; using yahoo", key, err)
+		return trading.NewPriceCache(days)
+	}
+	return prices
+}
+
+// paperMonitorFromConfig builds the paper trader from the paper section,
+// resuming its saved ledger and trading each configured pair
+func paperMonitorFromConfig(g *graph.Graph, hub *server.Hub) (*simulation.PaperMonitor, error) {
+	cfg := config.Global.Paper
+	path := cfg.File
+	if path == "" {
+		path = "margraf_paper.json"
+	}
+	capital, size := cfg.Capital, cfg.PositionSize
+	if capital <= 0 {
+		capital = 100000
+	}
+	if size <= 0 {
+		size = 10000
+	}
+	trader, err := trading.NewPaperTrader(config.DataPath(path), capital, size, cfg.Commission)
+	if err != nil {
+		return nil, err
+	}
+	entry, exit, stop, lookback := cfg.Entry, cfg.Exit, cfg.StopLoss, cfg.Lookback
+	if entry <= 0 {
+		entry = 2.0
+	}
+	if exit <= 0 {
+		exit = 0.5
+	}
+	if stop <= 0 {
+		stop = 0.05
+	}
+	if lookback <= 1 {
+		lookback = 20
+	}
+	constraints := trading.Constraints{
+		MaxHolding: time.Duration(cfg.MaxHolding * float64(time.Hour)),
+		Cooldown:   time.Duration(cfg.Cooldown * float64(time.Hour)),
+		Target:     strings.EqualFold(cfg.EventMode, "target"),
+	}
+	specs := make([]config.StrategyConfig, 0, len(cfg.Pairs)+len(cfg.Strategies))
+	for _, pair := range cfg.Pairs {
+		t1, t2, ok := strings.Cut(pair, "/")
+		if !ok || t1 == "" || t2 == "" {
+			logger.Warn(logger.StatusWarn, "Skipping paper pair %q (want TICKER1/TICKER2)", pair)
+			continue
+		}
+		specs = append(specs, config.StrategyConfig{Name: "pairs", Tickers: pair})
+	}
+	for _, sc := range append(specs, cfg.Strategies...) {
+		if sc.Entry <= 0 {
+			sc.Entry = entry
+		}
+		if sc.Exit <= 0 {
+			sc.Exit = exit
+		}
+		if sc.StopLoss <= 0 {
+			sc.StopLoss = stop
+		}
+		if sc.Lookback <= 0 {
+			sc.Lookback = lookback
+		}
+		if len(sc.ScaleIn) == 0 {
+			sc.ScaleIn = cfg.ScaleIn
+		}
+		if len(sc.ScaleOut) == 0 {
+			sc.ScaleOut = cfg.ScaleOut
+		}
+		s, err := trading.NewStrategy(sc)
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "Skipping paper strategy %q: %v", sc.Name, err)
+			continue
+		}
+		trader.AddStrategy(s, constraints)
+	}
+	m := simulation.NewPaperMonitor(g, hub, trader)
+	m.Currency = strings.ToUpper(cfg.Currency)
+	if m.Currency == "" {
+		m.Currency = "USD"
+	}
+	if cfg.Events > 0 {
+		m.EventBefore = time.Duration(cfg.Events) * 24 * time.Hour
+		m.EventAfter = m.EventBefore
+	}
+	return m, nil
+}
+
+// pairMonitorFromConfig builds the pair monitor from the pairs section,
+// keeping the defaults for anything unset
+func pairMonitorFromConfig(g *graph.Graph, hub *server.Hub) *simulation.PairMonitor {
+	cfg := config.Global.Pairs
+	days := cfg.Days
+	if days <= 0 {
+		days = 90
+	}
+	m := simulation.NewPairMonitor(g, hub, priceSourceFromConfig("pairs.prices", cfg.Prices, g, days))
+	if cfg.Top > 0 {
+		m.Top = cfg.Top
+	}
+	if cfg.Entry > 0 {
+		m.Entry = cfg.Entry
+	}
+	if cfg.Lookback > 1 {
+		m.Lookback = cfg.Lookback
+	}
+	if cfg.Shrinkage > 0 {
+		m.Shrinkage = cfg.Shrinkage
+	}
+	return m
+}
+
+// anomalyMonitorFromConfig builds the edge anomaly monitor from the anomalies
+// section, keeping the defaults for anything unset.
+func anomalyMonitorFromConfig(g *graph.Graph, hub *server.Hub) *simulation.EdgeAnomalyMonitor {
+	cfg := config.Global.Anomalies
+	m := simulation.NewEdgeAnomalyMonitor(g, hub)
+	m.Options = graph.EdgeAnomalyOptions{
+		Threshold:  cfg.Threshold,
+		MinChange:  cfg.MinChange,
+		MinHistory: cfg.MinHistory,
+		Lambda:     decayLambda,
+	}
+	if cfg.WindowHours > 0 {
+		m.Window = time.Duration(cfg.WindowHours) * time.Hour
+	}
+	return m
+}
+
+// stressMonitorFromConfig builds the stress monitor from the stress section,
+// keeping the defaults for anything unset.
+func stressMonitorFromConfig(g *graph.Graph, hub *server.Hub) *simulation.StressMonitor {
+	cfg := config.Global.Stress
+	m := simulation.NewStressMonitor(g, hub)
+	if cfg.WindowHours > 0 {
+		m.Window = time.Duration(cfg.WindowHours) * time.Hour
+	}
+	if cfg.Top > 0 {
+		m.Top = cfg.Top
+	}
+	if len(cfg.Weights) > 0 {
+		m.Weights = graph.StressWeights{
+			News:   cfg.Weights["news"],
+			Social: cfg.Weights["social"],
+			Edges:  cfg.Weights["edges"],
+			Health: cfg.Weights["health"],
+		}
+	}
+	return m
+}
+
+// printPipelines lists background engines and whether they are running
+func printPipelines() {
+	logger.Plain("")
+	logger.Section("Pipelines")
+	for _, p := range pipeline.All() {
+		state := "running"
+		if !p.Enabled {
+			state = "stopped"
+		}
+		logger.Plain("  %-10s %-8s %s", p.Name, state, p.Description)
+	}
+}
+
+// printTasks lists background tasks with their progress
+func printTasks(tasks []task.Info) {
+	logger.Plain("")
+	logger.Section("Tasks")
+	if len(tasks) == 0 {
+		logger.Plain("  No background tasks")
+		return
+	}
+	for _, t := range tasks {
+		progress := ""
+		if p := t.Progress(); p >= 0 && t.State == task.StateRunning {
+			progress = fmt.Sprintf(" %3.0f%%", p*100)
+		}
+		line := fmt.Sprintf("  %-4s %-10s %s%s", t.ID, t.State, t.Name, progress)
+		if t.Message != "" && t.State == task.StateRunning {
+			line += " - " + t.Message
+		}
+		if t.Error != "" {
+			line += " - " + t.Error
+		}
+		logger.Plain("%s", line)
+	}
+}
+
+// newScheduler registers the maintenance jobs with their schedules from the
+// config. A job whose pipeline is stopped skips its scheduled runs.
+func newScheduler(g *graph.Graph, seeder *discovery.Seeder, refresher *datasources.RefreshWorker, market *simulation.MarketMonitor, stress *simulation.StressMonitor, histories *history.Store) *jobs.Scheduler {
+	cfg := config.Global.Jobs
+	s := jobs.NewScheduler()
+	s.Enabled = func(name string) bool {
+		switch name {
+		case "decay_prune":
+			return pipeline.Enabled(pipeline.Decay)
+		case "refresh":
+			return pipeline.Enabled(pipeline.Refresh)
+		case "reseed":
+			return pipeline.Enabled(pipeline.Expansion)
+		case "tickers":
+			return pipeline.Enabled(pipeline.Market)
+		}
+		return true
+	}
+	add := func(name, spec string, timeout time.Duration, fn jobs.Func) {
+		if err := s.Add(name, spec, timeout, fn); err != nil {
+			// A bad schedule leaves the job manual-only rather than stopping startup
+			logger.Warn(logger.StatusWarn, "%v; run it with 'jobs run %s'", err, name)
+			s.Add(name, "", timeout, fn)
+		}
+	}
+
+	add("decay_prune", cfg.DecayPrune, 10*time.Minute, func(ctx context.Context, t *task.Task) (string, error) {
+		criteria := graph.PruneCriteria{
+			OlderThan:   time.Duration(cfg.PruneOlderThan) * 24 * time.Hour,
+			WeightBelow: cfg.PruneWeightBelow,
+		}
+		if criteria.OlderThan <= 0 {
+			criteria.OlderThan = 180 * 24 * time.Hour
+		}
+		if criteria.WeightBelow <= 0 {
+			criteria.WeightBelow = 0.05
+		}
+		baseline := g.ImpactBaseline()
+		decayed := g.ApplyTemporalDecay(decayLambda)
+		eventID := fmt.Sprintf("prune_%d", time.Now().Unix())
+		pruned, err := g.PruneEdges(criteria, eventID)
+		g.RecordImpact(graph.ImpactDecay, eventID, fmt.Sprintf("Nightly decay of %d edges and prune of %d", decayed, len(pruned)), baseline)
+		if err != nil {
+			return fmt.Sprintf("decayed %d edges", decayed), err
+		}
+		return fmt.Sprintf("decayed %d edges, pruned %d (no evidence for %dd, weight below %.2f)",
+			decayed, len(pruned), int(criteria.OlderThan.Hours()/24), criteria.WeightBelow), nil
+	})
+
+	add("refresh", cfg.Refresh, refresher.Timeout, func(ctx context.Context, t *task.Task) (string, error) {
+		report, err := refresher.Refresh(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d nodes and %d edges updated from %s data (%d errors)",
+			report.NodesUpdated, report.EdgesUpdated, report.Year, report.Errors), nil
+	})
+
+	add("reseed", cfg.Reseed, config.Timeout(config.Global.Timeouts.Seed, 2*time.Hour), func(ctx context.Context, t *task.Task) (string, error) {
+		staleDays, limit := cfg.StaleDays, cfg.ReseedLimit
+		if staleDays <= 0 {
+			staleDays = 30
+		}
+		if limit <= 0 {
+			limit = 10
+		}
+		if seeder.Client.ApiKey == "" {
+			return "", fmt.Errorf("no LLM API key set; cannot re-seed")
+		}
+		stale := g.Stale(time.Now().AddDate(0, 0, -staleDays), limit, graph.NodeTypeIndustry)
+		failed := 0
+		for i, n := range stale {
+			if err := ctx.Err(); err != nil {
+				return fmt.Sprintf("re-seeded %d of %d stale industries", i, len(stale)), err
+			}
+			t.Progress(i, len(stale), n.Name)
+			if err := seeder.ExpandNode(ctx, g, n.ID); err != nil {
+				logger.Warn(logger.StatusWarn, "Re-seeding %s failed: %v", n.Name, err)
+				failed++
+			}
+		}
+		return fmt.Sprintf("re-seeded %d stale industries (explored over %dd ago), %d failed", len(stale)-failed, staleDays, failed), nil
+	})
+
+	add("tickers", cfg.Tickers, time.Hour, func(ctx context.Context, t *task.Task) (string, error) {
+		limit := cfg.TickerLimit
+		if limit <= 0 {
+			limit = 50
+		}
+		report, err := market.EnrichTickers(ctx, limit, t)
+		cov := g.TickerCoverage()
+		return fmt.Sprintf("found %d of %d tickers looked up (%d failed, %d tried recently); %d of %d corporations covered",
+			report.Found, report.Candidates-report.Skipped, report.Failed, report.Skipped, cov.WithTicker, cov.Companies), err
+	})
+
+	add("digest", cfg.Digest, time.Minute, func(ctx context.Context, t *task.Task) (string, error) {
+		d := g.Digest(time.Now().Add(-24*time.Hour), 10)
+		path := cfg.DigestFile
+		if path == "" {
+			path = "margraf_digest.md"
+		}
+		path = config.DataPath(path)
+		if err := os.WriteFile(path, []byte(digestMarkdown(d)), 0644); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d nodes, %d edges (+%d, %d pruned), %d fallers, written to %s",
+			d.Nodes, d.Edges, d.EdgesAdded, d.EdgesPruned, len(d.Fallers), path), nil
+	})
+
+	add("embeddings", cfg.Embeddings, time.Hour, func(ctx context.Context, t *task.Task) (string, error) {
+		return saveEmbeddings(ctx, g, embeddingOptions(), embeddingsFile(), t)
+	})
+
+	add("history", cfg.History, 10*time.Minute, func(ctx context.Context, t *task.Task) (string, error) {
+		scores := make(map[string]float64)
+		for _, n := range g.StressIndex(stress.Window, stress.Weights) {
+			scores[n.NodeID] = n.Score
+		}
+		nodes, edges := history.Capture(g, scores, clock.Now())
+		date, err := histories.Append(nodes, edges)
+		if errors.Is(err, history.ErrRecorded) {
+			return err.Error(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("recorded %d nodes and %d edges for %s in %s", len(nodes), len(edges), date, histories.Dir), nil
+	})
+	return s
+}
+
+// historyDir is where the history job writes its end-of-day rows
+func historyDir() string {
+	dir := config.Global.Jobs.HistoryDir
+	if dir == "" {
+		dir = "margraf_history"
+	}
+	return config.DataPath(dir)
+}
+
+// embeddingsFile is where embed and the embeddings job save node vectors by default
+func embeddingsFile() string {
+	path := config.Global.Embeddings.File
+	if path == "" {
+		path = "margraf_embeddings.json"
+	}
+	return config.DataPath(path)
+}
+
+// embeddingOptions returns the embedding settings from config.yaml
+func embeddingOptions() graph.EmbeddingOptions {
+	cfg := config.Global.Embeddings
+	return graph.EmbeddingOptions{
+		Dimensions:   cfg.Dimensions,
+		WalksPerNode: cfg.WalksPerNode,
+		WalkLength:   cfg.WalkLength,
+		Window:       cfg.Window,
+		Return:       cfg.P,
+		InOut:        cfg.Q,
+		Epochs:       cfg.Epochs,
+		MinWeight:    cfg.MinWeight,
+		Seed:         cfg.Seed,
+	}
+}
+
+// analyticsOptions returns the centrality and hitting time settings from config.yaml
+func analyticsOptions() graph.SamplingOptions {
+	cfg := config.Global.Analytics
+	return graph.SamplingOptions{
+		Threshold: cfg.Threshold,
+		Sources:   cfg.Sources,
+		Walks:     cfg.Walks,
+		MaxSteps:  cfg.MaxSteps,
+		MinWeight: cfg.MinWeight,
+		Seed:      cfg.Seed,
+	}
+}
+
+// saveEmbeddings learns node vectors, reporting progress on t, and saves them to file
+func saveEmbeddings(ctx context.Context, g *graph.Graph, opts graph.EmbeddingOptions, file string, t *task.Task) (string, error) {
+	opts.Progress = func(done, total int) { t.Progress(done, total, "training on walks") }
+	e, err := g.Embed(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if err := e.Save(file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d nodes in %d dimensions, written to %s", len(e.Nodes), e.Dimensions, file), nil
+}
+
+// digestMarkdown renders a digest as a Markdown report
+func digestMarkdown(d graph.Digest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Margraf Digest, %s\n\n", clock.Format(time.Now(), "2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Changes since %s.\n\n", clock.Format(d.Since, "2006-01-02 15:04"))
+	fmt.Fprintf(&b, "- Nodes: %d\n", d.Nodes)
+	fmt.Fprintf(&b, "- Edges: %d (%d added, %d pruned)\n", d.Edges, d.EdgesAdded, d.EdgesPruned)
+	moves := func(title string, list []graph.HealthMove) {
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		if len(list) == 0 {
+			b.WriteString("None.\n")
+			return
+		}
+		b.WriteString("| Node | Health | Change |\n|------|--------|--------|\n")
+		for _, m := range list {
+			fmt.Fprintf(&b, "| %s (`%s`) | %.3f -> %.3f | %+.3f |\n", m.Name, m.NodeID, m.From, m.To, m.Change())
+		}
+	}
+	moves("Largest Health Drops", d.Fallers)
+	moves("Largest Health Gains", d.Risers)
+
+	b.WriteString("\n## Analyst Notes\n\n")
+	if len(d.Notes) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, n := range d.Notes {
+		fmt.Fprintf(&b, "- **%s** (`%s`, %s, %s): %s", n.Subject(), n.ID, n.Author, clock.Format(n.Time, "2006-01-02 15:04"), n.Text)
+		for _, t := range n.Tags {
+			fmt.Fprintf(&b, " #%s", t)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// printJobs lists the scheduled jobs with their next and latest runs
+func printJobs(list []jobs.Status) {
+	logger.Plain("")
+	logger.Section("Jobs")
+	for _, j := range list {
+		spec, next := j.Spec, "-"
+		if spec == "" {
+			spec = "manual"
+		} else if !j.Next.IsZero() {
+			next = clock.Format(j.Next, "2006-01-02 15:04")
+		}
+		last := "never run"
+		if j.Running {
+			last = "running"
+		} else if r, ok := j.Last(); ok {
+			last = fmt.Sprintf("%s %s (%v)", r.State, clock.Format(r.Started, "2006-01-02 15:04"), r.Duration.Round(time.Second))
+		}
+		logger.Plain("  %-12s %-14s next %-16s last %s", j.Name, spec, next, last)
+	}
+}
+
+// printJobRuns shows a job's recent runs, newest first
+func printJobRuns(list []jobs.Status, name string) {
+	for _, j := range list {
+		if j.Name != name {
+			continue
+		}
+		logger.Plain("")
+		logger.Section("Job: " + name)
+		if len(j.Runs) == 0 {
+			logger.Plain("  Not run yet")
+			return
+		}
+		for i := len(j.Runs) - 1; i >= 0; i-- {
+			r := j.Runs[i]
+			logger.Plain("  %s  %-9s %-8s %8v  %s", clock.Format(r.Started, "2006-01-02 15:04"), r.State, r.Trigger, r.Duration.Round(time.Second), r.Summary)
+			if r.Error != "" {
+				logger.Plain("      error: %s", r.Error)
+			}
+		}
+		return
+	}
+	logger.Warn(logger.StatusWarn, "Job %s not found. Usage: jobs [name | run <name>]", name)
+}
+
+// printTickerCoverage shows how many corporations have a ticker, by industry
+func printTickerCoverage(cov graph.TickerCoverage) {
+	logger.Plain("")
+	logger.Section("Ticker Coverage")
+	if cov.Companies == 0 {
+		logger.Plain("  No corporations in the graph")
+		return
+	}
+	row := func(c graph.IndustryCoverage) {
+		logger.Plain("  %-32s %5d / %-5d %5.1f%%  %d missing", oneLine(c.Name, 32), c.WithTicker, c.Companies, c.Share()*100, c.Missing())
+	}
+	row(cov.IndustryCoverage)
+	logger.Plain("")
+	for _, ic := range cov.Industries {
+		row(ic)
+	}
+	if cov.Missing() > 0 {
+		logger.Plain("")
+		logger.Plain("  'tickers missing' lists the gaps most central first; 'tickers enrich' looks them up")
+	}
+}
+
+// printMissingTickers lists corporations without a ticker, most central first
+func printMissingTickers(list []graph.TickerCandidate) {
+	logger.Plain("")
+	logger.Section("Missing Tickers")
+	if len(list) == 0 {
+		logger.Plain("  Every corporation has a ticker")
+		return
+	}
+	for i, c := range list {
+		logger.Plain("  %3d. %-32s %-28s %3d edges, weight %.2f", i+1, oneLine(c.Name, 32), c.NodeID, c.Degree, c.Centrality)
+	}
+}
+
+// printNewsStatus lists the news feeds with their polling schedules
+func printNewsStatus(feeds []news.FeedStatus) {
+	logger.Plain("")
+	logger.Section("News Feeds")
+	if len(feeds) == 0 {
+		logger.Plain("  No feeds configured")
+		return
+	}
+	now := time.Now()
+	for _, f := range feeds {
+		last, next := "never", "not scheduled"
+		if !f.LastPoll.IsZero() {
+			last = fmt.Sprintf("%s ago, %d new", now.Sub(f.LastPoll).Round(time.Second), f.Items)
+			if f.LastError != "" {
+				last = fmt.Sprintf("%s ago, failed", now.Sub(f.LastPoll).Round(time.Second))
+			}
+		}
+		if !f.NextPoll.IsZero() {
+			next = "in " + time.Until(f.NextPoll).Round(time.Second).String()
+			if !f.NextPoll.After(now) {
+				next = "due"
+			}
+		}
+		quiet := "none"
+		if f.QuietHours != "" {
+			quiet = f.QuietHours
+			if f.Quiet {
+				quiet += " (now)"
+			}
+		}
+		name := f.Name
+		if f.Country != "" {
+			name += " [" + f.Country + "]"
+		}
+		logger.Plain("  %-28s every %-8v quiet %-20s last %-22s next %s", oneLine(name, 28), f.Interval, quiet, last, next)
+		if f.LastError != "" {
+			logger.Plain("    error: %s", oneLine(f.LastError, 100))
+		}
+	}
+}
+
+// printImpacts lists the kept impacts, newest first
+func printImpacts(impacts []graph.Impact) {
+	logger.Plain("")
+	logger.Section("Impacts")
+	if len(impacts) == 0 {
+		logger.Plain("  No impacts recorded yet (shocks, news and decay sweeps record them)")
+		return
+	}
+	for _, imp := range impacts {
+		logger.Plain("  %s  %-5s %-36s %4d nodes, health %+.3f, edge loss %.3f  %s",
+			clock.Format(imp.Time, "01-02 15:04"), imp.Kind, imp.EventID, imp.NodesChanged, imp.HealthDelta, imp.EdgeLoss, oneLine(imp.Description, 50))
+	}
+}
+
+// printImpact ranks the nodes one event changed most
+func printImpact(imp graph.Impact) {
+	const shown = 20
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("Impact: %s (%s, %s)", imp.EventID, imp.Kind, clock.Format(imp.Time, "2006-01-02 15:04")))
+	if imp.Description != "" {
+		logger.Plain("  %s", imp.Description)
+	}
+	logger.Plain("  %d nodes and %d edges changed: health %+.3f, edge weight lost %.3f",
+		imp.NodesChanged, imp.EdgesChanged, imp.HealthDelta, imp.EdgeLoss)
+	logger.Plain("  %-4s %-28s %-14s %17s %9s %10s", "rank", "node", "type", "health", "edge loss", "edges")
+	for i, n := range imp.Nodes {
+		if i == shown {
+			logger.Plain("  ... and %d more", imp.NodesChanged-shown)
+			break
+		}
+		name := n.Name
+		if name == "" {
+			name = n.NodeID
+		}
+		logger.Plain("  %-4d %-28s %-14s %.3f -> %.3f %+10.3f %10d",
+			i+1, oneLine(name, 28), n.Type, n.HealthBefore, n.HealthAfter, n.EdgeLoss, n.EdgesChanged)
+	}
+}
+
+// traceDir is the directory of saved shock traces
+func traceDir() string {
+	if dir := config.Global.Simulation.Traces.Dir; dir != "" {
+		return config.DataPath(dir)
+	}
+	return config.DataPath("traces")
+}
+
+// printTrace shows a saved shock trace hop by hop: the one for eventID, or
+// the newest
+func printTrace(g *graph.Graph, eventID string) {
+	dir := traceDir()
+	path := ""
+	if eventID != "" {
+		path = filepath.Join(dir, eventID+".json")
+	} else {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Error(logger.StatusErr, "Reading traces failed: %v", err)
+			return
+		}
+		var newest time.Time
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+				continue
+			}
+			if info.ModTime().After(newest) {
+				newest, path = info.ModTime(), filepath.Join(dir, e.Name())
+			}
+		}
+		if path == "" {
+			logger.Plain("No shock traces in %s (enable simulation.traces, then run a shock)", dir)
+			return
+		}
+	}
+	t, err := simulation.LoadTrace(path)
+	if err != nil {
+		logger.Error(logger.StatusErr, "Reading trace failed: %v", err)
+		return
+	}
+
+	name := func(id string) string {
+		if n, ok := g.GetNode(id); ok {
+			return n.Name
+		}
+		return id
+	}
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("Shock Trace: %s (%s)", t.EventID, clock.Format(t.Time, "2006-01-02 15:04")))
+	logger.Plain("  %s on %s, impact %.2f (effective %.2f), %d steps over %d hops",
+		t.Description, name(t.Target), t.ImpactFactor, t.EffectiveImpact, len(t.Steps), t.Hops())
+	if energy, band := t.Downstream(); band.High > 0 {
+		logger.Plain("  downstream energy %.3f, band %.3f - %.3f given edge confidence", energy, band.Low, band.High)
+	}
+	for _, s := range t.Steps {
+		line := fmt.Sprintf("  hop %d %-8s", s.Hop, s.Kind)
+		if s.From != "" {
+			line += fmt.Sprintf(" %s ->", name(s.From))
+		}
+		line += fmt.Sprintf(" %s", name(s.To))
+		if s.EdgeType != "" {
+			line += fmt.Sprintf(" [%s]", s.EdgeType)
+		}
+		line += fmt.Sprintf(" energy %.3f", s.Energy)
+		if s.WeightBefore != nil && s.WeightAfter != nil {
+			line += fmt.Sprintf(", weight %.2f -> %.2f", *s.WeightBefore, *s.WeightAfter)
+		}
+		if s.HealthBefore != nil && s.HealthAfter != nil {
+			line += fmt.Sprintf(", health %.3f -> %.3f", *s.HealthBefore, *s.HealthAfter)
+		}
+		if s.Confidence != nil && s.Band != nil {
+			line += fmt.Sprintf(", confidence %.0f%% (band %.3f - %.3f)", *s.Confidence*100, s.Band.Low, s.Band.High)
+		}
+		logger.Plain("%s", line)
+	}
+}
+
+// scenarioLibrary is the directory of shared scenario files
+func scenarioLibrary() string {
+	if dir := config.Global.Scenarios.Library; dir != "" {
+		return dir
+	}
+	return "scenarios"
+}
+
+// importScenario copies a scenario file into the library, so it is loaded
+// again on the next start, and registers it
+func importScenario(path string) (simulation.Scenario, error) {
+	sc, err := simulation.ReadScenario(path)
+	if err != nil {
+		return sc, err
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yml" {
+		ext = ".yaml"
+	}
+	dest := filepath.Join(scenarioLibrary(), simulation.ScenarioKey(sc.Name)+ext)
+	if filepath.Clean(path) != dest {
+		if err := simulation.ExportScenario(sc, dest); err != nil {
+			return sc, err
+		}
+	}
+	return simulation.ImportScenario(dest)
+}
+
+// printScenarios lists built-in templates followed by library scenarios
+func printScenarios() {
+	logger.Plain("")
+	logger.Section("Scenario Templates")
+	for _, name := range simulation.TemplateNames() {
+		logger.Plain("  %-12s - %s", name, simulation.ScenarioTemplates[name].Description)
+	}
+	if names := simulation.LibraryNames(); len(names) > 0 {
+		logger.Section("Scenario Library")
+		for _, name := range names {
+			sc, _ := simulation.LookupScenario(name)
+			logger.Plain("  %-12s - %s (%s)", name, sc.Description, simulation.LibraryFile(name))
+		}
+	}
+	logger.Plain("Usage: scenario run|compare|export <name> ..., scenario import <file>")
+}
+
+// printComparison shows a baseline vs scenario report
+func printComparison(c *simulation.Comparison) {
+	logger.Plain("")
+	logger.Section("Baseline vs " + c.Scenario)
+	logger.Plain("  %-16s %10s %10s %10s", "metric", "baseline", "scenario", "delta")
+	for _, m := range c.Metrics {
+		logger.Plain("  %-16s %10.3f %10.3f %+10.3f", m.Name, m.Baseline, m.Scenario, m.Delta)
+	}
+	if len(c.Nodes) > 0 {
+		logger.Plain("  Most affected nodes (health / stress):")
+		for _, n := range c.Nodes {
+			logger.Plain("    %-28s %.2f -> %.2f   %.2f -> %.2f", n.Name, n.BaselineHealth, n.ScenarioHealth, n.BaselineStress, n.ScenarioStress)
+		}
+	}
+	if len(c.Paths) > 0 {
+		logger.Plain("  Top impacted paths (edge weight):")
+		for _, p := range c.Paths {
+			logger.Plain("    %s: %.2f -> %.2f", strings.Join(p.Names, " -> "), p.Baseline, p.Scenario)
+		}
+	}
+	if len(c.Hedges) > 0 {
+		logger.Plain("  Portfolio hedges (size as share of portfolio):")
+		for _, h := range c.Hedges {
+			switch h.Kind {
+			case trading.HedgeShort:
+				logger.Plain("    short %-8s %5.1f%%            %s", h.Short, h.Size*100, h.Rationale)
+			case trading.HedgeLong:
+				logger.Plain("    long  %-8s %5.1f%%            %s", h.Long, h.Size*100, h.Rationale)
+			case trading.HedgePair:
+				corr := "corr n/a"
+				if h.Correlation != 0 {
+					corr = fmt.Sprintf("corr %.2f", h.Correlation)
+				}
+				logger.Plain("    pair  long %s / short %s %5.1f%% x %.2f (%s)  %s", h.Long, h.Short, h.Size*100, h.Ratio, corr, h.Rationale)
+			}
+		}
+	}
+}
+
+// ragStore is the vector store file for node descriptions
+func ragStore() string {
+	if path := config.Global.RAG.Store; path != "" {
+		return config.DataPath(path)
+	}
+	return config.DataPath("margraf_vectors.json")
+}
+
+// printMatches lists search hits with their descriptions
+func printMatches(g *graph.Graph, query string, matches []rag.Match) {
+	logger.Plain("")
+	logger.Section("Nodes matching " + query)
+	if len(matches) == 0 {
+		logger.Plain("  No described nodes match (run 'describe' to index the graph)")
+		return
+	}
+	for _, m := range matches {
+		name := m.NodeID
+		if n, ok := g.GetNode(m.NodeID); ok {
+			name = fmt.Sprintf("%s [%s]", n.Name, n.Type)
+		}
+		logger.Plain("  %.2f  %-32s %s", m.Score, name, m.Description)
+	}
+}
+
+// weightPolicyFromConfig builds the normalization policy from weights.normalize
+func weightPolicyFromConfig() graph.WeightPolicy {
+	cfg := config.Global.Weights.Normalize
+	policy := graph.WeightPolicy{Method: graph.NormalizeMethod(cfg.Method), Floor: cfg.Floor}
+	for _, t := range cfg.Types {
+		policy.Types = append(policy.Types, graph.EdgeType(t))
+	}
+	return policy
+}
+
+// printWeightStats shows each edge type's weight distribution with a
+// histogram over [0, 1]
+func printWeightStats(title string, stats []graph.WeightStats) {
+	logger.Plain("")
+	logger.Section(title)
+	logger.Plain("  %-16s %6s %6s %6s %6s %6s %6s  %s", "type", "count", "min", "median", "mean", "max", "stddev", "0 ........ 1")
+	for _, s := range stats {
+		logger.Plain("  %-16s %6d %6.2f %6.2f %6.2f %6.2f %6.2f  %s", s.Type, s.Count, s.Min, s.Median, s.Mean, s.Max, s.StdDev, sparkline(s.Histogram[:]))
+	}
+}
+
+// sparkline draws counts as a row of block characters scaled to the largest
+func sparkline(counts []int) string {
+	bars := []rune(" ▁▂▃▄▅▆▇█")
+	peak := 0
+	for _, c := range counts {
+		peak = max(peak, c)
+	}
+	line := make([]rune, len(counts))
+	for i, c := range counts {
+		line[i] = bars[0]
+		if peak > 0 && c > 0 {
+			line[i] = bars[1+(c*(len(bars)-2))/peak]
+		}
+	}
+	return string(line)
+}
+
+// printAging shows how old the evidence behind the graph's edges is
+func printAging(buckets []graph.AgeBucket) {
+	logger.Plain("")
+	logger.Section("Edge Aging")
+	logger.Plain("  %-10s %7s %11s %7s", "age", "edges", "mean weight", "weak")
+	for _, b := range buckets {
+		logger.Plain("  %-10s %7d %11.2f %7d", b.Label, b.Count, b.MeanWeight, b.Weak)
+	}
+}
+
+// parsePrune reads prune's flags. Ages take a d suffix for days (90d) or
+// any Go duration (36h).
+func parsePrune(args []string) (criteria graph.PruneCriteria, dryRun bool, err error) {
+	value := func(i int) (string, error) {
+		if i+1 >= len(args) {
+			return "", fmt.Errorf("%s needs a value", args[i])
+		}
+		return args[i+1], nil
+	}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "":
+			continue
+		case "--dry-run":
+			dryRun = true
+			continue
+		}
+		v, err := value(i)
+		if err != nil {
+			return criteria, false, err
+		}
+		switch args[i] {
+		case "--older-than":
+			if criteria.OlderThan, err = parseAge(v); err != nil {
+				return criteria, false, err
+			}
+		case "--weight-below":
+			if criteria.WeightBelow, err = strconv.ParseFloat(v, 64); err != nil {
+				return criteria, false, fmt.Errorf("invalid weight %q", v)
+			}
+		case "--type":
+			criteria.Types = append(criteria.Types, graph.EdgeType(v))
+		default:
+			return criteria, false, fmt.Errorf("unknown prune flag %q", args[i])
+		}
+		i++
+	}
+	return criteria, dryRun, nil
+}
+
+// parseAge parses "90d" as 90 days, or anything time.ParseDuration accepts
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// parseEmbedOptions applies embed's flags to opts
+func parseEmbedOptions(args []string, opts graph.EmbeddingOptions) (graph.EmbeddingOptions, error) {
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return opts, fmt.Errorf("%s needs a value", args[i])
+		}
+		v := args[i+1]
+		switch args[i] {
+		case "--dim", "--walks", "--length", "--window", "--epochs":
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return opts, fmt.Errorf("invalid %s %q", strings.TrimPrefix(args[i], "--"), v)
+			}
+			switch args[i] {
+			case "--dim":
+				opts.Dimensions = n
+			case "--walks":
+				opts.WalksPerNode = n
+			case "--length":
+				opts.WalkLength = n
+			case "--window":
+				opts.Window = n
+			case "--epochs":
+				opts.Epochs = n
+			}
+		case "--p", "--q":
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 {
+				return opts, fmt.Errorf("invalid %s %q", strings.TrimPrefix(args[i], "--"), v)
+			}
+			if args[i] == "--p" {
+				opts.Return = f
+			} else {
+				opts.InOut = f
+			}
+		case "--seed":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return opts, fmt.Errorf("invalid seed %q", v)
+			}
+			opts.Seed = n
+		default:
+			return opts, fmt.Errorf("unknown embed flag %q", args[i])
+		}
+	}
+	return opts, nil
+}
+
+// parseSamplingOptions applies the flags of "central" and "hitting" to opts
+func parseSamplingOptions(args []string, opts graph.SamplingOptions) (graph.SamplingOptions, error) {
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		switch flag {
+		case "--exact":
+			opts.Exact = true
+			continue
+		case "--sample":
+			opts.Sampled = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return opts, fmt.Errorf("%s needs a value", flag)
+		}
+		i++
+		v := args[i]
+		switch flag {
+		case "--sources", "--walks", "--steps":
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return opts, fmt.Errorf("invalid %s %q", strings.TrimPrefix(flag, "--"), v)
+			}
+			switch flag {
+			case "--sources":
+				opts.Sources = n
+			case "--walks":
+				opts.Walks = n
+			case "--steps":
+				opts.MaxSteps = n
+			}
+		case "--seed":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return opts, fmt.Errorf("invalid seed %q", v)
+			}
+			opts.Seed = n
+		default:
+			return opts, fmt.Errorf("unknown option %s", flag)
+		}
+	}
+	if opts.Exact && opts.Sampled {
+		return opts, fmt.Errorf("--exact and --sample cannot be combined")
+	}
+	return opts, nil
+}
+
+// parseDOTOptions reads export's flags. --type may repeat; --hops defaults
+// to 2 with --around.
+func parseDOTOptions(args []string) (graph.DOTOptions, error) {
+	opts := graph.DOTOptions{Hops: -1}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "":
+			continue
+		case "--color-health":
+			opts.ColorByHealth = true
+			continue
+		case "--weight-width":
+			opts.WeightWidth = true
+			continue
+		}
+		if i+1 >= len(args) {
+			return opts, fmt.Errorf("%s needs a value", args[i])
+		}
+		v := args[i+1]
+		switch args[i] {
+		case "--type":
+			opts.NodeTypes = append(opts.NodeTypes, graph.NodeType(v))
+		case "--min-weight":
+			w, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return opts, fmt.Errorf("invalid weight %q", v)
+			}
+			opts.MinWeight = w
+		case "--around":
+			opts.Center = v
+		case "--hops":
+			k, err := strconv.Atoi(v)
+			if err != nil || k < 0 {
+				return opts, fmt.Errorf("invalid hop count %q", v)
+			}
+			opts.Hops = k
+		default:
+			return opts, fmt.Errorf("unknown export flag %q", args[i])
+		}
+		i++
+	}
+	if opts.Hops < 0 {
+		opts.Hops = 2
+	}
+	return opts, nil
+}
+
+// printPruned lists pruned (or prunable) edges, oldest first, up to 20
+func printPruned(g *graph.Graph, title string, edges []graph.EdgeAge) {
+	const shown = 20
+	logger.Plain("")
+	logger.Section(title)
+	name := func(id string) string {
+		if n, ok := g.GetNode(id); ok {
+			return n.Name
+		}
+		return id
+	}
+	for i, e := range edges {
+		if i == shown {
+			logger.Plain("  ... and %d more", len(edges)-shown)
+			break
+		}
+		logger.Plain("  %-24s -[%s]-> %-24s weight %.3f, last evidence %s (%dd ago)",
+			name(e.SourceID), e.Type, name(e.TargetID), e.Weight, clock.Format(e.LastEvidence, "2006-01-02"), int(e.Age.Hours()/24))
+	}
+}
+
+// statusArgs are the parsed arguments of the status command
+type statusArgs struct {
+	positional []string
+	hsCode     string
+	until      time.Time
+}
+
+// parseStatusArgs separates --until and --hs from the positional arguments.
+// Words after the first flag are returned as rest (part of the reason).
+func parseStatusArgs(args []string) (statusArgs, []string, error) {
+	var out statusArgs
+	var rest []string
+	flags := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--until", "--hs":
+			if i+1 >= len(args) {
+				return out, nil, fmt.Errorf("%s needs a value", args[i])
+			}
+			flags = true
+			if args[i] == "--hs" {
+				out.hsCode = args[i+1]
+			} else {
+				t, err := time.ParseInLocation("2006-01-02", args[i+1], clock.Display())
+				if err != nil {
+					return out, nil, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", args[i+1])
+				}
+				out.until = t
+			}
+			i++
+		default:
+			if flags {
+				rest = append(rest, args[i])
+			} else {
+				out.positional = append(out.positional, args[i])
+			}
+		}
+	}
+	return out, rest, nil
+}
+
+// printStatusOverrides lists edges with a manual status, soonest to lapse first
+func printStatusOverrides(g *graph.Graph) {
+	logger.Plain("")
+	logger.Section("Status Overrides")
+	edges := g.StatusOverrides()
+	if len(edges) == 0 {
+		logger.Plain("  No status overrides")
+		return
+	}
+	for _, e := range edges {
+		until := "until cleared"
+		if !e.Override.Until.IsZero() {
+			until = "until " + clock.Format(e.Override.Until, "2006-01-02")
+		}
+		reason := ""
+		if e.Override.Reason != "" {
+			reason = " - " + e.Override.Reason
+		}
+		logger.Plain("  %-24s -[%s]-> %-24s %-9s %s (weight %.2f)%s", e.SourceID, e.Type, e.TargetID, e.Override.Status, until, e.Weight, reason)
+	}
+}
+
+// handlePortfolio shows the portfolio's exposure or adds and removes holdings
+func handlePortfolio(m *simulation.PortfolioMonitor, args []string) {
+	switch {
+	case len(args) == 0 || args[0] == "show":
+		printPortfolio(m.Update(), m.Threshold)
+	case args[0] == "add" && len(args) == 3:
+		quantity, err := strconv.ParseFloat(args[2], 64)
+		if err != nil || quantity <= 0 {
+			logger.Warn(logger.StatusWarn, "Quantity must be a positive number, got %q", args[2])
+			return
+		}
+		if err := m.Set(args[1], quantity); err != nil {
+			logger.Error(logger.StatusErr, "Saving portfolio failed: %v", err)
+			return
+		}
+		logger.Success("Holding %s set to %g (saved to %s)", strings.ToUpper(args[1]), quantity, m.Path)
+	case args[0] == "remove" && len(args) == 2:
+		removed, err := m.Remove(args[1])
+		switch {
+		case err != nil:
+			logger.Error(logger.StatusErr, "Saving portfolio failed: %v", err)
+		case !removed:
+			logger.Warn(logger.StatusWarn, "No holding %s", args[1])
+		default:
+			logger.Success("Removed holding %s", strings.ToUpper(args[1]))
+		}
+	default:
+		logger.Warn(logger.StatusWarn, "Usage: portfolio [show] | portfolio add <TICKER> <quantity> | portfolio remove <TICKER>")
+	}
+}
+
+// handlePropagation shows and tunes the shock propagation factors. Factors set
+// here are saved to the overrides file and layered over config.yaml.
+func handlePropagation(g *graph.Graph, args []string) {
+	usage := "Usage: propagation [show] | propagation set <EdgeType> <0-1> [SrcType TgtType] | propagation reset <EdgeType> [SrcType TgtType] | propagation reset all"
+	if len(args) == 0 || args[0] == "show" {
+		printPropagation(g)
+		return
+	}
+
+	path := propagationOverridesPath()
+	saved, err := graph.LoadPropagationOverrides(path)
+	if err != nil {
+		logger.Error(logger.StatusErr, "Reading propagation overrides failed: %v", err)
+		return
+	}
+
+	switch {
+	case args[0] == "reset" && len(args) == 2 && args[1] == "all":
+		saved = graph.PropagationOverrides{}
+	case args[0] == "set" && (len(args) == 3 || len(args) == 5):
+		rule, err := parsePropagationRule(args[1], args[3:])
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
+			return
+		}
+		if rule.Factor, err = strconv.ParseFloat(args[2], 64); err != nil || rule.Factor < 0 || rule.Factor > 1 {
+			logger.Warn(logger.StatusWarn, "Factor must be a number between 0 and 1, got %s", args[2])
+			return
+		}
+		saved.Set(rule)
+	case args[0] == "reset" && (len(args) == 2 || len(args) == 4):
+		rule, err := parsePropagationRule(args[1], args[2:])
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "%v", err)
+			return
+		}
+		if !saved.Unset(rule) {
+			logger.Warn(logger.StatusWarn, "No tuned factor for %s", rule)
+			return
+		}
+	default:
+		logger.Warn(logger.StatusWarn, "%s", usage)
+		return
+	}
+
+	if err := saved.Save(path); err != nil {
+		logger.Error(logger.StatusErr, "Saving propagation overrides failed: %v", err)
+		return
+	}
+	model, err := propagationModelFromConfig()
+	if err != nil {
+		logger.Error(logger.StatusErr, "Propagation model invalid: %v", err)
+		return
+	}
+	g.SetPropagationModel(model)
+	logger.Success("Propagation factors updated (saved to %s)", path)
+}
+
+// parsePropagationRule reads an edge type and an optional source and target
+// node type ("*" = any)
+func parsePropagationRule(edgeType string, nodeTypes []string) (graph.PropagationRule, error) {
+	rule := graph.PropagationRule{EdgeType: graph.EdgeType(edgeType)}
+	if len(nodeTypes) == 2 {
+		if nodeTypes[0] != "*" {
+			rule.Source = graph.NodeType(nodeTypes[0])
+		}
+		if nodeTypes[1] != "*" {
+			rule.Target = graph.NodeType(nodeTypes[1])
+		}
+	}
+	return rule, checkRuleTypes(edgeType, string(rule.Source), string(rule.Target))
+}
+
+// checkRuleTypes checks a propagation rule names known types ("" = any node type)
+func checkRuleTypes(edgeType, source, target string) error {
+	if !graph.KnownEdgeType(graph.EdgeType(edgeType)) {
+		return fmt.Errorf("unknown edge type %s", edgeType)
+	}
+	for _, t := range []string{source, target} {
+		if t != "" && !graph.KnownNodeType(graph.NodeType(t)) {
+			return fmt.Errorf("unknown node type %s", t)
+		}
+	}
+	return nil
+}
+
+// printPropagation shows the factor for each edge type and node-type pair,
+// marking the ones tuned with "propagation set"
+func printPropagation(g *graph.Graph) {
+	model := g.PropagationModel()
+	saved, err := graph.LoadPropagationOverrides(propagationOverridesPath())
+	if err != nil {
+		logger.Warn(logger.StatusWarn, "Reading propagation overrides failed: %v", err)
+	}
+	mark := func(r graph.PropagationRule) string {
+		if saved.Has(r) {
+			return " (tuned)"
+		}
+		return ""
+	}
+
+	logger.Plain("")
+	logger.Section("Shock Propagation")
+	for _, t := range model.EdgeTypes() {
+		logger.Plain("  %-16s %.2f%s", t, model.ByEdgeType[t], mark(graph.PropagationRule{EdgeType: t}))
+	}
+	logger.Plain("  %-16s %.2f", "(other types)", model.Default)
+	if len(model.Rules) > 0 {
+		logger.Plain("")
+		logger.Plain("  Between node types:")
+		for _, r := range model.Rules {
+			logger.Plain("  %-40s %.2f%s", r.String(), r.Factor, mark(r))
+		}
+	}
+
+	confidence := g.ConfidenceModel()
+	logger.Plain("")
+	if confidence.Weight == 0 {
+		logger.Plain("  Edge confidence (not applied):")
+	} else {
+		logger.Plain("  Edge confidence (weight %.2f):", confidence.Weight)
+	}
+	for _, origin := range confidence.Origins() {
+		logger.Plain("  %-16s %.2f", origin, confidence.ByOrigin[origin])
+	}
+	logger.Plain("  %-16s %.2f", "(other origins)", confidence.Default)
+}
+
+// printMerge summarizes a merge, listing duplicates and conflicts
+func printMerge(file string, r graph.MergeReport, strategy graph.MergeStrategy) {
+	logger.Success("Merged %s (%s): %d nodes added, %d updated; %d edges added, %d updated", file, strategy, r.NodesAdded, r.NodesUpdated, r.EdgesAdded, r.EdgesUpdated)
+	if len(r.Duplicates) > 0 {
+		logger.Plain("")
+		logger.Section("Duplicate Nodes")
+		ids := make([]string, 0, len(r.Duplicates))
+		for id := range r.Duplicates {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			logger.Plain("  %s -> %s", id, r.Duplicates[id])
+		}
+	}
+	if len(r.Conflicts) == 0 {
+		return
+	}
+	logger.Plain("")
+	if strategy == graph.MergeReview {
+		logger.Section("Review (kept ours)")
+	} else {
+		logger.Section("Conflicts")
+	}
+	for _, c := range r.Conflicts {
+		what := "node " + c.Node
+		if c.Edge != "" {
+			what = "edge " + c.Edge
+		}
+		logger.Plain("  %-40s %s", what, strings.Join(c.Fields, ", "))
+		if strategy != graph.MergeReview {
+			logger.Plain("      kept %s: %s", c.Kept, c.Reason)
+		}
+	}
+}
+
+// printAudit shows the discovery records that added a node or its edges
+func printAudit(nodeID string) {
+	records, err := audit.ForNode(nodeID)
+	if err != nil {
+		logger.Error(logger.StatusErr, "Reading audit log failed: %v", err)
+		return
+	}
+	logger.Plain("")
+	logger.Section("Audit: " + nodeID)
+	if len(records) == 0 {
+		logger.Plain("  No discovery records (added before auditing began, or by hand)")
+		return
+	}
+	for _, r := range records {
+		what := "node " + r.NodeID
+		if r.Action == audit.ActionAddEdge {
+			what = fmt.Sprintf("edge %s -[%s]-> %s", r.SourceID, r.EdgeType, r.TargetID)
+		}
+		logger.Plain("  %s  %-20s %s", clock.Format(r.Time, "2006-01-02 15:04"), r.Actor, what)
+		if r.Reason != "" {
+			logger.Plain("      why: %s", r.Reason)
+		}
+		if r.Evidence != "" {
+			logger.Plain("      evidence: %s", r.Evidence)
+		}
+	}
+}
+
+// printExplain prints everything known about the edges from src to tgt
+// (optionally of one type): the graph's view of each edge, the audit log's
+// evidence for it, its history and the events that moved it, and its notes
+func printExplain(g *graph.Graph, src, tgt string, edgeType graph.EdgeType) {
+	name := func(id string) string {
+		if n, ok := g.GetNode(id); ok && n.Name != "" {
+			return n.Name
+		}
+		return id
+	}
+	var edges []graph.EdgeExplanation
+	for _, x := range g.ExplainEdges(src, tgt) {
+		if edgeType == "" || x.Edge.Type == edgeType {
+			edges = append(edges, x)
+		}
+	}
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("Explain: %s -> %s", name(src), name(tgt)))
+	if len(edges) == 0 {
+		switch {
+		case len(g.WeightSeries(src, tgt)) > 0:
+			logger.Plain("  No edge from %s to %s now; it has a history, so it was pruned or removed", src, tgt)
+		case len(g.ExplainEdges(tgt, src)) > 0:
+			logger.Plain("  No edge from %s to %s, but there are edges the other way (explain %s %s)", src, tgt, tgt, src)
+		default:
+			logger.Plain("  No edge from %s to %s", src, tgt)
+		}
+		return
+	}
+	records, err := audit.ForEdge(src, tgt)
+	if err != nil {
+		logger.Warn(logger.StatusWarn, "Reading audit log failed: %v", err)
+	}
+
+	now := time.Now()
+	for _, x := range edges {
+		e := x.Edge
+		title := string(e.Type)
+		if hs := e.Commodity(); hs != "" {
+			title += " (HS " + hs + ")"
+		}
+		logger.Plain("  %s", title)
+
+		status := string(e.Status)
+		if e.Override != nil {
+			status += fmt.Sprintf(" (pinned to %s", e.Override.Status)
+			if !e.Override.Until.IsZero() {
+				status += " until " + clock.Format(e.Override.Until, "2006-01-02")
+			}
+			if e.Override.Reason != "" {
+				status += ": " + e.Override.Reason
+			}
+			status += ")"
+		}
+		logger.Plain("    weight:      %.3f %s", e.Weight, status)
+		logger.Plain("    direction:   %s", x.Directionality)
+		origin := x.Origin
+		if origin == "" {
+			origin = "unknown origin"
+		}
+		logger.Plain("    confidence:  %.2f from %s (shocks keep %.0f%% of their propagation)", x.Confidence, origin, x.Scale*100)
+		logger.Plain("    history:     %d changes since %s, %d from events; weight %.2f to %.2f; decay %+.2f, events %+.2f",
+			x.Changes, clock.Format(x.FirstSeen, "2006-01-02"), x.EventChanges, x.MinWeight, x.MaxWeight, x.Decay, x.Event)
+		logger.Plain("    evidence:    last %s (%dd ago)", clock.Format(x.LastEvidence, "2006-01-02 15:04"), int(now.Sub(x.LastEvidence).Hours()/24))
+
+		var provenance []audit.Record
+		for _, r := range records {
+			if r.EdgeType == string(e.Type) {
+				provenance = append(provenance, r)
+			}
+		}
+		if len(provenance) > 0 {
+			logger.Plain("    provenance:")
+			for _, r := range provenance {
+				logger.Plain("      %s  %s", clock.Format(r.Time, "2006-01-02 15:04"), r.Actor)
+				if r.Reason != "" {
+					logger.Plain("        why: %s", r.Reason)
+				}
+				if r.Evidence != "" {
+					logger.Plain("        evidence: %s", r.Evidence)
+				}
+			}
+		}
+		if len(x.Events) > 0 {
+			logger.Plain("    recent events:")
+			for _, p := range x.Events {
+				what := p.EventID
+				if p.Description != "" {
+					what = p.Description + " (" + p.EventID + ")"
+				}
+				logger.Plain("      %s  %+.3f to %.3f  %s", clock.Format(p.Time, "2006-01-02 15:04"), p.Event, p.Weight, what)
+			}
+		}
+		if len(x.Notes) > 0 {
+			logger.Plain("    notes:")
+			for _, n := range x.Notes {
+				logger.Plain("      %s  %s  %s", clock.Format(n.Time, "2006-01-02 15:04"), n.Author, n.Text)
+			}
+		}
+	}
+}
+
+// walPath is the write-ahead log file from the config
+func walPath() string {
+	if p := config.Global.WAL.Path; p != "" {
+		return config.DataPath(p)
+	}
+	return config.DataPath("margraf_wal.jsonl")
+}
+
+// openWAL replays the changes logged since graphFile was last saved into g,
+// then logs g's changes from now on
+func openWAL(g *graph.Graph, graphFile string) (*graph.WAL, error) {
+	path := walPath()
+	applied, err := g.ReplayWAL(path, graphFile)
+	if err != nil {
+		return nil, err
+	}
+	if applied > 0 {
+		logger.Success("Recovered %d changes made after the last save from %s", applied, path)
+	}
+	wal, err := graph.OpenWAL(path)
+	if err != nil {
+		return nil, err
+	}
+	if mb := config.Global.WAL.MaxMB; mb > 0 {
+		wal.MaxBytes = int64(mb) << 20
+	}
+	g.SetWAL(wal)
+	return wal, nil
+}
+
+// printWAL shows the last n entries of the write-ahead log
+func printWAL(n int) {
+	path := walPath()
+	var entries []graph.WALEntry
+	err := graph.ReadWAL(path, 0, func(e graph.WALEntry) error {
+		entries = append(entries, e)
+		if len(entries) > n {
+			entries = entries[1:]
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		logger.Error(logger.StatusErr, "Reading write-ahead log failed: %v", err)
+		return
+	}
+	logger.Plain("")
+	logger.Section("Write-ahead Log: " + path)
+	if len(entries) == 0 {
+		logger.Plain("  No changes logged since it was last rotated")
+		return
+	}
+	for _, e := range entries {
+		var what string
+		switch {
+		case e.Checkpoint != "":
+			what = fmt.Sprintf("saved %s through #%d", e.Checkpoint, e.Through)
+		case e.Delta == nil:
+			continue
+		case e.Delta.Kind == graph.DeltaNode && e.Delta.Node != nil:
+			what = "node " + e.Delta.Node.ID
+		case (e.Delta.Kind == graph.DeltaEdge || e.Delta.Kind == graph.DeltaEdgeRemoved) && e.Delta.Edge != nil:
+			what = fmt.Sprintf("edge %s -[%s]-> %s", e.Delta.Edge.SourceID, e.Delta.Edge.Type, e.Delta.Edge.TargetID)
+		case e.Delta.Kind == graph.DeltaHealth:
+			what = fmt.Sprintf("%s health %.2f", e.Delta.NodeID, e.Delta.Health)
+		case (e.Delta.Kind == graph.DeltaNote || e.Delta.Kind == graph.DeltaNoteRemoved) && e.Delta.Note != nil:
+			what = fmt.Sprintf("note %s on %s", e.Delta.Note.ID, e.Delta.Note.Subject())
+		}
+		kind := "checkpoint"
+		if e.Delta != nil {
+			kind = string(e.Delta.Kind)
+		}
+		logger.Plain("  #%-8d %s  %-12s %s", e.Seq, clock.Format(e.Time, "2006-01-02 15:04:05"), kind, what)
+	}
+}
+
+// printLLMLog shows the LLM exchanges that named an entity or have a key
+func printLLMLog(ref string) {
+	exchanges, err := llm.FindExchanges(ref)
+	if err != nil {
+		logger.Error(logger.StatusErr, "Reading LLM log failed: %v", err)
+		return
+	}
+	logger.Plain("")
+	logger.Section("LLM Log: " + ref)
+	if len(exchanges) == 0 {
+		logger.Plain("  No exchanges (none named it, or they were rotated out)")
+		return
+	}
+	for _, x := range exchanges {
+		logger.Plain("  %s  %s  %-8s %s/%s  %dms  %d+%d tokens", clock.Format(x.Time, "2006-01-02 15:04"), x.Key, x.Consumer,
+			x.Provider, x.Model, x.LatencyMs, x.PromptTokens, x.ReplyTokens)
+		logger.Plain("      prompt: %s", oneLine(x.Prompt, 200))
+		if x.Error != "" {
+			logger.Plain("      error: %s", oneLine(x.Error, 200))
+			continue
+		}
+		logger.Plain("      reply: %s", oneLine(x.Response, 300))
+		if len(x.Entities) > 0 {
+			logger.Plain("      entities: %s", strings.Join(x.Entities, ", "))
+		}
+	}
+}
+
+// oneLine collapses whitespace in s and cuts it to max runes
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max]) + "..."
+	}
+	return s
+}
+
+// printExploration shows how much of each node type discovery has expanded
+func printExploration(g *graph.Graph) {
+	logger.Plain("")
+	logger.Section("Exploration")
+	counts := g.ExplorationCounts()
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+	logger.Plain("  %-16s %10s %10s %10s", "type", "unexplored", "partial", "complete")
+	for _, t := range types {
+		c := counts[graph.NodeType(t)]
+		logger.Plain("  %-16s %10d %10d %10d", t, c[graph.ExplorationUnexplored], c[graph.ExplorationPartial], c[graph.ExplorationComplete])
+	}
+}
+
+// seedStarter merges a starter dataset (built-in when source is empty) into g
+func seedStarter(ctx context.Context, g *graph.Graph, source string) error {
+	bundle, err := discovery.LoadStarterBundle(ctx, source)
+	if err != nil {
+		return err
+	}
+	companies, relations := discovery.ApplyStarter(g, bundle)
+	logger.Success("Starter dataset %q loaded: %d companies, %d supplier relations (%s)", bundle.Name, companies, relations, g.String())
+	return nil
+}
+
+// seedSynthetic merges a generated graph described by spec
+// ("nations,industries,companies[,density[,seed]]") into g
+func seedSynthetic(g *graph.Graph, spec string) error {
+	parts := strings.Split(spec, ",")
+	if len(parts) < 3 || len(parts) > 5 {
+		return fmt.Errorf("want nations,industries,companies[,density[,seed]], got %q", spec)
+	}
+	var counts [3]int
+	for i := range counts {
+		n, err := strconv.Atoi(strings.TrimSpace(parts[i]))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid count %q", parts[i])
+		}
+		counts[i] = n
+	}
+	density, seed := 0.05, int64(1)
+	if len(parts) > 3 {
+		d, err := strconv.ParseFloat(strings.TrimSpace(parts[3]), 64)
+		if err != nil || d < 0 || d > 1 {
+			return fmt.Errorf("invalid density %q (0-1)", parts[3])
+		}
+		density = d
+	}
+	if len(parts) > 4 {
+		n, err := strconv.ParseInt(strings.TrimSpace(parts[4]), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid seed %q", parts[4])
+		}
+		seed = n
+	}
+
+	synth := graph.GenerateSynthetic(counts[0], counts[1], counts[2], density, seed)
+	batch := g.NewBatch(0)
+	synth.NodesRange(func(n *graph.Node) { batch.AddNode(n) })
+	synth.EdgesRange(batch.AddEdge)
+	batch.Flush()
+	logger.Success("Synthetic graph generated: %s", g.String())
+	return nil
+}
+
+// printErrors lists recent subsystem failures, newest first
+func printErrors(events []syserr.Event) {
+	logger.Plain("")
+	logger.Section("System Errors")
+	if len(events) == 0 {
+		logger.Plain("  No errors reported")
+		return
+	}
+	for _, e := range events {
+		retry := ""
+		if e.Retryable {
+			retry = " (retryable)"
+		}
+		logger.Plain("  %s %-8s %-10s %s: %s%s", clock.Format(e.Timestamp, "15:04:05"), e.Severity, e.Module, e.Op, e.Message, retry)
+	}
+}
+
+// printIndustryRollups lists industries from least to most healthy with their
+// supplier-country concentration
+func printIndustryRollups(rollups []graph.IndustryRollup) {
+	logger.Plain("")
+	logger.Section("Industries")
+	if len(rollups) == 0 {
+		logger.Plain("  No industries with companies")
+		return
+	}
+	logger.Plain("  %-28s %6s %9s %7s  %s", "Industry", "Health", "Companies", "HHI", "Top supplier country")
+	for _, r := range rollups {
+		top := "-"
+		if r.TopCountry != "" {
+			top = fmt.Sprintf("%s (%.0f%%)", r.TopCountry, r.TopShare*100)
+		}
+		logger.Plain("  %-28s %6.2f %9d %7.2f  %s", r.Name+" ["+r.IndustryID+"]", r.Health, r.Companies, r.Concentration, top)
+	}
+}
+
+// printUnresolvedCountries lists the country names data sources could not map
+// to an ISO code
+func printUnresolvedCountries(names []string) {
+	logger.Plain("")
+	logger.Section("Unresolved Countries")
+	if len(names) == 0 {
+		logger.Plain("  All country names resolved")
+		return
+	}
+	for _, name := range names {
+		logger.Plain("  %s", name)
+	}
+	logger.Plain("  Map them under countries.aliases in config.yaml, then reload")
+}
+
+// printPortfolio lists the holdings and the nations, suppliers and raw
+// materials most of the portfolio depends on, marking concentrated ones
+func printPortfolio(risk *graph.PortfolioRisk, threshold float64) {
+	logger.Plain("")
+	logger.Section("Portfolio")
+	if len(risk.Positions) == 0 && len(risk.Unmapped) == 0 {
+		logger.Plain("  No holdings. Add one with: portfolio add <TICKER> <quantity>")
+		return
+	}
+	for _, p := range risk.Positions {
+		logger.Plain("  %-8s %-28s %10g  %5.1f%%  health %.2f", p.Ticker, p.Name, p.Quantity, p.Weight*100, p.Health)
+	}
+	if len(risk.Unmapped) > 0 {
+		logger.Plain("  Not in the graph: %s", strings.Join(risk.Unmapped, ", "))
+	}
+	if len(risk.Positions) == 0 {
+		return
+	}
+	logger.Plain("  Weighted health: %.2f", risk.Health)
+	logger.Plain("")
+	logger.Plain("  %-12s %-28s %6s %6s %4s  %s", "Exposure", "Depends on", "Share", "Direct", "Hops", "Holdings")
+	shown := 0
+	for _, e := range risk.Exposures {
+		if shown >= 15 && !e.Concentrated {
+			continue
+		}
+		flag := ""
+		if e.Concentrated {
+			flag = "  (concentrated)"
+		}
+		logger.Plain("  %-12s %-28s %5.1f%% %5.1f%% %4d  %s%s", e.Kind, e.Name, e.Share*100, e.Direct*100, e.Hops, strings.Join(e.Holdings, ","), flag)
+		shown++
+	}
+	if n := len(risk.Concentrated()); n > 0 {
+		logger.Warn(logger.StatusWarn, "%d hidden exposures at or above %.0f%% of the portfolio", n, threshold*100)
+	}
+}
+
+// printPaper shows the paper trader's performance, open positions and the
+// last two weeks of NAV
+func printPaper(perf *trading.PaperPerformance) {
+	logger.Plain("")
+	logger.Section("Paper Trading")
+	if len(perf.Pairs) == 0 {
+		logger.Plain("  No pairs configured. List them under paper.pairs (or paper.strategies) in config.yaml, e.g. [\"KO/PEP\"]")
+		return
+	}
+	logger.Plain("  Trading: %s", strings.Join(perf.Pairs, ", "))
+	logger.Plain("  NAV %.2f (started %.2f)   PnL %+.2f (realized %+.2f, unrealized %+.2f)", perf.NAV, perf.InitialCapital, perf.CumulativePnL, perf.RealizedPnL, perf.UnrealizedPnL)
+	logger.Plain("  Open risk %.2f   Signals %d   Hit rate %.0f%% (%d of %d closed)", perf.OpenRisk, perf.Signals, perf.HitRate*100, perf.Hits, perf.Closed)
+	for _, p := range perf.Positions {
+		entry := fmt.Sprintf("%.2f / %.2f", p.EntryPrice1, p.EntryPrice2)
+		if p.Ticker2 == "" {
+			entry = fmt.Sprintf("%.2f", p.EntryPrice1)
+		}
+		logger.Plain("    %-12s %-15s qty %.2f  entry %s  z %.2f  since %s", p.Pair, p.Direction, p.Quantity, entry, p.EntryZScore, clock.Format(p.EntryTime, "2006-01-02 15:04"))
+	}
+	history := perf.History
+	if len(history) > 14 {
+		history = history[len(history)-14:]
+	}
+	if len(history) > 0 {
+		logger.Plain("  %-10s %12s %10s %10s", "date", "nav", "pnl", "open risk")
+	}
+	for _, p := range history {
+		logger.Plain("  %-10s %12.2f %+10.2f %10.2f", p.Date, p.NAV, p.PnL, p.OpenRisk)
+	}
+}
+
+// printCalendar lists scheduled events over the next days, for one node or
+// all of them
+func printCalendar(g *graph.Graph, nodeID string, days int) {
+	logger.Plain("")
+	logger.Section("Calendar")
+	events := g.UpcomingEvents(nodeID, time.Duration(days)*24*time.Hour)
+	if len(events) == 0 {
+		logger.Plain("  No scheduled events in the next %d days (see the calendar section of config.yaml)", days)
+		return
+	}
+	for _, ev := range events {
+		name := ev.NodeID
+		if node, ok := g.GetNode(ev.NodeID); ok {
+			name = node.Name
+		}
+		logger.Plain("  %-16s %-6s %-8s %-24s %s", clock.Format(ev.Time, "Mon Jan 02 15:04"), ev.Impact, ev.Kind, name, ev.Title)
+	}
+}
+
+// printNotes lists analyst notes, oldest first
+func printNotes(title string, notes []graph.Note) {
+	logger.Plain("")
+	logger.Section(title)
+	if len(notes) == 0 {
+		logger.Plain("  No notes (add one with 'note <nodeID> <text>')")
+		return
+	}
+	for _, n := range notes {
+		tags := ""
+		for _, t := range n.Tags {
+			tags += " #" + t
 		}
-	case "export":
-		if len(parts) < 2 {
-			logger.Warn(logger.StatusWarn, "Usage: export <filename.dot>")
-			return
+		logger.Plain("  %s  %-16s %s  %s", n.ID, clock.Format(n.Time, "2006-01-02 15:04"), n.Author, n.Subject())
+		logger.Plain("      %s%s", n.Text, tags)
+	}
+}
+
+// consoleAuthor is who notes added from the console are by: console.author,
+// else the user running margraf
+func consoleAuthor() string {
+	if author := config.Global.Console.Author; author != "" {
+		return author
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "console"
+}
+
+// printCoMentions lists the proposed co-mention edges with their latest
+// supporting items
+func printCoMentions(learner *discovery.CoMentionLearner) {
+	settings := learner.Settings
+	logger.Plain("")
+	logger.Section("Co-mention Proposals")
+	proposals := learner.Proposals()
+	if len(proposals) == 0 {
+		logger.Plain("  None (no unlinked pair named together by %d items in %d days)", settings.MinItems, int(settings.Window.Hours()/24))
+		return
+	}
+	for _, p := range proposals {
+		logger.Plain("  %s -[%s]-> %s  (%d items)", p.Edge.SourceID, p.Edge.Type, p.Edge.TargetID, len(p.Items))
+		shown := p.Items
+		if len(shown) > 3 {
+			shown = shown[len(shown)-3:]
 		}
-		if err := os.WriteFile(parts[1], []byte(g.ToDOT()), 0644); err != nil {
-			logger.Error(logger.StatusErr, "Error exporting DOT: %v", err)
-		} else {
-			logger.Success("Graph exported to %s", parts[1])
+		for i := len(shown) - 1; i >= 0; i-- {
+			it := shown[i]
+			logger.Plain("      %s [%s] %s", clock.Format(it.Time, "2006-01-02 15:04"), it.Source, oneLine(it.Text, 80))
+		}
+	}
+	logger.Plain("")
+	logger.Plain("  Add one with: comentions accept <ID1> <ID2>")
+}
+
+// printMentions shows a node's hourly social mentions over the last day,
+// with the baseline a spike is measured against
+func printMentions(g *graph.Graph, nodeID string, settings social.VolumeSettings) {
+	logger.Plain("")
+	logger.Section("Mentions: " + nodeID)
+	now := time.Now()
+	v := g.MentionVolume(nodeID, now, settings.Baseline)
+	if v.Hours == 0 && v.Current == 0 {
+		logger.Plain("  No mentions recorded (mentions are counted during social crawls)")
+		return
+	}
+	hours := g.MentionCounts(nodeID, now.Add(-23*time.Hour))
+	counts := make([]int, len(hours))
+	total := 0
+	for i, b := range hours {
+		counts[i] = b.Count
+		total += b.Count
+	}
+	logger.Plain("  Last 24h  %s  %d mentions (%s to now)", sparkline(counts), total, clock.Format(hours[0].Hour, "Jan 02 15:00"))
+	logger.Plain("  This hour %d, baseline %.2f/hour ± %.2f over %d hours", v.Current, v.Mean, v.StdDev, v.Hours)
+	logger.Plain("  A spike needs at least %d mentions and %.1f standard deviations above the baseline", settings.MinMentions, settings.Threshold)
+}
+
+// printBudget shows today's LLM usage per consumer
+func printBudget(b *llm.Budget) {
+	logger.Plain("")
+	logger.Section("LLM Budget")
+	if b == nil {
+		logger.Plain("  No budgets set (see llm.budgets in config.yaml)")
+		return
+	}
+	logger.Plain("  %-10s %14s %16s %8s %8s %s", "Consumer", "Requests", "Tokens", "Degraded", "Queued", "When spent")
+	for _, u := range b.Usage() {
+		mode := u.Limit.OnExhausted
+		if mode == "" {
+			mode = llm.OnExhaustedDegrade
+		}
+		status := ""
+		if u.Exhausted() {
+			status = "  (exhausted)"
+		}
+		logger.Plain("  %-10s %14s %16s %8d %8d %s%s", u.Consumer,
+			budgetAmount(u.Requests, u.Limit.Requests), budgetAmount(u.Tokens, u.Limit.Tokens), u.Denied, u.Queued, mode, status)
+	}
+}
+
+// printProviders shows the LLM failover chain, healthiest first
+func printProviders(client *llm.Client) {
+	logger.Plain("")
+	logger.Section("LLM Providers")
+	for i, p := range client.Providers() {
+		status := ""
+		if p.CircuitOpen {
+			status = "  (circuit open)"
+		}
+		logger.Plain("  %d. %-10s %-32s %3d calls %3d failed (%3.0f%%)%s",
+			i+1, p.Provider, p.Model, p.Calls, p.Failures, p.FailureRate*100, status)
+	}
+}
+
+// budgetAmount formats an amount used against its limit (0 = unlimited)
+func budgetAmount(used, limit int) string {
+	if limit <= 0 {
+		return strconv.Itoa(used)
+	}
+	return fmt.Sprintf("%d/%d", used, limit)
+}
+
+// printMacros lists the defined macros
+func printMacros(list []script.Macro) {
+	logger.Plain("")
+	logger.Section("Macros")
+	if len(list) == 0 {
+		logger.Plain("  None. Define one with: crisis = shock taiwan; shock suez_canal; stress")
+		return
+	}
+	for _, m := range list {
+		logger.Plain("  %-14s = %s", m.Name, m.Body)
+	}
+}
+
+// printPairs lists the monitored pairs by how stretched their price ratio
+// is, marking those past entry
+func printPairs(readings []trading.PairWatch, entry float64) {
+	logger.Plain("")
+	logger.Section("Pair Monitor")
+	if len(readings) == 0 {
+		logger.Plain("  No readings yet. Pairs need two corporations with tickers linked in the graph; \"pairs scan\" rescans now")
+		return
+	}
+	for i, w := range readings {
+		p := w.Pair
+		mark := ""
+		if action := w.Action(entry); action != "" {
+			mark = "  <- " + action
+		}
+		logger.Plain("  %2d. %-8s / %-8s z %+.2f  corr %+.2f (prior %.2f)  %s%s",
+			i+1, p.Ticker1, p.Ticker2, w.ZScore, p.Correlation, p.GraphPrior, time.Unix(w.Timestamp, 0).UTC().Format("2006-01-02"), mark)
+	}
+}
+
+// printStress lists the most stressed nodes with their signal breakdown
+func printStress(ranked []graph.NodeStress) {
+	logger.Plain("")
+	logger.Section("Early Warning")
+	if len(ranked) == 0 {
+		logger.Plain("  No stressed nodes")
+		return
+	}
+	for i, n := range ranked {
+		c := n.Components
+		logger.Plain("  %2d. %-28s %.2f  (news %.2f, social %.2f, edges %.2f, health %.2f)",
+			i+1, n.Name, n.Score, c.News, c.Social, c.Edges, c.Health)
+	}
+}
+
+// parseStressOptions reads the flags of "stress --type"
+func parseStressOptions(args []string) (simulation.TypeStressOptions, error) {
+	var opts simulation.TypeStressOptions
+	for i := 0; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return opts, fmt.Errorf("%s needs a value", args[i])
+		}
+		v := args[i+1]
+		switch args[i] {
+		case "--type":
+			opts.Type = graph.NodeType(v)
+		case "--affected":
+			opts.Affected = graph.NodeType(v)
+		case "--impact":
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 || f > 1 {
+				return opts, fmt.Errorf("invalid impact %q (between 0 and 1)", v)
+			}
+			opts.Impact = f
+		case "--top":
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return opts, fmt.Errorf("invalid top %q", v)
+			}
+			opts.Top = n
+		default:
+			return opts, fmt.Errorf("unknown option %s", args[i])
+		}
+	}
+	if opts.Type == "" {
+		return opts, fmt.Errorf("--type is required")
+	}
+	return opts, nil
+}
+
+// printVulnerabilities prints a type stress test's ranked table
+func printVulnerabilities(r *simulation.TypeStressReport) {
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("Vulnerability: %d %s shocks at %.0f%%", r.Shocks, r.Type, r.Impact*100))
+	if len(r.Nodes) == 0 {
+		logger.Plain("  No %s lost health to any shock", r.Affected)
+		return
+	}
+	logger.Plain("  %d %s nodes hit at least once (%v)", r.Exposed, r.Affected, r.Duration.Round(time.Millisecond))
+	logger.Plain("  %3s  %-28s %5s %6s %9s %9s  %s", "#", string(r.Affected), "hits", "rate", "mean drop", "max drop", "worst shock")
+	for i, v := range r.Nodes {
+		logger.Plain("  %3d  %-28s %5d %5.0f%% %9.3f %9.3f  %s",
+			i+1, v.Name, v.Hits, v.HitRate*100, v.MeanDrop, v.MaxDrop, v.WorstName)
+	}
+}
+
+// printBetweenness prints a betweenness ranking
+func printBetweenness(r *graph.BetweennessReport) {
+	logger.Plain("")
+	how := "exact"
+	if r.Sampled {
+		how = fmt.Sprintf("sampled from %d of %d nodes", r.Sources, r.Total)
+	}
+	logger.Section(fmt.Sprintf("Betweenness Centrality (%s, %v)", how, r.Duration.Round(time.Millisecond)))
+	for i, n := range r.Nodes {
+		if n.Betweenness == 0 {
+			if i == 0 {
+				logger.Plain("  No node lies between others")
+			}
+			break
+		}
+		logger.Plain("  %3d. %-32s %-15s %.4f", i+1, n.Name, n.Type, n.Betweenness)
+	}
+}
+
+// printHittingTime prints a hitting time and how it was reached
+func printHittingTime(h *graph.HittingTime) {
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("Hitting Time: %s -> %s", h.From, h.To))
+	if !h.Sampled {
+		logger.Plain("  %.1f steps (exact, %v)", h.Steps, h.Duration.Round(time.Millisecond))
+		return
+	}
+	logger.Plain("  %.1f ± %.1f steps (%d walks, %.0f%% arrived, %v)",
+		h.Steps, 1.96*h.StdErr, h.Walks, h.Reached*100, h.Duration.Round(time.Millisecond))
+	if h.Reached < 0.95 {
+		logger.Plain("  Walks that gave up are left out, so the true time is longer; raise analytics.max_steps")
+	}
+}
+
+// historyRows bounds the days printed by "history <ID>"
+const historyRows = 30
+
+// printHistoryDays lists the days in the history store
+func printHistoryDays(store *history.Store) {
+	days, err := store.Days()
+	if err != nil {
+		logger.Error(logger.StatusErr, "Error reading history: %v", err)
+		return
+	}
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("History (%s)", store.Dir))
+	if len(days) == 0 {
+		logger.Plain("  No days recorded yet; the history job records one each evening, or run 'history snapshot'")
+		return
+	}
+	logger.Plain("  %d days, %s to %s", len(days), days[0].Date, days[len(days)-1].Date)
+	for _, d := range days[max(len(days)-historyRows, 0):] {
+		logger.Plain("  %s  %6d nodes  %7d edges", d.Date, d.Nodes, d.Edges)
+	}
+}
+
+// printNodeHistory prints a node's recent end-of-day rows
+func printNodeHistory(store *history.Store, id string) {
+	rows, err := store.Nodes(history.Query{NodeID: id})
+	if err != nil {
+		logger.Error(logger.StatusErr, "Error reading history: %v", err)
+		return
+	}
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("History: %s", id))
+	if len(rows) == 0 {
+		logger.Plain("  No days recorded for %s", id)
+		return
+	}
+	logger.Plain("  %-10s  %6s  %12s  %6s", "date", "health", "price", "stress")
+	for _, r := range rows[max(len(rows)-historyRows, 0):] {
+		price := "-"
+		if r.Price > 0 {
+			price = strings.TrimSpace(fmt.Sprintf("%.2f %s", r.Price, r.Currency))
+		}
+		logger.Plain("  %-10s  %6.3f  %12s  %6.3f", r.Date, r.Health, price, r.Stress)
+	}
+}
+
+// printEdgeHistory prints the recent end-of-day rows of the edges from src to tgt
+func printEdgeHistory(store *history.Store, src, tgt string) {
+	rows, err := store.Edges(history.Query{SourceID: src, TargetID: tgt})
+	if err != nil {
+		logger.Error(logger.StatusErr, "Error reading history: %v", err)
+		return
+	}
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("History: %s -> %s", src, tgt))
+	if len(rows) == 0 {
+		logger.Plain("  No days recorded for edges from %s to %s", src, tgt)
+		return
+	}
+	for _, r := range rows[max(len(rows)-historyRows, 0):] {
+		edge := string(r.Type)
+		if r.HSCode != "" {
+			edge += " (HS " + r.HSCode + ")"
+		}
+		logger.Plain("  %s  %-28s %.3f  %s", r.Date, edge, r.Weight, r.Status)
+	}
+}
+
+// printAnomalies lists edge anomalies, most unusual first
+func printAnomalies(g *graph.Graph, list []graph.EdgeAnomaly, window time.Duration) {
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("Edge Anomalies (last %v)", window))
+	if len(list) == 0 {
+		logger.Plain("  No edge weight moved far outside its history")
+		return
+	}
+	name := func(id string) string {
+		if n, ok := g.GetNode(id); ok && n.Name != "" {
+			return n.Name
+		}
+		return id
+	}
+	for _, a := range list {
+		p := server.EdgeAnomalyPayload{EdgeAnomaly: a, SourceName: name(a.SourceID), TargetName: name(a.TargetID)}
+		logger.Plain("  %s  %s", clock.Format(a.Time, "01-02 15:04"), simulation.AnomalyMessage(p))
+	}
+}
+
+// healthModelFromConfig builds the graph health model from simulation.health,
+// starting from the built-in defaults.
+func healthModelFromConfig() (*graph.HealthModel, error) {
+	cfg := config.Global.Simulation
+	model := graph.DefaultHealthModel()
+
+	toParams := func(p config.HealthParams) graph.HealthParams {
+		params := graph.HealthParams{Min: p.Min, Max: p.Max, Update: p.Update}
+		if len(p.Weights) > 0 {
+			params.Weights = make(map[graph.HealthInput]float64, len(p.Weights))
+			for input, w := range p.Weights {
+				params.Weights[graph.HealthInput(input)] = w
+			}
+		}
+		return params
+	}
+
+	def := toParams(cfg.Health.Default)
+	if def.Min != 0 {
+		model.Default.Min = def.Min
+	}
+	if def.Max != 0 {
+		model.Default.Max = def.Max
+	}
+	if def.Update != "" {
+		model.Default.Update = def.Update
+	}
+	for input, w := range def.Weights {
+		model.Default.Weights[input] = w
+	}
+	if _, ok := def.Weights[graph.InputSentiment]; !ok && cfg.SentimentScale != 0 {
+		model.Default.Weights[graph.InputSentiment] = cfg.SentimentScale
+	}
+
+	for nodeType, p := range cfg.Health.NodeTypes {
+		if !graph.KnownNodeType(graph.NodeType(nodeType)) {
+			return nil, fmt.Errorf("health.node_types: unknown node type %q (declare it under types.nodes)", nodeType)
+		}
+		model.ByType[graph.NodeType(nodeType)] = toParams(p)
+	}
+
+	return model, model.Validate()
+}
+
+// propagationOverridesPath is where "propagation set" saves its factors
+func propagationOverridesPath() string {
+	if p := config.Global.Simulation.Propagation.Overrides; p != "" {
+		return config.DataPath(p)
+	}
+	return config.DataPath("margraf_propagation.json")
+}
+
+// propagationModelFromConfig layers simulation.propagation and the factors
+// saved by "propagation set" over the built-in propagation factors
+func propagationModelFromConfig() (*graph.PropagationModel, error) {
+	cfg := config.Global.Simulation.Propagation
+	model := graph.DefaultPropagationModel()
+	if cfg.Default != 0 {
+		model.Default = cfg.Default
+	}
+	var fromConfig graph.PropagationOverrides
+	for edgeType, f := range cfg.EdgeTypes {
+		if !graph.KnownEdgeType(graph.EdgeType(edgeType)) {
+			return nil, fmt.Errorf("propagation.edge_types: unknown edge type %q (declare it under types.edges)", edgeType)
+		}
+		fromConfig.Set(graph.PropagationRule{EdgeType: graph.EdgeType(edgeType), Factor: f})
+	}
+	for _, p := range cfg.Pairs {
+		if p.Source == "" && p.Target == "" {
+			return nil, fmt.Errorf("propagation pair for %s names no node types; use edge_types", p.EdgeType)
+		}
+		if err := checkRuleTypes(p.EdgeType, p.Source, p.Target); err != nil {
+			return nil, fmt.Errorf("propagation.pairs: %w", err)
+		}
+		fromConfig.Set(graph.PropagationRule{
+			EdgeType: graph.EdgeType(p.EdgeType),
+			Source:   graph.NodeType(p.Source),
+			Target:   graph.NodeType(p.Target),
+			Factor:   p.Factor,
+		})
+	}
+	model.Apply(fromConfig)
+
+	saved, err := graph.LoadPropagationOverrides(propagationOverridesPath())
+	if err != nil {
+		return nil, err
+	}
+	model.Apply(saved)
+	return model, model.Validate()
+}
+
+// confidenceModelFromConfig layers simulation.confidence over the built-in
+// edge confidences. Disabled, confidence is still reported but no longer
+// scales propagation.
+func confidenceModelFromConfig() (*graph.ConfidenceModel, error) {
+	cfg := config.Global.Simulation.Confidence
+	model := graph.DefaultConfidenceModel()
+	model.Weight = 0
+	if cfg.Enabled {
+		model.Weight = 1
+		if cfg.Weight != 0 {
+			model.Weight = cfg.Weight
+		}
+	}
+	if cfg.Default != 0 {
+		model.Default = cfg.Default
+	}
+	for origin, c := range cfg.Origins {
+		model.ByOrigin[origin] = c
+	}
+	return model, model.Validate()
+}
+
+// declareTypesFromConfig declares the node and edge types listed under types
+func declareTypesFromConfig() error {
+	cfg := config.Global.Types
+	nodes := make([]graph.NodeTypeDef, 0, len(cfg.Nodes))
+	for _, t := range cfg.Nodes {
+		nodes = append(nodes, graph.NodeTypeDef{Name: graph.NodeType(t.Name), Color: t.Color, Description: t.Description})
+	}
+	edges := make([]graph.EdgeTypeDef, 0, len(cfg.Edges))
+	for _, t := range cfg.Edges {
+		edges = append(edges, graph.EdgeTypeDef{
+			Name:           graph.EdgeType(t.Name),
+			Directionality: graph.EdgeDirectionality(t.Directionality),
+			Propagation:    t.Propagation,
+			Description:    t.Description,
+		})
+	}
+	return graph.DeclareTypes(nodes, edges)
+}
+
+// applyCountryAliases registers the country names mapped in config.yaml
+func applyCountryAliases() {
+	for name, target := range config.Global.Countries.Aliases {
+		if err := graph.AddCountryAlias(name, target); err != nil {
+			logger.Warn(logger.StatusWarn, "countries.aliases: %v", err)
 		}
-	case "exit", "quit", "q":
-		logger.Info(logger.StatusOK, "Shutting down...")
-		tuiApp.Stop()
-	case "help", "?":
-		logger.Plain("")
-		logger.Section("Available Commands")
-		logger.Plain("  show          - Show all nodes and edges")
-		logger.Plain("  edges         - Show edge directionality rules")
-		logger.Plain("  discover      - Discover and add supplier/client relationships")
-		logger.Plain("  companies     - List all companies in the graph")
-		logger.Plain("  relations <ID>- Show supplier/client relations for a company")
-		logger.Plain("  shock <ID>    - Simulate a trade ban/shock on a Node ID")
-		logger.Plain("  boost <ID>    - Simulate positive news boost for a Node ID")
-		logger.Plain("  news          - Force check for latest news")
-		logger.Plain("  simulate <ID> <sentiment> - Test news impact (sentiment: -1.0 to 1.0)")
-		logger.Plain("  social <T>    - Crawl real social media for Topic T")
-		logger.Plain("  save <F>      - Save graph to file F")
-		logger.Plain("  load <F>      - Load graph from file F")
-		logger.Plain("  export <F>    - Export graph to DOT file F")
-		logger.Plain("  exit          - Quit")
-	default:
-		logger.Warn(logger.StatusWarn, "Unknown command: %s (type 'help' for commands)", parts[0])
 	}
 }
 
-func loadEnv() {
-	file, err := os.Open(".env")
+func loadEnv(path string) {
+	file, err := os.Open(path)
 	if err != nil {
 		// .env file is optional in some environments, so we just return if not found
 		return
@@ -477,22 +4368,7 @@ func printEdgeDirectionality() {
 	logger.Plain("How shocks propagate through different edge types:")
 	logger.Plain("")
 
-	edgeTypes := []graph.EdgeType{
-		graph.EdgeTypeSupplies,
-		graph.EdgeTypeProcuresFrom,
-		graph.EdgeTypeManufactures,
-		graph.EdgeTypeConsumes,
-		graph.EdgeTypeProduces,
-		graph.EdgeTypeDependsOn,
-		graph.EdgeTypeRequires,
-		graph.EdgeTypeTrade,
-		graph.EdgeTypeCapital,
-		graph.EdgeTypeCompetesWith,
-		graph.EdgeTypeSubstituteFor,
-		graph.EdgeTypeRegulatory,
-		graph.EdgeTypeHasIndustry,
-		graph.EdgeTypeHasCompany,
-	}
+	edgeTypes := graph.EdgeTypes() // Built-in, then declared under types.edges
 
 	logger.Plain("%-25s %-40s", "Edge Type", "Directionality & Propagation")
 	logger.Plain(strings.Repeat("-", 70))
@@ -512,6 +4388,31 @@ func printEdgeDirectionality() {
 	logger.Plain("")
 }
 
+// printTypes lists the node and edge types with how many nodes and edges
+// have each, marking declared types and ones the graph uses undeclared
+func printTypes(g *graph.Graph) {
+	origin := func(info graph.TypeInfo) string {
+		if info.Origin == graph.TypeBuiltin {
+			return ""
+		}
+		return " (" + info.Origin + ")"
+	}
+	logger.Section("Node Types")
+	for _, info := range g.NodeTypeInfo() {
+		logger.Plain("  %-22s %6d%s", info.Name, info.Count, origin(info))
+		if info.Description != "" {
+			logger.Plain("      %s", info.Description)
+		}
+	}
+	logger.Section("Edge Types")
+	for _, info := range g.EdgeTypeInfo() {
+		logger.Plain("  %-22s %6d  %-14s %.2f%s", info.Name, info.Count, info.Directionality, info.Propagation, origin(info))
+		if info.Description != "" {
+			logger.Plain("      %s", info.Description)
+		}
+	}
+}
+
 func migrateEdges(g *graph.Graph, graphFile string) {
 	logger.Plain("")
 	logger.Section("Edge Migration")
@@ -549,14 +4450,15 @@ func updateEdgesForTest(g *graph.Graph, nodeID string, sentiment float64, reason
 	// Get all outgoing edges
 	outgoingEdges := g.GetOutgoingEdges(nodeID)
 	relevance := 0.9 // High relevance for test events
-	eventID := fmt.Sprintf("test_%d", time.Now().Unix())
+	eventID := graph.NewEventID("test")
 
 	updatedCount := 0
 	for _, edge := range outgoingEdges {
-		err := g.UpdateEdgeWeight(
+		err := g.UpdateCommodityEdgeWeight(
 			edge.SourceID,
 			edge.TargetID,
 			edge.Type,
+			edge.Commodity(),
 			sentiment,
 			relevance,
 			eventID,
@@ -611,6 +4513,36 @@ func printCompanyRelations(relations *graph.CompanyRelations) {
 	}
 	logger.Plain("")
 
+	// Parents
+	logger.Plain("Parents (%d):", len(relations.Parents))
+	if len(relations.Parents) > 0 {
+		for _, parent := range relations.Parents {
+			ticker := ""
+			if parent.Ticker != "" {
+				ticker = fmt.Sprintf(" [%s]", parent.Ticker)
+			}
+			logger.Plain("  • %s%s - Health: %.2f", parent.Name, ticker, parent.Health)
+		}
+	} else {
+		logger.Plain("  (none)")
+	}
+	logger.Plain("")
+
+	// Subsidiaries
+	logger.Plain("Subsidiaries (%d):", len(relations.Subsidiaries))
+	if len(relations.Subsidiaries) > 0 {
+		for _, sub := range relations.Subsidiaries {
+			ticker := ""
+			if sub.Ticker != "" {
+				ticker = fmt.Sprintf(" [%s]", sub.Ticker)
+			}
+			logger.Plain("  • %s%s - Health: %.2f", sub.Name, ticker, sub.Health)
+		}
+	} else {
+		logger.Plain("  (none)")
+	}
+	logger.Plain("")
+
 	// Raw Materials
 	logger.Plain("Raw Materials (%d):", len(relations.RawMaterials))
 	if len(relations.RawMaterials) > 0 {
@@ -632,3 +4564,84 @@ func printCompanyRelations(relations *graph.CompanyRelations) {
 		logger.Plain("  (none)")
 	}
 }
+
+// billions formats a USD value as "$12.34B", or "n/a" when unknown
+func billions(v float64) string {
+	if v <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("$%.2fB", v/1e9)
+}
+
+// printNationRelations displays a nation's industries, companies and trade
+func printNationRelations(relations *graph.NationRelations) {
+	logger.Plain("")
+	logger.Section(fmt.Sprintf("Nation: %s [%s]", relations.NationName, relations.NationID))
+	logger.Plain("")
+
+	// Industries
+	logger.Plain("Industries (%d):", len(relations.Industries))
+	if len(relations.Industries) > 0 {
+		for _, industry := range relations.Industries {
+			logger.Plain("  • %s - Health: %.2f", industry.Name, industry.Health)
+		}
+	} else {
+		logger.Plain("  (none)")
+	}
+	logger.Plain("")
+
+	// Key Companies
+	logger.Plain("Key Companies (%d):", len(relations.KeyCompanies))
+	if len(relations.KeyCompanies) > 0 {
+		for _, company := range relations.KeyCompanies {
+			ticker := ""
+			if company.Ticker != "" {
+				ticker = fmt.Sprintf(" [%s]", company.Ticker)
+			}
+			logger.Plain("  • %s%s - Health: %.2f", company.Name, ticker, company.Health)
+		}
+	} else {
+		logger.Plain("  (none)")
+	}
+	logger.Plain("")
+
+	// Trade Partners
+	logger.Plain("Trade Partners (%d):", len(relations.TradePartners))
+	if len(relations.TradePartners) > 0 {
+		for _, p := range relations.TradePartners {
+			logger.Plain("  • %s - Exports: %s, Imports: %s, Weight: %.2f", p.Partner.Name, billions(p.Exports), billions(p.Imports), p.Weight)
+		}
+	} else {
+		logger.Plain("  (none)")
+	}
+	logger.Plain("")
+
+	// Commodities
+	logger.Plain("Produces (%d):", len(relations.Commodities))
+	if len(relations.Commodities) > 0 {
+		for _, commodity := range relations.Commodities {
+			logger.Plain("  • %s (%s) - Health: %.2f", commodity.Name, commodity.Type, commodity.Health)
+		}
+	} else {
+		logger.Plain("  (none)")
+	}
+	logger.Plain("")
+
+	// Import Dependencies
+	logger.Plain("Import Dependencies (%d):", len(relations.ImportDependencies))
+	if len(relations.ImportDependencies) > 0 {
+		for _, dep := range relations.ImportDependencies {
+			name := dep.Commodity
+			if name == "" {
+				name = "HS " + dep.HSCode
+			}
+			top := ""
+			if dep.TopSupplier != nil {
+				top = fmt.Sprintf(", %.0f%% from %s", dep.TopShare*100, dep.TopSupplier.Name)
+			}
+			logger.Plain("  • %s - %s%s", name, billions(dep.Value), top)
+		}
+	} else {
+		logger.Plain("  (none)")
+	}
+}
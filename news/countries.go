@@ -0,0 +1,155 @@
+package news
+
+import (
+	"cmp"
+	_ "embed"
+	"fmt"
+	"margraf/config"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/syserr"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Country feeds let news coverage grow with the graph: when a Nation node
+// appears, the feeds a catalog lists for its country (business sections of
+// major outlets, the central bank's releases) join the polled sources. The
+// bundled catalog covers the larger economies; news.country_feeds.catalog
+// adds countries or replaces their lists.
+
+//go:embed country_feeds.yaml
+var bundledCountryFeeds []byte
+
+// FeedCatalog lists news feeds by ISO 3166-1 alpha-2 country code
+type FeedCatalog map[string][]config.FeedConfig
+
+// LoadFeedCatalog reads the bundled catalog, with the countries listed in
+// the YAML file at path (if any) replacing its entries
+func LoadFeedCatalog(path string) (FeedCatalog, error) {
+	catalog, err := parseFeedCatalog(bundledCountryFeeds)
+	if err != nil {
+		return nil, fmt.Errorf("bundled feed catalog: %w", err)
+	}
+	if path == "" {
+		return catalog, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := parseFeedCatalog(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for code, feeds := range extra {
+		catalog[code] = feeds
+	}
+	return catalog, nil
+}
+
+// parseFeedCatalog reads a catalog, normalizing its country codes
+func parseFeedCatalog(data []byte) (FeedCatalog, error) {
+	var raw map[string][]config.FeedConfig
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	catalog := make(FeedCatalog, len(raw))
+	for code, feeds := range raw {
+		c, ok := graph.ResolveCountry(code)
+		if !ok {
+			return nil, fmt.Errorf("unknown country %q", code)
+		}
+		catalog[c.Alpha2] = feeds
+	}
+	return catalog, nil
+}
+
+// CoverNations adds the catalog's feeds for every Nation node whose country
+// is not covered yet, returning how many feeds were added. The Monitor calls
+// it before each poll, so a nation added to the graph is covered by the next one.
+func (e *Engine) CoverNations() int {
+	if e.Catalog == nil {
+		return 0
+	}
+	var nations []string
+	e.Graph.NodesRange(func(n *graph.Node) {
+		if n.Type == graph.NodeTypeNation {
+			nations = append(nations, n.Name)
+		}
+	})
+	sort.Strings(nations)
+	added := 0
+	for _, name := range nations {
+		added += e.AddCountryFeeds(name)
+	}
+	return added
+}
+
+// AddCountryFeeds adds the catalog's feeds for a country, named or given as
+// an ISO code, skipping feeds already polled by name or URL. A country is
+// only covered once, even if it has no feeds in the catalog.
+func (e *Engine) AddCountryFeeds(country string) int {
+	c, ok := graph.ResolveCountry(country)
+	if !ok {
+		return 0
+	}
+	e.feedsMu.Lock()
+	if e.countries[c.Alpha2] {
+		e.feedsMu.Unlock()
+		return 0
+	}
+	if e.countries == nil {
+		e.countries = make(map[string]bool)
+	}
+	e.countries[c.Alpha2] = true
+	known := make(map[string]bool, len(e.Sources))
+	for _, src := range e.Sources {
+		known[src.Name()] = true
+	}
+	for _, st := range e.feeds {
+		if st.url != "" {
+			known[st.url] = true
+		}
+	}
+
+	var names []string
+	for _, cfg := range e.Catalog[c.Alpha2] {
+		if cfg.Name == "" {
+			cfg.Name = sourceName(cfg, strings.ToLower(cmp.Or(cfg.Type, "rss")))
+		}
+		if known[cfg.Name] || cfg.URL != "" && known[cfg.URL] {
+			continue
+		}
+		src, err := NewFeedSource(cfg)
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "Skipping %s feed %s: %v", c.Name, cfg.Name, err)
+			syserr.Report(syserr.ModuleNews, "configure feed", err)
+			continue
+		}
+		st := e.feed(src.Name())
+		st.interval, st.country, st.url = time.Duration(cfg.PollInterval)*time.Second, c.Alpha2, cfg.URL
+		if st.interval <= 0 {
+			st.interval = e.CountryInterval
+		}
+		if cfg.QuietHours != "" {
+			if q, err := ParseQuietHours(cfg.QuietHours); err == nil {
+				st.quiet = q
+			}
+		}
+		// A new slice, since polls range over the one they were handed unlocked
+		e.Sources = append(e.Sources[:len(e.Sources):len(e.Sources)], src)
+		known[src.Name()], known[cfg.URL] = true, true
+		names = append(names, src.Name())
+	}
+	e.feedsMu.Unlock()
+
+	if len(names) > 0 {
+		logger.Info(logger.StatusNews, "Added news feeds for %s: %s", c.Name, strings.Join(names, ", "))
+	}
+	return len(names)
+}
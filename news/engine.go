@@ -1,16 +1,26 @@
 package news
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"margraf/audit"
+	"margraf/clock"
+	"margraf/config"
 	"margraf/discovery"
 	"margraf/graph"
 	"margraf/llm"
 	"margraf/logger"
+	"margraf/nlp"
+	"margraf/pipeline"
+	"margraf/rag"
 	"margraf/server"
 	"margraf/simulation"
 	"margraf/social"
+	"margraf/syserr"
+	"margraf/task"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,11 +31,46 @@ type Engine struct {
 	Simulator *simulation.Simulator
 	Hub       *server.Hub
 	Social    *social.SocialMonitor
-	FeedURL   string
+	Index     *rag.Index // Node descriptions for entity linking (nil = exact names only)
+	Extractor *nlp.Extractor // Headline analysis, with a local fallback when the LLM is down
+	Sources   []FeedSource // Polled in order (see ConfiguredSources); set before Monitor starts
 	LastCheck time.Time
+
+	CoMentions *discovery.CoMentionLearner // Proposes edges between nodes named in one headline (optional)
+
+	Timeout          time.Duration // Limit for one poll, including LLM analysis
+	ExpansionTimeout time.Duration // Limit for a news-triggered nation expansion
+
+	// EventWindow is how far ahead a high-impact scheduled event (see
+	// graph.ScheduledEvent) is announced, and makes news about its node and
+	// that node's edges count in full (0 = ignore the calendar)
+	EventWindow time.Duration
+	announceMu  sync.Mutex
+	announced   map[string]bool // Events announced so far, keyed by eventKey
+
+	// Per-feed polling (see poll.go)
+	feedsMu  sync.Mutex
+	feeds    map[string]*feedState // Keyed by source name
+	interval time.Duration         // Default between polls of a feed
+	quiet    QuietHours            // news.quiet_hours, for sources without their own
+
+	// Feeds added for Nation nodes (see countries.go)
+	Catalog         FeedCatalog     // nil = none added
+	CountryInterval time.Duration   // Between polls of added feeds without their own (0 = the default)
+	countries       map[string]bool // Countries covered, by ISO code; feedsMu guards it
 }
 
 func NewEngine(g *graph.Graph, c *llm.Client, s *discovery.Seeder, sim *simulation.Simulator, h *server.Hub, soc *social.SocialMonitor) *Engine {
+	sources, feeds := configuredFeeds()
+	quiet, _ := ParseQuietHours(config.Global.News.QuietHours) // Reported by configuredFeeds
+	var catalog FeedCatalog
+	if cfg := config.Global.News.CountryFeeds; cfg.Enabled {
+		var err error
+		if catalog, err = LoadFeedCatalog(cfg.Catalog); err != nil {
+			logger.Warn(logger.StatusWarn, "Not adding country news feeds: %v", err)
+			syserr.Report(syserr.ModuleNews, "load feed catalog", err)
+		}
+	}
 	return &Engine{
 		Graph:     g,
 		Client:    c,
@@ -33,137 +78,348 @@ func NewEngine(g *graph.Graph, c *llm.Client, s *discovery.Seeder, sim *simulati
 		Simulator: sim,
 		Hub:       h,
 		Social:    soc,
-		FeedURL:   "http://feeds.bbci.co.uk/news/business/rss.xml",
+		Extractor: nlp.NewExtractor(c, g),
+		Sources:   sources,
 		LastCheck: time.Now().Add(-24 * time.Hour),
+		feeds:     feeds,
+		interval:  time.Duration(config.Global.News.PollInterval) * time.Second,
+		quiet:     quiet,
+
+		Catalog:         catalog,
+		CountryInterval: time.Duration(config.Global.News.CountryFeeds.PollInterval) * time.Second,
+
+		Timeout:          config.Timeout(config.Global.Timeouts.News, 10*time.Minute),
+		ExpansionTimeout: config.Timeout(config.Global.Timeouts.Expansion, 30*time.Minute),
+		EventWindow:      48 * time.Hour,
 	}
 }
 
-type NewsImpact struct {
-	EntityName      string   `json:"entity"`
-	EntityType      string   `json:"type"`
-	ImpactScore     float64  `json:"impact"`
-	Reason          string   `json:"reason"`
-	IsNewEntitiy    bool     `json:"is_new"`
-	RelatedEntities []string `json:"related_entities,omitempty"`
-	SentimentScore  float64  `json:"sentiment,omitempty"`
-}
+// contextNodes is how many retrieved node descriptions a headline prompt gets
+const contextNodes = 5
 
-func (e *Engine) Monitor(interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	logger.Info(logger.StatusNews, "News Monitor active. Polling %s every %v...", e.FeedURL, interval)
+// Monitor polls each feed at once and then every interval (or the feed's
+// own poll_interval), skipping its quiet hours, until ctx is cancelled
+func (e *Engine) Monitor(ctx context.Context, interval time.Duration) {
+	e.CoverNations()
+	e.feedsMu.Lock()
+	e.interval = interval
+	names := make([]string, len(e.Sources))
+	for i, src := range e.Sources {
+		names[i] = src.Name()
+		e.feed(src.Name()).next = time.Time{}
+	}
+	e.feedsMu.Unlock()
+	logger.Info(logger.StatusNews, "News Monitor active. Polling %s every %v...", strings.Join(names, ", "), interval)
 
-	for range ticker.C {
-		e.FetchAndProcess()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		e.CoverNations()
+		if due := e.dueSources(time.Now()); len(due) > 0 && pipeline.Enabled(pipeline.News) {
+			e.poll(ctx, due, e.feedSince)
+		}
+		timer.Reset(time.Until(e.nextPoll()))
 	}
 }
 
-func (e *Engine) FetchAndProcess() {
-	logger.Info(logger.StatusNews, "Checking for news...")
-	items, err := FetchRSS(e.FeedURL)
-	if err != nil {
-		fmt.Printf("Error fetching RSS: %v\n", err)
-		return
+// itemsPerPoll caps how many new headlines of each feed one poll analyzes
+const itemsPerPoll = 3
+
+// FetchAndProcess analyzes the newest headlines of every source since
+// LastCheck, whatever their schedules, giving up after e.Timeout. A failing
+// source is reported and the others still run.
+func (e *Engine) FetchAndProcess(ctx context.Context) {
+	e.poll(ctx, e.sources(), func(string) time.Time { return e.LastCheck })
+	e.LastCheck = clock.Now()
+}
+
+// sources returns the sources to poll
+func (e *Engine) sources() []FeedSource {
+	e.feedsMu.Lock()
+	defer e.feedsMu.Unlock()
+	return e.Sources
+}
+
+// poll analyzes the newest headlines of sources published after since(name),
+// giving up after e.Timeout
+func (e *Engine) poll(ctx context.Context, sources []FeedSource, since func(name string) time.Time) {
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
 	}
+	ctx = llm.WithConsumer(ctx, llm.ConsumerNews)
 
-	count := 0
-	for _, item := range items {
-		if count >= 3 {
+	e.anticipate()
+
+	logger.Info(logger.StatusNews, "Checking for news...")
+	for _, src := range sources {
+		if ctx.Err() != nil {
 			break
 		}
-		
-		pubDate, _ := time.Parse(time.RFC1123, item.PubDate)
-		if pubDate.Before(e.LastCheck) {
+		started := time.Now()
+		items, err := src.Fetch(ctx)
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "Error fetching %s: %v", src.Name(), err)
+			syserr.Report(syserr.ModuleNews, "fetch "+src.Name(), err)
+			e.recordPoll(src.Name(), started, 0, err)
+			continue
+		}
+
+		cutoff := since(src.Name())
+		count := 0
+		for _, item := range items {
+			if count >= itemsPerPoll || ctx.Err() != nil {
+				break
+			}
+			// Undated items are kept; their event IDs stop repeats
+			if item.Title == "" || (!item.Published.IsZero() && item.Published.Before(cutoff)) {
+				continue
+			}
+
+			e.processItem(ctx, item)
+			count++
+		}
+		e.recordPoll(src.Name(), started, count, nil)
+	}
+}
+
+// anticipate announces each high-impact scheduled event once when it comes
+// within EventWindow, as a graph notice on its node
+func (e *Engine) anticipate() {
+	if e.EventWindow <= 0 {
+		return
+	}
+	e.announceMu.Lock()
+	defer e.announceMu.Unlock()
+	current := make(map[string]bool)
+	for _, ev := range e.Graph.UpcomingEvents("", e.EventWindow) {
+		if ev.Impact != graph.ImpactHigh {
+			continue
+		}
+		key := eventKey(ev)
+		current[key] = true
+		if e.announced[key] {
 			continue
 		}
-		
-		e.processItem(item)
-		count++
+		msg := fmt.Sprintf("Upcoming: %s on %s", ev.Title, clock.Format(ev.Time, "Mon Jan 2 15:04 MST"))
+		logger.Info(logger.StatusNews, "%s (%s)", msg, ev.NodeID)
+		e.Hub.Broadcast(server.TypeGraphNotice, server.GraphNoticePayload{NodeID: ev.NodeID, Message: msg})
+	}
+	e.announced = current // Forget events that have passed
+}
+
+// eventKey identifies a scheduled event across calendar refreshes
+func eventKey(ev graph.ScheduledEvent) string {
+	return ev.NodeID + "|" + ev.Title + "|" + ev.Time.UTC().Format(time.RFC3339)
+}
+
+// eventRelevance is how much news touching an edge counts: in full when
+// either end has a high-impact event coming up, else relevance
+func (e *Engine) eventRelevance(edge *graph.Edge, relevance float64) float64 {
+	if e.EventWindow <= 0 {
+		return relevance
+	}
+	for _, ev := range e.Graph.EdgeEvents(edge, e.EventWindow) {
+		if ev.Impact == graph.ImpactHigh {
+			return 1.0
+		}
 	}
-	e.LastCheck = time.Now()
+	return relevance
 }
 
-func (e *Engine) processItem(item RSSItem) {
+func (e *Engine) processItem(ctx context.Context, item Item) {
+	// The same headline re-delivered (feed reconnect, backfill) is applied once
+	eventID := graph.StableEventID("news", item.Title, item.Link)
+	if e.Graph.EventApplied(eventID) {
+		logger.InfoDepth(1, logger.StatusNews, "Already applied: %s", item.Title)
+		return
+	}
+
 	logger.InfoDepth(1, logger.StatusNews, "Analyzing: %s", item.Title)
-	e.Hub.Broadcast("news_alert", item.Title)
-	
-	prompt := fmt.Sprintf(`
-Analyze this financial news headline: "%s"
-Identify:
-1. The MAIN entity involved (Nation, Corporation, or RawMaterial)
-2. The economic impact score (-1.0 for catastrophic, 0.0 for neutral, 1.0 for boom)
-3. Any related entities mentioned (up to 3 other companies, nations, or commodities)
-4. The overall sentiment score (-1.0 to 1.0)
-
-Return ONLY a JSON object with this exact format:
-{"entity": "EntityName", "type": "Nation", "impact": -0.5, "reason": "Brief reason", "related_entities": ["Entity1", "Entity2"], "sentiment": 0.5}
-`, item.Title)
-
-	resp, err := e.Client.Complete(prompt)
+	var published string
+	if !item.Published.IsZero() {
+		published = item.Published.Format(time.RFC1123)
+	}
+	e.Hub.Broadcast(server.TypeNewsAlert, server.NewsAlertPayload{Title: item.Title, Link: item.Link, Published: published})
+
+	// Known nodes the headline may be about, so the LLM can use their names
+	var known string
+	if e.Index != nil {
+		known = e.Index.Context(ctx, item.Title, contextNodes)
+	}
+
+	impact, err := e.Extractor.Headline(ctx, item.Title, known)
 	if err != nil {
 		logger.ErrorDepth(2, logger.StatusErr, "LLM Error: %v", err)
+		syserr.Report(syserr.ModuleNews, "analyze headline", err)
 		return
 	}
-
-	var impact NewsImpact
-	cleaned := cleanJSON(resp)
-	if err := json.Unmarshal([]byte(cleaned), &impact); err != nil {
+	if impact.Source == nlp.SourceLocal {
+		if !errors.Is(impact.Err, llm.ErrBudgetExhausted) {
+			syserr.Report(syserr.ModuleNews, "analyze headline", impact.Err)
+		}
+		e.processWithoutLLM(impact, eventID)
+		e.observeCoMentions(item, "")
+		return
+	}
+	subject, ok := impact.Main()
+	if !ok {
 		return
 	}
-	
-	// 1. Trigger Social Crawler (Real)
-	go e.Social.CrawlReal(item.Title)
+	if !e.Graph.ClaimEvent(eventID) {
+		return // Applied concurrently while we waited for the LLM
+	}
+	baseline := e.Graph.ImpactBaseline()
 
-	id := cleanID(impact.EntityName)
+	nodeType := subject.Type
+	linkType := nodeType
+	if linkType == graph.NodeTypeProduct {
+		linkType = "" // Product is the catch-all; link to any type
+	}
+	id := e.resolve(ctx, subject.Name, linkType)
 	node, exists := e.Graph.GetNode(id)
 
 	if !exists {
-		logger.InfoDepth(2, logger.StatusNew, "New Entity Discovered in News: %s. Triggering Recursive Seeder...", impact.EntityName)
-		e.Hub.Broadcast("graph_update", fmt.Sprintf("New Node: %s", impact.EntityName))
-
-		var nodeType graph.NodeType
-		switch strings.ToLower(impact.EntityType) {
-		case "nation", "country":
-			nodeType = graph.NodeTypeNation
-		case "corporation", "company":
-			nodeType = graph.NodeTypeCorporation
-		case "rawmaterial", "commodity":
-			nodeType = graph.NodeTypeRawMaterial
-		default:
-			nodeType = graph.NodeTypeProduct
-		}
-		
-		newNode := &graph.Node{ID: id, Type: nodeType, Name: impact.EntityName}
+		logger.InfoDepth(2, logger.StatusNew, "New Entity Discovered in News: %s. Triggering Recursive Seeder...", subject.Name)
+		e.Hub.Broadcast(server.TypeGraphNotice, server.GraphNoticePayload{
+			NodeID:  id,
+			Name:    subject.Name,
+			Message: fmt.Sprintf("New Node: %s", subject.Name),
+		})
+
+		newNode := &graph.Node{ID: id, Type: nodeType, Name: subject.Name}
 		e.Graph.AddNode(newNode)
+		audit.Log(audit.Record{
+			Actor:    audit.ActorNews,
+			Action:   audit.ActionAddNode,
+			NodeID:   id,
+			Reason:   "entity first seen in news: " + impact.Reason,
+			Evidence: strings.TrimSpace(item.Title + " " + item.Link),
+		})
 
 		if nodeType == graph.NodeTypeNation {
-			go func(name string) {
+			name := subject.Name
+			task.StartTimeout("expand "+name, e.ExpansionTimeout, func(ctx context.Context, t *task.Task) error {
 				logger.InfoDepth(2, logger.StatusChk, "Expanding Knowledge Graph for new nation: %s...", name)
-				if err := e.Seeder.ProcessNation(e.Graph, name, 0); err != nil {
+				if err := e.Seeder.ProcessNation(ctx, e.Graph, name, 0); err != nil {
 					logger.WarnDepth(2, logger.StatusWarn, "Failed to expand nation %s: %v", name, err)
+					syserr.Report(syserr.ModuleDiscovery, "expand "+name, err)
+					return err
 				}
-			}(impact.EntityName)
+				return nil
+			})
 		}
 
 	} else {
 		logger.SuccessDepth(2, "Entity Found: %s", node.Name)
+		if e.EventWindow > 0 {
+			for _, ev := range e.Graph.UpcomingEvents(id, e.EventWindow) {
+				logger.InfoDepth(2, logger.StatusNews, "Ahead of %s (%s, %s impact)", ev.Title, clock.Format(ev.Time, "Jan 2 15:04"), ev.Impact)
+			}
+		}
 	}
 
-	if impact.ImpactScore != 0 {
+	e.observeCoMentions(item, id)
+
+	// Crawl social media for the entities the headline names
+	if pipeline.Enabled(pipeline.Social) {
+		for _, job := range social.HeadlineCrawls(e.Graph, impact, id) {
+			e.Social.Enqueue(job, false)
+		}
+	}
+
+	if impact.Impact != 0 {
 		evt := simulation.ShockEvent{
 			TargetNodeID: id,
 			Description:  fmt.Sprintf("News: %s (%s)", impact.Reason, item.Title),
-			ImpactFactor: 1.0 + impact.ImpactScore,
+			ImpactFactor: 1.0 + impact.Impact,
+			ID:           eventID + "_shock",
 		}
 		e.Simulator.RunShock(evt)
-		e.Hub.Broadcast("shock_event", evt)
+		e.Hub.Broadcast(server.TypeShockEvent, server.ShockPayload{
+			Kind:        server.ShockNode,
+			Target:      evt.TargetNodeID,
+			Impact:      evt.ImpactFactor,
+			Description: evt.Description,
+		})
+	}
+
+	// Record the reading for the stress index
+	sentiment := impact.Sentiment
+	if sentiment == 0 {
+		sentiment = impact.Impact
+	}
+	if sentiment != 0 {
+		e.Graph.RecordSentiment(id, graph.SentimentNews, sentiment)
 	}
 
 	// Update edge weights based on news sentiment
-	e.updateEdgeWeightsFromNews(id, impact, item.Title)
+	e.updateEdgeWeightsFromNews(ctx, id, impact, eventID)
+
+	// Rank what the headline changed, shock included
+	e.Graph.RecordImpact(graph.ImpactNews, eventID, item.Title, baseline)
+}
+
+// processWithoutLLM is the degraded analysis used when the LLM is down or
+// the news budget is spent: the local extraction's lexicon sentiment,
+// recorded for every known node the headline names. No shocks or new nodes
+// come out of it.
+func (e *Engine) processWithoutLLM(impact *nlp.Extraction, eventID string) {
+	if impact.Sentiment == 0 || !e.Graph.ClaimEvent(eventID) {
+		return
+	}
+	named := 0
+	for _, ent := range impact.Entities {
+		if ent.NodeID != "" {
+			e.Graph.RecordSentiment(ent.NodeID, graph.SentimentNews, impact.Sentiment)
+			named++
+		}
+	}
+	logger.InfoDepth(2, logger.StatusNews, "LLM unavailable (%v); lexicon sentiment %.2f for %d named nodes", impact.Err, impact.Sentiment, named)
+}
+
+// observeCoMentions passes the known nodes a headline names, starting with
+// its resolved main entity (when known), to the co-mention learner
+func (e *Engine) observeCoMentions(item Item, mainID string) {
+	if e.CoMentions == nil || e.Extractor == nil || e.Extractor.Local == nil {
+		return
+	}
+	ids := []string{mainID}
+	for _, ent := range e.Extractor.Local.Entities(item.Title) {
+		ids = append(ids, ent.NodeID)
+	}
+	e.CoMentions.Observe(ids, graph.CoMentionItem{
+		Time:   item.Published,
+		Source: graph.SentimentNews,
+		Text:   item.Title,
+		URL:    item.Link,
+	})
+}
+
+// resolve returns the ID of the node an entity name refers to: the exact
+// name match, else the closest described node of that type (nodeType "" =
+// any), else the ID a new node would get
+func (e *Engine) resolve(ctx context.Context, name string, nodeType graph.NodeType) string {
+	id := cleanID(name)
+	if _, exists := e.Graph.GetNode(id); exists || e.Index == nil {
+		return id
+	}
+	if node, score, ok := e.Index.Link(ctx, name, nodeType); ok {
+		logger.InfoDepth(2, logger.StatusOK, "Linked %q to %s (similarity %.2f)", name, node.Name, score)
+		return node.ID
+	}
+	return id
 }
 
 // updateEdgeWeightsFromNews updates weights of edges connected to the affected entity
-func (e *Engine) updateEdgeWeightsFromNews(entityID string, impact NewsImpact, newsTitle string) {
+// under the news item's event ID
+func (e *Engine) updateEdgeWeightsFromNews(ctx context.Context, entityID string, impact *nlp.Extraction, eventID string) {
 	// Get all outgoing edges from the entity
 	outgoingEdges := e.Graph.GetOutgoingEdges(entityID)
 
@@ -171,24 +427,25 @@ func (e *Engine) updateEdgeWeightsFromNews(entityID string, impact NewsImpact, n
 	relevanceScore := 0.8
 
 	// Use sentiment score if provided, otherwise derive from impact
-	sentimentScore := impact.SentimentScore
-	if sentimentScore == 0 && impact.ImpactScore != 0 {
-		sentimentScore = impact.ImpactScore
+	sentimentScore := impact.Sentiment
+	if sentimentScore == 0 && impact.Impact != 0 {
+		sentimentScore = impact.Impact
 	}
 
-	eventID := fmt.Sprintf("news_%d", time.Now().Unix())
-
 	// Update weights for all outgoing edges
 	for _, edge := range outgoingEdges {
-		err := e.Graph.UpdateEdgeWeight(
+		err := e.Graph.UpdateCommodityEdgeWeight(
 			edge.SourceID,
 			edge.TargetID,
 			edge.Type,
+			edge.Commodity(),
 			sentimentScore,
-			relevanceScore,
+			e.eventRelevance(edge, relevanceScore),
 			eventID,
 		)
-		if err != nil {
+		if errors.Is(err, graph.ErrEventApplied) {
+			continue
+		} else if err != nil {
 			logger.WarnDepth(2, logger.StatusWarn, "Failed to update edge weight: %v", err)
 		} else {
 			logger.SuccessDepth(2, "Updated edge %s->%s weight based on news", edge.SourceID, edge.TargetID)
@@ -196,8 +453,8 @@ func (e *Engine) updateEdgeWeightsFromNews(entityID string, impact NewsImpact, n
 	}
 
 	// Also update edges to related entities if they exist
-	for _, relatedEntity := range impact.RelatedEntities {
-		relatedID := cleanID(relatedEntity)
+	for _, related := range impact.Related() {
+		relatedID := e.resolve(ctx, related.Name, "")
 
 		// Check if this entity exists in the graph
 		if _, exists := e.Graph.GetNode(relatedID); !exists {
@@ -207,12 +464,13 @@ func (e *Engine) updateEdgeWeightsFromNews(entityID string, impact NewsImpact, n
 		// Try to find edges between the main entity and related entities
 		for _, edge := range e.Graph.GetOutgoingEdges(entityID) {
 			if edge.TargetID == relatedID {
-				err := e.Graph.UpdateEdgeWeight(
+				err := e.Graph.UpdateCommodityEdgeWeight(
 					edge.SourceID,
 					edge.TargetID,
 					edge.Type,
+					edge.Commodity(),
 					sentimentScore * 0.7, // Reduced impact for related entities
-					relevanceScore,
+					e.eventRelevance(edge, relevanceScore),
 					eventID,
 				)
 				if err == nil {
@@ -224,12 +482,13 @@ func (e *Engine) updateEdgeWeightsFromNews(entityID string, impact NewsImpact, n
 		// Also check reverse direction
 		for _, edge := range e.Graph.GetOutgoingEdges(relatedID) {
 			if edge.TargetID == entityID {
-				err := e.Graph.UpdateEdgeWeight(
+				err := e.Graph.UpdateCommodityEdgeWeight(
 					edge.SourceID,
 					edge.TargetID,
 					edge.Type,
+					edge.Commodity(),
 					sentimentScore * 0.7,
-					relevanceScore,
+					e.eventRelevance(edge, relevanceScore),
 					eventID,
 				)
 				if err == nil {
@@ -240,14 +499,6 @@ func (e *Engine) updateEdgeWeightsFromNews(entityID string, impact NewsImpact, n
 	}
 }
 
-func cleanJSON(s string) string {
-	s = strings.TrimSpace(s)
-	s = strings.TrimPrefix(s, "```json")
-	s = strings.TrimPrefix(s, "```")
-	s = strings.TrimSuffix(s, "```")
-	return strings.TrimSpace(s)
-}
-
 func cleanID(s string) string {
 	return strings.ToLower(strings.ReplaceAll(s, " ", "_"))
 }
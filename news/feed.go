@@ -1,46 +1,232 @@
 package news
 
 import (
-	"encoding/xml"
+	"context"
+	"fmt"
 	"io"
+	"margraf/config"
+	"margraf/logger"
+	"margraf/retry"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
-type RSSItem struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	Link        string `xml:"link"`
-	PubDate     string `xml:"pubDate"`
+// Item is one headline, whatever the feed format it came from
+type Item struct {
+	Title       string
+	Description string
+	Link        string
+	Published   time.Time // Zero when the feed gave no parseable date
+	Source      string    // Name of the FeedSource
 }
 
-type RSSChannel struct {
-	Items []RSSItem `xml:"item"`
+// FeedSource is a news feed the engine polls. Implementations translate
+// their format's quirks into Items; register new formats with RegisterFeedType.
+type FeedSource interface {
+	// Name identifies the source in logs and error reports
+	Name() string
+
+	// Fetch returns the feed's current items, newest first where the feed orders them
+	Fetch(ctx context.Context) ([]Item, error)
+}
+
+// FeedFactory builds a FeedSource from its config entry
+type FeedFactory func(cfg config.FeedConfig) (FeedSource, error)
+
+// DefaultFeedURL is polled when news.feeds and news.rss_url are both empty
+const DefaultFeedURL = "http://feeds.bbci.co.uk/news/business/rss.xml"
+
+var (
+	feedTypesMu sync.RWMutex
+	feedTypes   = make(map[string]FeedFactory)
+)
+
+func init() {
+	RegisterFeedType("rss", func(cfg config.FeedConfig) (FeedSource, error) {
+		return newURLSource(cfg, "rss", parseRSS)
+	})
+	RegisterFeedType("atom", func(cfg config.FeedConfig) (FeedSource, error) {
+		return newURLSource(cfg, "atom", parseAtom)
+	})
+	RegisterFeedType("jsonfeed", func(cfg config.FeedConfig) (FeedSource, error) {
+		return newURLSource(cfg, "jsonfeed", parseJSONFeed)
+	})
+	RegisterFeedType("newsapi", newNewsAPISource)
+}
+
+// RegisterFeedType makes a feed format available to news.feeds entries of
+// that type. It panics if the type is registered twice.
+func RegisterFeedType(kind string, factory FeedFactory) {
+	feedTypesMu.Lock()
+	defer feedTypesMu.Unlock()
+
+	if factory == nil {
+		panic("news: RegisterFeedType factory is nil")
+	}
+	if _, dup := feedTypes[kind]; dup {
+		panic("news: RegisterFeedType called twice for type " + kind)
+	}
+	feedTypes[kind] = factory
+}
+
+// FeedTypes returns the registered feed types sorted by name
+func FeedTypes() []string {
+	feedTypesMu.RLock()
+	defer feedTypesMu.RUnlock()
+
+	kinds := make([]string, 0, len(feedTypes))
+	for kind := range feedTypes {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewFeedSource builds the source a config entry describes
+func NewFeedSource(cfg config.FeedConfig) (FeedSource, error) {
+	kind := strings.ToLower(cfg.Type)
+	if kind == "" {
+		kind = "rss"
+	}
+	feedTypesMu.RLock()
+	factory, ok := feedTypes[kind]
+	feedTypesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown feed type %q (use %s)", cfg.Type, strings.Join(FeedTypes(), ", "))
+	}
+	return factory(cfg)
 }
 
-type RSSFeed struct {
-	Channel RSSChannel `xml:"channel"`
+// ConfiguredSources builds the sources listed under news.feeds, falling back
+// to news.rss_url and then DefaultFeedURL. Invalid entries are reported and
+// skipped.
+func ConfiguredSources() []FeedSource {
+	sources, _ := configuredFeeds()
+	return sources
 }
 
-func FetchRSS(url string) ([]RSSItem, error) {
+// sourceName is the configured name, else "<type> <host>"
+func sourceName(cfg config.FeedConfig, kind string) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	if u, err := url.Parse(cfg.URL); err == nil && u.Host != "" {
+		return kind + " " + u.Host
+	}
+	return kind
+}
+
+// fetchBody GETs a feed with the shared HTTP timeout and retry policy
+func fetchBody(ctx context.Context, feedURL string, header http.Header) ([]byte, error) {
 	client := http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: config.Timeout(config.Global.Timeouts.HTTP, 10*time.Second),
 	}
-	resp, err := client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	data, err := io.ReadAll(resp.Body)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := retry.DoRequest(&client, req, retry.HTTP)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
 
-	var feed RSSFeed
-	if err := xml.Unmarshal(data, &feed); err != nil {
-		return nil, err
-	}
+// feedTimeLayouts covers the date formats seen in the wild: RSS is meant to
+// use RFC 822 but feeds mix numeric zones, single-digit days, missing
+// weekdays or seconds, full month names and ISO dates
+var feedTimeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Mon, 2 January 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 MST",
+	"Mon, 2 Jan 06 15:04:05 -0700",
+	"Mon, 2 Jan 06 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC850,
+	time.UnixDate,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// zoneOffsets are the zone abbreviations feeds use, in seconds east of UTC.
+// time.Parse only knows the abbreviations of the local zone and reads any
+// other as UTC.
+var zoneOffsets = map[string]int{
+	"UT": 0, "UTC": 0, "GMT": 0, "Z": 0,
+	"EST": -5 * 3600, "EDT": -4 * 3600,
+	"CST": -6 * 3600, "CDT": -5 * 3600,
+	"MST": -7 * 3600, "MDT": -6 * 3600,
+	"PST": -8 * 3600, "PDT": -7 * 3600,
+	"AKST": -9 * 3600, "AKDT": -8 * 3600,
+	"HST": -10 * 3600,
+	"BST": 3600, "IST": 5*3600 + 1800,
+	"WET": 0, "WEST": 3600,
+	"CET": 3600, "CEST": 2 * 3600,
+	"EET": 2 * 3600, "EEST": 3 * 3600,
+	"MSK": 3 * 3600,
+	"SGT": 8 * 3600, "HKT": 8 * 3600,
+	"JST": 9 * 3600, "KST": 9 * 3600,
+	"AEST": 10 * 3600, "AEDT": 11 * 3600,
+	"NZST": 12 * 3600, "NZDT": 13 * 3600,
+}
+
+// unparsedDates holds the shapes of feed dates no layout fit, so each is
+// reported once
+var unparsedDates sync.Map
 
-	return feed.Channel.Items, nil
+// parseFeedTime parses a feed date into UTC, returning the zero time if no
+// layout fits
+func parseFeedTime(s string) time.Time {
+	s = strings.Join(strings.Fields(s), " ")
+	if i := strings.Index(s, " ("); i > 0 && strings.HasSuffix(s, ")") {
+		s = s[:i] // "+0000 (GMT)", "-0500 (Eastern Standard Time)"
+	}
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range feedTimeLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if name, offset := t.Zone(); offset == 0 {
+			if known, ok := zoneOffsets[strings.ToUpper(name)]; ok && known != 0 {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.FixedZone(name, known))
+			}
+		}
+		return t.UTC()
+	}
+	shape := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '9'
+		}
+		return r
+	}, s)
+	if _, seen := unparsedDates.LoadOrStore(shape, true); !seen {
+		logger.Warn(logger.StatusWarn, "Unrecognized feed date %q; items dated like it are treated as undated", s)
+	}
+	return time.Time{}
 }
@@ -0,0 +1,185 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"margraf/config"
+	"strings"
+)
+
+// RSS 2.0 wire format
+
+type RSSItem struct {
+	Title       string `xml:"title"`
+	Description string `xml:"description"`
+	Link        string `xml:"link"`
+	PubDate     string `xml:"pubDate"`
+}
+
+type RSSChannel struct {
+	Items []RSSItem `xml:"item"`
+}
+
+type RSSFeed struct {
+	Channel RSSChannel `xml:"channel"`
+}
+
+// Atom (RFC 4287) wire format
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type AtomEntry struct {
+	Title     string     `xml:"title"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Links     []AtomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+type AtomFeed struct {
+	Entries []AtomEntry `xml:"entry"`
+}
+
+// JSON Feed 1.1 (jsonfeed.org) wire format
+
+type JSONFeedItem struct {
+	Title         string `json:"title"`
+	Summary       string `json:"summary"`
+	ContentText   string `json:"content_text"`
+	URL           string `json:"url"`
+	DatePublished string `json:"date_published"`
+	DateModified  string `json:"date_modified"`
+}
+
+type JSONFeed struct {
+	Version string         `json:"version"`
+	Items   []JSONFeedItem `json:"items"`
+}
+
+// feedParser turns a fetched document into items
+type feedParser func(data []byte) ([]Item, error)
+
+// urlSource fetches a feed document from a URL and parses it
+type urlSource struct {
+	name  string
+	url   string
+	parse feedParser
+}
+
+func newURLSource(cfg config.FeedConfig, kind string, parse feedParser) (FeedSource, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("%s feed needs a url", kind)
+	}
+	return &urlSource{name: sourceName(cfg, kind), url: cfg.URL, parse: parse}, nil
+}
+
+// NewRSSSource returns a source for an RSS 2.0 feed
+func NewRSSSource(url string) FeedSource {
+	return &urlSource{name: sourceName(config.FeedConfig{URL: url}, "rss"), url: url, parse: parseRSS}
+}
+
+func (s *urlSource) Name() string { return s.name }
+
+func (s *urlSource) Fetch(ctx context.Context) ([]Item, error) {
+	data, err := fetchBody(ctx, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.parse(data)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Source = s.name
+	}
+	return items, nil
+}
+
+func parseRSS(data []byte) ([]Item, error) {
+	var feed RSSFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(feed.Channel.Items))
+	for _, it := range feed.Channel.Items {
+		items = append(items, Item{
+			Title:       strings.TrimSpace(it.Title),
+			Description: strings.TrimSpace(it.Description),
+			Link:        strings.TrimSpace(it.Link),
+			Published:   parseFeedTime(it.PubDate),
+		})
+	}
+	return items, nil
+}
+
+func parseAtom(data []byte) ([]Item, error) {
+	var feed AtomFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(feed.Entries))
+	for _, e := range feed.Entries {
+		published := e.Published
+		if published == "" {
+			published = e.Updated
+		}
+		summary := e.Summary
+		if summary == "" {
+			summary = e.Content
+		}
+		items = append(items, Item{
+			Title:       strings.TrimSpace(e.Title),
+			Description: strings.TrimSpace(summary),
+			Link:        atomLink(e.Links),
+			Published:   parseFeedTime(published),
+		})
+	}
+	return items, nil
+}
+
+// atomLink picks the entry's alternate (article) link; rel defaults to alternate
+func atomLink(links []AtomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+func parseJSONFeed(data []byte) ([]Item, error) {
+	var feed JSONFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("not a JSON Feed (version %q)", feed.Version)
+	}
+	items := make([]Item, 0, len(feed.Items))
+	for _, it := range feed.Items {
+		published := it.DatePublished
+		if published == "" {
+			published = it.DateModified
+		}
+		summary := it.Summary
+		if summary == "" {
+			summary = it.ContentText
+		}
+		items = append(items, Item{
+			Title:       strings.TrimSpace(it.Title),
+			Description: strings.TrimSpace(summary),
+			Link:        it.URL,
+			Published:   parseFeedTime(published),
+		})
+	}
+	return items, nil
+}
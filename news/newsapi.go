@@ -0,0 +1,110 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"margraf/config"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// newsAPIEndpoint is the NewsAPI (newsapi.org) v2 base URL
+const newsAPIEndpoint = "https://newsapi.org/v2"
+
+// newsAPISource polls NewsAPI's top headlines, or its article search when a
+// query is configured
+type newsAPISource struct {
+	name     string
+	endpoint string
+	apiKey   string
+	query    string
+	category string
+	language string
+}
+
+type newsAPIResponse struct {
+	Status   string `json:"status"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Articles []struct {
+		Source struct {
+			Name string `json:"name"`
+		} `json:"source"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		URL         string `json:"url"`
+		PublishedAt string `json:"publishedAt"`
+	} `json:"articles"`
+}
+
+func newNewsAPISource(cfg config.FeedConfig) (FeedSource, error) {
+	key := cfg.APIKey
+	if key == "" {
+		key = os.Getenv("NEWSAPI_KEY")
+	}
+	if key == "" {
+		return nil, fmt.Errorf("newsapi feed needs api_key or NEWSAPI_KEY")
+	}
+	s := &newsAPISource{
+		name:     cfg.Name,
+		endpoint: cfg.URL,
+		apiKey:   key,
+		query:    cfg.Query,
+		category: cfg.Category,
+		language: cfg.Language,
+	}
+	if s.name == "" {
+		s.name = "newsapi"
+	}
+	if s.endpoint == "" {
+		s.endpoint = newsAPIEndpoint
+	}
+	if s.category == "" {
+		s.category = "business"
+	}
+	if s.language == "" {
+		s.language = "en"
+	}
+	return s, nil
+}
+
+func (s *newsAPISource) Name() string { return s.name }
+
+func (s *newsAPISource) Fetch(ctx context.Context) ([]Item, error) {
+	params := url.Values{"language": {s.language}}
+	path := "/top-headlines"
+	if s.query != "" {
+		path = "/everything"
+		params.Set("q", s.query)
+		params.Set("sortBy", "publishedAt")
+	} else {
+		params.Set("category", s.category)
+	}
+
+	// The key goes in a header so it stays out of logged and recorded URLs
+	data, err := fetchBody(ctx, s.endpoint+path+"?"+params.Encode(), http.Header{"X-Api-Key": {s.apiKey}})
+	if err != nil {
+		return nil, err
+	}
+	var resp newsAPIResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "ok" {
+		return nil, fmt.Errorf("newsapi: %s: %s", resp.Code, resp.Message)
+	}
+
+	items := make([]Item, 0, len(resp.Articles))
+	for _, a := range resp.Articles {
+		items = append(items, Item{
+			Title:       a.Title,
+			Description: a.Description,
+			Link:        a.URL,
+			Published:   parseFeedTime(a.PublishedAt),
+			Source:      s.name,
+		})
+	}
+	return items, nil
+}
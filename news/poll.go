@@ -0,0 +1,269 @@
+package news
+
+import (
+	"fmt"
+	"margraf/clock"
+	"margraf/config"
+	"margraf/logger"
+	"margraf/syserr"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuietHours is a daily window of the display timezone's time (see clock) in
+// which a feed is not polled.
+// A window whose end is before its start runs past midnight.
+type QuietHours struct {
+	start, end int // Minutes from midnight; equal = no window
+}
+
+// ParseQuietHours reads "22-06" or "22:30-06:15". An empty string or "off"
+// is no window.
+func ParseQuietHours(s string) (QuietHours, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "off") {
+		return QuietHours{}, nil
+	}
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return QuietHours{}, fmt.Errorf("quiet hours %q: want start-end, e.g. 22-06", s)
+	}
+	var q QuietHours
+	var err error
+	if q.start, err = parseClock(from); err != nil {
+		return QuietHours{}, fmt.Errorf("quiet hours %q: %w", s, err)
+	}
+	if q.end, err = parseClock(to); err != nil {
+		return QuietHours{}, fmt.Errorf("quiet hours %q: %w", s, err)
+	}
+	return q, nil
+}
+
+// parseClock reads "6", "06" or "06:15" as minutes from midnight
+func parseClock(s string) (int, error) {
+	h, m, hasMin := strings.Cut(strings.TrimSpace(s), ":")
+	hour, err := strconv.Atoi(h)
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("invalid hour %q", h)
+	}
+	min := 0
+	if hasMin {
+		if min, err = strconv.Atoi(m); err != nil || min < 0 || min > 59 || hour == 24 && min > 0 {
+			return 0, fmt.Errorf("invalid minute %q", m)
+		}
+	}
+	return (hour*60 + min) % (24 * 60), nil
+}
+
+// IsZero reports whether there is no window
+func (q QuietHours) IsZero() bool {
+	return q.start == q.end
+}
+
+// Contains reports whether t falls in the window
+func (q QuietHours) Contains(t time.Time) bool {
+	if q.IsZero() {
+		return false
+	}
+	t = clock.Local(t)
+	m := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
+
+// End returns the end of the window t falls in, or t itself outside it
+func (q QuietHours) End(t time.Time) time.Time {
+	if !q.Contains(t) {
+		return t
+	}
+	t = clock.Local(t)
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.end/60, q.end%60, 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+func (q QuietHours) String() string {
+	if q.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.start/60, q.start%60, q.end/60, q.end%60)
+}
+
+// feedState is one feed's polling schedule and latest outcome
+type feedState struct {
+	interval  time.Duration // 0 = the interval given to Monitor
+	quiet     QuietHours
+	next      time.Time
+	lastPoll  time.Time
+	lastCheck time.Time // Start of the last successful fetch
+	lastErr   string
+	items     int
+	url       string // As configured, to add each URL once
+	country   string // ISO code of the nation the feed was added for (see countries.go)
+}
+
+// FeedStatus is a snapshot of one feed's polling, safe to serialize
+type FeedStatus struct {
+	Name       string        `json:"name"`
+	Interval   time.Duration `json:"interval"`
+	QuietHours string        `json:"quiet_hours,omitempty"`
+	Quiet      bool          `json:"quiet"`              // In its quiet hours now
+	LastPoll   time.Time     `json:"last_poll,omitzero"` // Zero until polled
+	NextPoll   time.Time     `json:"next_poll,omitzero"` // Zero until Monitor runs
+	Items      int           `json:"items"`              // Headlines analyzed by the last poll
+	Country    string        `json:"country,omitempty"`  // Set on feeds added for a Nation node
+	LastError  string        `json:"last_error,omitempty"`
+}
+
+// configuredFeeds builds the sources listed under news.feeds (see
+// ConfiguredSources) with each one's polling schedule, keyed by name
+func configuredFeeds() ([]FeedSource, map[string]*feedState) {
+	feeds := config.Global.News.Feeds
+	if len(feeds) == 0 {
+		feedURL := config.Global.News.RSSUrl
+		if feedURL == "" {
+			feedURL = DefaultFeedURL
+		}
+		feeds = []config.FeedConfig{{Type: "rss", URL: feedURL}}
+	}
+	quiet, err := ParseQuietHours(config.Global.News.QuietHours)
+	if err != nil {
+		logger.Warn(logger.StatusWarn, "Ignoring news.quiet_hours: %v", err)
+		syserr.Report(syserr.ModuleNews, "configure quiet hours", err)
+	}
+
+	sources := make([]FeedSource, 0, len(feeds))
+	states := make(map[string]*feedState, len(feeds))
+	for i, cfg := range feeds {
+		src, err := NewFeedSource(cfg)
+		if err != nil {
+			logger.Warn(logger.StatusWarn, "Skipping news feed %d: %v", i+1, err)
+			syserr.Report(syserr.ModuleNews, "configure feed", err)
+			continue
+		}
+		st := &feedState{interval: time.Duration(cfg.PollInterval) * time.Second, quiet: quiet, url: cfg.URL}
+		if cfg.QuietHours != "" {
+			if st.quiet, err = ParseQuietHours(cfg.QuietHours); err != nil {
+				logger.Warn(logger.StatusWarn, "News feed %s: ignoring quiet_hours: %v", src.Name(), err)
+				syserr.Report(syserr.ModuleNews, "configure quiet hours", err)
+				st.quiet = quiet
+			}
+		}
+		sources = append(sources, src)
+		states[src.Name()] = st
+	}
+	return sources, states
+}
+
+// feed returns name's state, creating a default one for sources set
+// directly on the engine. feedsMu must be held.
+func (e *Engine) feed(name string) *feedState {
+	st, ok := e.feeds[name]
+	if !ok {
+		if e.feeds == nil {
+			e.feeds = make(map[string]*feedState)
+		}
+		st = &feedState{quiet: e.quiet}
+		e.feeds[name] = st
+	}
+	return st
+}
+
+// feedInterval is st's interval, else the engine's. feedsMu must be held.
+func (e *Engine) feedInterval(st *feedState) time.Duration {
+	if st.interval > 0 {
+		return st.interval
+	}
+	return e.interval
+}
+
+// dueSources returns the sources due at now and schedules their next poll.
+// A source due in its quiet hours is pushed to their end instead.
+func (e *Engine) dueSources(now time.Time) []FeedSource {
+	e.feedsMu.Lock()
+	defer e.feedsMu.Unlock()
+
+	var due []FeedSource
+	for _, src := range e.Sources {
+		st := e.feed(src.Name())
+		if st.next.After(now) {
+			continue
+		}
+		if st.quiet.Contains(now) {
+			st.next = st.quiet.End(now)
+			continue
+		}
+		st.next = st.quiet.End(now.Add(e.feedInterval(st)))
+		due = append(due, src)
+	}
+	return due
+}
+
+// nextPoll is the earliest scheduled poll of any source
+func (e *Engine) nextPoll() time.Time {
+	e.feedsMu.Lock()
+	defer e.feedsMu.Unlock()
+
+	next := time.Now().Add(e.interval)
+	for _, src := range e.Sources {
+		if st := e.feed(src.Name()); st.next.Before(next) {
+			next = st.next
+		}
+	}
+	return next
+}
+
+// feedSince is the cutoff for name's headlines: its last successful fetch,
+// else LastCheck
+func (e *Engine) feedSince(name string) time.Time {
+	e.feedsMu.Lock()
+	defer e.feedsMu.Unlock()
+
+	if st := e.feed(name); !st.lastCheck.IsZero() {
+		return st.lastCheck
+	}
+	return e.LastCheck
+}
+
+// recordPoll records the outcome of fetching name, started at started
+func (e *Engine) recordPoll(name string, started time.Time, items int, err error) {
+	e.feedsMu.Lock()
+	defer e.feedsMu.Unlock()
+
+	st := e.feed(name)
+	st.lastPoll, st.items, st.lastErr = started, items, ""
+	if err != nil {
+		st.lastErr = err.Error()
+		return
+	}
+	st.lastCheck = started
+}
+
+// Status returns every source's polling schedule in polling order
+func (e *Engine) Status() []FeedStatus {
+	e.feedsMu.Lock()
+	defer e.feedsMu.Unlock()
+
+	now := time.Now()
+	out := make([]FeedStatus, 0, len(e.Sources))
+	for _, src := range e.Sources {
+		st := e.feed(src.Name())
+		out = append(out, FeedStatus{
+			Name:       src.Name(),
+			Interval:   e.feedInterval(st),
+			QuietHours: st.quiet.String(),
+			Quiet:      st.quiet.Contains(now),
+			LastPoll:   st.lastPoll,
+			NextPoll:   st.next,
+			Items:      st.items,
+			Country:    st.country,
+			LastError:  st.lastErr,
+		})
+	}
+	return out
+}
@@ -0,0 +1,282 @@
+// Package nlp turns headlines and social posts into typed extractions:
+// entities with node types, sentiment, relations and confidence. The LLM
+// does the work when it can; when it is down or out of budget, a local
+// named-entity recognizer and the sentiment lexicon take over, so the news
+// and social pipelines share one prompt, one parser and one fallback.
+package nlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margraf/graph"
+	"margraf/llm"
+	"strings"
+)
+
+// Where an extraction came from
+const (
+	SourceLLM   = "llm"
+	SourceLocal = "local" // Local NER and lexicon sentiment: the LLM was unavailable
+)
+
+// Confidence given when the LLM leaves it out, and to local extractions
+const (
+	defaultConfidence = 0.5
+	localConfidence   = 0.3
+)
+
+// ErrInvalidReply is returned (wrapped) when the LLM's reply cannot be parsed
+var ErrInvalidReply = errors.New("unparseable extraction")
+
+// Entity is a named thing an extraction found
+type Entity struct {
+	Name       string         `json:"name"`
+	Type       graph.NodeType `json:"type"`              // Product when unclear
+	NodeID     string         `json:"node_id,omitempty"` // Set when the entity is a known node
+	Confidence float64        `json:"confidence"`
+}
+
+// Relation is a link between two entities a text asserts
+type Relation struct {
+	Source     string         `json:"source"`
+	Target     string         `json:"target"`
+	Type       graph.EdgeType `json:"type"`
+	Confidence float64        `json:"confidence"`
+}
+
+// Extraction is what a text says: its entities, main entity first, the
+// relations between them, and how it feels about them
+type Extraction struct {
+	Entities   []Entity   `json:"entities"`
+	Relations  []Relation `json:"relations,omitempty"`
+	Sentiment  float64    `json:"sentiment"`        // -1.0 to 1.0
+	Impact     float64    `json:"impact,omitempty"` // Economic impact on the main entity, -1.0 to 1.0 (headlines)
+	Reason     string     `json:"reason,omitempty"`
+	Confidence float64    `json:"confidence"` // 0 to 1
+	Source     string     `json:"source"`     // SourceLLM or SourceLocal
+	Err        error      `json:"-"`          // Why the LLM was not used (SourceLocal only)
+}
+
+// Main returns the entity the text is mainly about
+func (x *Extraction) Main() (Entity, bool) {
+	if len(x.Entities) == 0 {
+		return Entity{}, false
+	}
+	return x.Entities[0], true
+}
+
+// Related returns the entities other than the main one
+func (x *Extraction) Related() []Entity {
+	if len(x.Entities) < 2 {
+		return nil
+	}
+	return x.Entities[1:]
+}
+
+// NER finds entities in text without an LLM
+type NER interface {
+	Entities(text string) []Entity
+}
+
+// Extractor extracts entities and sentiment from text. Tag ctx with the
+// consumer (llm.WithConsumer) so calls count against the right budget.
+type Extractor struct {
+	Client *llm.Client
+	Local  NER // Used when the LLM fails (nil = sentiment only)
+}
+
+// NewExtractor uses c, falling back to a gazetteer of g's node names
+func NewExtractor(c *llm.Client, g *graph.Graph) *Extractor {
+	return &Extractor{Client: c, Local: NewGazetteer(g)}
+}
+
+// Headline extracts the main entity of a news headline, its economic impact,
+// related entities and relations. known lists graph nodes the headline may
+// be about ("- Name (Type): description" lines), so the LLM uses their names.
+func (x *Extractor) Headline(ctx context.Context, headline, known string) (*Extraction, error) {
+	if known != "" {
+		known = "\nEntities already in the knowledge graph that may be relevant (use these exact names when the headline refers to them):\n" + known + "\n"
+	}
+	prompt := fmt.Sprintf(`
+Analyze this financial news headline: "%s"
+%sIdentify:
+1. The MAIN entity involved (Nation, Corporation, or RawMaterial)
+2. The economic impact score (-1.0 for catastrophic, 0.0 for neutral, 1.0 for boom)
+3. Any related entities mentioned (up to 3 other companies, nations, or commodities)
+4. The overall sentiment score (-1.0 to 1.0)
+5. Any supply-chain relations the headline states between entities (Supplies, DependsOn, CompetesWith, Owns, Produces, Consumes, Manufactures, Trade)
+6. Your confidence in this analysis (0.0 to 1.0)
+
+Return ONLY a JSON object with this exact format:
+{"entity": "EntityName", "type": "Nation", "impact": -0.5, "reason": "Brief reason", "related_entities": ["Entity1", "Entity2"], "sentiment": 0.5, "relations": [{"source": "Entity1", "target": "EntityName", "type": "Supplies"}], "confidence": 0.8}
+`, headline, known)
+	return x.extract(ctx, prompt, headline)
+}
+
+// Post extracts the sentiment of a social media post about topic, and the
+// entities it names
+func (x *Extractor) Post(ctx context.Context, topic, platform, content string) (*Extraction, error) {
+	// Limit content length for the LLM
+	quoted := content
+	if len(quoted) > 500 {
+		quoted = quoted[:500] + "..."
+	}
+	prompt := fmt.Sprintf(`
+Analyze the sentiment of this social media post about "%s".
+Platform: %s
+Content: "%s"
+
+Rate the sentiment from -1.0 (very negative) to 1.0 (very positive), list the companies, nations or commodities it names, and give your confidence (0.0 to 1.0).
+Return ONLY a JSON object: {"sentiment": 0.5, "related_entities": ["Entity1"], "confidence": 0.8}
+`, topic, platform, quoted)
+	return x.extract(ctx, prompt, content)
+}
+
+// extract asks the LLM, falling back to local extraction of text when the
+// LLM is unavailable. A reply that cannot be parsed is an error, not a
+// reason to fall back.
+func (x *Extractor) extract(ctx context.Context, prompt, text string) (*Extraction, error) {
+	if x.Client == nil {
+		return x.local(text, llm.ErrNoAPIKey), nil
+	}
+	resp, err := x.Client.Complete(ctx, prompt)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return x.local(text, err), nil
+	}
+	return parse(resp)
+}
+
+// local extracts without the LLM, which failed with cause
+func (x *Extractor) local(text string, cause error) *Extraction {
+	out := &Extraction{
+		Sentiment:  llm.LexiconSentiment(text),
+		Confidence: localConfidence,
+		Source:     SourceLocal,
+		Err:        cause,
+	}
+	if x.Local != nil {
+		out.Entities = x.Local.Entities(text)
+	}
+	return out
+}
+
+// reply is the JSON the prompts ask for
+type reply struct {
+	Entity          string          `json:"entity"`
+	Type            string          `json:"type"`
+	Impact          float64         `json:"impact"`
+	Reason          string          `json:"reason"`
+	RelatedEntities []replyEntity   `json:"related_entities"`
+	Sentiment       float64         `json:"sentiment"`
+	Relations       []replyRelation `json:"relations"`
+	Confidence      float64         `json:"confidence"`
+}
+
+// replyEntity is a related entity, given as a name or {"name", "type"}
+type replyEntity struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+func (e *replyEntity) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &e.Name); err == nil {
+		return nil
+	}
+	type plain replyEntity
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+type replyRelation struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// relationTypes are the edge types a relation may name
+var relationTypes = map[string]graph.EdgeType{}
+
+func init() {
+	for _, t := range []graph.EdgeType{
+		graph.EdgeTypeSupplies, graph.EdgeTypeDependsOn, graph.EdgeTypeCompetesWith, graph.EdgeTypeOwns,
+		graph.EdgeTypeProduces, graph.EdgeTypeConsumes, graph.EdgeTypeManufactures, graph.EdgeTypeTrade,
+	} {
+		relationTypes[strings.ToLower(string(t))] = t
+	}
+}
+
+// parse validates an LLM reply into an extraction: scores are clamped,
+// nameless and repeated entities dropped, and relations of unknown types or
+// without both ends dropped
+func parse(resp string) (*Extraction, error) {
+	var r reply
+	if err := json.Unmarshal([]byte(llm.CleanJSON(resp)), &r); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReply, err)
+	}
+
+	confidence := r.Confidence
+	if confidence <= 0 || confidence > 1 {
+		confidence = defaultConfidence
+	}
+	out := &Extraction{
+		Sentiment:  clamp(r.Sentiment),
+		Impact:     clamp(r.Impact),
+		Reason:     strings.TrimSpace(r.Reason),
+		Confidence: confidence,
+		Source:     SourceLLM,
+	}
+
+	seen := make(map[string]bool)
+	add := func(name, entityType string) {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		out.Entities = append(out.Entities, Entity{Name: name, Type: ParseEntityType(entityType), Confidence: confidence})
+	}
+	add(r.Entity, r.Type)
+	for _, e := range r.RelatedEntities {
+		add(e.Name, e.Type)
+	}
+
+	for _, rel := range r.Relations {
+		t, ok := relationTypes[strings.ToLower(strings.TrimSpace(rel.Type))]
+		source, target := strings.TrimSpace(rel.Source), strings.TrimSpace(rel.Target)
+		if !ok || source == "" || target == "" || strings.EqualFold(source, target) {
+			continue
+		}
+		out.Relations = append(out.Relations, Relation{Source: source, Target: target, Type: t, Confidence: confidence})
+	}
+	return out, nil
+}
+
+// ParseEntityType maps an entity type name to a node type; anything
+// unrecognized is a Product, the catch-all
+func ParseEntityType(entityType string) graph.NodeType {
+	switch strings.ToLower(strings.TrimSpace(entityType)) {
+	case "nation", "country":
+		return graph.NodeTypeNation
+	case "corporation", "company":
+		return graph.NodeTypeCorporation
+	case "rawmaterial", "raw material", "commodity":
+		return graph.NodeTypeRawMaterial
+	default:
+		return graph.NodeTypeProduct
+	}
+}
+
+func clamp(v float64) float64 {
+	if v < -1 {
+		return -1
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
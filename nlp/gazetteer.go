@@ -0,0 +1,60 @@
+package nlp
+
+import (
+	"margraf/graph"
+	"sort"
+	"strings"
+)
+
+// minGazetteerName is the shortest node name the gazetteer looks for, so
+// short names don't match inside unrelated words
+const minGazetteerName = 4
+
+// Gazetteer is the default local NER: it finds the graph's node names, and
+// tickers written as "$TICKER", in text. It only knows nodes already in the
+// graph, so it never discovers new ones.
+type Gazetteer struct {
+	Graph *graph.Graph
+}
+
+func NewGazetteer(g *graph.Graph) *Gazetteer {
+	return &Gazetteer{Graph: g}
+}
+
+// Entities returns the nodes text names, longest name first, so "Taiwan
+// Semiconductor" comes before "Taiwan" when both appear
+func (z *Gazetteer) Entities(text string) []Entity {
+	padded := " " + strings.ToLower(strings.Map(wordRune, text)) + " "
+	upper := strings.ToUpper(text)
+
+	var found []Entity
+	z.Graph.NodesRange(func(n *graph.Node) {
+		name := strings.ToLower(strings.Map(wordRune, n.Name))
+		named := len(name) >= minGazetteerName && strings.Contains(padded, " "+name+" ")
+		if !named && n.Ticker != "" {
+			named = strings.Contains(upper, "$"+strings.ToUpper(n.Ticker))
+		}
+		if named {
+			found = append(found, Entity{Name: n.Name, Type: n.Type, NodeID: n.ID, Confidence: localConfidence})
+		}
+	})
+	sort.SliceStable(found, func(i, j int) bool {
+		if len(found[i].Name) != len(found[j].Name) {
+			return len(found[i].Name) > len(found[j].Name)
+		}
+		return found[i].NodeID < found[j].NodeID
+	})
+	return found
+}
+
+// wordRune keeps letters and digits, turning punctuation into spaces so
+// "Apple's" and "(Apple)" still match
+func wordRune(r rune) rune {
+	if r == '\'' || r == '’' {
+		return ' '
+	}
+	if r < 128 && !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+		return ' '
+	}
+	return r
+}
@@ -0,0 +1,117 @@
+// Package pipeline holds the on/off switches for Margraf's background engines.
+// Engines keep their goroutines running and skip work while disabled, so a
+// pipeline can be stopped and started again at runtime.
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Names of the built-in pipelines
+const (
+	News      = "news"      // RSS polling and news-driven shocks
+	Social    = "social"    // Social crawls triggered by news
+	Market    = "market"    // Stock price polling and fundamentals
+	Refresh   = "refresh"   // Periodic World Bank / Comtrade refresh
+	Expansion = "expansion" // Periodic relationship discovery and nation expansion
+	Decay     = "decay"     // Temporal decay of edge weights
+	Normalize = "normalize" // Per-edge-type weight normalization
+	Stress    = "stress"    // Stress index recomputation
+	Anomalies = "anomalies" // Edge weight anomaly detection
+	Portfolio = "portfolio" // Portfolio exposure recomputation
+	Paper     = "paper"     // Paper trading on live prices
+	Pairs     = "pairs"     // Z-score alerts on graph-connected pairs
+	Calendar  = "calendar"  // Earnings and economic calendar refresh
+)
+
+// Descriptions of the built-in pipelines, shown by Status
+var descriptions = map[string]string{
+	News:      "RSS polling and news-driven shocks (LLM)",
+	Social:    "Social crawls triggered by news (LLM)",
+	Market:    "Stock prices and fundamentals",
+	Refresh:   "World Bank / Comtrade refresh",
+	Expansion: "Relationship discovery and nation expansion (LLM)",
+	Decay:     "Temporal decay of edge weights",
+	Normalize: "Per-edge-type weight normalization",
+	Stress:    "Stress index recomputation",
+	Anomalies: "Edge weight anomaly detection",
+	Portfolio: "Portfolio exposure recomputation",
+	Paper:     "Paper trading on live prices",
+	Pairs:     "Z-score alerts on graph-connected pairs",
+	Calendar:  "Earnings / economic calendar refresh",
+}
+
+// Status describes one pipeline
+type Status struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+var (
+	mu       sync.RWMutex
+	disabled = make(map[string]bool) // Pipelines are enabled unless listed here
+)
+
+// Names returns the built-in pipeline names, sorted
+func Names() []string {
+	names := make([]string, 0, len(descriptions))
+	for name := range descriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled reports whether a pipeline should do work. Unknown names are enabled.
+func Enabled(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return !disabled[name]
+}
+
+// Set enables or disables a pipeline
+func Set(name string, enabled bool) error {
+	name = strings.ToLower(name)
+	if _, ok := descriptions[name]; !ok {
+		return fmt.Errorf("unknown pipeline %q (pipelines: %s)", name, strings.Join(Names(), ", "))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if enabled {
+		delete(disabled, name)
+	} else {
+		disabled[name] = true
+	}
+	return nil
+}
+
+// Configure applies config flags (name -> enabled). Missing pipelines keep their state.
+func Configure(flags map[string]bool) error {
+	for name, enabled := range flags {
+		if err := Set(name, enabled); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// All returns the status of every pipeline, sorted by name
+func All() []Status {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	statuses := make([]Status, 0, len(descriptions))
+	for _, name := range Names() {
+		statuses = append(statuses, Status{
+			Name:        name,
+			Description: descriptions[name],
+			Enabled:     !disabled[name],
+		})
+	}
+	return statuses
+}
@@ -0,0 +1,8 @@
+// Package public embeds the built-in web dashboard so the binary
+// serves it regardless of working directory.
+package public
+
+import "embed"
+
+//go:embed index.html
+var Assets embed.FS
@@ -0,0 +1,48 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"margraf/llm"
+	"strings"
+)
+
+// askContext is how many relevant nodes an answer's prompt includes
+const askContext = 8
+
+// Ask answers a question about the graph, streaming the answer to onChunk as
+// the LLM writes it. The prompt includes the nodes most relevant to the
+// question. Out of LLM budget, the answer lists those nodes instead.
+func (ix *Index) Ask(ctx context.Context, question string, onChunk func(string)) (string, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return "", fmt.Errorf("empty question")
+	}
+	known := ix.Context(ctx, question, askContext)
+
+	prompt := fmt.Sprintf(`
+You are answering questions about a supply-chain knowledge graph of nations, companies, raw materials and products.
+Relevant nodes from the graph:
+%s
+
+Question: %s
+
+Answer in a few short paragraphs. Base the answer on the nodes above where they apply, and say so when the graph does not cover the question.
+`, orNone(known), question)
+
+	answer, err := ix.Client.Stream(llm.WithConsumer(ctx, llm.ConsumerQueries), prompt, onChunk)
+	if errors.Is(err, llm.ErrBudgetExhausted) {
+		answer = "Out of LLM budget for today. Most relevant nodes:\n" + orNone(known)
+		onChunk(answer)
+		return answer, nil
+	}
+	return answer, err
+}
+
+func orNone(lines string) string {
+	if lines == "" {
+		return "(none indexed; run 'describe')"
+	}
+	return lines
+}
@@ -0,0 +1,310 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"margraf/graph"
+	"margraf/llm"
+	"margraf/logger"
+	"margraf/task"
+	"sort"
+	"strings"
+)
+
+// Index tuning
+const (
+	DefaultLinkThreshold = 0.75 // Similarity Link needs to treat a name as an existing node
+	linkMargin           = 0.05 // Lead Link's best match needs over the runner-up
+	embedEvery           = 32   // Descriptions embedded per batch
+	maxNeighbours        = 5    // Linked nodes named in a description prompt
+	maxDescribeFailures  = 5    // Consecutive LLM failures before Describe gives up
+)
+
+// AttrDescription is the node attribute holding the generated description
+const AttrDescription = "description"
+
+// Index describes graph nodes, embeds the descriptions into a Store and
+// answers similarity queries against the graph
+type Index struct {
+	Graph         *graph.Graph
+	Client        *llm.Client
+	Store         *Store
+	Path          string  // Where Describe saves the store ("" = not saved)
+	LinkThreshold float64 // Minimum similarity for Link (0 = DefaultLinkThreshold)
+}
+
+// NewIndex loads the store at path (missing = empty) for g
+func NewIndex(g *graph.Graph, c *llm.Client, path string) (*Index, error) {
+	store, err := LoadStore(path)
+	if err != nil {
+		return nil, fmt.Errorf("load vector store %s: %w", path, err)
+	}
+	return &Index{Graph: g, Client: c, Store: store, Path: path}, nil
+}
+
+// Pending returns the nodes without an entry for the current embedding
+// model, sorted by ID
+func (ix *Index) Pending() []*graph.Node {
+	model := ix.Client.EmbeddingModel()
+	var pending []*graph.Node
+	ix.Graph.NodesRange(func(n *graph.Node) {
+		if e, ok := ix.Store.Get(n.ID); !ok || e.Model != model {
+			pending = append(pending, n)
+		}
+	})
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+	return pending
+}
+
+// Describe writes a short description for up to limit pending nodes (0 = all),
+// embeds it and saves the store. Nodes that already have a description (for
+// example after the embedding model changed) are only re-embedded. Without an
+// API key descriptions are built from the node's attributes and links. It
+// returns the number of nodes indexed.
+func (ix *Index) Describe(ctx context.Context, limit int) (int, error) {
+	pending := ix.Pending()
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+	model := ix.Client.EmbeddingModel()
+
+	var batch []Entry
+	indexed, failures := 0, 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		// Descriptions first, then names, in one request
+		texts := make([]string, 2*len(batch))
+		for i, e := range batch {
+			texts[i] = ix.document(e.NodeID, e.Description)
+			texts[len(batch)+i] = ix.name(e.NodeID)
+		}
+		vectors, err := ix.Client.Embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		for i, e := range batch {
+			e.Model, e.Vector, e.NameVector = model, vectors[i], vectors[len(batch)+i]
+			ix.Store.Put(e)
+			ix.Graph.UpdateNodeAttributes(e.NodeID, map[string]interface{}{AttrDescription: e.Description}, "describe")
+		}
+		indexed += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for i, n := range pending {
+		if err := ctx.Err(); err != nil {
+			return indexed, err
+		}
+		task.Report(ctx, i, len(pending), n.Name)
+
+		description, err := ix.description(ctx, n)
+		if err != nil {
+			failures++
+			logger.Warn(logger.StatusWarn, "Could not describe %s: %v", n.Name, err)
+			if failures >= maxDescribeFailures {
+				ix.save()
+				return indexed, fmt.Errorf("describe nodes: %w", err)
+			}
+			continue
+		}
+		failures = 0
+		batch = append(batch, Entry{NodeID: n.ID, Description: description})
+		if len(batch) >= embedEvery {
+			if err := flush(); err != nil {
+				ix.save()
+				return indexed, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		ix.save()
+		return indexed, err
+	}
+	task.Report(ctx, len(pending), len(pending), "")
+	return indexed, ix.save()
+}
+
+func (ix *Index) save() error {
+	if ix.Path == "" {
+		return nil
+	}
+	return ix.Store.Save(ix.Path)
+}
+
+// description reuses a node's stored description, or writes a new one
+func (ix *Index) description(ctx context.Context, n *graph.Node) (string, error) {
+	if e, ok := ix.Store.Get(n.ID); ok && e.Description != "" {
+		return e.Description, nil
+	}
+	if d, ok := n.Attributes[AttrDescription].(string); ok && d != "" {
+		return d, nil
+	}
+	if ix.Client.ApiKey == "" {
+		return ix.summary(n), nil
+	}
+
+	prompt := fmt.Sprintf(`
+Describe %q (%s) in one or two short, factual sentences for a supply-chain knowledge graph.
+For a company: what it does and its main products. For a raw material, crop or product: what it is mainly used for.
+For a nation: its main industries and exports. For anything else: what it is and why it matters to trade.
+Known context: %s
+Reply with the description only.
+`, n.Name, n.Type, ix.facts(n))
+
+	resp, err := ix.Client.Complete(llm.WithConsumer(ctx, llm.ConsumerQueries), prompt)
+	if errors.Is(err, llm.ErrBudgetExhausted) {
+		return ix.summary(n), nil // Out of LLM budget for today
+	} else if err != nil {
+		return "", err
+	}
+	description := strings.Join(strings.Fields(strings.Trim(strings.TrimSpace(resp), `"`)), " ")
+	if description == "" {
+		return "", fmt.Errorf("empty description")
+	}
+	return description, nil
+}
+
+// summary is the description used without an LLM: the node's facts
+func (ix *Index) summary(n *graph.Node) string {
+	return fmt.Sprintf("%s is a %s. %s.", n.Name, n.Type, ix.facts(n))
+}
+
+// facts lists what the graph already knows about a node
+func (ix *Index) facts(n *graph.Node) string {
+	var facts []string
+	if country := n.Country(); country != "" && n.Type != graph.NodeTypeNation {
+		facts = append(facts, "based in "+country)
+	}
+	if sector, ok := n.Attributes["sector"].(string); ok && sector != "" {
+		facts = append(facts, "sector "+sector)
+	}
+	if n.Ticker != "" {
+		facts = append(facts, "ticker "+n.Ticker)
+	}
+
+	var links []string
+	for _, e := range ix.Graph.GetOutgoingEdges(n.ID) {
+		if len(links) >= maxNeighbours {
+			break
+		}
+		if target, ok := ix.Graph.GetNode(e.TargetID); ok {
+			links = append(links, fmt.Sprintf("%s %s", e.Type, target.Name))
+		}
+	}
+	if len(links) > 0 {
+		facts = append(facts, "links: "+strings.Join(links, ", "))
+	}
+	if len(facts) == 0 {
+		return "no other facts known"
+	}
+	return strings.Join(facts, "; ")
+}
+
+// document is the text embedded for a node
+func (ix *Index) document(nodeID, description string) string {
+	if n, ok := ix.Graph.GetNode(nodeID); ok {
+		return fmt.Sprintf("%s (%s): %s", n.Name, n.Type, description)
+	}
+	return description
+}
+
+// name is the text embedded for a node's name
+func (ix *Index) name(nodeID string) string {
+	if n, ok := ix.Graph.GetNode(nodeID); ok {
+		return n.Name
+	}
+	return nodeID
+}
+
+// Retrieve returns the k indexed nodes most relevant to query
+func (ix *Index) Retrieve(ctx context.Context, query string, k int) ([]Match, error) {
+	return ix.search(ctx, query, k, "")
+}
+
+func (ix *Index) search(ctx context.Context, query string, k int, nodeType graph.NodeType) ([]Match, error) {
+	query = strings.TrimSpace(query)
+	if ix.Store.Len() == 0 || query == "" {
+		return nil, nil
+	}
+	vectors, err := ix.Client.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return ix.Store.Search(vectors[0], ix.Client.EmbeddingModel(), k, ix.keep(nodeType)), nil
+}
+
+// keep accepts indexed nodes that are still in the graph and of nodeType ("" = any)
+func (ix *Index) keep(nodeType graph.NodeType) func(string) bool {
+	return func(id string) bool {
+		n, ok := ix.Graph.GetNode(id)
+		return ok && (nodeType == "" || n.Type == nodeType)
+	}
+}
+
+// Link finds the existing node a free-text entity name most likely refers
+// to, e.g. "TSMC" for taiwan_semiconductor_manufacturing_company, by the
+// better of name and description similarity. nodeType limits candidates
+// ("" = any). ok is false when nothing is similar enough, or when a second
+// node is nearly as similar ("Samsung" with several Samsung companies).
+func (ix *Index) Link(ctx context.Context, name string, nodeType graph.NodeType) (node *graph.Node, score float64, ok bool) {
+	name = strings.TrimSpace(name)
+	if ix.Store.Len() == 0 || name == "" {
+		return nil, 0, false
+	}
+	vectors, err := ix.Client.Embed(ctx, []string{name})
+	if err != nil {
+		logger.Warn(logger.StatusWarn, "Entity lookup for %s failed: %v", name, err)
+		return nil, 0, false
+	}
+	model, keep := ix.Client.EmbeddingModel(), ix.keep(nodeType)
+
+	scores := make(map[string]float64)
+	for _, m := range append(ix.Store.SearchNames(vectors[0], model, 2, keep), ix.Store.Search(vectors[0], model, 2, keep)...) {
+		if m.Score > scores[m.NodeID] {
+			scores[m.NodeID] = m.Score
+		}
+	}
+	var best, second string
+	for id, s := range scores {
+		switch {
+		case best == "" || s > scores[best] || (s == scores[best] && id < best):
+			best, second = id, best
+		case second == "" || s > scores[second]:
+			second = id
+		}
+	}
+
+	threshold := ix.LinkThreshold
+	if threshold <= 0 {
+		threshold = DefaultLinkThreshold
+	}
+	if best == "" || scores[best] < threshold {
+		return nil, scores[best], false
+	}
+	if second != "" && scores[best]-scores[second] < linkMargin {
+		return nil, scores[best], false
+	}
+	node, ok = ix.Graph.GetNode(best)
+	return node, scores[best], ok
+}
+
+// Context formats the k nodes most relevant to query as prompt context, one
+// "- Name (Type): description" line each. It is empty when nothing is indexed.
+func (ix *Index) Context(ctx context.Context, query string, k int) string {
+	matches, err := ix.Retrieve(ctx, query, k)
+	if err != nil {
+		logger.Warn(logger.StatusWarn, "Context retrieval failed: %v", err)
+		return ""
+	}
+	var lines []string
+	for _, m := range matches {
+		if n, ok := ix.Graph.GetNode(m.NodeID); ok {
+			lines = append(lines, fmt.Sprintf("- %s (%s): %s", n.Name, n.Type, m.Description))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,166 @@
+// Package rag keeps short descriptions of graph nodes (what a company does,
+// what a material is used for) with their embeddings, so features that work
+// from free text - news entity linking, queries - can retrieve the relevant
+// nodes and their context instead of relying on exact names.
+package rag
+
+import (
+	"encoding/json"
+	"margraf/clock"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Entry is one node's description and its embedding
+type Entry struct {
+	NodeID      string    `json:"node_id"`
+	Description string    `json:"description"`
+	Model       string    `json:"model"`       // Embedding model; vectors only compare within one model
+	Vector      []float64 `json:"vector"`      // Embedding of the description
+	NameVector  []float64 `json:"name_vector"` // Embedding of the node's name, for entity linking
+	Updated     time.Time `json:"updated"`
+}
+
+// Match is a search hit, most similar first
+type Match struct {
+	NodeID      string  `json:"node_id"`
+	Description string  `json:"description"`
+	Score       float64 `json:"score"` // Cosine similarity
+}
+
+// Store is an in-memory vector store of node descriptions, saved as JSON
+type Store struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry // Keyed by node ID
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{entries: make(map[string]*Entry)}
+}
+
+// LoadStore reads a store saved with Save. A missing file gives an empty store.
+func LoadStore(path string) (*Store, error) {
+	s := NewStore()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		s.entries[e.NodeID] = e
+	}
+	return s, nil
+}
+
+// Save writes the store to path, sorted by node ID
+func (s *Store) Save(path string) error {
+	s.mu.RLock()
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	s.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].NodeID < entries[j].NodeID })
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Put adds or replaces a node's entry
+func (s *Store) Put(e Entry) {
+	if e.Updated.IsZero() {
+		e.Updated = clock.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[e.NodeID] = &e
+}
+
+// Get returns a copy of a node's entry
+func (s *Store) Get(nodeID string) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[nodeID]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// Delete removes a node's entry
+func (s *Store) Delete(nodeID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, nodeID)
+}
+
+// Len returns the number of entries
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Search returns the k entries embedded with model whose descriptions are
+// most similar to query. keep, if not nil, filters candidates by node ID.
+func (s *Store) Search(query []float64, model string, k int, keep func(nodeID string) bool) []Match {
+	return s.search(query, model, k, keep, func(e *Entry) []float64 { return e.Vector })
+}
+
+// SearchNames is Search against the node names instead of the descriptions
+func (s *Store) SearchNames(query []float64, model string, k int, keep func(nodeID string) bool) []Match {
+	return s.search(query, model, k, keep, func(e *Entry) []float64 { return e.NameVector })
+}
+
+func (s *Store) search(query []float64, model string, k int, keep func(nodeID string) bool, vector func(*Entry) []float64) []Match {
+	s.mu.RLock()
+	var matches []Match
+	for _, e := range s.entries {
+		v := vector(e)
+		if e.Model != model || len(v) != len(query) {
+			continue
+		}
+		if keep != nil && !keep(e.NodeID) {
+			continue
+		}
+		matches = append(matches, Match{NodeID: e.NodeID, Description: e.Description, Score: cosine(query, v)})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].NodeID < matches[j].NodeID
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,227 @@
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects how the transport treats outbound HTTP requests.
+type Mode string
+
+const (
+	ModeLive   Mode = "live"   // Pass through, nothing saved
+	ModeRecord Mode = "record" // Pass through and save every response as a fixture
+	ModeReplay Mode = "replay" // Serve saved fixtures only, never touch the network
+)
+
+// DefaultDir is where fixtures are written when no directory is given.
+const DefaultDir = "testdata/fixtures"
+
+// secretParams are query parameters stripped before a request is keyed,
+// so fixtures recorded with one API key replay under any other.
+var secretParams = []string{"key", "api_key", "apikey", "token", "access_token"}
+
+// volatileParams are query parameters left out of a fixture's key because
+// they change on every run (Yahoo's history window ends "now"), so a
+// recording keeps replaying on later days
+var volatileParams = []string{"period1", "period2"}
+
+// Fixture is a single recorded HTTP exchange.
+type Fixture struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	RequestBody string `json:"request_body,omitempty"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Transport is an http.RoundTripper that records or replays responses.
+type Transport struct {
+	Mode Mode
+	Dir  string
+	Next http.RoundTripper // Underlying transport for live/record
+
+	mu sync.Mutex
+}
+
+var current Mode = ModeLive
+
+// Install swaps http.DefaultTransport so every client built without an explicit
+// Transport (all external data sources, LLM, scrapers) goes through replay.
+func Install(mode Mode, dir string) error {
+	if mode == ModeLive || mode == "" {
+		return nil
+	}
+	if mode != ModeRecord && mode != ModeReplay {
+		return fmt.Errorf("unknown replay mode: %s", mode)
+	}
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if mode == ModeReplay {
+		// A missing directory would fail every request; say so up front
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("no fixture directory at %s", dir)
+		}
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	http.DefaultTransport = &Transport{
+		Mode: mode,
+		Dir:  dir,
+		Next: http.DefaultTransport,
+	}
+	current = mode
+	return nil
+}
+
+// Offline reports whether requests are being served from fixtures.
+func Offline() bool {
+	return current == ModeReplay
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = data
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	cleanURL := redactURL(req.URL)
+	path := filepath.Join(t.Dir, fixtureName(req.Method, req.URL, reqBody))
+
+	if t.Mode == ModeReplay {
+		return t.load(path, req)
+	}
+
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil || t.Mode != ModeRecord {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	fixture := Fixture{
+		Method:      req.Method,
+		URL:         cleanURL,
+		RequestBody: string(reqBody),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(body),
+	}
+	if err := t.save(path, fixture); err != nil {
+		return nil, fmt.Errorf("failed to record fixture: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (t *Transport) save(path string, f Fixture) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (t *Transport) load(path string, req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("offline: no fixture for %s %s", req.Method, redactURL(req.URL))
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("offline: corrupt fixture %s: %v", path, err)
+	}
+
+	header := make(http.Header)
+	if f.ContentType != "" {
+		header.Set("Content-Type", f.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}, nil
+}
+
+// redactURL drops credential query parameters from a URL
+func redactURL(u *url.URL) string {
+	clean := *u
+	q := clean.Query()
+	for _, p := range secretParams {
+		q.Del(p)
+	}
+	clean.RawQuery = q.Encode()
+	return clean.String()
+}
+
+// fixtureName derives a stable file name from the request, ignoring its
+// credentials and volatile parameters
+func fixtureName(method string, u *url.URL, body []byte) string {
+	keyed := *u
+	q := keyed.Query()
+	for _, p := range volatileParams {
+		q.Del(p)
+	}
+	keyed.RawQuery = q.Encode()
+
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte(redactURL(&keyed)))
+	h.Write(body)
+	sum := hex.EncodeToString(h.Sum(nil))[:16]
+
+	host := strings.ReplaceAll(u.Host, ":", "_")
+	return fmt.Sprintf("%s_%s.json", host, sum)
+}
+
+// WriteFixture saves f in dir under the name a request for f.Method and f.URL
+// (with f.RequestBody) is replayed from, for fixtures written by hand or
+// generated rather than recorded
+func WriteFixture(dir string, f Fixture) error {
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f.URL = redactURL(u)
+	t := &Transport{Dir: dir}
+	return t.save(filepath.Join(dir, fixtureName(f.Method, u, []byte(f.RequestBody))), f)
+}
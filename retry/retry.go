@@ -0,0 +1,253 @@
+// Package retry is the shared retry policy for external calls: exponential
+// backoff with jitter, an attempt and elapsed-time budget, and a per-error
+// decision on whether another attempt can help.
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Decision says whether a failed attempt should be retried
+type Decision int
+
+const (
+	Retry Decision = iota
+	Stop
+)
+
+// Policy configures backoff. If neither MaxAttempts nor MaxElapsed is set,
+// only one attempt is made.
+type Policy struct {
+	MaxAttempts int           // Attempts including the first; 0 = bounded by MaxElapsed only
+	Initial     time.Duration // Delay before the first retry
+	Max         time.Duration // Cap on a single computed delay; 0 = no cap
+	Multiplier  float64       // Delay growth per retry (2 = doubling)
+	Jitter      float64       // Random spread as a fraction of the delay (0.2 = ±20%)
+	MaxElapsed  time.Duration // Give up once a retry would end past this; 0 = no limit
+
+	Classify func(error) Decision                              // nil = Classify
+	OnRetry  func(attempt int, delay time.Duration, err error) // Called before each wait; may be nil
+}
+
+// HTTP suits scraper and data source requests: a few quick retries
+var HTTP = Policy{
+	MaxAttempts: 3,
+	Initial:     time.Second,
+	Max:         10 * time.Second,
+	Multiplier:  2,
+	Jitter:      0.2,
+	MaxElapsed:  30 * time.Second,
+}
+
+// LLM suits provider rate limits, which clear over tens of seconds. The
+// client's Timeout bounds the total.
+var LLM = Policy{
+	MaxAttempts: 6,
+	Initial:     5 * time.Second,
+	Max:         80 * time.Second,
+	Multiplier:  2,
+	Jitter:      0.2,
+}
+
+// StatusError is an HTTP response whose status may warrant a retry
+type StatusError struct {
+	Code       int
+	Body       string
+	RetryAfter time.Duration // Server-requested delay; 0 = none
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.Code, e.Body)
+}
+
+// Retryable reports whether the status is transient (429, 408 or 5xx)
+func (e *StatusError) Retryable() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code == http.StatusRequestTimeout || e.Code >= 500
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// Classify is the default decision: permanent errors and non-transient HTTP
+// statuses stop; network errors and transient statuses retry.
+func Classify(err error) Decision {
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return Stop
+	}
+	var status *StatusError
+	if errors.As(err, &status) && !status.Retryable() {
+		return Stop
+	}
+	return Retry
+}
+
+// Do calls fn until it succeeds, the policy gives up, or ctx is cancelled.
+// It returns fn's last error (unwrapped from Permanent), or ctx's error.
+func Do(ctx context.Context, p Policy, fn func() error) error {
+	classify := p.Classify
+	if classify == nil {
+		classify = Classify
+	}
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if classify(err) == Stop {
+			return err
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return err
+		}
+		if p.MaxAttempts <= 0 && p.MaxElapsed <= 0 {
+			return err
+		}
+
+		delay := p.Delay(attempt)
+		var status *StatusError
+		if errors.As(err, &status) && status.RetryAfter > 0 {
+			delay = status.RetryAfter
+		}
+		if p.MaxElapsed > 0 && time.Since(start)+delay > p.MaxElapsed {
+			return err
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, delay, err)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Delay returns the jittered backoff before retry number attempt (1-based)
+func (p Policy) Delay(attempt int) time.Duration {
+	d := float64(p.Initial)
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+	for i := 1; i < attempt; i++ {
+		d *= mult
+		if p.Max > 0 && d >= float64(p.Max) {
+			break
+		}
+	}
+	if p.Max > 0 && d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+// DoRequest sends req with client, retrying transport errors and transient
+// statuses (honouring Retry-After). The final response is returned as-is
+// whatever its status, so callers keep their own status handling. Requests
+// with a body must be replayable (http.NewRequest sets GetBody for common
+// readers).
+func DoRequest(client *http.Client, req *http.Request, p Policy) (*http.Response, error) {
+	var last *http.Response
+	err := Do(req.Context(), p, func() error {
+		attempt := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return Permanent(err)
+			}
+			attempt.Body = body
+		}
+
+		resp, err := client.Do(attempt)
+		if err != nil {
+			return err
+		}
+
+		status := &StatusError{Code: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		if !status.Retryable() {
+			last = resp
+			return nil
+		}
+		// Keep the body readable in case this is the final attempt
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		status.Body = string(body)
+		last = resp
+		return status
+	})
+
+	var status *StatusError
+	if errors.As(err, &status) && last != nil {
+		return last, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or HTTP-date form
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleep waits for d, returning early with ctx's error if it is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,259 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"margraf/clock"
+	"margraf/retry"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Public Telegram channels and Discord servers carry much of the commodity
+// and shipping chatter that never reaches Reddit or Hacker News. Telegram
+// channels are read through their public t.me previews, which need no
+// account; Discord channels through a bot that has been added to the server.
+
+// FetchTelegramPosts searches the public previews of the given Telegram
+// channels (usernames, with or without "@") for a topic, returning up to
+// limit posts per channel, newest first
+func (s *SocialScraper) FetchTelegramPosts(ctx context.Context, channels []string, topic string, limit int) ([]SocialPost, error) {
+	var posts []SocialPost
+	var lastErr error
+	for _, channel := range channels {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		channel = strings.TrimPrefix(strings.TrimSpace(channel), "@")
+		if channel == "" {
+			continue
+		}
+		found, err := s.fetchTelegramChannel(ctx, channel, topic, limit)
+		if err != nil {
+			lastErr = fmt.Errorf("telegram %s: %w", channel, err)
+			continue
+		}
+		posts = append(posts, found...)
+	}
+	if len(posts) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return posts, nil
+}
+
+func (s *SocialScraper) fetchTelegramChannel(ctx context.Context, channel, topic string, limit int) ([]SocialPost, error) {
+	s.rateLimit(1 * time.Second)
+
+	previewURL := fmt.Sprintf("https://t.me/s/%s?q=%s", url.PathEscape(channel), url.QueryEscape(topic))
+	req, err := http.NewRequestWithContext(ctx, "GET", previewURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; MargrafBot/2.0; +Educational Research)")
+
+	resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("preview status: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Find(".tgme_channel_info").Length() == 0 {
+		return nil, fmt.Errorf("no public preview (private channel or not a channel)")
+	}
+
+	// The preview lists messages oldest first
+	var posts []SocialPost
+	doc.Find(".tgme_widget_message").Each(func(i int, sel *goquery.Selection) {
+		text := strings.TrimSpace(sel.Find(".tgme_widget_message_text").Text())
+		if len(text) < 10 || !mentions(text, topic) {
+			return
+		}
+		if len(text) > 300 {
+			text = text[:300] + "..."
+		}
+		post, _ := sel.Attr("data-post") // "channel/123"
+		at := clock.Now()
+		if stamp, ok := sel.Find(".tgme_widget_message_date time").Attr("datetime"); ok {
+			if t, err := time.Parse(time.RFC3339, stamp); err == nil {
+				at = t.UTC()
+			}
+		}
+		posts = append(posts, SocialPost{
+			Platform: "Telegram",
+			User:     "@" + channel,
+			Content:  text,
+			URL:      "https://t.me/" + post,
+			Time:     at,
+		})
+	})
+
+	// Newest first
+	for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+		posts[i], posts[j] = posts[j], posts[i]
+	}
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+// DiscordBot reads channels through the Discord API as a bot
+type DiscordBot struct {
+	Token string
+
+	mu     sync.Mutex
+	guilds map[string]string // Channel ID -> server ID, for message links
+}
+
+// DiscordBotFromEnv reads DISCORD_BOT_TOKEN, returning nil when it is unset
+func DiscordBotFromEnv() *DiscordBot {
+	token := os.Getenv("DISCORD_BOT_TOKEN")
+	if token == "" {
+		return nil
+	}
+	return &DiscordBot{Token: token, guilds: make(map[string]string)}
+}
+
+const discordAPI = "https://discord.com/api/v10"
+
+// FetchDiscordPosts searches the latest 100 messages of each Discord channel
+// (by ID) for a topic, returning up to limit posts per channel, newest first.
+// The bot in DISCORD_BOT_TOKEN must be able to read the channels.
+func (s *SocialScraper) FetchDiscordPosts(ctx context.Context, channels []string, topic string, limit int) ([]SocialPost, error) {
+	if s.Discord == nil {
+		return nil, fmt.Errorf("DISCORD_BOT_TOKEN not set")
+	}
+	var posts []SocialPost
+	var lastErr error
+	for _, channel := range channels {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		channel = strings.TrimSpace(channel)
+		if channel == "" {
+			continue
+		}
+		found, err := s.fetchDiscordChannel(ctx, channel, topic, limit)
+		if err != nil {
+			lastErr = fmt.Errorf("discord %s: %w", channel, err)
+			continue
+		}
+		posts = append(posts, found...)
+	}
+	if len(posts) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return posts, nil
+}
+
+func (s *SocialScraper) fetchDiscordChannel(ctx context.Context, channel, topic string, limit int) ([]SocialPost, error) {
+	var messages []struct {
+		ID        string    `json:"id"`
+		Content   string    `json:"content"`
+		Timestamp time.Time `json:"timestamp"`
+		Author    struct {
+			Username string `json:"username"`
+		} `json:"author"`
+	}
+	if err := s.discordGet(ctx, "/channels/"+url.PathEscape(channel)+"/messages?limit=100", &messages); err != nil {
+		return nil, err
+	}
+	guild := s.discordGuild(ctx, channel)
+
+	// The API returns messages newest first
+	var posts []SocialPost
+	for _, m := range messages {
+		if len(posts) >= limit {
+			break
+		}
+		text := strings.TrimSpace(m.Content)
+		if len(text) < 10 || !mentions(text, topic) {
+			continue
+		}
+		if len(text) > 300 {
+			text = text[:300] + "..."
+		}
+		posts = append(posts, SocialPost{
+			Platform: "Discord",
+			User:     m.Author.Username,
+			Content:  text,
+			URL:      fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guild, channel, m.ID),
+			Time:     m.Timestamp,
+		})
+	}
+	return posts, nil
+}
+
+// discordGuild returns the server a channel belongs to, or "@me" (a link
+// Discord still resolves for members) when it cannot be looked up
+func (s *SocialScraper) discordGuild(ctx context.Context, channel string) string {
+	s.Discord.mu.Lock()
+	guild, ok := s.Discord.guilds[channel]
+	s.Discord.mu.Unlock()
+	if ok {
+		return guild
+	}
+	var info struct {
+		GuildID string `json:"guild_id"`
+	}
+	if err := s.discordGet(ctx, "/channels/"+url.PathEscape(channel), &info); err != nil || info.GuildID == "" {
+		return "@me"
+	}
+	s.Discord.mu.Lock()
+	s.Discord.guilds[channel] = info.GuildID
+	s.Discord.mu.Unlock()
+	return info.GuildID
+}
+
+// discordGet decodes the JSON reply to an authenticated GET of an API path
+func (s *SocialScraper) discordGet(ctx context.Context, path string, out interface{}) error {
+	s.rateLimit(1 * time.Second)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", discordAPI+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+s.Discord.Token)
+	req.Header.Set("User-Agent", "DiscordBot (https://margraf.app, 2.0)")
+
+	resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		return json.NewDecoder(resp.Body).Decode(out)
+	case 401:
+		return fmt.Errorf("bot token rejected")
+	case 403:
+		return fmt.Errorf("bot cannot read this channel")
+	case 429:
+		return fmt.Errorf("discord rate limited (retry after %ss)", resp.Header.Get("Retry-After"))
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("discord api status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// mentions reports whether text contains the topic, ignoring case
+func mentions(text, topic string) bool {
+	return strings.Contains(strings.ToLower(text), strings.ToLower(topic))
+}
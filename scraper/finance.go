@@ -1,8 +1,12 @@
 package scraper
 
 import (
+	"context"
 	"fmt"
+	"margraf/config"
+	"margraf/retry"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,10 +14,11 @@ import (
 )
 
 type StockData struct {
-	Ticker   string
-	Price    float64
-	Change   float64
-	Currency string
+	Ticker     string
+	Price      float64
+	Change     float64
+	Currency   string
+	MarketTime time.Time // When the price was quoted; zero if the page did not say
 }
 
 // FinanceScraper fetches data from Yahoo Finance.
@@ -24,25 +29,31 @@ type FinanceScraper struct {
 func NewFinanceScraper() *FinanceScraper {
 	return &FinanceScraper{
 		Client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: config.Timeout(config.Global.Timeouts.HTTP, 10*time.Second),
 		},
 	}
 }
 
 // GetTicker tries to find the ticker symbol for a company name via DuckDuckGo (RAG-lite)
-// because we don't have a direct symbol database.
-func (s *FinanceScraper) GetTicker(companyName string) (string, error) {
+// because we don't have a direct symbol database. With an exchange, the
+// search names it and a symbol with its Yahoo suffix (e.g. ".T") is
+// preferred over a US listing or ADR.
+func (s *FinanceScraper) GetTicker(ctx context.Context, companyName, exchange, suffix string) (string, error) {
 	// Simplified: In a real app, we'd use a lookup API.
 	// Here we assume the node name might ALREADY be a ticker if it's short,
 	// or we search for "CompanyName ticker yahoo finance"
 	
 	ws := NewWebSearcher()
 	query := fmt.Sprintf("%s ticker symbol yahoo finance", companyName)
-	results, err := ws.Search(query)
+	if exchange != "" {
+		query = fmt.Sprintf("%s %s ticker symbol yahoo finance", companyName, exchange)
+	}
+	results, err := ws.Search(ctx, query)
 	if err != nil {
 		return "", err
 	}
 
+	var found []string
 	for _, res := range results {
 		// Look for patterns like (AAPL) or "Symbol: AAPL" or finance.yahoo.com/quote/AAPL
 		if strings.Contains(res.Link, "finance.yahoo.com/quote/") {
@@ -50,20 +61,32 @@ func (s *FinanceScraper) GetTicker(companyName string) (string, error) {
 			if len(parts) > 1 {
 				ticker := strings.Split(parts[1], "/")[0]
 				ticker = strings.Split(ticker, "?")[0]
+				if ticker != "" {
+					found = append(found, strings.ToUpper(ticker))
+				}
+			}
+		}
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("ticker not found")
+	}
+	if suffix != "" {
+		for _, ticker := range found {
+			if strings.HasSuffix(ticker, strings.ToUpper(suffix)) {
 				return ticker, nil
 			}
 		}
 	}
-	return "", fmt.Errorf("ticker not found")
+	return found[0], nil
 }
 
 // FetchStockData scrapes the price from Yahoo Finance.
-func (s *FinanceScraper) FetchStockData(ticker string) (*StockData, error) {
+func (s *FinanceScraper) FetchStockData(ctx context.Context, ticker string) (*StockData, error) {
 	url := fmt.Sprintf("https://finance.yahoo.com/quote/%s", ticker)
-	req, _ := http.NewRequest("GET", url, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Safari/537.36")
 
-	resp, err := s.Client.Do(req)
+	resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, err
 	}
@@ -84,6 +107,7 @@ func (s *FinanceScraper) FetchStockData(ticker string) (*StockData, error) {
 	var price float64
 	var change float64
 	var currency string
+	var marketTime time.Time
 
 	doc.Find("fin-streamer").Each(func(i int, s *goquery.Selection) {
 		field, _ := s.Attr("data-field")
@@ -102,21 +126,28 @@ func (s *FinanceScraper) FetchStockData(ticker string) (*StockData, error) {
 		if field == "regularMarketChangePercent" {
 			fmt.Sscanf(valStr, "%f", &change)
 		}
+		if field == "regularMarketTime" {
+			// Unix seconds; the text is a display string like "At close: 4:00 PM EDT"
+			if v, ok := s.Attr("value"); ok {
+				if secs, err := strconv.ParseInt(v, 10, 64); err == nil && secs > 0 {
+					marketTime = time.Unix(secs, 0).UTC()
+				}
+			}
+		}
 	})
 
-	// Fallback for currency if not found in streamer
-	if currency == "" {
-		currency = "USD" // Default assumption
-	}
+	// Currency stays empty if not found in streamer; callers take it from
+	// the ticker's exchange (see graph.NormalizeQuote)
 
 	if price == 0 {
 		return nil, fmt.Errorf("could not parse price")
 	}
 
 	return &StockData{
-		Ticker:   ticker,
-		Price:    price,
-		Change:   change,
-		Currency: currency,
+		Ticker:     ticker,
+		Price:      price,
+		Change:     change,
+		Currency:   currency,
+		MarketTime: marketTime,
 	}, nil
 }
@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"margraf/retry"
+	"net/http"
+	"net/url"
+)
+
+// Fundamentals holds company-level data from Yahoo quoteSummary.
+// Monetary values are in Currency (the listing's reporting currency).
+type Fundamentals struct {
+	Ticker    string
+	MarketCap float64
+	Revenue   float64
+	Employees int
+	Sector    string
+	Industry  string
+	Country   string
+	Currency  string
+}
+
+// yahooRaw is Yahoo's {"raw": 123, "fmt": "123"} number wrapper
+type yahooRaw struct {
+	Raw float64 `json:"raw"`
+}
+
+type quoteSummaryResponse struct {
+	QuoteSummary struct {
+		Result []struct {
+			Price struct {
+				MarketCap yahooRaw `json:"marketCap"`
+				Currency  string   `json:"currency"`
+			} `json:"price"`
+			SummaryProfile struct {
+				Sector            string `json:"sector"`
+				Industry          string `json:"industry"`
+				Country           string `json:"country"`
+				FullTimeEmployees int    `json:"fullTimeEmployees"`
+			} `json:"summaryProfile"`
+			FinancialData struct {
+				TotalRevenue yahooRaw `json:"totalRevenue"`
+			} `json:"financialData"`
+		} `json:"result"`
+		Error *struct {
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"quoteSummary"`
+}
+
+// FetchFundamentals pulls market cap, revenue, employees, sector and country for a ticker.
+func (s *FinanceScraper) FetchFundamentals(ctx context.Context, ticker string) (*Fundamentals, error) {
+	endpoint := fmt.Sprintf("https://query2.finance.yahoo.com/v10/finance/quoteSummary/%s?modules=price,summaryProfile,financialData",
+		url.PathEscape(ticker))
+	req, _ := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Safari/537.36")
+
+	resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("yahoo quoteSummary status: %d", resp.StatusCode)
+	}
+
+	var data quoteSummaryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	if data.QuoteSummary.Error != nil {
+		return nil, fmt.Errorf("yahoo quoteSummary: %s", data.QuoteSummary.Error.Description)
+	}
+	if len(data.QuoteSummary.Result) == 0 {
+		return nil, fmt.Errorf("no fundamentals for %s", ticker)
+	}
+
+	r := data.QuoteSummary.Result[0]
+	return &Fundamentals{
+		Ticker:    ticker,
+		MarketCap: r.Price.MarketCap.Raw,
+		Revenue:   r.FinancialData.TotalRevenue.Raw,
+		Employees: r.SummaryProfile.FullTimeEmployees,
+		Sector:    r.SummaryProfile.Sector,
+		Industry:  r.SummaryProfile.Industry,
+		Country:   r.SummaryProfile.Country,
+		Currency:  r.Price.Currency,
+	}, nil
+}
+
+// Attributes converts fundamentals to node attributes, omitting missing values
+func (f *Fundamentals) Attributes() map[string]interface{} {
+	attrs := make(map[string]interface{})
+	if f.MarketCap > 0 {
+		attrs["market_cap"] = f.MarketCap
+	}
+	if f.Revenue > 0 {
+		attrs["revenue"] = f.Revenue
+	}
+	if f.Employees > 0 {
+		attrs["employees"] = float64(f.Employees) // float64 to match values reloaded from JSON
+	}
+	if f.Sector != "" {
+		attrs["sector"] = f.Sector
+	}
+	if f.Industry != "" {
+		attrs["yahoo_industry"] = f.Industry
+	}
+	if f.Country != "" {
+		attrs["country"] = f.Country
+	}
+	if f.Currency != "" {
+		attrs["fundamentals_currency"] = f.Currency
+	}
+	return attrs
+}
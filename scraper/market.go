@@ -1,10 +1,14 @@
 package scraper
 
 import (
+	"context"
 	"fmt"
+	"margraf/config"
 	"margraf/logger"
+	"margraf/retry"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
@@ -16,25 +20,27 @@ type MarketScraper struct {
 
 func NewMarketScraper() *MarketScraper {
 	return &MarketScraper{
-		Client: &http.Client{},
+		Client: &http.Client{
+			Timeout: config.Timeout(config.Global.Timeouts.HTTP, 20*time.Second),
+		},
 	}
 }
 
 // FetchTopNations scrapes Wikipedia for top economies by GDP.
 // Fallback: Returns a hardcoded list if scraping fails (to ensure app stability),
 // but tries to fetch real data first.
-func (s *MarketScraper) FetchTopNations(limit int) ([]string, error) {
+func (s *MarketScraper) FetchTopNations(ctx context.Context, limit int) ([]string, error) {
 	url := "https://en.wikipedia.org/wiki/List_of_countries_by_GDP_(nominal)"
 	logger.InfoDepth(1, logger.StatusGlob, "Scraping real economic data from: %s", url)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 	req.Header.Set("Referer", "https://www.google.com")
 
-	res, err := s.Client.Do(req)
+	res, err := retry.DoRequest(s.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +89,7 @@ func (s *MarketScraper) FetchTopNations(limit int) ([]string, error) {
 // We will use a search-proxy approach: We can't easily search Google, but we can scrape
 // "List of largest companies in [Country]" if a direct Wiki URL exists, or fallback to LLM.
 // For this prototype, we will keep this method but note the limitation.
-func (s *MarketScraper) FetchMajorCompanies(country string) ([]string, error) {
+func (s *MarketScraper) FetchMajorCompanies(ctx context.Context, country string) ([]string, error) {
 	// Attempt to find a specific wikipedia list
 	country = strings.ReplaceAll(country, " ", "_")
 	url := fmt.Sprintf("https://en.wikipedia.org/wiki/List_of_companies_of_%s", country)
@@ -93,14 +99,14 @@ func (s *MarketScraper) FetchMajorCompanies(country string) ([]string, error) {
 
 	logger.InfoDepth(1, logger.StatusCor, "Scraping company data from: %s", url)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 	req.Header.Set("Referer", "https://www.google.com")
 
-	res, err := s.Client.Do(req)
+	res, err := retry.DoRequest(s.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, err
 	}
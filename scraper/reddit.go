@@ -0,0 +1,146 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"margraf/retry"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reddit's official API allows 100 requests a minute per OAuth client, against
+// roughly 10 for the anonymous search.json endpoint
+const (
+	redditTokenURL  = "https://www.reddit.com/api/v1/access_token"
+	redditOAuthURL  = "https://oauth.reddit.com"
+	redditOAuthGap  = 600 * time.Millisecond
+	redditAnonGap   = 2 * time.Second
+	redditUserAgent = "go:margraf:2.0 (by /u/margraf)"
+)
+
+// RedditAuth holds app-only OAuth credentials for Reddit's official API and
+// the access token they were last exchanged for. The token is refreshed a
+// minute before it expires, or when Reddit rejects it.
+type RedditAuth struct {
+	ClientID     string
+	ClientSecret string
+	UserAgent    string // Reddit asks for "platform:app:version (by /u/user)"
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// RedditAuthFromEnv reads REDDIT_CLIENT_ID, REDDIT_CLIENT_SECRET and
+// REDDIT_USER_AGENT, returning nil (anonymous access) unless both
+// credentials are set
+func RedditAuthFromEnv() *RedditAuth {
+	id, secret := os.Getenv("REDDIT_CLIENT_ID"), os.Getenv("REDDIT_CLIENT_SECRET")
+	if id == "" || secret == "" {
+		return nil
+	}
+	agent := os.Getenv("REDDIT_USER_AGENT")
+	if agent == "" {
+		agent = redditUserAgent
+	}
+	return &RedditAuth{ClientID: id, ClientSecret: secret, UserAgent: agent}
+}
+
+// Token returns a valid access token, fetching a new one when none is held
+// or the current one is about to expire
+func (a *RedditAuth) Token(ctx context.Context, client *http.Client) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Now().Before(a.expires.Add(-time.Minute)) {
+		return a.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, "POST", redditTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(a.ClientID, a.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", a.UserAgent)
+
+	resp, err := retry.DoRequest(client, req, retry.HTTP)
+	if err != nil {
+		return "", fmt.Errorf("reddit token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("reddit token status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var grant struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"` // Seconds
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&grant); err != nil {
+		return "", fmt.Errorf("reddit token decode error: %w", err)
+	}
+	if grant.AccessToken == "" {
+		return "", fmt.Errorf("reddit token refused: %s", grant.Error)
+	}
+	if grant.ExpiresIn <= 0 {
+		grant.ExpiresIn = 3600
+	}
+	a.token = grant.AccessToken
+	a.expires = time.Now().Add(time.Duration(grant.ExpiresIn) * time.Second)
+	return a.token, nil
+}
+
+// invalidate drops a token Reddit rejected, so the next call fetches another
+func (a *RedditAuth) invalidate(token string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token == token {
+		a.token = ""
+	}
+}
+
+// searchRedditOAuth runs a search through the official API, refreshing the
+// token once if Reddit rejects it
+func (s *SocialScraper) searchRedditOAuth(ctx context.Context, query url.Values) (*http.Response, error) {
+	apiURL := redditOAuthURL + "/search?" + query.Encode()
+	for attempt := 0; ; attempt++ {
+		token, err := s.Reddit.Token(ctx, s.Client)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("User-Agent", s.Reddit.UserAgent)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+		resp.Body.Close()
+		s.Reddit.invalidate(token) // Revoked or expired early
+	}
+}
+
+// redditRateLimited describes a 429, with when Reddit says the window resets
+func redditRateLimited(resp *http.Response) error {
+	if reset := resp.Header.Get("X-Ratelimit-Reset"); reset != "" {
+		return fmt.Errorf("reddit rate limited (too many requests, resets in %ss)", reset)
+	}
+	return fmt.Errorf("reddit rate limited (too many requests)")
+}
@@ -1,12 +1,17 @@
 package scraper
 
 import (
+	"errors"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"margraf/config"
+	"margraf/retry"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -19,9 +24,15 @@ type SearchResult struct {
 	Snippet string
 }
 
+// ddgRetry gives DuckDuckGo one more try after a short pause
+var ddgRetry = retry.Policy{MaxAttempts: 2, Initial: 2 * time.Second, Jitter: 0.2}
+
+var errNoResults = errors.New("no results")
+
 // WebSearcher handles searching the web with multiple fallback methods.
 type WebSearcher struct {
 	Client        *http.Client
+	mu            sync.Mutex // Guards the fields below; crawls search concurrently
 	lastRequestAt time.Time
 	requestCount  int
 }
@@ -29,7 +40,7 @@ type WebSearcher struct {
 func NewWebSearcher() *WebSearcher {
 	return &WebSearcher{
 		Client: &http.Client{
-			Timeout: 15 * time.Second,
+			Timeout: config.Timeout(config.Global.Timeouts.HTTP, 15*time.Second),
 		},
 		lastRequestAt: time.Time{},
 		requestCount:  0,
@@ -39,43 +50,60 @@ func NewWebSearcher() *WebSearcher {
 // rateLimit applies a simple rate limiting mechanism
 func (s *WebSearcher) rateLimit() {
 	// Wait at least 1 second between requests to avoid being blocked
-	if !s.lastRequestAt.IsZero() {
-		elapsed := time.Since(s.lastRequestAt)
-		if elapsed < time.Second {
-			time.Sleep(time.Second - elapsed)
-		}
-	}
-	s.lastRequestAt = time.Now()
+	time.Sleep(reserveSlot(&s.mu, &s.lastRequestAt, time.Second))
+	s.mu.Lock()
 	s.requestCount++
+	s.mu.Unlock()
+}
+
+// reserveSlot claims the next request time at least minDelay after the last
+// one claimed, returning how long to wait for it. Concurrent callers get
+// successive slots rather than all firing once the delay passes.
+func reserveSlot(mu *sync.Mutex, last *time.Time, minDelay time.Duration) time.Duration {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	next := now
+	if !last.IsZero() && last.Add(minDelay).After(now) {
+		next = last.Add(minDelay)
+	}
+	*last = next
+	return next.Sub(now)
 }
 
 // Search performs a web search using multiple methods with fallbacks
-func (s *WebSearcher) Search(query string) ([]SearchResult, error) {
+func (s *WebSearcher) Search(ctx context.Context, query string) ([]SearchResult, error) {
 	s.rateLimit()
 
 	// Try Wikipedia API first for entity searches
 	if strings.Contains(strings.ToLower(query), "companies") ||
 	   strings.Contains(strings.ToLower(query), "industries") ||
 	   strings.Contains(strings.ToLower(query), "wikipedia") {
-		results, err := s.searchWikipedia(query)
+		results, err := s.searchWikipedia(ctx, query)
 		if err == nil && len(results) > 0 {
 			return results, nil
 		}
 	}
 
-	// Try DuckDuckGo with retry
-	for attempt := 0; attempt < 2; attempt++ {
-		results, err := s.searchDuckDuckGo(query)
-		if err == nil && len(results) > 0 {
-			return results, nil
-		}
-		if attempt < 1 {
-			time.Sleep(time.Second * 2)
+	// Try DuckDuckGo with retry; it answers throttled queries with empty results
+	var results []SearchResult
+	err := retry.Do(ctx, ddgRetry, func() error {
+		var err error
+		results, err = s.searchDuckDuckGo(ctx, query)
+		if err == nil && len(results) == 0 {
+			return errNoResults
 		}
+		return err
+	})
+	if err == nil {
+		return results, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
 	}
 
 	// Fallback to direct Wikipedia search
-	results, err := s.searchWikipediaFallback(query)
+	results, err = s.searchWikipediaFallback(ctx, query)
 	if err == nil && len(results) > 0 {
 		return results, nil
 	}
@@ -84,7 +112,7 @@ func (s *WebSearcher) Search(query string) ([]SearchResult, error) {
 }
 
 // searchWikipedia searches Wikipedia API directly
-func (s *WebSearcher) searchWikipedia(query string) ([]SearchResult, error) {
+func (s *WebSearcher) searchWikipedia(ctx context.Context, query string) ([]SearchResult, error) {
 	// Extract main search term
 	searchTerm := strings.TrimSpace(query)
 	searchTerm = strings.ReplaceAll(searchTerm, "wikipedia", "")
@@ -93,13 +121,13 @@ func (s *WebSearcher) searchWikipedia(query string) ([]SearchResult, error) {
 	apiURL := fmt.Sprintf("https://en.wikipedia.org/w/api.php?action=opensearch&search=%s&limit=5&format=json",
 		url.QueryEscape(searchTerm))
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("User-Agent", "MargrafBot/1.0 (Educational Research)")
 
-	resp, err := s.Client.Do(req)
+	resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, err
 	}
@@ -151,18 +179,18 @@ func (s *WebSearcher) searchWikipedia(query string) ([]SearchResult, error) {
 }
 
 // searchWikipediaFallback does a direct HTML search on Wikipedia
-func (s *WebSearcher) searchWikipediaFallback(query string) ([]SearchResult, error) {
+func (s *WebSearcher) searchWikipediaFallback(ctx context.Context, query string) ([]SearchResult, error) {
 	searchTerm := strings.TrimSpace(query)
 	searchTerm = strings.ReplaceAll(searchTerm, "wikipedia", "")
 	searchURL := fmt.Sprintf("https://en.wikipedia.org/w/index.php?search=%s", url.QueryEscape(searchTerm))
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("User-Agent", "MargrafBot/1.0 (Educational Research)")
 
-	resp, err := s.Client.Do(req)
+	resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, err
 	}
@@ -197,13 +225,13 @@ func (s *WebSearcher) searchWikipediaFallback(query string) ([]SearchResult, err
 }
 
 // searchDuckDuckGo performs a DuckDuckGo search
-func (s *WebSearcher) searchDuckDuckGo(query string) ([]SearchResult, error) {
+func (s *WebSearcher) searchDuckDuckGo(ctx context.Context, query string) ([]SearchResult, error) {
 	baseURL := "https://html.duckduckgo.com/html/"
 
 	vals := url.Values{}
 	vals.Add("q", query)
 
-	req, err := http.NewRequest("POST", baseURL, strings.NewReader(vals.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, strings.NewReader(vals.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -211,7 +239,7 @@ func (s *WebSearcher) searchDuckDuckGo(query string) ([]SearchResult, error) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 
-	resp, err := s.Client.Do(req)
+	resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, err
 	}
@@ -1,12 +1,17 @@
 package scraper
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"margraf/clock"
+	"margraf/config"
+	"margraf/retry"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -15,6 +20,9 @@ import (
 type SocialScraper struct {
 	Client         *http.Client
 	WebSearcher    *WebSearcher
+	Reddit         *RedditAuth // OAuth app credentials (nil = anonymous endpoint)
+	Discord        *DiscordBot // Bot that reads Discord channels (nil = Discord off)
+	mu             sync.Mutex // Guards the fields below; crawls run concurrently
 	lastRequestAt  time.Time
 	redditRequests int
 }
@@ -22,9 +30,11 @@ type SocialScraper struct {
 func NewSocialScraper() *SocialScraper {
 	return &SocialScraper{
 		Client: &http.Client{
-			Timeout: 15 * time.Second,
+			Timeout: config.Timeout(config.Global.Timeouts.HTTP, 15*time.Second),
 		},
 		WebSearcher:    NewWebSearcher(),
+		Reddit:         RedditAuthFromEnv(),
+		Discord:        DiscordBotFromEnv(),
 		lastRequestAt:  time.Time{},
 		redditRequests: 0,
 	}
@@ -32,13 +42,14 @@ func NewSocialScraper() *SocialScraper {
 
 // rateLimit ensures we don't hammer APIs
 func (s *SocialScraper) rateLimit(minDelay time.Duration) {
-	if !s.lastRequestAt.IsZero() {
-		elapsed := time.Since(s.lastRequestAt)
-		if elapsed < minDelay {
-			time.Sleep(minDelay - elapsed)
-		}
-	}
-	s.lastRequestAt = time.Now()
+	time.Sleep(reserveSlot(&s.mu, &s.lastRequestAt, minDelay))
+}
+
+// countRedditRequest tallies a request to Reddit
+func (s *SocialScraper) countRedditRequest() {
+	s.mu.Lock()
+	s.redditRequests++
+	s.mu.Unlock()
 }
 
 type RedditListing struct {
@@ -64,31 +75,45 @@ type SocialPost struct {
 	Time     time.Time
 }
 
-// FetchRedditPosts searches Reddit for a topic and returns recent posts.
-func (s *SocialScraper) FetchRedditPosts(topic string, limit int) ([]SocialPost, error) {
-	s.rateLimit(2 * time.Second) // Reddit requires 2s between requests
-	s.redditRequests++
-
-	encoded := url.QueryEscape(topic)
-	apiURL := fmt.Sprintf("https://www.reddit.com/search.json?q=%s&sort=new&limit=%d&t=week", encoded, limit)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return nil, err
+// FetchRedditPosts searches Reddit for a topic and returns recent posts,
+// through the official API when OAuth credentials are set (see
+// RedditAuthFromEnv) and the anonymous search.json endpoint otherwise.
+func (s *SocialScraper) FetchRedditPosts(ctx context.Context, topic string, limit int) ([]SocialPost, error) {
+	query := url.Values{
+		"q":     {topic},
+		"sort":  {"new"},
+		"limit": {fmt.Sprint(limit)},
+		"t":     {"week"},
 	}
 
-	// Reddit requires a unique, descriptive User-Agent
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; MargrafBot/2.0; +Educational Research)")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := s.Client.Do(req)
+	var resp *http.Response
+	var err error
+	if s.Reddit != nil {
+		s.rateLimit(redditOAuthGap)
+		s.countRedditRequest()
+		query.Set("raw_json", "1") // Unescaped text, as search.json returns it
+		resp, err = s.searchRedditOAuth(ctx, query)
+	} else {
+		s.rateLimit(redditAnonGap) // Reddit requires 2s between anonymous requests
+		s.countRedditRequest()
+		apiURL := fmt.Sprintf("https://www.reddit.com/search.json?q=%s&sort=new&limit=%d&t=week", url.QueryEscape(topic), limit)
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		// Reddit requires a unique, descriptive User-Agent
+		req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; MargrafBot/2.0; +Educational Research)")
+		req.Header.Set("Accept", "application/json")
+		resp, err = retry.DoRequest(s.Client, req, retry.HTTP)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("reddit request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 429 {
-		return nil, fmt.Errorf("reddit rate limited (too many requests)")
+		return nil, redditRateLimited(resp)
 	}
 
 	if resp.StatusCode != 200 {
@@ -123,7 +148,7 @@ func (s *SocialScraper) FetchRedditPosts(topic string, limit int) ([]SocialPost,
 			User:     "u/" + d.Author,
 			Content:  content,
 			URL:      "https://reddit.com" + d.Permalink,
-			Time:     time.Unix(int64(d.Created), 0),
+			Time:     time.Unix(int64(d.Created), 0).UTC(),
 		})
 	}
 
@@ -131,19 +156,19 @@ func (s *SocialScraper) FetchRedditPosts(topic string, limit int) ([]SocialPost,
 }
 
 // FetchHackerNewsPosts searches Hacker News using Algolia API
-func (s *SocialScraper) FetchHackerNewsPosts(topic string, limit int) ([]SocialPost, error) {
+func (s *SocialScraper) FetchHackerNewsPosts(ctx context.Context, topic string, limit int) ([]SocialPost, error) {
 	s.rateLimit(1 * time.Second)
 
 	encoded := url.QueryEscape(topic)
 	apiURL := fmt.Sprintf("https://hn.algolia.com/api/v1/search?query=%s&tags=(story,comment)&hitsPerPage=%d", encoded, limit)
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("User-Agent", "MargrafBot/2.0")
 
-	resp, err := s.Client.Do(req)
+	resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, fmt.Errorf("hacker news request failed: %w", err)
 	}
@@ -201,7 +226,7 @@ func (s *SocialScraper) FetchHackerNewsPosts(topic string, limit int) ([]SocialP
 }
 
 // FetchTwitterViaNitter uses Nitter (Twitter frontend) to get tweets without API
-func (s *SocialScraper) FetchTwitterViaNitter(topic string, limit int) ([]SocialPost, error) {
+func (s *SocialScraper) FetchTwitterViaNitter(ctx context.Context, topic string, limit int) ([]SocialPost, error) {
 	s.rateLimit(2 * time.Second)
 
 	// Try multiple Nitter instances in case one is down
@@ -213,26 +238,29 @@ func (s *SocialScraper) FetchTwitterViaNitter(topic string, limit int) ([]Social
 
 	var lastErr error
 	for _, instance := range nitterInstances {
-		posts, err := s.fetchFromNitterInstance(instance, topic, limit)
+		posts, err := s.fetchFromNitterInstance(ctx, instance, topic, limit)
 		if err == nil && len(posts) > 0 {
 			return posts, nil
 		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		lastErr = err
 	}
 
 	return nil, fmt.Errorf("all nitter instances failed: %w", lastErr)
 }
 
-func (s *SocialScraper) fetchFromNitterInstance(instance, topic string, limit int) ([]SocialPost, error) {
+func (s *SocialScraper) fetchFromNitterInstance(ctx context.Context, instance, topic string, limit int) ([]SocialPost, error) {
 	searchURL := fmt.Sprintf("https://%s/search?f=tweets&q=%s", instance, url.QueryEscape(topic))
 
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36")
 
-	resp, err := s.Client.Do(req)
+	resp, err := retry.DoRequest(s.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, err
 	}
@@ -263,7 +291,7 @@ func (s *SocialScraper) fetchFromNitterInstance(instance, topic string, limit in
 				User:     strings.TrimSpace(username),
 				Content:  strings.TrimSpace(tweetText),
 				URL:      "https://" + instance + tweetLink,
-				Time:     time.Now(),
+				Time:     clock.Now(),
 			})
 		}
 	})
@@ -272,12 +300,12 @@ func (s *SocialScraper) fetchFromNitterInstance(instance, topic string, limit in
 }
 
 // FetchYouTubeComments searches YouTube for videos and extracts comments from description
-func (s *SocialScraper) FetchYouTubeComments(topic string, limit int) ([]SocialPost, error) {
+func (s *SocialScraper) FetchYouTubeComments(ctx context.Context, topic string, limit int) ([]SocialPost, error) {
 	s.rateLimit(1 * time.Second)
 
 	// Use web search to find YouTube videos
 	query := fmt.Sprintf("site:youtube.com %s", topic)
-	results, err := s.WebSearcher.Search(query)
+	results, err := s.WebSearcher.Search(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -294,7 +322,7 @@ func (s *SocialScraper) FetchYouTubeComments(topic string, limit int) ([]SocialP
 				User:     "Video",
 				Content:  res.Title + " - " + res.Snippet,
 				URL:      res.Link,
-				Time:     time.Now(),
+				Time:     clock.Now(),
 			})
 		}
 	}
@@ -303,11 +331,11 @@ func (s *SocialScraper) FetchYouTubeComments(topic string, limit int) ([]SocialP
 }
 
 // FetchWebMentions finds content on specific social platforms using web search.
-func (s *SocialScraper) FetchWebMentions(platformName, domain, topic string, limit int) ([]SocialPost, error) {
+func (s *SocialScraper) FetchWebMentions(ctx context.Context, platformName, domain, topic string, limit int) ([]SocialPost, error) {
 	s.rateLimit(1 * time.Second)
 
 	query := fmt.Sprintf("site:%s \"%s\"", domain, topic)
-	results, err := s.WebSearcher.Search(query)
+	results, err := s.WebSearcher.Search(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -337,7 +365,7 @@ func (s *SocialScraper) FetchWebMentions(platformName, domain, topic string, lim
 			User:     user,
 			Content:  res.Snippet,
 			URL:      res.Link,
-			Time:     time.Now(),
+			Time:     clock.Now(),
 		})
 	}
 	return posts, nil
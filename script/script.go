@@ -0,0 +1,287 @@
+// Package script runs console input beyond single commands: several commands
+// on one line separated by ";", macros defined with "name = cmd; cmd" and
+// kept across restarts, and command files run with "run <file>".
+package script
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margraf/logger"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxDepth bounds nested macros and command files, so a macro or script
+// that runs itself stops instead of looping
+const maxDepth = 16
+
+// Words the runner handles itself, which macros can't take as names
+var reserved = map[string]bool{"run": true, "sleep": true, "macros": true}
+
+var (
+	macroName  = regexp.MustCompile(`^[A-Za-z_][\w-]*$`)
+	definition = regexp.MustCompile(`^([A-Za-z_][\w-]*)\s*=\s*(.*)$`)
+	argument   = regexp.MustCompile(`\$(\d|\*)`)
+)
+
+// Macro is a named line of commands. "$1" to "$9" in Body are replaced by
+// the arguments it is called with, "$*" by all of them.
+type Macro struct {
+	Name string `json:"name"`
+	Body string `json:"body"`
+}
+
+// Macros keeps the defined macros, saved to a file after every change
+type Macros struct {
+	mu   sync.Mutex
+	path string // "" = in memory only
+	defs map[string]string
+}
+
+// LoadMacros opens the macro file at path (missing = none). An empty path
+// keeps macros in memory until the process exits.
+func LoadMacros(path string) (*Macros, error) {
+	m := &Macros{path: path, defs: make(map[string]string)}
+	if path == "" {
+		return m, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Macro
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, macro := range list {
+		m.defs[macro.Name] = macro.Body
+	}
+	return m, nil
+}
+
+// Set defines a macro, replacing one of the same name. An empty body
+// removes it.
+func (m *Macros) Set(name, body string) error {
+	if reserved[name] {
+		return fmt.Errorf("%q is reserved", name)
+	}
+	if !macroName.MatchString(name) {
+		return fmt.Errorf("invalid macro name %q", name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	body = strings.TrimSpace(body)
+	if body == "" {
+		if _, ok := m.defs[name]; !ok {
+			return fmt.Errorf("no macro %q", name)
+		}
+		delete(m.defs, name)
+	} else {
+		m.defs[name] = body
+	}
+	return m.saveLocked()
+}
+
+// Get returns a macro's body
+func (m *Macros) Get(name string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	body, ok := m.defs[name]
+	return body, ok
+}
+
+// List returns the macros by name
+func (m *Macros) List() []Macro {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listLocked()
+}
+
+func (m *Macros) listLocked() []Macro {
+	list := make([]Macro, 0, len(m.defs))
+	for name, body := range m.defs {
+		list = append(list, Macro{Name: name, Body: body})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// saveLocked writes the macros to their file (caller holds m.mu)
+func (m *Macros) saveLocked() error {
+	if m.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m.listLocked(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// Runner runs console input. Lines are split into commands on ";" (outside
+// double quotes). "name = ..." defines a macro, "run <file>" runs a command
+// file and "sleep <duration>" pauses a demo; a macro's name runs its
+// commands. Everything else goes to Exec.
+type Runner struct {
+	Macros *Macros
+	Exec   func(command string)
+}
+
+// Run runs one line of input
+func (r *Runner) Run(line string) error {
+	return r.run(line, nil, 0)
+}
+
+// RunFile runs a command file: one line of input per line, with blank lines
+// and "#" comments skipped. An error stops the file.
+func (r *Runner) RunFile(path string) error {
+	return r.runFile(path, nil, 0)
+}
+
+func (r *Runner) run(line string, active []string, depth int) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	if depth > maxDepth {
+		return fmt.Errorf("macros and scripts nested more than %d deep", maxDepth)
+	}
+	if m := definition.FindStringSubmatch(line); m != nil {
+		if err := r.Macros.Set(m[1], m[2]); err != nil {
+			return fmt.Errorf("macro %s: %w", m[1], err)
+		}
+		if strings.TrimSpace(m[2]) == "" {
+			logger.Success("Macro %s removed", m[1])
+		} else {
+			logger.Success("Macro %s defined", m[1])
+		}
+		return nil
+	}
+
+	for _, command := range splitCommands(line) {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			continue
+		}
+		name, args := fields[0], fields[1:]
+		switch {
+		case name == "run":
+			if len(args) != 1 {
+				return fmt.Errorf("usage: run <file>")
+			}
+			if err := r.runFile(args[0], active, depth+1); err != nil {
+				return err
+			}
+		case name == "sleep":
+			d, err := parseSleep(args)
+			if err != nil {
+				return err
+			}
+			time.Sleep(d)
+		case r.expandable(name, active):
+			body, _ := r.Macros.Get(name)
+			if err := r.run(expandArgs(body, args), append(active, name), depth+1); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		default:
+			r.Exec(command)
+		}
+	}
+	return nil
+}
+
+// expandable reports whether name is a macro outside its own expansion, so
+// "show = show; stress" runs the built-in show
+func (r *Runner) expandable(name string, active []string) bool {
+	if r.Macros == nil {
+		return false
+	}
+	for _, a := range active {
+		if a == name {
+			return false
+		}
+	}
+	_, ok := r.Macros.Get(name)
+	return ok
+}
+
+func (r *Runner) runFile(path string, active []string, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("macros and scripts nested more than %d deep", maxDepth)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	logger.Info(logger.StatusInit, "Running %s...", path)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if err := r.run(scanner.Text(), active, depth); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// splitCommands splits a line on ";" outside double quotes
+func splitCommands(line string) []string {
+	var commands []string
+	var b strings.Builder
+	quoted := false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ';' && !quoted:
+			commands = append(commands, strings.TrimSpace(b.String()))
+			b.Reset()
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return append(commands, strings.TrimSpace(b.String()))
+}
+
+// expandArgs replaces $1..$9 and $* in a macro body
+func expandArgs(body string, args []string) string {
+	return argument.ReplaceAllStringFunc(body, func(ref string) string {
+		if ref == "$*" {
+			return strings.Join(args, " ")
+		}
+		i, _ := strconv.Atoi(ref[1:])
+		if i < 1 || i > len(args) {
+			return ""
+		}
+		return args[i-1]
+	})
+}
+
+// parseSleep reads "2s", "500ms" or plain seconds
+func parseSleep(args []string) (time.Duration, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("usage: sleep <duration>, e.g. sleep 2s")
+	}
+	if secs, err := strconv.ParseFloat(args[0], 64); err == nil && secs >= 0 {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("sleep %q: want a duration, e.g. 2s", args[0])
+	}
+	return d, nil
+}
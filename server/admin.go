@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"margraf/logger"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxAdminFailures is how many failed admin attempts an IP may make per
+// minute; further attempts are refused, right token or not, until they age out
+const maxAdminFailures = 5
+
+// AdminAction runs one operational command (save, decay, discover, ...).
+// args are the request's string parameters: the query string over HTTP, the
+// payload's string fields over WebSocket. The result is sent back as JSON.
+type AdminAction func(ctx context.Context, args map[string]string) (interface{}, error)
+
+// AdminResultPayload is the payload of "admin_result" responses
+type AdminResultPayload struct {
+	Action string      `json:"action"`
+	Result interface{} `json:"result"`
+}
+
+// SetAdmin enables the admin actions for clients presenting token. An empty
+// token leaves them disabled.
+func (h *Hub) SetAdmin(token string, actions map[string]AdminAction) {
+	h.adminToken = token
+	h.adminActions = actions
+}
+
+// isAdmin reports whether a client key (see clientKey) carries the admin token
+func (h *Hub) isAdmin(key string) bool {
+	token, ok := strings.CutPrefix(key, "token:")
+	return ok && h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
+
+// runAdmin authorizes and runs an admin action. key comes from headerKey, so
+// only a token in the Authorization header counts. An IP with too many
+// failed attempts is refused until its failures age out, whatever token it
+// presents.
+func (h *Hub) runAdmin(ctx context.Context, ip, key, action string, args map[string]string) (interface{}, string, error) {
+	if h.adminToken == "" {
+		return nil, ErrCodeUnavailable, fmt.Errorf("admin actions are disabled (set MARGRAF_ADMIN_TOKEN)")
+	}
+	if h.adminFailures.Exhausted(ip) {
+		return nil, ErrCodeRateLimited, fmt.Errorf("too many failed admin attempts; try again later")
+	}
+	if !h.isAdmin(key) {
+		h.adminFailures.Allow(ip)
+		return nil, ErrCodeForbidden, fmt.Errorf("admin token required (Authorization: Bearer)")
+	}
+	run, ok := h.adminActions[action]
+	if !ok {
+		return nil, ErrCodeNotFound, fmt.Errorf("unknown admin action %q (have %s)", action, strings.Join(h.adminActionNames(), ", "))
+	}
+	logger.Info(logger.StatusGlob, "Admin: %s %v", action, args)
+	result, err := run(ctx, args)
+	if err != nil {
+		return nil, ErrCodeInternal, err
+	}
+	return result, "", nil
+}
+
+func (h *Hub) adminActionNames() []string {
+	names := make([]string, 0, len(h.adminActions))
+	for name := range h.adminActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HandleAdmin serves /admin/<action>. Actions that change state need POST;
+// GET is enough for read-only ones such as diagnostics.
+func (h *Hub) HandleAdmin(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(r.URL.Path, "/admin/")
+	if r.Method != http.MethodPost && !(r.Method == http.MethodGet && action == "diagnostics") {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use POST"})
+		return
+	}
+
+	req := AdminRequest{Action: action, Args: make(map[string]string)}
+	for k, v := range r.URL.Query() {
+		if k != "token" && len(v) > 0 {
+			req.Args[k] = v[0]
+		}
+	}
+	if err := req.validate(); err != nil {
+		writeInvalid(w, err)
+		return
+	}
+
+	result, code, err := h.runAdmin(r.Context(), clientIP(r), headerKey(r), req.Action, req.Args)
+	if err != nil {
+		writeError(w, code, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, AdminResultPayload{Action: req.Action, Result: result})
+}
+
+// handleAdmin runs {"type": "admin", "payload": {"action": "save", ...}} for
+// connections opened with the admin token in their Authorization header
+func (h *Hub) handleAdmin(sub *subscriber, msg IncomingMessage, ip, key string) {
+	var req AdminRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+
+	result, code, err := h.runAdmin(context.Background(), ip, key, req.Action, req.Args)
+	if err != nil {
+		replyError(sub, msg.ID, code, err.Error())
+		return
+	}
+	reply(sub, msg.ID, TypeAdminResult, AdminResultPayload{Action: req.Action, Result: result})
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"margraf/graph"
+	"net/http"
+	"strconv"
+)
+
+// SetAnalytics serves betweenness and hitting times over GET /api/centrality
+// and /api/hitting-time with the sampling settings opts returns, read on each
+// request so a config reload applies. Without it both use the defaults.
+func (h *Hub) SetAnalytics(opts func() graph.SamplingOptions) {
+	h.analytics = opts
+}
+
+// samplingOptions reads the sampling overrides of an analytics request
+func (h *Hub) samplingOptions(r *http.Request) (graph.SamplingOptions, error) {
+	var opts graph.SamplingOptions
+	if h.analytics != nil {
+		opts = h.analytics()
+	}
+	q := r.URL.Query()
+	switch q.Get("mode") {
+	case "":
+	case "exact":
+		opts.Exact = true
+	case "sampled":
+		opts.Sampled = true
+	default:
+		return opts, &FieldError{Field: "mode", Message: "must be exact or sampled"}
+	}
+	for field, into := range map[string]*int{"sources": &opts.Sources, "walks": &opts.Walks} {
+		if s := q.Get(field); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return opts, &FieldError{Field: field, Message: "must be a positive number"}
+			}
+			*into = n
+		}
+	}
+	if s := q.Get("seed"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return opts, &FieldError{Field: "seed", Message: "must be a number"}
+		}
+		opts.Seed = n
+	}
+	return opts, nil
+}
+
+// HandleCentrality serves GET /api/centrality: the nodes most shortest paths
+// run through, computed exactly on small graphs and sampled on large ones
+func (h *Hub) HandleCentrality(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+		return
+	}
+	if h.graph == nil {
+		writeError(w, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+	opts, err := h.samplingOptions(r)
+	if err != nil {
+		writeInvalid(w, err)
+		return
+	}
+	top := 20
+	if s := r.URL.Query().Get("top"); s != "" {
+		if top, err = strconv.Atoi(s); err != nil || top < 0 {
+			writeInvalid(w, &FieldError{Field: "top", Message: "must be a number, 0 for every node"})
+			return
+		}
+	}
+	report, err := h.graph.Betweenness(r.Context(), opts, top)
+	if err != nil {
+		writeError(w, ErrCodeUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// HandleHittingTime serves GET /api/hitting-time: the expected steps a
+// weighted random walk from one node takes to reach another
+func (h *Hub) HandleHittingTime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+		return
+	}
+	if h.graph == nil {
+		writeError(w, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+	q := r.URL.Query()
+	from, to := q.Get("from"), q.Get("to")
+	if from == "" {
+		writeInvalid(w, &FieldError{Field: "from", Message: "required"})
+		return
+	}
+	if to == "" {
+		writeInvalid(w, &FieldError{Field: "to", Message: "required"})
+		return
+	}
+	opts, err := h.samplingOptions(r)
+	if err != nil {
+		writeInvalid(w, err)
+		return
+	}
+	// Unknown nodes, unconnected ones and walks that never arrive are all "no answer"
+	ht, err := h.graph.HittingTime(r.Context(), from, to, opts)
+	if err != nil {
+		writeError(w, ErrCodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ht)
+}
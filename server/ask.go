@@ -0,0 +1,54 @@
+package server
+
+import "context"
+
+// AskFunc answers a question, passing the answer to onChunk as it is written
+type AskFunc func(ctx context.Context, question string, onChunk func(string)) (string, error)
+
+// SetAsk answers {"type": "ask", "payload": {"question": "..."}} with ask.
+// Without it, ask requests are refused.
+func (h *Hub) SetAsk(ask AskFunc) {
+	h.ask = ask
+}
+
+// handleAsk streams an answer as ask_chunk frames, then sends ask_answer with
+// the whole text. Answering runs in the background so the connection's other
+// requests are not held up, and stops if the connection closes.
+func (h *Hub) handleAsk(sub *subscriber, msg IncomingMessage) {
+	if h.ask == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Questions are not configured")
+		return
+	}
+	var req AskRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	question := req.Question
+
+	go func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		answer, err := h.ask(ctx, question, func(text string) {
+			if !h.replyLive(sub, msg.ID, TypeAskChunk, AskChunkPayload{Text: text}) {
+				cancel()
+			}
+		})
+		if err != nil {
+			h.replyLive(sub, msg.ID, TypeError, ErrorPayload{Code: ErrCodeInternal, Message: err.Error(), RequestID: msg.ID})
+			return
+		}
+		h.replyLive(sub, msg.ID, TypeAskAnswer, AskAnswerPayload{Question: question, Answer: answer})
+	}()
+}
+
+// replyLive replies from outside the connection's read loop, reporting false
+// once the connection has closed
+func (h *Hub) replyLive(sub *subscriber, id, msgType string, payload interface{}) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.subscribers[sub] {
+		return false
+	}
+	reply(sub, id, msgType, payload)
+	return true
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"margraf/graph"
+	"time"
+)
+
+// defaultCalendarDays is how far ahead get_calendar looks without "days"
+const defaultCalendarDays = 30
+
+// handleGetCalendar replies with the scheduled events of the payload's
+// node_ids, or of the nodes the connection watches, over the next "days"
+func (h *Hub) handleGetCalendar(sub *subscriber, msg IncomingMessage) {
+	if h.graph == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+
+	var req CalendarRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	nodeIDs := req.NodeIDs
+	if len(nodeIDs) == 0 && req.NodeID != "" {
+		nodeIDs = []string{req.NodeID}
+	} else if len(nodeIDs) == 0 {
+		h.mu.Lock()
+		nodeIDs = sortedKeys(sub.watching)
+		h.mu.Unlock()
+	}
+
+	days := defaultCalendarDays
+	if req.Days > 0 {
+		days = req.Days
+	}
+	within := time.Duration(days) * 24 * time.Hour
+
+	payload := CalendarPayload{NodeIDs: nodeIDs, Days: days, Events: []graph.ScheduledEvent{}}
+	for _, id := range nodeIDs {
+		payload.Events = append(payload.Events, h.graph.UpcomingEvents(id, within)...)
+	}
+	graph.SortEvents(payload.Events)
+	reply(sub, msg.ID, TypeCalendar, payload)
+}
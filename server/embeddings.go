@@ -0,0 +1,96 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"margraf/graph"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// embeddingFile serves the node embeddings last saved to disk, reloading them
+// when the file changes
+type embeddingFile struct {
+	mu       sync.Mutex
+	path     string
+	modified time.Time
+	latest   *graph.Embeddings
+}
+
+// SetEmbeddings serves the node embeddings saved at path over GET
+// /api/embeddings. Without it the endpoint reports embeddings unavailable.
+func (h *Hub) SetEmbeddings(path string) {
+	h.embeddings.mu.Lock()
+	defer h.embeddings.mu.Unlock()
+	h.embeddings.path = path
+	h.embeddings.latest = nil
+}
+
+// load returns the saved embeddings, reading the file again if it changed
+func (f *embeddingFile) load() (*graph.Embeddings, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.path == "" {
+		return nil, fmt.Errorf("embeddings are not configured")
+	}
+	info, err := os.Stat(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no embeddings yet; run 'embed' or the embeddings job")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if f.latest == nil || !info.ModTime().Equal(f.modified) {
+		e, err := graph.LoadEmbeddings(f.path)
+		if err != nil {
+			return nil, err
+		}
+		f.latest, f.modified = e, info.ModTime()
+	}
+	return f.latest, nil
+}
+
+// HandleEmbeddings serves GET /api/embeddings: every node's vector, or those
+// of the comma-separated ids, as JSON or with format=csv as CSV
+func (h *Hub) HandleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+		return
+	}
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeInvalid(w, &FieldError{Field: "format", Message: "must be json or csv"})
+		return
+	}
+	e, err := h.embeddings.load()
+	if err != nil {
+		writeError(w, ErrCodeUnavailable, err.Error())
+		return
+	}
+	if ids := q.Get("ids"); ids != "" {
+		subset := *e
+		subset.Nodes = nil
+		for _, id := range strings.Split(ids, ",") {
+			id = strings.TrimSpace(id)
+			n, ok := e.Lookup(id)
+			if !ok {
+				writeError(w, ErrCodeNotFound, fmt.Sprintf("no embedding for node %q", id))
+				return
+			}
+			subset.Nodes = append(subset.Nodes, n)
+		}
+		e = &subset
+	}
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		e.WriteCSV(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"margraf/pipeline"
+	"margraf/syserr"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// /healthz and /readyz let Docker or Kubernetes manage a headless instance.
+// /healthz answers while the process can serve HTTP at all, so a failing
+// liveness probe means restart it. /readyz answers 200 only once the graph
+// is loaded, the hub dispatches broadcasts and at least one data pipeline
+// is working, and 503 otherwise, so traffic waits for a seeding instance and
+// moves off one whose feeds have all failed.
+
+// readyWindow is how long a pipeline's failure keeps it unhealthy, as on the
+// TUI health pane
+const readyWindow = 15 * time.Minute
+
+// dataPipelines are the pipelines that bring data in, with the subsystem
+// their failures are reported under
+var dataPipelines = map[string]syserr.Module{
+	pipeline.News:     syserr.ModuleNews,
+	pipeline.Social:   syserr.ModuleSocial,
+	pipeline.Market:   syserr.ModuleMarket,
+	pipeline.Refresh:  syserr.ModuleDataSource,
+	pipeline.Calendar: syserr.ModuleDataSource,
+}
+
+// HealthPayload is the body of /healthz
+type HealthPayload struct {
+	Status string `json:"status"` // Always "ok"
+	Uptime string `json:"uptime"`
+}
+
+// ReadyCheck is one readiness condition
+type ReadyCheck struct {
+	Name    string `json:"name"` // graph, hub or pipelines
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// ReadinessPayload is the body of /readyz
+type ReadinessPayload struct {
+	Ready  bool         `json:"ready"`
+	Checks []ReadyCheck `json:"checks"`
+}
+
+// Readiness checks whether the instance can serve: the graph holds nodes,
+// Run is dispatching broadcasts and a data pipeline is enabled without an
+// error or critical failure in the last readyWindow
+func (h *Hub) Readiness() ReadinessPayload {
+	checks := []ReadyCheck{h.graphReady(), h.hubReady(), pipelinesReady()}
+	ready := true
+	for _, c := range checks {
+		ready = ready && c.OK
+	}
+	return ReadinessPayload{Ready: ready, Checks: checks}
+}
+
+func (h *Hub) graphReady() ReadyCheck {
+	c := ReadyCheck{Name: "graph"}
+	if h.graph == nil {
+		c.Message = "no graph attached"
+		return c
+	}
+	nodes, edges := h.graph.Counts()
+	if nodes == 0 {
+		c.Message = "graph is empty (still seeding?)"
+		return c
+	}
+	c.OK = true
+	c.Message = fmt.Sprintf("%d nodes, %d edges", nodes, edges)
+	return c
+}
+
+func (h *Hub) hubReady() ReadyCheck {
+	if !h.running.Load() {
+		return ReadyCheck{Name: "hub", Message: "not dispatching broadcasts"}
+	}
+	return ReadyCheck{Name: "hub", OK: true, Message: fmt.Sprintf("%d clients", h.subscriberCount())}
+}
+
+// pipelinesReady passes when at least one data pipeline is healthy
+func pipelinesReady() ReadyCheck {
+	failing := make(map[syserr.Module]syserr.Event)
+	for _, e := range syserr.Health(readyWindow) {
+		if e.Severity != syserr.SeverityWarning {
+			failing[e.Module] = e
+		}
+	}
+	var healthy, down []string
+	for _, name := range sortedKeys(dataPipelines) {
+		switch e, failed := failing[dataPipelines[name]]; {
+		case !pipeline.Enabled(name):
+			down = append(down, name+" disabled")
+		case failed:
+			down = append(down, fmt.Sprintf("%s failing (%s: %s)", name, e.Op, e.Message))
+		default:
+			healthy = append(healthy, name)
+		}
+	}
+	c := ReadyCheck{Name: "pipelines", OK: len(healthy) > 0}
+	switch {
+	case len(healthy) == 0:
+		c.Message = "no healthy data pipeline: " + strings.Join(down, "; ")
+	case len(down) == 0:
+		c.Message = strings.Join(healthy, ", ") + " healthy"
+	default:
+		c.Message = strings.Join(healthy, ", ") + " healthy; " + strings.Join(down, "; ")
+	}
+	return c
+}
+
+// HandleHealth serves /healthz: 200 while the process serves HTTP
+func (h *Hub) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	if !probeMethod(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, HealthPayload{Status: "ok", Uptime: time.Since(h.started).Round(time.Second).String()})
+}
+
+// HandleReady serves /readyz: 200 when Readiness passes, 503 otherwise
+func (h *Hub) HandleReady(w http.ResponseWriter, r *http.Request) {
+	if !probeMethod(w, r) {
+		return
+	}
+	ready := h.Readiness()
+	status := http.StatusOK
+	if !ready.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, ready)
+}
+
+// probeMethod accepts GET and HEAD, answering other methods with 405
+func probeMethod(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+	return false
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"margraf/history"
+	"net/http"
+	"time"
+)
+
+// HistoryPayload is the answer of GET /api/history: the recorded days, or the
+// daily rows of a node or of the edges between two nodes
+type HistoryPayload struct {
+	Days  []history.Day     `json:"days,omitempty"`
+	Nodes []history.NodeRow `json:"nodes,omitempty"`
+	Edges []history.EdgeRow `json:"edges,omitempty"`
+}
+
+// SetHistory serves the end-of-day rows in store over GET /api/history.
+// Without it the endpoint reports history unavailable.
+func (h *Hub) SetHistory(store *history.Store) {
+	h.history = store
+}
+
+// HandleHistory serves GET /api/history. With node it returns that node's
+// daily health, price and stress; with source and target, the daily weight
+// and status of the edges between them; with neither, the recorded days.
+// since and until, as YYYY-MM-DD, bound the rows returned.
+func (h *Hub) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+		return
+	}
+	if h.history == nil {
+		writeError(w, ErrCodeUnavailable, "history is not configured")
+		return
+	}
+	q := r.URL.Query()
+	query := history.Query{NodeID: q.Get("node"), SourceID: q.Get("source"), TargetID: q.Get("target"), Since: q.Get("since"), Until: q.Get("until")}
+	for field, date := range map[string]string{"since": query.Since, "until": query.Until} {
+		if _, err := time.Parse(history.DateLayout, date); date != "" && err != nil {
+			writeInvalid(w, &FieldError{Field: field, Message: "must be YYYY-MM-DD"})
+			return
+		}
+	}
+	if query.NodeID != "" && (query.SourceID != "" || query.TargetID != "") {
+		writeInvalid(w, &FieldError{Field: "node", Message: "cannot be combined with source and target"})
+		return
+	}
+	if query.SourceID != "" && query.TargetID == "" {
+		writeInvalid(w, &FieldError{Field: "target", Message: "required with source"})
+		return
+	}
+	if query.TargetID != "" && query.SourceID == "" {
+		writeInvalid(w, &FieldError{Field: "source", Message: "required with target"})
+		return
+	}
+
+	var payload HistoryPayload
+	var err error
+	switch {
+	case query.NodeID != "":
+		payload.Nodes, err = h.history.Nodes(query)
+	case query.SourceID != "":
+		payload.Edges, err = h.history.Edges(query)
+	default:
+		payload.Days, err = h.history.Days()
+	}
+	if err != nil {
+		writeError(w, ErrCodeInternal, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
@@ -0,0 +1,63 @@
+package server
+
+import "margraf/graph"
+
+// handleAddNote attaches an analyst note to a node or edge and replies with
+// it. Connections watching either end get it as a watch_event.
+func (h *Hub) handleAddNote(sub *subscriber, msg IncomingMessage) {
+	if h.graph == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+	var req AddNoteRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	note, err := h.graph.AddNote(req.Note())
+	if err != nil {
+		replyError(sub, msg.ID, ErrCodeNotFound, err.Error())
+		return
+	}
+	reply(sub, msg.ID, TypeNote, note)
+}
+
+// handleGetNotes replies with the notes on a node and its edges, or every
+// note, optionally only those with a tag, oldest first
+func (h *Hub) handleGetNotes(sub *subscriber, msg IncomingMessage) {
+	if h.graph == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+	var req NotesRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	notes := h.graph.FindNotes(func(n graph.Note) bool {
+		if req.NodeID != "" && n.NodeID != req.NodeID && n.SourceID != req.NodeID && n.TargetID != req.NodeID {
+			return false
+		}
+		return req.Tag == "" || n.HasTag(req.Tag)
+	})
+	if notes == nil {
+		notes = []graph.Note{}
+	}
+	reply(sub, msg.ID, TypeNotes, notes)
+}
+
+// handleDeleteNote removes a note and replies with it
+func (h *Hub) handleDeleteNote(sub *subscriber, msg IncomingMessage) {
+	if h.graph == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+	var req DeleteNoteRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	note, err := h.graph.DeleteNote(req.NoteID)
+	if err != nil {
+		replyError(sub, msg.ID, ErrCodeNotFound, err.Error())
+		return
+	}
+	reply(sub, msg.ID, TypeNote, note)
+}
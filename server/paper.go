@@ -0,0 +1,36 @@
+package server
+
+import (
+	"margraf/trading"
+	"net/http"
+)
+
+// SetPaper serves the paper trader's performance from perf over GET /paper
+// and get_paper_performance. Without it both report the trader unavailable.
+func (h *Hub) SetPaper(perf func() *trading.PaperPerformance) {
+	h.paper = perf
+}
+
+// HandlePaper serves GET /paper: cumulative PnL, open risk, hit rate and the
+// daily NAV of the paper trader
+func (h *Hub) HandlePaper(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+		return
+	}
+	if h.paper == nil {
+		writeError(w, ErrCodeUnavailable, "paper trading is not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.paper())
+}
+
+// handleGetPaperPerformance replies with the paper trader's performance
+func (h *Hub) handleGetPaperPerformance(sub *subscriber, msg IncomingMessage) {
+	if h.paper == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Paper trading is not configured")
+		return
+	}
+	reply(sub, msg.ID, TypePaperPerformance, h.paper())
+}
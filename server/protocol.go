@@ -0,0 +1,251 @@
+package server
+
+import (
+	"margraf/graph"
+	"time"
+)
+
+// ProtocolVersion is sent as "v" on every message. Version 2 made every
+// payload a JSON object or array (version 1 sent some as JSON-encoded
+// strings or plain text) and moved textual graph_update notices to graph_notice.
+const ProtocolVersion = 2
+
+// Message types sent by the server
+const (
+	TypeSystem             = "system"              // SystemPayload
+	TypeError              = "error"               // ErrorPayload
+	TypeGraphUpdate        = "graph_update"        // graph.GraphData
+	TypeGraphNotice        = "graph_notice"        // GraphNoticePayload
+	TypeNewsAlert          = "news_alert"          // NewsAlertPayload
+	TypeSocialPulse        = "social_pulse"        // SocialPulsePayload
+	TypeMentionSpike       = "mention_spike"       // MentionSpikePayload
+	TypeEdgeAnomaly        = "edge_anomaly"        // EdgeAnomalyPayload
+	TypeShockEvent         = "shock_event"         // ShockPayload
+	TypeShockTrace         = "shock_trace"         // simulation.Trace
+	TypeMarketUpdate       = "market_update"       // MarketUpdatePayload
+	TypeStressUpdate       = "stress_update"       // StressUpdatePayload
+	TypePortfolioUpdate    = "portfolio_update"    // PortfolioUpdatePayload
+	TypePaperPerformance   = "paper_performance"   // trading.PaperPerformance
+	TypePairAlert          = "pair_alert"          // PairAlertPayload
+	TypeCalendar           = "calendar"            // CalendarPayload
+	TypeTaskUpdate         = "task_update"         // task.Info
+	TypeTasks              = "tasks"               // []task.Info
+	TypeSystemError        = "system_error"        // syserr.Event
+	TypeSystemErrors       = "system_errors"       // []syserr.Event
+	TypeScenarioComparison = "scenario_comparison" // simulation.Comparison
+	TypeCompanyRelations   = "company_relations"   // graph.CompanyRelations
+	TypeNationRelations    = "nation_relations"    // graph.NationRelations
+	TypeCompaniesList      = "companies_list"      // []CompanySummary
+	TypeProjection         = "projection"          // graph.Projection
+	TypeHealthHistory      = "health_history"      // graph.HealthHistory
+	TypeImpact             = "impact"              // graph.Impact
+	TypeImpacts            = "impacts"             // []graph.Impact
+	TypeSession            = "session"             // Session
+	TypeAdminResult        = "admin_result"        // AdminResultPayload
+	TypeWatchEvent         = "watch_event"         // WatchEventPayload
+	TypeWatching           = "watching"            // WatchingPayload
+	TypeAskChunk           = "ask_chunk"           // AskChunkPayload
+	TypeAskAnswer          = "ask_answer"          // AskAnswerPayload
+	TypeNote               = "note"                // graph.Note
+	TypeNotes              = "notes"               // []graph.Note
+	TypeTimeline           = "timeline"            // TimelinePayload
+
+	TypeCompanyRelationsUpdate = "company_relations_update" // CompanyRelationsUpdatePayload
+)
+
+// SystemPayload is a connection status message
+type SystemPayload struct {
+	Message string `json:"message"`
+}
+
+// GraphNoticePayload reports a change to one node in words, e.g. a node
+// discovered in the news or a sentiment-driven health move
+type GraphNoticePayload struct {
+	NodeID  string            `json:"node_id"`
+	Name    string            `json:"name,omitempty"`
+	Message string            `json:"message"`
+	Health  *float64          `json:"health,omitempty"` // New health, when the notice is about one
+	Topics  []SubTopicPayload `json:"topics,omitempty"` // Sub-topics behind a social sentiment change, largest first
+}
+
+// SubTopicPayload is a group of similar posts within a social crawl
+type SubTopicPayload struct {
+	Label     string  `json:"label"`
+	Posts     int     `json:"posts"`
+	Sentiment float64 `json:"sentiment"`
+}
+
+// NewsAlertPayload is a headline the news engine is analyzing
+type NewsAlertPayload struct {
+	Title     string `json:"title"`
+	Link      string `json:"link,omitempty"`
+	Published string `json:"published,omitempty"` // As given by the feed
+}
+
+// SocialPulsePayload is one analyzed social media post
+type SocialPulsePayload struct {
+	Platform  string  `json:"platform"`
+	User      string  `json:"user"`
+	Content   string  `json:"content"`
+	Sentiment float64 `json:"sentiment"` // -1.0 to 1.0
+	URL       string  `json:"url,omitempty"`
+	Topic     string  `json:"topic,omitempty"`
+	NodeID    string  `json:"node_id,omitempty"` // Node of the topic
+}
+
+// MentionSpikePayload reports a node talked about far more this hour than
+// its hourly baseline, before the posts' sentiment is known
+type MentionSpikePayload struct {
+	NodeID   string  `json:"node_id"`
+	Name     string  `json:"name"`
+	Mentions int     `json:"mentions"` // Mentions in the current hour
+	Baseline float64 `json:"baseline"` // Mean mentions per hour
+	StdDev   float64 `json:"std_dev"`
+	Ratio    float64 `json:"ratio"`           // Mentions over baseline
+	Topic    string  `json:"topic,omitempty"` // Crawl that found the posts
+	Message  string  `json:"message"`
+}
+
+// EdgeAnomalyPayload reports an edge whose weight moved far outside its own
+// history, a relationship breaking down or surging that news may have missed
+type EdgeAnomalyPayload struct {
+	graph.EdgeAnomaly
+	SourceName string `json:"source_name"`
+	TargetName string `json:"target_name"`
+	Message    string `json:"message"`
+}
+
+// Shock kinds carried in ShockPayload
+const (
+	ShockNode     = "node"     // One node, e.g. from news
+	ShockRegion   = "region"   // Every node in a region or country
+	ShockMonetary = "monetary" // Currency devaluation or rate move
+	ShockScenario = "scenario" // A multi-step scenario
+	ShockBoost    = "boost"    // Positive shock
+)
+
+// ShockPayload is a shock applied to the graph
+type ShockPayload struct {
+	Kind        string   `json:"kind"`
+	Target      string   `json:"target,omitempty"`  // Node ID for node, monetary and boost shocks
+	Targets     []string `json:"targets,omitempty"` // Nodes hit by region and scenario shocks
+	Region      string   `json:"region,omitempty"`
+	Scenario    string   `json:"scenario,omitempty"`
+	Move        string   `json:"move,omitempty"` // Monetary move: devalue, hike or cut
+	Impact      float64  `json:"impact,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Commodity   string   `json:"commodity,omitempty"` // HS code the shock was limited to
+}
+
+// Watch event kinds carried in WatchEventPayload
+const (
+	WatchEdge        = "edge"         // An edge touching the node was added or reweighted
+	WatchEdgeRemoved = "edge_removed" // An edge touching the node was pruned
+	WatchHealth      = "health"       // The node's health changed
+	WatchNode        = "node"         // The node was added or its fields changed
+	WatchShock       = "shock"        // A shock, e.g. from a news impact, hit the node
+	WatchNotice      = "notice"       // A graph notice, e.g. a sentiment-driven health move
+	WatchMarket      = "market"       // A new market price
+	WatchVolume      = "volume"       // Social mentions spiked
+	WatchAnomaly     = "anomaly"      // An edge touching the node collapsed or spiked against its history
+	WatchNote        = "note"         // An analyst note was added to the node or one of its edges
+)
+
+// WatchEventPayload is one change touching a node a client watches
+type WatchEventPayload struct {
+	NodeID  string      `json:"node_id"` // The watched node
+	Kind    string      `json:"kind"`    // One of the Watch constants
+	Message string      `json:"message"`
+	Edge    *graph.Edge `json:"edge,omitempty"`   // For edge and edge_removed
+	Health  *float64    `json:"health,omitempty"` // New health, when known
+	Note    *graph.Note `json:"note,omitempty"`   // For note
+	Time    time.Time   `json:"time"`
+}
+
+// CalendarPayload is the timeline of scheduled events for some nodes,
+// soonest first
+type CalendarPayload struct {
+	NodeIDs []string               `json:"node_ids"`
+	Days    int                    `json:"days"` // How far ahead the timeline reaches
+	Events  []graph.ScheduledEvent `json:"events"`
+}
+
+// AskChunkPayload is the next piece of an answer being written; the frame
+// carries the ask request's ID
+type AskChunkPayload struct {
+	Text string `json:"text"`
+}
+
+// AskAnswerPayload is a finished answer. It is the whole text, including any
+// chunks dropped because the connection fell behind.
+type AskAnswerPayload struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// WatchingPayload lists the nodes a connection watches
+type WatchingPayload struct {
+	NodeIDs []string `json:"node_ids"`
+}
+
+// CompanyRelationsUpdatePayload is a change to the relations of a company the
+// connection requested with get_company_relations. Added and Removed apply on
+// top of the last company_relations or update received.
+type CompanyRelationsUpdatePayload struct {
+	CompanyID string           `json:"company_id"`
+	Added     []RelationChange `json:"added,omitempty"`
+	Removed   []RelationChange `json:"removed,omitempty"`
+}
+
+// RelationChange is one node joining or leaving a relations list
+type RelationChange struct {
+	Relation string `json:"relation"` // suppliers, clients, raw_materials, products, parents or subsidiaries
+	NodeID   string `json:"node_id"`
+	Name     string `json:"name,omitempty"`
+}
+
+// MarketUpdatePayload is a new price for a listed node
+type MarketUpdatePayload struct {
+	ID       string  `json:"id"`
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency"`
+	Change   float64 `json:"change"` // Daily change, 0.05 = +5%
+	Health   float64 `json:"health"`
+}
+
+// StressUpdatePayload is a new early-warning stress ranking
+type StressUpdatePayload struct {
+	Window    string             `json:"window"`
+	Nodes     []graph.NodeStress `json:"nodes"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// PairAlertPayload is a monitored pair whose price ratio moved past the
+// entry z-score
+type PairAlertPayload struct {
+	Asset1      string    `json:"asset1"`
+	Asset2      string    `json:"asset2"`
+	Ticker1     string    `json:"ticker1"`
+	Ticker2     string    `json:"ticker2"`
+	ZScore      float64   `json:"z_score"`
+	Entry       float64   `json:"entry"`       // Threshold crossed
+	Action      string    `json:"action"`      // Entry the pairs strategy would take: LONG_1_SHORT_2 or LONG_2_SHORT_1
+	Correlation float64   `json:"correlation"` // Blended with the graph prior
+	GraphPrior  float64   `json:"graph_prior"`
+	Spread      float64   `json:"spread"`     // Latest price ratio
+	PriceTime   time.Time `json:"price_time"` // Of the latest prices
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// PortfolioUpdatePayload is a portfolio's recomputed exposure to the graph
+type PortfolioUpdatePayload struct {
+	*graph.PortfolioRisk
+	Threshold float64   `json:"threshold"` // Share at which exposures are flagged as concentrated
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CompanySummary is an entry of companies_list
+type CompanySummary struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
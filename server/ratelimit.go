@@ -0,0 +1,170 @@
+package server
+
+import (
+	"expvar"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitMetrics counts allowed/limited requests per limiter, served at /debug/vars
+var rateLimitMetrics = expvar.NewMap("ratelimit")
+
+// bucketIdleTTL is how long an unused bucket is kept before being pruned
+const bucketIdleTTL = 10 * time.Minute
+
+// bucket is a token bucket for a single client key
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is a per-client token bucket limiter. A nil limiter allows everything.
+type RateLimiter struct {
+	name  string  // Metric prefix, e.g. "http" or "ws"
+	rate  float64 // Tokens refilled per second
+	burst float64 // Bucket capacity
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+// NewRateLimiter allows perMinute requests per client with bursts up to burst.
+// Returns nil (no limiting) when perMinute <= 0.
+func NewRateLimiter(name string, perMinute, burst int) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = perMinute
+	}
+	return &RateLimiter{
+		name:      name,
+		rate:      float64(perMinute) / 60,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// Allow consumes a token for key, reporting false when the client is over its limit
+func (l *RateLimiter) Allow(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	b := l.refill(key, now)
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	if now.Sub(l.lastPrune) > bucketIdleTTL {
+		l.prune(now)
+	}
+	l.mu.Unlock()
+
+	if allowed {
+		rateLimitMetrics.Add(l.name+"_allowed", 1)
+	} else {
+		rateLimitMetrics.Add(l.name+"_limited", 1)
+	}
+	return allowed
+}
+
+// Exhausted reports whether key is over its limit, without consuming a token
+func (l *RateLimiter) Exhausted(key string) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.refill(key, time.Now()).tokens < 1
+}
+
+// refill returns key's bucket topped up for the time since its last use;
+// caller holds l.mu
+func (l *RateLimiter) refill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+	return b
+}
+
+// prune drops idle buckets; caller holds l.mu
+func (l *RateLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > bucketIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// Middleware rejects over-limit requests with 429 Too Many Requests
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.allowClient(clientIP(r), clientKey(r)) {
+			w.Header().Set("Retry-After", "1")
+			writeError(w, ErrCodeRateLimited, "too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowClient consumes a token from the client's IP bucket and, for a token
+// client, from its token bucket as well. Tokens are not verified here, so the
+// IP limit always applies: a client inventing a new token per request would
+// otherwise get a full bucket each time.
+func (l *RateLimiter) allowClient(ip, key string) bool {
+	if !l.Allow(ip) {
+		return false
+	}
+	return key == ip || l.Allow(key)
+}
+
+// clientKey identifies a client by API token when given, otherwise by remote IP
+func clientKey(r *http.Request) string {
+	if key := headerKey(r); strings.HasPrefix(key, "token:") {
+		return key
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		return "token:" + token
+	}
+	return clientIP(r)
+}
+
+// headerKey identifies a client by the token in its Authorization header,
+// otherwise by remote IP. Admin checks use it, so the admin token never has
+// to appear in a URL, where proxies and access logs would keep it.
+func headerKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return "token:" + strings.TrimPrefix(auth, "Bearer ")
+	}
+	return clientIP(r)
+}
+
+// clientIP identifies a client by remote IP
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"margraf/graph"
+	"time"
+)
+
+// maxFollowedCompanies bounds the companies a connection gets
+// company_relations_update for; requests beyond it are answered but not followed
+const maxFollowedCompanies = 50
+
+// relationsDebounce lets a burst of edge changes settle into one update
+const relationsDebounce = 250 * time.Millisecond
+
+// followLocked sends a connection company_relations_update for companyID from
+// now on; rel is what it was just sent (must be called with h.mu held)
+func (h *Hub) followLocked(sub *subscriber, companyID string, rel *graph.CompanyRelations) {
+	if sub.following == nil {
+		sub.following = make(map[string]bool)
+	}
+	if sub.following[companyID] || len(sub.following) >= maxFollowedCompanies {
+		return
+	}
+	sub.following[companyID] = true
+	h.followed[companyID]++
+	// Keep an older snapshot: changes since then reach the other followers,
+	// and re-adding what this one already has is harmless
+	if _, ok := h.relationsSent[companyID]; !ok {
+		h.relationsSent[companyID] = rel
+	}
+}
+
+// unfollowAllLocked stops a connection's company_relations_update (must be
+// called with h.mu held)
+func (h *Hub) unfollowAllLocked(sub *subscriber) {
+	for id := range sub.following {
+		delete(sub.following, id)
+		if h.followed[id]--; h.followed[id] <= 0 {
+			delete(h.followed, id)
+			delete(h.relationsSent, id)
+			delete(h.relationsPending, id)
+		}
+	}
+}
+
+// queueRelations marks the followed companies a graph change may affect for
+// the next update. It never blocks, so it is safe to call from a graph change
+// hook.
+func (h *Hub) queueRelations(d graph.Delta) {
+	ids := graph.RelationsAffected(d)
+	if len(ids) == 0 {
+		return
+	}
+	h.mu.Lock()
+	queued := false
+	for _, id := range ids {
+		if h.followed[id] > 0 {
+			h.relationsPending[id] = true
+			queued = true
+		}
+	}
+	h.mu.Unlock()
+	if queued {
+		select {
+		case h.relationsKick <- struct{}{}:
+		default: // An update is already due
+		}
+	}
+}
+
+// pushRelations sends company_relations_update for queued companies until
+// the hub is dropped
+func (h *Hub) pushRelations() {
+	for range h.relationsKick {
+		time.Sleep(relationsDebounce)
+		h.flushRelations()
+	}
+}
+
+// flushRelations recomputes the queued companies' relations and sends what
+// changed to their followers
+func (h *Hub) flushRelations() {
+	h.mu.Lock()
+	pending := h.relationsPending
+	h.relationsPending = make(map[string]bool)
+	h.mu.Unlock()
+	if h.graph == nil {
+		return
+	}
+
+	for id := range pending {
+		rel, err := h.graph.GetCompanyRelations(id)
+		if err != nil {
+			continue
+		}
+		h.mu.Lock()
+		prev, ok := h.relationsSent[id]
+		if !ok { // Unfollowed meanwhile
+			h.mu.Unlock()
+			continue
+		}
+		added, removed := diffRelations(prev, rel)
+		if len(added) > 0 || len(removed) > 0 {
+			h.relationsSent[id] = rel
+			msg := BroadcastMessage{Type: TypeCompanyRelationsUpdate, Payload: CompanyRelationsUpdatePayload{CompanyID: id, Added: added, Removed: removed}}
+			for sub := range h.subscribers {
+				if sub.following[id] {
+					sub.deliver(msg)
+				}
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// relationList is one list of a CompanyRelations, named as in its JSON
+type relationList struct {
+	name  string
+	nodes []*graph.Node
+}
+
+func relationLists(r *graph.CompanyRelations) []relationList {
+	return []relationList{
+		{"suppliers", r.Suppliers},
+		{"clients", r.Clients},
+		{"raw_materials", r.RawMaterials},
+		{"products", r.Products},
+		{"parents", r.Parents},
+		{"subsidiaries", r.Subsidiaries},
+	}
+}
+
+// diffRelations returns the nodes that joined and left each list from prev to next
+func diffRelations(prev, next *graph.CompanyRelations) (added, removed []RelationChange) {
+	before, after := relationLists(prev), relationLists(next)
+	for i, list := range after {
+		added = append(added, missingFrom(list.name, list.nodes, before[i].nodes)...)
+		removed = append(removed, missingFrom(list.name, before[i].nodes, list.nodes)...)
+	}
+	return added, removed
+}
+
+// missingFrom returns the nodes of list that other lacks
+func missingFrom(relation string, list, other []*graph.Node) []RelationChange {
+	have := make(map[string]bool, len(other))
+	for _, n := range other {
+		have[n.ID] = true
+	}
+	var out []RelationChange
+	for _, n := range list {
+		if !have[n.ID] {
+			out = append(out, RelationChange{Relation: relation, NodeID: n.ID, Name: n.Name})
+		}
+	}
+	return out
+}
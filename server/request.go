@@ -0,0 +1,522 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margraf/clock"
+	"margraf/graph"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request limits
+const (
+	maxMessageBytes   = 64 * 1024 // Largest WebSocket frame read from a client; larger ones close the connection
+	maxIDLength       = 256       // Node, company, nation, event, task and action IDs
+	maxQuestionLength = 2000      // Characters in an ask question
+	maxCalendarDays   = 366
+	maxAdminArgs      = 20
+	maxAdminArgLength = 1024
+	maxNoteTags       = 20
+)
+
+// FieldError is a request field that failed validation. It is sent as an
+// invalid_request error with the field named in ErrorPayload.Field.
+type FieldError struct {
+	Field   string // JSON name; "" = the payload as a whole
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + " " + e.Message
+}
+
+// validator is a request payload that checks its own fields after decoding
+type validator interface {
+	validate() error
+}
+
+// decodePayload unmarshals a request payload into req and validates it. A
+// missing or null payload leaves req at its zero value, so requests without
+// required fields need no payload.
+func decodePayload(raw json.RawMessage, req validator) error {
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, req); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				if typeErr.Field == "" {
+					return &FieldError{Message: "payload must be a JSON object"}
+				}
+				return &FieldError{Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type)}
+			}
+			var fieldErr *FieldError
+			if errors.As(err, &fieldErr) {
+				return fieldErr
+			}
+			return &FieldError{Message: "invalid payload: " + err.Error()}
+		}
+	}
+	return req.validate()
+}
+
+// decodeRequest decodes a WebSocket request's payload into req, replying
+// with an invalid_request error when it does not validate
+func decodeRequest(sub *subscriber, msg IncomingMessage, req validator) bool {
+	if err := decodePayload(msg.Payload, req); err != nil {
+		replyInvalid(sub, msg.ID, err)
+		return false
+	}
+	return true
+}
+
+// jsonKind names the JSON value a Go type decodes from
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Ptr:
+		return jsonKind(t.Elem())
+	}
+	return "an object"
+}
+
+// checkID rejects an ID longer than maxIDLength, and an empty one if required
+func checkID(field, id string, required bool) error {
+	switch {
+	case required && strings.TrimSpace(id) == "":
+		return &FieldError{Field: field, Message: "is required"}
+	case len(id) > maxIDLength:
+		return &FieldError{Field: field, Message: fmt.Sprintf("must be at most %d characters", maxIDLength)}
+	}
+	return nil
+}
+
+// checkIDs applies checkID to each entry of a list of at most max IDs
+func checkIDs(field string, ids []string, max int) error {
+	if len(ids) > max {
+		return &FieldError{Field: field, Message: fmt.Sprintf("must list at most %d entries", max)}
+	}
+	for _, id := range ids {
+		if err := checkID(field, id, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompanyRelationsRequest is the payload of get_company_relations
+type CompanyRelationsRequest struct {
+	CompanyID string `json:"company_id"`
+}
+
+func (r *CompanyRelationsRequest) validate() error {
+	return checkID("company_id", r.CompanyID, true)
+}
+
+// NationRelationsRequest is the payload of get_nation_relations
+type NationRelationsRequest struct {
+	NationID string `json:"nation_id"`
+}
+
+func (r *NationRelationsRequest) validate() error {
+	return checkID("nation_id", r.NationID, true)
+}
+
+// ProjectionRequest is the payload of get_projection
+type ProjectionRequest struct {
+	Level string `json:"level"` // "nation" (default) or "industry"
+}
+
+func (r *ProjectionRequest) validate() error {
+	switch graph.ProjectionLevel(r.Level) {
+	case "", graph.ProjectionNation, graph.ProjectionIndustry:
+		return nil
+	}
+	return &FieldError{Field: "level", Message: fmt.Sprintf("must be %q or %q", graph.ProjectionNation, graph.ProjectionIndustry)}
+}
+
+// NodeRequest is the payload of get_health_history and watch
+type NodeRequest struct {
+	NodeID string `json:"node_id"`
+}
+
+func (r *NodeRequest) validate() error {
+	return checkID("node_id", r.NodeID, true)
+}
+
+// UnwatchRequest is the payload of unwatch
+type UnwatchRequest struct {
+	NodeID string `json:"node_id"` // "" = every node
+}
+
+func (r *UnwatchRequest) validate() error {
+	return checkID("node_id", r.NodeID, false)
+}
+
+// ImpactRequest is the payload of get_impact
+type ImpactRequest struct {
+	EventID string `json:"event_id"`
+	Kind    string `json:"kind"`
+	List    bool   `json:"list"`
+}
+
+func (r *ImpactRequest) validate() error {
+	if err := checkID("event_id", r.EventID, false); err != nil {
+		return err
+	}
+	switch graph.ImpactKind(r.Kind) {
+	case "", graph.ImpactShock, graph.ImpactNews, graph.ImpactDecay:
+		return nil
+	}
+	return &FieldError{Field: "kind", Message: fmt.Sprintf("must be %q, %q or %q", graph.ImpactShock, graph.ImpactNews, graph.ImpactDecay)}
+}
+
+// CalendarRequest is the payload of get_calendar
+type CalendarRequest struct {
+	NodeIDs []string `json:"node_ids"`
+	NodeID  string   `json:"node_id"`
+	Days    int      `json:"days"` // 0 = defaultCalendarDays
+}
+
+func (r *CalendarRequest) validate() error {
+	if err := checkIDs("node_ids", r.NodeIDs, maxWatchedNodes); err != nil {
+		return err
+	}
+	if err := checkID("node_id", r.NodeID, false); err != nil {
+		return err
+	}
+	if r.Days < 0 || r.Days > maxCalendarDays {
+		return &FieldError{Field: "days", Message: fmt.Sprintf("must be between 1 and %d", maxCalendarDays)}
+	}
+	return nil
+}
+
+// AskRequest is the payload of ask
+type AskRequest struct {
+	Question string `json:"question"`
+}
+
+func (r *AskRequest) validate() error {
+	switch {
+	case strings.TrimSpace(r.Question) == "":
+		return &FieldError{Field: "question", Message: "is required"}
+	case len(r.Question) > maxQuestionLength:
+		return &FieldError{Field: "question", Message: fmt.Sprintf("must be at most %d characters", maxQuestionLength)}
+	}
+	return nil
+}
+
+// CancelTaskRequest is the payload of cancel_task
+type CancelTaskRequest struct {
+	TaskID string `json:"task_id"`
+}
+
+func (r *CancelTaskRequest) validate() error {
+	return checkID("task_id", r.TaskID, true)
+}
+
+// AddNoteRequest is the payload of add_note: the note's text, author and
+// tags, on a node (node_id) or an edge (source_id, target_id, edge_type and
+// commodity for commodity edges)
+type AddNoteRequest struct {
+	NodeID    string   `json:"node_id"`
+	SourceID  string   `json:"source_id"`
+	TargetID  string   `json:"target_id"`
+	EdgeType  string   `json:"edge_type"`
+	Commodity string   `json:"commodity"`
+	Author    string   `json:"author"`
+	Text      string   `json:"text"`
+	Tags      []string `json:"tags"`
+}
+
+func (r *AddNoteRequest) validate() error {
+	if r.NodeID == "" && r.SourceID == "" {
+		return &FieldError{Field: "node_id", Message: "or source_id, target_id and edge_type are required"}
+	}
+	if r.NodeID != "" && r.SourceID != "" {
+		return &FieldError{Field: "node_id", Message: "and source_id can't both be set"}
+	}
+	if r.NodeID != "" {
+		if err := checkID("node_id", r.NodeID, true); err != nil {
+			return err
+		}
+	} else {
+		for _, f := range []struct{ name, value string }{{"source_id", r.SourceID}, {"target_id", r.TargetID}, {"edge_type", r.EdgeType}} {
+			if err := checkID(f.name, f.value, true); err != nil {
+				return err
+			}
+		}
+		if err := checkID("commodity", r.Commodity, false); err != nil {
+			return err
+		}
+	}
+	if err := checkID("author", r.Author, true); err != nil {
+		return err
+	}
+	switch {
+	case strings.TrimSpace(r.Text) == "":
+		return &FieldError{Field: "text", Message: "is required"}
+	case len(r.Text) > graph.MaxNoteLength:
+		return &FieldError{Field: "text", Message: fmt.Sprintf("must be at most %d bytes", graph.MaxNoteLength)}
+	}
+	return checkIDs("tags", r.Tags, maxNoteTags)
+}
+
+// Note is the graph note the request describes
+func (r *AddNoteRequest) Note() graph.Note {
+	return graph.Note{
+		NodeID:    r.NodeID,
+		SourceID:  r.SourceID,
+		TargetID:  r.TargetID,
+		EdgeType:  graph.EdgeType(r.EdgeType),
+		Commodity: r.Commodity,
+		Author:    r.Author,
+		Text:      r.Text,
+		Tags:      r.Tags,
+	}
+}
+
+// NotesRequest is the payload of get_notes
+type NotesRequest struct {
+	NodeID string `json:"node_id"` // Notes on the node and its edges ("" = every note)
+	Tag    string `json:"tag"`     // Only notes with this tag
+}
+
+func (r *NotesRequest) validate() error {
+	if err := checkID("node_id", r.NodeID, false); err != nil {
+		return err
+	}
+	return checkID("tag", r.Tag, false)
+}
+
+// DeleteNoteRequest is the payload of delete_note
+type DeleteNoteRequest struct {
+	NoteID string `json:"note_id"`
+}
+
+func (r *DeleteNoteRequest) validate() error {
+	return checkID("note_id", r.NoteID, true)
+}
+
+// TimelineRequest is the payload of get_timeline and the query of GET
+// /api/timeline. Since and until take an RFC 3339 time, a date or "today" /
+// "yesterday" in the display timezone, or a duration back such as "6h".
+type TimelineRequest struct {
+	Since  string   `json:"since"` // "" = everything kept
+	Until  string   `json:"until"` // "" = now
+	Kinds  []string `json:"kinds"` // news, shock, decay, market, social, edge (empty = all)
+	NodeID string   `json:"node_id"`
+	Limit  int      `json:"limit"`  // 0 = defaultTimelineLimit
+	Cursor string   `json:"cursor"` // "next" of the previous page
+
+	since, until time.Time
+	cursor       TimelineEntry // Time and ID of the last entry already sent
+	hasCursor    bool
+}
+
+func (r *TimelineRequest) validate() error {
+	now := time.Now()
+	var err error
+	if r.since, err = parseTimelineTime(r.Since, now); err != nil {
+		return &FieldError{Field: "since", Message: err.Error()}
+	}
+	if r.until, err = parseTimelineTime(r.Until, now); err != nil {
+		return &FieldError{Field: "until", Message: err.Error()}
+	}
+	if len(r.Kinds) > len(timelineKinds) {
+		return &FieldError{Field: "kinds", Message: fmt.Sprintf("must list at most %d kinds", len(timelineKinds))}
+	}
+	for _, k := range r.Kinds {
+		if !timelineKinds[k] {
+			return &FieldError{Field: "kinds", Message: fmt.Sprintf("has unknown kind %q (news, shock, decay, market, social or edge)", k)}
+		}
+	}
+	if err := checkID("node_id", r.NodeID, false); err != nil {
+		return err
+	}
+	if r.Limit < 0 || r.Limit > maxTimelineLimit {
+		return &FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxTimelineLimit)}
+	}
+	if r.Cursor != "" {
+		nanos, id, ok := strings.Cut(r.Cursor, "_")
+		n, err := strconv.ParseInt(nanos, 10, 64)
+		if !ok || err != nil || id == "" || len(r.Cursor) > maxIDLength {
+			return &FieldError{Field: "cursor", Message: "is not a timeline cursor"}
+		}
+		r.cursor, r.hasCursor = TimelineEntry{ID: id, Time: time.Unix(0, n).UTC()}, true
+	}
+	return nil
+}
+
+// parseTimelineTime reads a since or until bound ("" = none)
+func parseTimelineTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	today := func() time.Time {
+		local := clock.Local(now)
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	}
+	switch strings.ToLower(s) {
+	case "":
+		return time.Time{}, nil
+	case "today":
+		return today(), nil
+	case "yesterday":
+		return today().AddDate(0, 0, -1), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, clock.Display()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("must be an RFC 3339 time, a date, today, yesterday or a duration such as 6h")
+}
+
+// AdminRequest is an admin action and its arguments: the payload of admin,
+// whose other fields are the arguments, or /admin/<action> with the query
+// string as arguments
+type AdminRequest struct {
+	Action string
+	Args   map[string]string
+}
+
+// UnmarshalJSON takes "action" and keeps the payload's other string, number
+// and boolean fields as arguments
+func (r *AdminRequest) UnmarshalJSON(data []byte) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return &FieldError{Message: "payload must be a JSON object"}
+	}
+	r.Args = make(map[string]string)
+	for k, v := range fields {
+		if k == "action" {
+			action, ok := v.(string)
+			if !ok {
+				return &FieldError{Field: "action", Message: "must be a string"}
+			}
+			r.Action = action
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			r.Args[k] = v
+		case float64, bool:
+			r.Args[k] = fmt.Sprint(v)
+		default:
+			return &FieldError{Field: k, Message: "must be a string, number or boolean"}
+		}
+	}
+	return nil
+}
+
+// jsonSchema describes the payload UnmarshalJSON takes
+func (r *AdminRequest) jsonSchema() schemaObject {
+	return schemaObject{
+		"type":                 "object",
+		"properties":           schemaObject{"action": schemaObject{"type": "string"}},
+		"required":             []string{"action"},
+		"additionalProperties": schemaObject{"type": []string{"string", "number", "boolean"}},
+	}
+}
+
+func (r *AdminRequest) validate() error {
+	if err := checkID("action", r.Action, true); err != nil {
+		return err
+	}
+	if len(r.Args) > maxAdminArgs {
+		return &FieldError{Message: fmt.Sprintf("at most %d arguments", maxAdminArgs)}
+	}
+	for k, v := range r.Args {
+		if len(v) > maxAdminArgLength {
+			return &FieldError{Field: k, Message: fmt.Sprintf("must be at most %d characters", maxAdminArgLength)}
+		}
+	}
+	return nil
+}
+
+// streamRequest is what a /ws or /events connection asks for in its URL
+type streamRequest struct {
+	Topics []string // ?topics= / ?topic=
+	Watch  []string // ?watch=
+}
+
+// parseStream reads and validates a stream connection's query parameters
+func parseStream(r *http.Request) (streamRequest, error) {
+	req := streamRequest{Topics: parseTopics(r), Watch: parseWatch(r)}
+	return req, req.validate()
+}
+
+func (r *streamRequest) validate() error {
+	if err := checkIDs("topics", r.Topics, maxSessionTopics); err != nil {
+		return err
+	}
+	return checkIDs("watch", r.Watch, maxWatchedNodes)
+}
+
+// replyInvalid sends a request's validation error as invalid_request
+func replyInvalid(sub *subscriber, id string, err error) {
+	payload := ErrorPayload{Code: ErrCodeInvalidRequest, Message: err.Error(), RequestID: id}
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		payload.Field = fieldErr.Field
+	}
+	reply(sub, id, TypeError, payload)
+}
+
+// errorStatus is the HTTP status of an error code
+func errorStatus(code string) int {
+	switch code {
+	case ErrCodeInvalidRequest, ErrCodeUnknownType:
+		return http.StatusBadRequest
+	case ErrCodeForbidden:
+		return http.StatusForbidden
+	case ErrCodeNotFound:
+		return http.StatusNotFound
+	case ErrCodeRateLimited:
+		return http.StatusTooManyRequests
+	case ErrCodeUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeJSON writes body as a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes the ErrorPayload of code, with the code's HTTP status
+func writeError(w http.ResponseWriter, code, message string) {
+	writeJSON(w, errorStatus(code), ErrorPayload{Code: code, Message: message})
+}
+
+// writeInvalid writes a request's validation error as a 400 invalid_request
+func writeInvalid(w http.ResponseWriter, err error) {
+	payload := ErrorPayload{Code: ErrCodeInvalidRequest, Message: err.Error()}
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		payload.Field = fieldErr.Field
+	}
+	writeJSON(w, http.StatusBadRequest, payload)
+}
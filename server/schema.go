@@ -0,0 +1,619 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"margraf/graph"
+	"margraf/syserr"
+	"margraf/task"
+	"margraf/trading"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every message type's payload and every request's is a Go type, so the
+// server describes its own protocol: JSON Schemas generated from those types
+// by reflection, served over GET /api/schema, and an OpenAPI document of the
+// HTTP endpoints built on them over GET /api/openapi.json. Frontends and
+// integrators generate typed clients from these rather than from samples.
+
+var (
+	payloadsMu sync.RWMutex
+	payloads   = make(map[string]reflect.Type)
+)
+
+func init() {
+	RegisterPayload(TypeSystem, SystemPayload{})
+	RegisterPayload(TypeError, ErrorPayload{})
+	RegisterPayload(TypeGraphUpdate, graph.GraphData{})
+	RegisterPayload(TypeGraphNotice, GraphNoticePayload{})
+	RegisterPayload(TypeNewsAlert, NewsAlertPayload{})
+	RegisterPayload(TypeSocialPulse, SocialPulsePayload{})
+	RegisterPayload(TypeMentionSpike, MentionSpikePayload{})
+	RegisterPayload(TypeEdgeAnomaly, EdgeAnomalyPayload{})
+	RegisterPayload(TypeShockEvent, ShockPayload{})
+	RegisterPayload(TypeMarketUpdate, MarketUpdatePayload{})
+	RegisterPayload(TypeStressUpdate, StressUpdatePayload{})
+	RegisterPayload(TypePortfolioUpdate, PortfolioUpdatePayload{})
+	RegisterPayload(TypePaperPerformance, trading.PaperPerformance{})
+	RegisterPayload(TypePairAlert, PairAlertPayload{})
+	RegisterPayload(TypeCalendar, CalendarPayload{})
+	RegisterPayload(TypeTaskUpdate, task.Info{})
+	RegisterPayload(TypeTasks, []task.Info{})
+	RegisterPayload(TypeSystemError, syserr.Event{})
+	RegisterPayload(TypeSystemErrors, []syserr.Event{})
+	RegisterPayload(TypeCompanyRelations, graph.CompanyRelations{})
+	RegisterPayload(TypeNationRelations, graph.NationRelations{})
+	RegisterPayload(TypeCompaniesList, []CompanySummary{})
+	RegisterPayload(TypeProjection, graph.Projection{})
+	RegisterPayload(TypeHealthHistory, graph.HealthHistory{})
+	RegisterPayload(TypeImpact, graph.Impact{})
+	RegisterPayload(TypeImpacts, []graph.Impact{})
+	RegisterPayload(TypeSession, Session{})
+	RegisterPayload(TypeAdminResult, AdminResultPayload{})
+	RegisterPayload(TypeWatchEvent, WatchEventPayload{})
+	RegisterPayload(TypeWatching, WatchingPayload{})
+	RegisterPayload(TypeAskChunk, AskChunkPayload{})
+	RegisterPayload(TypeAskAnswer, AskAnswerPayload{})
+	RegisterPayload(TypeNote, graph.Note{})
+	RegisterPayload(TypeNotes, []graph.Note{})
+	RegisterPayload(TypeTimeline, TimelinePayload{})
+	RegisterPayload(TypeCompanyRelationsUpdate, CompanyRelationsUpdatePayload{})
+}
+
+// RegisterPayload records the Go type of a message type's payload, given as
+// a value of it, for the published schemas. Packages that broadcast types
+// of their own (simulation's traces and comparisons) register them from
+// init. It panics if the type is registered twice.
+func RegisterPayload(msgType string, example interface{}) {
+	payloadsMu.Lock()
+	defer payloadsMu.Unlock()
+
+	if example == nil {
+		panic("server: RegisterPayload example is nil")
+	}
+	if _, dup := payloads[msgType]; dup {
+		panic("server: RegisterPayload called twice for " + msgType)
+	}
+	payloads[msgType] = reflect.TypeOf(example)
+}
+
+// requestSpec is a WebSocket request's payload type and the message types
+// it is answered with (besides error)
+type requestSpec struct {
+	payload interface{} // nil = no payload
+	replies []string
+}
+
+// requests lists the WebSocket requests handleMessages dispatches
+var requests = map[string]requestSpec{
+	"get_company_relations": {CompanyRelationsRequest{}, []string{TypeCompanyRelations}},
+	"get_nation_relations":  {NationRelationsRequest{}, []string{TypeNationRelations}},
+	"get_companies_list":    {nil, []string{TypeCompaniesList}},
+	"get_full_graph":        {nil, []string{TypeGraphUpdate}},
+	"get_projection":        {ProjectionRequest{}, []string{TypeProjection}},
+	"get_health_history":    {NodeRequest{}, []string{TypeHealthHistory}},
+	"get_impact":            {ImpactRequest{}, []string{TypeImpact, TypeImpacts}},
+	"get_paper_performance": {nil, []string{TypePaperPerformance}},
+	"get_calendar":          {CalendarRequest{}, []string{TypeCalendar}},
+	"ask":                   {AskRequest{}, []string{TypeAskChunk, TypeAskAnswer}},
+	"get_tasks":             {nil, []string{TypeTasks}},
+	"cancel_task":           {CancelTaskRequest{}, []string{TypeTasks}},
+	"get_system_errors":     {nil, []string{TypeSystemErrors}},
+	"get_session":           {nil, []string{TypeSession}},
+	"update_session":        {SessionUpdate{}, []string{TypeSession}},
+	"watch":                 {NodeRequest{}, []string{TypeWatching}},
+	"unwatch":               {UnwatchRequest{}, []string{TypeWatching}},
+	"add_note":              {AddNoteRequest{}, []string{TypeNote}},
+	"get_notes":             {NotesRequest{}, []string{TypeNotes}},
+	"delete_note":           {DeleteNoteRequest{}, []string{TypeNote}},
+	"get_timeline":          {TimelineRequest{}, []string{TypeTimeline}},
+	"admin":                 {AdminRequest{}, []string{TypeAdminResult}},
+}
+
+// schemaObject is a JSON Schema
+type schemaObject = map[string]interface{}
+
+// customSchema is a request whose JSON doesn't follow its fields
+type customSchema interface {
+	jsonSchema() schemaObject
+}
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	durationType     = reflect.TypeOf(time.Duration(0))
+	rawMessageType   = reflect.TypeOf(json.RawMessage(nil))
+	customSchemaType = reflect.TypeOf((*customSchema)(nil)).Elem()
+	serverPkgPath    = reflect.TypeOf(SystemPayload{}).PkgPath()
+)
+
+// schemaBuilder turns Go types into JSON Schemas, collecting named structs
+// as definitions referenced under prefix
+type schemaBuilder struct {
+	prefix string
+	defs   map[string]interface{}
+	input  bool // Building requests: fields are never required, since missing ones decode to zero
+}
+
+func newSchemaBuilder(prefix string) *schemaBuilder {
+	return &schemaBuilder{prefix: prefix, defs: make(map[string]interface{})}
+}
+
+// defName names a struct's definition: unqualified for this package's
+// types, "graph.Node" for others
+func defName(t reflect.Type) string {
+	if t.PkgPath() == serverPkgPath {
+		return t.Name()
+	}
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+// ref returns a reference to t's definition
+func (b *schemaBuilder) ref(t reflect.Type) schemaObject {
+	return schemaObject{"$ref": b.prefix + defName(t)}
+}
+
+// of returns the schema of values of t as encoding/json writes them
+func (b *schemaBuilder) of(t reflect.Type) schemaObject {
+	switch t {
+	case timeType:
+		return schemaObject{"type": "string", "format": "date-time"}
+	case durationType:
+		return schemaObject{"type": "integer", "description": "Nanoseconds"}
+	case rawMessageType:
+		return schemaObject{}
+	}
+	if reflect.PointerTo(t).Implements(customSchemaType) {
+		if _, ok := b.defs[defName(t)]; !ok {
+			b.defs[defName(t)] = reflect.New(t).Interface().(customSchema).jsonSchema()
+		}
+		return b.ref(t)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.of(t.Elem())
+	case reflect.Bool:
+		return schemaObject{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schemaObject{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schemaObject{"type": "number"}
+	case reflect.String:
+		return schemaObject{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return schemaObject{"type": "string", "contentEncoding": "base64"}
+		}
+		return schemaObject{"type": "array", "items": b.of(t.Elem())}
+	case reflect.Map:
+		return schemaObject{"type": "object", "additionalProperties": b.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		if _, ok := b.defs[defName(t)]; !ok {
+			b.defs[defName(t)] = nil // Placeholder, so recursive types end
+			b.defs[defName(t)] = b.object(t)
+		}
+		return b.ref(t)
+	}
+	return schemaObject{} // Interfaces: any value
+}
+
+// object returns the schema of a struct's fields
+func (b *schemaBuilder) object(t reflect.Type) schemaObject {
+	props := make(map[string]interface{})
+	var required []string
+	b.fields(t, props, &required)
+	s := schemaObject{"type": "object", "properties": props}
+	if len(required) > 0 && !b.input {
+		s["required"] = required
+	}
+	return s
+}
+
+// fields adds t's JSON fields to props, following encoding/json's rules for
+// tags, unexported fields and embedded structs
+func (b *schemaBuilder) fields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				var embedded []string
+				b.fields(ft, props, &embedded)
+				if f.Type.Kind() != reflect.Ptr {
+					*required = append(*required, embedded...)
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := b.of(ft)
+		optional := strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,")
+		if !optional {
+			switch ft.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+				if ft != rawMessageType && ft.Kind() != reflect.Interface {
+					s = nullable(s)
+				}
+			}
+			*required = append(*required, name)
+		}
+		props[name] = s
+	}
+}
+
+// nullable lets a schema also match null, as nil pointers, slices and maps
+// are written
+func nullable(s schemaObject) schemaObject {
+	if typ, ok := s["type"].(string); ok {
+		n := make(schemaObject, len(s))
+		for k, v := range s {
+			n[k] = v
+		}
+		n["type"] = []string{typ, "null"}
+		return n
+	}
+	return schemaObject{"anyOf": []interface{}{s, schemaObject{"type": "null"}}}
+}
+
+// envelope is the schema of a message of msgType whose payload is payload
+func envelope(msgType string, payload schemaObject, requestID bool) schemaObject {
+	props := schemaObject{
+		"type":    schemaObject{"const": msgType},
+		"payload": payload,
+	}
+	required := []string{"type"}
+	if requestID {
+		props["id"] = schemaObject{"type": "string", "description": "Echoed on the replies"}
+	} else {
+		props["v"] = schemaObject{"const": ProtocolVersion}
+		props["id"] = schemaObject{"type": "string", "description": "The request ID on replies; absent on broadcasts"}
+		required = []string{"v", "type", "payload"}
+	}
+	return schemaObject{"type": "object", "properties": props, "required": required}
+}
+
+// protocolDefs adds the ServerMessage and ClientMessage definitions, one
+// variant per message type, and returns the payload schemas by message type
+// and the request schemas by request type
+func (b *schemaBuilder) protocolDefs() (messages, reqs map[string]interface{}) {
+	payloadsMu.RLock()
+	defer payloadsMu.RUnlock()
+
+	messages = make(map[string]interface{}, len(payloads))
+	var server []interface{}
+	for _, msgType := range sortedKeys(payloads) {
+		s := b.of(payloads[msgType])
+		messages[msgType] = s
+		server = append(server, envelope(msgType, s, false))
+	}
+	b.defs["ServerMessage"] = schemaObject{
+		"description": "A message from the server: a broadcast, or a reply to a request carrying its id",
+		"oneOf":       server,
+	}
+
+	b.input = true
+	defer func() { b.input = false }()
+	reqs = make(map[string]interface{}, len(requests))
+	var client []interface{}
+	for _, reqType := range sortedKeys(requests) {
+		spec := requests[reqType]
+		var payload schemaObject
+		if spec.payload != nil {
+			payload = b.of(reflect.TypeOf(spec.payload))
+		} else {
+			payload = schemaObject{"type": []string{"object", "null"}, "description": "No fields"}
+		}
+		replies := make([]interface{}, len(spec.replies))
+		for i, r := range spec.replies {
+			replies[i] = r
+		}
+		reqs[reqType] = schemaObject{"payload": payload, "replies": replies}
+		client = append(client, envelope(reqType, payload, true))
+	}
+	b.defs["ClientMessage"] = schemaObject{
+		"description": "A request from a WebSocket client",
+		"oneOf":       client,
+	}
+	return messages, reqs
+}
+
+// JSONSchema returns a JSON Schema (2020-12) of the protocol. Under $defs,
+// ServerMessage and ClientMessage match any message either way, and every
+// payload's Go type has a definition. "messages" maps each server message
+// type to its payload's schema, and "requests" each request type to its
+// payload's schema and the message types that answer it.
+func JSONSchema() map[string]interface{} {
+	b := newSchemaBuilder("#/$defs/")
+	messages, reqs := b.protocolDefs()
+	return map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "Margraf protocol v" + strconv.Itoa(ProtocolVersion),
+		"description": "Messages exchanged over /ws. /events sends the same payloads as server-sent events named by type.",
+		"anyOf": []interface{}{
+			schemaObject{"$ref": b.prefix + "ServerMessage"},
+			schemaObject{"$ref": b.prefix + "ClientMessage"},
+		},
+		"$defs":    b.defs,
+		"messages": messages,
+		"requests": reqs,
+	}
+}
+
+// OpenAPI returns an OpenAPI 3.1 document of the HTTP endpoints. Its
+// components hold the same definitions as JSONSchema, including
+// ServerMessage and ClientMessage for /ws.
+func OpenAPI() map[string]interface{} {
+	b := newSchemaBuilder("#/components/schemas/")
+	b.protocolDefs()
+	errorResponse := func(description string) schemaObject {
+		return schemaObject{"description": description, "content": jsonContent(b.of(reflect.TypeOf(ErrorPayload{})))}
+	}
+	ok := func(description string, body schemaObject) schemaObject {
+		return schemaObject{"description": description, "content": jsonContent(body)}
+	}
+	query := func(name, description string, typ schemaObject) schemaObject {
+		return schemaObject{"name": name, "in": "query", "description": description, "schema": typ}
+	}
+	str := schemaObject{"type": "string"}
+	rateLimited := errorResponse("Too many requests (rate_limited)")
+
+	adminOp := func(method string) schemaObject {
+		op := schemaObject{
+			"summary":     "Run an admin action",
+			"description": "Query parameters are the action's arguments. Actions that change state need POST; diagnostics also takes GET.",
+			"security":    []interface{}{schemaObject{"bearer": []string{}}},
+			"parameters": []interface{}{
+				schemaObject{"name": "action", "in": "path", "required": true, "schema": str},
+			},
+			"responses": schemaObject{
+				"200": ok("The action's result", b.of(reflect.TypeOf(AdminResultPayload{}))),
+				"400": errorResponse("Invalid arguments (invalid_request)"),
+				"403": errorResponse("Admin token required (forbidden)"),
+				"404": errorResponse("Unknown action (not_found)"),
+				"429": rateLimited,
+				"500": errorResponse("The action failed (internal)"),
+				"503": errorResponse("Admin actions are disabled (unavailable)"),
+			},
+		}
+		if method == http.MethodGet {
+			op["summary"] = "Run a read-only admin action (diagnostics)"
+		}
+		return op
+	}
+	document := schemaObject{
+		"summary": "This document",
+		"responses": schemaObject{
+			"200": ok("OpenAPI 3.1", schemaObject{"type": "object"}),
+		},
+	}
+
+	paths := schemaObject{
+		"/api/timeline": schemaObject{"get": schemaObject{
+			"summary": "One page of the timeline of graph events, newest first",
+			"parameters": []interface{}{
+				query("since", "RFC 3339 time, date, today, yesterday or a duration back such as 6h", str),
+				query("until", "As since; default now", str),
+				query("kinds", "Comma-separated: "+strings.Join(sortedKeys(timelineKinds), ", "), str),
+				query("node", "Only entries affecting this node", str),
+				query("limit", fmt.Sprintf("Entries per page (default %d, at most %d)", defaultTimelineLimit, maxTimelineLimit), schemaObject{"type": "integer"}),
+				query("cursor", "next of the previous page", str),
+			},
+			"responses": schemaObject{
+				"200": ok("A page of entries", b.of(reflect.TypeOf(TimelinePayload{}))),
+				"400": errorResponse("Invalid parameter (invalid_request)"),
+				"429": rateLimited,
+			},
+		}},
+		"/api/embeddings": schemaObject{"get": schemaObject{
+			"summary": "Node embeddings last computed by embed or the embeddings job",
+			"parameters": []interface{}{
+				query("ids", "Comma-separated node IDs (default all)", str),
+				query("format", "json (default) or csv: id, name, type, then d0, d1, ...", str),
+			},
+			"responses": schemaObject{
+				"200": schemaObject{"description": "One vector per node", "content": schemaObject{
+					"application/json": schemaObject{"schema": b.of(reflect.TypeOf(graph.Embeddings{}))},
+					"text/csv":         schemaObject{"schema": str},
+				}},
+				"400": errorResponse("Invalid parameter (invalid_request)"),
+				"404": errorResponse("A node has no embedding (not_found)"),
+				"429": rateLimited,
+				"503": errorResponse("No embeddings computed yet (unavailable)"),
+			},
+		}},
+		"/api/edges/history": schemaObject{"get": schemaObject{
+			"summary": "Weight history of the edges between two nodes, as plottable series",
+			"parameters": []interface{}{
+				query("source", "Source node ID (required)", str),
+				query("target", "Target node ID (required)", str),
+				query("type", "Edge type (default all)", str),
+				query("hs", "HS code of a commodity edge; empty for the aggregate edge (default all)", str),
+				query("since", "As in /api/timeline (default the whole history)", str),
+			},
+			"responses": schemaObject{
+				"200": ok("One series per edge, points oldest first", b.of(reflect.TypeOf(WeightHistoryPayload{}))),
+				"400": errorResponse("Invalid parameter (invalid_request)"),
+				"404": errorResponse("No edge history between the nodes (not_found)"),
+				"429": rateLimited,
+				"503": errorResponse("Graph not initialized (unavailable)"),
+			},
+		}},
+		"/api/centrality": schemaObject{"get": schemaObject{
+			"summary": "Nodes ranked by betweenness centrality, sampled on large graphs",
+			"parameters": []interface{}{
+				query("top", "Nodes returned (default 20, 0 = all)", schemaObject{"type": "integer"}),
+				query("mode", "exact or sampled (default exact up to analytics.threshold nodes)", str),
+				query("sources", "Source nodes sampled (default analytics.sources)", schemaObject{"type": "integer"}),
+				query("seed", "Sampling seed (default analytics.seed)", schemaObject{"type": "integer"}),
+			},
+			"responses": schemaObject{
+				"200": ok("Most central first, with whether and how far the graph was sampled", b.of(reflect.TypeOf(graph.BetweennessReport{}))),
+				"400": errorResponse("Invalid parameter (invalid_request)"),
+				"429": rateLimited,
+				"503": errorResponse("Graph not initialized or empty (unavailable)"),
+			},
+		}},
+		"/api/hitting-time": schemaObject{"get": schemaObject{
+			"summary": "Expected steps a weighted random walk takes between two nodes",
+			"parameters": []interface{}{
+				query("from", "Start node ID (required)", str),
+				query("to", "Target node ID (required)", str),
+				query("mode", "exact or sampled (default exact up to analytics.threshold nodes)", str),
+				query("walks", "Random walks averaged (default analytics.walks)", schemaObject{"type": "integer"}),
+				query("seed", "Sampling seed (default analytics.seed)", schemaObject{"type": "integer"}),
+			},
+			"responses": schemaObject{
+				"200": ok("The expected steps, with the standard error and arrivals of a sampled estimate", b.of(reflect.TypeOf(graph.HittingTime{}))),
+				"400": errorResponse("Invalid parameter (invalid_request)"),
+				"404": errorResponse("A node is unknown or the target cannot be reached (not_found)"),
+				"429": rateLimited,
+				"503": errorResponse("Graph not initialized (unavailable)"),
+			},
+		}},
+		"/api/history": schemaObject{"get": schemaObject{
+			"summary": "End-of-day history recorded by the history job",
+			"parameters": []interface{}{
+				query("node", "A node's daily health, price and stress", str),
+				query("source", "With target, the daily weight and status of the edges between them", str),
+				query("target", "See source", str),
+				query("since", "First day, YYYY-MM-DD (default the first recorded)", str),
+				query("until", "Last day, YYYY-MM-DD (default the last recorded)", str),
+			},
+			"responses": schemaObject{
+				"200": ok("The rows asked for, oldest first, or the recorded days without node or source", b.of(reflect.TypeOf(HistoryPayload{}))),
+				"400": errorResponse("Invalid parameter (invalid_request)"),
+				"429": rateLimited,
+				"503": errorResponse("History is not configured (unavailable)"),
+			},
+		}},
+		"/api/types": schemaObject{"get": schemaObject{
+			"summary": "Node and edge types, built-in and declared in config",
+			"responses": schemaObject{
+				"200": ok("Each type's origin, count, color or directionality and propagation", b.of(reflect.TypeOf(TypesPayload{}))),
+				"429": rateLimited,
+				"503": errorResponse("Graph not initialized (unavailable)"),
+			},
+		}},
+		"/paper": schemaObject{"get": schemaObject{
+			"summary": "Paper trader performance",
+			"responses": schemaObject{
+				"200": ok("Cumulative PnL, open risk, hit rate and daily NAV", b.of(reflect.TypeOf(trading.PaperPerformance{}))),
+				"429": rateLimited,
+				"503": errorResponse("Paper trading is not configured (unavailable)"),
+			},
+		}},
+		"/admin/{action}": schemaObject{
+			"post": adminOp(http.MethodPost),
+			"get":  adminOp(http.MethodGet),
+		},
+		"/ws": schemaObject{"get": schemaObject{
+			"summary":     "WebSocket connection",
+			"description": "The server sends ServerMessage and accepts ClientMessage (see components). Requests are answered with the message types listed in /api/schema, or an error carrying the request's id.",
+			"parameters": []interface{}{
+				query("topics", "Comma-separated message types to receive (default all)", str),
+				query("watch", "Comma-separated node IDs to watch", str),
+				query("token", "API token, as an alternative to Authorization: Bearer", str),
+			},
+			"responses": schemaObject{
+				"101": schemaObject{"description": "Switching to the WebSocket protocol"},
+				"400": errorResponse("Invalid parameter (invalid_request)"),
+				"429": rateLimited,
+			},
+		}},
+		"/events": schemaObject{"get": schemaObject{
+			"summary":     "Server-sent events",
+			"description": "Broadcasts as events named by message type, each carrying its payload as data (see the messages in /api/schema). Takes the query parameters of /ws.",
+			"parameters": []interface{}{
+				query("topics", "Comma-separated message types to receive (default all)", str),
+				query("watch", "Comma-separated node IDs to watch", str),
+			},
+			"responses": schemaObject{
+				"200": schemaObject{"description": "An event stream", "content": schemaObject{"text/event-stream": schemaObject{"schema": str}}},
+				"400": errorResponse("Invalid parameter (invalid_request)"),
+				"429": rateLimited,
+			},
+		}},
+		"/api/schema": schemaObject{"get": schemaObject{
+			"summary": "JSON Schema of the WebSocket and event stream protocol",
+			"responses": schemaObject{
+				"200": ok("JSON Schema 2020-12", schemaObject{"type": "object"}),
+			},
+		}},
+		"/api/openapi.json": schemaObject{"get": document},
+		"/healthz": schemaObject{"get": schemaObject{
+			"summary": "Liveness: 200 while the process serves HTTP (also HEAD; not rate limited)",
+			"responses": schemaObject{
+				"200": ok("Alive", b.of(reflect.TypeOf(HealthPayload{}))),
+			},
+		}},
+		"/readyz": schemaObject{"get": schemaObject{
+			"summary": "Readiness: graph loaded, hub running and a data pipeline healthy (also HEAD; not rate limited)",
+			"responses": schemaObject{
+				"200": ok("Ready", b.of(reflect.TypeOf(ReadinessPayload{}))),
+				"503": ok("Not ready; the failing checks say why", b.of(reflect.TypeOf(ReadinessPayload{}))),
+			},
+		}},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": schemaObject{
+			"title":       "Margraf",
+			"version":     strconv.Itoa(ProtocolVersion),
+			"description": "HTTP endpoints of the Margraf server. The version is the protocol version sent as v on every message.",
+		},
+		"paths": paths,
+		"components": schemaObject{
+			"schemas": b.defs,
+			"securitySchemes": schemaObject{
+				"bearer": schemaObject{"type": "http", "scheme": "bearer", "description": "MARGRAF_ADMIN_TOKEN; also accepted as ?token="},
+			},
+		},
+	}
+}
+
+func jsonContent(s schemaObject) schemaObject {
+	return schemaObject{"application/json": schemaObject{"schema": s}}
+}
+
+// HandleSchema serves GET /api/schema: the JSONSchema of the protocol
+func (h *Hub) HandleSchema(w http.ResponseWriter, r *http.Request) {
+	serveDocument(w, r, JSONSchema)
+}
+
+// HandleOpenAPI serves GET /api/openapi.json: the OpenAPI document
+func (h *Hub) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	serveDocument(w, r, OpenAPI)
+}
+
+func serveDocument(w http.ResponseWriter, r *http.Request, document func() map[string]interface{}) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+		return
+	}
+	writeJSON(w, http.StatusOK, document())
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margraf/clock"
+	"margraf/logger"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session limits
+const (
+	sessionTTL       = 90 * 24 * time.Hour // Sessions unused this long are dropped on load
+	maxWatchedNodes  = 100
+	maxSessionTopics = 50
+	maxSessionLayout = 16 * 1024 // Bytes of encoded layout hints
+	maxSessions      = 10000     // Tokens are not verified, so the least recently updated session makes room
+	sessionSaveDelay = 5 * time.Second
+)
+
+var errSessionNoToken = errors.New("sessions need an API token (Authorization: Bearer <token> or ?token=)")
+
+// Session is the dashboard state a client saved under its API token. It is
+// sent as a "session" message on connect, so a reconnecting client restores
+// its view without re-issuing every query.
+type Session struct {
+	WatchedNodes []string               `json:"watched_nodes"`
+	Topics       []string               `json:"topics"`           // Broadcast filter applied when the client connects without ?topics=
+	Layout       map[string]interface{} `json:"layout,omitempty"` // Free-form hints (open panel, zoom, ...)
+	Updated      time.Time              `json:"updated"`
+}
+
+// SessionStore keeps sessions keyed by a hash of the client's API token, so
+// the file on disk holds no tokens. A nil store disables sessions.
+type SessionStore struct {
+	mu       sync.Mutex
+	path     string // "" = in memory only
+	sessions map[string]*Session
+	dirty    bool // Changed since the last save
+	saving   bool // A save is scheduled
+}
+
+// LoadSessions opens the session file at path (missing = empty). An empty
+// path keeps sessions in memory until the process exits.
+func LoadSessions(path string) (*SessionStore, error) {
+	s := &SessionStore{path: path, sessions: make(map[string]*Session)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.sessions); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for id, sess := range s.sessions {
+		if time.Since(sess.Updated) > sessionTTL {
+			delete(s.sessions, id)
+		}
+	}
+	return s, nil
+}
+
+// sessionID hashes a client key (see clientKey); only token clients have sessions
+func sessionID(key string) (string, bool) {
+	token, ok := strings.CutPrefix(key, "token:")
+	if !ok || token == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// Get returns a copy of the session for a client key
+func (s *SessionStore) Get(key string) (Session, bool) {
+	id, ok := sessionID(key)
+	if s == nil || !ok {
+		return Session{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	return *sess, true
+}
+
+// SessionUpdate replaces the session fields that are set
+type SessionUpdate struct {
+	WatchedNodes *[]string              `json:"watched_nodes,omitempty"`
+	Topics       *[]string              `json:"topics,omitempty"`
+	Layout       map[string]interface{} `json:"layout,omitempty"`
+}
+
+func (u *SessionUpdate) validate() error {
+	if u.WatchedNodes != nil {
+		if err := checkIDs("watched_nodes", *u.WatchedNodes, maxWatchedNodes); err != nil {
+			return err
+		}
+	}
+	if u.Topics != nil {
+		if err := checkIDs("topics", *u.Topics, maxSessionTopics); err != nil {
+			return err
+		}
+	}
+	if u.Layout != nil {
+		if data, err := json.Marshal(u.Layout); err != nil || len(data) > maxSessionLayout {
+			return &FieldError{Field: "layout", Message: fmt.Sprintf("must encode to at most %d bytes", maxSessionLayout)}
+		}
+	}
+	return nil
+}
+
+// Update applies u to the session for a client key, creating it if needed,
+// and returns the result. The store is saved within sessionSaveDelay, once
+// for any number of updates.
+func (s *SessionStore) Update(key string, u SessionUpdate) (Session, error) {
+	if s == nil {
+		return Session{}, fmt.Errorf("sessions are disabled")
+	}
+	id, ok := sessionID(key)
+	if !ok {
+		return Session{}, errSessionNoToken
+	}
+	if err := u.validate(); err != nil {
+		return Session{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		if len(s.sessions) >= maxSessions {
+			s.evictLocked()
+		}
+		sess = &Session{WatchedNodes: []string{}, Topics: []string{}}
+		s.sessions[id] = sess
+	}
+	if u.WatchedNodes != nil {
+		sess.WatchedNodes = *u.WatchedNodes
+	}
+	if u.Topics != nil {
+		sess.Topics = *u.Topics
+	}
+	if u.Layout != nil {
+		sess.Layout = u.Layout
+	}
+	sess.Updated = clock.Now()
+	s.dirty = true
+	if s.path != "" && !s.saving {
+		s.saving = true
+		time.AfterFunc(sessionSaveDelay, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.saving = false
+			if err := s.saveLocked(); err != nil {
+				logger.Warn(logger.StatusWarn, "Failed to save client sessions: %v", err)
+			}
+		})
+	}
+	return *sess, nil
+}
+
+// Flush saves any updates not yet written, for use on shutdown
+func (s *SessionStore) Flush() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saveLocked()
+}
+
+// evictLocked drops the least recently updated session (caller holds s.mu)
+func (s *SessionStore) evictLocked() {
+	var oldest string
+	for id, sess := range s.sessions {
+		if oldest == "" || sess.Updated.Before(s.sessions[oldest].Updated) {
+			oldest = id
+		}
+	}
+	delete(s.sessions, oldest)
+}
+
+// saveLocked writes the store to its file if it changed (caller holds s.mu)
+func (s *SessionStore) saveLocked() error {
+	if s.path == "" || !s.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
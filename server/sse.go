@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"margraf/logger"
+	"net/http"
+	"time"
+)
+
+// sseHeartbeat keeps idle SSE connections alive through proxies
+const sseHeartbeat = 15 * time.Second
+
+// HandleSSE streams Hub broadcasts as Server-Sent Events.
+// Filter with ?topics=shock_event,news_alert (or repeated ?topic=), and follow
+// nodes with ?watch=tsmc,apple to get their watch_event stream.
+func (h *Hub) HandleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, ErrCodeInternal, "streaming unsupported")
+		return
+	}
+	stream, err := parseStream(r)
+	if err != nil {
+		writeInvalid(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	sub := h.subscribe(stream.Topics, stream.Watch)
+	defer h.unsubscribe(sub)
+
+	writeSSE(w, BroadcastMessage{Type: TypeSystem, Payload: SystemPayload{Message: "Connected to Margraf Stream"}})
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-sub.send:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, msg); err != nil {
+				logger.Warn(logger.StatusWarn, "SSE Error: %v", err)
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSE writes one event; the event name is the broadcast type
+func writeSSE(w http.ResponseWriter, msg BroadcastMessage) error {
+	data, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, data)
+	return err
+}
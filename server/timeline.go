@@ -0,0 +1,345 @@
+package server
+
+import (
+	"fmt"
+	"margraf/graph"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The timeline is a merged stream of what moved the graph, newest first:
+// news impacts, shocks and decay sweeps (the graph's kept impacts) and market
+// anomalies, social pulses and edge anomalies (recorded from broadcasts). It
+// backs a "what happened today" view over GET /api/timeline and get_timeline.
+
+// Timeline entry kinds
+const (
+	TimelineNews   = "news"   // A headline's impact, including the shock it caused
+	TimelineShock  = "shock"  // A shock run from the console, a scenario or a monitor
+	TimelineDecay  = "decay"  // A temporal decay sweep
+	TimelineMarket = "market" // A daily price move past the anomaly threshold, or a pair alert
+	TimelineSocial = "social" // A scored social post or a mention spike
+	TimelineEdge   = "edge"   // An edge weight collapse or spike against its history
+)
+
+var timelineKinds = map[string]bool{
+	TimelineNews: true, TimelineShock: true, TimelineDecay: true, TimelineMarket: true, TimelineSocial: true, TimelineEdge: true,
+}
+
+const (
+	defaultTimelineSize  = 2000 // Market, social and edge entries kept
+	defaultMarketMove    = 0.05 // Daily price change recorded as an anomaly
+	defaultTimelineLimit = 50
+	maxTimelineLimit     = 500
+	maxTimelineNodes     = 10 // Affected nodes listed per entry
+)
+
+// TimelineNode is a node an entry affected
+type TimelineNode struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name,omitempty"`
+	HealthDelta float64 `json:"health_delta,omitempty"` // Impacts only
+}
+
+// TimelineEntry is one event on the timeline
+type TimelineEntry struct {
+	ID    string         `json:"id"` // An impact's event ID (see get_impact), otherwise "<kind>_<n>"
+	Kind  string         `json:"kind"`
+	Time  time.Time      `json:"time"`
+	Title string         `json:"title"`
+	Value float64        `json:"value"`           // Health moved (impacts), daily change (price moves), z-score (pair alerts), sentiment (posts), mentions over baseline (spikes)
+	Nodes []TimelineNode `json:"nodes,omitempty"` // Most affected first
+}
+
+// affects reports whether the entry names nodeID
+func (e TimelineEntry) affects(nodeID string) bool {
+	for _, n := range e.Nodes {
+		if n.ID == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// cursor marks the entry's place in the timeline for the next page
+func (e TimelineEntry) cursor() string {
+	return strconv.FormatInt(e.Time.UnixNano(), 10) + "_" + e.ID
+}
+
+// before reports whether e comes after o in the timeline (older, or as old
+// with a lower ID)
+func (e TimelineEntry) before(o TimelineEntry) bool {
+	if !e.Time.Equal(o.Time) {
+		return e.Time.Before(o.Time)
+	}
+	return e.ID < o.ID
+}
+
+// TimelinePayload is one page of the timeline
+type TimelinePayload struct {
+	Entries []TimelineEntry `json:"entries"`
+	Next    string          `json:"next,omitempty"` // Cursor of the next, older page; empty on the last
+}
+
+// timeline keeps the entries recorded from broadcasts
+type timeline struct {
+	mu         sync.Mutex
+	entries    []TimelineEntry // Oldest first
+	size       int
+	seq        int
+	marketMove float64
+}
+
+func newTimeline() *timeline {
+	return &timeline{size: defaultTimelineSize, marketMove: defaultMarketMove}
+}
+
+// SetTimeline sets how many market, social and edge entries the timeline keeps
+// and the daily price change recorded as a market anomaly (0 keeps the default)
+func (h *Hub) SetTimeline(size int, marketMove float64) {
+	h.timeline.mu.Lock()
+	defer h.timeline.mu.Unlock()
+	if size > 0 {
+		h.timeline.size = size
+	}
+	if marketMove > 0 {
+		h.timeline.marketMove = marketMove
+	}
+}
+
+// add keeps e under a new ID, dropping the oldest entries past the size
+func (t *timeline) add(e TimelineEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	e.ID = fmt.Sprintf("%s_%d", e.Kind, t.seq)
+	t.entries = append(t.entries, e)
+	if len(t.entries) > t.size {
+		t.entries = append(t.entries[:0:0], t.entries[len(t.entries)-t.size:]...)
+	}
+}
+
+// recordTimeline keeps the market anomalies, social pulses and edge anomalies
+// among broadcasts
+func (h *Hub) recordTimeline(msg BroadcastMessage) {
+	now := time.Now()
+	switch msg.Type {
+	case TypeMarketUpdate:
+		var p MarketUpdatePayload
+		h.timeline.mu.Lock()
+		move := h.timeline.marketMove
+		h.timeline.mu.Unlock()
+		if !decodeInto(msg.Payload, &p) || p.ID == "" || p.Change < move && p.Change > -move {
+			return
+		}
+		h.timeline.add(TimelineEntry{
+			Kind:  TimelineMarket,
+			Time:  now,
+			Title: fmt.Sprintf("%s moved %+.1f%% to %.2f %s", h.nodeName(p.ID), p.Change*100, p.Price, p.Currency),
+			Value: p.Change,
+			Nodes: []TimelineNode{h.timelineNode(p.ID)},
+		})
+	case TypePairAlert:
+		var p PairAlertPayload
+		if !decodeInto(msg.Payload, &p) {
+			return
+		}
+		h.timeline.add(TimelineEntry{
+			Kind:  TimelineMarket,
+			Time:  now,
+			Title: fmt.Sprintf("%s/%s spread at z %+.2f (%s)", p.Ticker1, p.Ticker2, p.ZScore, p.Action),
+			Value: p.ZScore,
+			Nodes: []TimelineNode{h.timelineNode(p.Asset1), h.timelineNode(p.Asset2)},
+		})
+	case TypeSocialPulse:
+		var p SocialPulsePayload
+		if !decodeInto(msg.Payload, &p) {
+			return
+		}
+		e := TimelineEntry{
+			Kind:  TimelineSocial,
+			Time:  now,
+			Title: fmt.Sprintf("[%s] @%s: %s", p.Platform, p.User, truncate(p.Content, 140)),
+			Value: p.Sentiment,
+		}
+		if p.NodeID != "" {
+			e.Nodes = []TimelineNode{h.timelineNode(p.NodeID)}
+		}
+		h.timeline.add(e)
+	case TypeMentionSpike:
+		var p MentionSpikePayload
+		if !decodeInto(msg.Payload, &p) || p.NodeID == "" {
+			return
+		}
+		h.timeline.add(TimelineEntry{
+			Kind:  TimelineSocial,
+			Time:  now,
+			Title: p.Message,
+			Value: p.Ratio,
+			Nodes: []TimelineNode{h.timelineNode(p.NodeID)},
+		})
+	case TypeEdgeAnomaly:
+		var p EdgeAnomalyPayload
+		if !decodeInto(msg.Payload, &p) {
+			return
+		}
+		h.timeline.add(TimelineEntry{
+			Kind:  TimelineEdge,
+			Time:  now,
+			Title: p.Message,
+			Value: p.Residual,
+			Nodes: []TimelineNode{h.timelineNode(p.SourceID), h.timelineNode(p.TargetID)},
+		})
+	}
+}
+
+// timelineNode references a node by ID and current name
+func (h *Hub) timelineNode(id string) TimelineNode {
+	n := TimelineNode{ID: id}
+	if name := h.nodeName(id); name != id {
+		n.Name = name
+	}
+	return n
+}
+
+// nodeName returns a node's name, or its ID when unknown
+func (h *Hub) nodeName(id string) string {
+	if h.graph != nil {
+		if n, ok := h.graph.GetNode(id); ok && n.Name != "" {
+			return n.Name
+		}
+	}
+	return id
+}
+
+// impactEntry turns a kept impact into a timeline entry
+func impactEntry(imp graph.Impact) TimelineEntry {
+	e := TimelineEntry{ID: imp.EventID, Kind: string(imp.Kind), Time: imp.Time, Title: imp.Description, Value: imp.HealthDelta}
+	if e.Title == "" {
+		e.Title = fmt.Sprintf("%s: %d nodes changed", imp.Kind, imp.NodesChanged)
+	}
+	for _, n := range imp.Nodes {
+		e.Nodes = append(e.Nodes, TimelineNode{ID: n.NodeID, Name: n.Name, HealthDelta: n.HealthDelta})
+	}
+	return e
+}
+
+// Timeline returns one page of the timeline, newest first
+func (h *Hub) Timeline(req TimelineRequest) TimelinePayload {
+	var all []TimelineEntry
+	if h.graph != nil {
+		impacts := h.graph.Impacts()
+		news := make(map[string]bool)
+		for _, imp := range impacts {
+			if imp.Kind == graph.ImpactNews {
+				news[imp.EventID] = true
+			}
+		}
+		for _, imp := range impacts {
+			// A headline's shock is part of its news impact
+			if imp.Kind == graph.ImpactShock && news[strings.TrimSuffix(imp.EventID, "_shock")] {
+				continue
+			}
+			all = append(all, impactEntry(imp))
+		}
+	}
+	h.timeline.mu.Lock()
+	all = append(all, h.timeline.entries...)
+	h.timeline.mu.Unlock()
+
+	kinds := make(map[string]bool, len(req.Kinds))
+	for _, k := range req.Kinds {
+		kinds[k] = true
+	}
+	entries := all[:0]
+	for _, e := range all {
+		switch {
+		case len(kinds) > 0 && !kinds[e.Kind],
+			!req.since.IsZero() && e.Time.Before(req.since),
+			!req.until.IsZero() && !e.Time.Before(req.until),
+			req.NodeID != "" && !e.affects(req.NodeID),
+			req.hasCursor && !e.before(req.cursor):
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[j].before(entries[i]) })
+
+	limit := defaultTimelineLimit
+	if req.Limit > 0 {
+		limit = req.Limit
+	}
+	page := TimelinePayload{Entries: []TimelineEntry{}}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.Next = entries[limit-1].cursor()
+	}
+	for _, e := range entries {
+		if len(e.Nodes) > maxTimelineNodes {
+			e.Nodes = e.Nodes[:maxTimelineNodes]
+		}
+		page.Entries = append(page.Entries, e)
+	}
+	return page
+}
+
+// HandleTimeline serves GET /api/timeline. Query parameters are those of
+// get_timeline, with kinds comma-separated and node for node_id.
+func (h *Hub) HandleTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+		return
+	}
+	q := r.URL.Query()
+	req := TimelineRequest{
+		Since:  q.Get("since"),
+		Until:  q.Get("until"),
+		NodeID: q.Get("node"),
+		Cursor: q.Get("cursor"),
+	}
+	for _, k := range strings.Split(q.Get("kinds"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			req.Kinds = append(req.Kinds, k)
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			writeInvalid(w, &FieldError{Field: "limit", Message: "must be a number"})
+			return
+		}
+		req.Limit = n
+	}
+	if err := req.validate(); err != nil {
+		writeInvalid(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Timeline(req))
+}
+
+// handleGetTimeline replies with one page of the timeline
+func (h *Hub) handleGetTimeline(sub *subscriber, msg IncomingMessage) {
+	var req TimelineRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	reply(sub, msg.ID, TypeTimeline, h.Timeline(req))
+}
+
+// truncate shortens s to n bytes at a word boundary, adding "..."
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= n {
+		return s
+	}
+	cut := strings.LastIndex(s[:n], " ")
+	if cut <= 0 {
+		cut = n
+	}
+	return s[:cut] + "..."
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"margraf/graph"
+	"net/http"
+)
+
+// TypesPayload is the answer of GET /api/types: the node and edge types the
+// graph knows, built-in and declared in config, with how many of each it holds
+type TypesPayload struct {
+	Nodes []graph.TypeInfo `json:"nodes"`
+	Edges []graph.TypeInfo `json:"edges"`
+}
+
+// HandleTypes serves GET /api/types, so clients can color and describe types
+// they were not built with
+func (h *Hub) HandleTypes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+		return
+	}
+	if h.graph == nil {
+		writeError(w, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+	writeJSON(w, http.StatusOK, TypesPayload{Nodes: h.graph.NodeTypeInfo(), Edges: h.graph.EdgeTypeInfo()})
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"margraf/graph"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Watch adds nodeID to the console's watch list; the watch hook receives its
+// events
+func (h *Hub) Watch(nodeID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.consoleWatch[nodeID] {
+		h.consoleWatch[nodeID] = true
+		h.watched[nodeID]++
+	}
+}
+
+// Unwatch removes nodeID from the console's watch list ("" = all), reporting
+// whether anything was removed
+func (h *Hub) Unwatch(nodeID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	removed := false
+	for id := range h.consoleWatch {
+		if nodeID == "" || id == nodeID {
+			delete(h.consoleWatch, id)
+			h.releaseLocked(id)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// Watching returns the console's watched nodes, sorted
+func (h *Hub) Watching() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return sortedKeys(h.consoleWatch)
+}
+
+// SetWatchHook registers fn to receive events for the console's watched nodes.
+// fn is called from the goroutine that made the change, so it must not block.
+func (h *Hub) SetWatchHook(fn func(WatchEventPayload)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watchHook = fn
+}
+
+// NotifyDelta passes a graph change to the watchers of the nodes it touches
+// and queues company_relations_update for the companies it may affect. It
+// never blocks, so it is safe to call from a graph change hook.
+func (h *Hub) NotifyDelta(d graph.Delta) {
+	h.queueRelations(d)
+	h.mu.Lock()
+	if len(h.watched) == 0 {
+		h.mu.Unlock()
+		return
+	}
+	h.mu.Unlock()
+	h.dispatchWatch(deltaWatchEvents(d))
+}
+
+// notifyBroadcast passes a broadcast to the watchers of the nodes it names
+func (h *Hub) notifyBroadcast(msg BroadcastMessage) {
+	h.mu.Lock()
+	if len(h.watched) == 0 {
+		h.mu.Unlock()
+		return
+	}
+	h.mu.Unlock()
+	h.dispatchWatch(broadcastWatchEvents(msg))
+}
+
+// dispatchWatch delivers each event to the connections and console watching
+// its node
+func (h *Hub) dispatchWatch(events []WatchEventPayload) {
+	var console []WatchEventPayload
+	var hook func(WatchEventPayload)
+
+	h.mu.Lock()
+	for _, e := range events {
+		if h.watched[e.NodeID] == 0 {
+			continue
+		}
+		for sub := range h.subscribers {
+			if sub.watching[e.NodeID] {
+				sub.deliver(BroadcastMessage{Type: TypeWatchEvent, Payload: e})
+			}
+		}
+		if h.consoleWatch[e.NodeID] {
+			console = append(console, e)
+		}
+	}
+	hook = h.watchHook
+	h.mu.Unlock()
+
+	if hook != nil {
+		for _, e := range console {
+			hook(e)
+		}
+	}
+}
+
+// watchLocked adds nodeIDs to a connection's watch list (must be called with h.mu held)
+func (h *Hub) watchLocked(sub *subscriber, nodeIDs []string) error {
+	if sub.watching == nil {
+		sub.watching = make(map[string]bool)
+	}
+	for _, id := range nodeIDs {
+		if sub.watching[id] {
+			continue
+		}
+		if len(sub.watching) >= maxWatchedNodes {
+			return fmt.Errorf("at most %d watched nodes", maxWatchedNodes)
+		}
+		sub.watching[id] = true
+		h.watched[id]++
+	}
+	return nil
+}
+
+// unwatchLocked removes nodeID from a connection's watch list ("" = all)
+// (must be called with h.mu held)
+func (h *Hub) unwatchLocked(sub *subscriber, nodeID string) {
+	for id := range sub.watching {
+		if nodeID == "" || id == nodeID {
+			delete(sub.watching, id)
+			h.releaseLocked(id)
+		}
+	}
+}
+
+// releaseLocked drops one watcher of id (must be called with h.mu held)
+func (h *Hub) releaseLocked(id string) {
+	if h.watched[id]--; h.watched[id] <= 0 {
+		delete(h.watched, id)
+	}
+}
+
+// handleWatch adds the payload's node_id to the connection's watch list
+func (h *Hub) handleWatch(sub *subscriber, msg IncomingMessage) {
+	var req NodeRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	nodeID := req.NodeID
+	if h.graph != nil {
+		if _, exists := h.graph.GetNode(nodeID); !exists {
+			replyError(sub, msg.ID, ErrCodeNotFound, "Node not found: "+nodeID)
+			return
+		}
+	}
+	h.mu.Lock()
+	err := h.watchLocked(sub, []string{nodeID})
+	watching := sortedKeys(sub.watching)
+	h.mu.Unlock()
+	if err != nil {
+		replyError(sub, msg.ID, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+	reply(sub, msg.ID, TypeWatching, WatchingPayload{NodeIDs: watching})
+}
+
+// handleUnwatch removes the payload's node_id from the connection's watch
+// list, or every node when node_id is missing
+func (h *Hub) handleUnwatch(sub *subscriber, msg IncomingMessage) {
+	var req UnwatchRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	h.mu.Lock()
+	h.unwatchLocked(sub, req.NodeID)
+	watching := sortedKeys(sub.watching)
+	h.mu.Unlock()
+	reply(sub, msg.ID, TypeWatching, WatchingPayload{NodeIDs: watching})
+}
+
+// parseWatch reads the nodes to watch from ?watch=a,b
+func parseWatch(r *http.Request) []string {
+	var ids []string
+	for _, v := range r.URL.Query()["watch"] {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// deltaWatchEvents describes a graph change once per node it touches
+func deltaWatchEvents(d graph.Delta) []WatchEventPayload {
+	now := time.Now()
+	switch d.Kind {
+	case graph.DeltaEdge, graph.DeltaEdgeRemoved:
+		if d.Edge == nil {
+			return nil
+		}
+		e := d.Edge
+		kind := WatchEdge
+		msg := fmt.Sprintf("%s -[%s]-> %s weight %.3f (%s)", e.SourceID, e.Type, e.TargetID, e.Weight, e.Status)
+		if d.Kind == graph.DeltaEdgeRemoved {
+			kind = WatchEdgeRemoved
+			msg = fmt.Sprintf("%s -[%s]-> %s removed", e.SourceID, e.Type, e.TargetID)
+		}
+		events := []WatchEventPayload{{NodeID: e.SourceID, Kind: kind, Message: msg, Edge: e, Time: now}}
+		if e.TargetID != e.SourceID {
+			events = append(events, WatchEventPayload{NodeID: e.TargetID, Kind: kind, Message: msg, Edge: e, Time: now})
+		}
+		return events
+	case graph.DeltaHealth:
+		health := d.Health
+		return []WatchEventPayload{{NodeID: d.NodeID, Kind: WatchHealth, Message: fmt.Sprintf("health %.3f", health), Health: &health, Time: now}}
+	case graph.DeltaNode:
+		if d.Node == nil {
+			return nil
+		}
+		health := d.Node.Health
+		return []WatchEventPayload{{NodeID: d.Node.ID, Kind: WatchNode, Message: fmt.Sprintf("%s updated (health %.3f)", d.Node.Name, health), Health: &health, Time: now}}
+	case graph.DeltaNote:
+		if d.Note == nil {
+			return nil
+		}
+		n := d.Note
+		msg := fmt.Sprintf("note by %s on %s: %s", n.Author, n.Subject(), n.Text)
+		if !n.OnEdge() {
+			return []WatchEventPayload{{NodeID: n.NodeID, Kind: WatchNote, Message: msg, Note: n, Time: now}}
+		}
+		events := []WatchEventPayload{{NodeID: n.SourceID, Kind: WatchNote, Message: msg, Note: n, Time: now}}
+		if n.TargetID != n.SourceID {
+			events = append(events, WatchEventPayload{NodeID: n.TargetID, Kind: WatchNote, Message: msg, Note: n, Time: now})
+		}
+		return events
+	}
+	return nil
+}
+
+// broadcastWatchEvents describes a broadcast once per node it names. Payloads
+// relayed from other instances arrive as decoded JSON, so both shapes are read
+// through a JSON round trip.
+func broadcastWatchEvents(msg BroadcastMessage) []WatchEventPayload {
+	now := time.Now()
+	switch msg.Type {
+	case TypeShockEvent:
+		var p ShockPayload
+		if !decodeInto(msg.Payload, &p) {
+			return nil
+		}
+		text := p.Description
+		if text == "" {
+			text = fmt.Sprintf("%s shock, impact %.2f", p.Kind, p.Impact)
+		}
+		var events []WatchEventPayload
+		for _, id := range append([]string{p.Target}, p.Targets...) {
+			if id != "" {
+				events = append(events, WatchEventPayload{NodeID: id, Kind: WatchShock, Message: text, Time: now})
+			}
+		}
+		return events
+	case TypeGraphNotice:
+		var p GraphNoticePayload
+		if !decodeInto(msg.Payload, &p) || p.NodeID == "" {
+			return nil
+		}
+		return []WatchEventPayload{{NodeID: p.NodeID, Kind: WatchNotice, Message: p.Message, Health: p.Health, Time: now}}
+	case TypeMentionSpike:
+		var p MentionSpikePayload
+		if !decodeInto(msg.Payload, &p) || p.NodeID == "" {
+			return nil
+		}
+		return []WatchEventPayload{{NodeID: p.NodeID, Kind: WatchVolume, Message: p.Message, Time: now}}
+	case TypeEdgeAnomaly:
+		var p EdgeAnomalyPayload
+		if !decodeInto(msg.Payload, &p) {
+			return nil
+		}
+		return []WatchEventPayload{
+			{NodeID: p.SourceID, Kind: WatchAnomaly, Message: p.Message, Time: now},
+			{NodeID: p.TargetID, Kind: WatchAnomaly, Message: p.Message, Time: now},
+		}
+	case TypeMarketUpdate:
+		var p MarketUpdatePayload
+		if !decodeInto(msg.Payload, &p) || p.ID == "" {
+			return nil
+		}
+		health := p.Health
+		return []WatchEventPayload{{NodeID: p.ID, Kind: WatchMarket, Message: fmt.Sprintf("price %.2f %s", p.Price, p.Currency), Health: &health, Time: now}}
+	}
+	return nil
+}
+
+func decodeInto(payload interface{}, v interface{}) bool {
+	data, err := json.Marshal(payload)
+	return err == nil && json.Unmarshal(data, v) == nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
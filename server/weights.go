@@ -0,0 +1,78 @@
+package server
+
+import (
+	"fmt"
+	"margraf/graph"
+	"net/http"
+	"time"
+)
+
+// WeightHistoryPayload is the answer of GET /api/edges/history: the weight
+// series of each matching edge between two nodes
+type WeightHistoryPayload struct {
+	Source string               `json:"source"` // Node names
+	Target string               `json:"target"`
+	Series []graph.WeightSeries `json:"series"`
+}
+
+// HandleWeightHistory serves GET /api/edges/history: the weight history of
+// the edges from source to target as plottable series, optionally of one type
+// and HS code and since a time (as in /api/timeline)
+func (h *Hub) HandleWeightHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorPayload{Code: ErrCodeInvalidRequest, Message: "use GET"})
+		return
+	}
+	q := r.URL.Query()
+	source, target := q.Get("source"), q.Get("target")
+	if err := checkID("source", source, true); err != nil {
+		writeInvalid(w, err)
+		return
+	}
+	if err := checkID("target", target, true); err != nil {
+		writeInvalid(w, err)
+		return
+	}
+	since, err := parseTimelineTime(q.Get("since"), time.Now())
+	if err != nil {
+		writeInvalid(w, &FieldError{Field: "since", Message: err.Error()})
+		return
+	}
+	if h.graph == nil {
+		writeError(w, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+
+	edgeType, hs, hasHS := graph.EdgeType(q.Get("type")), q.Get("hs"), q.Has("hs")
+	p := WeightHistoryPayload{Source: h.nodeName(source), Target: h.nodeName(target), Series: []graph.WeightSeries{}}
+	for _, s := range h.graph.WeightSeries(source, target) {
+		if edgeType != "" && s.Type != edgeType || hasHS && s.Commodity != hs {
+			continue
+		}
+		if !since.IsZero() {
+			s = seriesSince(s, since)
+		}
+		p.Series = append(p.Series, s)
+	}
+	if len(p.Series) == 0 {
+		writeError(w, ErrCodeNotFound, fmt.Sprintf("no edge history from %q to %q", source, target))
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// seriesSince keeps the points at or after since, with the totals over them
+func seriesSince(s graph.WeightSeries, since time.Time) graph.WeightSeries {
+	points := s.Points
+	s.Points, s.Decay, s.Event = []graph.WeightPoint{}, 0, 0
+	for _, p := range points {
+		if p.Time.Before(since) {
+			continue
+		}
+		s.Points = append(s.Points, p)
+		s.Decay += p.Decay
+		s.Event += p.Event
+	}
+	return s
+}
@@ -2,10 +2,20 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"margraf/bus"
 	"margraf/graph"
+	"margraf/history"
 	"margraf/logger"
+	"margraf/public"
+	"margraf/syserr"
+	"margraf/task"
+	"margraf/trading"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -16,22 +26,119 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// BroadcastMessage is a server -> client frame. Each Type has one payload
+// shape, listed with the Type constants in protocol.go.
 type BroadcastMessage struct {
-	Type    string      `json:"type"`    // "graph_update", "news_alert", "social_pulse"
-	Payload interface{} `json:"payload"` // The actual data
+	Version int         `json:"v"`            // ProtocolVersion
+	ID      string      `json:"id,omitempty"` // Request ID echoed on responses; empty for broadcasts
+	Type    string      `json:"type"`         // One of the Type constants
+	Payload interface{} `json:"payload"`      // The actual data
+
+	remote bool // Received from another instance; not relayed again
+}
+
+// Error codes carried in ErrorPayload
+const (
+	ErrCodeInvalidRequest = "invalid_request"
+	ErrCodeUnknownType    = "unknown_type"
+	ErrCodeNotFound       = "not_found"
+	ErrCodeUnavailable    = "unavailable"
+	ErrCodeRateLimited    = "rate_limited"
+	ErrCodeForbidden      = "forbidden"
+	ErrCodeInternal       = "internal"
+)
+
+// ErrorPayload is the payload of "error" responses
+type ErrorPayload struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Field     string `json:"field,omitempty"` // Request field that failed validation
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// subscriberBuffer is the per-subscriber queue length before broadcasts are dropped
+const subscriberBuffer = 256
+
+// subscriber is a single stream consumer (WebSocket or SSE connection)
+type subscriber struct {
+	send      chan BroadcastMessage
+	topics    map[string]bool // nil = all broadcast types
+	watching  map[string]bool // Nodes whose watch_event the subscriber gets, whatever its topics
+	following map[string]bool // Companies whose company_relations_update the subscriber gets
+}
+
+// wants reports whether the subscriber's topic filter accepts msgType
+func (s *subscriber) wants(msgType string) bool {
+	return s.topics == nil || s.topics[msgType]
+}
+
+// deliver queues a message without blocking the caller; full queues drop it
+func (s *subscriber) deliver(msg BroadcastMessage) bool {
+	msg.Version = ProtocolVersion
+	select {
+	case s.send <- msg:
+		return true
+	default:
+		return false
+	}
 }
 
 type Hub struct {
-	clients   map[*websocket.Conn]bool
-	broadcast chan BroadcastMessage
-	mu        sync.Mutex
-	graph     *graph.Graph
+	subscribers map[*subscriber]bool
+	broadcast   chan BroadcastMessage
+	mu          sync.Mutex
+	graph       *graph.Graph
+
+	httpLimiter *RateLimiter // HTTP requests and WS/SSE connects
+	msgLimiter  *RateLimiter // Incoming WS messages
+
+	relay func(payload interface{}) // Publishes broadcasts to other instances (nil = single instance)
+
+	sessions *SessionStore // Saved client state (nil = sessions disabled)
+
+	adminToken    string                 // Token admin clients present ("" = admin disabled)
+	adminFailures *RateLimiter           // Failed admin attempts per IP
+	adminActions  map[string]AdminAction // Operational commands by name
+
+	watched      map[string]int          // Watchers per node, connections and console together
+	consoleWatch map[string]bool         // Nodes watched from the console
+	watchHook    func(WatchEventPayload) // Receives the console's watch events
+
+	followed         map[string]int                     // Followers per company (see relations.go)
+	relationsSent    map[string]*graph.CompanyRelations // Relations followers last received, per company
+	relationsPending map[string]bool                    // Companies due a company_relations_update
+	relationsKick    chan struct{}                      // Wakes pushRelations
+
+	paper func() *trading.PaperPerformance // Paper trader performance (nil = not configured)
+	ask   AskFunc                          // Answers questions (nil = not configured)
+
+	timeline *timeline // Market anomalies and social pulses (see timeline.go)
+
+	embeddings embeddingFile // Node vectors for /api/embeddings (see embeddings.go)
+
+	analytics func() graph.SamplingOptions // Sampling settings for /api/centrality and /api/hitting-time (nil = defaults)
+	history   *history.Store               // End-of-day rows for /api/history (nil = not configured)
+
+	started time.Time   // For /healthz
+	running atomic.Bool // Set once Run dispatches broadcasts
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan BroadcastMessage),
+		subscribers:  make(map[*subscriber]bool),
+		broadcast:    make(chan BroadcastMessage),
+		watched:      make(map[string]int),
+		consoleWatch: make(map[string]bool),
+
+		followed:         make(map[string]int),
+		relationsSent:    make(map[string]*graph.CompanyRelations),
+		relationsPending: make(map[string]bool),
+		relationsKick:    make(chan struct{}, 1),
+
+		adminFailures: NewRateLimiter("admin_auth", maxAdminFailures, maxAdminFailures),
+
+		timeline: newTimeline(),
+		started:  time.Now(),
 	}
 }
 
@@ -40,196 +147,518 @@ func (h *Hub) SetGraph(g *graph.Graph) {
 	h.graph = g
 }
 
+// SetSessions enables per-token client sessions stored in s
+func (h *Hub) SetSessions(s *SessionStore) {
+	h.sessions = s
+}
+
+// SetRateLimits sets per-client limits for HTTP endpoints and WS messages (nil = unlimited)
+func (h *Hub) SetRateLimits(httpLimiter, msgLimiter *RateLimiter) {
+	h.httpLimiter = httpLimiter
+	h.msgLimiter = msgLimiter
+}
+
+// SetBus relays broadcasts to other instances over b and rebroadcasts theirs
+// locally. graph_update snapshots are not relayed; each instance builds its
+// own from its replicated graph.
+func (h *Hub) SetBus(b *bus.Bus) {
+	if b == nil {
+		return
+	}
+	h.relay = b.Async(bus.TopicHub, subscriberBuffer)
+	b.Subscribe(bus.TopicHub, func(m bus.Message) {
+		var msg BroadcastMessage
+		if err := json.Unmarshal(m.Payload, &msg); err != nil {
+			logger.Warn(logger.StatusWarn, "Bus: bad hub message from %s: %v", m.Origin, err)
+			return
+		}
+		msg.remote = true
+		h.broadcast <- msg
+	})
+}
+
 func (h *Hub) Run() {
+	h.running.Store(true)
+	defer h.running.Store(false)
+	go h.pushRelations()
 	for msg := range h.broadcast {
+		if h.relay != nil && !msg.remote && msg.Type != TypeGraphUpdate {
+			h.relay(msg)
+		}
+
 		h.mu.Lock()
-		for client := range h.clients {
-			err := client.WriteJSON(msg)
-			if err != nil {
-				logger.Warn(logger.StatusWarn, "WS Error: %v", err)
-				client.Close()
-				delete(h.clients, client)
+		for sub := range h.subscribers {
+			if sub.wants(msg.Type) && !sub.deliver(msg) {
+				logger.Warn(logger.StatusWarn, "Subscriber queue full, dropped %s", msg.Type)
 			}
 		}
 		h.mu.Unlock()
+		h.notifyBroadcast(msg)
+		h.recordTimeline(msg)
+	}
+}
+
+// subscribe registers a new consumer for broadcasts matching topics (empty =
+// all) and the watch events of the nodes in watch (first maxWatchedNodes)
+func (h *Hub) subscribe(topics, watch []string) *subscriber {
+	sub := &subscriber{send: make(chan BroadcastMessage, subscriberBuffer)}
+	if len(topics) > 0 {
+		sub.topics = make(map[string]bool, len(topics))
+		for _, t := range topics {
+			sub.topics[t] = true
+		}
 	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = true
+	h.watchLocked(sub, watch)
+	h.mu.Unlock()
+	return sub
+}
+
+// setTopics replaces a subscriber's topic filter (empty = all)
+func (h *Hub) setTopics(sub *subscriber, topics []string) {
+	var filter map[string]bool
+	if len(topics) > 0 {
+		filter = make(map[string]bool, len(topics))
+		for _, t := range topics {
+			filter[t] = true
+		}
+	}
+	h.mu.Lock()
+	sub.topics = filter
+	h.mu.Unlock()
+}
+
+// unsubscribe removes a consumer and closes its queue
+func (h *Hub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[sub] {
+		h.unwatchLocked(sub, "")
+		h.unfollowAllLocked(sub)
+		delete(h.subscribers, sub)
+		close(sub.send)
+	}
+}
+
+// subscriberCount returns the number of connected WS and SSE clients
+func (h *Hub) subscriberCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
 }
 
+// parseTopics reads the topic filter from ?topics=a,b and/or repeated ?topic= params
+func parseTopics(r *http.Request) []string {
+	var topics []string
+	q := r.URL.Query()
+	for _, v := range append(q["topics"], q["topic"]...) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				topics = append(topics, t)
+			}
+		}
+	}
+	return topics
+}
+
+// Broadcast sends a message to every subscriber of msgType. payload should be
+// the type's payload struct from protocol.go; pre-encoded JSON goes in as a
+// json.RawMessage.
 func (h *Hub) Broadcast(msgType string, payload interface{}) {
 	h.broadcast <- BroadcastMessage{
+		Version: ProtocolVersion,
 		Type:    msgType,
 		Payload: payload,
 	}
 }
 
-// IncomingMessage represents a message from the client
+// IncomingMessage represents a message from the client. Handlers decode the
+// payload into the type's request struct (see request.go).
 type IncomingMessage struct {
-	Type    string                 `json:"type"`
-	Payload map[string]interface{} `json:"payload"`
+	ID      string          `json:"id,omitempty"` // Optional, echoed in the response
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
 }
 
 func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	stream, err := parseStream(r)
+	if err != nil {
+		writeInvalid(w, err)
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logger.Warn(logger.StatusWarn, "Upgrade error: %v", err)
 		return
 	}
+	conn.SetReadLimit(maxMessageBytes)
 
-	h.mu.Lock()
-	h.clients[conn] = true
-	h.mu.Unlock()
+	key := clientKey(r)
+	session, hasSession := h.sessions.Get(key)
+	topics := stream.Topics
+	if len(topics) == 0 && hasSession {
+		topics = session.Topics
+	}
+	sub := h.subscribe(topics, stream.Watch)
 
-	// Send initial "connected" message
-	conn.WriteJSON(BroadcastMessage{Type: "system", Payload: "Connected to Margraf Stream"})
+	// Send initial "connected" message, then any saved session
+	sub.deliver(BroadcastMessage{Type: TypeSystem, Payload: SystemPayload{Message: "Connected to Margraf Stream"}})
+	if hasSession {
+		sub.deliver(BroadcastMessage{Type: TypeSession, Payload: session})
+	}
+
+	// All writes go through the subscriber queue so the conn has a single writer
+	go h.writeLoop(conn, sub)
 
 	// Start listening for incoming messages from this client
-	go h.handleClientMessages(conn)
+	go h.handleClientMessages(conn, sub, clientIP(r), key, headerKey(r))
 }
 
-// handleClientMessages listens for incoming messages from a client
-func (h *Hub) handleClientMessages(conn *websocket.Conn) {
+// writeLoop drains a subscriber's queue onto its WebSocket connection
+func (h *Hub) writeLoop(conn *websocket.Conn, sub *subscriber) {
+	for msg := range sub.send {
+		if err := conn.WriteJSON(msg); err != nil {
+			logger.Warn(logger.StatusWarn, "WS Error: %v", err)
+			// Closing the conn ends handleClientMessages, which unsubscribes
+			conn.Close()
+			for range sub.send {
+			}
+			return
+		}
+	}
+}
+
+// handleClientMessages listens for incoming messages from a client. key
+// identifies it for sessions and limits, adminKey (see headerKey) for admin
+// actions.
+func (h *Hub) handleClientMessages(conn *websocket.Conn, sub *subscriber, ip, key, adminKey string) {
 	defer func() {
-		h.mu.Lock()
-		delete(h.clients, conn)
-		h.mu.Unlock()
+		h.unsubscribe(sub)
 		conn.Close()
 	}()
 
 	for {
-		var msg IncomingMessage
-		err := conn.ReadJSON(&msg)
+		_, data, err := conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				logger.Warn(logger.StatusWarn, "WS message over %d bytes, closing connection", maxMessageBytes)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				logger.Warn(logger.StatusWarn, "WS read error: %v", err)
 			}
 			break
 		}
 
+		var msg IncomingMessage
+		parseErr := json.Unmarshal(data, &msg)
+		if !h.msgLimiter.allowClient(ip, key) {
+			replyError(sub, msg.ID, ErrCodeRateLimited, "Too many requests")
+			continue
+		}
+		if parseErr != nil {
+			replyInvalid(sub, msg.ID, &FieldError{Message: "message must be a JSON object with a type"})
+			continue
+		}
+		if msg.Type == "" {
+			replyInvalid(sub, msg.ID, &FieldError{Field: "type", Message: "is required"})
+			continue
+		}
+
 		// Handle different message types
 		switch msg.Type {
 		case "get_company_relations":
-			h.handleGetCompanyRelations(conn, msg.Payload)
+			h.handleGetCompanyRelations(sub, msg)
+		case "get_nation_relations":
+			h.handleGetNationRelations(sub, msg)
 		case "get_companies_list":
-			h.handleGetCompaniesList(conn)
+			h.handleGetCompaniesList(sub, msg)
 		case "get_full_graph":
-			h.handleGetFullGraph(conn)
+			h.handleGetFullGraph(sub, msg)
+		case "get_projection":
+			h.handleGetProjection(sub, msg)
+		case "get_health_history":
+			h.handleGetHealthHistory(sub, msg)
+		case "get_impact":
+			h.handleGetImpact(sub, msg)
+		case "get_paper_performance":
+			h.handleGetPaperPerformance(sub, msg)
+		case "get_calendar":
+			h.handleGetCalendar(sub, msg)
+		case "ask":
+			h.handleAsk(sub, msg)
+		case "get_tasks":
+			reply(sub, msg.ID, TypeTasks, task.List())
+		case "cancel_task":
+			h.handleCancelTask(sub, msg)
+		case "get_system_errors":
+			reply(sub, msg.ID, TypeSystemErrors, syserr.Recent())
+		case "get_session":
+			h.handleGetSession(sub, msg, key)
+		case "update_session":
+			h.handleUpdateSession(sub, msg, key)
+		case "watch":
+			h.handleWatch(sub, msg)
+		case "unwatch":
+			h.handleUnwatch(sub, msg)
+		case "add_note":
+			h.handleAddNote(sub, msg)
+		case "get_notes":
+			h.handleGetNotes(sub, msg)
+		case "delete_note":
+			h.handleDeleteNote(sub, msg)
+		case "get_timeline":
+			h.handleGetTimeline(sub, msg)
+		case "admin":
+			h.handleAdmin(sub, msg, ip, adminKey)
 		default:
 			logger.Warn(logger.StatusWarn, "Unknown message type: %s", msg.Type)
+			replyError(sub, msg.ID, ErrCodeUnknownType, "Unknown message type: "+msg.Type)
 		}
 	}
 }
 
-// handleGetCompanyRelations handles requests for company relationship data
-func (h *Hub) handleGetCompanyRelations(conn *websocket.Conn, payload map[string]interface{}) {
+// reply sends a response to a single client, echoing the request ID
+func reply(sub *subscriber, id, msgType string, payload interface{}) {
+	sub.deliver(BroadcastMessage{ID: id, Type: msgType, Payload: payload})
+}
+
+// replyError sends a structured error response to a single client
+func replyError(sub *subscriber, id, code, message string) {
+	reply(sub, id, TypeError, ErrorPayload{Code: code, Message: message, RequestID: id})
+}
+
+// handleGetCompanyRelations handles requests for company relationship data.
+// The connection then gets company_relations_update when they change.
+func (h *Hub) handleGetCompanyRelations(sub *subscriber, msg IncomingMessage) {
 	if h.graph == nil {
-		conn.WriteJSON(BroadcastMessage{
-			Type:    "error",
-			Payload: "Graph not initialized",
-		})
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
 		return
 	}
 
-	companyID, ok := payload["company_id"].(string)
-	if !ok {
-		conn.WriteJSON(BroadcastMessage{
-			Type:    "error",
-			Payload: "Invalid company_id",
-		})
+	var req CompanyRelationsRequest
+	if !decodeRequest(sub, msg, &req) {
 		return
 	}
+	companyID := req.CompanyID
 
 	relations, err := h.graph.GetCompanyRelations(companyID)
 	if err != nil {
-		conn.WriteJSON(BroadcastMessage{
-			Type:    "error",
-			Payload: err.Error(),
-		})
+		replyError(sub, msg.ID, ErrCodeNotFound, err.Error())
+		return
+	}
+
+	h.mu.Lock()
+	h.followLocked(sub, companyID, relations)
+	h.mu.Unlock()
+	reply(sub, msg.ID, TypeCompanyRelations, relations)
+}
+
+// handleGetNationRelations handles requests for nation relationship data
+func (h *Hub) handleGetNationRelations(sub *subscriber, msg IncomingMessage) {
+	if h.graph == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+
+	var req NationRelationsRequest
+	if !decodeRequest(sub, msg, &req) {
 		return
 	}
 
-	// Convert to JSON to send back
-	relationsJSON, err := json.Marshal(relations)
+	relations, err := h.graph.GetNationRelations(req.NationID)
 	if err != nil {
-		conn.WriteJSON(BroadcastMessage{
-			Type:    "error",
-			Payload: "Failed to encode relations",
-		})
+		replyError(sub, msg.ID, ErrCodeNotFound, err.Error())
 		return
 	}
 
-	conn.WriteJSON(BroadcastMessage{
-		Type:    "company_relations",
-		Payload: string(relationsJSON),
-	})
+	reply(sub, msg.ID, TypeNationRelations, relations)
 }
 
 // handleGetCompaniesList handles requests for the list of all companies
-func (h *Hub) handleGetCompaniesList(conn *websocket.Conn) {
+func (h *Hub) handleGetCompaniesList(sub *subscriber, msg IncomingMessage) {
 	if h.graph == nil {
-		conn.WriteJSON(BroadcastMessage{
-			Type:    "error",
-			Payload: "Graph not initialized",
-		})
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
 		return
 	}
 
-	companies := make([]map[string]interface{}, 0)
+	companies := make([]CompanySummary, 0)
 
 	h.graph.NodesRange(func(n *graph.Node) {
 		if n.Type == graph.NodeTypeCorporation {
-			companies = append(companies, map[string]interface{}{
-				"id":   n.ID,
-				"name": n.Name,
-			})
+			companies = append(companies, CompanySummary{ID: n.ID, Name: n.Name})
 		}
 	})
 
-	companiesJSON, err := json.Marshal(companies)
+	reply(sub, msg.ID, TypeCompaniesList, companies)
+}
+
+// handleGetFullGraph handles requests for the complete graph data
+func (h *Hub) handleGetFullGraph(sub *subscriber, msg IncomingMessage) {
+	if h.graph == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+
+	// Export the graph to JSON format
+	graphJSON, err := h.graph.ToJSON()
 	if err != nil {
-		conn.WriteJSON(BroadcastMessage{
-			Type:    "error",
-			Payload: "Failed to encode companies",
-		})
+		replyError(sub, msg.ID, ErrCodeInternal, "Failed to export graph")
 		return
 	}
 
-	conn.WriteJSON(BroadcastMessage{
-		Type:    "companies_list",
-		Payload: string(companiesJSON),
-	})
+	reply(sub, msg.ID, TypeGraphUpdate, json.RawMessage(graphJSON))
 }
 
-// handleGetFullGraph handles requests for the complete graph data
-func (h *Hub) handleGetFullGraph(conn *websocket.Conn) {
+// handleGetProjection handles requests for a nation- or industry-level summary graph
+func (h *Hub) handleGetProjection(sub *subscriber, msg IncomingMessage) {
 	if h.graph == nil {
-		conn.WriteJSON(BroadcastMessage{
-			Type:    "error",
-			Payload: "Graph not initialized",
-		})
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
 		return
 	}
 
-	// Export the graph to JSON format
-	graphJSON, err := h.graph.ToJSON()
+	var req ProjectionRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	level := graph.ProjectionLevel(req.Level)
+	if level == "" {
+		level = graph.ProjectionNation
+	}
+
+	projection, err := h.graph.Project(level)
 	if err != nil {
-		conn.WriteJSON(BroadcastMessage{
-			Type:    "error",
-			Payload: "Failed to export graph",
-		})
+		replyError(sub, msg.ID, ErrCodeInvalidRequest, err.Error())
 		return
 	}
 
-	conn.WriteJSON(BroadcastMessage{
-		Type:    "graph_update",
-		Payload: graphJSON,
-	})
+	reply(sub, msg.ID, TypeProjection, projection)
+}
+
+// handleGetHealthHistory handles requests for a node's health time series
+func (h *Hub) handleGetHealthHistory(sub *subscriber, msg IncomingMessage) {
+	if h.graph == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+
+	var req NodeRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+
+	history, err := h.graph.GetHealthHistory(req.NodeID)
+	if err != nil {
+		replyError(sub, msg.ID, ErrCodeNotFound, err.Error())
+		return
+	}
+
+	reply(sub, msg.ID, TypeHealthHistory, graph.HealthHistory{NodeID: req.NodeID, History: history})
+}
+
+// handleGetImpact returns the impact of an event ID, the latest impact
+// (optionally of a kind) without one, or every kept impact with "list": true
+func (h *Hub) handleGetImpact(sub *subscriber, msg IncomingMessage) {
+	if h.graph == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Graph not initialized")
+		return
+	}
+
+	var req ImpactRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	if req.List {
+		reply(sub, msg.ID, TypeImpacts, h.graph.Impacts())
+		return
+	}
+	var impact graph.Impact
+	var ok bool
+	if req.EventID != "" {
+		impact, ok = h.graph.ImpactOf(req.EventID)
+	} else {
+		impact, ok = h.graph.LastImpact(graph.ImpactKind(req.Kind))
+	}
+	if !ok {
+		replyError(sub, msg.ID, ErrCodeNotFound, "No impact recorded")
+		return
+	}
+	reply(sub, msg.ID, TypeImpact, impact)
+}
+
+// handleGetSession returns the client's saved session (empty if none)
+func (h *Hub) handleGetSession(sub *subscriber, msg IncomingMessage, key string) {
+	if h.sessions == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Sessions are disabled")
+		return
+	}
+	if _, ok := sessionID(key); !ok {
+		replyError(sub, msg.ID, ErrCodeInvalidRequest, errSessionNoToken.Error())
+		return
+	}
+	session, ok := h.sessions.Get(key)
+	if !ok {
+		session = Session{WatchedNodes: []string{}, Topics: []string{}}
+	}
+	reply(sub, msg.ID, TypeSession, session)
+}
+
+// handleUpdateSession saves the session fields in the payload. A new topic
+// list also becomes this connection's broadcast filter.
+func (h *Hub) handleUpdateSession(sub *subscriber, msg IncomingMessage, key string) {
+	if h.sessions == nil {
+		replyError(sub, msg.ID, ErrCodeUnavailable, "Sessions are disabled")
+		return
+	}
+	var update SessionUpdate
+	if !decodeRequest(sub, msg, &update) {
+		return
+	}
+	session, err := h.sessions.Update(key, update)
+	if err != nil {
+		replyInvalid(sub, msg.ID, err)
+		return
+	}
+	if update.Topics != nil {
+		h.setTopics(sub, session.Topics)
+	}
+	reply(sub, msg.ID, TypeSession, session)
+}
+
+// handleCancelTask cancels a running background task
+func (h *Hub) handleCancelTask(sub *subscriber, msg IncomingMessage) {
+	var req CancelTaskRequest
+	if !decodeRequest(sub, msg, &req) {
+		return
+	}
+	if err := task.Cancel(req.TaskID); err != nil {
+		replyError(sub, msg.ID, ErrCodeNotFound, err.Error())
+		return
+	}
+	reply(sub, msg.ID, TypeTasks, task.List())
 }
 
 func StartServer(h *Hub, port string) {
-	http.HandleFunc("/ws", h.HandleWebSocket)
-	http.Handle("/", http.FileServer(http.Dir("./public")))
+	http.Handle("/ws", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleWebSocket)))
+	http.Handle("/events", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleSSE)))
+	http.Handle("/admin/", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleAdmin)))
+	http.Handle("/paper", h.httpLimiter.Middleware(http.HandlerFunc(h.HandlePaper)))
+	http.Handle("/api/timeline", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleTimeline)))
+	http.Handle("/api/embeddings", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleEmbeddings)))
+	http.Handle("/api/edges/history", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleWeightHistory)))
+	http.Handle("/api/centrality", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleCentrality)))
+	http.Handle("/api/hitting-time", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleHittingTime)))
+	http.Handle("/api/history", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleHistory)))
+	http.Handle("/api/types", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleTypes)))
+	http.Handle("/api/schema", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleSchema)))
+	http.Handle("/api/openapi.json", h.httpLimiter.Middleware(http.HandlerFunc(h.HandleOpenAPI)))
+	http.Handle("/", h.httpLimiter.Middleware(http.FileServer(http.FS(public.Assets))))
+	// Probes are not rate limited, so a busy client can't get the instance restarted
+	http.HandleFunc("/healthz", h.HandleHealth)
+	http.HandleFunc("/readyz", h.HandleReady)
 
 	logger.Info(logger.StatusGlob, "WebSocket Server started on ws://localhost%s/ws", port)
+	logger.Info(logger.StatusGlob, "Event stream available at http://localhost%s/events", port)
 	logger.Info(logger.StatusGlob, "Web Dashboard available at http://localhost%s", port)
 
 	go func() {
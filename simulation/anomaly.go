@@ -0,0 +1,140 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/pipeline"
+	"margraf/server"
+	"sync"
+	"time"
+)
+
+// EdgeAnomalyMonitor checks edge weight changes against each edge's own
+// history whenever the graph changes, and broadcasts every change far outside
+// it as "edge_anomaly".
+type EdgeAnomalyMonitor struct {
+	Graph    *graph.Graph
+	Hub      *server.Hub
+	Options  graph.EdgeAnomalyOptions // Since is set by the monitor
+	Window   time.Duration            // How far back changes are checked at startup, and alerts remembered
+	Debounce time.Duration            // Minimum time between checks
+
+	notify chan struct{}
+
+	mu      sync.Mutex
+	checked time.Time            // Changes up to here have been judged
+	alerted map[string]time.Time // Alerted changes by key, to raise each once
+}
+
+// NewEdgeAnomalyMonitor creates a monitor with a 24h window and default options
+func NewEdgeAnomalyMonitor(g *graph.Graph, h *server.Hub) *EdgeAnomalyMonitor {
+	return &EdgeAnomalyMonitor{
+		Graph:    g,
+		Hub:      h,
+		Window:   24 * time.Hour,
+		Debounce: 2 * time.Second,
+		notify:   make(chan struct{}, 1),
+		alerted:  make(map[string]time.Time),
+	}
+}
+
+// Notify schedules a check. It never blocks, so it is safe to call from the
+// graph change hook.
+func (m *EdgeAnomalyMonitor) Notify() {
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Start checks on every notification (at most once per Debounce) and at
+// least once per interval, so replicated changes are caught too. It returns
+// when ctx is cancelled.
+func (m *EdgeAnomalyMonitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	logger.Info(logger.StatusMon, "Edge Anomaly Monitor active. |z| above %.1f, check at least every %v...", m.threshold(), interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.notify:
+		case <-ticker.C:
+		}
+		if !pipeline.Enabled(pipeline.Anomalies) {
+			continue
+		}
+		m.Check()
+		time.Sleep(m.Debounce)
+	}
+}
+
+// threshold is the |z| the options flag, for logging
+func (m *EdgeAnomalyMonitor) threshold() float64 {
+	if m.Options.Threshold > 0 {
+		return m.Options.Threshold
+	}
+	return 3
+}
+
+// Check judges the changes made since the last check and broadcasts the
+// anomalies among them, returning those it raised
+func (m *EdgeAnomalyMonitor) Check() []server.EdgeAnomalyPayload {
+	now := time.Now()
+	m.mu.Lock()
+	since := m.checked
+	if since.IsZero() {
+		since = now.Add(-m.Window)
+	}
+	// A change can be recorded a moment before the check that misses it, so
+	// overlap the previous check and rely on alerted to skip repeats
+	opts := m.Options
+	opts.Since = since.Add(-m.Debounce)
+	m.checked = now
+	for key, at := range m.alerted {
+		if now.Sub(at) > m.Window {
+			delete(m.alerted, key)
+		}
+	}
+	m.mu.Unlock()
+
+	var raised []server.EdgeAnomalyPayload
+	for _, a := range m.Graph.EdgeAnomalies(opts) {
+		m.mu.Lock()
+		_, seen := m.alerted[a.Key()]
+		m.alerted[a.Key()] = now
+		m.mu.Unlock()
+		if seen {
+			continue
+		}
+		p := server.EdgeAnomalyPayload{EdgeAnomaly: a, SourceName: m.nodeName(a.SourceID), TargetName: m.nodeName(a.TargetID)}
+		p.Message = AnomalyMessage(p)
+		logger.Warn(logger.StatusTrend, "Edge anomaly: %s", p.Message)
+		if m.Hub != nil {
+			m.Hub.Broadcast(server.TypeEdgeAnomaly, p)
+		}
+		raised = append(raised, p)
+	}
+	return raised
+}
+
+// nodeName returns a node's name, or its ID when unknown
+func (m *EdgeAnomalyMonitor) nodeName(id string) string {
+	if n, ok := m.Graph.GetNode(id); ok && n.Name != "" {
+		return n.Name
+	}
+	return id
+}
+
+// AnomalyMessage describes an edge anomaly in words
+func AnomalyMessage(p server.EdgeAnomalyPayload) string {
+	verb := "collapsed"
+	if p.Kind == graph.EdgeSpike {
+		verb = "spiked"
+	}
+	return fmt.Sprintf("%s -[%s]-> %s %s from %.2f to %.2f (%.2f expected after decay, z %+.1f over %d changes)",
+		p.SourceName, p.Type, p.TargetName, verb, p.From, p.To, p.Expected, p.ZScore, p.Changes)
+}
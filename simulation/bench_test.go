@@ -0,0 +1,17 @@
+package simulation_test
+
+import (
+	"io"
+	"margraf/bench"
+	"margraf/logger"
+	"testing"
+)
+
+// The body lives in package bench, shared with cmd/bench; run it with
+// go test -bench . ./simulation
+
+func BenchmarkRunShock(b *testing.B) {
+	logger.Init("error", false)
+	logger.SetOutput(io.Discard) // Shock propagation logs every step
+	bench.RunSizes(b, bench.RunShock)
+}
@@ -0,0 +1,303 @@
+package simulation
+
+import (
+	"fmt"
+	"margraf/clock"
+	"margraf/graph"
+	"margraf/trading"
+	"math"
+	"sort"
+	"time"
+)
+
+// Comparison tuning
+const (
+	stressAlert  = 0.5  // Stress score counted as a stressed node (matches the dashboard's red rows)
+	minPathDrop  = 0.01 // Edge weight drop that counts as impacted
+	maxPathHops  = 3    // Longest impact path followed from a shocked node
+	maxPathFan   = 5    // Most-weakened edges followed out of each node
+	defaultCompN = 10   // Nodes and paths per report when top is 0
+)
+
+// MetricDelta is one graph-wide metric before and after a scenario
+type MetricDelta struct {
+	Name     string  `json:"name"`
+	Baseline float64 `json:"baseline"`
+	Scenario float64 `json:"scenario"`
+	Delta    float64 `json:"delta"`
+}
+
+// NodeDelta is one node's health and stress before and after a scenario
+type NodeDelta struct {
+	NodeID         string         `json:"node_id"`
+	Name           string         `json:"name"`
+	Type           graph.NodeType `json:"type"`
+	BaselineHealth float64        `json:"baseline_health"`
+	ScenarioHealth float64        `json:"scenario_health"`
+	HealthDelta    float64        `json:"health_delta"`
+	BaselineStress float64        `json:"baseline_stress"`
+	ScenarioStress float64        `json:"scenario_stress"`
+	StressDelta    float64        `json:"stress_delta"`
+}
+
+// PathDelta is a chain of edges the shock travelled along, with the summed
+// edge weight before and after the scenario
+type PathDelta struct {
+	Nodes    []string `json:"nodes"` // Node IDs in shock-flow order
+	Names    []string `json:"names"`
+	Baseline float64  `json:"baseline"`
+	Scenario float64  `json:"scenario"`
+	Delta    float64  `json:"delta"`
+}
+
+// Comparison is the "scenario_comparison" payload: the same metrics on the
+// baseline graph and on a copy with the scenario applied
+type Comparison struct {
+	Scenario  string                    `json:"scenario"`
+	Shocked   []string                  `json:"shocked"`
+	Metrics   []MetricDelta             `json:"metrics"`
+	Nodes     []NodeDelta               `json:"nodes"`            // Most affected first
+	Paths     []PathDelta               `json:"paths"`            // Largest weight loss first
+	Hedges    []trading.HedgeSuggestion `json:"hedges,omitempty"` // For the registered portfolio, when it is hit
+	Timestamp time.Time                 `json:"timestamp"`
+}
+
+// Compare runs sc on a copy of the graph and reports how health, the stress
+// index (over window, with weights) and edge weights differ from the
+// baseline. The live graph is not changed. top bounds the node and path
+// lists (0 = 10). With a Hedger, the report suggests hedges for the
+// holdings the scenario hurts.
+func (s *Simulator) Compare(sc Scenario, window time.Duration, weights graph.StressWeights, top int) (*Comparison, error) {
+	if top <= 0 {
+		top = defaultCompN
+	}
+	baseline, err := s.Graph.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy graph: %w", err)
+	}
+	after, err := baseline.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy graph: %w", err)
+	}
+
+	what := &Simulator{Graph: after, ShockDamage: s.ShockDamage}
+	result, err := what.RunScenario(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeStress := stressByNode(baseline.StressIndex(window, weights))
+	afterStress := stressByNode(after.StressIndex(window, weights))
+
+	c := &Comparison{
+		Scenario:  result.Scenario,
+		Shocked:   dedupe(result.Shocked),
+		Timestamp: clock.Now(),
+	}
+	c.Metrics = compareMetrics(baseline, after, beforeStress, afterStress)
+	c.Nodes = compareNodes(baseline, after, beforeStress, afterStress, top)
+	c.Paths = comparePaths(baseline, after, c.Shocked, top)
+	if s.Hedger != nil {
+		c.Hedges = s.Hedger.Suggest(baseline, after, c.Shocked)
+	}
+	return c, nil
+}
+
+func stressByNode(ranked []graph.NodeStress) map[string]float64 {
+	scores := make(map[string]float64, len(ranked))
+	for _, n := range ranked {
+		scores[n.NodeID] = n.Score
+	}
+	return scores
+}
+
+// graphMetrics are the graph-wide figures compared side by side
+type graphMetrics struct {
+	avgHealth, minHealth            float64
+	stressed, maxStress, meanStress float64
+	edgeWeight                      float64
+}
+
+func measure(g *graph.Graph, stress map[string]float64) graphMetrics {
+	m := graphMetrics{minHealth: math.Inf(1)}
+	nodes := 0
+	g.NodesRange(func(n *graph.Node) {
+		nodes++
+		m.avgHealth += n.Health
+		m.minHealth = math.Min(m.minHealth, n.Health)
+	})
+	if nodes == 0 {
+		return graphMetrics{}
+	}
+	m.avgHealth /= float64(nodes)
+
+	for _, score := range stress {
+		if score >= stressAlert {
+			m.stressed++
+		}
+		m.maxStress = math.Max(m.maxStress, score)
+		m.meanStress += score
+	}
+	m.meanStress /= float64(nodes)
+
+	g.EdgesRange(func(e *graph.Edge) {
+		m.edgeWeight += e.Weight
+	})
+	return m
+}
+
+func compareMetrics(baseline, after *graph.Graph, beforeStress, afterStress map[string]float64) []MetricDelta {
+	b, a := measure(baseline, beforeStress), measure(after, afterStress)
+	metric := func(name string, before, after float64) MetricDelta {
+		return MetricDelta{Name: name, Baseline: before, Scenario: after, Delta: after - before}
+	}
+	return []MetricDelta{
+		metric("avg_health", b.avgHealth, a.avgHealth),
+		metric("min_health", b.minHealth, a.minHealth),
+		metric("stressed_nodes", b.stressed, a.stressed),
+		metric("max_stress", b.maxStress, a.maxStress),
+		metric("avg_stress", b.meanStress, a.meanStress),
+		metric("edge_weight", b.edgeWeight, a.edgeWeight),
+	}
+}
+
+// compareNodes returns the nodes whose health or stress moved most
+func compareNodes(baseline, after *graph.Graph, beforeStress, afterStress map[string]float64, top int) []NodeDelta {
+	var deltas []NodeDelta
+	after.NodesRange(func(n *graph.Node) {
+		before, ok := baseline.GetNode(n.ID)
+		if !ok {
+			return
+		}
+		d := NodeDelta{
+			NodeID:         n.ID,
+			Name:           n.Name,
+			Type:           n.Type,
+			BaselineHealth: before.Health,
+			ScenarioHealth: n.Health,
+			HealthDelta:    n.Health - before.Health,
+			BaselineStress: beforeStress[n.ID],
+			ScenarioStress: afterStress[n.ID],
+		}
+		d.StressDelta = d.ScenarioStress - d.BaselineStress
+		if d.HealthDelta == 0 && d.StressDelta == 0 {
+			return
+		}
+		deltas = append(deltas, d)
+	})
+
+	impact := func(d NodeDelta) float64 { return math.Abs(d.HealthDelta) + math.Abs(d.StressDelta) }
+	sort.Slice(deltas, func(i, j int) bool {
+		if impact(deltas[i]) != impact(deltas[j]) {
+			return impact(deltas[i]) > impact(deltas[j])
+		}
+		return deltas[i].NodeID < deltas[j].NodeID
+	})
+	if len(deltas) > top {
+		deltas = deltas[:top]
+	}
+	return deltas
+}
+
+// impactedEdge is an edge whose weight fell, oriented the way shocks flow along it
+type impactedEdge struct {
+	from, to      string
+	before, after float64
+}
+
+// comparePaths follows weakened edges out from the shocked nodes and returns
+// the paths that lost the most weight
+func comparePaths(baseline, after *graph.Graph, shocked []string, top int) []PathDelta {
+	edgeID := func(e *graph.Edge) string {
+		return fmt.Sprintf("%s|%s|%s|%s", e.SourceID, e.TargetID, e.Type, e.Commodity())
+	}
+	before := make(map[string]float64)
+	baseline.EdgesRange(func(e *graph.Edge) {
+		before[edgeID(e)] = e.Weight
+	})
+
+	// Parallel edges (e.g. a Supplies / ProcuresFrom pair) carry the shock
+	// the same way; keep the one that lost the most weight
+	strongest := make(map[[2]string]impactedEdge)
+	after.EdgesRange(func(e *graph.Edge) {
+		w, ok := before[edgeID(e)]
+		if !ok || w-e.Weight < minPathDrop {
+			return
+		}
+		ie := impactedEdge{from: e.SourceID, to: e.TargetID, before: w, after: e.Weight}
+		if e.Directionality == graph.DirectionalityReverse {
+			ie.from, ie.to = e.TargetID, e.SourceID
+		}
+		key := [2]string{ie.from, ie.to}
+		if prev, ok := strongest[key]; !ok || ie.before-ie.after > prev.before-prev.after {
+			strongest[key] = ie
+		}
+	})
+	flows := make(map[string][]impactedEdge)
+	for _, ie := range strongest {
+		flows[ie.from] = append(flows[ie.from], ie)
+	}
+
+	for from, edges := range flows {
+		sort.Slice(edges, func(i, j int) bool {
+			di, dj := edges[i].before-edges[i].after, edges[j].before-edges[j].after
+			if di != dj {
+				return di > dj
+			}
+			return edges[i].to < edges[j].to
+		})
+		if len(edges) > maxPathFan {
+			flows[from] = edges[:maxPathFan]
+		}
+	}
+
+	var paths []PathDelta
+	var walk func(path []string, b, a float64)
+	walk = func(path []string, b, a float64) {
+		extended := false
+		if len(path) <= maxPathHops {
+			for _, ie := range flows[path[len(path)-1]] {
+				if containsID(path, ie.to) {
+					continue
+				}
+				extended = true
+				walk(append(path[:len(path):len(path)], ie.to), b+ie.before, a+ie.after)
+			}
+		}
+		if !extended && len(path) > 1 {
+			paths = append(paths, PathDelta{Nodes: path, Baseline: b, Scenario: a, Delta: a - b})
+		}
+	}
+	for _, id := range shocked {
+		walk([]string{id}, 0, 0)
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].Delta != paths[j].Delta {
+			return paths[i].Delta < paths[j].Delta
+		}
+		return fmt.Sprint(paths[i].Nodes) < fmt.Sprint(paths[j].Nodes)
+	})
+	if len(paths) > top {
+		paths = paths[:top]
+	}
+	for i := range paths {
+		paths[i].Names = make([]string, len(paths[i].Nodes))
+		for j, id := range paths[i].Nodes {
+			paths[i].Names[j] = id
+			if n, ok := after.GetNode(id); ok {
+				paths[i].Names[j] = n.Name
+			}
+		}
+	}
+	return paths
+}
+
+func containsID(ids []string, id string) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
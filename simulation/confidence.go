@@ -0,0 +1,42 @@
+package simulation
+
+import (
+	"margraf/graph"
+	"math"
+)
+
+// An edge of confidence c either exists, passing on its full share of a
+// shock, or doesn't, passing on nothing. The simulator sends the share scaled
+// by confidence (see graph.ConfidenceModel) and reports the band one spread
+// of that bet, sqrt(c(1-c)), either side of it: narrow along verified trade
+// flows, wide along LLM guesses.
+
+// Band is the range an impact could span given the confidence of the edges it
+// travelled
+type Band struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// scale multiplies both ends of the band by f
+func (b Band) scale(f float64) Band {
+	return Band{Low: b.Low * f, High: b.High * f}
+}
+
+// times compounds the band with the next edge's along a path
+func (b Band) times(o Band) Band {
+	return Band{Low: b.Low * o.Low, High: b.High * o.High}
+}
+
+// add widens a running total by b
+func (b Band) add(o Band) Band {
+	return Band{Low: b.Low + o.Low, High: b.High + o.High}
+}
+
+// transmission returns e's confidence, the share of its propagation factor a
+// shock keeps for it, and the band around that share within [0, 1]
+func (s *Simulator) transmission(e *graph.Edge) (confidence, scale float64, band Band) {
+	confidence, scale = s.Graph.EdgeConfidence(e)
+	spread := math.Sqrt(confidence * (1 - confidence))
+	return confidence, scale, Band{Low: math.Max(0, scale-spread), High: math.Min(1, scale+spread)}
+}
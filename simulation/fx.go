@@ -0,0 +1,51 @@
+package simulation
+
+import (
+	"context"
+	"margraf/graph"
+	"margraf/logger"
+	"time"
+)
+
+// fxMaxAge is how long a currency's US dollar rate is used before it is
+// fetched again
+const fxMaxAge = time.Hour
+
+// refreshRates fetches the US dollar rate of every currency priced nodes are
+// quoted in, where the graph's rate is missing or older than fxMaxAge, so
+// prices on different exchanges can be compared (see graph.ConvertPrice)
+func (m *MarketMonitor) refreshRates(ctx context.Context) {
+	m.mu.Lock()
+	if m.fxRunning {
+		m.mu.Unlock()
+		return
+	}
+	m.fxRunning = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.fxRunning = false
+		m.mu.Unlock()
+	}()
+
+	currencies := make(map[string]bool)
+	m.Graph.NodesRange(func(n *graph.Node) {
+		if n.Price > 0 && n.Currency != "" {
+			currencies[n.Currency] = true
+		}
+	})
+	for currency := range currencies {
+		if _, updated, ok := m.Graph.USDRate(currency); ok && (updated.IsZero() || time.Since(updated) < fxMaxAge) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		data, err := m.Scraper.FetchStockData(ctx, currency+"USD=X")
+		if err != nil {
+			logger.WarnDepth(2, logger.StatusWarn, "No %s/USD rate: %v", currency, err)
+			continue
+		}
+		m.Graph.SetUSDRate(currency, data.Price)
+	}
+}
@@ -0,0 +1,114 @@
+package simulation
+
+import (
+	"context"
+	"margraf/graph"
+	"margraf/trading"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Hedger suggests hedges for the registered portfolio after a scenario:
+// shorts in holdings the scenario hurts, longs in listed companies it
+// helps, and pairs between them.
+type Hedger struct {
+	Portfolio  *PortfolioMonitor
+	Prices     trading.PriceSource // Price history for pair ratios; nil pairs 1:1
+	MinImpact  float64             // Health change that counts a company as hit or helped
+	MaxWinners int                 // Most winners suggested as longs
+	Timeout    time.Duration       // Limit on fetching prices for one report
+}
+
+// NewHedger creates a hedger for the monitor's holdings, counting health
+// moves of 0.02 or more and suggesting up to 5 winners
+func NewHedger(p *PortfolioMonitor, prices trading.PriceSource) *Hedger {
+	return &Hedger{
+		Portfolio:  p,
+		Prices:     prices,
+		MinImpact:  0.02,
+		MaxWinners: 5,
+		Timeout:    time.Minute,
+	}
+}
+
+// Suggest compares health on the baseline and after a scenario that shocked
+// the given nodes. A holding is exposed when its health fell; the shocked
+// node it depends on most, if any, is given as the reason.
+func (h *Hedger) Suggest(baseline, after *graph.Graph, shocked []string) []trading.HedgeSuggestion {
+	holdings := h.Portfolio.Holdings()
+	if len(holdings) == 0 {
+		return nil
+	}
+	risk := baseline.PortfolioRisk(graph.Portfolio{Holdings: holdings}, h.Portfolio.Depth, h.Portfolio.Threshold)
+
+	hit := make(map[string]bool, len(shocked))
+	for _, id := range shocked {
+		hit[id] = true
+	}
+	// Shocked node each ticker depends on most; exposures come largest first
+	via := make(map[string]string)
+	for _, e := range risk.Exposures {
+		if e.NodeID == "" || !hit[e.NodeID] {
+			continue
+		}
+		for _, ticker := range e.Holdings {
+			if _, ok := via[ticker]; !ok {
+				via[ticker] = e.Name
+			}
+		}
+	}
+
+	change := func(id string) float64 {
+		before, ok1 := baseline.GetNode(id)
+		now, ok2 := after.GetNode(id)
+		if !ok1 || !ok2 {
+			return 0
+		}
+		return now.Health - before.Health
+	}
+
+	var exposed []trading.HedgeCandidate
+	held := make(map[string]bool)
+	for _, p := range risk.Positions {
+		held[p.NodeID] = true
+		impact := change(p.NodeID)
+		if impact > -h.MinImpact {
+			continue
+		}
+		exposed = append(exposed, trading.HedgeCandidate{
+			NodeID: p.NodeID,
+			Ticker: strings.ToUpper(p.Ticker),
+			Name:   p.Name,
+			Impact: impact,
+			Weight: p.Weight,
+			Via:    via[p.Ticker],
+		})
+	}
+	if len(exposed) == 0 {
+		return nil
+	}
+
+	var winners []trading.HedgeCandidate
+	after.NodesRange(func(n *graph.Node) {
+		if n.Type != graph.NodeTypeCorporation || n.Ticker == "" || held[n.ID] {
+			return
+		}
+		if impact := change(n.ID); impact >= h.MinImpact {
+			winners = append(winners, trading.HedgeCandidate{NodeID: n.ID, Ticker: strings.ToUpper(n.Ticker), Name: n.Name, Impact: impact})
+		}
+	})
+	sort.Slice(winners, func(i, j int) bool {
+		if winners[i].Impact != winners[j].Impact {
+			return winners[i].Impact > winners[j].Impact
+		}
+		return winners[i].NodeID < winners[j].NodeID
+	})
+	if h.MaxWinners > 0 && len(winners) > h.MaxWinners {
+		winners = winners[:h.MaxWinners]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+	return trading.SuggestHedges(ctx, exposed, winners, h.Prices)
+}
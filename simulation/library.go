@@ -0,0 +1,210 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"margraf/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScenarioFormatVersion is the scenario file format written by ExportScenario.
+// Files with a newer version are rejected rather than half-understood.
+const ScenarioFormatVersion = 1
+
+// ScenarioFile is a shareable scenario definition on disk (JSON or YAML)
+type ScenarioFile struct {
+	Version  int `json:"version" yaml:"version"`
+	Scenario `yaml:",inline"`
+}
+
+// Library scenarios, loaded from files and keyed by ScenarioKey
+var (
+	libraryMu sync.RWMutex
+	library   = make(map[string]Scenario)
+	libraryAt = make(map[string]string) // Key -> file the scenario came from
+)
+
+// ScenarioKey normalises a scenario name for lookups, so "Taiwan blockade v2"
+// can be run as taiwan_blockade_v2.
+func ScenarioKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "_"))
+}
+
+// LookupScenario finds a scenario by name, preferring library scenarios over
+// built-in templates of the same name
+func LookupScenario(name string) (Scenario, bool) {
+	key := ScenarioKey(name)
+	libraryMu.RLock()
+	sc, ok := library[key]
+	libraryMu.RUnlock()
+	if ok {
+		return sc, true
+	}
+	sc, ok = ScenarioTemplates[key]
+	return sc, ok
+}
+
+// LibraryNames returns the keys of the loaded library scenarios, sorted
+func LibraryNames() []string {
+	libraryMu.RLock()
+	defer libraryMu.RUnlock()
+	names := make([]string, 0, len(library))
+	for key := range library {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LibraryFile returns the file a library scenario was loaded from
+func LibraryFile(name string) string {
+	libraryMu.RLock()
+	defer libraryMu.RUnlock()
+	return libraryAt[ScenarioKey(name)]
+}
+
+// Validate checks that every step has the fields its type needs
+func (sc Scenario) Validate() error {
+	if strings.TrimSpace(sc.Name) == "" {
+		return fmt.Errorf("scenario has no name")
+	}
+	if len(sc.Steps) == 0 {
+		return fmt.Errorf("scenario %s has no steps", sc.Name)
+	}
+	for i, step := range sc.Steps {
+		var missing string
+		switch step.Type {
+		case StepShock:
+			if step.Target == "" {
+				missing = "target"
+			}
+		case StepRegion:
+			if step.Region == "" {
+				missing = "region"
+			}
+		case StepMonetary:
+			switch {
+			case step.Target == "":
+				missing = "target"
+			case step.Kind == "":
+				missing = "kind"
+			}
+		case StepClimate:
+			if step.Hazard == "" {
+				missing = "hazard"
+			}
+		default:
+			return fmt.Errorf("step %d: unknown step type %q", i+1, step.Type)
+		}
+		if missing != "" {
+			return fmt.Errorf("step %d: %s step needs %s", i+1, step.Type, missing)
+		}
+	}
+	return nil
+}
+
+// ExportScenario writes sc to path as a versioned scenario file. The format
+// follows the extension: .yaml / .yml, otherwise JSON.
+func ExportScenario(sc Scenario, path string) error {
+	file := ScenarioFile{Version: ScenarioFormatVersion, Scenario: sc}
+
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(file)
+	default:
+		data, err = json.MarshalIndent(file, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadScenario parses and validates a scenario file. Files without a version
+// are read as version 1.
+func ReadScenario(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+
+	var file ScenarioFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return Scenario{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if file.Version > ScenarioFormatVersion {
+		return Scenario{}, fmt.Errorf("%s: scenario format version %d is newer than supported version %d", path, file.Version, ScenarioFormatVersion)
+	}
+	if err := file.Scenario.Validate(); err != nil {
+		return Scenario{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return file.Scenario, nil
+}
+
+// ImportScenario reads a scenario file and adds it to the library
+func ImportScenario(path string) (Scenario, error) {
+	sc, err := ReadScenario(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	key := ScenarioKey(sc.Name)
+
+	libraryMu.Lock()
+	library[key] = sc
+	libraryAt[key] = path
+	libraryMu.Unlock()
+
+	if _, builtin := ScenarioTemplates[key]; builtin {
+		logger.Warn(logger.StatusWarn, "Scenario %s from %s overrides the built-in template", sc.Name, path)
+	}
+	return sc, nil
+}
+
+// LoadLibrary imports every .json, .yaml and .yml scenario file in dir. Bad
+// files are logged and skipped. A missing directory is not an error.
+func LoadLibrary(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	loaded := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+		if _, err := ImportScenario(filepath.Join(dir, entry.Name())); err != nil {
+			logger.Warn(logger.StatusWarn, "Skipping scenario file: %v", err)
+			continue
+		}
+		loaded++
+	}
+	return loaded, nil
+}
@@ -1,85 +1,155 @@
 package simulation
 
 import (
+	"context"
 	"margraf/graph"
 	"margraf/logger"
+	"margraf/pipeline"
 	"margraf/scraper"
 	"margraf/server"
+	"sync"
 	"time"
 )
 
+// fundamentalsRetry is how long to wait before retrying a failed fundamentals lookup
+const fundamentalsRetry = 24 * time.Hour
+
 type MarketMonitor struct {
 	Graph   *graph.Graph
 	Hub     *server.Hub
 	Scraper *scraper.FinanceScraper
+
+	mu                sync.Mutex
+	fundamentalsTried map[string]time.Time // node ID -> last failed lookup
+	tickerTried       map[string]time.Time // node ID -> last failed ticker lookup (see tickers.go)
+	fxRunning         bool                 // A refreshRates call is in flight
 }
 
 func NewMarketMonitor(g *graph.Graph, h *server.Hub) *MarketMonitor {
 	return &MarketMonitor{
-		Graph:   g,
-		Hub:     h,
-		Scraper: scraper.NewFinanceScraper(),
+		Graph:             g,
+		Hub:               h,
+		Scraper:           scraper.NewFinanceScraper(),
+		fundamentalsTried: make(map[string]time.Time),
+		tickerTried:       make(map[string]time.Time),
 	}
 }
 
-func (m *MarketMonitor) Start(interval time.Duration) {
+// Start polls prices every interval until ctx is cancelled
+func (m *MarketMonitor) Start(ctx context.Context, interval time.Duration) {
 	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	logger.Info(logger.StatusMon, "Market Monitor active. Checking prices every %v...", interval)
 	
-	for range ticker.C {
-		m.UpdatePrices()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !pipeline.Enabled(pipeline.Market) {
+			continue
+		}
+		m.UpdatePrices(ctx)
 	}
 }
 
-func (m *MarketMonitor) UpdatePrices() {
+func (m *MarketMonitor) UpdatePrices(ctx context.Context) {
 	// Iterate over all nodes, find Corporations
 	// (Optimization: Maintain a separate list of corporate IDs)
 	
 	m.Graph.NodesRange(func(n *graph.Node) {
 		if n.Type == graph.NodeTypeCorporation {
-			go m.checkStock(n)
+			go m.checkStock(ctx, n)
 		}
 	})
+	go m.refreshRates(ctx)
 }
 
-func (m *MarketMonitor) checkStock(n *graph.Node) {
+func (m *MarketMonitor) checkStock(ctx context.Context, n *graph.Node) {
 	// If no ticker, try to find one
 	ticker, _ := m.Graph.GetNodeTicker(n.ID)
 	if ticker == "" {
-		t, err := m.Scraper.GetTicker(n.Name)
+		t, err := m.lookupTicker(ctx, n.ID, n.Name, n.Country())
 		if err != nil {
-			// fmt.Printf("    ⚠️ No ticker found for %s\n", n.Name)
 			return
 		}
-		m.Graph.SetNodeTicker(n.ID, t)
 		ticker = t
-		logger.InfoDepth(2, logger.StatusTag, "Found Ticker for %s: %s", n.Name, t)
 	}
 
-	data, err := m.Scraper.FetchStockData(ticker)
+	if n.MarketCap() == 0 {
+		m.enrichFundamentals(ctx, n, ticker)
+	}
+
+	data, err := m.Scraper.FetchStockData(ctx, ticker)
 	if err != nil {
 		// fmt.Printf("    ⚠️ Failed to fetch price for %s (%s): %v\n", n.Name, ticker, err)
 		return
 	}
 
+	// Pence, cents and agorot become pounds, rand and shekels; a missing
+	// currency is the exchange's
+	price, currency := graph.NormalizeQuote(ticker, data.Price, data.Currency)
+
 	// Update Node with thread-safe method
-	if err := m.Graph.UpdateNodePrice(n.ID, data.Price, data.Currency, ""); err != nil {
+	if err := m.Graph.UpdateNodePrice(n.ID, price, currency, "", data.MarketTime); err != nil {
 		logger.WarnDepth(2, logger.StatusWarn, "Failed to update price for %s: %v", n.Name, err)
 		return
 	}
 
-	// Adjust health based on daily change
-	// e.g. +5% change = +0.05 health (Simplified logic)
-	healthImpact := data.Change * 0.1 // Scale down
-	newHealth, _ := m.Graph.UpdateNodeHealth(n.ID, healthImpact)
+	// Adjust health based on daily change, scaled by the health model's
+	// market weight for this node type
+	newHealth, _ := m.Graph.ApplyHealthInput(n.ID, graph.InputMarket, data.Change)
 
-	logger.InfoDepth(2, logger.StatusFin, "%s (%s): %.2f %s (Change: %.2f%%)", n.Name, ticker, data.Price, data.Currency, data.Change*100)
+	logger.InfoDepth(2, logger.StatusFin, "%s (%s): %.2f %s (Change: %.2f%%)", n.Name, ticker, price, currency, data.Change*100)
 
 	// Broadcast update
-	m.Hub.Broadcast("market_update", map[string]interface{}{
-		"id":       n.ID,
-		"price":    data.Price,
-		"currency": data.Currency,
-		"health":   newHealth,
+	m.Hub.Broadcast(server.TypeMarketUpdate, server.MarketUpdatePayload{
+		ID:       n.ID,
+		Price:    price,
+		Currency: currency,
+		Change:   data.Change,
+		Health:   newHealth,
 	})
 }
+
+// enrichFundamentals adds market cap, revenue, employees, sector and country
+// to a corporation. Failed lookups are retried after fundamentalsRetry.
+func (m *MarketMonitor) enrichFundamentals(ctx context.Context, n *graph.Node, ticker string) {
+	m.mu.Lock()
+	last, tried := m.fundamentalsTried[n.ID]
+	if tried && time.Since(last) < fundamentalsRetry {
+		m.mu.Unlock()
+		return
+	}
+	m.fundamentalsTried[n.ID] = time.Now()
+	m.mu.Unlock()
+
+	f, err := m.Scraper.FetchFundamentals(ctx, ticker)
+	if err != nil {
+		logger.WarnDepth(2, logger.StatusWarn, "Fundamentals unavailable for %s (%s): %v", n.Name, ticker, err)
+		return
+	}
+
+	attrs := f.Attributes()
+	if len(attrs) == 0 {
+		return
+	}
+	// Keep an existing (e.g. Wikidata) location; otherwise normalize Yahoo's country
+	if n.Country() != "" {
+		delete(attrs, graph.AttrCountry)
+	} else if f.Country != "" {
+		for k, v := range graph.LocationAttributes(0, 0, f.Country) {
+			attrs[k] = v
+		}
+	}
+	if err := m.Graph.UpdateNodeAttributes(n.ID, attrs, "fundamentals_"+ticker); err != nil {
+		logger.WarnDepth(2, logger.StatusWarn, "Failed to store fundamentals for %s: %v", n.Name, err)
+		return
+	}
+
+	m.mu.Lock()
+	delete(m.fundamentalsTried, n.ID)
+	m.mu.Unlock()
+	logger.InfoDepth(2, logger.StatusFin, "%s fundamentals: market cap %.2fB %s, sector %s", n.Name, f.MarketCap/1e9, f.Currency, f.Sector)
+}
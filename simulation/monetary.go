@@ -0,0 +1,107 @@
+package simulation
+
+import (
+	"fmt"
+	"margraf/graph"
+	"margraf/logger"
+	"strings"
+)
+
+// MonetaryKind is the type of monetary shock
+type MonetaryKind string
+
+const (
+	MonetaryDevaluation MonetaryKind = "devalue" // Currency loses Magnitude of its value (0.2 = -20%)
+	MonetaryRateHike    MonetaryKind = "hike"    // Policy rate rises by Magnitude percentage points
+	MonetaryRateCut     MonetaryKind = "cut"     // Policy rate falls by Magnitude percentage points
+)
+
+// MonetaryShock is a currency or interest-rate event
+type MonetaryShock struct {
+	Currency  string // ISO code (e.g. TRY) or currency node ID (currency_try)
+	Kind      MonetaryKind
+	Magnitude float64
+}
+
+// RunMonetaryShock applies a monetary shock. Unlike supply shocks, which
+// travel along supply chains, monetary shocks move prices:
+//   - Devaluation makes the issuing nations' exports cheaper and imports dearer,
+//     so their outgoing trade edges strengthen and incoming ones weaken, while
+//     capital flees and companies reporting in the currency take translation losses.
+//   - Rate hikes strengthen the currency and attract capital, but slow the
+//     economy and raise financing costs for companies reporting in it. Cuts do
+//     the reverse.
+func (s *Simulator) RunMonetaryShock(shock MonetaryShock) error {
+	currencyID := graph.CurrencyNodeID(shock.Currency)
+	currency, ok := s.Graph.GetNode(currencyID)
+	if !ok || currency.Type != graph.NodeTypeCurrency {
+		return fmt.Errorf("currency %s not found", shock.Currency)
+	}
+	if shock.Magnitude <= 0 {
+		return fmt.Errorf("magnitude must be positive")
+	}
+
+	// Signed effects per unit of magnitude
+	var currencyMove, economyMove, tradeOut, tradeIn, capital, companyMove float64
+	switch shock.Kind {
+	case MonetaryDevaluation:
+		currencyMove, economyMove = -1.0, -0.3
+		tradeOut, tradeIn, capital = 0.5, -0.5, -0.5
+		companyMove = -0.5
+	case MonetaryRateHike:
+		currencyMove, economyMove = 0.1, -0.1
+		capital = 0.1
+		companyMove = -0.1
+	case MonetaryRateCut:
+		currencyMove, economyMove = -0.1, 0.1
+		capital = -0.1
+		companyMove = 0.1
+	default:
+		return fmt.Errorf("unknown monetary shock %q (use devalue, hike or cut)", shock.Kind)
+	}
+
+	m := shock.Magnitude
+	logger.Info(logger.StatusShock, "MONETARY SHOCK: %s %s by %.2f", currency.Name, shock.Kind, m)
+
+	s.Graph.ApplyHealthInput(currencyID, graph.InputMonetary, currencyMove*m)
+
+	nations, companies := s.Graph.CurrencyUsers(currencyID)
+	eventID := graph.NewEventID(fmt.Sprintf("monetary_%s_%s", strings.ToLower(currency.Currency), shock.Kind))
+
+	for _, nation := range nations {
+		health, _ := s.Graph.ApplyHealthInput(nation.ID, graph.InputMonetary, economyMove*m)
+		logger.InfoDepth(2, "", "%s economy: health -> %.2f", nation.Name, health)
+
+		for _, e := range s.Graph.GetOutgoingEdges(nation.ID) {
+			s.adjustMonetaryEdge(e, tradeOut, capital, m, eventID)
+		}
+		for _, e := range s.Graph.GetIncomingEdges(nation.ID) {
+			s.adjustMonetaryEdge(e, tradeIn, capital, m, eventID)
+		}
+	}
+
+	for _, company := range companies {
+		health, _ := s.Graph.ApplyHealthInput(company.ID, graph.InputMonetary, companyMove*m)
+		logger.InfoDepth(2, "", "%s (reports in %s): health -> %.2f", company.Name, currency.Currency, health)
+	}
+
+	logger.InfoDepth(1, logger.StatusData, "Summary: %d nations, %d companies exposed to %s", len(nations), len(companies), currency.Currency)
+	return nil
+}
+
+// adjustMonetaryEdge shifts a trade or capital edge's weight by the monetary effect
+func (s *Simulator) adjustMonetaryEdge(e *graph.Edge, trade, capital, magnitude float64, eventID string) {
+	var effect float64
+	switch e.Type {
+	case graph.EdgeTypeTrade:
+		effect = trade
+	case graph.EdgeTypeCapital:
+		effect = capital
+	default:
+		return
+	}
+	if effect == 0 {
+		return
+	}
+	s.Graph.UpdateCommodityEdgeWeight(e.SourceID, e.TargetID, e.Type, e.Commodity(), effect*magnitude, 1.0, eventID)
+}
@@ -0,0 +1,165 @@
+package simulation
+
+import (
+	"context"
+	"margraf/clock"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/pipeline"
+	"margraf/server"
+	"margraf/trading"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PairMonitor watches the graph's most connected ticker pairs between
+// analyses: each scan ranks pairs by their graph prior, recomputes the
+// z-score of the top ones' price ratio from cached daily prices, and
+// broadcasts "pair_alert" when one crosses the entry threshold.
+type PairMonitor struct {
+	Graph     *graph.Graph
+	Hub       *server.Hub
+	Prices    trading.PriceSource
+	Top       int     // Pairs watched, by graph prior
+	Entry     float64 // |z| that raises an alert
+	Lookback  int     // Aligned daily prices behind the z-score
+	Shrinkage float64 // Observations the graph prior is worth in the correlation (see trading.CorrelationAnalyzer)
+
+	mu      sync.RWMutex
+	latest  []trading.PairWatch
+	alerted map[string]string // Pair key -> action last alerted, until |z| falls back under Entry
+}
+
+// NewPairMonitor creates a monitor of the top 20 pairs, alerting at |z| 2
+// over 20 days
+func NewPairMonitor(g *graph.Graph, h *server.Hub, prices trading.PriceSource) *PairMonitor {
+	return &PairMonitor{
+		Graph:     g,
+		Hub:       h,
+		Prices:    prices,
+		Top:       20,
+		Entry:     2.0,
+		Lookback:  20,
+		Shrinkage: 20,
+		alerted:   make(map[string]string),
+	}
+}
+
+// Start scans at startup, then once per interval, until ctx is cancelled
+func (m *PairMonitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	logger.Info(logger.StatusMon, "Pair Monitor active. Top %d pairs, alerting at |z| %.1f, scanning every %v...", m.Top, m.Entry, interval)
+
+	for {
+		if pipeline.Enabled(pipeline.Pairs) {
+			m.Scan(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan recomputes the watched pairs' z-scores, broadcasts an alert for each
+// pair newly past the entry threshold, and returns the readings, most
+// stretched first. Pairs without enough prices are skipped.
+func (m *PairMonitor) Scan(ctx context.Context) []trading.PairWatch {
+	assets := make(map[string]string)
+	m.Graph.NodesRange(func(n *graph.Node) {
+		if n.Type == graph.NodeTypeCorporation && n.Ticker != "" {
+			assets[n.ID] = strings.ToUpper(n.Ticker)
+		}
+	})
+	analyzer := trading.NewCorrelationAnalyzer(m.Graph)
+	analyzer.Shrinkage = m.Shrinkage
+
+	var readings []trading.PairWatch
+	for _, pair := range analyzer.ConnectedPairs(assets, m.Top) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		prices1, err := m.Prices.Prices(ctx, pair.Ticker1)
+		if err != nil {
+			continue
+		}
+		prices2, err := m.Prices.Prices(ctx, pair.Ticker2)
+		if err != nil {
+			continue
+		}
+		w, err := analyzer.WatchPair(pair, prices1, prices2, m.Lookback)
+		if err != nil {
+			logger.Debug(logger.StatusMon, "Pair monitor: %v", err)
+			continue
+		}
+		readings = append(readings, w)
+	}
+	sort.Slice(readings, func(i, j int) bool {
+		return math.Abs(readings[i].ZScore) > math.Abs(readings[j].ZScore)
+	})
+
+	m.mu.Lock()
+	m.latest = readings
+	var alerts []trading.PairWatch
+	watched := make(map[string]bool, len(readings))
+	for _, w := range readings {
+		key := trading.PairKey(w.Pair.Ticker1, w.Pair.Ticker2)
+		watched[key] = true
+		action := w.Action(m.Entry)
+		if action != "" && m.alerted[key] != action {
+			alerts = append(alerts, w)
+		}
+		if action == "" {
+			delete(m.alerted, key)
+		} else {
+			m.alerted[key] = action
+		}
+	}
+	for key := range m.alerted {
+		if !watched[key] {
+			delete(m.alerted, key)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, w := range alerts {
+		m.alert(w)
+	}
+	return readings
+}
+
+// alert logs and broadcasts a pair past the entry threshold
+func (m *PairMonitor) alert(w trading.PairWatch) {
+	p := w.Pair
+	action := w.Action(m.Entry)
+	logger.Info(logger.StatusMon, "Pair alert: %s/%s z %+.2f (%s), correlation %.2f", p.Ticker1, p.Ticker2, w.ZScore, action, p.Correlation)
+	if m.Hub == nil {
+		return
+	}
+	m.Hub.Broadcast(server.TypePairAlert, server.PairAlertPayload{
+		Asset1:      p.Asset1,
+		Asset2:      p.Asset2,
+		Ticker1:     p.Ticker1,
+		Ticker2:     p.Ticker2,
+		ZScore:      w.ZScore,
+		Entry:       m.Entry,
+		Action:      action,
+		Correlation: p.Correlation,
+		GraphPrior:  p.GraphPrior,
+		Spread:      w.Spread,
+		PriceTime:   time.Unix(w.Timestamp, 0).UTC(),
+		Timestamp:   clock.Now(),
+	})
+}
+
+// Latest returns the readings of the last scan, most stretched first
+func (m *PairMonitor) Latest() []trading.PairWatch {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]trading.PairWatch(nil), m.latest...)
+}
@@ -0,0 +1,126 @@
+package simulation
+
+import (
+	"context"
+	"margraf/clock"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/pipeline"
+	"margraf/server"
+	"margraf/syserr"
+	"margraf/trading"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PaperMonitor feeds the graph's market prices to a paper trader and
+// broadcasts its performance as "paper_performance" whenever it books
+// something.
+type PaperMonitor struct {
+	Graph  *graph.Graph
+	Hub    *server.Hub
+	Trader *trading.PaperTrader
+
+	// Windows around the high-impact calendar events (earnings, rate
+	// decisions, ...) of either leg's node, from EventBefore ahead of the
+	// event until EventAfter past its end. Pairs avoid them, or trade only
+	// inside them with Constraints.Target. Both zero disables them.
+	EventBefore time.Duration
+	EventAfter  time.Duration
+
+	// Currency legs are priced in, so a pair across exchanges compares like
+	// with like. A leg waits until its currency's rate is known (see
+	// graph.ConvertPrice). Empty trades prices as quoted.
+	Currency string
+}
+
+// NewPaperMonitor creates a monitor for trader
+func NewPaperMonitor(g *graph.Graph, h *server.Hub, trader *trading.PaperTrader) *PaperMonitor {
+	return &PaperMonitor{Graph: g, Hub: h, Trader: trader}
+}
+
+// Start ticks the trader once per interval until ctx is cancelled
+func (m *PaperMonitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	logger.Info(logger.StatusMon, "Paper Trader active. %d strategies, ticking every %v...", len(m.Trader.Pairs()), interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !pipeline.Enabled(pipeline.Paper) {
+			continue
+		}
+		if m.EventBefore > 0 || m.EventAfter > 0 {
+			m.RefreshBlackouts()
+		}
+		m.Tick()
+	}
+}
+
+// RefreshBlackouts sets each strategy's windows from the graph calendar's
+// high-impact events on the nodes of its tickers
+func (m *PaperMonitor) RefreshBlackouts() {
+	nodes := make(map[string][]string) // Ticker -> node IDs
+	m.Graph.NodesRange(func(n *graph.Node) {
+		if n.Ticker != "" {
+			ticker := strings.ToUpper(n.Ticker)
+			nodes[ticker] = append(nodes[ticker], n.ID)
+		}
+	})
+	for key, legs := range m.Trader.Legs() {
+		var windows []trading.Blackout
+		for _, id := range append(nodes[legs[0]], nodes[legs[1]]...) {
+			for _, ev := range m.Graph.UpcomingEvents(id, 0) {
+				if ev.Impact != graph.ImpactHigh {
+					continue
+				}
+				windows = append(windows, trading.Blackout{
+					Start:  ev.Time.Add(-m.EventBefore),
+					End:    ev.End().Add(m.EventAfter),
+					Reason: ev.Title,
+				})
+			}
+		}
+		sort.Slice(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+		m.Trader.SetBlackouts(key, windows)
+	}
+}
+
+// Tick passes the latest prices of the traded tickers to the trader
+func (m *PaperMonitor) Tick() {
+	wanted := make(map[string]bool)
+	for _, legs := range m.Trader.Legs() {
+		wanted[legs[0]] = true
+		if legs[1] != "" {
+			wanted[legs[1]] = true
+		}
+	}
+	prices := make(map[string]float64, len(wanted))
+	m.Graph.NodesRange(func(n *graph.Node) {
+		ticker := strings.ToUpper(n.Ticker)
+		if !wanted[ticker] || n.Price <= 0 {
+			return
+		}
+		price := n.Price
+		if m.Currency != "" {
+			var ok bool
+			if price, ok = m.Graph.ConvertPrice(n.Price, n.Currency, m.Currency); !ok {
+				return
+			}
+		}
+		prices[ticker] = price
+	})
+
+	booked, err := m.Trader.Tick(clock.Now(), prices)
+	if err != nil {
+		syserr.Report(syserr.ModuleStorage, "save paper ledger", err)
+	}
+	if booked && m.Hub != nil {
+		m.Hub.Broadcast(server.TypePaperPerformance, m.Trader.Performance())
+	}
+}
@@ -0,0 +1,158 @@
+package simulation
+
+import (
+	"context"
+	"margraf/clock"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/pipeline"
+	"margraf/server"
+	"sync"
+	"time"
+)
+
+// PortfolioMonitor keeps a portfolio's exposure to the graph current,
+// broadcasting it as "portfolio_update" and warning when a concentrated
+// hidden exposure appears.
+type PortfolioMonitor struct {
+	Graph     *graph.Graph
+	Hub       *server.Hub
+	Path      string        // File the holdings are saved to
+	Depth     int           // Supply chain hops followed upstream of each holding
+	Threshold float64       // Share of the portfolio that makes an exposure concentrated
+	Debounce  time.Duration // Minimum time between recomputations
+
+	notify chan struct{}
+
+	mu        sync.RWMutex
+	portfolio graph.Portfolio
+	latest    *graph.PortfolioRisk
+	flagged   map[string]bool // Exposures flagged by the last update, by kind:name
+}
+
+// NewPortfolioMonitor creates a monitor for the holdings saved at path,
+// following 3 hops with a 30% threshold
+func NewPortfolioMonitor(g *graph.Graph, h *server.Hub, path string) (*PortfolioMonitor, error) {
+	p, err := graph.LoadPortfolio(path)
+	if err != nil {
+		return nil, err
+	}
+	return &PortfolioMonitor{
+		Graph:     g,
+		Hub:       h,
+		Path:      path,
+		Depth:     3,
+		Threshold: 0.3,
+		Debounce:  2 * time.Second,
+		notify:    make(chan struct{}, 1),
+		portfolio: p,
+		flagged:   make(map[string]bool),
+	}, nil
+}
+
+// Notify schedules a recomputation. It never blocks, so it is safe to call
+// from the graph change hook.
+func (m *PortfolioMonitor) Notify() {
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Start recomputes on every notification (at most once per Debounce) and at
+// least once per interval. It returns when ctx is cancelled.
+func (m *PortfolioMonitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	logger.Info(logger.StatusMon, "Portfolio Monitor active. %d holdings, refresh at least every %v...", len(m.Holdings()), interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.notify:
+		case <-ticker.C:
+		}
+		if !pipeline.Enabled(pipeline.Portfolio) || len(m.Holdings()) == 0 {
+			continue
+		}
+		m.Update()
+		time.Sleep(m.Debounce)
+	}
+}
+
+// Holdings returns the current holdings
+func (m *PortfolioMonitor) Holdings() []graph.Holding {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]graph.Holding(nil), m.portfolio.Holdings...)
+}
+
+// Set adds or changes a holding and saves the portfolio
+func (m *PortfolioMonitor) Set(ticker string, quantity float64) error {
+	m.mu.Lock()
+	m.portfolio.Set(ticker, quantity)
+	err := m.portfolio.Save(m.Path)
+	m.mu.Unlock()
+	m.Notify()
+	return err
+}
+
+// Remove drops a holding and saves the portfolio, reporting whether it existed
+func (m *PortfolioMonitor) Remove(ticker string) (bool, error) {
+	m.mu.Lock()
+	if !m.portfolio.Remove(ticker) {
+		m.mu.Unlock()
+		return false, nil
+	}
+	err := m.portfolio.Save(m.Path)
+	m.mu.Unlock()
+	m.Notify()
+	return true, err
+}
+
+// Update recomputes the portfolio's exposure, broadcasts it and warns about
+// concentrated exposures that weren't flagged last time
+func (m *PortfolioMonitor) Update() *graph.PortfolioRisk {
+	m.mu.RLock()
+	p := graph.Portfolio{Holdings: append([]graph.Holding(nil), m.portfolio.Holdings...)}
+	m.mu.RUnlock()
+	risk := m.Graph.PortfolioRisk(p, m.Depth, m.Threshold)
+
+	flagged := make(map[string]bool)
+	m.mu.Lock()
+	for _, e := range risk.Concentrated() {
+		key := e.Kind + ":" + e.Name
+		flagged[key] = true
+		if !m.flagged[key] {
+			logger.Warn(logger.StatusWarn, "Portfolio: %.0f%% depends on %s %s (%.0f%% held directly)", e.Share*100, kindLabel(e.Kind), e.Name, e.Direct*100)
+		}
+	}
+	m.flagged = flagged
+	m.latest = risk
+	m.mu.Unlock()
+
+	if m.Hub != nil {
+		m.Hub.Broadcast(server.TypePortfolioUpdate, server.PortfolioUpdatePayload{
+			PortfolioRisk: risk,
+			Threshold:     m.Threshold,
+			Timestamp:     clock.Now(),
+		})
+	}
+	return risk
+}
+
+// Latest returns the most recent exposure, or nil before the first update
+func (m *PortfolioMonitor) Latest() *graph.PortfolioRisk {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.latest
+}
+
+// kindLabel names an exposure kind for messages
+func kindLabel(kind string) string {
+	if kind == graph.ExposureRawMaterial {
+		return "raw material"
+	}
+	return kind
+}
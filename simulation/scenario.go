@@ -0,0 +1,216 @@
+package simulation
+
+import (
+	"fmt"
+	"margraf/graph"
+	"margraf/logger"
+	"sort"
+	"strings"
+)
+
+// StepType identifies what a scenario step does
+type StepType string
+
+const (
+	StepShock    StepType = "shock"    // Shock one node
+	StepRegion   StepType = "region"   // Shock every node in a region or country
+	StepMonetary StepType = "monetary" // Currency devaluation or rate move
+	StepClimate  StepType = "climate"  // Shock nodes in proportion to a climate exposure
+)
+
+// ScenarioStep is one event in a scenario
+type ScenarioStep struct {
+	Type        StepType `json:"type" yaml:"type"`
+	Target      string   `json:"target,omitempty" yaml:"target,omitempty"`         // Node ID (shock), currency (monetary)
+	Region      string   `json:"region,omitempty" yaml:"region,omitempty"`         // Region/country (region, climate)
+	Commodity   string   `json:"commodity,omitempty" yaml:"commodity,omitempty"`   // HS code (shock)
+	Impact      float64  `json:"impact,omitempty" yaml:"impact,omitempty"`         // ImpactFactor (shock, region)
+	Kind        string   `json:"kind,omitempty" yaml:"kind,omitempty"`             // MonetaryKind (monetary)
+	Magnitude   float64  `json:"magnitude,omitempty" yaml:"magnitude,omitempty"`   // Monetary magnitude
+	Hazard      string   `json:"hazard,omitempty" yaml:"hazard,omitempty"`         // ClimateHazard (climate)
+	Severity    float64  `json:"severity,omitempty" yaml:"severity,omitempty"`     // Impact at full exposure (climate)
+	NodeTypes   []string `json:"node_types,omitempty" yaml:"node_types,omitempty"` // Restrict climate steps to these types
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Scenario is a named sequence of shocks
+type Scenario struct {
+	Name        string         `json:"name" yaml:"name"`
+	Description string         `json:"description" yaml:"description"`
+	Steps       []ScenarioStep `json:"steps" yaml:"steps"`
+}
+
+// ScenarioResult lists the nodes each scenario run shocked
+type ScenarioResult struct {
+	Scenario string   `json:"scenario"`
+	Shocked  []string `json:"shocked"`
+}
+
+// ScenarioTemplates are the built-in scenarios
+var ScenarioTemplates = map[string]Scenario{
+	"drought": {
+		Name:        "drought",
+		Description: "Severe drought hitting exposed crop-growing nations and crops in proportion to drought exposure",
+		Steps: []ScenarioStep{{
+			Type:        StepClimate,
+			Hazard:      string(graph.HazardDrought),
+			Severity:    0.6,
+			NodeTypes:   []string{string(graph.NodeTypeNation), string(graph.NodeTypeCrop)},
+			Description: "Drought / Crop Failure",
+		}},
+	},
+	"heatwave": {
+		Name:        "heatwave",
+		Description: "Extended heatwave hitting labour productivity and power grids",
+		Steps: []ScenarioStep{{
+			Type:        StepClimate,
+			Hazard:      string(graph.HazardHeat),
+			Severity:    0.3,
+			NodeTypes:   []string{string(graph.NodeTypeNation), string(graph.NodeTypeCorporation)},
+			Description: "Heatwave",
+		}},
+	},
+	"flood": {
+		Name:        "flood",
+		Description: "Major flooding disrupting production sites and logistics",
+		Steps: []ScenarioStep{{
+			Type:        StepClimate,
+			Hazard:      string(graph.HazardFlood),
+			Severity:    0.5,
+			Description: "Flooding",
+		}},
+	},
+	"carbon_tax": {
+		Name:        "carbon_tax",
+		Description: "Carbon tax raising costs for carbon-intensive producers",
+		Steps: []ScenarioStep{{
+			Type:        StepClimate,
+			Hazard:      string(graph.HazardCarbonIntensity),
+			Severity:    0.4,
+			NodeTypes:   []string{string(graph.NodeTypeCorporation), string(graph.NodeTypeIndustry), string(graph.NodeTypeRawMaterial)},
+			Description: "Carbon Tax",
+		}},
+	},
+}
+
+// TemplateNames returns the built-in scenario names, sorted
+func TemplateNames() []string {
+	names := make([]string, 0, len(ScenarioTemplates))
+	for name := range ScenarioTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithRegion returns a copy of the scenario with region applied to every
+// region and climate step that doesn't set its own.
+func (sc Scenario) WithRegion(region string) Scenario {
+	steps := make([]ScenarioStep, len(sc.Steps))
+	copy(steps, sc.Steps)
+	for i := range steps {
+		if steps[i].Region == "" && (steps[i].Type == StepRegion || steps[i].Type == StepClimate) {
+			steps[i].Region = region
+		}
+	}
+	sc.Steps = steps
+	if region != "" {
+		sc.Name = sc.Name + " (" + region + ")"
+	}
+	return sc
+}
+
+// minClimateExposure skips nodes whose exposure is too small to matter
+const minClimateExposure = 0.05
+
+// RunScenario runs each step in order
+func (s *Simulator) RunScenario(sc Scenario) (*ScenarioResult, error) {
+	logger.Info(logger.StatusShock, "SCENARIO: %s - %s", sc.Name, sc.Description)
+
+	result := &ScenarioResult{Scenario: sc.Name}
+	for i, step := range sc.Steps {
+		desc := step.Description
+		if desc == "" {
+			desc = fmt.Sprintf("%s step %d", sc.Name, i+1)
+		}
+
+		switch step.Type {
+		case StepShock:
+			if _, ok := s.Graph.GetNode(step.Target); !ok {
+				return result, fmt.Errorf("step %d: node %s not found", i+1, step.Target)
+			}
+			s.RunShock(ShockEvent{
+				TargetNodeID: step.Target,
+				Description:  desc,
+				ImpactFactor: step.Impact,
+				Commodity:    step.Commodity,
+			})
+			result.Shocked = append(result.Shocked, step.Target)
+
+		case StepRegion:
+			result.Shocked = append(result.Shocked, s.RunRegionShock(step.Region, desc, step.Impact)...)
+
+		case StepMonetary:
+			err := s.RunMonetaryShock(MonetaryShock{
+				Currency:  step.Target,
+				Kind:      MonetaryKind(step.Kind),
+				Magnitude: step.Magnitude,
+			})
+			if err != nil {
+				return result, fmt.Errorf("step %d: %w", i+1, err)
+			}
+			result.Shocked = append(result.Shocked, graph.CurrencyNodeID(step.Target))
+
+		case StepClimate:
+			result.Shocked = append(result.Shocked, s.runClimateStep(step, desc)...)
+
+		default:
+			return result, fmt.Errorf("step %d: unknown step type %q", i+1, step.Type)
+		}
+	}
+
+	logger.InfoDepth(1, logger.StatusData, "Scenario %s: %d nodes shocked", sc.Name, len(result.Shocked))
+	return result, nil
+}
+
+// runClimateStep shocks every matching node in proportion to its exposure:
+// a node with exposure 1.0 gets ImpactFactor 1-Severity.
+func (s *Simulator) runClimateStep(step ScenarioStep, desc string) []string {
+	hazard := graph.ClimateHazard(step.Hazard)
+	types := make(map[graph.NodeType]bool, len(step.NodeTypes))
+	for _, t := range step.NodeTypes {
+		types[graph.NodeType(t)] = true
+	}
+
+	var candidates []*graph.Node
+	if step.Region != "" {
+		candidates = s.Graph.NodesInRegion(step.Region)
+	} else {
+		s.Graph.NodesRange(func(n *graph.Node) {
+			candidates = append(candidates, n)
+		})
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].ID < candidates[j].ID })
+	}
+
+	var shocked []string
+	for _, n := range candidates {
+		if len(types) > 0 && !types[n.Type] {
+			continue
+		}
+		exposure := n.ClimateExposure(hazard)
+		if exposure < minClimateExposure {
+			continue
+		}
+		s.RunShock(ShockEvent{
+			TargetNodeID: n.ID,
+			Description:  fmt.Sprintf("%s (%s %.2f)", desc, strings.TrimPrefix(step.Hazard, "climate_"), exposure),
+			ImpactFactor: 1.0 - step.Severity*exposure,
+		})
+		shocked = append(shocked, n.ID)
+	}
+
+	if len(shocked) == 0 {
+		logger.Warn(logger.StatusWarn, "No nodes exposed to %s (run 'climate' to score nodes)", step.Hazard)
+	}
+	return shocked
+}
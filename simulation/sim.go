@@ -2,17 +2,45 @@ package simulation
 
 import (
 	"fmt"
+	"margraf/clock"
 	"margraf/graph"
 	"margraf/logger"
+	"margraf/server"
+	"math"
 )
 
+func init() {
+	// Broadcast by main; registered for the server's published schemas
+	server.RegisterPayload(server.TypeShockTrace, Trace{})
+	server.RegisterPayload(server.TypeScenarioComparison, Comparison{})
+}
+
 // Simulator handles shock propagation.
 type Simulator struct {
-	Graph *graph.Graph
+	Graph       *graph.Graph
+	ShockDamage float64 // Raw health input applied to the shocked node itself
+	Hedger      *Hedger // Adds portfolio hedges to comparison reports when set
+	Quiet       bool    // Skips the shock-by-shock log, for batch runs
+
+	OnTrace func(*Trace) // Receives each shock's propagation trace; none are recorded when nil
 }
 
 func NewSimulator(g *graph.Graph) *Simulator {
-	return &Simulator{Graph: g}
+	return &Simulator{Graph: g, ShockDamage: -0.2}
+}
+
+// info logs a step of a shock unless the simulator is quiet
+func (s *Simulator) info(depth int, status logger.StatusCode, format string, args ...interface{}) {
+	if !s.Quiet {
+		logger.InfoDepth(depth, status, format, args...)
+	}
+}
+
+// success logs an edge or node a shock changed unless the simulator is quiet
+func (s *Simulator) success(depth int, format string, args ...interface{}) {
+	if !s.Quiet {
+		logger.SuccessDepth(depth, format, args...)
+	}
 }
 
 // ShockEvent represents a disruption.
@@ -20,11 +48,34 @@ type ShockEvent struct {
 	TargetNodeID string
 	Description  string
 	ImpactFactor float64 // 0.0 to 1.0 (1.0 = no change, 0.0 = total block)
+	Commodity    string  // Optional HS code: route only through trade edges for this commodity
+	ID           string  // Optional event ID: a shock whose ID was already applied is skipped (replays)
+}
+
+// routesCommodity reports whether a shock scoped to commodity should travel along e.
+// Unscoped shocks use aggregate trade edges; scoped shocks use the matching commodity edge.
+func routesCommodity(e *graph.Edge, commodity string) bool {
+	if e.Type != graph.EdgeTypeTrade {
+		return true
+	}
+	return e.Commodity() == commodity
 }
 
 // RunShock simulates a shock event using Spreading Activation (Section 5.2).
+// Shocks with an ID are applied at most once; the edge updates are recorded
+// under IDs derived from it. The ranked impact is kept under the event ID
+// (see graph.Impact). With OnTrace set, the spread is recorded and handed to
+// it once the shock has run.
 func (s *Simulator) RunShock(event ShockEvent) {
-	logger.Info(logger.StatusShock, "SIMULATING SHOCK: %s on %s (Factor: %.2f)", event.Description, event.TargetNodeID, event.ImpactFactor)
+	eventID := event.ID
+	if eventID == "" {
+		eventID = graph.NewEventID("shock_" + event.TargetNodeID)
+	} else if !s.Graph.ClaimEvent(eventID) {
+		s.info(0, logger.StatusShock, "Shock %s already applied, skipping", eventID)
+		return
+	}
+
+	s.info(0, logger.StatusShock, "SIMULATING SHOCK: %s on %s (Factor: %.2f)", event.Description, event.TargetNodeID, event.ImpactFactor)
 
 	target, ok := s.Graph.GetNode(event.TargetNodeID)
 	if !ok {
@@ -49,17 +100,34 @@ func (s *Simulator) RunShock(event ShockEvent) {
 		effectiveImpact = 1
 	}
 
-	logger.InfoDepth(1, logger.StatusHlth, "Node Health: %.2f -> Effective Impact Factor: %.2f", target.Health, effectiveImpact)
+	s.info(1, logger.StatusHlth, "Node Health: %.2f -> Effective Impact Factor: %.2f", target.Health, effectiveImpact)
+
+	baseline := s.Graph.ImpactBaseline()
+	var trace *Trace
+	if s.OnTrace != nil {
+		trace = &Trace{
+			EventID:         eventID,
+			Target:          event.TargetNodeID,
+			Description:     event.Description,
+			Commodity:       event.Commodity,
+			ImpactFactor:    event.ImpactFactor,
+			EffectiveImpact: effectiveImpact,
+			Time:            clock.Now(),
+		}
+	}
 
 	// Apply damage to the node itself
-	s.Graph.UpdateNodeHealth(event.TargetNodeID, -0.2)
+	healthBefore := target.Health
+	healthAfter, _ := s.Graph.ApplyHealthInput(event.TargetNodeID, graph.InputShock, s.ShockDamage)
+	trace.add(TraceStep{Hop: 0, Kind: TraceOrigin, To: event.TargetNodeID, Energy: 1.0 - effectiveImpact}.health(healthBefore, healthAfter))
 
 	// Spreading Activation: Propagate impact through the graph
-	logger.InfoDepth(1, "", "Direct Impact on %s:", target.Name)
+	s.info(1, "", "Direct Impact on %s:", target.Name)
 
 	// Track propagation across multiple hops
 	activationMap := make(map[string]float64)                 // nodeID -> activation energy
 	activationMap[event.TargetNodeID] = 1.0 - effectiveImpact // Initial shock energy
+	bands := make(map[string]Band)                            // nodeID -> range of its activation given edge confidence
 
 	// First-order propagation - respect edge directionality
 	outgoing := s.Graph.GetOutgoingEdges(event.TargetNodeID)
@@ -67,9 +135,13 @@ func (s *Simulator) RunShock(event ShockEvent) {
 	winners := make([]string, 0) // Track nodes that benefit (substitutes, competitors)
 
 	for _, e := range outgoing {
+		if !routesCommodity(e, event.Commodity) {
+			continue
+		}
+
 		// Check if shock should propagate through this edge (respects directionality)
 		if !graph.ShouldPropagateShock(e, true) {
-			logger.InfoDepth(2, "", "Skipping %s -> %s (%s): Wrong direction for shock propagation",
+			s.info(2, "", "Skipping %s -> %s (%s): Wrong direction for shock propagation",
 				target.Name, e.TargetID, e.Type)
 			continue
 		}
@@ -77,8 +149,9 @@ func (s *Simulator) RunShock(event ShockEvent) {
 		neighbor, _ := s.Graph.GetNode(e.TargetID)
 		originalWeight := e.Weight
 
-		// Get propagation factor based on edge type
-		propagationFactor := graph.GetShockPropagationFactor(e.Type)
+		// Get propagation factor based on edge type, scaled by how sure we are the edge exists
+		confidence, scale, band := s.transmission(e)
+		propagationFactor := s.Graph.ShockPropagationFactor(e)
 
 		// Calculate new weight based on shock
 		newWeight := originalWeight * effectiveImpact
@@ -86,19 +159,25 @@ func (s *Simulator) RunShock(event ShockEvent) {
 		// Actually update the edge weight in the graph
 		sentimentScore := -(1.0 - effectiveImpact) // Negative shock
 		relevanceScore := 1.0                      // Direct connection = high relevance
-		eventID := fmt.Sprintf("shock_%s_%d", event.TargetNodeID, len(activationMap))
 
-		if err := s.Graph.UpdateEdgeWeight(e.SourceID, e.TargetID, e.Type, sentimentScore, relevanceScore, eventID); err == nil {
-			logger.SuccessDepth(2, "%s -> %s [%s]: Weight %.2f -> %.2f (-%0.f%%, propagation: %.0f%%)",
+		if err := s.Graph.UpdateCommodityEdgeWeight(e.SourceID, e.TargetID, e.Type, e.Commodity(), sentimentScore, relevanceScore, eventID); err == nil {
+			s.success(2, "%s -> %s [%s]: Weight %.2f -> %.2f (-%0.f%%, propagation: %.0f%%, confidence: %.0f%%)",
 				target.Name, neighbor.Name, e.Type, originalWeight, newWeight,
-				(1.0-effectiveImpact)*100, propagationFactor*100)
+				(1.0-effectiveImpact)*100, propagationFactor*scale*100, confidence*100)
 
 			// Propagate activation energy with edge-specific factor
-			activationMap[e.TargetID] = (1.0 - effectiveImpact) * e.Weight * propagationFactor
-
-			// Apply health impact to downstream node (scaled by propagation factor)
-			healthDelta := -0.1 * (1.0 - effectiveImpact) * propagationFactor
-			s.Graph.UpdateNodeHealth(e.TargetID, healthDelta)
+			energy := (1.0 - effectiveImpact) * e.Weight * propagationFactor
+			activationMap[e.TargetID] = energy * scale
+			bands[e.TargetID] = band.scale(energy)
+
+			// Apply health impact to downstream node (scaled by propagation factor, confidence and relative size)
+			healthDelta := -0.1 * (1.0 - effectiveImpact) * propagationFactor * scale * s.sizeFactor(target, neighbor)
+			healthBefore := neighbor.Health
+			healthAfter, _ := s.Graph.ApplyHealthInput(e.TargetID, graph.InputPropagation, healthDelta)
+			trace.add(TraceStep{
+				Hop: 1, Kind: TraceForward, From: e.SourceID, To: e.TargetID, EdgeType: e.Type, Commodity: e.Commodity(),
+				Energy: activationMap[e.TargetID],
+			}.weights(originalWeight, e.Weight).health(healthBefore, healthAfter).confident(confidence, bands[e.TargetID]))
 
 			impactedNodeIDs = append(impactedNodeIDs, e.TargetID)
 		}
@@ -106,25 +185,38 @@ func (s *Simulator) RunShock(event ShockEvent) {
 
 	// Also check for reverse-direction edges (e.g., ProcuresFrom)
 	// These would be incoming edges where we are the target, but shock flows backwards
-	s.propagateReverseShocks(event.TargetNodeID, target, effectiveImpact, activationMap, &impactedNodeIDs)
+	s.propagateReverseShocks(event.TargetNodeID, target, event.Commodity, effectiveImpact, activationMap, bands, &impactedNodeIDs, eventID, trace)
+	var downstream Band // Summed over the nodes the shock reached
+	for _, b := range bands {
+		downstream = downstream.add(b)
+	}
+
+	// A blocked chokepoint also throttles every trade flow routed through it
+	if target.Type == graph.NodeTypeInfrastructure {
+		s.disruptRoutes(target, event.Commodity, effectiveImpact, eventID, trace)
+	}
 
 	// Identify WINNERS: Find substitute and competitor nodes
 	s.identifyWinners(event.TargetNodeID, &winners)
+	winners = dedupe(winners)
 
 	if len(winners) > 0 {
-		logger.Info(logger.StatusFin, "WINNERS (Positive Impact):")
+		s.info(0, logger.StatusFin, "WINNERS (Positive Impact):")
+		boosts := s.winnerBoosts(winners)
 		for _, winnerID := range winners {
 			winner, _ := s.Graph.GetNode(winnerID)
-			logger.SuccessDepth(2, "%s (Substitute/Competitor) - Expected demand increase", winner.Name)
+			s.success(2, "%s (Substitute/Competitor) - Expected demand increase (+%.3f)", winner.Name, boosts[winnerID])
 
 			// Apply positive health boost
-			s.Graph.UpdateNodeHealth(winnerID, +0.15)
+			healthBefore := winner.Health
+			healthAfter, _ := s.Graph.ApplyHealthInput(winnerID, graph.InputWinner, boosts[winnerID])
+			trace.add(TraceStep{Hop: 1, Kind: TraceWinner, From: event.TargetNodeID, To: winnerID, Energy: boosts[winnerID]}.health(healthBefore, healthAfter))
 		}
 	}
 
 	// Second-order ripple effects with actual propagation
 	if len(impactedNodeIDs) > 0 {
-		logger.InfoDepth(1, logger.StatusRipple, "Ripple Effects (2nd Order):")
+		s.info(1, logger.StatusRipple, "Ripple Effects (2nd Order):")
 		for _, impactedID := range impactedNodeIDs {
 			impactedNode, _ := s.Graph.GetNode(impactedID)
 			activation := activationMap[impactedID]
@@ -135,16 +227,27 @@ func (s *Simulator) RunShock(event ShockEvent) {
 
 			secondaryOutgoing := s.Graph.GetOutgoingEdges(impactedID)
 			for _, e := range secondaryOutgoing {
-				downstream, _ := s.Graph.GetNode(e.TargetID)
+				if !routesCommodity(e, event.Commodity) {
+					continue
+				}
 
-				// Propagate reduced activation (50% attenuation per hop)
-				sentimentScore := -activation * 0.5
-				relevanceScore := 0.7 // Indirect connection
-				eventID := fmt.Sprintf("shock_%s_2nd_%s", event.TargetNodeID, impactedID)
+				next, _ := s.Graph.GetNode(e.TargetID)
 
-				s.Graph.UpdateEdgeWeight(e.SourceID, e.TargetID, e.Type, sentimentScore, relevanceScore, eventID)
+				// Propagate reduced activation (50% attenuation per hop), scaled by the edge's confidence
+				confidence, scale, band := s.transmission(e)
+				rippleBand := bands[impactedID].scale(0.5).times(band)
+				downstream = downstream.add(rippleBand)
+				sentimentScore := -activation * 0.5 * scale
+				relevanceScore := 0.7 // Indirect connection
+				weightBefore := e.Weight
+				if err := s.Graph.UpdateCommodityEdgeWeight(e.SourceID, e.TargetID, e.Type, e.Commodity(), sentimentScore, relevanceScore, eventID+"_2nd_"+impactedID); err == nil {
+					trace.add(TraceStep{
+						Hop: 2, Kind: TraceRipple, From: impactedID, To: e.TargetID, EdgeType: e.Type, Commodity: e.Commodity(),
+						Energy: activation * 0.5 * scale,
+					}.weights(weightBefore, e.Weight).confident(confidence, rippleBand))
+				}
 
-				logger.InfoDepth(2, "", "%s -> %s: Reduced flow (Activation: %.2f)", impactedNode.Name, downstream.Name, activation)
+				s.info(2, "", "%s -> %s: Reduced flow (Activation: %.2f)", impactedNode.Name, next.Name, activation)
 
 				// Propagate to third order if significant
 				if activation > 0.15 {
@@ -154,7 +257,37 @@ func (s *Simulator) RunShock(event ShockEvent) {
 		}
 	}
 
-	logger.InfoDepth(1, logger.StatusData, "Summary: %d directly impacted, %d winners identified", len(impactedNodeIDs), len(winners))
+	s.info(1, logger.StatusData, "Summary: %d directly impacted, %d winners identified", len(impactedNodeIDs), len(winners))
+	if downstream.High > 0 {
+		s.info(1, logger.StatusData, "Downstream energy band given edge confidence: %.3f - %.3f", downstream.Low, downstream.High)
+	}
+	s.Graph.RecordImpact(graph.ImpactShock, eventID, event.Description, baseline)
+	if trace != nil {
+		s.OnTrace(trace)
+	}
+}
+
+// RunRegionShock shocks every node located in a region or country (natural
+// disasters, regional conflicts) and returns the IDs of the shocked nodes.
+func (s *Simulator) RunRegionShock(region, description string, impactFactor float64) []string {
+	nodes := s.Graph.NodesInRegion(region)
+	if len(nodes) == 0 {
+		logger.Warn(logger.StatusWarn, "No nodes located in %s", region)
+		return nil
+	}
+
+	s.info(0, logger.StatusShock, "REGIONAL SHOCK: %s in %s (%d nodes)", description, region, len(nodes))
+
+	ids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		s.RunShock(ShockEvent{
+			TargetNodeID: n.ID,
+			Description:  description,
+			ImpactFactor: impactFactor,
+		})
+		ids = append(ids, n.ID)
+	}
+	return ids
 }
 
 // identifyWinners finds nodes that benefit from the shock (substitutes, competitors).
@@ -210,12 +343,12 @@ func (s *Simulator) findSubstitutes(commodityID string, winners *[]string) {
 }
 
 // propagateReverseShocks handles edges where shocks flow backwards (client -> supplier)
-func (s *Simulator) propagateReverseShocks(targetNodeID string, target *graph.Node, effectiveImpact float64, activationMap map[string]float64, impactedNodeIDs *[]string) {
+func (s *Simulator) propagateReverseShocks(targetNodeID string, target *graph.Node, commodity string, effectiveImpact float64, activationMap map[string]float64, bands map[string]Band, impactedNodeIDs *[]string, eventID string, trace *Trace) {
 	// We need to check all edges in the graph where we are the TARGET
 	// and the edge has reverse directionality
 	// Use thread-safe edge iteration
 	s.Graph.EdgesRange(func(edge *graph.Edge) {
-		if edge.TargetID != targetNodeID {
+		if edge.TargetID != targetNodeID || !routesCommodity(edge, commodity) {
 			return
 		}
 
@@ -230,26 +363,147 @@ func (s *Simulator) propagateReverseShocks(targetNodeID string, target *graph.No
 			return
 		}
 
-		propagationFactor := graph.GetShockPropagationFactor(edge.Type)
+		confidence, scale, band := s.transmission(edge)
+		propagationFactor := s.Graph.ShockPropagationFactor(edge)
 		originalWeight := edge.Weight
 		newWeight := originalWeight * effectiveImpact
 
 		sentimentScore := -(1.0 - effectiveImpact)
 		relevanceScore := 1.0
-		eventID := fmt.Sprintf("shock_%s_reverse", targetNodeID)
 
-		if err := s.Graph.UpdateEdgeWeight(edge.SourceID, edge.TargetID, edge.Type, sentimentScore, relevanceScore, eventID); err == nil {
-			logger.SuccessDepth(2, "%s <- %s [%s REVERSE]: Weight %.2f -> %.2f (upstream impact: %.0f%%)",
-				upstream.Name, target.Name, edge.Type, originalWeight, newWeight, propagationFactor*100)
+		if err := s.Graph.UpdateCommodityEdgeWeight(edge.SourceID, edge.TargetID, edge.Type, edge.Commodity(), sentimentScore, relevanceScore, eventID+"_reverse"); err == nil {
+			s.success(2, "%s <- %s [%s REVERSE]: Weight %.2f -> %.2f (upstream impact: %.0f%%, confidence: %.0f%%)",
+				upstream.Name, target.Name, edge.Type, originalWeight, newWeight, propagationFactor*scale*100, confidence*100)
 
 			// Propagate activation energy upstream
-			activationMap[edge.SourceID] = (1.0 - effectiveImpact) * edge.Weight * propagationFactor
+			energy := (1.0 - effectiveImpact) * edge.Weight * propagationFactor
+			activationMap[edge.SourceID] = energy * scale
+			bands[edge.SourceID] = band.scale(energy)
 
 			// Apply health impact to upstream node
-			healthDelta := -0.05 * (1.0 - effectiveImpact) * propagationFactor // Weaker upstream impact
-			s.Graph.UpdateNodeHealth(edge.SourceID, healthDelta)
+			healthDelta := -0.05 * (1.0 - effectiveImpact) * propagationFactor * scale * s.sizeFactor(target, upstream) // Weaker upstream impact
+			healthBefore := upstream.Health
+			healthAfter, _ := s.Graph.ApplyHealthInput(edge.SourceID, graph.InputPropagation, healthDelta)
+			trace.add(TraceStep{
+				Hop: 1, Kind: TraceReverse, From: targetNodeID, To: edge.SourceID, EdgeType: edge.Type, Commodity: edge.Commodity(),
+				Energy: activationMap[edge.SourceID],
+			}.weights(originalWeight, edge.Weight).health(healthBefore, healthAfter).confident(confidence, bands[edge.SourceID]))
 
 			*impactedNodeIDs = append(*impactedNodeIDs, edge.SourceID)
 		}
 	})
 }
+
+// disruptRoutes cuts the weight of trade edges that pass through a shocked chokepoint
+func (s *Simulator) disruptRoutes(chokepoint *graph.Node, commodity string, effectiveImpact float64, eventID string, trace *Trace) {
+	type route struct {
+		source, target, hsCode string
+		edge                   *graph.Edge
+	}
+	var routes []route
+	s.Graph.EdgesRange(func(e *graph.Edge) {
+		if e.Type == graph.EdgeTypeTrade && routesCommodity(e, commodity) && e.RoutesThrough(chokepoint.ID) {
+			routes = append(routes, route{e.SourceID, e.TargetID, e.Commodity(), e})
+		}
+	})
+	if len(routes) == 0 {
+		return
+	}
+
+	s.info(1, logger.StatusRipple, "Trade flows routed through %s:", chokepoint.Name)
+	for _, r := range routes {
+		weightBefore := r.edge.Weight
+		if err := s.Graph.UpdateCommodityEdgeWeight(r.source, r.target, graph.EdgeTypeTrade, r.hsCode, -(1.0 - effectiveImpact), 1.0, eventID+"_routes"); err == nil {
+			s.info(2, "", "%s -> %s: Rerouted/delayed", r.source, r.target)
+			trace.add(TraceStep{
+				Hop: 1, Kind: TraceRoute, From: r.source, To: r.target, EdgeType: graph.EdgeTypeTrade, Commodity: r.hsCode,
+				Energy: 1.0 - effectiveImpact,
+			}.weights(weightBefore, r.edge.Weight))
+		}
+	}
+}
+
+// winnerBoost is the health boost each winner gets when company sizes are unknown
+const winnerBoost = 0.15
+
+// sizeFactor scales shock transmission by relative company size: a large
+// company's distress hits small partners harder than the reverse. Returns 1
+// unless both nodes have a market cap known in US dollars.
+func (s *Simulator) sizeFactor(from, to *graph.Node) float64 {
+	if from == nil || to == nil {
+		return 1.0
+	}
+	fromCap, toCap := s.usdMarketCap(from), s.usdMarketCap(to)
+	if fromCap <= 0 || toCap <= 0 {
+		return 1.0
+	}
+	return math.Max(0.25, math.Min(2.0, math.Sqrt(fromCap/toCap)))
+}
+
+// usdMarketCap returns a node's market cap in US dollars, converted from its
+// listing's currency, or 0 if the cap or the currency's rate is unknown
+func (s *Simulator) usdMarketCap(n *graph.Node) float64 {
+	capValue := n.MarketCap()
+	if capValue <= 0 {
+		return 0
+	}
+	usd, ok := s.Graph.ConvertPrice(capValue, n.Currency, "USD")
+	if !ok {
+		return 0
+	}
+	return usd
+}
+
+// winnerBoosts splits the total winner boost by market-cap share, so larger
+// competitors capture more of the displaced demand. Caps are compared in US
+// dollars; winners without a known cap (or currency rate) are treated as
+// average-sized.
+func (s *Simulator) winnerBoosts(winners []string) map[string]float64 {
+	caps := make(map[string]float64, len(winners))
+	var known, total float64
+	for _, id := range winners {
+		n, ok := s.Graph.GetNode(id)
+		if !ok {
+			continue
+		}
+		if capValue := s.usdMarketCap(n); capValue > 0 {
+			caps[id] = capValue
+			known++
+			total += capValue
+		}
+	}
+
+	boosts := make(map[string]float64, len(winners))
+	if known == 0 {
+		for _, id := range winners {
+			boosts[id] = winnerBoost
+		}
+		return boosts
+	}
+
+	avg := total / known
+	for _, id := range winners {
+		if _, ok := caps[id]; !ok {
+			caps[id] = avg
+			total += avg
+		}
+	}
+	pool := winnerBoost * float64(len(winners))
+	for _, id := range winners {
+		boosts[id] = math.Max(0.05, math.Min(0.3, pool*caps[id]/total))
+	}
+	return boosts
+}
+
+// dedupe removes repeated IDs, keeping first occurrences in order
+func dedupe(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
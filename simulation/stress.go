@@ -0,0 +1,101 @@
+package simulation
+
+import (
+	"context"
+	"margraf/clock"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/pipeline"
+	"margraf/server"
+	"sync"
+	"time"
+)
+
+// StressMonitor recomputes the graph's stress index whenever the graph changes
+// and broadcasts the ranked warning list as "stress_update".
+type StressMonitor struct {
+	Graph    *graph.Graph
+	Hub      *server.Hub
+	Window   time.Duration       // How far back signals count
+	Weights  graph.StressWeights // Contribution of each signal
+	Top      int                 // Nodes per broadcast (0 = all)
+	Debounce time.Duration       // Minimum time between recomputations
+
+	notify chan struct{}
+
+	mu     sync.RWMutex
+	latest []graph.NodeStress
+}
+
+// NewStressMonitor creates a monitor with a 24h window and default weights
+func NewStressMonitor(g *graph.Graph, h *server.Hub) *StressMonitor {
+	return &StressMonitor{
+		Graph:    g,
+		Hub:      h,
+		Window:   24 * time.Hour,
+		Weights:  graph.DefaultStressWeights(),
+		Top:      20,
+		Debounce: 2 * time.Second,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// Notify schedules a recomputation. It never blocks, so it is safe to call
+// from the graph change hook.
+func (m *StressMonitor) Notify() {
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Start recomputes on every notification (at most once per Debounce) and at
+// least once per interval, so replicas and decaying signals stay current.
+// It returns when ctx is cancelled.
+func (m *StressMonitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	logger.Info(logger.StatusMon, "Stress Monitor active. Window %v, refresh at least every %v...", m.Window, interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.notify:
+		case <-ticker.C:
+		}
+		if !pipeline.Enabled(pipeline.Stress) {
+			continue
+		}
+		m.Update()
+		time.Sleep(m.Debounce)
+	}
+}
+
+// Update recomputes the stress index and broadcasts it
+func (m *StressMonitor) Update() []graph.NodeStress {
+	ranked := m.Graph.StressIndex(m.Window, m.Weights)
+	if m.Top > 0 && len(ranked) > m.Top {
+		ranked = ranked[:m.Top]
+	}
+
+	m.mu.Lock()
+	m.latest = ranked
+	m.mu.Unlock()
+
+	if m.Hub != nil {
+		m.Hub.Broadcast(server.TypeStressUpdate, server.StressUpdatePayload{
+			Window:    m.Window.String(),
+			Nodes:     ranked,
+			Timestamp: clock.Now(),
+		})
+	}
+	return ranked
+}
+
+// Latest returns the most recent ranking
+func (m *StressMonitor) Latest() []graph.NodeStress {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]graph.NodeStress(nil), m.latest...)
+}
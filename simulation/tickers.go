@@ -0,0 +1,88 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/task"
+	"time"
+)
+
+// tickerRetry is how long to wait before retrying a failed ticker lookup
+const tickerRetry = 7 * 24 * time.Hour
+
+// errTickerTried marks a lookup skipped because it failed recently
+var errTickerTried = errors.New("ticker lookup failed recently")
+
+// TickerReport summarizes an EnrichTickers run
+type TickerReport struct {
+	Candidates int `json:"candidates"` // Corporations without a ticker considered
+	Found      int `json:"found"`
+	Failed     int `json:"failed"`
+	Skipped    int `json:"skipped"` // Failed within the last week
+}
+
+// lookupTicker resolves a company's ticker via the symbol search, preferring
+// a listing on its country's exchange, and stores it on the node. A failed
+// lookup is not retried for a week.
+func (m *MarketMonitor) lookupTicker(ctx context.Context, id, name, country string) (string, error) {
+	m.mu.Lock()
+	last, tried := m.tickerTried[id]
+	if tried && time.Since(last) < tickerRetry {
+		m.mu.Unlock()
+		return "", errTickerTried
+	}
+	m.tickerTried[id] = time.Now()
+	m.mu.Unlock()
+
+	ex, _ := graph.CountryExchange(country)
+	ticker, err := m.Scraper.GetTicker(ctx, name, ex.Name, ex.Suffix)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Cancelled, not a miss: try again next time
+			m.mu.Lock()
+			delete(m.tickerTried, id)
+			m.mu.Unlock()
+		}
+		return "", err
+	}
+	if err := m.Graph.SetNodeTicker(id, ticker); err != nil {
+		return "", err
+	}
+	ticker, _ = m.Graph.GetNodeTicker(id) // As qualified for its exchange
+
+	m.mu.Lock()
+	delete(m.tickerTried, id)
+	m.mu.Unlock()
+	logger.InfoDepth(2, logger.StatusTag, "Found Ticker for %s: %s", name, ticker)
+	return ticker, nil
+}
+
+// EnrichTickers looks up tickers for up to limit corporations without one
+// (0 = all), most central first (see graph.MissingTickers). Lookups that
+// failed within the last week are skipped. t, if set, reports progress.
+func (m *MarketMonitor) EnrichTickers(ctx context.Context, limit int, t *task.Task) (TickerReport, error) {
+	candidates := m.Graph.MissingTickers(limit)
+	report := TickerReport{Candidates: len(candidates)}
+	for i, c := range candidates {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if t != nil {
+			t.Progress(i, len(candidates), c.Name)
+		}
+		_, err := m.lookupTicker(ctx, c.NodeID, c.Name, c.Country)
+		switch {
+		case err == nil:
+			report.Found++
+		case errors.Is(err, errTickerTried):
+			report.Skipped++
+		case ctx.Err() != nil:
+			return report, ctx.Err()
+		default:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
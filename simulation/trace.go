@@ -0,0 +1,160 @@
+package simulation
+
+import (
+	"encoding/json"
+	"margraf/graph"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A trace records how one shock spread: every node it reached, in order,
+// with the edge it travelled along and the energy it carried there, so the
+// dashboard can replay the spread instead of showing only the end state.
+
+// TraceKind is how a shock reached a node
+type TraceKind string
+
+const (
+	TraceOrigin  TraceKind = "origin"  // The shocked node itself
+	TraceForward TraceKind = "forward" // Along an edge, source to target
+	TraceReverse TraceKind = "reverse" // Against a reverse edge, client to supplier
+	TraceRoute   TraceKind = "route"   // A trade flow routed through a shocked chokepoint
+	TraceWinner  TraceKind = "winner"  // A substitute or competitor that gains
+	TraceRipple  TraceKind = "ripple"  // Second-order spread from a directly hit node
+)
+
+// TraceStep is one node reached by a shock
+type TraceStep struct {
+	Hop          int            `json:"hop"` // 0 = the shocked node, 1 = its neighbours, 2 = theirs
+	Kind         TraceKind      `json:"kind"`
+	From         string         `json:"from,omitempty"`
+	To           string         `json:"to"`
+	EdgeType     graph.EdgeType `json:"edge_type,omitempty"`
+	Commodity    string         `json:"commodity,omitempty"`
+	Energy       float64        `json:"energy"`                  // Activation reaching To; a winner's boost is positive
+	Confidence   *float64       `json:"confidence,omitempty"`    // Of the edge travelled; left out for routes and winners
+	Band         *Band          `json:"band,omitempty"`          // Range Energy could span given the confidence of the edges travelled
+	WeightBefore *float64       `json:"weight_before,omitempty"` // Left out when no edge was updated
+	WeightAfter  *float64       `json:"weight_after,omitempty"`
+	HealthBefore *float64       `json:"health_before,omitempty"` // Left out when To's health was not touched
+	HealthAfter  *float64       `json:"health_after,omitempty"`
+}
+
+// weights sets the step's edge weight before and after
+func (s TraceStep) weights(before, after float64) TraceStep {
+	s.WeightBefore, s.WeightAfter = &before, &after
+	return s
+}
+
+// confident sets the confidence of the edge travelled and the step's energy band
+func (s TraceStep) confident(confidence float64, band Band) TraceStep {
+	s.Confidence, s.Band = &confidence, &band
+	return s
+}
+
+// health sets To's health before and after
+func (s TraceStep) health(before, after float64) TraceStep {
+	s.HealthBefore, s.HealthAfter = &before, &after
+	return s
+}
+
+// Trace is the ordered spread of one shock
+type Trace struct {
+	EventID         string      `json:"event_id"`
+	Target          string      `json:"target"`
+	Description     string      `json:"description"`
+	Commodity       string      `json:"commodity,omitempty"`
+	ImpactFactor    float64     `json:"impact_factor"`
+	EffectiveImpact float64     `json:"effective_impact"` // After the target's resilience
+	Time            time.Time   `json:"time"`
+	Steps           []TraceStep `json:"steps"` // In the order the shock reached them
+}
+
+// add appends a step; a nil trace records nothing
+func (t *Trace) add(step TraceStep) {
+	if t != nil {
+		t.Steps = append(t.Steps, step)
+	}
+}
+
+// Hops is the deepest hop the shock reached
+func (t *Trace) Hops() int {
+	max := 0
+	for _, s := range t.Steps {
+		if s.Hop > max {
+			max = s.Hop
+		}
+	}
+	return max
+}
+
+// Downstream sums the energy and confidence band of the steps along edges;
+// a trace recorded before edge confidence has no band
+func (t *Trace) Downstream() (energy float64, band Band) {
+	for _, s := range t.Steps {
+		if s.Band != nil {
+			energy += s.Energy
+			band = band.add(*s.Band)
+		}
+	}
+	return energy, band
+}
+
+// maxSavedTraces bounds how many trace files SaveTrace keeps in a directory
+const maxSavedTraces = 200
+
+// SaveTrace writes t to dir as <event ID>.json and returns the file's path.
+// The oldest traces beyond the newest 200 are removed.
+func SaveTrace(dir string, t *Trace) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(t.EventID) + ".json"
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) <= maxSavedTraces {
+		return path, nil
+	}
+	type file struct {
+		path string
+		mod  time.Time
+	}
+	var files []file
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files = append(files, file{filepath.Join(dir, e.Name()), info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mod.After(files[j].mod) })
+	for _, f := range files[min(len(files), maxSavedTraces):] {
+		os.Remove(f.path)
+	}
+	return path, nil
+}
+
+// LoadTrace reads a trace written by SaveTrace
+func LoadTrace(path string) (*Trace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Trace
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
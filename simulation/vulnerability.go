@@ -0,0 +1,180 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"margraf/clock"
+	"margraf/graph"
+	"sort"
+	"time"
+)
+
+// A type stress test shocks every node of one type in turn, each on a fresh
+// copy of the graph, and ranks the nodes downstream by how often and how
+// badly the shocks reach them. Where Compare answers "what does this
+// scenario do", the test answers "which companies does any raw material
+// (or nation, or port) failing hurt most".
+
+// Type stress test defaults
+const (
+	defaultStressImpact = 0.3  // Share of strength each shocked node loses
+	defaultStressDrop   = 0.01 // Health drop counted as a hit
+	defaultStressTop    = 20
+)
+
+// TypeStressOptions configures StressType. Zero fields take the defaults.
+type TypeStressOptions struct {
+	Type     graph.NodeType // Nodes shocked, one at a time
+	Impact   float64        // Share of strength each loses (0 = 0.3): a shock with ImpactFactor 1 - Impact
+	Affected graph.NodeType // Nodes ranked ("" = Corporation)
+	MinDrop  float64        // Health drop counted as a hit (0 = 0.01)
+	Top      int            // Nodes in the table (0 = 20)
+
+	Progress func(done, total int, nodeID string) `json:"-"` // Called after each shock (optional)
+}
+
+// withDefaults fills in the zero fields
+func (o TypeStressOptions) withDefaults() TypeStressOptions {
+	if o.Impact <= 0 {
+		o.Impact = defaultStressImpact
+	}
+	if o.Affected == "" {
+		o.Affected = graph.NodeTypeCorporation
+	}
+	if o.MinDrop <= 0 {
+		o.MinDrop = defaultStressDrop
+	}
+	if o.Top <= 0 {
+		o.Top = defaultStressTop
+	}
+	return o
+}
+
+// Vulnerability is how the shocks of a type stress test hit one node
+type Vulnerability struct {
+	NodeID    string  `json:"node_id"`
+	Name      string  `json:"name"`
+	Hits      int     `json:"hits"`      // Shocks that cut its health by MinDrop or more
+	HitRate   float64 `json:"hit_rate"`  // Hits over shocks run
+	MeanDrop  float64 `json:"mean_drop"` // Mean health drop over its hits
+	MaxDrop   float64 `json:"max_drop"`  // Largest health drop
+	WorstID   string  `json:"worst_id"`  // Shocked node behind the largest drop
+	WorstName string  `json:"worst_name"`
+	Score     float64 `json:"score"` // Expected health drop per shock: hit rate times mean drop, the ranking key
+}
+
+// TypeStressReport is the result of a type stress test
+type TypeStressReport struct {
+	Type      graph.NodeType  `json:"type"`
+	Impact    float64         `json:"impact"`
+	Affected  graph.NodeType  `json:"affected"`
+	Shocks    int             `json:"shocks"`  // Nodes shocked
+	Exposed   int             `json:"exposed"` // Affected nodes hit at least once
+	Nodes     []Vulnerability `json:"nodes"`   // Most vulnerable first
+	Duration  time.Duration   `json:"duration"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// StressType shocks every node of opts.Type in turn, each on its own copy of
+// the graph, and ranks the opts.Affected nodes by the health they lose. The
+// live graph is not changed. It stops early with ctx's error when ctx is
+// cancelled.
+func (s *Simulator) StressType(ctx context.Context, opts TypeStressOptions) (*TypeStressReport, error) {
+	opts = opts.withDefaults()
+	if opts.Impact > 1 {
+		return nil, fmt.Errorf("impact must be between 0 and 1, got %.2f", opts.Impact)
+	}
+	start := time.Now()
+	baseline, err := s.Graph.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy graph: %w", err)
+	}
+
+	var targets []string
+	health := make(map[string]float64)
+	names := make(map[string]string)
+	baseline.NodesRange(func(n *graph.Node) {
+		switch n.Type {
+		case opts.Type:
+			targets = append(targets, n.ID)
+		case opts.Affected:
+			health[n.ID] = n.Health
+		}
+		names[n.ID] = n.Name
+	})
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no %s nodes to shock", opts.Type)
+	}
+	sort.Strings(targets)
+
+	hits := make(map[string]*Vulnerability)
+	for i, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		sandbox, err := baseline.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy graph: %w", err)
+		}
+		what := &Simulator{Graph: sandbox, ShockDamage: s.ShockDamage, Quiet: true}
+		what.RunShock(ShockEvent{
+			TargetNodeID: target,
+			Description:  fmt.Sprintf("Stress test: %s loses %.0f%%", names[target], opts.Impact*100),
+			ImpactFactor: 1 - opts.Impact,
+		})
+		sandbox.NodesRange(func(n *graph.Node) {
+			before, ok := health[n.ID]
+			if !ok {
+				return
+			}
+			drop := before - n.Health
+			if drop < opts.MinDrop {
+				return
+			}
+			v := hits[n.ID]
+			if v == nil {
+				v = &Vulnerability{NodeID: n.ID, Name: names[n.ID]}
+				hits[n.ID] = v
+			}
+			v.Hits++
+			v.MeanDrop += drop // Summed here, averaged below
+			if drop > v.MaxDrop {
+				v.MaxDrop, v.WorstID, v.WorstName = drop, target, names[target]
+			}
+		})
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(targets), target)
+		}
+	}
+
+	r := &TypeStressReport{
+		Type:      opts.Type,
+		Impact:    opts.Impact,
+		Affected:  opts.Affected,
+		Shocks:    len(targets),
+		Exposed:   len(hits),
+		Nodes:     []Vulnerability{},
+		Timestamp: clock.Now(),
+	}
+	for _, v := range hits {
+		v.Score = v.MeanDrop / float64(len(targets))
+		v.HitRate = float64(v.Hits) / float64(len(targets))
+		v.MeanDrop /= float64(v.Hits)
+		r.Nodes = append(r.Nodes, *v)
+	}
+	sort.Slice(r.Nodes, func(i, j int) bool {
+		a, b := r.Nodes[i], r.Nodes[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Hits != b.Hits {
+			return a.Hits > b.Hits
+		}
+		return a.NodeID < b.NodeID
+	})
+	if len(r.Nodes) > opts.Top {
+		r.Nodes = r.Nodes[:opts.Top]
+	}
+	r.Duration = time.Since(start)
+	return r, nil
+}
@@ -0,0 +1,243 @@
+package social
+
+import (
+	"context"
+	"margraf/llm"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A crawl about "Nvidia" mixes posts on export bans, earnings and layoffs.
+// Averaging them hides which story moved the mood, so posts are grouped by
+// embedding similarity and each group gets its own sentiment and label.
+
+// Similarity a post needs to the centroid of a group to join it. Provider
+// embeddings place posts on the same story close together; the local hashed
+// embedding only matches shared words, so it gets a lower bar.
+const (
+	clusterSimilarity      = 0.75
+	localClusterSimilarity = 0.3
+)
+
+// maxDominant is how many sub-topics a sentiment reading is explained by
+const maxDominant = 3
+
+// SubTopic is a group of similar posts within one crawl
+type SubTopic struct {
+	Label     string  `json:"label"` // Words most particular to the group's posts
+	Posts     int     `json:"posts"`
+	Sentiment float64 `json:"sentiment"` // Average over the group, -1.0 to 1.0
+}
+
+// clusterPosts groups scored posts about topic by embedding similarity,
+// largest group first. Embeddings come from the client's embedding model,
+// or the local hashed embedding when that fails.
+func (s *SocialMonitor) clusterPosts(ctx context.Context, topic string, comments []SocialComment) []SubTopic {
+	if len(comments) == 0 {
+		return nil
+	}
+	// Every post mentions the topic, so its words would pull them all together
+	skip := topicWords(topic)
+	texts := make([]string, len(comments))
+	for i, c := range comments {
+		texts[i] = strings.Join(contentWords(c.Content, skip), " ")
+	}
+
+	threshold := clusterSimilarity
+	var vectors [][]float64
+	var err error
+	if s.Client != nil && s.Client.EmbeddingModel() != llm.LocalEmbedModel {
+		vectors, err = s.Client.Embed(ctx, texts)
+	}
+	if vectors == nil || err != nil {
+		threshold = localClusterSimilarity
+		vectors = make([][]float64, len(texts))
+		for i, text := range texts {
+			vectors[i] = llm.LocalEmbed(text)
+		}
+	}
+
+	// Single pass: each post joins the closest group it is similar enough
+	// to, else starts a new one
+	type group struct {
+		centroid []float64
+		members  []int
+	}
+	var groups []*group
+	for i, v := range vectors {
+		var best *group
+		bestScore := threshold
+		for _, g := range groups {
+			if score := cosine(v, g.centroid); score >= bestScore {
+				best, bestScore = g, score
+			}
+		}
+		if best == nil {
+			groups = append(groups, &group{centroid: append([]float64(nil), v...), members: []int{i}})
+			continue
+		}
+		n := float64(len(best.members))
+		for d := range best.centroid {
+			best.centroid[d] = (best.centroid[d]*n + v[d]) / (n + 1)
+		}
+		best.members = append(best.members, i)
+	}
+
+	// Document frequency of each word and word pair across all posts, so
+	// labels favor what is particular to a group
+	terms := make([][]string, len(texts))
+	df := make(map[string]int)
+	for i, text := range texts {
+		terms[i] = postTerms(text)
+		for _, t := range terms[i] {
+			df[t]++
+		}
+	}
+
+	out := make([]SubTopic, 0, len(groups))
+	for _, g := range groups {
+		var sum float64
+		inGroup := make(map[string]int)
+		for _, i := range g.members {
+			sum += comments[i].Sentiment
+			for _, t := range terms[i] {
+				inGroup[t]++
+			}
+		}
+		out = append(out, SubTopic{
+			Label:     groupLabel(inGroup, df, len(g.members), texts[g.members[0]]),
+			Posts:     len(g.members),
+			Sentiment: sum / float64(len(g.members)),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Posts > out[j].Posts })
+	return out
+}
+
+// dominant returns the sub-topics that explain a reading: the largest groups
+// of more than one post, or the largest group when every post stands alone
+func dominant(topics []SubTopic) []SubTopic {
+	var out []SubTopic
+	for _, t := range topics {
+		if t.Posts > 1 && len(out) < maxDominant {
+			out = append(out, t)
+		}
+	}
+	if len(out) == 0 && len(topics) > 0 {
+		out = topics[:1]
+	}
+	return out
+}
+
+// groupLabel picks the word or word pair most particular to a group: shared
+// by its posts and rare outside it, preferring pairs ("export ban") on ties.
+// A lone post, or a group sharing nothing, is named by its first post's
+// opening words.
+func groupLabel(inGroup, df map[string]int, size int, first string) string {
+	best, bestScore := "", 0.0
+	for t, n := range inGroup {
+		if n < 2 || size < 2 {
+			continue
+		}
+		score := float64(n) * float64(n) / float64(df[t])
+		if strings.Contains(t, " ") {
+			score *= 1.2
+		}
+		if score > bestScore || score == bestScore && t < best {
+			best, bestScore = t, score
+		}
+	}
+	if best != "" {
+		return best
+	}
+	words := strings.Fields(first)
+	if len(words) > 4 {
+		words = words[:4]
+	}
+	if len(words) == 0 {
+		return "other"
+	}
+	return strings.Join(words, " ")
+}
+
+// postTerms lists the distinct words and adjacent word pairs of a post
+func postTerms(text string) []string {
+	words := strings.Fields(text)
+	seen := make(map[string]bool)
+	var out []string
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	for i, w := range words {
+		add(w)
+		if i+1 < len(words) {
+			add(w + " " + words[i+1])
+		}
+	}
+	return out
+}
+
+// topicWords are the lowercased words of a topic
+func topicWords(topic string) map[string]bool {
+	out := make(map[string]bool)
+	for _, w := range contentWords(topic, nil) {
+		out[w] = true
+	}
+	return out
+}
+
+// contentWords lowercases text and keeps its words of three letters or more
+// that are not stopwords, numbers or in skip
+func contentWords(text string, skip map[string]bool) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var out []string
+	for _, w := range fields {
+		if len([]rune(w)) < 3 || stopwords[w] || skip[w] || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+// stopwords carry no topic of their own
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true, "you": true,
+	"all": true, "any": true, "can": true, "had": true, "her": true, "was": true, "one": true,
+	"our": true, "out": true, "has": true, "have": true, "his": true, "how": true, "its": true,
+	"now": true, "new": true, "who": true, "why": true, "will": true, "with": true, "this": true,
+	"that": true, "they": true, "them": true, "their": true, "there": true, "then": true,
+	"than": true, "from": true, "been": true, "were": true, "what": true, "when": true,
+	"where": true, "which": true, "would": true, "could": true, "should": true, "about": true,
+	"into": true, "just": true, "more": true, "most": true, "some": true, "such": true,
+	"only": true, "over": true, "also": true, "very": true, "like": true, "your": true,
+	"here": true, "after": true, "before": true, "being": true, "does": true, "doing": true,
+	"these": true, "those": true, "because": true, "while": true, "http": true, "https": true,
+	"www": true, "com": true, "amp": true, "get": true, "got": true, "say": true, "says": true,
+	"said": true, "think": true, "really": true, "still": true, "even": true, "much": true,
+	"many": true, "other": true, "going": true, "know": true, "see": true, "way": true,
+}
+
+// cosine is the cosine similarity of two vectors (0 when either is zero)
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -1,14 +1,21 @@
 package social
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"margraf/config"
+	"margraf/discovery"
 	"margraf/graph"
 	"margraf/llm"
 	"margraf/logger"
+	"margraf/nlp"
 	"margraf/scraper"
 	"margraf/server"
-	"strings"
+	"margraf/syserr"
+	"margraf/task"
+	"sync"
+	"time"
 )
 
 // Platform represents a social network
@@ -30,121 +37,181 @@ type SocialComment struct {
 }
 
 type SocialMonitor struct {
-	Client  *llm.Client
-	Hub     *server.Hub
-	Graph   *graph.Graph
-	Scraper *scraper.SocialScraper
+	Client    *llm.Client
+	Hub       *server.Hub
+	Graph     *graph.Graph
+	Scraper   *scraper.SocialScraper
+	Extractor *nlp.Extractor // Post analysis, with a local fallback when the LLM is down
+	Volume    VolumeSettings // Mention spike thresholds
+	Crawl     CrawlSettings  // Crawl queue workers, cooldown and size
+
+	CoMentions *discovery.CoMentionLearner // Proposes edges between nodes named in one post (optional)
+
+	queue crawlQueue
+
+	volumeMu  sync.Mutex
+	mentioned map[string]time.Time // Posts counted as mentions, by URL, with when
+	spiked    map[string]time.Time // Hour each node last raised a mention spike
 }
 
 func NewMonitor(c *llm.Client, h *server.Hub, g *graph.Graph) *SocialMonitor {
 	return &SocialMonitor{
-		Client:  c,
-		Hub:     h,
-		Graph:   g,
-		Scraper: scraper.NewSocialScraper(),
+		Client:    c,
+		Hub:       h,
+		Graph:     g,
+		Scraper:   scraper.NewSocialScraper(),
+		Extractor: nlp.NewExtractor(c, g),
+		Volume:    VolumeSettingsFromConfig(),
+		Crawl:     CrawlSettingsFromConfig(),
 	}
 }
 
 // CrawlReal fetches real social media discussions and analyzes them with AI.
-func (s *SocialMonitor) CrawlReal(topic string) {
-	logger.Info(logger.StatusSoc, "Crawling Social Media for: '%s'", topic)
+// Cancelling ctx stops the crawl between platforms and posts.
+func (s *SocialMonitor) CrawlReal(ctx context.Context, job CrawlJob) {
+	topic, query := job.Topic, job.query()
+	logger.Info(logger.StatusSoc, "Crawling Social Media for: '%s' (searching %q)", topic, query)
 
 	var allPosts []scraper.SocialPost
+	var failures []error
 	sources := 0
 
 	// 1. Hacker News (Most reliable - official API)
+	task.Report(ctx, 0, 7, "Hacker News")
 	logger.InfoDepth(1, logger.StatusSoc, "Searching Hacker News...")
-	if posts, err := s.Scraper.FetchHackerNewsPosts(topic, 3); err == nil && len(posts) > 0 {
+	if posts, err := s.Scraper.FetchHackerNewsPosts(ctx, query, 3); err == nil && len(posts) > 0 {
 		allPosts = append(allPosts, posts...)
 		logger.SuccessDepth(2, "Found %d Hacker News posts", len(posts))
 		sources++
 	} else if err != nil {
 		logger.WarnDepth(2, logger.StatusWarn, "Hacker News: %v", err)
+		failures = append(failures, fmt.Errorf("Hacker News: %w", err))
 	}
 
 	// 2. Reddit (Official JSON API)
+	if ctx.Err() != nil {
+		return
+	}
+	task.Report(ctx, 1, 7, "Reddit")
 	logger.InfoDepth(1, logger.StatusSoc, "Searching Reddit...")
-	if posts, err := s.Scraper.FetchRedditPosts(topic, 3); err == nil && len(posts) > 0 {
+	if posts, err := s.Scraper.FetchRedditPosts(ctx, query, 3); err == nil && len(posts) > 0 {
 		allPosts = append(allPosts, posts...)
 		logger.SuccessDepth(2, "Found %d Reddit posts", len(posts))
 		sources++
 	} else if err != nil {
 		logger.WarnDepth(2, logger.StatusWarn, "Reddit: %v", err)
+		failures = append(failures, fmt.Errorf("Reddit: %w", err))
 	}
 
 	// 3. Twitter/X (via Nitter)
+	if ctx.Err() != nil {
+		return
+	}
+	task.Report(ctx, 2, 7, "Twitter/X")
 	logger.InfoDepth(1, logger.StatusSoc, "Searching Twitter/X...")
-	if posts, err := s.Scraper.FetchTwitterViaNitter(topic, 3); err == nil && len(posts) > 0 {
+	if posts, err := s.Scraper.FetchTwitterViaNitter(ctx, query, 3); err == nil && len(posts) > 0 {
 		allPosts = append(allPosts, posts...)
 		logger.SuccessDepth(2, "Found %d tweets", len(posts))
 		sources++
 	} else if err != nil {
 		logger.WarnDepth(2, logger.StatusWarn, "Twitter: %v", err)
+		failures = append(failures, fmt.Errorf("Twitter: %w", err))
 	}
 
 	// 4. YouTube (via search)
+	if ctx.Err() != nil {
+		return
+	}
+	task.Report(ctx, 3, 7, "YouTube")
 	logger.InfoDepth(1, logger.StatusSoc, "Searching YouTube...")
-	if posts, err := s.Scraper.FetchYouTubeComments(topic, 2); err == nil && len(posts) > 0 {
+	if posts, err := s.Scraper.FetchYouTubeComments(ctx, query, 2); err == nil && len(posts) > 0 {
 		allPosts = append(allPosts, posts...)
 		logger.SuccessDepth(2, "Found %d YouTube videos", len(posts))
 		sources++
 	}
 
+	// 5. Telegram channels (public previews). Channel posts are matched
+	// literally, so these search the name rather than the query.
+	if channels := config.Global.Social.Telegram; len(channels) > 0 {
+		if ctx.Err() != nil {
+			return
+		}
+		task.Report(ctx, 4, 7, "Telegram")
+		logger.InfoDepth(1, logger.StatusSoc, "Searching %d Telegram channels...", len(channels))
+		if posts, err := s.Scraper.FetchTelegramPosts(ctx, channels, topic, 3); err == nil && len(posts) > 0 {
+			allPosts = append(allPosts, posts...)
+			logger.SuccessDepth(2, "Found %d Telegram posts", len(posts))
+			sources++
+		} else if err != nil {
+			logger.WarnDepth(2, logger.StatusWarn, "Telegram: %v", err)
+			failures = append(failures, fmt.Errorf("Telegram: %w", err))
+		}
+	}
+
+	// 6. Discord channels (bot)
+	if channels := config.Global.Social.Discord; len(channels) > 0 {
+		if ctx.Err() != nil {
+			return
+		}
+		task.Report(ctx, 5, 7, "Discord")
+		logger.InfoDepth(1, logger.StatusSoc, "Searching %d Discord channels...", len(channels))
+		if posts, err := s.Scraper.FetchDiscordPosts(ctx, channels, topic, 3); err == nil && len(posts) > 0 {
+			allPosts = append(allPosts, posts...)
+			logger.SuccessDepth(2, "Found %d Discord messages", len(posts))
+			sources++
+		} else if err != nil {
+			logger.WarnDepth(2, logger.StatusWarn, "Discord: %v", err)
+			failures = append(failures, fmt.Errorf("Discord: %w", err))
+		}
+	}
+
 	if len(allPosts) == 0 {
 		logger.Warn(logger.StatusWarn, "No posts found across any platform for '%s'", topic)
+		if len(failures) > 0 {
+			syserr.Report(syserr.ModuleSocial, "crawl", syserr.Warning(syserr.ModuleSocial, "crawl", errors.Join(failures...)))
+		}
 		return
 	}
 
 	logger.Success("Collected %d posts from %d sources", len(allPosts), sources)
-	s.analyzeAndBroadcast(topic, allPosts)
+	task.Report(ctx, 6, 7, "Analyzing sentiment")
+	s.analyzeAndBroadcast(ctx, job, allPosts)
 }
 
-func (s *SocialMonitor) analyzeAndBroadcast(topic string, posts []scraper.SocialPost) {
-	type Analysis struct {
-		Sentiment float64 `json:"sentiment"`
-	}
+// Analyze scores posts collected elsewhere (imports, test fixtures) about
+// topic and applies the average sentiment to the topic's node, as CrawlReal
+// does for crawled posts
+func (s *SocialMonitor) Analyze(ctx context.Context, topic string, posts []scraper.SocialPost) {
+	s.analyzeAndBroadcast(ctx, CrawlJob{Topic: topic}, posts)
+}
+
+func (s *SocialMonitor) analyzeAndBroadcast(ctx context.Context, job CrawlJob, posts []scraper.SocialPost) {
+	ctx = llm.WithConsumer(ctx, llm.ConsumerSocial)
+	topic := job.Topic
+
+	// Volume first: a burst of posts is news before anyone scores them
+	s.trackVolume(job, posts)
 
 	var totalSentiment float64
 	var count float64
+	var lastErr error
+	var scored []SocialComment
 
 	logger.InfoDepth(1, logger.StatusSoc, "Analyzing sentiment with LLM...")
 
 	for i, p := range posts {
-		// Limit content length for LLM
-		content := p.Content
-		if len(content) > 500 {
-			content = content[:500] + "..."
+		if ctx.Err() != nil {
+			return
 		}
-
-		// Use LLM to analyze the REAL sentiment
-		prompt := fmt.Sprintf(`
-Analyze the sentiment of this social media post about "%s".
-Platform: %s
-Content: "%s"
-
-Rate the sentiment from -1.0 (very negative) to 1.0 (very positive).
-Return ONLY a JSON object: {"sentiment": 0.5}
-`, topic, p.Platform, content)
-
-		resp, err := s.Client.Complete(prompt)
+		analysis, err := s.Extractor.Post(ctx, topic, p.Platform, p.Content)
 		if err != nil {
 			logger.WarnDepth(2, logger.StatusWarn, "LLM analysis failed for post %d: %v", i+1, err)
+			lastErr = err
 			continue
 		}
-
-		var analysis Analysis
-		cleaned := cleanJSON(resp)
-		if err := json.Unmarshal([]byte(cleaned), &analysis); err != nil {
-			logger.WarnDepth(2, logger.StatusWarn, "JSON parse error: %v", err)
-			continue
-		}
-
-		// Clamp sentiment to valid range
-		if analysis.Sentiment < -1.0 {
-			analysis.Sentiment = -1.0
-		}
-		if analysis.Sentiment > 1.0 {
-			analysis.Sentiment = 1.0
+		if analysis.Source == nlp.SourceLocal && !errors.Is(analysis.Err, llm.ErrBudgetExhausted) {
+			// Scored with the word list instead; keep the cause for the report below
+			lastErr = analysis.Err
 		}
 
 		comment := SocialComment{
@@ -166,42 +233,60 @@ Return ONLY a JSON object: {"sentiment": 0.5}
 		}
 
 		logger.InfoDepth(2, logger.StatusSoc, "[%s] @%s: %s", comment.Platform, comment.User, sentimentStr)
-		s.Hub.Broadcast("social_pulse", comment)
+		s.Hub.Broadcast(server.TypeSocialPulse, server.SocialPulsePayload{
+			Platform:  string(comment.Platform),
+			User:      comment.User,
+			Content:   comment.Content,
+			Sentiment: comment.Sentiment,
+			URL:       comment.URL,
+			Topic:     topic,
+			NodeID:    job.nodeID(),
+		})
 
 		totalSentiment += analysis.Sentiment
 		count++
+		scored = append(scored, comment)
 	}
 
 	if count > 0 {
 		avgSentiment := totalSentiment / count
 		logger.Success("Average sentiment: %.2f across %d posts", avgSentiment, int(count))
-		s.applySentimentToGraph(topic, avgSentiment)
+		topics := s.clusterPosts(ctx, topic, scored)
+		for _, t := range topics {
+			logger.InfoDepth(2, logger.StatusSoc, "Sub-topic %q: %.2f across %d posts", t.Label, t.Sentiment, t.Posts)
+		}
+		s.applySentimentToGraph(job, avgSentiment, dominant(topics))
 	} else {
 		logger.Warn(logger.StatusWarn, "No sentiment data collected")
+		syserr.Report(syserr.ModuleSocial, "analyze sentiment", lastErr)
 	}
 }
 
-func (s *SocialMonitor) applySentimentToGraph(topic string, sentiment float64) {
-	// Simple mapping: Topic name -> Node ID
-	// In a real system, we'd need Entity Linking (NER) to map "Apple" -> "apple_inc" or "apple_fruit"
-	// Here we assume the topic IS the entity name for simplicity.
-	id := strings.ToLower(strings.ReplaceAll(topic, " ", "_"))
+// applySentimentToGraph records a crawl's sentiment against the topic's node,
+// explained by the sub-topics that dominated it
+func (s *SocialMonitor) applySentimentToGraph(job CrawlJob, sentiment float64, topics []SubTopic) {
+	topic := job.Topic
+	id := job.nodeID()
 	
-	// Scale sentiment to health impact (e.g. sentiment -0.5 -> health -0.05)
-	impact := sentiment * 0.1 
-	
-	newHealth, ok := s.Graph.UpdateNodeHealth(id, impact)
-	if ok {
-		logger.InfoDepth(2, logger.StatusTrend, "Social Sentiment Impact: %s health adjusted by %.3f -> %.3f", topic, impact, newHealth)
-		s.Hub.Broadcast("graph_update", fmt.Sprintf("Node %s Health: %.2f", topic, newHealth))
+	// Record the raw reading for the stress index
+	labels := make([]string, len(topics))
+	payload := make([]server.SubTopicPayload, len(topics))
+	for i, t := range topics {
+		labels[i] = fmt.Sprintf("%s (%+.2f, %d posts)", t.Label, t.Sentiment, t.Posts)
+		payload[i] = server.SubTopicPayload{Label: t.Label, Posts: t.Posts, Sentiment: t.Sentiment}
 	}
-}
-
+	s.Graph.RecordSentiment(id, graph.SentimentSocial, sentiment, labels...)
 
-func cleanJSON(s string) string {
-	s = strings.TrimSpace(s)
-	s = strings.TrimPrefix(s, "```json")
-	s = strings.TrimPrefix(s, "```")
-	s = strings.TrimSuffix(s, "```")
-	return strings.TrimSpace(s)
+	// Sentiment is scaled to a health change by the health model (default: -0.5 -> -0.05)
+	newHealth, ok := s.Graph.ApplyHealthInput(id, graph.InputSentiment, sentiment)
+	if ok {
+		logger.InfoDepth(2, logger.StatusTrend, "Social Sentiment Impact: %s sentiment %.3f -> health %.3f", topic, sentiment, newHealth)
+		s.Hub.Broadcast(server.TypeGraphNotice, server.GraphNoticePayload{
+			NodeID:  id,
+			Name:    topic,
+			Message: fmt.Sprintf("Node %s Health: %.2f", topic, newHealth),
+			Health:  &newHealth,
+			Topics:  payload,
+		})
+	}
 }
@@ -0,0 +1,56 @@
+package social
+
+import (
+	"margraf/graph"
+	"margraf/nlp"
+	"strings"
+	"unicode"
+)
+
+// A headline makes a poor search query: "UK inflation eases as energy prices
+// fall" matches almost no posts. A headline is crawled through the entities
+// it names instead, each searched the way people talk about it.
+
+// maxHeadlineCrawls bounds the crawls one headline queues
+const maxHeadlineCrawls = 3
+
+// HeadlineCrawls turns a headline's extraction into crawls of its entities,
+// main entity first, at most maxHeadlineCrawls. mainID is the node the main
+// entity resolved to; other entities use their node when known.
+func HeadlineCrawls(g *graph.Graph, x *nlp.Extraction, mainID string) []CrawlJob {
+	var jobs []CrawlJob
+	seen := make(map[string]bool)
+	for i, e := range x.Entities {
+		if len(jobs) >= maxHeadlineCrawls {
+			break
+		}
+		key := normalizeTopic(e.Name)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		id := e.NodeID
+		if i == 0 && mainID != "" {
+			id = mainID
+		}
+		jobs = append(jobs, CrawlJob{Topic: e.Name, Query: entityQuery(g, e, id), NodeID: id})
+	}
+	return jobs
+}
+
+// entityQuery is the search for an entity: a company with its ticker
+// ("Nvidia NVDA"), a nation with "trade", since its economy is what matters
+// here and its name alone finds mostly politics, and anything else by name
+func entityQuery(g *graph.Graph, e nlp.Entity, id string) string {
+	switch e.Type {
+	case graph.NodeTypeCorporation:
+		ticker, _ := g.GetNodeTicker(id)
+		ticker, _, _ = strings.Cut(ticker, ".") // "7203.T": posts drop the exchange
+		if strings.IndexFunc(ticker, unicode.IsLetter) >= 0 && !strings.EqualFold(ticker, e.Name) {
+			return e.Name + " " + ticker
+		}
+	case graph.NodeTypeNation:
+		return e.Name + " trade"
+	}
+	return e.Name
+}
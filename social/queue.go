@@ -0,0 +1,179 @@
+package social
+
+import (
+	"context"
+	"margraf/config"
+	"margraf/logger"
+	"margraf/task"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Every analyzed headline asks for crawls of what it names. In a busy news
+// cycle that is dozens of overlapping crawls hitting the same endpoints, so
+// crawls go through a queue: a topic already queued or running is not queued
+// again, a topic crawled recently waits out its cooldown, and a few workers
+// run the queue.
+
+// Defaults for social.crawl
+const (
+	defaultCrawlWorkers  = 2
+	defaultCrawlCooldown = 30 * time.Minute
+	defaultCrawlQueue    = 20
+)
+
+// CrawlSettings bound how many crawls run and how often a topic is crawled
+type CrawlSettings struct {
+	Workers  int           // Crawls run at once
+	Cooldown time.Duration // Wait after a topic's crawl before it is crawled again
+	MaxQueue int           // Crawls waiting; more are dropped
+	Timeout  time.Duration // Limit for one crawl
+}
+
+// CrawlSettingsFromConfig reads social.crawl and timeouts.crawl, filling in defaults
+func CrawlSettingsFromConfig() CrawlSettings {
+	cfg := config.Global.Social.Crawl
+	c := CrawlSettings{
+		Workers:  cfg.Workers,
+		Cooldown: time.Duration(cfg.CooldownMinutes) * time.Minute,
+		MaxQueue: cfg.MaxQueue,
+		Timeout:  config.Timeout(config.Global.Timeouts.Crawl, 10*time.Minute),
+	}
+	if c.Workers <= 0 {
+		c.Workers = defaultCrawlWorkers
+	}
+	if cfg.CooldownMinutes == 0 {
+		c.Cooldown = defaultCrawlCooldown
+	} else if c.Cooldown < 0 {
+		c.Cooldown = 0
+	}
+	if c.MaxQueue <= 0 {
+		c.MaxQueue = defaultCrawlQueue
+	}
+	return c
+}
+
+// CrawlJob is one crawl: what it is about and what to search for
+type CrawlJob struct {
+	Topic  string // Name of what the posts are about; crawls are deduplicated by it
+	Query  string // Search terms (Topic when empty)
+	NodeID string // Node the posts count toward (derived from Topic when empty)
+}
+
+func (j CrawlJob) query() string {
+	if j.Query != "" {
+		return j.Query
+	}
+	return j.Topic
+}
+
+// nodeID is the node the crawl's sentiment and mentions are recorded on
+func (j CrawlJob) nodeID() string {
+	if j.NodeID != "" {
+		return j.NodeID
+	}
+	return strings.ToLower(strings.ReplaceAll(j.Topic, " ", "_"))
+}
+
+// crawlQueue holds the crawls waiting for a worker
+type crawlQueue struct {
+	mu       sync.Mutex
+	pending  []CrawlJob           // Crawls in arrival order
+	queued   map[string]bool      // Normalized topics pending or running
+	finished map[string]time.Time // When each normalized topic's last crawl ended
+	workers  int                  // Workers running
+}
+
+// Enqueue queues a crawl, reporting whether it was queued. It is not when
+// the same topic (ignoring case and punctuation) is already queued or
+// running, when it was crawled within the cooldown (unless force), or when
+// the queue is full.
+func (s *SocialMonitor) Enqueue(job CrawlJob, force bool) bool {
+	topic := job.Topic
+	key := normalizeTopic(topic)
+	if key == "" {
+		return false
+	}
+	settings := s.Crawl
+	q := &s.queue
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.queued == nil {
+		q.queued = make(map[string]bool)
+		q.finished = make(map[string]time.Time)
+	}
+	if q.queued[key] {
+		logger.InfoDepth(1, logger.StatusSoc, "Crawl of '%s' already queued", topic)
+		return false
+	}
+	if last, ok := q.finished[key]; ok && !force && time.Since(last) < settings.Cooldown {
+		logger.InfoDepth(1, logger.StatusSoc, "Crawl of '%s' skipped: crawled %s ago", topic, time.Since(last).Round(time.Second))
+		return false
+	}
+	if len(q.pending) >= settings.MaxQueue {
+		logger.Warn(logger.StatusWarn, "Crawl queue full (%d waiting); dropping '%s'", len(q.pending), topic)
+		return false
+	}
+	q.pending = append(q.pending, job)
+	q.queued[key] = true
+	// Forget topics whose cooldown is over, so the map stays small
+	cutoff := time.Now().Add(-settings.Cooldown)
+	for k, at := range q.finished {
+		if at.Before(cutoff) {
+			delete(q.finished, k)
+		}
+	}
+	if q.workers < settings.Workers {
+		q.workers++
+		go s.crawlWorker()
+	}
+	return true
+}
+
+// QueuedCrawls returns the crawls waiting for a worker, next first
+func (s *SocialMonitor) QueuedCrawls() []CrawlJob {
+	s.queue.mu.Lock()
+	defer s.queue.mu.Unlock()
+	return append([]CrawlJob(nil), s.queue.pending...)
+}
+
+// crawlWorker runs queued crawls, one at a time, until the queue is empty
+func (s *SocialMonitor) crawlWorker() {
+	q := &s.queue
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.workers--
+			q.mu.Unlock()
+			return
+		}
+		job := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		done := make(chan struct{})
+		task.StartTimeout("social: "+job.Topic, s.Crawl.Timeout, func(ctx context.Context, t *task.Task) error {
+			defer close(done)
+			s.CrawlReal(ctx, job)
+			return nil
+		})
+		<-done
+
+		key := normalizeTopic(job.Topic)
+		q.mu.Lock()
+		delete(q.queued, key)
+		q.finished[key] = time.Now()
+		q.mu.Unlock()
+	}
+}
+
+// normalizeTopic is the key crawls are deduplicated by: lowercase words
+// without punctuation, so "Chip Export Ban!" and "chip export ban" match
+func normalizeTopic(topic string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(topic), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
@@ -0,0 +1,161 @@
+package social
+
+import (
+	"fmt"
+	"margraf/config"
+	"margraf/graph"
+	"margraf/logger"
+	"margraf/scraper"
+	"margraf/server"
+	"math"
+	"sort"
+	"time"
+)
+
+// Unusual chatter is often the first sign of a developing disruption, well
+// before the sentiment of that chatter is known. Every crawled post counts as
+// a mention of the crawl's topic and of each node it names, in the hour it
+// was posted; a node whose mentions this hour stand far above its hourly
+// baseline raises a mention_spike.
+
+// Defaults for social.volume
+const (
+	defaultBaselineHours = 7 * 24
+	defaultSpikeSigma    = 3.0
+	defaultMinMentions   = 5
+	minBaselineHours     = 24 // History needed before a spike can be called
+)
+
+// VolumeSettings are the thresholds of mention spike detection
+type VolumeSettings struct {
+	Baseline    time.Duration // History a spike is measured against
+	Threshold   float64       // Standard deviations above the baseline mean
+	MinMentions int           // Fewest mentions in an hour that can be a spike
+}
+
+// VolumeSettingsFromConfig reads social.volume, filling in defaults
+func VolumeSettingsFromConfig() VolumeSettings {
+	cfg := config.Global.Social.Volume
+	v := VolumeSettings{
+		Baseline:    time.Duration(cfg.BaselineHours) * time.Hour,
+		Threshold:   cfg.Threshold,
+		MinMentions: cfg.MinMentions,
+	}
+	if v.Baseline <= 0 {
+		v.Baseline = defaultBaselineHours * time.Hour
+	}
+	if v.Threshold <= 0 {
+		v.Threshold = defaultSpikeSigma
+	}
+	if v.MinMentions <= 0 {
+		v.MinMentions = defaultMinMentions
+	}
+	return v
+}
+
+// isSpike reports whether a node's latest hour stands out from its baseline.
+// Quiet nodes have a spread of at least the square root of their mean, as
+// counts of rare events do, so a handful of extra posts is not a spike.
+func (v VolumeSettings) isSpike(m graph.MentionVolume) bool {
+	if m.Current < v.MinMentions || m.Hours < minBaselineHours {
+		return false
+	}
+	spread := math.Max(m.StdDev, math.Sqrt(m.Mean))
+	return float64(m.Current) > m.Mean+v.Threshold*spread
+}
+
+// trackVolume counts posts as mentions of the topic's node and of the nodes
+// each names, skipping posts already counted, then checks the nodes touched
+// for spikes. Nodes named in the same post are also passed on as
+// co-mentions.
+func (s *SocialMonitor) trackVolume(job CrawlJob, posts []scraper.SocialPost) {
+	now := time.Now()
+	settings := s.Volume
+	topic, topicID := job.Topic, job.nodeID()
+
+	s.volumeMu.Lock()
+	if s.mentioned == nil {
+		s.mentioned = make(map[string]time.Time)
+		s.spiked = make(map[string]time.Time)
+	}
+	for key, at := range s.mentioned {
+		if now.Sub(at) > settings.Baseline {
+			delete(s.mentioned, key)
+		}
+	}
+	var fresh []scraper.SocialPost
+	for _, p := range posts {
+		key := p.URL
+		if key == "" {
+			key = p.Platform + "|" + p.User + "|" + p.Content
+		}
+		if _, seen := s.mentioned[key]; !seen {
+			s.mentioned[key] = now
+			fresh = append(fresh, p)
+		}
+	}
+	s.volumeMu.Unlock()
+
+	touched := make(map[string]bool)
+	for _, p := range fresh {
+		at := p.Time
+		if at.IsZero() || at.After(now) {
+			at = now
+		}
+		ids := map[string]bool{topicID: true}
+		if s.Extractor != nil && s.Extractor.Local != nil {
+			for _, e := range s.Extractor.Local.Entities(p.Content) {
+				if e.NodeID != "" {
+					ids[e.NodeID] = true
+				}
+			}
+		}
+		named := make([]string, 0, len(ids))
+		for id := range ids {
+			if s.Graph.RecordMentions(id, at, 1) == nil {
+				touched[id] = true
+				named = append(named, id)
+			}
+		}
+		if s.CoMentions != nil && len(named) > 1 {
+			sort.Strings(named)
+			s.CoMentions.Observe(named, graph.CoMentionItem{Time: at, Source: graph.SentimentSocial, Text: p.Content, URL: p.URL})
+		}
+	}
+
+	for id := range touched {
+		m := s.Graph.MentionVolume(id, now, settings.Baseline)
+		if !settings.isSpike(m) || !s.claimSpike(id, now) {
+			continue
+		}
+		name := id
+		if node, ok := s.Graph.GetNode(id); ok {
+			name = node.Name
+		}
+		ratio := float64(m.Current) / math.Max(m.Mean, 1.0/float64(m.Hours))
+		logger.Warn(logger.StatusTrend, "Mention spike: %s has %d mentions this hour against a baseline of %.1f/hour (%.0fx)", name, m.Current, m.Mean, ratio)
+		s.Hub.Broadcast(server.TypeMentionSpike, server.MentionSpikePayload{
+			NodeID:   id,
+			Name:     name,
+			Mentions: m.Current,
+			Baseline: m.Mean,
+			StdDev:   m.StdDev,
+			Ratio:    ratio,
+			Topic:    topic,
+			Message:  fmt.Sprintf("%d mentions this hour, %.0fx the usual %.1f", m.Current, ratio, m.Mean),
+		})
+	}
+}
+
+// claimSpike reports whether id has not raised a spike this hour yet, and
+// marks it as having done so
+func (s *SocialMonitor) claimSpike(id string, now time.Time) bool {
+	s.volumeMu.Lock()
+	defer s.volumeMu.Unlock()
+	hour := now.Truncate(time.Hour)
+	if last, ok := s.spiked[id]; ok && last.Equal(hour) {
+		return false
+	}
+	s.spiked[id] = hour
+	return true
+}
@@ -0,0 +1,170 @@
+// Package syserr defines Margraf's typed subsystem errors and reports them as
+// "system_error" events, so dashboards and the TUI can show which parts of
+// the system are failing instead of leaving failures in the log.
+package syserr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"margraf/clock"
+	"margraf/retry"
+	"sync"
+	"time"
+)
+
+// Module is the subsystem an error came from
+type Module string
+
+const (
+	ModuleLLM        Module = "llm"        // LLM providers
+	ModuleDiscovery  Module = "discovery"  // Seeding and nation expansion
+	ModuleNews       Module = "news"       // RSS polling and headline analysis
+	ModuleSocial     Module = "social"     // Social crawls
+	ModuleMarket     Module = "market"     // Stock prices and fundamentals
+	ModuleDataSource Module = "datasource" // World Bank, Comtrade, Wikidata and custom connectors
+	ModuleBus        Module = "bus"        // Multi-instance pub/sub
+	ModuleStorage    Module = "storage"    // Saving and loading the graph
+)
+
+// Severity ranks how much an error affects the system
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"  // Degraded; work continues
+	SeverityError    Severity = "error"    // An operation failed
+	SeverityCritical Severity = "critical" // A subsystem is unusable until fixed
+)
+
+// maxRecent bounds how many events Recent keeps
+const maxRecent = 50
+
+// Error is a failure attributed to a subsystem
+type Error struct {
+	Module    Module
+	Op        string // What was being done, e.g. "seed" or "fetch feed"
+	Severity  Severity
+	Retryable bool // Trying again later may succeed
+	Err       error
+}
+
+// New wraps err as an error-severity failure of module. Retryable follows the
+// shared retry classification.
+func New(module Module, op string, err error) *Error {
+	return &Error{
+		Module:    module,
+		Op:        op,
+		Severity:  SeverityError,
+		Retryable: retry.Classify(err) == retry.Retry,
+		Err:       err,
+	}
+}
+
+// Warning is New with warning severity, for degraded but working subsystems
+func Warning(module Module, op string, err error) *Error {
+	e := New(module, op, err)
+	e.Severity = SeverityWarning
+	return e
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Module, e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Event is the "system_error" broadcast payload
+type Event struct {
+	Module    Module    `json:"module"`
+	Op        string    `json:"op"`
+	Severity  Severity  `json:"severity"`
+	Message   string    `json:"message"`
+	Retryable bool      `json:"retryable"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+var (
+	mu      sync.Mutex
+	recent  []Event
+	onEvent func(Event)
+	redact  func(string) string
+)
+
+// SetHook registers fn to receive every reported event. fn must not block.
+func SetHook(fn func(Event)) {
+	mu.Lock()
+	defer mu.Unlock()
+	onEvent = fn
+}
+
+// SetRedactor registers fn to scrub credentials from event messages before
+// they are kept or passed to the hook. Transport errors quote whole URLs, so
+// a message can carry whatever secret a request held.
+func SetRedactor(fn func(string) string) {
+	mu.Lock()
+	defer mu.Unlock()
+	redact = fn
+}
+
+// Report records a failure and passes it to the hook. A typed *Error keeps its
+// own module, op and severity; other errors are attributed to module and op.
+// Cancellations are not failures and are ignored.
+func Report(module Module, op string, err error) {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	var typed *Error
+	if !errors.As(err, &typed) {
+		typed = New(module, op, err)
+	}
+
+	event := Event{
+		Module:    typed.Module,
+		Op:        typed.Op,
+		Severity:  typed.Severity,
+		Message:   typed.Err.Error(),
+		Retryable: typed.Retryable,
+		Timestamp: clock.Now(),
+	}
+
+	mu.Lock()
+	if redact != nil {
+		event.Message = redact(event.Message)
+	}
+	recent = append(recent, event)
+	if excess := len(recent) - maxRecent; excess > 0 {
+		recent = append(recent[:0:0], recent[excess:]...)
+	}
+	fn := onEvent
+	mu.Unlock()
+
+	if fn != nil {
+		fn(event)
+	}
+}
+
+// Recent returns the latest events, newest first
+func Recent() []Event {
+	mu.Lock()
+	defer mu.Unlock()
+	events := make([]Event, len(recent))
+	for i, e := range recent {
+		events[len(recent)-1-i] = e
+	}
+	return events
+}
+
+// Health returns each module's most recent event within window, newest first
+func Health(window time.Duration) []Event {
+	since := time.Now().Add(-window)
+	seen := make(map[Module]bool)
+	var latest []Event
+	for _, e := range Recent() {
+		if e.Timestamp.Before(since) || seen[e.Module] {
+			continue
+		}
+		seen[e.Module] = true
+		latest = append(latest, e)
+	}
+	return latest
+}
@@ -0,0 +1,266 @@
+// Package task tracks long-running background operations (seeding, social
+// crawls, data refreshes) so they can report progress and be cancelled.
+package task
+
+import (
+	"context"
+	"fmt"
+	"margraf/clock"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is a task's lifecycle stage
+type State string
+
+const (
+	StateRunning   State = "running"
+	StateDone      State = "done"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// maxFinished bounds how many finished tasks are kept for listing
+const maxFinished = 20
+
+// Info is a snapshot of a task, safe to serialize
+type Info struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	State    State     `json:"state"`
+	Done     int       `json:"done"`
+	Total    int       `json:"total"` // 0 = unknown
+	Message  string    `json:"message,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitzero"`
+}
+
+// Progress returns the completed fraction, or -1 if the total is unknown
+func (i Info) Progress() float64 {
+	if i.Total <= 0 {
+		return -1
+	}
+	return float64(i.Done) / float64(i.Total)
+}
+
+// Task is a running operation
+type Task struct {
+	mu     sync.Mutex
+	info   Info
+	cancel context.CancelFunc
+}
+
+// Info returns a snapshot of the task
+func (t *Task) Info() Info {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.info
+}
+
+// Progress records done of total steps, with an optional status message
+func (t *Task) Progress(done, total int, message string) {
+	t.mu.Lock()
+	t.info.Done = done
+	t.info.Total = total
+	if message != "" {
+		t.info.Message = message
+	}
+	info := t.info
+	t.mu.Unlock()
+	notify(info)
+}
+
+// Step advances the task by one step, with an optional status message
+func (t *Task) Step(message string) {
+	t.mu.Lock()
+	t.info.Done++
+	if message != "" {
+		t.info.Message = message
+	}
+	info := t.info
+	t.mu.Unlock()
+	notify(info)
+}
+
+// AddTotal grows the task's expected number of steps (e.g. as recursion discovers more work)
+func (t *Task) AddTotal(n int) {
+	t.mu.Lock()
+	t.info.Total += n
+	info := t.info
+	t.mu.Unlock()
+	notify(info)
+}
+
+var (
+	mu      sync.Mutex
+	tasks   = make(map[string]*Task)
+	nextID  int
+	onEvent func(Info)
+)
+
+// SetHook registers fn to receive every task change (start, progress, finish).
+// fn is called from the task's goroutine and must not block.
+func SetHook(fn func(Info)) {
+	mu.Lock()
+	defer mu.Unlock()
+	onEvent = fn
+}
+
+func notify(info Info) {
+	mu.Lock()
+	fn := onEvent
+	mu.Unlock()
+	if fn != nil {
+		fn(info)
+	}
+}
+
+type contextKey struct{}
+
+// FromContext returns the task running under ctx, or nil
+func FromContext(ctx context.Context) *Task {
+	t, _ := ctx.Value(contextKey{}).(*Task)
+	return t
+}
+
+// Report records progress on the task running under ctx, if any
+func Report(ctx context.Context, done, total int, message string) {
+	if t := FromContext(ctx); t != nil {
+		t.Progress(done, total, message)
+	}
+}
+
+// Start runs fn in a new goroutine as a named, cancellable task. fn should
+// return ctx.Err() promptly once ctx is cancelled.
+func Start(name string, fn func(ctx context.Context, t *Task) error) *Task {
+	return StartTimeout(name, 0, fn)
+}
+
+// StartTimeout is Start with a deadline. A task that runs past timeout is
+// cancelled and marked failed. A timeout of 0 means no deadline.
+func StartTimeout(name string, timeout time.Duration, fn func(ctx context.Context, t *Task) error) *Task {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	mu.Lock()
+	nextID++
+	t := &Task{
+		info:   Info{ID: fmt.Sprintf("t%d", nextID), Name: name, State: StateRunning, Started: clock.Now()},
+		cancel: cancel,
+	}
+	tasks[t.info.ID] = t
+	mu.Unlock()
+	notify(t.Info())
+
+	go func() {
+		defer cancel()
+		err := fn(context.WithValue(ctx, contextKey{}, t), t)
+
+		t.mu.Lock()
+		t.info.Finished = clock.Now()
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			t.info.State = StateFailed
+			t.info.Error = fmt.Sprintf("timed out after %v", timeout)
+		case ctx.Err() != nil:
+			t.info.State = StateCancelled
+		case err != nil:
+			t.info.State = StateFailed
+			t.info.Error = err.Error()
+		default:
+			t.info.State = StateDone
+			if t.info.Total > 0 {
+				t.info.Done = t.info.Total
+			}
+		}
+		info := t.info
+		t.mu.Unlock()
+
+		prune()
+		notify(info)
+	}()
+	return t
+}
+
+// Cancel stops a running task
+func Cancel(id string) error {
+	mu.Lock()
+	t, ok := tasks[id]
+	mu.Unlock()
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	if info := t.Info(); info.State != StateRunning {
+		return fmt.Errorf("task %s is already %s", id, info.State)
+	}
+	t.cancel()
+	return nil
+}
+
+// CancelAll stops every running task (e.g. on shutdown)
+func CancelAll() {
+	mu.Lock()
+	all := make([]*Task, 0, len(tasks))
+	for _, t := range tasks {
+		all = append(all, t)
+	}
+	mu.Unlock()
+
+	for _, t := range all {
+		if t.Info().State == StateRunning {
+			t.cancel()
+		}
+	}
+}
+
+// List returns running tasks followed by recently finished ones, newest first
+func List() []Info {
+	mu.Lock()
+	all := make([]*Task, 0, len(tasks))
+	for _, t := range tasks {
+		all = append(all, t)
+	}
+	mu.Unlock()
+
+	infos := make([]Info, 0, len(all))
+	for _, t := range all {
+		infos = append(infos, t.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		ri, rj := infos[i].State == StateRunning, infos[j].State == StateRunning
+		if ri != rj {
+			return ri
+		}
+		return infos[i].Started.After(infos[j].Started)
+	})
+	return infos
+}
+
+// prune drops the oldest finished tasks beyond maxFinished
+func prune() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var finished []*Task
+	for _, t := range tasks {
+		if t.Info().State != StateRunning {
+			finished = append(finished, t)
+		}
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].Info().Finished.Before(finished[j].Info().Finished)
+	})
+	for _, t := range finished[:len(finished)-maxFinished] {
+		delete(tasks, t.Info().ID)
+	}
+}
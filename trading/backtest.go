@@ -3,6 +3,7 @@ package trading
 import (
 	"fmt"
 	"math"
+	"sort"
 	"time"
 )
 
@@ -13,13 +14,26 @@ type Trade struct {
 	Asset1     string
 	Asset2     string
 	Direction  string
-	EntryPrice1 float64
+	Quantity    float64 // Shares of each leg closed
+	Lots        int     // Lots the position was built from
+	EntryPrice1 float64 // Average over the lots
 	EntryPrice2 float64
 	ExitPrice1  float64
 	ExitPrice2  float64
 	PnL         float64
 	PnLPercent  float64
 	Duration    time.Duration
+	ExitReason  string // One of the Exit constants
+
+	// Execution diagnostics
+	EntryZScore     float64
+	ExitZScore      float64       // The last z-score seen for end_of_data
+	Reverted        bool          // |z| came within Backtester.ReversionBand while held
+	TimeToReversion time.Duration // From entry until it did
+	MAE             float64       // Max adverse excursion: worst unrealized PnL while held (<= 0)
+	MFE             float64       // Max favorable excursion: best unrealized PnL (>= 0)
+	MAEPercent      float64       // Of the entry notional
+	MFEPercent      float64
 }
 
 // BacktestResult contains the results of a backtest
@@ -52,6 +66,9 @@ type BacktestResult struct {
 
 	// Equity curve
 	EquityCurve    []EquityPoint
+
+	// Entry and exit z-scores, reversion times, MAE/MFE and their distributions
+	Execution ExecutionStats
 }
 
 // EquityPoint represents a point in the equity curve
@@ -66,6 +83,7 @@ type Backtester struct {
 	InitialCapital float64
 	PositionSize   float64 // Size per trade (e.g., $10,000)
 	Commission     float64 // Commission per trade (e.g., 0.001 for 0.1%)
+	ReversionBand  float64 // |z| counted as back at the mean in the diagnostics (0 = 0.5)
 }
 
 // NewBacktester creates a new backtester
@@ -77,29 +95,49 @@ func NewBacktester(initialCapital, positionSize, commission float64) *Backtester
 	}
 }
 
-// RunBacktest runs a backtest on a pairs trading strategy
-func (b *Backtester) RunBacktest(strategy *PairsTradingStrategy, prices1, prices2 []PricePoint) (*BacktestResult, error) {
-	if len(prices1) != len(prices2) {
+// RunBacktest runs a backtest on a strategy. prices2 is ignored for a
+// single-asset strategy and may be nil.
+func (b *Backtester) RunBacktest(strategy Strategy, prices1, prices2 []PricePoint) (*BacktestResult, error) {
+	ticker1, ticker2 := strategy.Tickers()
+	single := ticker2 == ""
+	if !single && len(prices1) != len(prices2) {
 		return nil, fmt.Errorf("price series must have same length")
 	}
 
-	if len(prices1) < strategy.LookbackWindow {
-		return nil, fmt.Errorf("insufficient data: need at least %d points", strategy.LookbackWindow)
+	if len(prices1) < strategy.Warmup() || len(prices1) == 0 {
+		return nil, fmt.Errorf("insufficient data: need at least %d points", max(strategy.Warmup(), 1))
+	}
+
+	pair := CorrelationPair{Asset1: ticker1, Asset2: ticker2, Ticker1: ticker1, Ticker2: ticker2}
+	if pairs, ok := strategy.(*PairsTradingStrategy); ok {
+		pair = pairs.Pair
+	}
+	price2At := func(i int) float64 {
+		if single {
+			return 0
+		}
+		return prices2[i].Price
+	}
+	state := strategy.State()
+	track := tradeTracker{band: b.ReversionBand}
+	if track.band <= 0 {
+		track.band = defaultReversionBand
 	}
+	track.zscorer, _ = strategy.(ZScorer)
 
 	// Initialize result
 	result := &BacktestResult{
-		Strategy:       "Pairs Trading",
-		Pair:           strategy.Pair,
+		Strategy:       strategy.Name(),
+		Pair:           pair,
 		InitialCapital: b.InitialCapital,
-		StartDate:      time.Unix(prices1[0].Timestamp, 0),
-		EndDate:        time.Unix(prices1[len(prices1)-1].Timestamp, 0),
+		StartDate:      time.Unix(prices1[0].Timestamp, 0).UTC(),
+		EndDate:        time.Unix(prices1[len(prices1)-1].Timestamp, 0).UTC(),
 		Trades:         []Trade{},
 		EquityCurve:    []EquityPoint{},
 	}
 
 	// Reset strategy state
-	strategy.Reset()
+	state.Reset()
 
 	// Current capital
 	capital := b.InitialCapital
@@ -116,13 +154,16 @@ func (b *Backtester) RunBacktest(strategy *PairsTradingStrategy, prices1, prices
 	for i := 0; i < len(prices1); i++ {
 		timestamp := prices1[i].Timestamp
 		price1 := prices1[i].Price
-		price2 := prices2[i].Price
+		price2 := price2At(i)
 
 		// Update strategy with new prices
-		strategy.UpdatePrices(timestamp, price1, price2)
+		strategy.Update(timestamp, price1, price2)
+		if state.HasOpenPosition() {
+			track.observe(state.GetCurrentPosition(), timestamp, price1, price2)
+		}
 
 		// Generate signal
-		signal, err := strategy.GenerateSignal(timestamp)
+		signal, err := strategy.Signal(timestamp)
 		if err != nil {
 			// Not enough data yet, continue
 			continue
@@ -130,8 +171,8 @@ func (b *Backtester) RunBacktest(strategy *PairsTradingStrategy, prices1, prices
 
 		if signal == nil {
 			// No signal, but update equity if we have a position
-			if strategy.HasOpenPosition() {
-				pnl := strategy.CalculatePnL(price1, price2)
+			if state.HasOpenPosition() {
+				pnl := state.CalculatePnL(price1, price2)
 				currentEquity := capital + pnl
 
 				drawdown := 0.0
@@ -149,44 +190,44 @@ func (b *Backtester) RunBacktest(strategy *PairsTradingStrategy, prices1, prices
 		}
 
 		// Execute signal
-		if signal.Action == "CLOSE" && strategy.HasOpenPosition() {
-			// Close position
-			pos := strategy.GetCurrentPosition()
-			pnl := strategy.CalculatePnL(price1, price2)
-
-			// Apply commission (both entry and exit)
-			commissionCost := b.Commission * (pos.EntryPrice1 + pos.EntryPrice2 + price1 + price2) * pos.Quantity
-			pnl -= commissionCost
-
-			// Update capital
-			capital += pnl
-
-			// Record trade
-			trade := Trade{
-				EntryTime:   pos.EntryTimestamp,
-				ExitTime:    timestamp,
-				Asset1:      pos.Asset1,
-				Asset2:      pos.Asset2,
-				Direction:   pos.Direction,
-				EntryPrice1: pos.EntryPrice1,
-				EntryPrice2: pos.EntryPrice2,
-				ExitPrice1:  price1,
-				ExitPrice2:  price2,
-				PnL:         pnl,
-				PnLPercent:  pnl / (pos.EntryPrice1 + pos.EntryPrice2) * 100,
-				Duration:    time.Unix(timestamp, 0).Sub(time.Unix(pos.EntryTimestamp, 0)),
-			}
+		if signal.Action == ActionClose && state.HasOpenPosition() {
+			// Close position, applying commission (both entry and exit)
+			pos := state.GetCurrentPosition()
+			trade := b.realize(pos, pos.Quantity, timestamp, price1, price2, signal.Reason)
+			track.annotate(&trade, pos, signal.ZScore)
+			capital += trade.PnL
 			result.Trades = append(result.Trades, trade)
 
 			// Execute close
-			strategy.ExecuteSignal(signal, b.PositionSize)
+			strategy.Execute(signal, 0)
 
 			// Update max capital
 			if capital > maxCapital {
 				maxCapital = capital
 			}
 
-		} else if (signal.Action == "LONG_1_SHORT_2" || signal.Action == "LONG_2_SHORT_1") && !strategy.HasOpenPosition() {
+		} else if signal.Action == ActionScaleOut && state.HasOpenPosition() {
+			// Take part of the position off, booked as a trade of its own
+			pos := state.GetCurrentPosition()
+			trade := b.realize(pos, pos.Part(signal.Fraction), timestamp, price1, price2, signal.Reason)
+			track.annotate(&trade, pos, signal.ZScore)
+			capital += trade.PnL
+			result.Trades = append(result.Trades, trade)
+			strategy.Execute(signal, 0)
+
+			if capital > maxCapital {
+				maxCapital = capital
+			}
+
+		} else if signal.Action == ActionScaleIn && state.HasOpenPosition() {
+			// Add a lot of the same size as an entry
+			if capital < b.PositionSize {
+				continue
+			}
+			quantity := b.PositionSize / entryCost(state.GetCurrentPosition().Direction, signal.Price1, signal.Price2)
+			strategy.Execute(signal, quantity)
+
+		} else if IsEntry(signal.Action) && !state.HasOpenPosition() {
 			// Open new position
 			// Check if we have enough capital
 			if capital < b.PositionSize {
@@ -194,14 +235,15 @@ func (b *Backtester) RunBacktest(strategy *PairsTradingStrategy, prices1, prices
 			}
 
 			// Calculate position quantity
-			quantity := b.PositionSize / (signal.Price1 + signal.Price2)
-			strategy.ExecuteSignal(signal, quantity)
+			quantity := b.PositionSize / entryCost(signal.Action, signal.Price1, signal.Price2)
+			strategy.Execute(signal, quantity)
+			track.start(timestamp)
 		}
 
 		// Record equity point
 		currentEquity := capital
-		if strategy.HasOpenPosition() {
-			currentEquity += strategy.CalculatePnL(price1, price2)
+		if state.HasOpenPosition() {
+			currentEquity += state.CalculatePnL(price1, price2)
 		}
 
 		drawdown := 0.0
@@ -217,31 +259,15 @@ func (b *Backtester) RunBacktest(strategy *PairsTradingStrategy, prices1, prices
 	}
 
 	// Close any remaining position
-	if strategy.HasOpenPosition() {
+	if state.HasOpenPosition() {
 		lastPrice1 := prices1[len(prices1)-1].Price
-		lastPrice2 := prices2[len(prices2)-1].Price
+		lastPrice2 := price2At(len(prices1) - 1)
 		lastTimestamp := prices1[len(prices1)-1].Timestamp
 
-		pos := strategy.GetCurrentPosition()
-		pnl := strategy.CalculatePnL(lastPrice1, lastPrice2)
-		commissionCost := b.Commission * (pos.EntryPrice1 + pos.EntryPrice2 + lastPrice1 + lastPrice2) * pos.Quantity
-		pnl -= commissionCost
-		capital += pnl
-
-		trade := Trade{
-			EntryTime:   pos.EntryTimestamp,
-			ExitTime:    lastTimestamp,
-			Asset1:      pos.Asset1,
-			Asset2:      pos.Asset2,
-			Direction:   pos.Direction,
-			EntryPrice1: pos.EntryPrice1,
-			EntryPrice2: pos.EntryPrice2,
-			ExitPrice1:  lastPrice1,
-			ExitPrice2:  lastPrice2,
-			PnL:         pnl,
-			PnLPercent:  pnl / (pos.EntryPrice1 + pos.EntryPrice2) * 100,
-			Duration:    time.Unix(lastTimestamp, 0).Sub(time.Unix(pos.EntryTimestamp, 0)),
-		}
+		pos := state.GetCurrentPosition()
+		trade := b.realize(pos, pos.Quantity, lastTimestamp, lastPrice1, lastPrice2, ExitEndOfData)
+		track.annotate(&trade, pos, math.NaN())
+		capital += trade.PnL
 		result.Trades = append(result.Trades, trade)
 	}
 
@@ -289,9 +315,38 @@ func (b *Backtester) RunBacktest(strategy *PairsTradingStrategy, prices1, prices
 	// Calculate Sharpe ratio
 	result.SharpeRatio = b.calculateSharpeRatio(result.EquityCurve)
 
+	result.computeExecution()
+
 	return result, nil
 }
 
+// realize books quantity shares per leg of pos closed at the given prices
+// as a trade, its PnL less commission on their entry and exit
+func (b *Backtester) realize(pos *Position, quantity float64, exitTime int64, price1, price2 float64, reason string) Trade {
+	pnl := positionPnL(pos.Direction, pos.EntryPrice1, pos.EntryPrice2, price1, price2, quantity)
+	pnl -= b.Commission * (pos.EntryPrice1 + pos.EntryPrice2 + price1 + price2) * quantity
+	trade := Trade{
+		EntryTime:   pos.EntryTimestamp,
+		ExitTime:    exitTime,
+		Asset1:      pos.Asset1,
+		Asset2:      pos.Asset2,
+		Direction:   pos.Direction,
+		Quantity:    quantity,
+		Lots:        len(pos.Lots),
+		EntryPrice1: pos.EntryPrice1,
+		EntryPrice2: pos.EntryPrice2,
+		ExitPrice1:  price1,
+		ExitPrice2:  price2,
+		PnL:         pnl,
+		Duration:    time.Unix(exitTime, 0).Sub(time.Unix(pos.EntryTimestamp, 0)),
+		ExitReason:  reason,
+	}
+	if notional := (pos.EntryPrice1 + pos.EntryPrice2) * quantity; notional > 0 {
+		trade.PnLPercent = pnl / notional * 100
+	}
+	return trade
+}
+
 // calculateMaxDrawdown calculates the maximum drawdown
 func (b *Backtester) calculateMaxDrawdown(equityCurve []EquityPoint) float64 {
 	if len(equityCurve) == 0 {
@@ -366,8 +421,12 @@ func (r *BacktestResult) PrintReport() {
 	fmt.Println(separator)
 
 	fmt.Printf("\nStrategy: %s\n", r.Strategy)
-	fmt.Printf("Pair: %s (%s) <-> %s (%s)\n", r.Pair.Asset1, r.Pair.Ticker1, r.Pair.Asset2, r.Pair.Ticker2)
-	fmt.Printf("Correlation: %.4f\n", r.Pair.Correlation)
+	if r.Pair.Ticker2 == "" {
+		fmt.Printf("Asset: %s (%s)\n", r.Pair.Asset1, r.Pair.Ticker1)
+	} else {
+		fmt.Printf("Pair: %s (%s) <-> %s (%s)\n", r.Pair.Asset1, r.Pair.Ticker1, r.Pair.Asset2, r.Pair.Ticker2)
+		fmt.Printf("Correlation: %.4f\n", r.Pair.Correlation)
+	}
 	fmt.Printf("Period: %s to %s\n", r.StartDate.Format("2006-01-02"), r.EndDate.Format("2006-01-02"))
 
 	fmt.Println("\n" + line)
@@ -392,7 +451,25 @@ func (r *BacktestResult) PrintReport() {
 	fmt.Printf("Average Loss:       $%.2f\n", r.AvgLoss)
 	fmt.Printf("Avg Trade Duration: %v\n", r.AvgTradeDuration.Round(time.Hour))
 
+	exits := make(map[string]int)
+	var reasons []string
+	for _, t := range r.Trades {
+		if exits[t.ExitReason] == 0 {
+			reasons = append(reasons, t.ExitReason)
+		}
+		exits[t.ExitReason]++
+	}
+	sort.Strings(reasons)
+	if len(reasons) > 0 {
+		fmt.Println("Exits by Reason:")
+	}
+	for _, reason := range reasons {
+		fmt.Printf("  %-17s %d\n", reason+":", exits[reason])
+	}
+
 	if len(r.Trades) > 0 {
+		r.printExecution(line)
+
 		fmt.Println("\n" + line)
 		fmt.Println("RECENT TRADES (Last 10)")
 		fmt.Println(line)
@@ -404,12 +481,17 @@ func (r *BacktestResult) PrintReport() {
 
 		for i := start; i < len(r.Trades); i++ {
 			t := r.Trades[i]
-			entryTime := time.Unix(t.EntryTime, 0).Format("2006-01-02")
-			exitTime := time.Unix(t.ExitTime, 0).Format("2006-01-02")
+			entryTime := time.Unix(t.EntryTime, 0).UTC().Format("2006-01-02")
+			exitTime := time.Unix(t.ExitTime, 0).UTC().Format("2006-01-02")
 
 			fmt.Printf("\nTrade #%d: %s\n", i+1, t.Direction)
 			fmt.Printf("  Entry: %s  Exit: %s  Duration: %v\n", entryTime, exitTime, t.Duration.Round(time.Hour*24))
-			fmt.Printf("  P&L: $%.2f (%.2f%%)\n", t.PnL, t.PnLPercent)
+			fmt.Printf("  P&L: $%.2f (%.2f%%)  Exit: %s\n", t.PnL, t.PnLPercent, t.ExitReason)
+			reverted := "never"
+			if t.Reverted {
+				reverted = t.TimeToReversion.Round(time.Hour).String()
+			}
+			fmt.Printf("  Z: %.2f -> %.2f  Reverted: %s  MAE: %.2f%%  MFE: %.2f%%\n", t.EntryZScore, t.ExitZScore, reverted, t.MAEPercent, t.MFEPercent)
 		}
 	}
 
@@ -26,7 +26,11 @@ type CorrelationPair struct {
 	Asset2         string
 	Ticker1        string
 	Ticker2        string
-	Correlation    float64
+	Correlation    float64 // Blended with the graph prior (the raw correlation without shrinkage)
+	RawCorrelation float64 // Sample correlation of the prices alone
+	GraphPrior     float64 // Correlation the graph suggests (see GraphPrior)
+	PriorWeight    float64 // Share of Correlation that came from the prior
+	Observations   int     // Aligned price points behind RawCorrelation
 	GraphDistance  int     // Distance in the knowledge graph
 	HasDirectEdge  bool    // Whether there's a direct edge between them
 	EdgeWeight     float64 // Weight of the edge if exists
@@ -35,6 +39,10 @@ type CorrelationPair struct {
 // CorrelationAnalyzer analyzes correlations between assets
 type CorrelationAnalyzer struct {
 	Graph *graph.Graph
+
+	// Shrinkage is how many price observations the graph prior is worth
+	// (see prior.go); 0 uses the sample correlation alone
+	Shrinkage float64
 }
 
 // NewCorrelationAnalyzer creates a new correlation analyzer
@@ -107,7 +115,8 @@ func alignTimeSeries(prices1, prices2 []PricePoint) ([]float64, []float64) {
 	return aligned1, aligned2
 }
 
-// FindCorrelatedPairs finds all correlated asset pairs
+// FindCorrelatedPairs finds all correlated asset pairs, ranked and filtered
+// by their correlation blended with the graph prior
 func (ca *CorrelationAnalyzer) FindCorrelatedPairs(priceHistories map[string]*AssetPriceHistory, minCorrelation float64) ([]CorrelationPair, error) {
 	var pairs []CorrelationPair
 
@@ -127,26 +136,38 @@ func (ca *CorrelationAnalyzer) FindCorrelatedPairs(priceHistories map[string]*As
 			hist2 := priceHistories[asset2]
 
 			// Calculate statistical correlation
-			corr, err := CalculateCorrelation(hist1.Prices, hist2.Prices)
+			raw, err := CalculateCorrelation(hist1.Prices, hist2.Prices)
 			if err != nil {
 				// Skip pairs with insufficient data
 				continue
 			}
 
+			// Shrink it towards what the graph suggests
+			aligned, _ := alignTimeSeries(hist1.Prices, hist2.Prices)
+			var prior float64
+			if ca.Shrinkage > 0 {
+				prior = ca.GraphPrior(asset1, asset2)
+			}
+			corr, priorWeight := blendCorrelation(raw, prior, len(aligned), ca.Shrinkage)
+
 			// Only include pairs meeting minimum correlation threshold
 			if math.Abs(corr) >= minCorrelation {
 				// Get graph structure information
 				distance, hasEdge, weight := ca.getGraphRelationship(asset1, asset2)
 
 				pair := CorrelationPair{
-					Asset1:        asset1,
-					Asset2:        asset2,
-					Ticker1:       hist1.Ticker,
-					Ticker2:       hist2.Ticker,
-					Correlation:   corr,
-					GraphDistance: distance,
-					HasDirectEdge: hasEdge,
-					EdgeWeight:    weight,
+					Asset1:         asset1,
+					Asset2:         asset2,
+					Ticker1:        hist1.Ticker,
+					Ticker2:        hist2.Ticker,
+					Correlation:    corr,
+					RawCorrelation: raw,
+					GraphPrior:     prior,
+					PriorWeight:    priorWeight,
+					Observations:   len(aligned),
+					GraphDistance:  distance,
+					HasDirectEdge:  hasEdge,
+					EdgeWeight:     weight,
 				}
 				pairs = append(pairs, pair)
 			}
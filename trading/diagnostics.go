@@ -0,0 +1,227 @@
+package trading
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Top-line return hides how a strategy trades. These diagnostics follow
+// each position while it is held: the z-scores it entered and left at, how
+// long the spread took to come back, and how far it went against (MAE) and
+// for (MFE) the position on the way, so thresholds and stops are tuned on
+// how trades behave.
+
+// defaultReversionBand is the |z| counted as back at the mean when the
+// backtester sets none
+const defaultReversionBand = 0.5
+
+// histogramBins is the number of bins per distribution
+const histogramBins = 10
+
+// ZScorer is a strategy that trades on a z-score. Backtests follow it while
+// a position is held, to time the reversion to the mean.
+type ZScorer interface {
+	CalculateZScore() (float64, error)
+}
+
+// HistogramBin counts the values in [From, To); the last bin includes To
+type HistogramBin struct {
+	From  float64
+	To    float64
+	Count int
+}
+
+// ExecutionStats summarizes the trades' diagnostics
+type ExecutionStats struct {
+	AvgEntryZScore     float64 // Mean |z| at entry
+	AvgExitZScore      float64 // Mean |z| at exit
+	ReversionRate      float64 // Percentage of trades whose z-score reached the reversion band
+	AvgTimeToReversion time.Duration
+	AvgMAEPercent      float64 // Mean adverse excursion, as a percentage of entry notional
+	AvgMFEPercent      float64 // Mean favorable excursion
+	Capture            float64 // Realized PnL over MFE summed over winners, in percent: how much of the best move was kept
+
+	// Distributions over the trades
+	PnLPercent  []HistogramBin
+	HoldingDays []HistogramBin
+	MAEPercent  []HistogramBin
+	MFEPercent  []HistogramBin
+	EntryZScore []HistogramBin // |z|
+}
+
+// tradeTracker follows the open position between entry and exit
+type tradeTracker struct {
+	entry      int64
+	band       float64
+	zscorer    ZScorer // Nil for strategies without a z-score
+	lastZ      float64
+	revertedAt int64   // 0 = not yet
+	minUnit    float64 // Worst and best PnL per share of each leg
+	maxUnit    float64
+	minPct     float64 // The same, as a percentage of entry notional
+	maxPct     float64
+}
+
+// start resets the tracker for a position opened at timestamp
+func (t *tradeTracker) start(timestamp int64) {
+	*t = tradeTracker{entry: timestamp, band: t.band, zscorer: t.zscorer}
+}
+
+// observe records the prices at timestamp against the open position
+func (t *tradeTracker) observe(pos *Position, timestamp int64, price1, price2 float64) {
+	if t.zscorer != nil {
+		if z, err := t.zscorer.CalculateZScore(); err == nil {
+			t.lastZ = z
+			if t.revertedAt == 0 && math.Abs(z) <= t.band {
+				t.revertedAt = timestamp
+			}
+		}
+	}
+	unit := positionPnL(pos.Direction, pos.EntryPrice1, pos.EntryPrice2, price1, price2, 1)
+	t.minUnit, t.maxUnit = math.Min(t.minUnit, unit), math.Max(t.maxUnit, unit)
+	if notional := pos.EntryPrice1 + pos.EntryPrice2; notional > 0 {
+		t.minPct = math.Min(t.minPct, unit/notional*100)
+		t.maxPct = math.Max(t.maxPct, unit/notional*100)
+	}
+}
+
+// annotate fills a trade of pos's diagnostics in. exitZ is the signal's
+// z-score, or NaN to use the last one observed.
+func (t *tradeTracker) annotate(trade *Trade, pos *Position, exitZ float64) {
+	trade.EntryZScore = pos.EntryZScore
+	trade.ExitZScore = exitZ
+	if math.IsNaN(exitZ) {
+		trade.ExitZScore = t.lastZ
+	}
+	trade.MAE = t.minUnit * trade.Quantity
+	trade.MFE = t.maxUnit * trade.Quantity
+	trade.MAEPercent, trade.MFEPercent = t.minPct, t.maxPct
+	if t.revertedAt != 0 {
+		trade.Reverted = true
+		trade.TimeToReversion = time.Unix(t.revertedAt, 0).Sub(time.Unix(t.entry, 0))
+	}
+}
+
+// computeExecution summarizes the trades' diagnostics
+func (r *BacktestResult) computeExecution() {
+	n := len(r.Trades)
+	if n == 0 {
+		return
+	}
+	e := &r.Execution
+	var pnl, days, mae, mfe, entryZ []float64
+	var reverted int
+	var reversion time.Duration
+	var kept, best float64
+	for _, t := range r.Trades {
+		e.AvgEntryZScore += math.Abs(t.EntryZScore)
+		e.AvgExitZScore += math.Abs(t.ExitZScore)
+		e.AvgMAEPercent += t.MAEPercent
+		e.AvgMFEPercent += t.MFEPercent
+		if t.Reverted {
+			reverted++
+			reversion += t.TimeToReversion
+		}
+		if t.PnL > 0 && t.MFE > 0 {
+			kept += t.PnL
+			best += t.MFE
+		}
+		pnl = append(pnl, t.PnLPercent)
+		days = append(days, t.Duration.Hours()/24)
+		mae = append(mae, t.MAEPercent)
+		mfe = append(mfe, t.MFEPercent)
+		entryZ = append(entryZ, math.Abs(t.EntryZScore))
+	}
+	e.AvgEntryZScore /= float64(n)
+	e.AvgExitZScore /= float64(n)
+	e.AvgMAEPercent /= float64(n)
+	e.AvgMFEPercent /= float64(n)
+	e.ReversionRate = float64(reverted) / float64(n) * 100
+	if reverted > 0 {
+		e.AvgTimeToReversion = reversion / time.Duration(reverted)
+	}
+	if best > 0 {
+		e.Capture = kept / best * 100
+	}
+	e.PnLPercent = histogram(pnl, histogramBins)
+	e.HoldingDays = histogram(days, histogramBins)
+	e.MAEPercent = histogram(mae, histogramBins)
+	e.MFEPercent = histogram(mfe, histogramBins)
+	e.EntryZScore = histogram(entryZ, histogramBins)
+}
+
+// histogram counts values into equal-width bins between their minimum and
+// maximum; equal values share one bin
+func histogram(values []float64, bins int) []HistogramBin {
+	if len(values) == 0 || bins <= 0 {
+		return nil
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	if hi == lo {
+		return []HistogramBin{{From: lo, To: hi, Count: len(values)}}
+	}
+	width := (hi - lo) / float64(bins)
+	out := make([]HistogramBin, bins)
+	for i := range out {
+		out[i].From = lo + float64(i)*width
+		out[i].To = lo + float64(i+1)*width
+	}
+	for _, v := range values {
+		i := min(int((v-lo)/width), bins-1)
+		out[i].Count++
+	}
+	return out
+}
+
+// printExecution prints the execution quality section of the report
+func (r *BacktestResult) printExecution(line string) {
+	e := r.Execution
+	fmt.Println("\n" + line)
+	fmt.Println("EXECUTION QUALITY")
+	fmt.Println(line)
+
+	fmt.Printf("Avg Entry |Z|:      %.2f\n", e.AvgEntryZScore)
+	fmt.Printf("Avg Exit |Z|:       %.2f\n", e.AvgExitZScore)
+	fmt.Printf("Reverted to Mean:   %.1f%% (avg %v)\n", e.ReversionRate, e.AvgTimeToReversion.Round(time.Hour))
+	fmt.Printf("Avg MAE:            %.2f%%\n", e.AvgMAEPercent)
+	fmt.Printf("Avg MFE:            %.2f%%\n", e.AvgMFEPercent)
+	fmt.Printf("MFE Captured:       %.1f%% (winners)\n", e.Capture)
+
+	for _, h := range []struct {
+		title string
+		bins  []HistogramBin
+	}{
+		{"P&L (%)", e.PnLPercent},
+		{"Holding (days)", e.HoldingDays},
+		{"MAE (%)", e.MAEPercent},
+		{"MFE (%)", e.MFEPercent},
+		{"Entry |Z|", e.EntryZScore},
+	} {
+		printHistogram(h.title, h.bins)
+	}
+}
+
+// printHistogram draws bins as rows of bars scaled to the largest
+func printHistogram(title string, bins []HistogramBin) {
+	if len(bins) == 0 {
+		return
+	}
+	fmt.Printf("\n%s\n", title)
+	largest := 0
+	for _, b := range bins {
+		largest = max(largest, b.Count)
+	}
+	for _, b := range bins {
+		bar := 0
+		if largest > 0 {
+			bar = int(math.Round(float64(b.Count) / float64(largest) * 40))
+		}
+		row := fmt.Sprintf("  %9.2f .. %-9.2f %4d %s", b.From, b.To, b.Count, strings.Repeat("#", bar))
+		fmt.Println(strings.TrimRight(row, " "))
+	}
+}
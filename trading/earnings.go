@@ -0,0 +1,121 @@
+package trading
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"margraf/retry"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FetchEarningsDates returns a ticker's scheduled earnings dates from Yahoo's
+// calendar. Yahoo lists the next report (sometimes as a range of possible
+// days), not past ones; use LoadEarningsDates for history.
+func (h *HistoricalDataFetcher) FetchEarningsDates(ctx context.Context, ticker string) ([]time.Time, error) {
+	endpoint := fmt.Sprintf("https://query2.finance.yahoo.com/v10/finance/quoteSummary/%s?modules=calendarEvents",
+		url.PathEscape(ticker))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := retry.DoRequest(h.Client, req, retry.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("calendar failed for %s: %w", ticker, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("calendar returned status %d for %s", resp.StatusCode, ticker)
+	}
+
+	var result struct {
+		QuoteSummary struct {
+			Result []struct {
+				CalendarEvents struct {
+					Earnings struct {
+						EarningsDate []struct {
+							Raw int64 `json:"raw"`
+						} `json:"earningsDate"`
+					} `json:"earnings"`
+				} `json:"calendarEvents"`
+			} `json:"result"`
+			Error *struct {
+				Description string `json:"description"`
+			} `json:"error"`
+		} `json:"quoteSummary"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar for %s: %w", ticker, err)
+	}
+	if result.QuoteSummary.Error != nil {
+		return nil, fmt.Errorf("yahoo API error for %s: %s", ticker, result.QuoteSummary.Error.Description)
+	}
+	if len(result.QuoteSummary.Result) == 0 {
+		return nil, fmt.Errorf("no calendar for %s", ticker)
+	}
+
+	var dates []time.Time
+	for _, d := range result.QuoteSummary.Result[0].CalendarEvents.Earnings.EarningsDate {
+		if d.Raw > 0 {
+			dates = append(dates, time.Unix(d.Raw, 0).UTC())
+		}
+	}
+	return dates, nil
+}
+
+// LoadEarningsDates reads earnings dates from a file of "TICKER,YYYY-MM-DD"
+// lines, keyed by upper-case ticker. Blank lines and lines starting with #
+// are skipped.
+func LoadEarningsDates(path string) (map[string][]time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dates := make(map[string][]time.Time)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		ticker, day, ok := strings.Cut(text, ",")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: want TICKER,YYYY-MM-DD", path, line)
+		}
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(day))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		ticker = strings.ToUpper(strings.TrimSpace(ticker))
+		dates[ticker] = append(dates[ticker], date)
+	}
+	return dates, scanner.Err()
+}
+
+// EarningsBlackouts turns each ticker's earnings dates into blackout windows
+// from before the date's start to after its end, sorted by start
+func EarningsBlackouts(dates map[string][]time.Time, before, after time.Duration) []Blackout {
+	var blackouts []Blackout
+	for ticker, days := range dates {
+		for _, d := range days {
+			day := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, d.Location())
+			blackouts = append(blackouts, Blackout{
+				Start:  day.Add(-before),
+				End:    day.AddDate(0, 0, 1).Add(after),
+				Reason: ticker + " earnings",
+			})
+		}
+	}
+	sort.Slice(blackouts, func(i, j int) bool { return blackouts[i].Start.Before(blackouts[j].Start) })
+	return blackouts
+}
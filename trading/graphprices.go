@@ -0,0 +1,54 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"margraf/graph"
+	"time"
+)
+
+// GraphPrices is a PriceSource that reads the daily closes the graph has
+// recorded from the market monitor, so analyses run offline and without
+// waiting on Yahoo. Each close is stamped with the start of its UTC day, so
+// series of different tickers align as Yahoo's do.
+type GraphPrices struct {
+	Graph *graph.Graph
+	Days  int // Days of history returned (0 = all recorded)
+}
+
+// NewGraphPrices creates a source over days of g's recorded prices
+func NewGraphPrices(g *graph.Graph, days int) *GraphPrices {
+	return &GraphPrices{Graph: g, Days: days}
+}
+
+// Prices returns the ticker's recorded daily closes, oldest first
+func (s *GraphPrices) Prices(ctx context.Context, ticker string) ([]PricePoint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var since time.Time
+	if s.Days > 0 {
+		since = time.Now().AddDate(0, 0, -s.Days)
+	}
+	_, history := s.Graph.PriceHistoryByTicker(ticker, since)
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no recorded prices for %s", ticker)
+	}
+	prices := make([]PricePoint, len(history))
+	for i, snap := range history {
+		prices[i] = PricePoint{Timestamp: snap.Timestamp.UTC().Truncate(24 * time.Hour).Unix(), Price: snap.Price}
+	}
+	return prices, nil
+}
+
+// NewPriceSource returns the PriceSource named by source: "graph" for the
+// prices g has recorded, "yahoo" (or "") for Yahoo history cached per ticker
+func NewPriceSource(source string, g *graph.Graph, days int) (PriceSource, error) {
+	switch source {
+	case "", "yahoo":
+		return NewPriceCache(days), nil
+	case "graph":
+		return NewGraphPrices(g, days), nil
+	}
+	return nil, fmt.Errorf("unknown price source %q (want yahoo or graph)", source)
+}
@@ -0,0 +1,211 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HedgeKind is what a hedge suggestion trades
+type HedgeKind string
+
+const (
+	HedgeShort HedgeKind = "short" // Short (or trim) an exposed holding
+	HedgeLong  HedgeKind = "long"  // Buy a name the shock benefits
+	HedgePair  HedgeKind = "pair"  // Long a winner against a short exposed holding
+)
+
+// HedgeCandidate is a listed company a shock moved
+type HedgeCandidate struct {
+	NodeID string
+	Ticker string
+	Name   string
+	Impact float64 // Health change the shock caused
+	Weight float64 // Portfolio weight at risk (exposed holdings only)
+	Via    string  // Shocked node the holding depends on, if any
+}
+
+// HedgeSuggestion is one trade that offsets a shock's hit to the portfolio
+type HedgeSuggestion struct {
+	Kind        HedgeKind `json:"kind"`
+	Short       string    `json:"short,omitempty"` // Ticker to sell
+	Long        string    `json:"long,omitempty"`  // Ticker to buy
+	ShortNodeID string    `json:"short_node_id,omitempty"`
+	LongNodeID  string    `json:"long_node_id,omitempty"`
+	Size        float64   `json:"size"`                  // Share of portfolio value to put on (the short leg for pairs)
+	Ratio       float64   `json:"ratio,omitempty"`       // Long notional per unit of short notional (pairs)
+	Correlation float64   `json:"correlation,omitempty"` // Return correlation of the pair, when prices are known
+	Rationale   string    `json:"rationale"`
+}
+
+// PriceSource provides daily price history for a ticker
+type PriceSource interface {
+	Prices(ctx context.Context, ticker string) ([]PricePoint, error)
+}
+
+// Hedge ratio bounds, so a noisy volatility estimate can't size one leg at
+// many times the other
+const (
+	minHedgeRatio = 0.25
+	maxHedgeRatio = 4.0
+)
+
+// SuggestHedges turns a shock's exposed holdings and winners into trades:
+// a short for each exposed holding sized by its weight times its health
+// drop, longs in the winners splitting that total by gain, and a pair per
+// exposed holding against the winner it moves with most. With prices, pair
+// legs use the minimum-variance hedge ratio (correlation times the
+// volatility ratio); without them, pairs are 1:1 against the biggest
+// winner. prices may be nil.
+func SuggestHedges(ctx context.Context, exposed, winners []HedgeCandidate, prices PriceSource) []HedgeSuggestion {
+	suggestions := make([]HedgeSuggestion, 0)
+	if len(exposed) == 0 {
+		return suggestions
+	}
+	exposed = append([]HedgeCandidate(nil), exposed...)
+	sort.Slice(exposed, func(i, j int) bool {
+		return exposed[i].Weight*exposed[i].Impact < exposed[j].Weight*exposed[j].Impact
+	})
+	winners = append([]HedgeCandidate(nil), winners...)
+	sort.Slice(winners, func(i, j int) bool { return winners[i].Impact > winners[j].Impact })
+
+	history := make(map[string][]PricePoint)
+	lookup := func(ticker string) []PricePoint {
+		if prices == nil {
+			return nil
+		}
+		if h, ok := history[ticker]; ok {
+			return h
+		}
+		h, err := prices.Prices(ctx, ticker)
+		if err != nil {
+			h = nil
+		}
+		history[ticker] = h
+		return h
+	}
+
+	total := 0.0
+	for _, e := range exposed {
+		size := e.Weight * math.Min(1, -e.Impact)
+		total += size
+		reason := fmt.Sprintf("%s health %+.2f", e.Name, e.Impact)
+		if e.Via != "" {
+			reason += " via " + e.Via
+		}
+		suggestions = append(suggestions, HedgeSuggestion{
+			Kind:        HedgeShort,
+			Short:       e.Ticker,
+			ShortNodeID: e.NodeID,
+			Size:        size,
+			Rationale:   reason,
+		})
+	}
+
+	gain := 0.0
+	for _, w := range winners {
+		gain += w.Impact
+	}
+	for _, w := range winners {
+		if gain <= 0 {
+			break
+		}
+		suggestions = append(suggestions, HedgeSuggestion{
+			Kind:       HedgeLong,
+			Long:       w.Ticker,
+			LongNodeID: w.NodeID,
+			Size:       total * w.Impact / gain,
+			Rationale:  fmt.Sprintf("%s health %+.2f", w.Name, w.Impact),
+		})
+	}
+
+	for _, e := range exposed {
+		if len(winners) == 0 {
+			break
+		}
+		pair := HedgeSuggestion{
+			Kind:        HedgePair,
+			Short:       e.Ticker,
+			ShortNodeID: e.NodeID,
+			Size:        e.Weight * math.Min(1, -e.Impact),
+			Ratio:       1,
+		}
+		best := winners[0]
+		short := lookup(e.Ticker)
+		if len(short) > 0 {
+			bestCorr := math.Inf(-1)
+			for _, w := range winners {
+				corr, err := CalculateCorrelation(short, lookup(w.Ticker))
+				if err != nil || corr <= bestCorr {
+					continue
+				}
+				best, bestCorr = w, corr
+			}
+			if !math.IsInf(bestCorr, -1) {
+				pair.Correlation = bestCorr
+				if volLong := CalculateVolatility(lookup(best.Ticker)); volLong > 0 && bestCorr > 0 {
+					ratio := bestCorr * CalculateVolatility(short) / volLong
+					pair.Ratio = math.Max(minHedgeRatio, math.Min(maxHedgeRatio, ratio))
+				}
+			}
+		}
+		pair.Long = best.Ticker
+		pair.LongNodeID = best.NodeID
+		pair.Rationale = fmt.Sprintf("%s %+.2f vs %s %+.2f", e.Name, e.Impact, best.Name, best.Impact)
+		suggestions = append(suggestions, pair)
+	}
+	return suggestions
+}
+
+// PriceCache is a PriceSource that fetches Days of Yahoo history per ticker
+// and reuses it for TTL
+type PriceCache struct {
+	Fetcher *HistoricalDataFetcher
+	Days    int
+	TTL     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedPrices
+}
+
+type cachedPrices struct {
+	prices  []PricePoint
+	err     error
+	fetched time.Time
+}
+
+// NewPriceCache creates a cache over days of history, refetched daily
+func NewPriceCache(days int) *PriceCache {
+	return &PriceCache{
+		Fetcher: NewHistoricalDataFetcher(),
+		Days:    days,
+		TTL:     24 * time.Hour,
+		entries: make(map[string]cachedPrices),
+	}
+}
+
+// Prices returns the ticker's history, fetching it when not cached. Failures
+// are cached too, so a delisted ticker isn't refetched on every call.
+func (c *PriceCache) Prices(ctx context.Context, ticker string) ([]PricePoint, error) {
+	ticker = strings.ToUpper(ticker)
+	c.mu.Lock()
+	entry, ok := c.entries[ticker]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetched) < c.TTL {
+		return entry.prices, entry.err
+	}
+
+	end := time.Now()
+	prices, err := c.Fetcher.FetchYahooHistoricalData(ctx, ticker, end.AddDate(0, 0, -c.Days), end)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	c.mu.Lock()
+	c.entries[ticker] = cachedPrices{prices: prices, err: err, fetched: end}
+	c.mu.Unlock()
+	return prices, err
+}
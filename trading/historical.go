@@ -1,10 +1,14 @@
 package trading
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"margraf/config"
+	"margraf/graph"
+	"margraf/retry"
 	"net/http"
 	"strconv"
 	"strings"
@@ -20,14 +24,14 @@ type HistoricalDataFetcher struct {
 func NewHistoricalDataFetcher() *HistoricalDataFetcher {
 	return &HistoricalDataFetcher{
 		Client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: config.Timeout(config.Global.Timeouts.HTTP, 30*time.Second),
 		},
 	}
 }
 
 // FetchYahooHistoricalData fetches historical data from Yahoo Finance
 // This uses Yahoo's download API which returns CSV data
-func (h *HistoricalDataFetcher) FetchYahooHistoricalData(ticker string, startDate, endDate time.Time) ([]PricePoint, error) {
+func (h *HistoricalDataFetcher) FetchYahooHistoricalData(ctx context.Context, ticker string, startDate, endDate time.Time) ([]PricePoint, error) {
 	// Convert dates to Unix timestamps
 	period1 := startDate.Unix()
 	period2 := endDate.Unix()
@@ -36,7 +40,7 @@ func (h *HistoricalDataFetcher) FetchYahooHistoricalData(ticker string, startDat
 	url := fmt.Sprintf("https://query2.finance.yahoo.com/v7/finance/download/%s?period1=%d&period2=%d&interval=1d&events=history&includeAdjustedClose=true",
 		ticker, period1, period2)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +57,7 @@ func (h *HistoricalDataFetcher) FetchYahooHistoricalData(ticker string, startDat
 	req.Header.Set("Sec-Fetch-Site", "none")
 	req.Header.Set("Cache-Control", "max-age=0")
 
-	resp, err := h.Client.Do(req)
+	resp, err := retry.DoRequest(h.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch data for %s: %w", ticker, err)
 	}
@@ -61,7 +65,7 @@ func (h *HistoricalDataFetcher) FetchYahooHistoricalData(ticker string, startDat
 
 	if resp.StatusCode != 200 {
 		// Try alternate approach - scrape from Yahoo Finance page directly
-		return h.fetchFromYahooChartAPI(ticker, startDate, endDate)
+		return h.fetchFromYahooChartAPI(ctx, ticker, startDate, endDate)
 	}
 
 	// Parse CSV response
@@ -89,6 +93,7 @@ func (h *HistoricalDataFetcher) FetchYahooHistoricalData(ticker string, startDat
 	}
 
 	var pricePoints []PricePoint
+	scale := quoteScale(ticker, "") // The CSV gives no currency
 
 	// Read data rows
 	for {
@@ -120,7 +125,7 @@ func (h *HistoricalDataFetcher) FetchYahooHistoricalData(ticker string, startDat
 
 		pricePoints = append(pricePoints, PricePoint{
 			Timestamp: t.Unix(),
-			Price:     price,
+			Price:     price * scale,
 		})
 	}
 
@@ -132,7 +137,7 @@ func (h *HistoricalDataFetcher) FetchYahooHistoricalData(ticker string, startDat
 }
 
 // fetchFromYahooChartAPI uses Yahoo's chart API as an alternative
-func (h *HistoricalDataFetcher) fetchFromYahooChartAPI(ticker string, startDate, endDate time.Time) ([]PricePoint, error) {
+func (h *HistoricalDataFetcher) fetchFromYahooChartAPI(ctx context.Context, ticker string, startDate, endDate time.Time) ([]PricePoint, error) {
 	period1 := startDate.Unix()
 	period2 := endDate.Unix()
 
@@ -140,7 +145,7 @@ func (h *HistoricalDataFetcher) fetchFromYahooChartAPI(ticker string, startDate,
 	url := fmt.Sprintf("https://query2.finance.yahoo.com/v8/finance/chart/%s?period1=%d&period2=%d&interval=1d&events=history",
 		ticker, period1, period2)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -148,7 +153,7 @@ func (h *HistoricalDataFetcher) fetchFromYahooChartAPI(ticker string, startDate,
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36")
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := h.Client.Do(req)
+	resp, err := retry.DoRequest(h.Client, req, retry.HTTP)
 	if err != nil {
 		return nil, fmt.Errorf("chart API failed for %s: %w", ticker, err)
 	}
@@ -162,6 +167,9 @@ func (h *HistoricalDataFetcher) fetchFromYahooChartAPI(ticker string, startDate,
 	var result struct {
 		Chart struct {
 			Result []struct {
+				Meta struct {
+					Currency string `json:"currency"`
+				} `json:"meta"`
 				Timestamp  []int64 `json:"timestamp"`
 				Indicators struct {
 					Quote []struct {
@@ -197,6 +205,7 @@ func (h *HistoricalDataFetcher) fetchFromYahooChartAPI(ticker string, startDate,
 	}
 
 	closes := data.Indicators.Quote[0].Close
+	scale := quoteScale(ticker, data.Meta.Currency)
 
 	if len(timestamps) != len(closes) {
 		return nil, fmt.Errorf("mismatched data lengths for %s", ticker)
@@ -211,7 +220,7 @@ func (h *HistoricalDataFetcher) fetchFromYahooChartAPI(ticker string, startDate,
 
 		pricePoints = append(pricePoints, PricePoint{
 			Timestamp: timestamps[i],
-			Price:     closes[i],
+			Price:     closes[i] * scale,
 		})
 	}
 
@@ -222,13 +231,24 @@ func (h *HistoricalDataFetcher) fetchFromYahooChartAPI(ticker string, startDate,
 	return pricePoints, nil
 }
 
+// quoteScale converts a ticker's quoted prices to its currency proper: 0.01
+// for listings quoted in pence, cents or agorot (see graph.NormalizeQuote),
+// so history is in the same units as the live prices in the graph
+func quoteScale(ticker, currency string) float64 {
+	scale, _ := graph.NormalizeQuote(ticker, 1, currency)
+	return scale
+}
+
 // FetchMultipleHistoricalData fetches data for multiple tickers
-func (h *HistoricalDataFetcher) FetchMultipleHistoricalData(tickers []string, startDate, endDate time.Time) (map[string][]PricePoint, error) {
+func (h *HistoricalDataFetcher) FetchMultipleHistoricalData(ctx context.Context, tickers []string, startDate, endDate time.Time) (map[string][]PricePoint, error) {
 	results := make(map[string][]PricePoint)
 	errors := []string{}
 
 	for _, ticker := range tickers {
-		prices, err := h.FetchYahooHistoricalData(ticker, startDate, endDate)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		prices, err := h.FetchYahooHistoricalData(ctx, ticker, startDate, endDate)
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("%s: %v", ticker, err))
 			continue
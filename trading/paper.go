@@ -0,0 +1,522 @@
+package trading
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Order sides
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// PaperOrder is one leg the paper trader sent for a signal
+type PaperOrder struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Pair     string    `json:"pair"`
+	Ticker   string    `json:"ticker"`
+	Side     string    `json:"side"`
+	Quantity float64   `json:"quantity"`
+	Action   string    `json:"action"` // Signal action: one of the Action constants
+	ZScore   float64   `json:"z_score"`
+}
+
+// PaperFill is an order filled at the last price
+type PaperFill struct {
+	OrderID    string    `json:"order_id"`
+	Time       time.Time `json:"time"`
+	Ticker     string    `json:"ticker"`
+	Side       string    `json:"side"`
+	Quantity   float64   `json:"quantity"`
+	Price      float64   `json:"price"`
+	Commission float64   `json:"commission"`
+}
+
+// PaperPosition is an open position of one strategy
+type PaperPosition struct {
+	Pair        string    `json:"pair"` // The strategy's key (see StrategyKey)
+	Strategy    string    `json:"strategy,omitempty"`
+	Ticker1     string    `json:"ticker1"`
+	Ticker2     string    `json:"ticker2"` // Empty for a single-asset strategy
+	Direction   string    `json:"direction"`
+	Quantity    float64   `json:"quantity"`     // Shares of each leg
+	EntryPrice1 float64   `json:"entry_price1"` // Average over the lots
+	EntryPrice2 float64   `json:"entry_price2"`
+	EntryZScore float64   `json:"entry_z_score"`
+	EntryTime   time.Time `json:"entry_time"`
+	Commission  float64   `json:"commission"` // Paid on the entry and scale-ins, less what scale-outs booked
+	Lots        []Lot     `json:"lots,omitempty"`
+	ScaledOut   int       `json:"scaled_out,omitempty"`
+}
+
+// PaperTrade is a closed round trip
+type PaperTrade struct {
+	Pair      string    `json:"pair"`
+	Direction string    `json:"direction"`
+	EntryTime time.Time `json:"entry_time"`
+	ExitTime  time.Time `json:"exit_time"`
+	PnL       float64   `json:"pnl"`               // After commission on both legs, entry and exit
+	Reason    string    `json:"reason,omitempty"`  // Why it closed: one of the Exit constants
+	Partial   bool      `json:"partial,omitempty"` // A scale-out; the position stayed open
+}
+
+// NAVPoint is the account's value at the end of a day
+type NAVPoint struct {
+	Date     string  `json:"date"` // YYYY-MM-DD
+	NAV      float64 `json:"nav"`
+	PnL      float64 `json:"pnl"`       // Cumulative, realized and unrealized
+	OpenRisk float64 `json:"open_risk"` // Gross notional of open positions
+}
+
+// PaperLedger is everything the paper trader persists
+type PaperLedger struct {
+	InitialCapital float64                    `json:"initial_capital"`
+	Cash           float64                    `json:"cash"` // Initial capital plus realized PnL
+	Orders         []PaperOrder               `json:"orders"`
+	Fills          []PaperFill                `json:"fills"`
+	Positions      map[string]*PaperPosition  `json:"positions"` // By pair
+	Trades         []PaperTrade               `json:"trades"`
+	NAV            []NAVPoint                 `json:"nav"`
+	Windows        map[string][2][]PricePoint `json:"windows"` // Each strategy's recent prices, so z-scores survive restarts
+}
+
+// PaperPerformance summarizes the ledger for dashboards
+type PaperPerformance struct {
+	InitialCapital float64          `json:"initial_capital"`
+	NAV            float64          `json:"nav"`
+	CumulativePnL  float64          `json:"cumulative_pnl"`
+	RealizedPnL    float64          `json:"realized_pnl"`
+	UnrealizedPnL  float64          `json:"unrealized_pnl"`
+	OpenRisk       float64          `json:"open_risk"` // Gross notional of open positions
+	Signals        int              `json:"signals"`   // Entries taken
+	Hits           int              `json:"hits"`      // Closed trades with positive PnL, their scale-outs included
+	Closed         int              `json:"closed"`
+	HitRate        float64          `json:"hit_rate"` // Hits / Closed
+	Positions      []*PaperPosition `json:"positions"`
+	History        []NAVPoint       `json:"history"` // Daily NAV, oldest first
+	Pairs          []string         `json:"pairs"`
+}
+
+// PaperTrader runs strategies on live prices and books their signals into
+// a ledger saved to Path after every change
+type PaperTrader struct {
+	Path         string
+	PositionSize float64 // Notional per position, split across its legs
+	Commission   float64 // Share of notional per fill
+
+	mu         sync.Mutex
+	ledger     PaperLedger
+	strategies map[string]Strategy   // By key (see StrategyKey)
+	last       map[string][2]float64 // Prices each strategy last stepped on
+	prices     map[string]float64    // Latest price per ticker
+	seq        int
+}
+
+// PairKey names a pair in the ledger, e.g. "AAPL/MSFT"
+func PairKey(ticker1, ticker2 string) string {
+	return strings.ToUpper(ticker1) + "/" + strings.ToUpper(ticker2)
+}
+
+// NewPaperTrader loads the ledger at path, starting one with capital when
+// the file doesn't exist
+func NewPaperTrader(path string, capital, positionSize, commission float64) (*PaperTrader, error) {
+	p := &PaperTrader{
+		Path:         path,
+		PositionSize: positionSize,
+		Commission:   commission,
+		strategies:   make(map[string]Strategy),
+		last:         make(map[string][2]float64),
+		prices:       make(map[string]float64),
+		ledger:       PaperLedger{InitialCapital: capital, Cash: capital},
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &p.ledger); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if p.ledger.Positions == nil {
+		p.ledger.Positions = make(map[string]*PaperPosition)
+	}
+	if p.ledger.Windows == nil {
+		p.ledger.Windows = make(map[string][2][]PricePoint)
+	}
+	p.seq = len(p.ledger.Orders)
+	return p, nil
+}
+
+// AddPair trades a pair with the given strategy settings and constraints,
+// resuming its price window, open position and last exit from the ledger
+func (p *PaperTrader) AddPair(ticker1, ticker2 string, entry, exit, stopLoss float64, lookback int, constraints Constraints) {
+	p.AddStrategy(NewPairsTradingStrategy(CorrelationPair{
+		Asset1:  strings.ToUpper(ticker1),
+		Asset2:  strings.ToUpper(ticker2),
+		Ticker1: strings.ToUpper(ticker1),
+		Ticker2: strings.ToUpper(ticker2),
+	}, entry, exit, stopLoss, lookback), constraints)
+}
+
+// AddStrategy trades s under constraints, resuming its price window, open
+// position and last exit from the ledger
+func (p *PaperTrader) AddStrategy(s Strategy, constraints Constraints) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := StrategyKey(s)
+	st := s.State()
+	st.Constraints = constraints
+	for _, t := range p.ledger.Trades {
+		if t.Pair == key && !t.Partial {
+			st.LastExit = t.ExitTime.Unix()
+		}
+	}
+	if w, ok := p.ledger.Windows[key]; ok {
+		st.PriceHistory1, st.PriceHistory2 = w[0], w[1]
+	}
+	if pos, ok := p.ledger.Positions[key]; ok {
+		st.CurrentPosition = &Position{
+			EntryTimestamp: pos.EntryTime.Unix(),
+			Asset1:         pos.Ticker1,
+			Asset2:         pos.Ticker2,
+			Ticker1:        pos.Ticker1,
+			Ticker2:        pos.Ticker2,
+			Direction:      pos.Direction,
+			EntryPrice1:    pos.EntryPrice1,
+			EntryPrice2:    pos.EntryPrice2,
+			EntryZScore:    pos.EntryZScore,
+			Quantity:       pos.Quantity,
+			Lots:           append([]Lot(nil), pos.Lots...),
+			ScaledOut:      pos.ScaledOut,
+		}
+		if pos.EntryPrice2 != 0 {
+			st.CurrentPosition.EntrySpread = pos.EntryPrice1 / pos.EntryPrice2
+		}
+	}
+	p.strategies[key] = s
+}
+
+// SetBlackouts replaces a strategy's blackout windows (or targeted windows,
+// see Constraints.Target), e.g. when the calendar changes
+func (p *PaperTrader) SetBlackouts(key string, blackouts []Blackout) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, ok := p.strategies[key]; ok {
+		s.State().Constraints.Blackouts = blackouts
+	}
+}
+
+// Legs returns the tickers each strategy trades, by key; the second is
+// empty for single-asset strategies
+func (p *PaperTrader) Legs() map[string][2]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	legs := make(map[string][2]string, len(p.strategies))
+	for key, s := range p.strategies {
+		t1, t2 := s.Tickers()
+		legs[key] = [2]string{strings.ToUpper(t1), strings.ToUpper(t2)}
+	}
+	return legs
+}
+
+// Pairs returns the keys of the traded strategies, sorted
+func (p *PaperTrader) Pairs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := make([]string, 0, len(p.strategies))
+	for key := range p.strategies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Tick feeds the latest prices by ticker to every strategy whose prices moved,
+// fills the resulting signals at those prices, marks the day's NAV and saves
+// the ledger. It reports whether anything was booked.
+func (p *PaperTrader) Tick(now time.Time, prices map[string]float64) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ticker, price := range prices {
+		if price > 0 {
+			p.prices[strings.ToUpper(ticker)] = price
+		}
+	}
+
+	booked := false
+	keys := make([]string, 0, len(p.strategies))
+	for key := range p.strategies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := p.strategies[key]
+		st := s.State()
+		ticker1, ticker2 := s.Tickers()
+		price1, price2 := p.prices[strings.ToUpper(ticker1)], 0.0
+		if ticker2 != "" {
+			if price2 = p.prices[strings.ToUpper(ticker2)]; price2 <= 0 {
+				continue
+			}
+		}
+		if price1 <= 0 || p.last[key] == [2]float64{price1, price2} {
+			continue
+		}
+		p.last[key] = [2]float64{price1, price2}
+		s.Update(now.Unix(), price1, price2)
+		p.ledger.Windows[key] = [2][]PricePoint{st.PriceHistory1, st.PriceHistory2}
+		booked = true
+
+		signal, err := s.Signal(now.Unix())
+		if err != nil || signal == nil {
+			continue
+		}
+		switch {
+		case signal.Action == ActionClose && st.HasOpenPosition():
+			p.close(key, s, signal, now)
+		case signal.Action == ActionScaleOut && st.HasOpenPosition():
+			p.scaleOut(key, s, signal, now)
+		case signal.Action == ActionScaleIn && st.HasOpenPosition() && p.ledger.Cash >= p.PositionSize:
+			p.scaleIn(key, s, signal, now)
+		case IsEntry(signal.Action) && !st.HasOpenPosition() && p.ledger.Cash >= p.PositionSize:
+			p.open(key, s, signal, now)
+		}
+	}
+	if !booked {
+		return false, nil
+	}
+	p.markLocked(now)
+	return true, p.saveLocked()
+}
+
+// open books the legs of an entry signal
+func (p *PaperTrader) open(key string, s Strategy, signal *Signal, now time.Time) {
+	ticker1, ticker2 := s.Tickers()
+	quantity := p.PositionSize / entryCost(signal.Action, signal.Price1, signal.Price2)
+	side1, side2 := legSides(signal.Action)
+	commission := p.fill(key, signal, ticker1, side1, quantity, signal.Price1, now)
+	if ticker2 != "" {
+		commission += p.fill(key, signal, ticker2, side2, quantity, signal.Price2, now)
+	}
+	s.Execute(signal, quantity)
+	pos := &PaperPosition{
+		Pair:        key,
+		Strategy:    s.Name(),
+		Ticker1:     ticker1,
+		Ticker2:     ticker2,
+		Direction:   signal.Action,
+		EntryZScore: signal.ZScore,
+		EntryTime:   now,
+		Commission:  commission,
+	}
+	pos.sync(s.State().CurrentPosition)
+	p.ledger.Positions[key] = pos
+}
+
+// scaleIn books a lot added to an open position, as large as an entry
+func (p *PaperTrader) scaleIn(key string, s Strategy, signal *Signal, now time.Time) {
+	pos := p.ledger.Positions[key]
+	st := s.State()
+	ticker1, ticker2 := s.Tickers()
+	direction := st.CurrentPosition.Direction
+	quantity := p.PositionSize / entryCost(direction, signal.Price1, signal.Price2)
+	side1, side2 := legSides(direction)
+	commission := p.fill(key, signal, ticker1, side1, quantity, signal.Price1, now)
+	if ticker2 != "" {
+		commission += p.fill(key, signal, ticker2, side2, quantity, signal.Price2, now)
+	}
+	s.Execute(signal, quantity)
+	if pos != nil {
+		pos.Commission += commission
+		pos.sync(st.CurrentPosition)
+	}
+}
+
+// scaleOut books the part of an open position a scale-out takes off as a
+// partial trade, with its share of the entry commission
+func (p *PaperTrader) scaleOut(key string, s Strategy, signal *Signal, now time.Time) {
+	pos := p.ledger.Positions[key]
+	st := s.State()
+	ticker1, ticker2 := s.Tickers()
+	open := st.CurrentPosition
+	quantity := open.Part(signal.Fraction)
+	pnl := positionPnL(open.Direction, open.EntryPrice1, open.EntryPrice2, signal.Price1, signal.Price2, quantity)
+	side2, side1 := legSides(open.Direction) // Reversed
+	commission := p.fill(key, signal, ticker1, side1, quantity, signal.Price1, now)
+	if ticker2 != "" {
+		commission += p.fill(key, signal, ticker2, side2, quantity, signal.Price2, now)
+	}
+
+	trade := PaperTrade{Pair: key, Direction: open.Direction, ExitTime: now, PnL: pnl - commission, Reason: signal.Reason, Partial: true}
+	if pos != nil && open.Quantity > 0 {
+		entryShare := pos.Commission * quantity / open.Quantity
+		pos.Commission -= entryShare
+		trade.PnL -= entryShare
+		trade.EntryTime = pos.EntryTime
+	}
+	s.Execute(signal, 0)
+	if pos != nil {
+		pos.sync(st.CurrentPosition)
+	}
+	p.ledger.Cash += trade.PnL
+	p.ledger.Trades = append(p.ledger.Trades, trade)
+}
+
+// sync copies the size, average entry prices and lots of the strategy's
+// position
+func (pp *PaperPosition) sync(pos *Position) {
+	if pos == nil {
+		return
+	}
+	pp.Quantity = pos.Quantity
+	pp.EntryPrice1, pp.EntryPrice2 = pos.EntryPrice1, pos.EntryPrice2
+	pp.Lots = append(pp.Lots[:0], pos.Lots...)
+	pp.ScaledOut = pos.ScaledOut
+}
+
+// close books the exit legs and the round trip's PnL
+func (p *PaperTrader) close(key string, s Strategy, signal *Signal, now time.Time) {
+	pos := p.ledger.Positions[key]
+	st := s.State()
+	ticker1, ticker2 := s.Tickers()
+	pnl := st.CalculatePnL(signal.Price1, signal.Price2)
+	side2, side1 := legSides(st.CurrentPosition.Direction) // Reversed
+	quantity := st.CurrentPosition.Quantity
+	commission := p.fill(key, signal, ticker1, side1, quantity, signal.Price1, now)
+	if ticker2 != "" {
+		commission += p.fill(key, signal, ticker2, side2, quantity, signal.Price2, now)
+	}
+	s.Execute(signal, 0)
+
+	trade := PaperTrade{Pair: key, Direction: signal.Action, ExitTime: now, PnL: pnl - commission, Reason: signal.Reason}
+	if pos != nil {
+		trade.Direction = pos.Direction
+		trade.EntryTime = pos.EntryTime
+		trade.PnL -= pos.Commission
+	}
+	p.ledger.Cash += trade.PnL
+	p.ledger.Trades = append(p.ledger.Trades, trade)
+	delete(p.ledger.Positions, key)
+}
+
+// fill records an order and its fill, returning the commission
+func (p *PaperTrader) fill(key string, signal *Signal, ticker, side string, quantity, price float64, now time.Time) float64 {
+	p.seq++
+	id := fmt.Sprintf("paper-%d", p.seq)
+	commission := p.Commission * quantity * price
+	p.ledger.Orders = append(p.ledger.Orders, PaperOrder{
+		ID:       id,
+		Time:     now,
+		Pair:     key,
+		Ticker:   ticker,
+		Side:     side,
+		Quantity: quantity,
+		Action:   signal.Action,
+		ZScore:   signal.ZScore,
+	})
+	p.ledger.Fills = append(p.ledger.Fills, PaperFill{
+		OrderID:    id,
+		Time:       now,
+		Ticker:     ticker,
+		Side:       side,
+		Quantity:   quantity,
+		Price:      price,
+		Commission: commission,
+	})
+	return commission
+}
+
+// unrealizedLocked is the open positions' PnL and gross notional at the
+// latest prices
+func (p *PaperTrader) unrealizedLocked() (pnl, risk float64) {
+	for _, pos := range p.ledger.Positions {
+		price1, price2 := p.prices[pos.Ticker1], p.prices[pos.Ticker2]
+		if price1 <= 0 {
+			price1 = pos.EntryPrice1
+		}
+		if price2 <= 0 {
+			price2 = pos.EntryPrice2
+		}
+		pnl += positionPnL(pos.Direction, pos.EntryPrice1, pos.EntryPrice2, price1, price2, pos.Quantity) - pos.Commission
+		risk += (price1 + price2) * pos.Quantity
+	}
+	return pnl, risk
+}
+
+// markLocked records today's NAV, replacing an earlier mark from the same day
+func (p *PaperTrader) markLocked(now time.Time) {
+	unrealized, risk := p.unrealizedLocked()
+	point := NAVPoint{
+		Date:     now.UTC().Format("2006-01-02"),
+		NAV:      p.ledger.Cash + unrealized,
+		PnL:      p.ledger.Cash + unrealized - p.ledger.InitialCapital,
+		OpenRisk: risk,
+	}
+	if n := len(p.ledger.NAV); n > 0 && p.ledger.NAV[n-1].Date == point.Date {
+		p.ledger.NAV[n-1] = point
+		return
+	}
+	p.ledger.NAV = append(p.ledger.NAV, point)
+}
+
+func (p *PaperTrader) saveLocked() error {
+	data, err := json.MarshalIndent(p.ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.Path, data, 0644)
+}
+
+// Performance returns cumulative PnL, open risk, hit rate and the daily NAV
+func (p *PaperTrader) Performance() *PaperPerformance {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	unrealized, risk := p.unrealizedLocked()
+	perf := &PaperPerformance{
+		InitialCapital: p.ledger.InitialCapital,
+		NAV:            p.ledger.Cash + unrealized,
+		RealizedPnL:    p.ledger.Cash - p.ledger.InitialCapital,
+		UnrealizedPnL:  unrealized,
+		OpenRisk:       risk,
+		Signals:        len(p.ledger.Positions),
+		Positions:      make([]*PaperPosition, 0, len(p.ledger.Positions)),
+		History:        append([]NAVPoint{}, p.ledger.NAV...),
+		Pairs:          make([]string, 0, len(p.strategies)),
+	}
+	perf.CumulativePnL = perf.RealizedPnL + unrealized
+	partial := make(map[string]float64) // Scale-outs count with the close they lead up to
+	for _, t := range p.ledger.Trades {
+		if t.Partial {
+			partial[t.Pair] += t.PnL
+			continue
+		}
+		perf.Signals++
+		perf.Closed++
+		if t.PnL+partial[t.Pair] > 0 {
+			perf.Hits++
+		}
+		delete(partial, t.Pair)
+	}
+	if perf.Closed > 0 {
+		perf.HitRate = float64(perf.Hits) / float64(perf.Closed)
+	}
+	for _, pos := range p.ledger.Positions {
+		copied := *pos
+		perf.Positions = append(perf.Positions, &copied)
+	}
+	sort.Slice(perf.Positions, func(i, j int) bool { return perf.Positions[i].Pair < perf.Positions[j].Pair })
+	for key := range p.strategies {
+		perf.Pairs = append(perf.Pairs, key)
+	}
+	sort.Strings(perf.Pairs)
+	return perf
+}
@@ -0,0 +1,115 @@
+package trading
+
+import (
+	"margraf/graph"
+	"math"
+)
+
+// With short price histories, sample correlations are noisy. The graph says
+// which companies ought to move together - same industry, shared suppliers,
+// clients and materials, a direct relationship - so FindCorrelatedPairs can
+// shrink the sample correlation towards a prior read from the graph. The
+// prior's weight is Shrinkage / (Shrinkage + n) for n aligned prices: it
+// dominates a few weeks of data and fades as history grows.
+
+// Contributions to the graph prior, combined as 1 - (1-a)(1-b)(1-c)
+const (
+	priorSameIndustry = 0.5 // Both listed under the same Industry node
+	priorCounterparty = 0.6 // Scaled by the weighted overlap of counterparties
+	priorDirectEdge   = 0.4 // Scaled by the direct edge's weight
+)
+
+// counterpartyEdges are the relationships whose far ends count as a company's
+// counterparties for the prior
+var counterpartyEdges = map[graph.EdgeType]bool{
+	graph.EdgeTypeSupplies:     true,
+	graph.EdgeTypeProcuresFrom: true,
+	graph.EdgeTypeDependsOn:    true,
+	graph.EdgeTypeConsumes:     true,
+	graph.EdgeTypeRequires:     true,
+}
+
+// GraphPrior estimates from the graph alone how correlated two assets'
+// prices should be, from 0 (unrelated) to 1
+func (ca *CorrelationAnalyzer) GraphPrior(asset1, asset2 string) float64 {
+	if ca.Graph == nil {
+		return 0
+	}
+	industry := 0.0
+	industries := make(map[string]bool)
+	for _, e := range ca.Graph.GetIncomingEdges(asset1) {
+		if e.Type == graph.EdgeTypeHasCompany {
+			industries[e.SourceID] = true
+		}
+	}
+	for _, e := range ca.Graph.GetIncomingEdges(asset2) {
+		if e.Type == graph.EdgeTypeHasCompany && industries[e.SourceID] {
+			industry = priorSameIndustry
+			break
+		}
+	}
+
+	shared := priorCounterparty * weightedOverlap(ca.counterparties(asset1), ca.counterparties(asset2))
+
+	direct := 0.0
+	if _, hasEdge, weight := ca.getGraphRelationship(asset1, asset2); hasEdge {
+		direct = priorDirectEdge * math.Min(1, math.Max(0, weight))
+	}
+
+	return 1 - (1-industry)*(1-shared)*(1-direct)
+}
+
+// counterparties maps the suppliers, clients and materials of an asset to the
+// weight of its strongest edge to each
+func (ca *CorrelationAnalyzer) counterparties(id string) map[string]float64 {
+	parties := make(map[string]float64)
+	add := func(other string, w float64) {
+		if other == id {
+			return
+		}
+		w = math.Min(1, math.Max(0, w))
+		if w > parties[other] {
+			parties[other] = w
+		}
+	}
+	for _, e := range ca.Graph.GetOutgoingEdges(id) {
+		if counterpartyEdges[e.Type] {
+			add(e.TargetID, e.Weight)
+		}
+	}
+	for _, e := range ca.Graph.GetIncomingEdges(id) {
+		if counterpartyEdges[e.Type] {
+			add(e.SourceID, e.Weight)
+		}
+	}
+	return parties
+}
+
+// weightedOverlap is the weighted Jaccard index of two weighted sets
+func weightedOverlap(a, b map[string]float64) float64 {
+	var shared, union float64
+	for id, wa := range a {
+		wb := b[id]
+		shared += math.Min(wa, wb)
+		union += math.Max(wa, wb)
+	}
+	for id, wb := range b {
+		if _, ok := a[id]; !ok {
+			union += wb
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return shared / union
+}
+
+// blendCorrelation shrinks a sample correlation over n observations towards
+// prior, returning the blend and the prior's weight
+func blendCorrelation(raw, prior float64, n int, shrinkage float64) (blended, weight float64) {
+	if shrinkage <= 0 {
+		return raw, 0
+	}
+	weight = shrinkage / (shrinkage + float64(n))
+	return weight*prior + (1-weight)*raw, weight
+}
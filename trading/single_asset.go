@@ -0,0 +1,146 @@
+package trading
+
+import (
+	"fmt"
+	"margraf/config"
+	"math"
+)
+
+// SingleAssetStrategy trades one ticker on the z-score of its price against
+// the lookback window's mean. Mean reversion fades a move (sells above the
+// mean, buys below) and closes once the price is back near it; momentum
+// follows it and closes once it fades.
+type SingleAssetStrategy struct {
+	StrategyState
+	Ticker         string
+	Momentum       bool    // Follow moves instead of fading them
+	EntryThreshold float64 // Z-score that opens a position
+	ExitThreshold  float64 // Z-score that closes it
+	StopLoss       float64 // Loss as a share of the entry price that closes it
+	LookbackWindow int
+}
+
+// NewSingleAssetStrategy creates a mean reversion strategy on ticker, or a
+// momentum one
+func NewSingleAssetStrategy(ticker string, momentum bool, entryThreshold, exitThreshold, stopLoss float64, lookbackWindow int) *SingleAssetStrategy {
+	return &SingleAssetStrategy{
+		Ticker:         ticker,
+		Momentum:       momentum,
+		EntryThreshold: entryThreshold,
+		ExitThreshold:  exitThreshold,
+		StopLoss:       stopLoss,
+		LookbackWindow: lookbackWindow,
+	}
+}
+
+func newSingleAssetStrategy(cfg config.StrategyConfig, momentum bool) (Strategy, error) {
+	t1, t2 := splitTickers(cfg.Tickers)
+	if t1 == "" || t2 != "" {
+		return nil, fmt.Errorf("%s: tickers %q: want a single ticker", cfg.Name, cfg.Tickers)
+	}
+	if cfg.Lookback < 2 {
+		return nil, fmt.Errorf("%s: lookback %d: need at least 2", cfg.Name, cfg.Lookback)
+	}
+	return NewSingleAssetStrategy(t1, momentum, cfg.Entry, cfg.Exit, cfg.StopLoss, cfg.Lookback), nil
+}
+
+// Name implements Strategy
+func (s *SingleAssetStrategy) Name() string {
+	if s.Momentum {
+		return "momentum"
+	}
+	return "mean_reversion"
+}
+
+// Tickers implements Strategy
+func (s *SingleAssetStrategy) Tickers() (string, string) { return s.Ticker, "" }
+
+// Warmup implements Strategy
+func (s *SingleAssetStrategy) Warmup() int { return s.LookbackWindow }
+
+// Update implements Strategy
+func (s *SingleAssetStrategy) Update(timestamp int64, price1, price2 float64) {
+	s.Record(timestamp, price1, 0, s.LookbackWindow*2)
+}
+
+// CalculateZScore is the latest price's z-score within the lookback window
+func (s *SingleAssetStrategy) CalculateZScore() (float64, error) {
+	if len(s.PriceHistory1) < s.LookbackWindow {
+		return 0, fmt.Errorf("insufficient data: have %d, need %d", len(s.PriceHistory1), s.LookbackWindow)
+	}
+	recent := s.PriceHistory1[len(s.PriceHistory1)-s.LookbackWindow:]
+	var sum float64
+	for _, p := range recent {
+		sum += p.Price
+	}
+	mean := sum / float64(len(recent))
+	var variance float64
+	for _, p := range recent {
+		variance += (p.Price - mean) * (p.Price - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(len(recent)-1))
+	if stdDev == 0 {
+		return 0, fmt.Errorf("zero standard deviation")
+	}
+	return (recent[len(recent)-1].Price - mean) / stdDev, nil
+}
+
+// Signal implements Strategy
+func (s *SingleAssetStrategy) Signal(timestamp int64) (*Signal, error) {
+	zScore, err := s.CalculateZScore()
+	if err != nil {
+		return nil, err
+	}
+	price := s.PriceHistory1[len(s.PriceHistory1)-1].Price
+	signal := &Signal{
+		Timestamp: timestamp,
+		Asset1:    s.Ticker,
+		Ticker1:   s.Ticker,
+		ZScore:    zScore,
+		Price1:    price,
+	}
+
+	if s.CurrentPosition != nil {
+		signal.Action = ActionClose
+		if signal.Reason = s.CheckExit(timestamp, price, 0, s.StopLoss); signal.Reason != "" {
+			return signal, nil
+		}
+		long := s.CurrentPosition.Direction == ActionLong1
+		if s.Momentum {
+			// The move faded, or turned against the position
+			if (long && zScore < s.ExitThreshold) || (!long && zScore > -s.ExitThreshold) {
+				signal.Reason = ExitReversal
+				return signal, nil
+			}
+			return nil, nil
+		}
+		if math.Abs(zScore) < s.ExitThreshold {
+			signal.Reason = ExitMeanReversion
+			return signal, nil
+		}
+		if (long && zScore > 0) || (!long && zScore < 0) {
+			signal.Reason = ExitReversal
+			return signal, nil
+		}
+		return nil, nil
+	}
+
+	if !s.CanEnter(timestamp) {
+		return nil, nil
+	}
+	switch {
+	case zScore > s.EntryThreshold:
+		signal.Action = ActionShort1
+		if s.Momentum {
+			signal.Action = ActionLong1
+		}
+	case zScore < -s.EntryThreshold:
+		signal.Action = ActionLong1
+		if s.Momentum {
+			signal.Action = ActionShort1
+		}
+	default:
+		return nil, nil
+	}
+	return signal, nil
+}
@@ -0,0 +1,370 @@
+package trading
+
+import (
+	"fmt"
+	"margraf/config"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Signal actions. A strategy trades one ticker (LONG_1 / SHORT_1) or two
+// against each other (LONG_1_SHORT_2 / LONG_2_SHORT_1), and may add to an
+// open position or take part of it off before closing it.
+const (
+	ActionLong1Short2 = "LONG_1_SHORT_2"
+	ActionLong2Short1 = "LONG_2_SHORT_1"
+	ActionLong1       = "LONG_1"
+	ActionShort1      = "SHORT_1"
+	ActionScaleIn     = "SCALE_IN"  // Add a lot in the position's direction
+	ActionScaleOut    = "SCALE_OUT" // Take Signal.Fraction of the position off
+	ActionClose       = "CLOSE"
+)
+
+// IsEntry reports whether action opens a position
+func IsEntry(action string) bool {
+	switch action {
+	case ActionLong1Short2, ActionLong2Short1, ActionLong1, ActionShort1:
+		return true
+	}
+	return false
+}
+
+// Lot is one addition to a position: its entry or a scale-in
+type Lot struct {
+	Timestamp int64   `json:"timestamp"`
+	Price1    float64 `json:"price1"`
+	Price2    float64 `json:"price2,omitempty"`
+	ZScore    float64 `json:"z_score"`
+	Quantity  float64 `json:"quantity"` // Shares of each leg still held
+}
+
+// Strategy is a trading strategy the backtester and the paper trader can
+// run. Each step they pass it the latest prices with Update, ask it for a
+// Signal, and report what they did with it through Execute. State holds the
+// position and price windows they read, reset and restore, so most
+// strategies embed a StrategyState and only write Update and Signal.
+//
+// Register a strategy with RegisterStrategy to trade it from
+// paper.strategies.
+type Strategy interface {
+	// Name is the strategy's registered name, e.g. "pairs"
+	Name() string
+
+	// Tickers are the tickers it trades; the second is empty for a
+	// single-asset strategy, which is then passed a zero price2
+	Tickers() (string, string)
+
+	// Warmup is the number of price points needed before the first signal
+	Warmup() int
+
+	// Update adds the prices at timestamp
+	Update(timestamp int64, price1, price2 float64)
+
+	// Signal returns the action to take at timestamp, or nil to do nothing.
+	// An error means it can't tell yet, e.g. too few prices.
+	Signal(timestamp int64) (*Signal, error)
+
+	// Execute records that signal was acted on: an entry or scale-in of
+	// quantity shares of each leg, a scale-out or a close
+	Execute(signal *Signal, quantity float64)
+
+	// State is the strategy's position, price windows and constraints
+	State() *StrategyState
+}
+
+// StrategyState is what every strategy keeps between steps
+type StrategyState struct {
+	CurrentPosition *Position
+	PriceHistory1   []PricePoint
+	PriceHistory2   []PricePoint // Empty for single-asset strategies
+	Constraints     Constraints
+	LastExit        int64 // Timestamp of the last close, for Constraints.Cooldown (0 = none yet)
+}
+
+// State implements Strategy
+func (st *StrategyState) State() *StrategyState { return st }
+
+// Record appends the prices at timestamp to the windows, keeping the last
+// keep points (0 = all). price2 is only kept once there is a second window
+// or it is set.
+func (st *StrategyState) Record(timestamp int64, price1, price2 float64, keep int) {
+	st.PriceHistory1 = append(st.PriceHistory1, PricePoint{Timestamp: timestamp, Price: price1})
+	if price2 != 0 || len(st.PriceHistory2) > 0 {
+		st.PriceHistory2 = append(st.PriceHistory2, PricePoint{Timestamp: timestamp, Price: price2})
+	}
+	if keep > 0 && len(st.PriceHistory1) > keep {
+		st.PriceHistory1 = st.PriceHistory1[len(st.PriceHistory1)-keep:]
+	}
+	if keep > 0 && len(st.PriceHistory2) > keep {
+		st.PriceHistory2 = st.PriceHistory2[len(st.PriceHistory2)-keep:]
+	}
+}
+
+// Execute implements Strategy: an entry signal opens a position of
+// quantity shares per leg, a scale-in adds a lot of quantity shares, a
+// scale-out takes its fraction off and a close clears it
+func (st *StrategyState) Execute(signal *Signal, quantity float64) {
+	pos := st.CurrentPosition
+	lot := Lot{Timestamp: signal.Timestamp, Price1: signal.Price1, Price2: signal.Price2, ZScore: signal.ZScore, Quantity: quantity}
+	switch {
+	case signal.Action == ActionClose:
+		if pos != nil {
+			st.CurrentPosition = nil
+			st.LastExit = signal.Timestamp
+		}
+	case signal.Action == ActionScaleIn:
+		if pos != nil {
+			pos.AddLot(lot)
+		}
+	case signal.Action == ActionScaleOut:
+		if pos != nil {
+			pos.Reduce(signal.Fraction)
+		}
+	case IsEntry(signal.Action):
+		st.CurrentPosition = &Position{
+			EntryTimestamp: signal.Timestamp,
+			Asset1:         signal.Asset1,
+			Asset2:         signal.Asset2,
+			Ticker1:        signal.Ticker1,
+			Ticker2:        signal.Ticker2,
+			Direction:      signal.Action,
+			EntrySpread:    signal.Spread,
+			EntryZScore:    signal.ZScore,
+		}
+		st.CurrentPosition.AddLot(lot)
+	}
+}
+
+// CalculatePnL calculates the current P&L for an open position
+func (st *StrategyState) CalculatePnL(currentPrice1, currentPrice2 float64) float64 {
+	if st.CurrentPosition == nil {
+		return 0
+	}
+	return st.CurrentPosition.PnL(currentPrice1, currentPrice2)
+}
+
+// HasOpenPosition returns whether there's an open position
+func (st *StrategyState) HasOpenPosition() bool {
+	return st.CurrentPosition != nil
+}
+
+// GetCurrentPosition returns the current position if any
+func (st *StrategyState) GetCurrentPosition() *Position {
+	return st.CurrentPosition
+}
+
+// Reset clears the position, price windows and last exit, keeping the
+// constraints
+func (st *StrategyState) Reset() {
+	*st = StrategyState{
+		PriceHistory1: []PricePoint{},
+		PriceHistory2: []PricePoint{},
+		Constraints:   st.Constraints,
+	}
+}
+
+// CheckExit returns why the open position must close at timestamp whatever
+// the strategy's own exit rule says: a loss beyond stopLoss as a share of
+// its entry notional, or one of the constraints. Empty means it may be held.
+func (st *StrategyState) CheckExit(timestamp int64, price1, price2, stopLoss float64) string {
+	pos := st.CurrentPosition
+	if pos == nil {
+		return ""
+	}
+	if entry := (pos.EntryPrice1 + pos.EntryPrice2) * pos.Quantity; entry > 0 && pos.PnL(price1, price2)/entry < -stopLoss {
+		return ExitStopLoss
+	}
+	now := time.Unix(timestamp, 0)
+	if st.Constraints.MaxHolding > 0 && now.Sub(time.Unix(pos.EntryTimestamp, 0)) >= st.Constraints.MaxHolding {
+		return ExitMaxHolding
+	}
+	_, blackedOut := st.Constraints.blackoutAt(now)
+	if blackedOut && !st.Constraints.Target {
+		return ExitBlackout
+	}
+	if !blackedOut && st.Constraints.Target {
+		return ExitWindowEnd
+	}
+	return ""
+}
+
+// CanEnter reports whether the constraints allow a new position at
+// timestamp: not in a blackout (inside a window when targeting) and not
+// cooling down after an exit
+func (st *StrategyState) CanEnter(timestamp int64) bool {
+	now := time.Unix(timestamp, 0)
+	if _, blackedOut := st.Constraints.blackoutAt(now); blackedOut != st.Constraints.Target {
+		return false
+	}
+	return st.Constraints.Cooldown <= 0 || st.LastExit == 0 || now.Sub(time.Unix(st.LastExit, 0)) >= st.Constraints.Cooldown
+}
+
+// PnL is the position's profit at the given prices, before commission
+func (p *Position) PnL(price1, price2 float64) float64 {
+	return positionPnL(p.Direction, p.EntryPrice1, p.EntryPrice2, price1, price2, p.Quantity)
+}
+
+// AddLot adds a lot to the position, moving the entry prices to the
+// quantity-weighted average
+func (p *Position) AddLot(lot Lot) {
+	if total := p.Quantity + lot.Quantity; total > 0 {
+		p.EntryPrice1 = (p.EntryPrice1*p.Quantity + lot.Price1*lot.Quantity) / total
+		p.EntryPrice2 = (p.EntryPrice2*p.Quantity + lot.Price2*lot.Quantity) / total
+		p.Quantity = total
+	} else if len(p.Lots) == 0 {
+		p.EntryPrice1, p.EntryPrice2 = lot.Price1, lot.Price2
+	}
+	if p.EntryPrice2 != 0 {
+		p.EntrySpread = p.EntryPrice1 / p.EntryPrice2
+	}
+	p.Lots = append(p.Lots, lot)
+}
+
+// Part is the shares per leg that taking fraction of the position off removes
+func (p *Position) Part(fraction float64) float64 {
+	return p.Quantity * math.Min(math.Max(fraction, 0), 1)
+}
+
+// Reduce takes fraction of the position off every lot alike, leaving the
+// average entry prices as they are
+func (p *Position) Reduce(fraction float64) {
+	fraction = math.Min(math.Max(fraction, 0), 1)
+	p.Quantity -= p.Part(fraction)
+	for i := range p.Lots {
+		p.Lots[i].Quantity *= 1 - fraction
+	}
+	p.ScaledOut++
+}
+
+// positionPnL is the profit of quantity shares per leg held in direction
+func positionPnL(direction string, entry1, entry2, price1, price2, quantity float64) float64 {
+	var move float64
+	switch direction {
+	case ActionLong1Short2:
+		move = (price1 - entry1) + (entry2 - price2)
+	case ActionLong2Short1:
+		move = (entry1 - price1) + (price2 - entry2)
+	case ActionLong1:
+		move = price1 - entry1
+	case ActionShort1:
+		move = entry1 - price1
+	}
+	return move * quantity
+}
+
+// entryCost is the price of one share of each leg an entry in direction
+// trades, to size positions by notional
+func entryCost(direction string, price1, price2 float64) float64 {
+	if direction == ActionLong1 || direction == ActionShort1 {
+		return price1
+	}
+	return price1 + price2
+}
+
+// legSides returns the side each leg trades to open a position in
+// direction; closing trades the opposite sides
+func legSides(direction string) (string, string) {
+	if direction == ActionLong2Short1 || direction == ActionShort1 {
+		return SideSell, SideBuy
+	}
+	return SideBuy, SideSell
+}
+
+// StrategyFactory builds a Strategy from its config entry. Tickers and
+// thresholds come filled in; the factory checks what it needs.
+type StrategyFactory func(cfg config.StrategyConfig) (Strategy, error)
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = make(map[string]StrategyFactory)
+)
+
+func init() {
+	RegisterStrategy("pairs", newPairsStrategy)
+	RegisterStrategy("momentum", func(cfg config.StrategyConfig) (Strategy, error) {
+		return newSingleAssetStrategy(cfg, true)
+	})
+	RegisterStrategy("mean_reversion", func(cfg config.StrategyConfig) (Strategy, error) {
+		return newSingleAssetStrategy(cfg, false)
+	})
+}
+
+// RegisterStrategy makes a strategy available to paper.strategies entries
+// of that name. It panics if the name is registered twice.
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+
+	if factory == nil {
+		panic("trading: RegisterStrategy factory is nil")
+	}
+	if _, dup := strategies[name]; dup {
+		panic("trading: RegisterStrategy called twice for strategy " + name)
+	}
+	strategies[name] = factory
+}
+
+// StrategyNames returns the registered strategies sorted by name
+func StrategyNames() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewStrategy builds the strategy a config entry describes
+func NewStrategy(cfg config.StrategyConfig) (Strategy, error) {
+	name := strings.ToLower(cfg.Name)
+	strategiesMu.RLock()
+	factory, ok := strategies[name]
+	strategiesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q (use %s)", cfg.Name, strings.Join(StrategyNames(), ", "))
+	}
+	return factory(cfg)
+}
+
+// StrategyKey names a strategy in the paper ledger: "KO/PEP" for the pairs
+// strategy, "momentum:NVDA" for others
+func StrategyKey(s Strategy) string {
+	t1, t2 := s.Tickers()
+	key := strings.ToUpper(t1)
+	if t2 != "" {
+		key = PairKey(t1, t2)
+	}
+	if s.Name() != "pairs" {
+		key = s.Name() + ":" + key
+	}
+	return key
+}
+
+// splitTickers reads "T1/T2" (pairs) or "T" (single asset)
+func splitTickers(tickers string) (string, string) {
+	t1, t2, _ := strings.Cut(tickers, "/")
+	return strings.ToUpper(strings.TrimSpace(t1)), strings.ToUpper(strings.TrimSpace(t2))
+}
+
+func newPairsStrategy(cfg config.StrategyConfig) (Strategy, error) {
+	t1, t2 := splitTickers(cfg.Tickers)
+	if t1 == "" || t2 == "" {
+		return nil, fmt.Errorf("pairs: tickers %q: want TICKER1/TICKER2", cfg.Tickers)
+	}
+	if cfg.Lookback < 2 {
+		return nil, fmt.Errorf("pairs: lookback %d: need at least 2", cfg.Lookback)
+	}
+	s := NewPairsTradingStrategy(CorrelationPair{Asset1: t1, Asset2: t2, Ticker1: t1, Ticker2: t2},
+		cfg.Entry, cfg.Exit, cfg.StopLoss, cfg.Lookback)
+	s.ScaleIn = append([]float64(nil), cfg.ScaleIn...)
+	s.ScaleOut = append([]float64(nil), cfg.ScaleOut...)
+	sort.Float64s(s.ScaleIn)
+	sort.Sort(sort.Reverse(sort.Float64Slice(s.ScaleOut)))
+	return s, nil
+}
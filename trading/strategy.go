@@ -3,6 +3,7 @@ package trading
 import (
 	"fmt"
 	"math"
+	"time"
 )
 
 // Signal represents a trading signal
@@ -12,11 +13,60 @@ type Signal struct {
 	Asset2    string
 	Ticker1   string
 	Ticker2   string
-	Action    string  // "LONG_1_SHORT_2", "LONG_2_SHORT_1", "CLOSE"
+	Action    string // One of the Action constants
 	ZScore    float64
 	Price1    float64
 	Price2    float64
 	Spread    float64
+	Reason    string  // Why a CLOSE or SCALE_OUT fired: one of the Exit constants
+	Fraction  float64 // Share of the position a SCALE_OUT takes off
+}
+
+// Reasons a position is closed
+const (
+	ExitMeanReversion = "mean_reversion" // Z-score back inside the exit threshold
+	ExitReversal      = "reversal"       // Z-score crossed zero
+	ExitStopLoss      = "stop_loss"
+	ExitScaleOut      = "scale_out"   // Part of the position taken off (see PairsTradingStrategy.ScaleOut)
+	ExitMaxHolding    = "max_holding" // Held for Constraints.MaxHolding
+	ExitBlackout      = "blackout"    // A blackout window started
+	ExitWindowEnd     = "window_end"  // The targeted window ended (Constraints.Target)
+	ExitEndOfData     = "end_of_data" // Backtest ran out of prices
+)
+
+// Blackout is a window in which a strategy takes no new positions and
+// closes open ones, e.g. around an earnings date
+type Blackout struct {
+	Start  time.Time
+	End    time.Time
+	Reason string // e.g. "AAPL earnings"
+}
+
+// Contains reports whether t falls in the window
+func (b Blackout) Contains(t time.Time) bool {
+	return !t.Before(b.Start) && t.Before(b.End)
+}
+
+// Constraints limit how long a strategy holds and when it may enter
+type Constraints struct {
+	MaxHolding time.Duration // Close positions held this long (0 = no limit)
+	Cooldown   time.Duration // Minimum time from an exit to the next entry (0 = none)
+	Blackouts  []Blackout
+
+	// Target inverts the blackouts: the strategy only enters inside one of
+	// the windows and closes when it ends, to trade around events instead
+	// of avoiding them
+	Target bool
+}
+
+// blackoutAt returns the window containing t, if any
+func (c Constraints) blackoutAt(t time.Time) (Blackout, bool) {
+	for _, b := range c.Blackouts {
+		if b.Contains(t) {
+			return b, true
+		}
+	}
+	return Blackout{}, false
 }
 
 // Position represents an open trading position
@@ -26,24 +76,32 @@ type Position struct {
 	Asset2         string
 	Ticker1        string
 	Ticker2        string
-	Direction      string  // "LONG_1_SHORT_2" or "LONG_2_SHORT_1"
-	EntryPrice1    float64
+	Direction      string  // The entry signal's action
+	EntryPrice1    float64 // Quantity-weighted average over the lots
 	EntryPrice2    float64
 	EntrySpread    float64
 	EntryZScore    float64
 	Quantity       float64 // Position size
+	Lots           []Lot   // The entry and every scale-in, oldest first
+	ScaledOut      int     // Scale-outs taken so far
 }
 
 // PairsTradingStrategy implements a statistical arbitrage pairs trading strategy
 type PairsTradingStrategy struct {
-	Pair              CorrelationPair
-	EntryThreshold    float64 // Z-score threshold for entry (e.g., 2.0)
-	ExitThreshold     float64 // Z-score threshold for exit (e.g., 0.5)
-	StopLoss          float64 // Stop loss as percentage (e.g., 0.05 for 5%)
-	LookbackWindow    int     // Number of periods for calculating spread statistics
-	CurrentPosition   *Position
-	PriceHistory1     []PricePoint
-	PriceHistory2     []PricePoint
+	StrategyState
+	Pair           CorrelationPair
+	EntryThreshold float64 // Z-score threshold for entry (e.g., 2.0)
+	ExitThreshold  float64 // Z-score threshold for exit (e.g., 0.5)
+	StopLoss       float64 // Stop loss as percentage (e.g., 0.05 for 5%)
+	LookbackWindow int     // Number of periods for calculating spread statistics
+
+	// ScaleIn lists z-scores beyond EntryThreshold, ascending, at which a
+	// lot is added to the position (e.g. 2.5, 3.0). ScaleOut lists z-scores
+	// above ExitThreshold, descending, at which a share of it is taken off
+	// (e.g. 1.0), so the position steps down in equal parts before the
+	// exit closes the rest. Both empty = all in, all out.
+	ScaleIn  []float64
+	ScaleOut []float64
 }
 
 // NewPairsTradingStrategy creates a new pairs trading strategy
@@ -57,17 +115,29 @@ func NewPairsTradingStrategy(pair CorrelationPair, entryThreshold, exitThreshold
 	}
 }
 
+// Name implements Strategy
+func (s *PairsTradingStrategy) Name() string { return "pairs" }
+
+// Tickers implements Strategy
+func (s *PairsTradingStrategy) Tickers() (string, string) { return s.Pair.Ticker1, s.Pair.Ticker2 }
+
+// Warmup implements Strategy
+func (s *PairsTradingStrategy) Warmup() int { return s.LookbackWindow }
+
+// Update implements Strategy
+func (s *PairsTradingStrategy) Update(timestamp int64, price1, price2 float64) {
+	s.UpdatePrices(timestamp, price1, price2)
+}
+
+// Signal implements Strategy
+func (s *PairsTradingStrategy) Signal(timestamp int64) (*Signal, error) {
+	return s.GenerateSignal(timestamp)
+}
+
 // UpdatePrices adds new price observations
 func (s *PairsTradingStrategy) UpdatePrices(timestamp int64, price1, price2 float64) {
-	s.PriceHistory1 = append(s.PriceHistory1, PricePoint{Timestamp: timestamp, Price: price1})
-	s.PriceHistory2 = append(s.PriceHistory2, PricePoint{Timestamp: timestamp, Price: price2})
-
 	// Keep only the lookback window + some buffer
-	maxLen := s.LookbackWindow * 2
-	if len(s.PriceHistory1) > maxLen {
-		s.PriceHistory1 = s.PriceHistory1[len(s.PriceHistory1)-maxLen:]
-		s.PriceHistory2 = s.PriceHistory2[len(s.PriceHistory2)-maxLen:]
-	}
+	s.Record(timestamp, price1, price2, s.LookbackWindow*2)
 }
 
 // CalculateSpread calculates the spread between two price series
@@ -154,41 +224,62 @@ func (s *PairsTradingStrategy) GenerateSignal(timestamp int64) (*Signal, error)
 
 	// Check if we have an open position
 	if s.CurrentPosition != nil {
-		// Check stop loss
-		pnl := s.CalculatePnL(currentPrice1, currentPrice2)
-		pnlPercent := pnl / (s.CurrentPosition.EntryPrice1 + s.CurrentPosition.EntryPrice2)
+		signal.Action = ActionClose
 
-		if pnlPercent < -s.StopLoss {
-			signal.Action = "CLOSE"
+		// Check stop loss and holding constraints
+		if signal.Reason = s.CheckExit(timestamp, currentPrice1, currentPrice2, s.StopLoss); signal.Reason != "" {
 			return signal, nil
 		}
 
 		// Check exit conditions
 		if math.Abs(zScore) < s.ExitThreshold {
-			signal.Action = "CLOSE"
+			signal.Reason = ExitMeanReversion
 			return signal, nil
 		}
 
 		// Check reversal (z-score crossed zero - spread mean reverted too much)
-		if (s.CurrentPosition.Direction == "LONG_1_SHORT_2" && zScore < 0) ||
-			(s.CurrentPosition.Direction == "LONG_2_SHORT_1" && zScore > 0) {
-			signal.Action = "CLOSE"
+		pos := s.CurrentPosition
+		if (pos.Direction == ActionLong1Short2 && zScore > 0) ||
+			(pos.Direction == ActionLong2Short1 && zScore < 0) {
+			signal.Reason = ExitReversal
 			return signal, nil
 		}
 
+		// Step down as the spread comes back, one level at a time
+		if pos.ScaledOut < len(s.ScaleOut) && math.Abs(zScore) < s.ScaleOut[pos.ScaledOut] {
+			signal.Action = ActionScaleOut
+			signal.Reason = ExitScaleOut
+			signal.Fraction = 1 / float64(len(s.ScaleOut)-pos.ScaledOut+1)
+			return signal, nil
+		}
+
+		// Add a lot as the spread stretches further, unless stepping down
+		if added := max(len(pos.Lots)-1, 0); pos.ScaledOut == 0 && added < len(s.ScaleIn) && s.CanEnter(timestamp) {
+			band := s.ScaleIn[added]
+			if (pos.Direction == ActionLong2Short1 && zScore > band) ||
+				(pos.Direction == ActionLong1Short2 && zScore < -band) {
+				signal.Action = ActionScaleIn
+				return signal, nil
+			}
+		}
+
 		return nil, nil // Hold current position
 	}
 
+	if !s.CanEnter(timestamp) {
+		return nil, nil
+	}
+
 	// Check entry conditions
 	if zScore > s.EntryThreshold {
 		// Spread is high: short asset1, long asset2
-		signal.Action = "LONG_2_SHORT_1"
+		signal.Action = ActionLong2Short1
 		return signal, nil
 	}
 
 	if zScore < -s.EntryThreshold {
 		// Spread is low: long asset1, short asset2
-		signal.Action = "LONG_1_SHORT_2"
+		signal.Action = ActionLong1Short2
 		return signal, nil
 	}
 
@@ -197,64 +288,5 @@ func (s *PairsTradingStrategy) GenerateSignal(timestamp int64) (*Signal, error)
 
 // ExecuteSignal executes a trading signal
 func (s *PairsTradingStrategy) ExecuteSignal(signal *Signal, positionSize float64) {
-	if signal.Action == "CLOSE" && s.CurrentPosition != nil {
-		s.CurrentPosition = nil
-		return
-	}
-
-	if signal.Action == "LONG_1_SHORT_2" || signal.Action == "LONG_2_SHORT_1" {
-		s.CurrentPosition = &Position{
-			EntryTimestamp: signal.Timestamp,
-			Asset1:         signal.Asset1,
-			Asset2:         signal.Asset2,
-			Ticker1:        signal.Ticker1,
-			Ticker2:        signal.Ticker2,
-			Direction:      signal.Action,
-			EntryPrice1:    signal.Price1,
-			EntryPrice2:    signal.Price2,
-			EntrySpread:    signal.Spread,
-			EntryZScore:    signal.ZScore,
-			Quantity:       positionSize,
-		}
-	}
-}
-
-// CalculatePnL calculates the current P&L for an open position
-func (s *PairsTradingStrategy) CalculatePnL(currentPrice1, currentPrice2 float64) float64 {
-	if s.CurrentPosition == nil {
-		return 0
-	}
-
-	var pnl float64
-
-	if s.CurrentPosition.Direction == "LONG_1_SHORT_2" {
-		// Long asset1, short asset2
-		pnl1 := (currentPrice1 - s.CurrentPosition.EntryPrice1) * s.CurrentPosition.Quantity
-		pnl2 := (s.CurrentPosition.EntryPrice2 - currentPrice2) * s.CurrentPosition.Quantity
-		pnl = pnl1 + pnl2
-	} else {
-		// Long asset2, short asset1
-		pnl1 := (s.CurrentPosition.EntryPrice1 - currentPrice1) * s.CurrentPosition.Quantity
-		pnl2 := (currentPrice2 - s.CurrentPosition.EntryPrice2) * s.CurrentPosition.Quantity
-		pnl = pnl1 + pnl2
-	}
-
-	return pnl
-}
-
-// HasOpenPosition returns whether there's an open position
-func (s *PairsTradingStrategy) HasOpenPosition() bool {
-	return s.CurrentPosition != nil
-}
-
-// GetCurrentPosition returns the current position if any
-func (s *PairsTradingStrategy) GetCurrentPosition() *Position {
-	return s.CurrentPosition
-}
-
-// Reset resets the strategy state
-func (s *PairsTradingStrategy) Reset() {
-	s.CurrentPosition = nil
-	s.PriceHistory1 = []PricePoint{}
-	s.PriceHistory2 = []PricePoint{}
+	s.Execute(signal, positionSize)
 }
@@ -0,0 +1,180 @@
+package trading
+
+import (
+	"fmt"
+	"margraf/graph"
+	"math"
+	"sort"
+)
+
+// PairWatch is a monitored pair's latest reading
+type PairWatch struct {
+	Pair      CorrelationPair
+	ZScore    float64 // Of the price ratio over the lookback window
+	Spread    float64 // Latest price ratio
+	Timestamp int64   // Of the latest aligned prices
+}
+
+// Action is the pairs strategy's entry for the reading at entry, or "" while
+// |z| is below it
+func (w PairWatch) Action(entry float64) string {
+	switch {
+	case w.ZScore > entry:
+		return ActionLong2Short1
+	case w.ZScore < -entry:
+		return ActionLong1Short2
+	}
+	return ""
+}
+
+// AlignPrices keeps the prices both series have a timestamp for, in time
+// order
+func AlignPrices(prices1, prices2 []PricePoint) ([]PricePoint, []PricePoint) {
+	at := make(map[int64]float64, len(prices2))
+	for _, p := range prices2 {
+		at[p.Timestamp] = p.Price
+	}
+	var aligned1, aligned2 []PricePoint
+	for _, p := range prices1 {
+		if price, ok := at[p.Timestamp]; ok {
+			aligned1 = append(aligned1, p)
+			aligned2 = append(aligned2, PricePoint{Timestamp: p.Timestamp, Price: price})
+		}
+	}
+	order := make([]int, len(aligned1))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return aligned1[order[i]].Timestamp < aligned1[order[j]].Timestamp })
+	sorted1, sorted2 := make([]PricePoint, len(order)), make([]PricePoint, len(order))
+	for i, k := range order {
+		sorted1[i], sorted2[i] = aligned1[k], aligned2[k]
+	}
+	return sorted1, sorted2
+}
+
+// ConnectedPairs ranks pairs of assets (node ID -> ticker) by their graph
+// prior and returns the top ones (0 = all). Only pairs sharing an industry,
+// a counterparty or an edge are scored, so large graphs stay cheap.
+func (ca *CorrelationAnalyzer) ConnectedPairs(assets map[string]string, top int) []CorrelationPair {
+	if ca.Graph == nil {
+		return nil
+	}
+	// Assets grouped by what links them: an industry or a counterparty
+	groups := make(map[string][]string)
+	for id := range assets {
+		for _, e := range ca.Graph.GetIncomingEdges(id) {
+			if e.Type == graph.EdgeTypeHasCompany {
+				groups[e.SourceID] = append(groups[e.SourceID], id)
+			}
+		}
+		for party := range ca.counterparties(id) {
+			groups[party] = append(groups[party], id)
+		}
+	}
+
+	seen := make(map[[2]string]bool)
+	var pairs []CorrelationPair
+	for _, members := range groups {
+		for i := 0; i < len(members); i++ {
+			for j := i + 1; j < len(members); j++ {
+				a1, a2 := members[i], members[j]
+				if a1 > a2 {
+					a1, a2 = a2, a1
+				}
+				key := [2]string{a1, a2}
+				if a1 == a2 || seen[key] || assets[a1] == assets[a2] {
+					continue
+				}
+				seen[key] = true
+				prior := ca.GraphPrior(a1, a2)
+				if prior <= 0 {
+					continue
+				}
+				distance, hasEdge, weight := ca.getGraphRelationship(a1, a2)
+				pairs = append(pairs, CorrelationPair{
+					Asset1:        a1,
+					Asset2:        a2,
+					Ticker1:       assets[a1],
+					Ticker2:       assets[a2],
+					GraphPrior:    prior,
+					GraphDistance: distance,
+					HasDirectEdge: hasEdge,
+					EdgeWeight:    weight,
+				})
+			}
+		}
+	}
+	// Assets linked only by an edge between them
+	for a1 := range assets {
+		for _, e := range ca.Graph.GetOutgoingEdges(a1) {
+			a2 := e.TargetID
+			if _, ok := assets[a2]; !ok || a2 == a1 || assets[a1] == assets[a2] {
+				continue
+			}
+			lo, hi := min(a1, a2), max(a1, a2)
+			if seen[[2]string{lo, hi}] {
+				continue
+			}
+			seen[[2]string{lo, hi}] = true
+			if prior := ca.GraphPrior(lo, hi); prior > 0 {
+				pairs = append(pairs, CorrelationPair{
+					Asset1:        lo,
+					Asset2:        hi,
+					Ticker1:       assets[lo],
+					Ticker2:       assets[hi],
+					GraphPrior:    prior,
+					GraphDistance: 1,
+					HasDirectEdge: true,
+					EdgeWeight:    e.Weight,
+				})
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].GraphPrior != pairs[j].GraphPrior {
+			return pairs[i].GraphPrior > pairs[j].GraphPrior
+		}
+		return pairs[i].Asset1+pairs[i].Asset2 < pairs[j].Asset1+pairs[j].Asset2
+	})
+	if top > 0 && len(pairs) > top {
+		pairs = pairs[:top]
+	}
+	return pairs
+}
+
+// WatchPair reads a pair's correlation and the z-score of its price ratio
+// over the last lookback aligned prices
+func (ca *CorrelationAnalyzer) WatchPair(pair CorrelationPair, prices1, prices2 []PricePoint, lookback int) (PairWatch, error) {
+	aligned1, aligned2 := AlignPrices(prices1, prices2)
+	if len(aligned1) < lookback {
+		return PairWatch{}, fmt.Errorf("%s/%s: insufficient data: have %d aligned prices, need %d", pair.Ticker1, pair.Ticker2, len(aligned1), lookback)
+	}
+	raw, err := CalculateCorrelation(aligned1, aligned2)
+	if err != nil {
+		return PairWatch{}, fmt.Errorf("%s/%s: %w", pair.Ticker1, pair.Ticker2, err)
+	}
+	pair.RawCorrelation = raw
+	pair.Observations = len(aligned1)
+	pair.Correlation, pair.PriorWeight = blendCorrelation(raw, pair.GraphPrior, len(aligned1), ca.Shrinkage)
+
+	strategy := NewPairsTradingStrategy(pair, 0, 0, 0, lookback)
+	for i := range aligned1 {
+		strategy.UpdatePrices(aligned1[i].Timestamp, aligned1[i].Price, aligned2[i].Price)
+	}
+	z, err := strategy.CalculateZScore()
+	if err != nil {
+		return PairWatch{}, fmt.Errorf("%s/%s: %w", pair.Ticker1, pair.Ticker2, err)
+	}
+	last := len(aligned1) - 1
+	if aligned2[last].Price == 0 || math.IsNaN(z) {
+		return PairWatch{}, fmt.Errorf("%s/%s: no spread", pair.Ticker1, pair.Ticker2)
+	}
+	return PairWatch{
+		Pair:      pair,
+		ZScore:    z,
+		Spread:    aligned1[last].Price / aligned2[last].Price,
+		Timestamp: aligned1[last].Timestamp,
+	}, nil
+}
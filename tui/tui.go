@@ -2,6 +2,12 @@ package tui
 
 import (
 	"fmt"
+	"margraf/clock"
+	"margraf/llm"
+	"margraf/pipeline"
+	"margraf/syserr"
+	"margraf/task"
+	"strings"
 	"sync"
 
 	"github.com/gdamore/tcell/v2"
@@ -14,10 +20,14 @@ type TUI struct {
 	logsView    *tview.TextView
 	inputField  *tview.InputField
 	statsView   *tview.TextView
+	tasksView   *tview.TextView
+	healthView  *tview.TextView
+	budgetView  *tview.TextView
 	headerView  *tview.TextView
 	commandChan chan string
 	mu          sync.Mutex
 	logBuffer   []string
+	lineOpen    bool // The last line was written by Append and not yet ended
 	maxLogLines int
 }
 
@@ -47,6 +57,33 @@ func New() *TUI {
 	// Initialize stats view directly (app not running yet)
 	t.initStats()
 
+	// Create tasks view
+	t.tasksView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignLeft)
+	t.tasksView.SetBorder(true).
+		SetTitle(" Tasks ").
+		SetBorderColor(tcell.ColorNames["purple"])
+	t.renderTasks(nil)
+
+	// Create health view
+	t.healthView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignLeft)
+	t.healthView.SetBorder(true).
+		SetTitle(" Health ").
+		SetBorderColor(tcell.ColorNames["red"])
+	t.renderHealth(nil)
+
+	// Create LLM budget view
+	t.budgetView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignLeft)
+	t.budgetView.SetBorder(true).
+		SetTitle(" LLM Budget ").
+		SetBorderColor(tcell.ColorNames["teal"])
+	t.renderBudget(nil)
+
 	// Create logs view
 	t.logsView = tview.NewTextView().
 		SetDynamicColors(true).
@@ -80,7 +117,12 @@ func New() *TUI {
 		AddItem(t.headerView, 3, 0, false).
 		AddItem(tview.NewFlex().SetDirection(tview.FlexColumn).
 			AddItem(t.logsView, 0, 3, false).
-			AddItem(t.statsView, 40, 0, false),
+			AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(t.statsView, 0, 2, false).
+				AddItem(t.tasksView, 0, 1, false).
+				AddItem(t.healthView, 0, 1, false).
+				AddItem(t.budgetView, 0, 1, false),
+				40, 0, false),
 			0, 1, false).
 		AddItem(t.inputField, 3, 0, true)
 
@@ -111,6 +153,7 @@ func (t *TUI) Log(message string) {
 
 	// Add to buffer
 	t.logBuffer = append(t.logBuffer, message)
+	t.lineOpen = false
 
 	// Keep only last N lines
 	if len(t.logBuffer) > t.maxLogLines {
@@ -129,36 +172,176 @@ func (t *TUI) Log(message string) {
 	}()
 }
 
+// Append writes text without ending the line, continuing a line Append left
+// open; newlines in text start new lines. Streamed answers use it to show each piece as it arrives.
+func (t *TUI) Append(text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := strings.Split(tview.Escape(text), "\n")
+	if !t.lineOpen {
+		t.logBuffer = append(t.logBuffer, "")
+	}
+	t.logBuffer[len(t.logBuffer)-1] += lines[0]
+	t.logBuffer = append(t.logBuffer, lines[1:]...)
+	// Text ending in a newline leaves the next line to whoever writes next
+	t.lineOpen = !strings.HasSuffix(text, "\n")
+	if !t.lineOpen {
+		t.logBuffer = t.logBuffer[:len(t.logBuffer)-1]
+	}
+	if len(t.logBuffer) > t.maxLogLines {
+		t.logBuffer = t.logBuffer[len(t.logBuffer)-t.maxLogLines:]
+	}
+
+	go func() {
+		t.app.QueueUpdateDraw(func() {
+			t.logsView.Clear()
+			for _, line := range t.logBuffer {
+				fmt.Fprintln(t.logsView, line)
+			}
+			t.logsView.ScrollToEnd()
+		})
+	}()
+}
+
 // initStats initializes the statistics display (before app is running)
 func (t *TUI) initStats() {
+	t.renderStats(0, 0, pipeline.All())
+}
+
+// UpdateStats updates the statistics display
+func (t *TUI) UpdateStats(nodeCount, edgeCount int, pipelines []pipeline.Status) {
+	t.app.QueueUpdateDraw(func() {
+		t.renderStats(nodeCount, edgeCount, pipelines)
+	})
+}
+
+// renderStats draws counts, pipeline status and the command cheat sheet
+func (t *TUI) renderStats(nodeCount, edgeCount int, pipelines []pipeline.Status) {
 	t.statsView.Clear()
-	fmt.Fprintf(t.statsView, "[green::b]Nodes:[-:-:-] %d\n", 0)
-	fmt.Fprintf(t.statsView, "[yellow::b]Edges:[-:-:-] %d\n", 0)
+	fmt.Fprintf(t.statsView, "[green::b]Nodes:[-:-:-] %d\n", nodeCount)
+	fmt.Fprintf(t.statsView, "[yellow::b]Edges:[-:-:-] %d\n", edgeCount)
 	fmt.Fprintf(t.statsView, "\n[cyan]Status:[-] Running\n")
+	fmt.Fprintf(t.statsView, "\n[white::b]Pipelines:[-:-:-]\n")
+	for _, p := range pipelines {
+		if p.Enabled {
+			fmt.Fprintf(t.statsView, "[green]● %s[-]\n", p.Name)
+		} else {
+			fmt.Fprintf(t.statsView, "[red]○ %s (stopped)[-]\n", p.Name)
+		}
+	}
 	fmt.Fprintf(t.statsView, "\n[white::b]Available Commands:[-:-:-]\n")
 	fmt.Fprintln(t.statsView, "[gray]show, edges, discover[-]")
 	fmt.Fprintln(t.statsView, "[gray]companies, relations[-]")
 	fmt.Fprintln(t.statsView, "[gray]shock, boost, news[-]")
+	fmt.Fprintln(t.statsView, "[gray]pipeline start/stop[-]")
 	fmt.Fprintln(t.statsView, "[gray]save, load, export[-]")
 	fmt.Fprintln(t.statsView, "[gray]exit[-]")
 }
 
-// UpdateStats updates the statistics display
-func (t *TUI) UpdateStats(nodeCount, edgeCount int) {
+// UpdateTasks updates the tasks pane
+func (t *TUI) UpdateTasks(tasks []task.Info) {
+	t.app.QueueUpdateDraw(func() {
+		t.renderTasks(tasks)
+	})
+}
+
+// renderTasks draws running tasks with progress, then recently finished ones
+func (t *TUI) renderTasks(tasks []task.Info) {
+	t.tasksView.Clear()
+	if len(tasks) == 0 {
+		fmt.Fprintln(t.tasksView, "[gray]No background tasks[-]")
+		return
+	}
+	for _, info := range tasks {
+		name := info.Name
+		if len(name) > 24 {
+			name = name[:21] + "..."
+		}
+		switch info.State {
+		case task.StateRunning:
+			if p := info.Progress(); p >= 0 {
+				fmt.Fprintf(t.tasksView, "[yellow]%s[-] %s [::b]%3.0f%%[-:-:-]\n", info.ID, name, p*100)
+			} else {
+				fmt.Fprintf(t.tasksView, "[yellow]%s[-] %s [::b]...[-:-:-]\n", info.ID, name)
+			}
+		case task.StateDone:
+			fmt.Fprintf(t.tasksView, "[gray]%s %s ✓[-]\n", info.ID, name)
+		default:
+			fmt.Fprintf(t.tasksView, "[red]%s %s (%s)[-]\n", info.ID, name, info.State)
+		}
+	}
+}
+
+// UpdateHealth updates the health pane
+func (t *TUI) UpdateHealth(events []syserr.Event) {
+	t.app.QueueUpdateDraw(func() {
+		t.renderHealth(events)
+	})
+}
+
+// renderHealth draws each failing module's latest error
+func (t *TUI) renderHealth(events []syserr.Event) {
+	t.healthView.Clear()
+	if len(events) == 0 {
+		fmt.Fprintln(t.healthView, "[green]All systems OK[-]")
+		return
+	}
+	for _, e := range events {
+		color := "yellow"
+		if e.Severity != syserr.SeverityWarning {
+			color = "red"
+		}
+		msg := e.Message
+		if len(msg) > 28 {
+			msg = msg[:25] + "..."
+		}
+		fmt.Fprintf(t.healthView, "[%s::b]%s[-:-:-] %s\n[gray]%s[-]\n", color, e.Module, clock.Format(e.Timestamp, "15:04"), msg)
+	}
+}
+
+// UpdateBudget updates the LLM budget pane
+func (t *TUI) UpdateBudget(usage []llm.BudgetUsage) {
 	t.app.QueueUpdateDraw(func() {
-		t.statsView.Clear()
-		fmt.Fprintf(t.statsView, "[green::b]Nodes:[-:-:-] %d\n", nodeCount)
-		fmt.Fprintf(t.statsView, "[yellow::b]Edges:[-:-:-] %d\n", edgeCount)
-		fmt.Fprintf(t.statsView, "\n[cyan]Status:[-] Running\n")
-		fmt.Fprintf(t.statsView, "\n[white::b]Available Commands:[-:-:-]\n")
-		fmt.Fprintln(t.statsView, "[gray]show, edges, discover[-]")
-		fmt.Fprintln(t.statsView, "[gray]companies, relations[-]")
-		fmt.Fprintln(t.statsView, "[gray]shock, boost, news[-]")
-		fmt.Fprintln(t.statsView, "[gray]save, load, export[-]")
-		fmt.Fprintln(t.statsView, "[gray]exit[-]")
+		t.renderBudget(usage)
 	})
 }
 
+// renderBudget draws each consumer's requests and tokens against its daily
+// limits
+func (t *TUI) renderBudget(usage []llm.BudgetUsage) {
+	t.budgetView.Clear()
+	if len(usage) == 0 {
+		fmt.Fprintln(t.budgetView, "[gray]No budgets set[-]")
+		return
+	}
+	for _, u := range usage {
+		color := "green"
+		if u.Exhausted() {
+			color = "red"
+		}
+		fmt.Fprintf(t.budgetView, "[%s::b]%-8s[-:-:-] %s req %s tok\n", color, u.Consumer,
+			budgetAmount(u.Requests, u.Limit.Requests), budgetAmount(u.Tokens, u.Limit.Tokens))
+		if u.Denied > 0 || u.Queued > 0 {
+			fmt.Fprintf(t.budgetView, "[gray]  %d degraded, %d queued[-]\n", u.Denied, u.Queued)
+		}
+	}
+}
+
+// budgetAmount formats used against limit, compactly
+func budgetAmount(used, limit int) string {
+	short := func(n int) string {
+		if n >= 10000 {
+			return fmt.Sprintf("%dk", n/1000)
+		}
+		return fmt.Sprint(n)
+	}
+	if limit <= 0 {
+		return short(used)
+	}
+	return short(used) + "/" + short(limit)
+}
+
 // SetHeader updates the header text
 func (t *TUI) SetHeader(text string) {
 	t.app.QueueUpdateDraw(func() {
@@ -191,3 +374,8 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 	w.tui.Log(message)
 	return len(p), nil
 }
+
+// WritePartial implements logger.PartialWriter
+func (w *Writer) WritePartial(text string) {
+	w.tui.Append(text)
+}